@@ -2,8 +2,8 @@ package main
 
 import (
 	"fmt"
-	"funterm/factory"
 	"funterm/repl"
+	"funterm/runtime"
 	"funterm/shared"
 	"os"
 	"path/filepath"
@@ -12,7 +12,18 @@ import (
 )
 
 // BatchMode выполняет файл в пакетном режиме (без интерактивного REPL)
-func BatchMode(filePath string, language string, configPath string, verbose bool) error {
+func BatchMode(filePath string, language string, configPath string, verbose bool, cassette *runtime.Cassette) error {
+	return runBatch(filePath, language, configPath, verbose, cassette, batchOptions{})
+}
+
+// batchOptions — параметры runBatch сверх параметров BatchMode
+type batchOptions struct {
+	prelude string // скрипт, выполняемый перед файлом (инструменты funterm build)
+	target  string // SSH-хост для рантаймов-процессов (--target)
+}
+
+// runBatch выполняет файл, предварительно выполнив прелюдию, если она задана
+func runBatch(filePath, language, configPath string, verbose bool, cassette *runtime.Cassette, options batchOptions) error {
 	// Load configuration
 	cfg, err := LoadConfig(configPath)
 	if err != nil {
@@ -23,40 +34,13 @@ func BatchMode(filePath string, language string, configPath string, verbose bool
 	if verbose {
 		cfg.Engine.Verbose = true
 	}
-
-	// Create runtime registry with disabled languages support
-	registry := factory.NewRuntimeRegistry()
-
-	// Register runtimes based on configuration
-	if !cfg.IsLanguageDisabled("lua") {
-		luaFactory := factory.NewLuaRuntimeFactory()
-		if err := registry.RegisterFactory(luaFactory); err != nil {
-			fmt.Printf("Warning: Failed to register Lua runtime: %v\n", err)
+	if options.target != "" {
+		if err := cfg.SetRemote(options.target); err != nil {
+			return usageErrorf("run", "--target: %v", err)
 		}
 	}
 
-	if !cfg.IsLanguageDisabled("python") && !cfg.IsLanguageDisabled("py") {
-		pythonPath := cfg.GetRuntimePath("python")
-		executionTimeout := time.Duration(cfg.Engine.MaxExecutionTime) * time.Second
-		pythonFactory := factory.NewPythonRuntimeFactoryWithConfig(pythonPath, cfg.Engine.Verbose, executionTimeout)
-		if err := registry.RegisterFactory(pythonFactory); err != nil {
-			fmt.Printf("Warning: Failed to register Python runtime: %v\n", err)
-		}
-	}
-
-	if !cfg.IsLanguageDisabled("go") {
-		goFactory := factory.NewGoRuntimeFactory()
-		if err := registry.RegisterFactory(goFactory); err != nil {
-			fmt.Printf("Warning: Failed to register Go runtime: %v\n", err)
-		}
-	}
-
-	if !cfg.IsLanguageDisabled("node") && !cfg.IsLanguageDisabled("js") && !cfg.IsLanguageDisabled("javascript") {
-		nodeFactory := factory.NewNodeRuntimeFactory()
-		if err := registry.RegisterFactory(nodeFactory); err != nil {
-			fmt.Printf("Warning: Failed to register Node.js runtime: %v\n", err)
-		}
-	}
+	registry := newRuntimeRegistry(cfg, cassette)
 
 	// Create REPL with configuration
 	replInstance := repl.NewREPLWithConfig(repl.REPLConfig{
@@ -66,7 +50,22 @@ func BatchMode(filePath string, language string, configPath string, verbose bool
 		ContinuePrompt: "... ", // Default continuation prompt
 		HistoryFile:    cfg.REPL.HistoryFile,
 		HistorySize:    cfg.REPL.HistorySize,
+		// Спиннер для долгих вызовов рантаймов
+		SpinnerThreshold: time.Duration(cfg.Engine.SpinnerThresholdMs) * time.Millisecond,
+		MemoryBudget:     int64(cfg.Engine.MemoryBudgetMB) << 20,
+		ParserHandlers:   cfg.Parser,
+		Numbers:          cfg.Engine.Numbers,
+		MatchWarnings:    cfg.Engine.MatchWarnings,
+		GuardedCalls:     cfg.Engine.GuardedCalls,
+		CollectUsage:     cfg.Telemetry.Enabled,
+		ResultCacheDir:   expandHome(cfg.Engine.ResultCache),
+		StorePath:        expandHome(cfg.Engine.Store),
+		Strict:           cfg.Engine.Strict,
+		StrictRules:      cfg.Engine.StrictRules,
+		SegmentOverflow:  cfg.Engine.SegmentOverflow,
+		Policy:           cfg.Engine.Policy.sessionPolicy(),
 	})
+	defer func() { recordTelemetry(cfg, "script", registry, replInstance.GetEngine().Usage()) }()
 
 	// Отключаем приветственное сообщение в пакетном режиме
 	replInstance.SetWelcomeMessage(false)
@@ -81,6 +80,16 @@ func BatchMode(filePath string, language string, configPath string, verbose bool
 		return fmt.Errorf("ошибка инициализации рантаймов REPL: %v", err)
 	}
 
+	if options.prelude != "" {
+		prelude, err := os.ReadFile(options.prelude)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения прелюдии: %v", err)
+		}
+		if _, _, _, err := replInstance.GetEngine().Execute(string(prelude)); err != nil {
+			return fmt.Errorf("prelude error: %v", err)
+		}
+	}
+
 	// Определяем тип файла по расширению, если язык не указан
 	if language == "" {
 		ext := strings.ToLower(filepath.Ext(filePath))
@@ -147,12 +156,16 @@ func executeMixedFile(r *repl.REPL, filePath string, verbose bool) error {
 		fmt.Printf("Executing mixed language file: %s (%d characters)\n", filePath, len(fileContent))
 	}
 
+	// Обработчики on_exit выполняются и после ошибки скрипта
+	defer r.GetEngine().RunExitHooks()
+
 	// Выполняем весь файл как единое целое через ExecutionEngine
 	// Это позволяет правильно обрабатывать многострочные конструкции как блоки кода
 	result, _, _, err := r.GetEngine().Execute(fileContent)
 	if err != nil {
 		return fmt.Errorf("script execution error: %v", err)
 	}
+	r.ReportMemoryWarnings()
 
 	// Выводим результат выполнения, если он не пустой
 	if result != nil && result != "" {
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"funterm/bench"
+	"funterm/engine"
+)
+
+// RunBench implements `funterm bench [options] [workload...]`: it measures the
+// standard workloads, optionally saves the results and compares them against
+// a baseline. It returns false if a workload regressed beyond the tolerance.
+func RunBench(args []string, configPath string) (bool, error) {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "Print results as JSON")
+	baselinePath := flags.String("baseline", "", "Compare against results saved with --save")
+	savePath := flags.String("save", "", "Save results as JSON to this file")
+	tolerance := flags.Float64("tolerance", 0.10, "Allowed slowdown and allocation growth over the baseline (0.10 = 10%)")
+	timeTolerance := flags.Float64("time-tolerance", -1, "Allowed slowdown over the baseline, --tolerance when not set")
+	minTime := flags.Duration("time", time.Second, "Minimum run time of each workload")
+	list := flags.Bool("list", false, "List the workloads")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+
+	if *list {
+		for _, workload := range bench.Workloads {
+			fmt.Printf("%-20s %s\n", workload.Name, workload.Description)
+		}
+		return true, nil
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+
+	report, err := bench.Run(bench.Workloads, bench.Options{
+		MinTime: *minTime,
+		Names:   flags.Args(),
+		NewEngine: func() (*engine.ExecutionEngine, error) {
+			return engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+				ParserHandlers: cfg.Parser,
+				Numbers:        cfg.Engine.Numbers,
+			})
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if *savePath != "" {
+		if err := bench.SaveReport(*savePath, report); err != nil {
+			return false, err
+		}
+	}
+	if *jsonOutput {
+		if err := bench.WriteReport(os.Stdout, report); err != nil {
+			return false, err
+		}
+	} else {
+		printBenchReport(report)
+	}
+
+	if *baselinePath == "" {
+		return true, nil
+	}
+	baseline, err := bench.LoadReport(*baselinePath)
+	if err != nil {
+		return false, err
+	}
+	if *timeTolerance < 0 {
+		*timeTolerance = *tolerance
+	}
+	regressions := bench.Compare(report, baseline, *timeTolerance, *tolerance)
+	for _, regression := range regressions {
+		fmt.Fprintf(os.Stderr, "regression: %s\n", regression)
+	}
+	return len(regressions) == 0, nil
+}
+
+// printBenchReport prints the results as a table
+func printBenchReport(report *bench.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "workload\titerations\tns/op\tB/op\tallocs/op\t")
+	for _, result := range report.Results {
+		if result.Skipped != "" {
+			fmt.Fprintf(w, "%s\tskipped: %s\t\t\t\t\n", result.Name, result.Skipped)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.0f\t%d\t%d\t\n", result.Name, result.Iterations, result.NsPerOp, result.BytesPerOp, result.AllocsPerOp)
+	}
+	w.Flush()
+}
@@ -0,0 +1,166 @@
+// Package bench runs representative funterm workloads and compares their
+// timings against a stored baseline, so performance regressions are caught
+// before a release
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	goruntime "runtime"
+	"time"
+
+	"funterm/engine"
+)
+
+// Workload is one benchmark. Prepare sets it up on a fresh engine and returns
+// the operation that is timed
+type Workload struct {
+	Name        string
+	Description string
+	Prepare     func(eng *engine.ExecutionEngine) (func() error, error)
+}
+
+// Result is the measurement of one workload
+type Result struct {
+	Name        string  `json:"name"`
+	Iterations  int     `json:"iterations"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  uint64  `json:"bytes_per_op"`
+	AllocsPerOp uint64  `json:"allocs_per_op"`
+	// Skipped is why the workload did not run, e.g. a runtime that isn't installed
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Report is the output of a run, written as JSON by --save
+type Report struct {
+	GoVersion string   `json:"go_version"`
+	OS        string   `json:"os"`
+	Arch      string   `json:"arch"`
+	Results   []Result `json:"results"`
+}
+
+// Options controls a run
+type Options struct {
+	// MinTime is how long each workload runs at least (default 1s)
+	MinTime time.Duration
+	// Names selects workloads by name; empty runs all of them
+	Names []string
+	// NewEngine creates the engine a workload runs on
+	NewEngine func() (*engine.ExecutionEngine, error)
+}
+
+// Run measures the selected workloads one after another
+func Run(workloads []Workload, opts Options) (*Report, error) {
+	if opts.MinTime <= 0 {
+		opts.MinTime = time.Second
+	}
+	selected, err := selectWorkloads(workloads, opts.Names)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{GoVersion: goruntime.Version(), OS: goruntime.GOOS, Arch: goruntime.GOARCH}
+	for _, workload := range selected {
+		eng, err := opts.NewEngine()
+		if err != nil {
+			return nil, err
+		}
+		op, err := workload.Prepare(eng)
+		if err != nil {
+			report.Results = append(report.Results, Result{Name: workload.Name, Skipped: err.Error()})
+			continue
+		}
+		result, err := measure(op, opts.MinTime)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", workload.Name, err)
+		}
+		result.Name = workload.Name
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// selectWorkloads keeps the workloads with the given names, in the order given
+func selectWorkloads(workloads []Workload, names []string) ([]Workload, error) {
+	if len(names) == 0 {
+		return workloads, nil
+	}
+	var selected []Workload
+	for _, name := range names {
+		found := false
+		for _, workload := range workloads {
+			if workload.Name == name {
+				selected = append(selected, workload)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown workload %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// measure runs op until minTime has passed, after one warm-up run
+func measure(op func() error, minTime time.Duration) (Result, error) {
+	if err := op(); err != nil {
+		return Result{}, err
+	}
+	goruntime.GC()
+
+	var before, after goruntime.MemStats
+	goruntime.ReadMemStats(&before)
+	start := time.Now()
+	iterations := 0
+	for time.Since(start) < minTime {
+		if err := op(); err != nil {
+			return Result{}, err
+		}
+		iterations++
+	}
+	elapsed := time.Since(start)
+	goruntime.ReadMemStats(&after)
+
+	return Result{
+		Iterations:  iterations,
+		NsPerOp:     float64(elapsed.Nanoseconds()) / float64(iterations),
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(iterations),
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / uint64(iterations),
+	}, nil
+}
+
+// LoadReport reads a report saved with SaveReport
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &report, nil
+}
+
+// WriteReport writes a report as indented JSON
+func WriteReport(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// SaveReport writes a report to a file as indented JSON
+func SaveReport(path string, report *Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteReport(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,54 @@
+package bench
+
+import "fmt"
+
+// Regression is a workload that is slower, or allocates more, than its
+// baseline allows
+type Regression struct {
+	Name     string
+	Metric   string // "ns/op" or "allocs/op"
+	Baseline float64
+	Current  float64
+}
+
+// Change is the relative growth over the baseline, 0.25 for 25%
+func (r Regression) Change() float64 {
+	return r.Current/r.Baseline - 1
+}
+
+// String formats the regression as "name: ns/op 1200 -> 1500 (+25.0%)"
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s %.0f -> %.0f (+%.1f%%)", r.Name, r.Metric, r.Baseline, r.Current, r.Change()*100)
+}
+
+// Compare returns the workloads of current whose time per operation exceeds
+// the baseline by more than timeTolerance, or whose allocations per
+// operation exceed it by more than allocTolerance (0.1 allows 10%).
+// Workloads skipped or missing in either report are not compared
+func Compare(current, baseline *Report, timeTolerance, allocTolerance float64) []Regression {
+	previous := make(map[string]Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		if result.Skipped == "" {
+			previous[result.Name] = result
+		}
+	}
+
+	var regressions []Regression
+	for _, result := range current.Results {
+		old, found := previous[result.Name]
+		if !found || result.Skipped != "" {
+			continue
+		}
+		if exceeds(result.NsPerOp, old.NsPerOp, timeTolerance) {
+			regressions = append(regressions, Regression{Name: result.Name, Metric: "ns/op", Baseline: old.NsPerOp, Current: result.NsPerOp})
+		}
+		if exceeds(float64(result.AllocsPerOp), float64(old.AllocsPerOp), allocTolerance) {
+			regressions = append(regressions, Regression{Name: result.Name, Metric: "allocs/op", Baseline: float64(old.AllocsPerOp), Current: float64(result.AllocsPerOp)})
+		}
+	}
+	return regressions
+}
+
+func exceeds(current, baseline, tolerance float64) bool {
+	return baseline > 0 && current > baseline*(1+tolerance)
+}
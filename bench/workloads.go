@@ -0,0 +1,92 @@
+package bench
+
+import (
+	"fmt"
+	"strings"
+
+	"funterm/engine"
+	"go-parser/pkg/parser"
+)
+
+// Workloads are the standard benchmarks run by `funterm bench`
+var Workloads = []Workload{
+	{
+		Name:        "bitstring-match",
+		Description: "build and match 100 packets in a loop",
+		Prepare:     prepareBitstringMatch,
+	},
+	{
+		Name:        "py-call",
+		Description: "call a Python function 5 times in a loop",
+		Prepare:     preparePyCall,
+	},
+	{
+		Name:        "parse-large-script",
+		Description: "parse a generated script of 1000 statements",
+		Prepare:     prepareParseLargeScript,
+	},
+}
+
+const bitstringMatchLoop = `for i = 0,100 {
+    packet = <<i:8, 1500:16/big, "payload"/binary>>
+    match packet {
+        <<kind:8, length:16/big, rest/binary>> -> seen = kind,
+        _ -> seen = 0
+    }
+}`
+
+func prepareBitstringMatch(eng *engine.ExecutionEngine) (func() error, error) {
+	return executeOp(eng, bitstringMatchLoop), nil
+}
+
+const pyCallSetup = `python {
+def bench_add(a, b):
+    return a + b
+}`
+
+const pyCallLoop = `for i = 0,5 {
+    total = py.bench_add(i, 1)
+}`
+
+func preparePyCall(eng *engine.ExecutionEngine) (func() error, error) {
+	if !eng.IsLanguageAvailable("python") {
+		return nil, fmt.Errorf("python runtime is not available")
+	}
+	if _, _, _, err := eng.Execute(pyCallSetup); err != nil {
+		return nil, fmt.Errorf("python runtime is not available: %v", err)
+	}
+	return executeOp(eng, pyCallLoop), nil
+}
+
+func prepareParseLargeScript(eng *engine.ExecutionEngine) (func() error, error) {
+	script := largeScript(200)
+	p := parser.NewUnifiedParser()
+	return func() error {
+		if _, errs := p.Parse(script); len(errs) > 0 {
+			return fmt.Errorf("parse error: %s", errs[0].Message)
+		}
+		return nil
+	}, nil
+}
+
+// largeScript generates blocks of five statements covering assignments,
+// collections, conditionals, bitstrings and match
+func largeScript(blocks int) string {
+	var sb strings.Builder
+	for i := 0; i < blocks; i++ {
+		fmt.Fprintf(&sb, "x_%d = %d\n", i, i)
+		fmt.Fprintf(&sb, "items_%d = [x_%d, 2, 3, \"four\", {\"key\": x_%d}]\n", i, i, i)
+		fmt.Fprintf(&sb, "if (x_%d > 10) { y_%d = x_%d * 2 } else { y_%d = 0 }\n", i, i, i, i)
+		fmt.Fprintf(&sb, "packet_%d = <<x_%d:8, 512:16/big, \"data\"/binary>>\n", i, i)
+		fmt.Fprintf(&sb, "match packet_%d {\n    <<a:8, b:16/big, rest/binary>> -> lua.print(a),\n    _ -> lua.print(\"short\")\n}\n", i)
+	}
+	return sb.String()
+}
+
+// executeOp runs code on eng as one operation
+func executeOp(eng *engine.ExecutionEngine, code string) func() error {
+	return func() error {
+		_, _, _, err := eng.Execute(code)
+		return err
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"funterm/engine"
+	"funterm/runtime"
+)
+
+// bundleMagic ends a binary made by `funterm build`. It follows the length of
+// the bundle, which is appended to a copy of the funterm binary
+const bundleMagic = "\x00funterm-bundle1"
+
+// bundleTrailerSize is the length and the magic after the bundle
+const bundleTrailerSize = 8 + len(bundleMagic)
+
+// bundle is what a built tool carries: the script and, when given at build
+// time, a prelude run before it and a configuration
+type bundle struct {
+	Name       string `json:"name"` // file name of the script, used in errors
+	Script     string `json:"script"`
+	Prelude    string `json:"prelude,omitempty"`
+	Config     string `json:"config,omitempty"`
+	ConfigName string `json:"config_name,omitempty"`
+}
+
+// RunBuild implements `funterm build script.su -o tool`: it checks the script,
+// prelude and configuration and writes a copy of this binary that runs the
+// script when started
+func RunBuild(args []string, output, configPath, preludePath string) (bool, error) {
+	if len(args) != 1 {
+		return false, usageErrorf("build", "expected one script file")
+	}
+	script := args[0]
+	if output == "" {
+		output = strings.TrimSuffix(filepath.Base(script), filepath.Ext(script))
+		if goruntime.GOOS == "windows" {
+			output += ".exe"
+		}
+	}
+
+	b := &bundle{Name: filepath.Base(script)}
+	source, err := os.ReadFile(script)
+	if err != nil {
+		return false, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	b.Script = string(source)
+	if preludePath != "" {
+		prelude, err := os.ReadFile(preludePath)
+		if err != nil {
+			return false, fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+		b.Prelude = string(prelude)
+	}
+
+	// The configuration is checked now, the tool only sees it when it starts
+	cfg, err := LoadConfig("")
+	if configPath != "" {
+		data, readErr := os.ReadFile(expandHome(configPath))
+		if readErr != nil {
+			return false, fmt.Errorf("ошибка загрузки конфигурации: %v", readErr)
+		}
+		b.Config, b.ConfigName = string(data), filepath.Base(configPath)
+		cfg, err = LoadConfig(configPath)
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	if err := checkBundleSyntax(b, cfg, script, preludePath); err != nil {
+		return false, err
+	}
+
+	size, err := writeBundle(output, b)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Built %s from %s (%d bytes)\n", output, script, size)
+	return true, nil
+}
+
+// checkBundleSyntax parses the script and the prelude so that a tool never
+// ships with a syntax error
+func checkBundleSyntax(b *bundle, cfg *Config, script, preludePath string) error {
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+		ParserHandlers: cfg.Parser,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := eng.Parse(b.Script); err != nil {
+		return fmt.Errorf("%s: %v", script, err)
+	}
+	if b.Prelude != "" {
+		if _, err := eng.Parse(b.Prelude); err != nil {
+			return fmt.Errorf("%s: %v", preludePath, err)
+		}
+	}
+	return nil
+}
+
+// writeBundle copies the funterm binary without any bundle it already carries
+// to output and appends b, returning the size of the tool
+func writeBundle(output string, b *bundle) (int64, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("cannot find the funterm binary: %v", err)
+	}
+	if outputInfo, err := os.Stat(output); err == nil {
+		if executableInfo, err := os.Stat(executable); err == nil && os.SameFile(outputInfo, executableInfo) {
+			return 0, fmt.Errorf("%s is the funterm binary itself, choose another output with -o", output)
+		}
+	}
+	source, err := os.Open(executable)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+	_, base, err := findBundle(source)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return 0, err
+	}
+
+	// The tool is written next to the output and renamed, so a failure
+	// leaves no half-written executable behind
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	trailer := make([]byte, bundleTrailerSize)
+	binary.BigEndian.PutUint64(trailer, uint64(len(payload)))
+	copy(trailer[8:], bundleMagic)
+	_, err = io.Copy(tmp, io.NewSectionReader(source, 0, base))
+	if err == nil {
+		_, err = tmp.Write(append(payload, trailer...))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), output)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot write %s: %v", output, err)
+	}
+	return base + int64(len(payload)+len(trailer)), nil
+}
+
+// findBundle returns the bundle at the end of a binary, nil when there is
+// none, and the size of the binary without it
+func findBundle(file *os.File) (*bundle, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if size < int64(bundleTrailerSize) {
+		return nil, size, nil
+	}
+	trailer := make([]byte, bundleTrailerSize)
+	if _, err := file.ReadAt(trailer, size-int64(bundleTrailerSize)); err != nil {
+		return nil, 0, err
+	}
+	if !bytes.Equal(trailer[8:], []byte(bundleMagic)) {
+		return nil, size, nil
+	}
+	length := binary.BigEndian.Uint64(trailer)
+	if length > uint64(size)-uint64(bundleTrailerSize) {
+		return nil, 0, fmt.Errorf("the bundled script is damaged")
+	}
+	base := size - int64(bundleTrailerSize) - int64(length)
+	payload := make([]byte, length)
+	if _, err := file.ReadAt(payload, base); err != nil {
+		return nil, 0, err
+	}
+	b := &bundle{}
+	if err := json.Unmarshal(payload, b); err != nil {
+		return nil, 0, fmt.Errorf("the bundled script is damaged: %v", err)
+	}
+	return b, base, nil
+}
+
+// readBundle returns the bundle of the running binary, nil for funterm itself
+func readBundle() (*bundle, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, nil
+	}
+	file, err := os.Open(executable)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+	b, _, err := findBundle(file)
+	return b, err
+}
+
+// runBundle runs the script of a built tool. The script, prelude and
+// configuration are written to a temporary directory and run like `funterm
+// run`; without a bundled configuration the defaults and FUNTERM_*
+// variables apply, never the configuration files of the machine
+func runBundle(b *bundle, args []string) int {
+	if len(args) > 0 {
+		fmt.Printf("Error: unexpected argument '%s', %s takes no arguments\n", args[0], filepath.Base(os.Args[0]))
+		return 2
+	}
+	dir, err := os.MkdirTemp("", "funterm-tool-")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	// The shutdown manager removes the directory when the script exits
+	// funterm before runBundle returns
+	runtime.TrackTempPath(dir)
+	defer runtime.RemoveTempPath(dir)
+
+	// The script has a directory of its own so that its name can't clash
+	// with the prelude or the configuration
+	scriptPath, configPath, preludePath := filepath.Join(dir, "script", b.Name), "", ""
+	files := map[string]string{scriptPath: b.Script}
+	if b.Config != "" {
+		configPath = filepath.Join(dir, b.ConfigName)
+		files[configPath] = b.Config
+	}
+	if b.Prelude != "" {
+		preludePath = filepath.Join(dir, "prelude.su")
+		files[preludePath] = b.Prelude
+	}
+	if err := os.Mkdir(filepath.Dir(scriptPath), 0700); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if err := runBatch(scriptPath, "", configPath, false, nil, batchOptions{prelude: preludePath}); err != nil {
+		fmt.Printf("Error executing script: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"funterm/bench"
+	"funterm/examples"
+)
+
+// funtermVersion is the version reported by --version and the man page
+const funtermVersion = "0.1.0"
+
+// cliOptions holds the values of the global flags and the flags of the commands
+type cliOptions struct {
+	configPath    string
+	noConfig      bool
+	showVersion   bool
+	showHelp      bool
+	execFile      string
+	language      string
+	packages      string
+	packageTarget string
+	modules       string
+	moduleTarget  string
+	doctor        bool
+	verbose       bool
+	envInfo       bool
+	recordPath    string
+	replayPath    string
+	lint          bool
+	checkPatterns bool
+	noPrelude     bool
+	write         bool   // fmt --write
+	check         bool   // fmt --check, self-update --check
+	timeout       string // test/fuzz-parse --timeout
+	runs          string // test/fuzz-parse --runs
+	seed          string // test/fuzz-parse --seed
+	maxMemory     string // fuzz-parse --max-memory
+	parseOnly     bool   // fuzz-parse --parse-only
+	json          bool   // doctor --json
+	channel       string // self-update --channel
+	list          bool   // tutorial --list
+	reset         bool   // tutorial --reset
+	output        string // build/fuzz-parse --output
+	prelude       string // build --prelude
+	target        string // run/repl --target
+	graph         string // run --graph
+}
+
+// cliFlag describes a flag once for parsing, --help, shell completion and
+// the man page
+type cliFlag struct {
+	Name  string
+	Short string // one-letter alias, e.g. "o" for -o
+	Arg   string // placeholder of the value, empty for switches
+	Usage string
+	Group string // section of --help; empty leaves the flag out of the docs
+	// Complete tells shells how to complete the value: "file" or a list
+	// of words; Choices are completed when it is empty
+	Complete string
+	Choices  []cliChoice // documented values
+	Bool     *bool       // target of a switch
+	String   *string     // target of a flag with a value
+}
+
+// cliChoice is a named entry in a list of the docs: a value, an
+// environment variable, a file or an example
+type cliChoice struct {
+	Name  string
+	Usage string
+}
+
+// cliCommand describes a subcommand. Subcommands of a command only document
+// it; the command's Run receives them as its first argument. The command's
+// flags are parsed before Run unless RawArgs is set, anywhere among the
+// arguments, so `funterm run script.su --verbose` works like a shebang line
+type cliCommand struct {
+	Name     string
+	Args     string // positional arguments as shown in the synopsis
+	Usage    string
+	Group    string
+	Complete string // how shells complete the positional arguments, like cliFlag.Complete
+	Flags    []cliFlag
+	Commands []cliCommand
+	RawArgs  bool // Run parses the flags itself, Flags only document them
+	Run      func(args []string, options *cliOptions) (bool, error)
+}
+
+// cliDefinition is the whole command line of funterm
+type cliDefinition struct {
+	Name        string
+	Summary     string
+	Description string
+	Groups      []string // --help sections in order
+	Flags       []cliFlag
+	Commands    []cliCommand
+	Environment []cliChoice
+	Files       []cliChoice
+	Examples    []cliChoice
+	ExitCodes   []cliChoice
+}
+
+// completionShells are the shells `funterm completion` writes scripts for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// newCLI defines the command line, binding the global flags to options
+func newCLI(options *cliOptions) *cliDefinition {
+	cli := &cliDefinition{
+		Name:        "funterm",
+		Summary:     "Multi-Language REPL",
+		Description: "funterm runs Lua, Python, JavaScript, Go, Perl, Erlang and Elixir side by side in one REPL or script. Without arguments it starts the REPL; a .su file given as the first argument is run as a script, like `funterm run`.",
+		Groups:      []string{"Commands", "Options", "Static Checks", "Record/Replay", "Configuration", "Shell Integration"},
+	}
+
+	// Flags shared by the top level and the commands
+	config := cliFlag{Name: "config", Arg: "path", Usage: "Path to configuration file", Complete: "file", String: &options.configPath}
+	noConfig := cliFlag{Name: "no-config", Usage: "Don't load a configuration file", Bool: &options.noConfig}
+	verbose := cliFlag{Name: "verbose", Usage: "Enable verbose output", Bool: &options.verbose}
+	noPrelude := cliFlag{Name: "no-prelude", Usage: "Don't run ~/.funterm/prelude.su at REPL start", Bool: &options.noPrelude}
+	lang := cliFlag{Name: "lang", Arg: "language", Usage: "Language for file execution (lua, python, go, mixed)", Complete: "lua python go mixed", String: &options.language}
+	record := cliFlag{Name: "record", Arg: "file", Usage: "Record Python/Node interactions to a cassette", Complete: "file", String: &options.recordPath}
+	replay := cliFlag{Name: "replay", Arg: "file", Usage: "Serve Python/Node interactions from a cassette without starting the interpreters", Complete: "file", String: &options.replayPath}
+	target := cliFlag{Name: "target", Arg: "user@host", Usage: "Run Python, Node.js and Perl on this host over SSH", String: &options.target}
+	inGroup := func(group string, f cliFlag) cliFlag {
+		f.Group = group
+		return f
+	}
+
+	cli.Flags = []cliFlag{
+		inGroup("Options", config),
+		inGroup("Options", noConfig),
+		inGroup("Options", verbose),
+		inGroup("Options", noPrelude),
+		inGroup("Options", target),
+		{Name: "version", Usage: "Show version information, details with --verbose", Group: "Options", Bool: &options.showVersion},
+		{Name: "help", Usage: "Show this help message", Group: "Options", Bool: &options.showHelp},
+
+		{Name: "lint", Usage: "Warn about unreachable and non-exhaustive match arms in the scripts given as arguments, without running them", Group: "Static Checks", Bool: &options.lint},
+		{Name: "check-patterns", Usage: "Check the bitstring patterns of the scripts given as arguments for impossible sizes, conflicting specifiers and unreachable rest segments, without running them", Group: "Static Checks", Bool: &options.checkPatterns},
+
+		inGroup("Record/Replay", record),
+		inGroup("Record/Replay", replay),
+
+		// The flags before the commands, still accepted by scripts and
+		// aliases but left out of the docs
+		{Name: "exec", Arg: "file", Usage: "Execute file in batch mode", Complete: "file", String: &options.execFile},
+		lang,
+		{Name: "packages", Arg: "command", Usage: "Python package management", String: &options.packages},
+		{Name: "package-name", Arg: "name", Usage: "Target package for install/check operations", String: &options.packageTarget},
+		{Name: "modules", Arg: "command", Usage: "Lua module management", String: &options.modules},
+		{Name: "module-name", Arg: "name", Usage: "Target module for info/test operations", String: &options.moduleTarget},
+		{Name: "doctor", Usage: "Run system diagnostics", Bool: &options.doctor},
+		{Name: "env-info", Usage: "Show Python environment information", Bool: &options.envInfo},
+	}
+
+	cli.Commands = []cliCommand{
+		{
+			Name:     "run",
+			Args:     "<file>",
+			Usage:    "Run a script",
+			Group:    "Commands",
+			Complete: "file",
+			Flags: []cliFlag{config, noConfig, verbose, lang, target, record, replay,
+				{Name: "graph", Arg: "format", Usage: "Print the stages of the script's pipelines as a graph instead of running it", String: &options.graph, Choices: []cliChoice{
+					{Name: "dot", Usage: "Graphviz DOT"},
+					{Name: "mermaid", Usage: "Mermaid flowchart"},
+				}},
+			},
+			Run: runFile,
+		},
+		{
+			Name:  "repl",
+			Usage: "Start the REPL, the default without arguments",
+			Group: "Commands",
+			Flags: []cliFlag{config, noConfig, verbose, noPrelude, target, record, replay},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) != 0 {
+					return false, usageErrorf("repl", "unexpected argument '%s'", args[0])
+				}
+				cassette, err := openCassette(options.recordPath, options.replayPath)
+				if err != nil {
+					return false, err
+				}
+				defer cassette.Close()
+				return runREPL(options, cassette) == 0, nil
+			},
+		},
+		{
+			Name:  "pkg",
+			Args:  "<command>",
+			Usage: "Manage Python packages",
+			Group: "Commands",
+			Flags: []cliFlag{verbose},
+			Commands: []cliCommand{
+				{Name: "list", Usage: "List installed packages"},
+				{Name: "install", Args: "<name>", Usage: "Install a package"},
+				{Name: "check", Args: "<name>", Usage: "Check if package is installed"},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				command, target, err := commandTarget("pkg", args)
+				if err != nil {
+					return false, err
+				}
+				return true, handlePythonPackages(command, target, options.verbose)
+			},
+		},
+		{
+			Name:  "modules",
+			Args:  "<command>",
+			Usage: "Inspect Lua modules",
+			Group: "Commands",
+			Flags: []cliFlag{verbose},
+			Commands: []cliCommand{
+				{Name: "list", Usage: "List available modules"},
+				{Name: "info", Args: "<name>", Usage: "Show module information"},
+				{Name: "test", Args: "<name>", Usage: "Test module loading"},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				command, target, err := commandTarget("modules", args)
+				if err != nil {
+					return false, err
+				}
+				return true, handleLuaModules(command, target, options.verbose)
+			},
+		},
+		{
+			Name:  "doctor",
+			Usage: "Start every runtime and check it, PATH and the configuration",
+			Group: "Commands",
+			Flags: []cliFlag{
+				config, noConfig, verbose,
+				{Name: "json", Usage: "Print the report as JSON", Bool: &options.json},
+				{Name: "python", Usage: "Show the Python environment instead", Bool: &options.envInfo},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) != 0 {
+					return false, usageErrorf("doctor", "unexpected argument '%s'", args[0])
+				}
+				if options.envInfo {
+					return true, showPythonEnvironmentInfo(options.configPath, options.verbose)
+				}
+				return runDiagnostics(options.configPath, options.noConfig, options.verbose, options.json)
+			},
+		},
+		{
+			Name:     "fmt",
+			Args:     "<file...>",
+			Usage:    "Indent scripts consistently, printing the result",
+			Group:    "Commands",
+			Complete: "file",
+			Flags: []cliFlag{
+				config,
+				{Name: "write", Usage: "Rewrite the files instead of printing them", Bool: &options.write},
+				{Name: "check", Usage: "List the files that are not formatted and fail if there are any", Bool: &options.check},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) == 0 {
+					return false, usageErrorf("fmt", "no files to format")
+				}
+				return FormatFiles(args, options.configPath, options.write, options.check)
+			},
+		},
+		{
+			Name:     "test",
+			Args:     "[path...]",
+			Usage:    "Run the scripts in the paths, tests by default, and report which fail",
+			Group:    "Commands",
+			Complete: "file",
+			Flags: []cliFlag{
+				config, noConfig, verbose,
+				{Name: "timeout", Arg: "duration", Usage: "Time limit of each script, default 1m", String: &options.timeout},
+				{Name: "runs", Arg: "n", Usage: "Runs of forall blocks without runs=, default 100", String: &options.runs},
+				{Name: "seed", Arg: "n", Usage: "Seed of forall blocks, to repeat a failure", String: &options.seed},
+			},
+			Run: RunTests,
+		},
+		{
+			Name:     "fuzz-parse",
+			Args:     "[path...]",
+			Usage:    "Feed mutated scripts to the parser and a sandboxed engine to find panics and hangs",
+			Group:    "Commands",
+			Complete: "file",
+			Flags: []cliFlag{
+				{Name: "runs", Arg: "n", Usage: "Inputs to try, default 10000", String: &options.runs},
+				{Name: "seed", Arg: "n", Usage: "Seed of the mutations, to repeat a run", String: &options.seed},
+				{Name: "timeout", Arg: "duration", Usage: "Time limit of each input, default 5s", String: &options.timeout},
+				{Name: "max-memory", Arg: "mb", Usage: "Heap limit in megabytes, default 512", String: &options.maxMemory},
+				{Name: "parse-only", Usage: "Only parse the inputs, never run them", Bool: &options.parseOnly},
+				{Name: "output", Short: "o", Arg: "dir", Usage: "Directory for failing inputs, default fuzz-failures", Complete: "file", String: &options.output},
+			},
+			Run: RunFuzzParse,
+		},
+		{
+			Name:     "tutorial",
+			Args:     "[lesson]",
+			Usage:    "Learn funterm with checked exercises, continuing where you stopped",
+			Group:    "Commands",
+			Complete: strings.Join(tutorialLessonNames(), " "),
+			Flags: []cliFlag{
+				config, noConfig,
+				{Name: "list", Usage: "List the lessons and your progress", Bool: &options.list},
+				{Name: "reset", Usage: "Forget your progress", Bool: &options.reset},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				return RunTutorial(args, options.configPath, options.noConfig, options.list, options.reset)
+			},
+		},
+		{
+			Name:     "build",
+			Args:     "<file>",
+			Usage:    "Package a script into a standalone executable",
+			Group:    "Commands",
+			Complete: "file",
+			Flags: []cliFlag{
+				{Name: "output", Short: "o", Arg: "file", Usage: "Executable to write, default the script name without .su", Complete: "file", String: &options.output},
+				{Name: "prelude", Arg: "file", Usage: "Script run before the script", Complete: "file", String: &options.prelude},
+				{Name: "config", Arg: "path", Usage: "Configuration embedded in the executable", Complete: "file", String: &options.configPath},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				return RunBuild(args, options.output, options.configPath, options.prelude)
+			},
+		},
+		{
+			Name:  "examples",
+			Args:  "<command>",
+			Usage: "List, show or run the example scripts built into funterm",
+			Group: "Commands",
+			Flags: []cliFlag{config, noConfig, verbose},
+			Commands: []cliCommand{
+				{Name: "list", Usage: "List the examples"},
+				{Name: "show", Args: "<name>", Usage: "Print the source of an example", Complete: strings.Join(exampleNames(), " ")},
+				{Name: "run", Args: "<name>", Usage: "Run an example", Complete: strings.Join(exampleNames(), " ")},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				command, target, err := commandTarget("examples", args)
+				if err != nil {
+					return false, err
+				}
+				return RunExamplesCommand(command, target, options.configPath, options.noConfig, options.verbose)
+			},
+		},
+		{
+			Name:  "self-update",
+			Usage: "Replace funterm with the newest release of the channel",
+			Group: "Commands",
+			Flags: []cliFlag{
+				config,
+				{Name: "channel", Arg: "channel", Usage: "stable or nightly, default update.channel of the configuration", Complete: strings.Join(updateChannels, " "), String: &options.channel},
+				{Name: "check", Usage: "Only report whether there is a newer version, failing if there is", Bool: &options.check},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) != 0 {
+					return false, usageErrorf("self-update", "unexpected argument '%s'", args[0])
+				}
+				return RunSelfUpdate(options.configPath, options.channel, options.check)
+			},
+		},
+		{
+			Name:     "bench",
+			Args:     "[workload...]",
+			Usage:    "Measure the standard workloads",
+			Group:    "Commands",
+			Complete: strings.Join(benchWorkloadNames(), " "),
+			Flags: []cliFlag{
+				{Name: "list", Usage: "List the workloads"},
+				{Name: "json", Usage: "Print results as JSON"},
+				{Name: "save", Arg: "file", Usage: "Save results as a baseline", Complete: "file"},
+				{Name: "baseline", Arg: "file", Usage: "Fail if a workload is slower than the baseline", Complete: "file"},
+				{Name: "tolerance", Arg: "ratio", Usage: "Allowed slowdown and allocation growth, default 0.10 (10%)"},
+				{Name: "time-tolerance", Arg: "ratio", Usage: "Allowed slowdown, default --tolerance"},
+				{Name: "time", Arg: "duration", Usage: "Minimum run time of each workload, default 1s"},
+			},
+			RawArgs: true,
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				return RunBench(args, options.configPath)
+			},
+		},
+		{
+			Name:  "config",
+			Args:  "<command>",
+			Usage: "Check, show or upgrade the configuration",
+			Group: "Configuration",
+			Commands: []cliCommand{
+				{Name: "validate", Args: "[file]", Usage: "Report every problem in the configuration file", Complete: "file"},
+				{Name: "print-effective", Usage: "Print the configuration with defaults applied"},
+				{Name: "upgrade", Args: "[file]", Usage: "Rewrite the configuration file in the current layout", Complete: "file"},
+			},
+			RawArgs: true,
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				return RunConfigCommand(args, options.configPath, options.noConfig)
+			},
+		},
+		{
+			Name:  "telemetry",
+			Args:  "<command>",
+			Usage: "Show, export or delete the opt-in usage statistics",
+			Group: "Configuration",
+			Flags: []cliFlag{config, noConfig},
+			Commands: []cliCommand{
+				{Name: "show", Usage: "Summarize the statistics and whether they are collected"},
+				{Name: "export", Usage: "Print the statistics as JSON for sharing"},
+				{Name: "reset", Usage: "Delete the statistics"},
+			},
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) == 0 {
+					return false, usageErrorf("telemetry", "no command specified")
+				}
+				if len(args) > 1 {
+					return false, usageErrorf("telemetry", "unexpected argument '%s'", args[1])
+				}
+				return RunTelemetryCommand(args[0], options.configPath, options.noConfig)
+			},
+		},
+		{
+			Name:     "completion",
+			Args:     "<shell>",
+			Usage:    "Print completion for " + strings.Join(completionShells[:len(completionShells)-1], ", ") + " or " + completionShells[len(completionShells)-1],
+			Group:    "Shell Integration",
+			Complete: strings.Join(completionShells, " "),
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) != 1 {
+					return false, usageErrorf("completion", "expected one of %s", strings.Join(completionShells, ", "))
+				}
+				if err := writeCompletion(os.Stdout, cli, args[0]); err != nil {
+					return false, usageErrorf("completion", "%v", err)
+				}
+				return true, nil
+			},
+		},
+		{
+			Name:  "man",
+			Usage: "Print the manual page in roff format",
+			Group: "Shell Integration",
+			Run: func(args []string, options *cliOptions) (bool, error) {
+				if len(args) != 0 {
+					return false, usageErrorf("man", "unexpected argument '%s'", args[0])
+				}
+				writeManPage(os.Stdout, cli)
+				return true, nil
+			},
+		},
+	}
+
+	cli.Environment = []cliChoice{
+		{"FUNTERM_<SECTION>_<KEY>", "Override a config key, e.g. FUNTERM_ENGINE_VERBOSE"},
+	}
+	cli.Files = []cliChoice{
+		{"~/.funterm/config.yaml", "Configuration unless --config is given"},
+		{"./config.yaml", "Configuration if the file above is missing"},
+		{"~/.funterm/prelude.su", "Script run at REPL start"},
+		{"~/.funterm/tutorial.json", "Progress of funterm tutorial"},
+		{"~/.funterm/crashes", "Reports of internal errors the REPL recovered from"},
+		{"~/.funterm/telemetry.json", "Usage statistics, only when telemetry is enabled"},
+	}
+	cli.Examples = []cliChoice{
+		{"funterm", "Run REPL with default configuration"},
+		{"funterm script.su", "Run a script file"},
+		{"funterm run --verbose script.su", "Run a script with verbose output"},
+		{"funterm pkg install requests", "Install a Python package"},
+		{"funterm fmt --write *.su", "Format scripts in place"},
+		{"funterm build tool.su -o tool", "Package a script as a standalone executable"},
+		{"funterm test tests", "Run every script in the tests directory"},
+		{"funterm completion bash > /etc/bash_completion.d/funterm", "Install bash completion"},
+		{"funterm man > /usr/local/share/man/man1/funterm.1", "Install the manual page"},
+	}
+	cli.ExitCodes = []cliChoice{
+		{"0", "Success"},
+		{"1", "The command failed: a script error, a failed test or check, an unformatted file"},
+		{"2", "The command line is wrong: an unknown command or flag, a missing argument"},
+	}
+	return cli
+}
+
+// commandTarget splits the arguments of `funterm pkg` and `funterm modules`
+// into the subcommand and its optional name
+func commandTarget(name string, args []string) (command, target string, err error) {
+	switch len(args) {
+	case 0:
+		return "", "", usageErrorf(name, "no command specified")
+	case 1:
+		return args[0], "", nil
+	case 2:
+		return args[0], args[1], nil
+	}
+	return "", "", usageErrorf(name, "unexpected argument '%s'", args[2])
+}
+
+// tutorialLessonNames are the lessons `funterm tutorial` can start with
+func tutorialLessonNames() []string {
+	names := make([]string, len(tutorialLessons))
+	for i, lesson := range tutorialLessons {
+		names[i] = lesson.Name
+	}
+	return names
+}
+
+// exampleNames are the examples `funterm examples` can show and run
+func exampleNames() []string {
+	var names []string
+	for _, example := range examples.All() {
+		names = append(names, example.Name)
+	}
+	return names
+}
+
+// benchWorkloadNames are the workloads `funterm bench` can be limited to
+func benchWorkloadNames() []string {
+	names := make([]string, len(bench.Workloads))
+	for i, workload := range bench.Workloads {
+		names[i] = workload.Name
+	}
+	return names
+}
+
+// command returns the subcommand called name, nil when there is none
+func (cli *cliDefinition) command(name string) *cliCommand {
+	for i := range cli.Commands {
+		if cli.Commands[i].Name == name {
+			return &cli.Commands[i]
+		}
+	}
+	return nil
+}
+
+// flagSet registers the global flags for parsing
+func (cli *cliDefinition) flagSet() *flag.FlagSet {
+	flags := newFlagSet(cli.Name, cli.Flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Run '%s --help' for usage\n", cli.Name)
+	}
+	return flags
+}
+
+// newFlagSet registers flags for parsing, keeping the values they already have
+func newFlagSet(name string, list []cliFlag) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, f := range list {
+		for _, name := range []string{f.Name, f.Short} {
+			if name == "" {
+				continue
+			}
+			if f.Bool != nil {
+				flags.BoolVar(f.Bool, name, *f.Bool, f.Usage)
+			} else if f.String != nil {
+				flags.StringVar(f.String, name, *f.String, f.Usage)
+			}
+		}
+	}
+	return flags
+}
+
+// runCommand parses the flags of a command and runs it with the other arguments
+func (cli *cliDefinition) runCommand(command *cliCommand, args []string, options *cliOptions) (bool, error) {
+	if command.RawArgs {
+		return command.Run(args, options)
+	}
+	flags := newFlagSet(cli.Name+" "+command.Name, command.Flags)
+	flags.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				writeCommandHelp(os.Stdout, cli, command)
+				return true, nil
+			}
+			return false, usageErrorf(command.Name, "%v", err)
+		}
+		// Flags may follow the arguments; "--" ends them
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		if args[0] == "--" {
+			positional = append(positional, args[1:]...)
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if options.noConfig && options.configPath != "" {
+		return false, usageErrorf(command.Name, "--config and --no-config cannot be used together")
+	}
+	return command.Run(positional, options)
+}
+
+// usageError is a mistake in the command line rather than a failure of the
+// command; it exits with status 2
+type usageError struct {
+	command string // the command whose --help explains the usage, empty for funterm itself
+	err     error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+func usageErrorf(command, format string, args ...interface{}) error {
+	return &usageError{command: command, err: fmt.Errorf(format, args...)}
+}
+
+// exitStatus reports the outcome of a command and turns it into the exit status
+func exitStatus(ok bool, err error) int {
+	var usage *usageError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		// The command printed its usage itself
+		return 0
+	case errors.As(err, &usage):
+		fmt.Printf("Error: %v\n", err)
+		fmt.Printf("Run '%s --help' for usage\n", strings.TrimSpace("funterm "+usage.command))
+		return 2
+	case err != nil:
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	case !ok:
+		return 1
+	}
+	return 0
+}
+
+// helpColumn is where usage text starts in --help
+const helpColumn = 28
+
+// writeHelp prints the --help text
+func writeHelp(w io.Writer, cli *cliDefinition) {
+	fmt.Fprintf(w, "%s - %s\n\n", cli.Name, cli.Summary)
+	fmt.Fprintf(w, "Usage: %s [options]\n", cli.Name)
+	fmt.Fprintf(w, "Run script: %s <path-to-file>\n", cli.Name)
+	fmt.Fprintf(w, "Commands: %s <command> [options] [args]\n", cli.Name)
+
+	for _, group := range cli.Groups {
+		fmt.Fprintf(w, "\n%s:\n", group)
+		for _, f := range cli.Flags {
+			if f.Group == group {
+				writeHelpFlag(w, 2, f)
+			}
+		}
+		for _, command := range cli.Commands {
+			if command.Group == group {
+				writeHelpLine(w, 2, strings.TrimSpace(command.Name+" "+command.Args), command.Usage)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> --help' for the options of a command.\n", cli.Name)
+
+	fmt.Fprintln(w, "\nEnvironment Variables:")
+	for _, env := range cli.Environment {
+		writeHelpLine(w, 2, env.Name, env.Usage)
+	}
+	fmt.Fprintln(w, "\nFiles:")
+	for _, file := range cli.Files {
+		writeHelpLine(w, 2, file.Name, file.Usage)
+	}
+	fmt.Fprintln(w, "\nExamples:")
+	for _, example := range cli.Examples {
+		writeHelpLine(w, 2, example.Name, example.Usage)
+	}
+	fmt.Fprintln(w, "\nExit Status:")
+	for _, code := range cli.ExitCodes {
+		writeHelpLine(w, 2, code.Name, code.Usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "For more information, visit: https://github.com/funvibe/funterm")
+}
+
+// writeCommandHelp prints the --help text of a command
+func writeCommandHelp(w io.Writer, cli *cliDefinition, command *cliCommand) {
+	fmt.Fprintf(w, "%s %s - %s\n\n", cli.Name, command.Name, command.Usage)
+	synopsis := cli.Name + " " + command.Name
+	if len(command.Flags) > 0 {
+		synopsis += " [options]"
+	}
+	fmt.Fprintf(w, "Usage: %s\n", strings.TrimSpace(synopsis+" "+command.Args))
+	if len(command.Commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range command.Commands {
+			writeHelpLine(w, 2, strings.TrimSpace(sub.Name+" "+sub.Args), sub.Usage)
+		}
+	}
+	if len(command.Flags) > 0 {
+		fmt.Fprintln(w, "\nOptions:")
+		for _, f := range command.Flags {
+			writeHelpFlag(w, 2, f)
+		}
+	}
+}
+
+// writeHelpFlag prints a flag with the values it documents
+func writeHelpFlag(w io.Writer, indent int, f cliFlag) {
+	writeHelpLine(w, indent, flagSynopsis(f), f.Usage)
+	if len(f.Choices) > 0 {
+		fmt.Fprintf(w, "%sCommands:\n", strings.Repeat(" ", indent+2))
+		for _, choice := range f.Choices {
+			writeHelpLine(w, indent+4, choice.Name, choice.Usage)
+		}
+	}
+}
+
+// writeHelpLine prints an indented name with its usage wrapped at helpColumn
+func writeHelpLine(w io.Writer, indent int, name, usage string) {
+	line := strings.Repeat(" ", indent) + name
+	for _, text := range wrapWords(usage, 80-helpColumn) {
+		if len(line) >= helpColumn {
+			fmt.Fprintln(w, line)
+			line = ""
+		}
+		fmt.Fprintf(w, "%-*s%s\n", helpColumn, line, text)
+		line = ""
+	}
+}
+
+// wrapWords splits text into lines of at most width characters
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
+
+// flagSynopsis is a flag as written on the command line, e.g. --config <path>
+func flagSynopsis(f cliFlag) string {
+	synopsis := "--" + f.Name
+	if f.Short != "" {
+		synopsis = "-" + f.Short + ", " + synopsis
+	}
+	if f.Arg == "" {
+		return synopsis
+	}
+	return fmt.Sprintf("%s <%s>", synopsis, f.Arg)
+}
+
+// completeWords returns the words shells offer for a value completed as
+// complete, or for the choices when complete is empty
+func completeWords(complete string, choices []cliChoice) []string {
+	if complete == "" {
+		var words []string
+		for _, choice := range choices {
+			words = append(words, strings.Fields(choice.Name)[0])
+		}
+		return words
+	}
+	if complete == "file" {
+		return nil
+	}
+	return strings.Fields(complete)
+}
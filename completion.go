@@ -0,0 +1,394 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// completionContext is what shells offer after a subcommand path
+type completionContext struct {
+	path     string // subcommand path, e.g. "config validate"; empty at the top
+	leaf     bool   // arguments after the path stay in this context
+	flags    []cliFlag
+	commands []cliCommand
+	values   []string
+	files    bool
+}
+
+// completionContexts lists the contexts of the command line, subcommands
+// before their commands
+func completionContexts(cli *cliDefinition) []completionContext {
+	var flags []cliFlag
+	for _, f := range cli.Flags {
+		if f.Group != "" {
+			flags = append(flags, f)
+		}
+	}
+	var contexts []completionContext
+	for _, command := range cli.Commands {
+		for _, sub := range command.Commands {
+			contexts = append(contexts, completionContext{
+				path:   command.Name + " " + sub.Name,
+				leaf:   true,
+				flags:  sub.Flags,
+				values: completeWords(sub.Complete, nil),
+				files:  sub.Complete == "file",
+			})
+		}
+		contexts = append(contexts, completionContext{
+			path:     command.Name,
+			leaf:     len(command.Commands) == 0,
+			flags:    command.Flags,
+			commands: command.Commands,
+			values:   completeWords(command.Complete, nil),
+			files:    command.Complete == "file",
+		})
+	}
+	return append(contexts, completionContext{flags: flags, commands: cli.Commands, files: true})
+}
+
+// valueFlags lists every flag that takes a value, so completion can skip the
+// value; a flag shared by several commands is listed once
+func valueFlags(cli *cliDefinition) []cliFlag {
+	var flags []cliFlag
+	seen := make(map[string]bool)
+	add := func(list []cliFlag) {
+		for _, f := range list {
+			if f.Arg != "" && !seen[f.Name] {
+				seen[f.Name] = true
+				flags = append(flags, f)
+			}
+		}
+	}
+	add(cli.Flags)
+	for _, command := range cli.Commands {
+		add(command.Flags)
+		for _, sub := range command.Commands {
+			add(sub.Flags)
+		}
+	}
+	return flags
+}
+
+// words are the flags, subcommands and values offered in the context
+func (c completionContext) words() []string {
+	var words []string
+	for _, f := range c.flags {
+		words = append(words, "--"+f.Name)
+	}
+	for _, command := range c.commands {
+		words = append(words, command.Name)
+	}
+	return append(words, c.values...)
+}
+
+// writeCompletion writes the completion script for shell
+func writeCompletion(w io.Writer, cli *cliDefinition, shell string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, cli)
+	case "zsh":
+		writeZshCompletion(w, cli)
+	case "fish":
+		writeFishCompletion(w, cli)
+	case "powershell":
+		writePowerShellCompletion(w, cli)
+	default:
+		return fmt.Errorf("unknown shell '%s', expected one of %s", shell, strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+func writeBashCompletion(w io.Writer, cli *cliDefinition) {
+	name := cli.Name
+	fmt.Fprintf(w, "# bash completion for %s, generated by `%s completion bash`\n\n", name, name)
+	fmt.Fprintf(w, "_%s() {\n", name)
+	fmt.Fprintln(w, `    local cur prev word path i`)
+	fmt.Fprintln(w, `    cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `    prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w)
+
+	// The value of a flag
+	var all []string
+	fmt.Fprintln(w, `    case "$prev" in`)
+	for _, f := range valueFlags(cli) {
+		all = append(all, "--"+f.Name)
+		fmt.Fprintf(w, "        --%s)\n", f.Name)
+		if words := completeWords(f.Complete, f.Choices); f.Complete == "file" {
+			fmt.Fprintln(w, `            COMPREPLY=($(compgen -f -- "$cur"))`)
+		} else if len(words) > 0 {
+			fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(words, " "))
+		}
+		fmt.Fprintln(w, `            return ;;`)
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w)
+
+	// The subcommand path before the cursor, without flags and their values
+	fmt.Fprintln(w, `    path=""`)
+	fmt.Fprintln(w, `    for ((i = 1; i < COMP_CWORD; i++)); do`)
+	fmt.Fprintln(w, `        word="${COMP_WORDS[i]}"`)
+	fmt.Fprintln(w, `        case "$word" in`)
+	fmt.Fprintf(w, "            %s) ((i++)) ;;\n", strings.Join(all, "|"))
+	fmt.Fprintln(w, `            -*) ;;`)
+	fmt.Fprintln(w, `            *) path="${path:+$path }$word" ;;`)
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, `    case "$path" in`)
+	for _, c := range completionContexts(cli) {
+		pattern := fmt.Sprintf("%q", c.path)
+		if c.leaf {
+			pattern += fmt.Sprintf("|%q*", c.path+" ")
+		}
+		fmt.Fprintf(w, "        %s)\n", pattern)
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(c.words(), " "))
+		if c.files {
+			fmt.Fprintln(w, `            [[ $cur != -* ]] && COMPREPLY+=($(compgen -f -- "$cur"))`)
+		}
+		fmt.Fprintln(w, `            ;;`)
+	}
+	fmt.Fprintln(w, `        *)`)
+	fmt.Fprintln(w, `            COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, `            ;;`)
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "\ncomplete -o filenames -F _%s %s\n", name, name)
+}
+
+func writeZshCompletion(w io.Writer, cli *cliDefinition) {
+	name := cli.Name
+	fmt.Fprintf(w, "#compdef %s\n# zsh completion for %s, generated by `%s completion zsh`\n\n", name, name, name)
+	fmt.Fprintf(w, "_%s() {\n", name)
+	fmt.Fprintln(w, `  local curcontext="$curcontext" state line`)
+	fmt.Fprintln(w, `  typeset -A opt_args`)
+	fmt.Fprintln(w)
+
+	contexts := completionContexts(cli)
+	top := contexts[len(contexts)-1]
+	fmt.Fprintln(w, `  _arguments -C \`)
+	for _, f := range top.flags {
+		fmt.Fprintf(w, "    %s \\\n", zshFlagSpec(f))
+	}
+	fmt.Fprintln(w, `    '1: :->command' \`)
+	fmt.Fprintln(w, `    '*:: :->args'`)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, `  case $state in`)
+	fmt.Fprintln(w, `    command)`)
+	writeZshCommands(w, "      ", "command", top.commands)
+	fmt.Fprintln(w, `      _files`)
+	fmt.Fprintln(w, `      ;;`)
+	fmt.Fprintln(w, `    args)`)
+	fmt.Fprintln(w, `      case $line[1] in`)
+	for _, command := range cli.Commands {
+		fmt.Fprintf(w, "        %s)\n", command.Name)
+		if len(command.Commands) > 0 {
+			fmt.Fprintln(w, `          if (( CURRENT == 2 )); then`)
+			writeZshCommands(w, "            ", command.Name+" command", command.Commands)
+			fmt.Fprintln(w, `          else`)
+			fmt.Fprintln(w, `            case $words[2] in`)
+			for _, sub := range command.Commands {
+				fmt.Fprintf(w, "              %s) %s ;;\n", sub.Name, zshArguments(sub))
+			}
+			fmt.Fprintln(w, `            esac`)
+			fmt.Fprintln(w, `          fi`)
+		} else {
+			fmt.Fprintf(w, "          %s\n", zshArguments(command))
+		}
+		fmt.Fprintln(w, `          ;;`)
+	}
+	fmt.Fprintln(w, `        *)`)
+	fmt.Fprintln(w, `          _files`)
+	fmt.Fprintln(w, `          ;;`)
+	fmt.Fprintln(w, `      esac`)
+	fmt.Fprintln(w, `      ;;`)
+	fmt.Fprintln(w, `  esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintf(w, "\n_%s \"$@\"\n", name)
+}
+
+// writeZshCommands offers commands with their descriptions
+func writeZshCommands(w io.Writer, indent, tag string, commands []cliCommand) {
+	fmt.Fprintf(w, "%slocal -a commands\n%scommands=(\n", indent, indent)
+	for _, command := range commands {
+		fmt.Fprintf(w, "%s  %s\n", indent, zshQuote(strings.ReplaceAll(command.Name, ":", `\:`)+":"+command.Usage))
+	}
+	fmt.Fprintf(w, "%s)\n%s_describe -t commands %s commands\n", indent, indent, zshQuote(tag))
+}
+
+// zshArguments completes the flags and arguments of a command
+func zshArguments(command cliCommand) string {
+	specs := []string{"_arguments"}
+	for _, f := range command.Flags {
+		specs = append(specs, zshFlagSpec(f))
+	}
+	switch words := completeWords(command.Complete, nil); {
+	case command.Complete == "file":
+		specs = append(specs, zshQuote("*:file:_files"))
+	case len(words) > 0:
+		specs = append(specs, zshQuote("*:argument:("+strings.Join(words, " ")+")"))
+	}
+	if len(specs) == 1 {
+		return "_message 'no arguments'"
+	}
+	return strings.Join(specs, " ")
+}
+
+// zshFlagSpec is the _arguments spec of a flag
+func zshFlagSpec(f cliFlag) string {
+	description := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(f.Usage)
+	spec := "--" + f.Name + "[" + description + "]"
+	if f.Arg != "" {
+		action := ""
+		if words := completeWords(f.Complete, f.Choices); f.Complete == "file" {
+			action = "_files"
+		} else if len(words) > 0 {
+			action = "(" + strings.Join(words, " ") + ")"
+		}
+		spec += ":" + f.Arg + ":" + action
+	}
+	return zshQuote(spec)
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeFishCompletion(w io.Writer, cli *cliDefinition) {
+	name := cli.Name
+	fmt.Fprintf(w, "# fish completion for %s, generated by `%s completion fish`\n\n", name, name)
+	for _, c := range completionContexts(cli) {
+		condition := "__fish_use_subcommand"
+		if c.path != "" {
+			parts := strings.Fields(c.path)
+			condition = "__fish_seen_subcommand_from " + parts[0]
+			if len(parts) > 1 {
+				condition += "; and __fish_seen_subcommand_from " + parts[1]
+			}
+			if len(c.commands) > 0 {
+				var subs []string
+				for _, sub := range c.commands {
+					subs = append(subs, sub.Name)
+				}
+				condition += "; and not __fish_seen_subcommand_from " + strings.Join(subs, " ")
+			}
+		}
+		prefix := fmt.Sprintf("complete -c %s -n %s", name, fishQuote(condition))
+		var lines []string
+
+		for _, f := range c.flags {
+			line := prefix + " -l " + f.Name
+			if f.Arg != "" {
+				if words := completeWords(f.Complete, f.Choices); f.Complete == "file" {
+					line += " -r -F"
+				} else {
+					line += " -x"
+					if len(words) > 0 {
+						line += " -a " + fishQuote(strings.Join(words, " "))
+					}
+				}
+			}
+			lines = append(lines, line+" -d "+fishQuote(f.Usage))
+		}
+		for _, command := range c.commands {
+			line := prefix
+			if !c.files {
+				line += " -f"
+			}
+			lines = append(lines, line+" -a "+command.Name+" -d "+fishQuote(command.Usage))
+		}
+		if len(c.values) > 0 {
+			lines = append(lines, prefix+" -f -a "+fishQuote(strings.Join(c.values, " ")))
+		} else if !c.files && len(c.commands) == 0 {
+			lines = append(lines, prefix+" -f")
+		}
+		if len(lines) > 0 {
+			fmt.Fprintln(w, strings.Join(lines, "\n")+"\n")
+		}
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func writePowerShellCompletion(w io.Writer, cli *cliDefinition) {
+	name := cli.Name
+	fmt.Fprintf(w, "# powershell completion for %s, generated by `%s completion powershell`\n\n", name, name)
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", name)
+	fmt.Fprintln(w, `    param($wordToComplete, $commandAst, $cursorPosition)`)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, `    $flagValues = @{`)
+	for _, f := range valueFlags(cli) {
+		words := completeWords(f.Complete, f.Choices)
+		if f.Complete == "file" {
+			words = []string{"<file>"}
+		}
+		fmt.Fprintf(w, "        '--%s' = %s\n", f.Name, powerShellArray(words))
+	}
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintln(w, `    $contexts = @{`)
+	var fileContexts, leafContexts []string
+	for _, c := range completionContexts(cli) {
+		fmt.Fprintf(w, "        '%s' = %s\n", c.path, powerShellArray(c.words()))
+		if c.files {
+			fileContexts = append(fileContexts, c.path)
+		}
+		if c.leaf {
+			leafContexts = append(leafContexts, c.path)
+		}
+	}
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintf(w, "    $fileContexts = %s\n", powerShellArray(fileContexts))
+	fmt.Fprintf(w, "    $leafContexts = %s\n", powerShellArray(leafContexts))
+	fmt.Fprintln(w)
+
+	// The subcommand path before the word being completed
+	fmt.Fprintln(w, `    $path = ''`)
+	fmt.Fprintln(w, `    $flag = $null`)
+	fmt.Fprintln(w, `    foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {`)
+	fmt.Fprintln(w, `        if ($element.Extent.StartOffset -ge $cursorPosition - $wordToComplete.Length) { break }`)
+	fmt.Fprintln(w, `        $text = $element.ToString()`)
+	fmt.Fprintln(w, `        if ($flag) { $flag = $null; continue }`)
+	fmt.Fprintln(w, `        if ($flagValues.ContainsKey($text)) { $flag = $text; continue }`)
+	fmt.Fprintln(w, `        if ($text.StartsWith('-')) { continue }`)
+	fmt.Fprintln(w, `        if ($leafContexts -contains $path) { continue }`)
+	fmt.Fprintln(w, `        $next = ($path + ' ' + $text).Trim()`)
+	fmt.Fprintln(w, `        if (-not $contexts.ContainsKey($next)) { $path = $null; break }`)
+	fmt.Fprintln(w, `        $path = $next`)
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, `    $words = @()`)
+	fmt.Fprintln(w, `    $files = $true`)
+	fmt.Fprintln(w, `    if ($flag) {`)
+	fmt.Fprintln(w, `        $words = $flagValues[$flag]`)
+	fmt.Fprintln(w, `        $files = $words -contains '<file>'`)
+	fmt.Fprintln(w, `        $words = $words | Where-Object { $_ -ne '<file>' }`)
+	fmt.Fprintln(w, `    } elseif ($null -ne $path) {`)
+	fmt.Fprintln(w, `        $words = $contexts[$path]`)
+	fmt.Fprintln(w, `        $files = $fileContexts -contains $path`)
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintln(w, `    $words | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {`)
+	fmt.Fprintln(w, `        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)`)
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintln(w, `    if ($files -and -not $wordToComplete.StartsWith('-')) {`)
+	fmt.Fprintln(w, `        Get-ChildItem -Path "$wordToComplete*" -ErrorAction SilentlyContinue | ForEach-Object {`)
+	fmt.Fprintln(w, `            $file = Resolve-Path -Relative -LiteralPath $_.FullName`)
+	fmt.Fprintln(w, `            [System.Management.Automation.CompletionResult]::new($file, $_.Name, 'ProviderItem', $_.FullName)`)
+	fmt.Fprintln(w, `        }`)
+	fmt.Fprintln(w, `    }`)
+	fmt.Fprintln(w, `}`)
+}
+
+func powerShellArray(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = "'" + strings.ReplaceAll(word, "'", "''") + "'"
+	}
+	return "@(" + strings.Join(quoted, ", ") + ")"
+}
@@ -5,31 +5,111 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"funterm/engine"
+	"funterm/runtime"
+	"go-parser/pkg/parser"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the simple application configuration
 type Config struct {
+	// Version is the layout of the file, older layouts are migrated when loaded
+	Version   int             `json:"version" yaml:"version"`
 	REPL      REPLConfig      `json:"repl" yaml:"repl"`
 	Engine    EngineConfig    `json:"engine" yaml:"engine"`
 	Logging   LoggingConfig   `json:"logging" yaml:"logging"`
 	Languages LanguagesConfig `json:"languages" yaml:"languages"`
+	Update    UpdateConfig    `json:"update" yaml:"update"`
+	Telemetry TelemetryConfig `json:"telemetry" yaml:"telemetry"`
+	// Parser отключает конструкции языка и меняет приоритеты обработчиков парсера
+	Parser parser.HandlerOptions `json:"parser" yaml:"parser"`
 }
 
 // REPLConfig contains REPL configuration
 type REPLConfig struct {
-	Prompt      string `json:"prompt" yaml:"prompt"`
+	// Prompt may use {cwd}, {active_runtimes}, {last_duration} and {error_state}
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// RightPrompt is shown at the right edge of the input line
+	RightPrompt string `json:"right_prompt" yaml:"right_prompt"`
 	HistorySize int    `json:"history_size" yaml:"history_size"`
 	HistoryFile string `json:"history_file" yaml:"history_file"`
 	ShowWelcome bool   `json:"show_welcome" yaml:"show_welcome"`
+	// Prelude is a script executed when the REPL starts (empty disables)
+	Prelude string `json:"prelude" yaml:"prelude"`
+	// PreludeCache is where the parsed prelude is cached (empty disables caching)
+	PreludeCache string `json:"prelude_cache" yaml:"prelude_cache"`
+	// CrashDir is where a report is saved when the REPL recovers from an
+	// internal error (empty saves none)
+	CrashDir string `json:"crash_dir" yaml:"crash_dir"`
+	// Snippets are lines of code offered by the Ctrl+P palette, by name
+	Snippets map[string]string `json:"snippets,omitempty" yaml:"snippets,omitempty"`
 }
 
 // EngineConfig contains execution engine configuration
 type EngineConfig struct {
 	MaxExecutionTime int  `json:"max_execution_time_seconds" yaml:"max_execution_time_seconds"`
 	Verbose          bool `json:"verbose" yaml:"verbose"`
+	// SpinnerThresholdMs is the delay before a spinner is shown for slow runtime calls (negative disables)
+	SpinnerThresholdMs int `json:"spinner_threshold_ms" yaml:"spinner_threshold_ms"`
+	// MemoryBudgetMB is the size of session variables above which a warning is shown (0 disables)
+	MemoryBudgetMB int `json:"memory_budget_mb" yaml:"memory_budget_mb"`
+	// Numbers controls how numbers coming from runtimes are represented
+	Numbers runtime.NumberPolicy `json:"numbers" yaml:"numbers"`
+	// MatchWarnings reports unreachable and non-exhaustive match arms when a match first runs
+	MatchWarnings bool `json:"match_warnings" yaml:"match_warnings"`
+	// GuardedCalls are calls that run only after interactive confirmation and are
+	// denied in batch mode, e.g. "sh.*" or `sql.execute("DROP%")`
+	GuardedCalls []string `json:"guarded_calls" yaml:"guarded_calls"`
+	// ResultCache is where cache_result() keeps results between runs (empty
+	// evaluates every expression)
+	ResultCache string `json:"result_cache" yaml:"result_cache"`
+	// Store is the database of store.put() and store.get(), kept between
+	// sessions (empty turns the store off)
+	Store string `json:"store" yaml:"store"`
+	// Strict turns silent coercions, such as reading an undefined variable as
+	// nil, into errors
+	Strict bool `json:"strict" yaml:"strict"`
+	// StrictRules sets single rules of strict mode to error, warn or allow
+	StrictRules map[string]string `json:"strict_rules" yaml:"strict_rules"`
+	// SegmentOverflow is what happens to an integer too large for its
+	// bitstring segment: truncate (default), saturate or error
+	SegmentOverflow string `json:"segment_overflow" yaml:"segment_overflow"`
+	// StopGraceMs is how long a stopped interpreter or daemon may take to
+	// exit after SIGTERM before it is killed (0 kills it right away)
+	StopGraceMs int `json:"stop_grace_ms" yaml:"stop_grace_ms"`
+	// Policy limits what scripts of the session may run
+	Policy PolicyConfig `json:"policy" yaml:"policy"`
+}
+
+// PolicyConfig limits the languages and builtins a session may use and how
+// long a single command may run, see engine.SessionPolicy
+type PolicyConfig struct {
+	// Languages are the runtimes scripts may use (empty allows every runtime)
+	Languages []string `json:"languages" yaml:"languages"`
+	// Builtins are the builtin functions and modules scripts may call (empty
+	// allows every builtin)
+	Builtins []string `json:"builtins" yaml:"builtins"`
+	// MaxExecutionMs limits a single command (0 disables)
+	MaxExecutionMs int `json:"max_execution_ms" yaml:"max_execution_ms"`
+}
+
+// sessionPolicy is the engine policy of the config, nil when it limits nothing
+func (p PolicyConfig) sessionPolicy() *engine.SessionPolicy {
+	if len(p.Languages) == 0 && len(p.Builtins) == 0 && p.MaxExecutionMs == 0 {
+		return nil
+	}
+	policy := &engine.SessionPolicy{MaxExecutionTime: time.Duration(p.MaxExecutionMs) * time.Millisecond}
+	if len(p.Languages) > 0 {
+		policy.Languages = p.Languages
+	}
+	if len(p.Builtins) > 0 {
+		policy.Builtins = p.Builtins
+	}
+	return policy
 }
 
 // LoggingConfig contains logging configuration
@@ -43,23 +123,62 @@ type LanguagesConfig struct {
 	Runtimes map[string]RuntimeConfig `json:"runtimes" yaml:"runtimes"`
 }
 
+// UpdateConfig controls `funterm self-update` and the update notice
+type UpdateConfig struct {
+	// Channel is stable (releases) or nightly (pre-releases too)
+	Channel string `json:"channel" yaml:"channel"`
+	// Notify shows a notice in the REPL banner when a newer version is out;
+	// the check runs in the background at most once a day. Off by default,
+	// because it contacts the releases URL
+	Notify bool `json:"notify" yaml:"notify"`
+	// URL is the GitHub releases API of funterm or a mirror of it
+	URL string `json:"url" yaml:"url"`
+}
+
+// TelemetryConfig controls the opt-in usage statistics. They are only counted
+// into a local file and never sent anywhere; `funterm telemetry export` prints
+// them for sharing
+type TelemetryConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	File    string `json:"file" yaml:"file"`
+}
+
 // RuntimeConfig contains runtime-specific configuration
 type RuntimeConfig struct {
 	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Mode: embedded (движок внутри funterm) или external (отдельный процесс)
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Encoding, locale и env интерпретатора
+	runtime.ProcessOptions `json:",inline" yaml:",inline"`
+	// Preload are the Python modules imported when the interpreter starts,
+	// e.g. numpy or "pandas as pd"
+	Preload []string `json:"preload,omitempty" yaml:"preload,omitempty"`
+	// Snapshot forks Python interpreters from a process that has already
+	// imported Preload, on Linux
+	Snapshot bool `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
+		Version: currentConfigVersion,
 		REPL: REPLConfig{
-			Prompt:      "> ",
-			HistorySize: 1000,
-			HistoryFile: "/tmp/funterm_history",
-			ShowWelcome: true,
+			Prompt:       "> ",
+			HistorySize:  1000,
+			HistoryFile:  "/tmp/funterm_history",
+			ShowWelcome:  true,
+			Prelude:      "~/.funterm/prelude.su",
+			PreludeCache: "~/.funterm/cache",
+			CrashDir:     "~/.funterm/crashes",
 		},
 		Engine: EngineConfig{
-			MaxExecutionTime: 30,
-			Verbose:          false,
+			MaxExecutionTime:   30,
+			Verbose:            false,
+			SpinnerThresholdMs: 1000,
+			MemoryBudgetMB:     256,
+			ResultCache:        "~/.funterm/cache/results",
+			Store:              "~/.funterm/store.db",
+			StopGraceMs:        2000,
 		},
 		Logging: LoggingConfig{
 			Level: "info",
@@ -71,51 +190,106 @@ func DefaultConfig() *Config {
 				// По умолчанию используются: python3, lua, node, go
 			},
 		},
+		Update: UpdateConfig{
+			Channel: "stable",
+			Notify:  false,
+			URL:     "https://api.github.com/repos/funvibe/funterm/releases",
+		},
+		Telemetry: TelemetryConfig{
+			Enabled: false,
+			File:    "~/.funterm/telemetry.json",
+		},
 	}
 }
 
+// findConfigFile returns path, or the first existing default location when
+// path is empty. Empty means no configuration file
+func findConfigFile(path string) string {
+	if path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	defaultPaths := []string{
+		filepath.Join(home, ".funterm", "config.yaml"),
+		"./config.yaml",
+	}
+	for _, defaultPath := range defaultPaths {
+		if _, err := os.Stat(defaultPath); err == nil {
+			return defaultPath
+		}
+	}
+	return ""
+}
+
 // LoadConfig loads configuration from a file
 func LoadConfig(path string) (*Config, error) {
 	// Start with default config
 	config := DefaultConfig()
 
-	// If no path provided, return default config
-	if path == "" {
-		return config, nil
+	// Without a file, or when it doesn't exist, the defaults are used
+	var root *yaml.Node
+	var problems []ConfigProblem
+	if path != "" {
+		path = expandHome(path)
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read config file: %v", err)
+		}
+		if err == nil {
+			// The document is checked as a tree, where lines are known, so that
+			// every problem is reported at once. YAML parsing covers JSON files too
+			root = &yaml.Node{}
+			if err := yaml.Unmarshal(data, root); err != nil {
+				return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+			}
+			if _, err := migrateConfigDocument(root); err != nil {
+				return nil, fmt.Errorf("invalid configuration %s: %v", path, err)
+			}
+			problems = checkConfigSchema(root)
+			// Values of the wrong type are already reported, the rest is still
+			// decoded and checked
+			if err := root.Decode(config); err != nil && len(problems) == 0 {
+				return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+			}
+		} else {
+			path = ""
+		}
 	}
 
-	// Expand ~ in path
-	path = expandHome(path)
-
-	// Read the config file
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, return default config
-			return config, nil
-		}
-		return nil, fmt.Errorf("failed to read config file: %v", err)
+	// FUNTERM_* variables override the file
+	overrides, envProblems := applyEnvOverrides(config, os.Environ())
+	problems = append(problems, envProblems...)
+	problems = append(problems, checkConfigValues(config, root, overrides)...)
+	if len(problems) > 0 {
+		sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+		return nil, &ConfigError{Path: path, Problems: problems}
 	}
 
-	// Determine file format by extension
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".json":
-		if err := json.Unmarshal(data, config); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON config: %v", err)
-		}
-	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, config); err != nil {
-			return nil, fmt.Errorf("failed to parse YAML config: %v", err)
+	return config, nil
+}
+
+// validateRuntimeMode checks the mode against the engines this build has:
+// Perl, Erlang and Elixir run as external processes; Lua, Python and
+// JavaScript either way (embedded means gopher-lua, the Starlark subset of
+// Python and goja)
+func validateRuntimeMode(language, mode string) error {
+	if mode == "" {
+		return nil
+	}
+	if mode != "embedded" && mode != "external" {
+		return fmt.Errorf("invalid mode '%s', expected embedded or external", mode)
+	}
+	switch language {
+	case "perl", "pl":
+		if mode == "embedded" {
+			return fmt.Errorf("no embedded Perl is available, perl runs as an external process")
 		}
-	default:
-		// Try YAML as default
-		if err := yaml.Unmarshal(data, config); err != nil {
-			return nil, fmt.Errorf("failed to parse config file: %v", err)
+	case "erlang", "erl", "elixir", "ex":
+		if mode == "embedded" {
+			return fmt.Errorf("no embedded BEAM is available, %s runs as an external process", language)
 		}
 	}
-
-	return config, nil
+	return nil
 }
 
 // SaveConfig saves configuration to a file
@@ -198,7 +372,48 @@ func (c *Config) GetRuntimePath(language string) string {
 		return "node"
 	case "go":
 		return "go"
+	case "perl", "pl":
+		return "perl"
+	case "erlang", "erl":
+		return "erl"
+	case "elixir", "ex":
+		return "elixir"
 	default:
 		return language
 	}
 }
+
+// GetRuntimeMode returns the configured mode of a runtime, empty when unset
+func (c *Config) GetRuntimeMode(language string) string {
+	return c.Languages.Runtimes[language].Mode
+}
+
+// remoteLanguages are the runtimes --target runs on a remote host
+var remoteLanguages = []string{"python", "node", "perl"}
+
+// SetRemote runs the process runtimes on an SSH destination, in place of a
+// container they are configured with; embedded runtimes stay in funterm.
+// It fails when target is not a valid destination
+func (c *Config) SetRemote(target string) error {
+	if c.Languages.Runtimes == nil {
+		c.Languages.Runtimes = make(map[string]RuntimeConfig)
+	}
+	for _, language := range remoteLanguages {
+		runtimeConfig := c.Languages.Runtimes[language]
+		if runtimeConfig.Mode == "embedded" {
+			continue
+		}
+		runtimeConfig.Remote = target
+		runtimeConfig.Container = ""
+		if err := runtimeConfig.ProcessOptions.Validate(language); err != nil {
+			return err
+		}
+		c.Languages.Runtimes[language] = runtimeConfig
+	}
+	return nil
+}
+
+// GetProcessOptions returns the environment, locale and encoding configured for a runtime
+func (c *Config) GetProcessOptions(language string) runtime.ProcessOptions {
+	return c.Languages.Runtimes[language].ProcessOptions
+}
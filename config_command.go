@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RunConfigCommand implements `funterm config validate [file]`,
+// `funterm config print-effective` and `funterm config upgrade [file]`. It returns false if the configuration
+// has problems. With noConfig only the FUNTERM_* variables are read
+func RunConfigCommand(args []string, configPath string, noConfig bool) (bool, error) {
+	if len(args) == 0 {
+		return false, fmt.Errorf("usage: funterm config validate [file] | print-effective | upgrade [file]")
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	flags.StringVar(&configPath, "config", configPath, "Path to configuration file")
+	flags.BoolVar(&noConfig, "no-config", noConfig, "Don't load a configuration file, only FUNTERM_* variables")
+	if err := flags.Parse(args[1:]); err != nil {
+		return false, err
+	}
+	if flags.NArg() > 0 {
+		if flags.NArg() > 1 || args[0] == "print-effective" {
+			return false, fmt.Errorf("usage: funterm config validate [file] | print-effective | upgrade [file]")
+		}
+		configPath = flags.Arg(0)
+	}
+
+	path := ""
+	if !noConfig {
+		path = findConfigFile(configPath)
+	}
+	switch args[0] {
+	case "validate":
+		if path != "" {
+			if _, err := os.Stat(expandHome(path)); err != nil {
+				return false, fmt.Errorf("cannot read %s: %v", path, err)
+			}
+		}
+		if _, err := LoadConfig(path); err != nil {
+			if _, ok := err.(*ConfigError); ok {
+				fmt.Println(err)
+				return false, nil
+			}
+			return false, err
+		}
+		if path == "" {
+			fmt.Println("No configuration file, the defaults and FUNTERM_* variables are valid")
+		} else {
+			fmt.Printf("%s is valid\n", path)
+		}
+		return true, nil
+	case "print-effective":
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			return false, err
+		}
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal config: %v", err)
+		}
+		if path == "" {
+			fmt.Println("# No configuration file, defaults and FUNTERM_* variables")
+		} else {
+			fmt.Printf("# %s with defaults and FUNTERM_* variables applied\n", path)
+		}
+		fmt.Print(string(data))
+		return true, nil
+	case "upgrade":
+		if path == "" {
+			return false, fmt.Errorf("no configuration file to upgrade")
+		}
+		changed, err := upgradeConfigFile(path)
+		if err != nil {
+			return false, err
+		}
+		if !changed {
+			fmt.Printf("%s already has layout version %d\n", path, currentConfigVersion)
+			return true, nil
+		}
+		fmt.Printf("%s upgraded to layout version %d, the original is saved as %s.bak\n", path, currentConfigVersion, path)
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown config command '%s', expected validate, print-effective or upgrade", args[0])
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPrefix starts the environment variables overriding config keys
+const envPrefix = "FUNTERM"
+
+// applyEnvOverrides sets config keys from FUNTERM_* variables in environ, so a
+// container can be configured without a file. The name is the key path in
+// upper case joined by underscores:
+//
+//	FUNTERM_ENGINE_SPINNER_THRESHOLD_MS=500
+//	FUNTERM_ENGINE_NUMBERS_LUA_INTEGERS=float
+//	FUNTERM_LANGUAGES_DISABLED=perl,erlang          (lists are comma-separated)
+//	FUNTERM_LANGUAGES_RUNTIMES_PYTHON_PATH=/usr/bin/python3.12
+//	FUNTERM_LANGUAGES_RUNTIMES_PYTHON_ENV_PYTHONPATH=/app/lib
+//	FUNTERM_PARSER_PRIORITIES=code-block-statement=120
+//
+// It returns the variable used for each key it set and the values that
+// couldn't be converted
+func applyEnvOverrides(config *Config, environ []string) (map[string]string, []ConfigProblem) {
+	vars := make(map[string]string)
+	for _, entry := range environ {
+		name, value, found := strings.Cut(entry, "=")
+		if found && strings.HasPrefix(name, envPrefix+"_") {
+			vars[name] = value
+		}
+	}
+	overrides := make(map[string]string)
+	var problems []ConfigProblem
+	if len(vars) > 0 {
+		setFromEnv(reflect.ValueOf(config).Elem(), envPrefix, "", vars, overrides, &problems)
+	}
+	return overrides, problems
+}
+
+// setFromEnv sets value and the fields, items or entries inside it from the
+// variables named after prefix; key is its config key path
+func setFromEnv(value reflect.Value, prefix, key string, vars map[string]string, overrides map[string]string, problems *[]ConfigProblem) {
+	switch value.Kind() {
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" {
+				continue
+			}
+			if strings.Contains(options, "inline") {
+				setFromEnv(value.Field(i), prefix, key, vars, overrides, problems)
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			setFromEnv(value.Field(i), prefix+"_"+strings.ToUpper(name), joinKey(key, name), vars, overrides, problems)
+		}
+	case reflect.Map:
+		setMapFromEnv(value, prefix, key, vars, overrides, problems)
+	default:
+		text, found := vars[prefix]
+		if !found {
+			return
+		}
+		if err := setScalar(value, text); err != nil {
+			*problems = append(*problems, ConfigProblem{Key: key, Message: fmt.Sprintf("%s: %v", prefix, err)})
+			return
+		}
+		overrides[key] = prefix
+	}
+}
+
+// setMapFromEnv adds or changes map entries. Entries that are sections of
+// their own (runtimes) are named in lower case by the part of the variable name
+// after prefix up to the next underscore. Other entries (env) are named by all
+// of the rest as it is, or listed in one variable as name=value,name=value
+// for names that can't be part of a variable name (parser priorities)
+func setMapFromEnv(value reflect.Value, prefix, key string, vars map[string]string, overrides map[string]string, problems *[]ConfigProblem) {
+	elemType := value.Type().Elem()
+	if text, found := vars[prefix]; found && elemType.Kind() != reflect.Struct {
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			name, itemValue, found := strings.Cut(item, "=")
+			entry := reflect.New(elemType).Elem()
+			if !found {
+				*problems = append(*problems, ConfigProblem{Key: key, Message: fmt.Sprintf("%s: expected name=value, got %q", prefix, item)})
+				continue
+			}
+			if err := setScalar(entry, strings.TrimSpace(itemValue)); err != nil {
+				*problems = append(*problems, ConfigProblem{Key: key, Message: fmt.Sprintf("%s: %v", prefix, err)})
+				continue
+			}
+			if value.IsNil() {
+				value.Set(reflect.MakeMap(value.Type()))
+			}
+			value.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)), entry)
+		}
+		overrides[key] = prefix
+	}
+
+	entries := make(map[string]bool)
+	for name := range vars {
+		rest, found := strings.CutPrefix(name, prefix+"_")
+		if !found || rest == "" {
+			continue
+		}
+		if elemType.Kind() == reflect.Struct {
+			rest, _, _ = strings.Cut(rest, "_")
+		}
+		entries[rest] = true
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entryKey := name
+		if elemType.Kind() == reflect.Struct {
+			entryKey = strings.ToLower(name)
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
+		}
+		// Map entries aren't addressable, so the entry is changed on a copy
+		entry := reflect.New(elemType).Elem()
+		if existing := value.MapIndex(reflect.ValueOf(entryKey)); existing.IsValid() {
+			entry.Set(existing)
+		}
+		before := len(overrides)
+		setFromEnv(entry, prefix+"_"+name, joinKey(key, entryKey), vars, overrides, problems)
+		if len(overrides) > before {
+			value.SetMapIndex(reflect.ValueOf(entryKey), entry)
+		}
+	}
+}
+
+// setScalar converts the text of a variable to the type of value
+func setScalar(value reflect.Value, text string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", text)
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", text)
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", text)
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(value.Type(), 0, 0)
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			element := reflect.New(value.Type().Elem()).Elem()
+			if err := setScalar(element, item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, element)
+		}
+		value.Set(items)
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// currentConfigVersion is the layout of the config file this build writes.
+// Files without a version key predate versioning and have layout 1
+const currentConfigVersion = 1
+
+// configMigrations[i] rewrites a document of layout i+1 into layout i+2.
+// When the layout changes, bump currentConfigVersion and append the step
+// here, so older files keep loading and `funterm config upgrade` can rewrite
+// them
+var configMigrations = []func(root *yaml.Node) error{}
+
+// migrateConfigDocument brings the document up to currentConfigVersion in
+// place and returns the layout it had
+func migrateConfigDocument(doc *yaml.Node) (int, error) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return currentConfigVersion, nil
+	}
+	version := 1
+	versionNode := mappingValue(root, "version")
+	if versionNode != nil {
+		v, err := strconv.Atoi(versionNode.Value)
+		if err != nil || versionNode.Kind != yaml.ScalarNode || v < 1 {
+			return 0, fmt.Errorf("line %d: version: expected a layout number, got %q", versionNode.Line, versionNode.Value)
+		}
+		version = v
+	}
+	if version > currentConfigVersion {
+		return 0, fmt.Errorf("line %d: version: layout %d is newer than this funterm supports (%d), upgrade funterm", versionNode.Line, version, currentConfigVersion)
+	}
+
+	for v := version; v < currentConfigVersion; v++ {
+		if err := configMigrations[v-1](root); err != nil {
+			return 0, fmt.Errorf("failed to migrate config from layout %d: %v", v, err)
+		}
+	}
+	if version < currentConfigVersion && versionNode != nil {
+		versionNode.Value = strconv.Itoa(currentConfigVersion)
+	}
+	return version, nil
+}
+
+// upgradeConfigFile rewrites the file in the current layout with a version
+// key, keeping the original as path.bak. It reports whether anything changed
+func upgradeConfigFile(path string) (bool, error) {
+	path = expandHome(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	version, err := migrateConfigDocument(&doc)
+	if err != nil {
+		return false, err
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	}
+	if root.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("config file %s is not a mapping of sections", path)
+	}
+	if version == currentConfigVersion && mappingValue(root, "version") != nil {
+		return false, nil
+	}
+	if mappingValue(root, "version") == nil {
+		// The version goes first, where it is seen before anything else, under
+		// the comment that opened the file
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+		if len(root.Content) > 0 {
+			key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{
+			key,
+			{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(currentConfigVersion)},
+		}, root.Content...)
+	}
+
+	var out []byte
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		var buf bytes.Buffer
+		if err := writeJSONNode(&buf, root, ""); err != nil {
+			return false, err
+		}
+		buf.WriteString("\n")
+		out = buf.Bytes()
+	} else {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return false, fmt.Errorf("failed to marshal YAML config: %v", err)
+		}
+		encoder.Close()
+		out = buf.Bytes()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(path+".bak", data, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to back up config file: %v", err)
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write config file: %v", err)
+	}
+	return true, nil
+}
+
+// writeJSONNode writes a document tree as indented JSON, keeping the order of keys
+func writeJSONNode(buf *bytes.Buffer, node *yaml.Node, indent string) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "{", "}", 2
+		if node.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(open + close)
+			return nil
+		}
+		buf.WriteString(open + "\n")
+		for i := 0; i < len(node.Content); i += step {
+			buf.WriteString(indent + "  ")
+			if step == 2 {
+				key, _ := json.Marshal(node.Content[i].Value)
+				buf.Write(key)
+				buf.WriteString(": ")
+			}
+			if err := writeJSONNode(buf, node.Content[i+step-1], indent+"  "); err != nil {
+				return err
+			}
+			if i+step < len(node.Content) {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + close)
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buf.WriteString(node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			value, _ := json.Marshal(node.Value)
+			buf.Write(value)
+		}
+	default:
+		return fmt.Errorf("unsupported YAML node at line %d", node.Line)
+	}
+	return nil
+}
+
+// documentRoot returns the top mapping of a parsed document, nil when it is empty
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	if doc.Kind == 0 {
+		return nil
+	}
+	return doc
+}
+
+// mappingValue returns the value of key in a mapping node, nil when it is absent
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
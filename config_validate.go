@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"funterm/engine"
+	"go-parser/pkg/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigProblem is one problem found in a configuration file
+type ConfigProblem struct {
+	Line    int    // line in the file, 0 when the problem has no place in it
+	Key     string // dotted path of the setting, e.g. engine.spinner_threshold_ms
+	Message string
+}
+
+func (p ConfigProblem) String() string {
+	var sb strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&sb, "line %d: ", p.Line)
+	}
+	if p.Key != "" {
+		sb.WriteString(p.Key + ": ")
+	}
+	sb.WriteString(p.Message)
+	return sb.String()
+}
+
+// ConfigError lists every problem found in a configuration file, so they can
+// all be fixed at once
+type ConfigError struct {
+	Path     string // empty when only the environment was read
+	Problems []ConfigProblem
+}
+
+func (e *ConfigError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	source := e.Path
+	if source == "" {
+		source = "from the environment"
+	}
+	if len(e.Problems) == 1 {
+		lines = append(lines, fmt.Sprintf("invalid configuration %s:", source))
+	} else {
+		lines = append(lines, fmt.Sprintf("invalid configuration %s, %d problems:", source, len(e.Problems)))
+	}
+	for _, problem := range e.Problems {
+		lines = append(lines, "  "+problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// knownLanguages are the names accepted in languages.disabled and languages.runtimes
+var knownLanguages = []string{"elixir", "erlang", "go", "javascript", "lua", "node", "perl", "python", "ex", "erl", "js", "pl", "py"}
+
+// preloadModule is a module the Python interpreter can import when it starts
+var preloadModule = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*( as [A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// logLevels are the values of logging.level
+var logLevels = []string{"debug", "info", "warning", "warn", "error", "fatal"}
+
+// checkConfigSchema compares the document with the Config struct and reports
+// unknown keys and values of the wrong type. The document is parsed as YAML,
+// which also covers JSON files
+func checkConfigSchema(root *yaml.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	if root = documentRoot(root); root == nil {
+		return nil // empty file
+	}
+	checkNode(root, reflect.TypeOf(Config{}), "", &problems)
+	return problems
+}
+
+// checkNode checks a node against the Go type it is decoded into
+func checkNode(node *yaml.Node, t reflect.Type, key string, problems *[]ConfigProblem) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+	report := func(format string, args ...interface{}) {
+		*problems = append(*problems, ConfigProblem{Line: node.Line, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			report("expected a section of settings, got %s", describeNode(node))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, found := fields[name]
+			if !found {
+				*problems = append(*problems, ConfigProblem{
+					Line:    node.Content[i].Line,
+					Key:     joinKey(key, name),
+					Message: "unknown key" + suggestKey(name, fields),
+				})
+				continue
+			}
+			checkNode(node.Content[i+1], field, joinKey(key, name), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			report("expected a mapping, got %s", describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkNode(node.Content[i+1], t.Elem(), joinKey(key, node.Content[i].Value), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			report("expected a list, got %s", describeNode(node))
+			return
+		}
+		for i, item := range node.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), problems)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			report("expected a string, got %s", describeNode(node))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			report("expected true or false, got %s", describeNode(node))
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			report("expected an integer, got %s", describeNode(node))
+		}
+	case reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.ShortTag() != "!!int" && node.ShortTag() != "!!float") {
+			report("expected a number, got %s", describeNode(node))
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct to field types, including the
+// fields of inlined structs
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if strings.Contains(options, "inline") {
+			for inlineName, inlineType := range yamlFields(field.Type) {
+				fields[inlineName] = inlineType
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return strconv.Quote(node.Value)
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// suggestKey names the closest known key, or lists the known keys when
+// nothing is close
+func suggestKey(name string, fields map[string]reflect.Type) string {
+	known := make([]string, 0, len(fields))
+	for field := range fields {
+		known = append(known, field)
+	}
+	sort.Strings(known)
+	best, bestDistance := "", len(name)/2+1
+	for _, field := range known {
+		if d := editDistance(name, field); d < bestDistance {
+			best, bestDistance = field, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf(", did you mean %s?", best)
+	}
+	return fmt.Sprintf(" (expected one of %s)", strings.Join(known, ", "))
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// checkConfigValues reports settings that have the right type but an invalid
+// value. root locates them in the file and may be nil; overrides names the
+// variable that set a key from the environment
+func checkConfigValues(config *Config, root *yaml.Node, overrides map[string]string) []ConfigProblem {
+	var problems []ConfigProblem
+	report := func(message string, path ...string) {
+		key := ""
+		for _, name := range path {
+			if _, err := strconv.Atoi(name); err == nil {
+				key += "[" + name + "]"
+			} else {
+				key = joinKey(key, name)
+			}
+		}
+		for overridden, variable := range overrides {
+			// parser is checked as a whole, its keys one by one
+			if key == overridden || strings.HasPrefix(key, overridden+".") || strings.HasPrefix(key, overridden+"[") || strings.HasPrefix(overridden, key+".") {
+				problems = append(problems, ConfigProblem{Key: key, Message: fmt.Sprintf("%s (set by %s)", message, variable)})
+				return
+			}
+		}
+		problems = append(problems, ConfigProblem{Line: keyLine(root, path...), Key: key, Message: message})
+	}
+
+	if config.REPL.HistorySize < 0 {
+		report("must not be negative", "repl", "history_size")
+	}
+	if config.Engine.MaxExecutionTime < 0 {
+		report("must not be negative", "engine", "max_execution_time_seconds")
+	}
+	if config.Engine.MemoryBudgetMB < 0 {
+		report("must not be negative, use 0 to disable the budget", "engine", "memory_budget_mb")
+	}
+	if err := config.Engine.Numbers.Validate(); err != nil {
+		report(err.Error(), "engine", "numbers")
+	}
+	for i, pattern := range config.Engine.GuardedCalls {
+		if err := engine.ValidateGuardedCalls([]string{pattern}); err != nil {
+			report(err.Error(), "engine", "guarded_calls", strconv.Itoa(i))
+		}
+	}
+	if err := engine.ValidateStrictRules(config.Engine.StrictRules); err != nil {
+		report(err.Error(), "engine", "strict_rules")
+	}
+	if err := engine.ValidateSegmentOverflow(config.Engine.SegmentOverflow); err != nil {
+		report(err.Error(), "engine", "segment_overflow")
+	}
+	if config.Engine.Policy.MaxExecutionMs < 0 {
+		report("must not be negative, use 0 for no limit", "engine", "policy", "max_execution_ms")
+	}
+
+	if config.Logging.Level != "" && !contains(logLevels, config.Logging.Level) {
+		report(fmt.Sprintf("unknown level '%s', expected one of %s", config.Logging.Level, strings.Join(logLevels, ", ")), "logging", "level")
+	}
+
+	if !contains(updateChannels, config.Update.Channel) {
+		report(fmt.Sprintf("unknown channel '%s', expected one of %s", config.Update.Channel, strings.Join(updateChannels, ", ")), "update", "channel")
+	}
+	if config.Update.URL == "" {
+		report("must not be empty", "update", "url")
+	}
+	if config.Telemetry.Enabled && config.Telemetry.File == "" {
+		report("must not be empty when telemetry is enabled", "telemetry", "file")
+	}
+
+	for i, language := range config.Languages.Disabled {
+		if !contains(knownLanguages, language) {
+			report(fmt.Sprintf("unknown language '%s'", language), "languages", "disabled", strconv.Itoa(i))
+		}
+	}
+	languages := make([]string, 0, len(config.Languages.Runtimes))
+	for language := range config.Languages.Runtimes {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		runtimeConfig := config.Languages.Runtimes[language]
+		if !contains(knownLanguages, language) {
+			report("unknown language", "languages", "runtimes", language)
+			continue
+		}
+		if err := runtimeConfig.ProcessOptions.Validate(language); err != nil {
+			report(err.Error(), "languages", "runtimes", language)
+		}
+		if err := validateRuntimeMode(language, runtimeConfig.Mode); err != nil {
+			report(err.Error(), "languages", "runtimes", language, "mode")
+		}
+		if runtimeConfig.Container != "" && runtimeConfig.Mode == "embedded" {
+			report("an embedded runtime cannot run in a container", "languages", "runtimes", language, "container")
+		}
+		if runtimeConfig.Remote != "" && runtimeConfig.Mode == "embedded" {
+			report("an embedded runtime cannot run on a remote host", "languages", "runtimes", language, "remote")
+		}
+		if language == "lua" && runtimeConfig.Mode == "external" && runtimeConfig.Encoding != "" {
+			report("an external lua exchanges values as UTF-8 JSON, encoding is not supported", "languages", "runtimes", language, "encoding")
+		}
+		if (len(runtimeConfig.Preload) > 0 || runtimeConfig.Snapshot) && language != "python" {
+			report("only python preloads modules", "languages", "runtimes", language)
+		}
+		for i, module := range runtimeConfig.Preload {
+			if !preloadModule.MatchString(module) {
+				report(fmt.Sprintf("invalid module '%s', expected a name like numpy or \"pandas as pd\"", module), "languages", "runtimes", language, "preload", strconv.Itoa(i))
+			}
+		}
+		if runtimeConfig.Snapshot && (runtimeConfig.Mode == "embedded" || !runtimeConfig.ProcessOptions.OnHost()) {
+			report("a snapshot needs an external interpreter on this machine", "languages", "runtimes", language, "snapshot")
+		}
+		// The path of an interpreter in a container or on a remote host is
+		// a path there
+		if runtimeConfig.Path != "" && runtimeConfig.Mode != "embedded" && runtimeConfig.ProcessOptions.OnHost() {
+			if _, err := exec.LookPath(expandHome(runtimeConfig.Path)); err != nil {
+				report(fmt.Sprintf("interpreter '%s' not found", runtimeConfig.Path), "languages", "runtimes", language, "path")
+			}
+		}
+	}
+
+	// Проверяем имена обработчиков сразу, чтобы опечатка не всплыла при создании движка
+	if err := parser.NewUnifiedParser().ApplyHandlerOptions(config.Parser); err != nil {
+		report(err.Error(), "parser")
+	}
+	return problems
+}
+
+// keyLine returns the line of the deepest key of path found in the document;
+// list items are addressed by their index
+func keyLine(root *yaml.Node, path ...string) int {
+	if root == nil {
+		return 0
+	}
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 0
+	for _, key := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(node.Content) {
+				next = node.Content[index]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
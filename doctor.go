@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"funterm/factory"
+	"funterm/runtime"
+)
+
+// doctorPings is how many calls measure the round trip to a runtime
+const doctorPings = 5
+
+// doctorSlowRoundTrip is the round trip above which a runtime is reported as slow
+const doctorSlowRoundTrip = 250 * time.Millisecond
+
+// runtimeProbe is the code doctor evaluates in a runtime: expressions for its
+// version, the encoding of its text and whether it can encode JSON, and a
+// trivial one timed for the round trip. An empty expression isn't probed
+type runtimeProbe struct {
+	language string
+	process  bool // the runtime is an interpreter process unless its mode says otherwise
+	version  string
+	encoding string
+	json     string
+	ping     string
+}
+
+var runtimeProbes = []runtimeProbe{
+	{language: "lua", version: "return _VERSION", ping: "return 1"},
+	{
+		language: "python", process: true,
+		version:  "__import__('sys').version.split()[0]",
+		encoding: "__import__('sys').stdout.encoding",
+		json:     "__import__('json').dumps([1]) == '[1]'",
+		ping:     "1",
+	},
+	{language: "go"},
+	{
+		language: "node", process: true,
+		version: "typeof process === 'undefined' ? 'goja (embedded)' : process.version",
+		json:    "JSON.stringify([1]) === '[1]'",
+		ping:    "1",
+	},
+	{
+		language: "perl", process: true,
+		version: "sprintf('%vd', $^V)",
+		json:    "eval { require JSON::PP; 1 } ? 1 : 0",
+		ping:    "1",
+	},
+	{
+		language: "erlang", process: true,
+		version:  "list_to_binary(erlang:system_info(otp_release)).",
+		encoding: "file:native_name_encoding().",
+		json:     "element(1, code:ensure_loaded(json)) =:= module.",
+		ping:     "1.",
+	},
+	{
+		language: "elixir", process: true,
+		version:  "System.version()",
+		encoding: ":file.native_name_encoding()",
+		json:     "Code.ensure_loaded?(JSON)",
+		ping:     "1",
+	},
+}
+
+// doctorProblem is something doctor found wrong, with how to fix it
+type doctorProblem struct {
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"`
+}
+
+// doctorRuntime is what doctor found out about one runtime
+type doctorRuntime struct {
+	Language    string          `json:"language"`
+	Status      string          `json:"status"` // "ok", "warning", "error" or "disabled"
+	Executable  string          `json:"executable,omitempty"`
+	Version     string          `json:"version,omitempty"`
+	Encoding    string          `json:"encoding,omitempty"`
+	JSON        *bool           `json:"json,omitempty"`
+	StartupMs   float64         `json:"startup_ms,omitempty"`
+	RoundTripMs float64         `json:"round_trip_ms,omitempty"` // median of doctorPings calls
+	Problems    []doctorProblem `json:"problems,omitempty"`
+}
+
+// doctorReport is the whole result of `funterm doctor`, printed as is with --json
+type doctorReport struct {
+	Funterm    string          `json:"funterm"`
+	Platform   string          `json:"platform"`
+	Config     string          `json:"config"` // empty when the defaults are used
+	WorkingDir string          `json:"working_dir"`
+	Runtimes   []doctorRuntime `json:"runtimes"`
+	Problems   []doctorProblem `json:"problems,omitempty"`
+	OK         bool            `json:"ok"`
+}
+
+// runDiagnostics implements `funterm doctor`: it starts every runtime the
+// configuration enables, evaluates a probe in it, measures the round trip
+// and checks PATH and the configuration, then prints the findings with fix
+// suggestions, as JSON with jsonOutput. It returns false if there are errors.
+func runDiagnostics(configPath string, noConfig, verbose, jsonOutput bool) (bool, error) {
+	report := doctorReport{
+		Funterm:  funtermVersion,
+		Platform: goruntime.GOOS + "/" + goruntime.GOARCH,
+	}
+	if !noConfig {
+		report.Config = findConfigFile(configPath)
+	}
+	cfg, err := LoadConfig(report.Config)
+	if err != nil {
+		report.Problems = append(report.Problems, doctorProblem{
+			Severity: "error",
+			Message:  fmt.Sprintf("configuration: %v", err),
+			Fix:      "run `funterm config validate` and correct the file, or use --no-config",
+		})
+		if cfg, err = LoadConfig(""); err != nil {
+			return false, err
+		}
+	}
+	if report.WorkingDir, err = os.Getwd(); err != nil {
+		report.Problems = append(report.Problems, doctorProblem{Severity: "error", Message: fmt.Sprintf("working directory: %v", err)})
+	}
+	report.Problems = append(report.Problems, checkPath()...)
+
+	if !jsonOutput {
+		fmt.Println("=== Funterm System Diagnostics ===")
+		fmt.Println()
+	}
+	registry := newRuntimeRegistry(cfg, nil)
+	for i, probe := range runtimeProbes {
+		result := doctorRuntime{Language: probe.language, Status: "disabled"}
+		if _, err := registry.GetFactory(probe.language); err == nil {
+			result = probeRuntime(cfg, probe, registry)
+		}
+		report.Runtimes = append(report.Runtimes, result)
+		if !jsonOutput {
+			printDoctorRuntime(i+1, result, verbose)
+		}
+	}
+
+	report.OK = true
+	for _, problem := range report.Problems {
+		report.OK = report.OK && problem.Severity != "error"
+	}
+	for _, result := range report.Runtimes {
+		report.OK = report.OK && result.Status != "error"
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(data))
+		return report.OK, nil
+	}
+
+	fmt.Printf("%d. Environment:\n", len(runtimeProbes)+1)
+	if report.Config == "" {
+		fmt.Printf("   ℹ️  Using default configuration\n")
+	} else {
+		fmt.Printf("   ✅ Configuration file: %s\n", report.Config)
+	}
+	fmt.Printf("   ✅ Working directory: %s\n", report.WorkingDir)
+	fmt.Printf("   ℹ️  funterm %s on %s\n", report.Funterm, report.Platform)
+	printDoctorProblems(report.Problems)
+	fmt.Println()
+	fmt.Println("=== Diagnostics Complete ===")
+	return report.OK, nil
+}
+
+// runsAsProcess reports whether the runtime is an interpreter process with
+// the mode configured for it
+func (probe runtimeProbe) runsAsProcess(cfg *Config) bool {
+	switch cfg.GetRuntimeMode(probe.language) {
+	case "embedded":
+		return false
+	case "external":
+		return true
+	}
+	return probe.process
+}
+
+// probeRuntime starts a runtime and evaluates the probe in it
+func probeRuntime(cfg *Config, probe runtimeProbe, registry *factory.RuntimeRegistry) doctorRuntime {
+	result := doctorRuntime{Language: probe.language, Status: "ok"}
+	problem := func(severity, message, fix string) {
+		result.Problems = append(result.Problems, doctorProblem{Severity: severity, Message: message, Fix: fix})
+		if severity == "error" || result.Status == "ok" {
+			result.Status = severity
+		}
+	}
+
+	process := probe.runsAsProcess(cfg)
+	if options := cfg.GetProcessOptions(probe.language); process && !options.OnHost() {
+		// The interpreter is found in the image or on the remote host, the
+		// container engine or ssh starts it
+		launcher, err := options.Launcher()
+		if err != nil {
+			key, fix := "container", "install docker or podman"
+			if options.Remote != "" {
+				key, fix = "remote", "install ssh"
+			}
+			problem("error", err.Error(),
+				fmt.Sprintf("%s, or remove languages.runtimes.%s.%s from the configuration", fix, probe.language, key))
+			return result
+		}
+		result.Executable = launcher + " " + options.Container + options.Remote
+	} else if process {
+		configured := cfg.GetRuntimePath(probe.language)
+		path, err := runtime.ResolveExecutable(configured)
+		if err != nil {
+			if info, statErr := os.Stat(configured); statErr == nil && !info.IsDir() && info.Mode().Perm()&0111 == 0 {
+				problem("error", fmt.Sprintf("'%s' is not executable", configured), "chmod +x "+configured)
+			} else {
+				// A language that is enabled but not installed only matters to
+				// scripts that call it, unless its path was set on purpose
+				severity := "warning"
+				if cfg.Languages.Runtimes[probe.language].Path != "" {
+					severity = "error"
+				}
+				problem(severity, fmt.Sprintf("'%s' not found in PATH", configured),
+					fmt.Sprintf("install %s or set languages.runtimes.%s.path in the configuration", probe.language, probe.language))
+			}
+			return result
+		}
+		result.Executable = path
+	}
+
+	start := time.Now()
+	rt, err := registry.CreateRuntimeForLanguage(probe.language)
+	if err == nil {
+		err = rt.Initialize()
+	}
+	if err != nil {
+		problem("error", fmt.Sprintf("failed to start: %v", err),
+			fmt.Sprintf("check that the %s installation works outside funterm, or disable the language in the configuration", probe.language))
+		return result
+	}
+	defer rt.Cleanup()
+	result.StartupMs = milliseconds(time.Since(start))
+
+	eval := func(code string) (string, error) {
+		value, err := rt.Eval(code)
+		if err != nil {
+			return "", err
+		}
+		return strings.Trim(fmt.Sprint(value), `'"`), nil
+	}
+
+	if probe.ping != "" {
+		var times []time.Duration
+		for i := 0; i < doctorPings; i++ {
+			start := time.Now()
+			if _, err := eval(probe.ping); err != nil {
+				problem("error", fmt.Sprintf("doesn't answer to calls: %v", err), "")
+				return result
+			}
+			times = append(times, time.Since(start))
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		median := times[len(times)/2]
+		result.RoundTripMs = milliseconds(median)
+		if median > doctorSlowRoundTrip {
+			problem("warning", fmt.Sprintf("calls take %v", median.Round(time.Millisecond)),
+				"the interpreter is slow to answer; check the load of the machine and the env in the configuration")
+		}
+	}
+
+	if probe.version != "" {
+		version, err := eval(probe.version)
+		if err != nil {
+			problem("warning", fmt.Sprintf("cannot report its version: %v", err), "")
+		}
+		result.Version = version
+	}
+	if probe.encoding != "" {
+		if encoding, err := eval(probe.encoding); err == nil {
+			result.Encoding = encoding
+			expected := cfg.GetProcessOptions(probe.language).Encoding
+			if expected == "" {
+				expected = "UTF-8"
+			}
+			if charsetKey(encoding) != charsetKey(expected) {
+				problem("warning", fmt.Sprintf("writes text as %s, funterm reads it as %s", encoding, expected),
+					fmt.Sprintf("set languages.runtimes.%s.encoding to %s, or a UTF-8 locale", probe.language, encoding))
+			}
+		}
+	}
+	if probe.json != "" {
+		value, err := eval(probe.json)
+		available := err == nil && (value == "true" || value == "True" || value == "1")
+		result.JSON = &available
+		if !available {
+			problem("warning", "cannot encode JSON", fmt.Sprintf("install a JSON library for %s", probe.language))
+		}
+	}
+	return result
+}
+
+// checkPath reports PATH entries that don't exist or aren't directories
+func checkPath() []doctorProblem {
+	var problems []doctorProblem
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		info, err := os.Stat(dir)
+		switch {
+		case err != nil:
+			problems = append(problems, doctorProblem{Severity: "warning", Message: fmt.Sprintf("PATH entry %s doesn't exist", dir), Fix: "remove it from PATH"})
+		case !info.IsDir():
+			problems = append(problems, doctorProblem{Severity: "warning", Message: fmt.Sprintf("PATH entry %s is not a directory", dir), Fix: "remove it from PATH"})
+		}
+	}
+	return problems
+}
+
+func printDoctorRuntime(number int, result doctorRuntime, verbose bool) {
+	fmt.Printf("%d. %s:\n", number, strings.ToUpper(result.Language[:1])+result.Language[1:])
+	switch result.Status {
+	case "disabled":
+		fmt.Printf("   ℹ️  Disabled in the configuration\n")
+		fmt.Println()
+		return
+	case "error":
+		fmt.Printf("   ❌ Not working\n")
+	case "warning":
+		if result.StartupMs == 0 && result.RoundTripMs == 0 {
+			fmt.Printf("   ⚠️  Not available\n")
+			break
+		}
+		fallthrough
+	default:
+		details := []string{}
+		if result.Version != "" {
+			details = append(details, result.Version)
+		}
+		if result.Encoding != "" {
+			details = append(details, result.Encoding)
+		}
+		if result.JSON != nil && *result.JSON {
+			details = append(details, "JSON")
+		}
+		fmt.Printf("   ✅ Working")
+		if len(details) > 0 {
+			fmt.Printf(" (%s)", strings.Join(details, ", "))
+		}
+		fmt.Println()
+	}
+	if result.Executable != "" && verbose {
+		fmt.Printf("   📂 %s\n", result.Executable)
+	}
+	if result.RoundTripMs > 0 {
+		fmt.Printf("   ⏱️  Startup %.1f ms, round trip %.2f ms\n", result.StartupMs, result.RoundTripMs)
+	}
+	printDoctorProblems(result.Problems)
+	fmt.Println()
+}
+
+func printDoctorProblems(problems []doctorProblem) {
+	for _, problem := range problems {
+		mark := "⚠️ "
+		if problem.Severity == "error" {
+			mark = "❌"
+		}
+		fmt.Printf("   %s %s\n", mark, strings.ReplaceAll(problem.Message, "\n", "\n      "))
+		if problem.Fix != "" {
+			fmt.Printf("      Fix: %s\n", problem.Fix)
+		}
+	}
+}
+
+// charsetKey makes "UTF-8", "utf8" and "utf_8" compare equal
+func charsetKey(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
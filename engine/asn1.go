@@ -0,0 +1,527 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// asn1MaxDepth bounds the nesting of decoded and encoded trees
+const asn1MaxDepth = 64
+
+// Universal tags with a decoded representation
+const (
+	asn1Boolean         = 1
+	asn1Integer         = 2
+	asn1BitString       = 3
+	asn1OctetString     = 4
+	asn1Null            = 5
+	asn1OID             = 6
+	asn1Enumerated      = 10
+	asn1UTF8String      = 12
+	asn1Sequence        = 16
+	asn1Set             = 17
+	asn1NumericString   = 18
+	asn1PrintableString = 19
+	asn1T61String       = 20
+	asn1IA5String       = 22
+	asn1UTCTime         = 23
+	asn1GeneralizedTime = 24
+	asn1VisibleString   = 26
+	asn1BMPString       = 30
+)
+
+// asn1TypeNames names the universal tags; they are also accepted as "type" by asn1.encode()
+var asn1TypeNames = map[int64]string{
+	asn1Boolean:         "BOOLEAN",
+	asn1Integer:         "INTEGER",
+	asn1BitString:       "BIT STRING",
+	asn1OctetString:     "OCTET STRING",
+	asn1Null:            "NULL",
+	asn1OID:             "OBJECT IDENTIFIER",
+	asn1Enumerated:      "ENUMERATED",
+	asn1UTF8String:      "UTF8String",
+	asn1Sequence:        "SEQUENCE",
+	asn1Set:             "SET",
+	asn1NumericString:   "NumericString",
+	asn1PrintableString: "PrintableString",
+	asn1T61String:       "T61String",
+	asn1IA5String:       "IA5String",
+	asn1UTCTime:         "UTCTime",
+	asn1GeneralizedTime: "GeneralizedTime",
+	asn1VisibleString:   "VisibleString",
+	asn1BMPString:       "BMPString",
+}
+
+// asn1Classes are the tag classes by their two high bits
+var asn1Classes = []string{"universal", "application", "context", "private"}
+
+// callASN1Function implements asn1.decode(bits), asn1.decode_all(bits) and asn1.encode(tree)
+func callASN1Function(function string, args []interface{}) (interface{}, error) {
+	name := "asn1." + function
+	if len(args) != 1 {
+		return nil, errors.NewUserError("ASN1_ARGUMENT_ERROR", fmt.Sprintf("%s() requires exactly one argument", name))
+	}
+
+	switch function {
+	case "decode", "decode_all":
+		data, err := wholeBytes(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := decodeASN1Elements(data, 0, 0)
+		if err != nil {
+			return nil, errors.NewUserError("ASN1_DECODE_ERROR", fmt.Sprintf("%s(): %v", name, err))
+		}
+		if function == "decode_all" {
+			return nodes, nil
+		}
+		if len(nodes) != 1 {
+			return nil, errors.NewUserError("ASN1_DECODE_ERROR", fmt.Sprintf("asn1.decode(): expected one element, found %d; use asn1.decode_all()", len(nodes)))
+		}
+		return nodes[0], nil
+	case "encode":
+		encoded, err := encodeASN1(args[0], 0)
+		if err != nil {
+			return nil, errors.NewUserError("ASN1_ENCODE_ERROR", fmt.Sprintf("asn1.encode(): %v", err))
+		}
+		return shared.NewBitstringObjectFromBytes(encoded), nil
+	}
+	return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("asn1 has no function '%s'; use decode, decode_all or encode", function))
+}
+
+// decodeASN1Elements decodes consecutive elements; offset is used in error messages
+func decodeASN1Elements(data []byte, offset, depth int) ([]interface{}, error) {
+	nodes := []interface{}{}
+	for len(data) > 0 {
+		node, size, err := decodeASN1Element(data, offset, depth)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		data = data[size:]
+		offset += size
+	}
+	return nodes, nil
+}
+
+// decodeASN1Element decodes one BER element and returns it with its encoded size
+func decodeASN1Element(data []byte, offset, depth int) (map[string]interface{}, int, error) {
+	if depth > asn1MaxDepth {
+		return nil, 0, fmt.Errorf("nesting deeper than %d levels at byte %d", asn1MaxDepth, offset)
+	}
+	if len(data) < 2 {
+		return nil, 0, fmt.Errorf("truncated element at byte %d", offset)
+	}
+
+	first := data[0]
+	class := asn1Classes[first>>6]
+	constructed := first&0x20 != 0
+	tag := int64(first & 0x1f)
+	pos := 1
+	if tag == 0x1f {
+		// High tag numbers continue in base 128
+		tag = 0
+		for {
+			if pos >= len(data) {
+				return nil, 0, fmt.Errorf("truncated tag at byte %d", offset)
+			}
+			b := data[pos]
+			pos++
+			if tag > 1<<55 {
+				return nil, 0, fmt.Errorf("tag number too large at byte %d", offset)
+			}
+			tag = tag<<7 | int64(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	if pos >= len(data) {
+		return nil, 0, fmt.Errorf("truncated length at byte %d", offset)
+	}
+	lengthByte := data[pos]
+	pos++
+
+	node := map[string]interface{}{
+		"class":       class,
+		"tag":         tag,
+		"constructed": constructed,
+	}
+	if typeName, ok := asn1TypeNames[tag]; ok && class == "universal" {
+		node["type"] = typeName
+	}
+
+	if lengthByte == 0x80 {
+		// Indefinite length: children up to the end-of-contents marker
+		if !constructed {
+			return nil, 0, fmt.Errorf("indefinite length on a primitive element at byte %d", offset)
+		}
+		children := []interface{}{}
+		for {
+			if pos+2 > len(data) {
+				return nil, 0, fmt.Errorf("missing end-of-contents for element at byte %d", offset)
+			}
+			if data[pos] == 0 && data[pos+1] == 0 {
+				pos += 2
+				break
+			}
+			child, size, err := decodeASN1Element(data[pos:], offset+pos, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			children = append(children, child)
+			pos += size
+		}
+		node["children"] = children
+		return node, pos, nil
+	}
+
+	length := int(lengthByte)
+	if lengthByte&0x80 != 0 {
+		count := int(lengthByte & 0x7f)
+		if count > 4 || pos+count > len(data) {
+			return nil, 0, fmt.Errorf("invalid length at byte %d", offset)
+		}
+		length = 0
+		for _, b := range data[pos : pos+count] {
+			length = length<<8 | int(b)
+		}
+		pos += count
+	}
+	if length < 0 || pos+length > len(data) {
+		return nil, 0, fmt.Errorf("element at byte %d needs %d bytes, only %d left", offset, length, len(data)-pos)
+	}
+	content := data[pos : pos+length]
+	node["length"] = int64(length)
+
+	if constructed {
+		children, err := decodeASN1Elements(content, offset+pos, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		node["children"] = children
+	} else {
+		value, err := decodeASN1Value(class, tag, content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s at byte %d: %v", node["type"], offset, err)
+		}
+		node["value"] = value
+	}
+	return node, pos + length, nil
+}
+
+// decodeASN1Value converts the content of a primitive element; contents of
+// unknown or non-universal types are kept as bitstrings
+func decodeASN1Value(class string, tag int64, content []byte) (interface{}, error) {
+	raw := shared.NewBitstringObjectFromBytes(append([]byte(nil), content...))
+	if class != "universal" {
+		return raw, nil
+	}
+
+	switch tag {
+	case asn1Boolean:
+		if len(content) != 1 {
+			return nil, fmt.Errorf("boolean must be one byte")
+		}
+		return content[0] != 0, nil
+	case asn1Integer, asn1Enumerated:
+		if len(content) == 0 {
+			return nil, fmt.Errorf("empty integer")
+		}
+		n := new(big.Int).SetBytes(content)
+		if content[0]&0x80 != 0 {
+			// Two's complement negative number
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(content))*8))
+		}
+		return compactBigInt(n), nil
+	case asn1BitString:
+		if len(content) == 0 || content[0] > 7 || (len(content) == 1 && content[0] != 0) {
+			return nil, fmt.Errorf("invalid bit string")
+		}
+		bits := shared.NewBitstringObjectFromBytes(append([]byte(nil), content[1:]...))
+		return bits.Slice(0, uint(len(content)-1)*8-uint(content[0]))
+	case asn1Null:
+		return nil, nil
+	case asn1OID:
+		return decodeOID(content)
+	case asn1UTF8String, asn1NumericString, asn1PrintableString, asn1T61String,
+		asn1IA5String, asn1UTCTime, asn1GeneralizedTime, asn1VisibleString:
+		return string(content), nil
+	case asn1BMPString:
+		if len(content)%2 != 0 {
+			return nil, fmt.Errorf("BMPString has an odd length")
+		}
+		units := make([]uint16, len(content)/2)
+		for i := range units {
+			units[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+	return raw, nil
+}
+
+// decodeOID formats an object identifier in dotted notation
+func decodeOID(content []byte) (string, error) {
+	if len(content) == 0 || content[len(content)-1]&0x80 != 0 {
+		return "", fmt.Errorf("invalid object identifier")
+	}
+	var parts []string
+	value := new(big.Int)
+	for _, b := range content {
+		value.Lsh(value, 7).Or(value, big.NewInt(int64(b&0x7f)))
+		if b&0x80 != 0 {
+			continue
+		}
+		if len(parts) == 0 {
+			// The first subidentifier packs the first two arcs
+			first := int64(2)
+			if value.Cmp(big.NewInt(80)) < 0 {
+				first = value.Int64() / 40
+			}
+			value.Sub(value, big.NewInt(first*40))
+			parts = append(parts, strconv.FormatInt(first, 10))
+		}
+		parts = append(parts, value.String())
+		value = new(big.Int)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// encodeASN1 encodes a node as produced by asn1.decode(); "class" defaults to
+// universal, and "tag" may be omitted when "type" names a universal type
+func encodeASN1(value interface{}, depth int) ([]byte, error) {
+	if depth > asn1MaxDepth {
+		return nil, fmt.Errorf("nesting deeper than %d levels", asn1MaxDepth)
+	}
+	node, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a node object, got %s", orderTypeName(value))
+	}
+
+	classIndex := 0
+	if class, exists := node["class"]; exists {
+		classIndex = -1
+		for i, name := range asn1Classes {
+			if class == name {
+				classIndex = i
+			}
+		}
+		if classIndex < 0 {
+			return nil, fmt.Errorf("class must be universal, application, context or private, got %v", class)
+		}
+	}
+
+	tag := int64(-1)
+	if rawTag, exists := node["tag"]; exists {
+		n, ok := toInt64(rawTag)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("tag must be a non-negative integer, got %v", rawTag)
+		}
+		tag = n
+	} else if typeName, exists := node["type"]; exists && classIndex == 0 {
+		for t, name := range asn1TypeNames {
+			if strings.EqualFold(name, fmt.Sprint(typeName)) {
+				tag = t
+			}
+		}
+	}
+	if tag < 0 {
+		return nil, fmt.Errorf("node needs a tag or a known type")
+	}
+
+	children, hasChildren := node["children"]
+	constructed := hasChildren
+	if flag, exists := node["constructed"]; exists {
+		constructed, _ = flag.(bool)
+	}
+
+	var content []byte
+	if constructed {
+		list, ok := children.([]interface{})
+		if !ok && hasChildren {
+			return nil, fmt.Errorf("children must be an array")
+		}
+		for _, child := range list {
+			encoded, err := encodeASN1(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, encoded...)
+		}
+	} else {
+		encoded, err := encodeASN1Value(classIndex == 0, tag, node["value"])
+		if err != nil {
+			return nil, err
+		}
+		content = encoded
+	}
+
+	out := asn1Identifier(classIndex, constructed, tag)
+	out = append(out, asn1Length(len(content))...)
+	return append(out, content...), nil
+}
+
+// asn1Identifier encodes the identifier octets
+func asn1Identifier(class int, constructed bool, tag int64) []byte {
+	first := byte(class << 6)
+	if constructed {
+		first |= 0x20
+	}
+	if tag < 0x1f {
+		return []byte{first | byte(tag)}
+	}
+	var digits []byte
+	for n := tag; n > 0; n >>= 7 {
+		digits = append([]byte{byte(n & 0x7f)}, digits...)
+	}
+	for i := 0; i < len(digits)-1; i++ {
+		digits[i] |= 0x80
+	}
+	return append([]byte{first | 0x1f}, digits...)
+}
+
+// asn1Length encodes a definite length
+func asn1Length(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var digits []byte
+	for n := length; n > 0; n >>= 8 {
+		digits = append([]byte{byte(n)}, digits...)
+	}
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+// encodeASN1Value encodes the content of a primitive element
+func encodeASN1Value(universal bool, tag int64, value interface{}) ([]byte, error) {
+	if universal {
+		switch tag {
+		case asn1Boolean:
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("BOOLEAN value must be true or false, got %v", value)
+			}
+			if b {
+				return []byte{0xff}, nil
+			}
+			return []byte{0}, nil
+		case asn1Integer, asn1Enumerated:
+			return encodeASN1Integer(value)
+		case asn1BitString:
+			bits, err := binaryArgument("asn1.encode", value)
+			if err != nil {
+				return nil, err
+			}
+			unused := byte((8 - bits.Len()%8) % 8)
+			return append([]byte{unused}, bits.Bytes()...), nil
+		case asn1Null:
+			return nil, nil
+		case asn1OID:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("OBJECT IDENTIFIER value must be a dotted string, got %v", value)
+			}
+			return encodeOID(s)
+		case asn1BMPString:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("BMPString value must be a string, got %v", value)
+			}
+			var out []byte
+			for _, unit := range utf16.Encode([]rune(s)) {
+				out = append(out, byte(unit>>8), byte(unit))
+			}
+			return out, nil
+		}
+	}
+	if value == nil {
+		return nil, nil
+	}
+	bits, err := binaryArgument("asn1.encode", value)
+	if err != nil {
+		return nil, err
+	}
+	if bits.Len()%8 != 0 {
+		return nil, fmt.Errorf("value must be whole bytes, got %d bits", bits.Len())
+	}
+	return bits.Bytes(), nil
+}
+
+// encodeASN1Integer encodes a number in minimal two's complement
+func encodeASN1Integer(value interface{}) ([]byte, error) {
+	var n *big.Int
+	switch v := value.(type) {
+	case *big.Int:
+		n = v
+	default:
+		i, ok := toInt64(value)
+		if !ok {
+			return nil, fmt.Errorf("INTEGER value must be an integer, got %v", value)
+		}
+		n = big.NewInt(i)
+	}
+
+	if n.Sign() >= 0 {
+		out := n.Bytes()
+		if len(out) == 0 || out[0]&0x80 != 0 {
+			out = append([]byte{0}, out...)
+		}
+		return out, nil
+	}
+	// Negative: add 2^(8k) for the smallest k that keeps the sign bit set
+	size := len(n.Bytes())
+	for {
+		twos := new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(size)*8))
+		out := twos.Bytes()
+		if twos.Sign() > 0 && len(out) == size && out[0]&0x80 != 0 {
+			return out, nil
+		}
+		size++
+	}
+}
+
+// encodeOID encodes a dotted object identifier
+func encodeOID(s string) ([]byte, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("object identifier needs at least two arcs: %q", s)
+	}
+	arcs := make([]*big.Int, len(parts))
+	for i, part := range parts {
+		arc, ok := new(big.Int).SetString(part, 10)
+		if !ok || arc.Sign() < 0 {
+			return nil, fmt.Errorf("invalid object identifier %q", s)
+		}
+		arcs[i] = arc
+	}
+	if arcs[0].Cmp(big.NewInt(2)) > 0 || (arcs[0].Cmp(big.NewInt(2)) < 0 && arcs[1].Cmp(big.NewInt(40)) >= 0) {
+		return nil, fmt.Errorf("invalid object identifier %q", s)
+	}
+
+	first := new(big.Int).Mul(arcs[0], big.NewInt(40))
+	first.Add(first, arcs[1])
+	var out []byte
+	for _, arc := range append([]*big.Int{first}, arcs[2:]...) {
+		var digits []byte
+		n := new(big.Int).Set(arc)
+		for {
+			digits = append([]byte{byte(new(big.Int).And(n, big.NewInt(0x7f)).Int64())}, digits...)
+			n.Rsh(n, 7)
+			if n.Sign() == 0 {
+				break
+			}
+		}
+		for i := 0; i < len(digits)-1; i++ {
+			digits[i] |= 0x80
+		}
+		out = append(out, digits...)
+	}
+	return out, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"funterm/errors"
+	"funterm/runtime"
 	"funterm/shared"
 	"go-parser/pkg/ast"
 )
@@ -27,6 +28,9 @@ func (e *ExecutionEngine) executeExpressionAssignment(exprAssignment *ast.Expres
 	case *ast.IndexExpression:
 		// Handle indexed assignment like dict["key"] = value
 		return e.executeIndexedAssignment(leftExpr, value)
+	case *ast.SliceExpression:
+		// Handle slice assignment like arr[2:5] = [7, 8, 9]
+		return e.executeSliceAssignment(leftExpr, value)
 	default:
 		return nil, errors.NewUserErrorWithASTPos("EXPRESSION_ASSIGNMENT_ERROR", fmt.Sprintf("cannot assign to expression of type %T", exprAssignment.Left), exprAssignment.Left.Position())
 	}
@@ -40,6 +44,10 @@ func (e *ExecutionEngine) executeAssignment(left ast.Expression, rightValue inte
 		// Handle indexed assignment like dict["key"] = value
 		return e.executeIndexedAssignment(leftExpr, rightValue)
 
+	case *ast.SliceExpression:
+		// Handle slice assignment like arr[2:5] = [7, 8, 9]
+		return e.executeSliceAssignment(leftExpr, rightValue)
+
 	case *ast.Identifier:
 		// Handle simple variable assignment
 		if !leftExpr.Qualified {
@@ -58,6 +66,11 @@ func (e *ExecutionEngine) executeAssignment(left ast.Expression, rightValue inte
 			language = "node"
 		}
 
+		if len(leftExpr.Path) > 0 {
+			root, path := qualifiedPath(leftExpr)
+			return e.assignPath(&VariableName{language: language, name: root}, path, rightValue, leftExpr.Position())
+		}
+
 		// Try to get the runtime from the runtime manager first
 		rt, err := e.runtimeManager.GetRuntime(language)
 		if err == nil {
@@ -80,19 +93,119 @@ func (e *ExecutionEngine) executeAssignment(left ast.Expression, rightValue inte
 	}
 }
 
+// sliceStep is a colon slice in a path, arr[2:5] or bits[0:8]: items of an
+// array, bits of a bitstring. A nil bound is the start or the end
+type sliceStep struct {
+	start, end *int64
+}
+
+// bounds resolves the slice for a collection of length items
+func (s *sliceStep) bounds(length int64) (int64, int64) {
+	start, end := int64(0), length
+	if s.start != nil {
+		start = *s.start
+	}
+	if s.end != nil {
+		end = *s.end
+	}
+	return start, end
+}
+
+// String returns the slice as written, e.g. [2:5] or [:1]
+func (s *sliceStep) String() string {
+	var start, end string
+	if s.start != nil {
+		start = fmt.Sprint(*s.start)
+	}
+	if s.end != nil {
+		end = fmt.Sprint(*s.end)
+	}
+	return fmt.Sprintf("[%s:%s]", start, end)
+}
+
+// evaluateSlice evaluates the bounds of a colon slice
+func (e *ExecutionEngine) evaluateSlice(slice *ast.SliceExpression) (*sliceStep, error) {
+	step := &sliceStep{}
+	for _, bound := range []struct {
+		expr   ast.Expression
+		target **int64
+	}{{slice.Start, &step.start}, {slice.End, &step.end}} {
+		if bound.expr == nil {
+			continue
+		}
+		value, err := e.convertExpressionToValue(bound.expr)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := toInt64(value)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ERROR", fmt.Sprintf("slice bound must be an integer, got %s", valueTypeName(value)), bound.expr.Position())
+		}
+		*bound.target = &n
+	}
+	return step, nil
+}
+
+// executeSliceExpression reads a colon slice: arr[2:5] is a new array of the
+// items 2 to 4, bits[0:4] a bitstring of the first four bits
+func (e *ExecutionEngine) executeSliceExpression(slice *ast.SliceExpression) (interface{}, error) {
+	object, err := e.convertExpressionToValue(slice.Object)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("SLICE_ERROR", fmt.Sprintf("failed to evaluate object: %v", err), slice.Object.Position())
+	}
+	step, err := e.evaluateSlice(slice)
+	if err != nil {
+		return nil, err
+	}
+	length, ok := sliceLength(object, 1)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("SLICE_ERROR", fmt.Sprintf("cannot slice %s", valueTypeName(object)), slice.Position())
+	}
+	start, end := step.bounds(length)
+	if start < 0 || start > end || end > length {
+		return nil, errors.NewUserErrorWithASTPos("SLICE_ERROR", fmt.Sprintf("slice %s is out of range for length %d", step, length), slice.Position())
+	}
+
+	switch obj := object.(type) {
+	case []interface{}:
+		return append([]interface{}{}, obj[start:end]...), nil
+	default:
+		if start == end {
+			return shared.NewBitstringObjectFromBytes(nil), nil
+		}
+		bits, err := obj.(*shared.BitstringObject).Slice(uint(start), uint(end-start))
+		if err != nil {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ERROR", err.Error(), slice.Position())
+		}
+		return bits, nil
+	}
+}
+
+// executeSliceAssignment replaces a colon slice of an array or a bitstring:
+// arr[2:5] = [7, 8, 9] or bits[0:8] = <<0xFF>>
+func (e *ExecutionEngine) executeSliceAssignment(slice *ast.SliceExpression, rightValue interface{}) (interface{}, error) {
+	path, root, err := e.extractNestedPath(slice.Object)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("failed to extract path: %v", err), slice.Position())
+	}
+	step, err := e.evaluateSlice(slice)
+	if err != nil {
+		return nil, err
+	}
+	return e.assignPath(root, append(path, step), rightValue, slice.Position())
+}
+
 // executeIndexedAssignment executes indexed assignment like dict["key"] = value
 func (e *ExecutionEngine) executeIndexedAssignment(indexExpr *ast.IndexExpression, rightValue interface{}) (interface{}, error) {
-	// Check if this is a nested IndexExpression (like py.data.users[0].age = value)
-	if nestedIndexExpr, ok := indexExpr.Object.(*ast.IndexExpression); ok {
-		// This is a nested assignment - handle it by evaluating the nested expression first
-		if e.verbose {
-			fmt.Printf("DEBUG: executeIndexedAssignment - detected nested IndexExpression, handling recursively\n")
+	// Nested (py.data.users[0]["age"] = value) or below a qualified path
+	// (py.state.cache["users"] = value): assign along the whole path
+	_, nested := indexExpr.Object.(*ast.IndexExpression)
+	if ident, ok := indexExpr.Object.(*ast.Identifier); nested || ok && len(ident.Path) > 0 {
+		path, root, err := e.extractNestedPath(indexExpr)
+		if err != nil {
+			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to extract nested path: %v", err), indexExpr.Position())
 		}
-
-		// For nested assignments, we need to handle the case where we're assigning to an index that doesn't exist
-		// Instead of evaluating the nested index expression (which would fail for out-of-bounds), we need to build the path
-		// and handle the assignment step by step
-		return e.executeNestedIndexedAssignmentWithExpansion(nestedIndexExpr, indexExpr.Index, rightValue)
+		return e.assignPath(root, path, rightValue, indexExpr.Index.Position())
 	}
 
 	// 1. Evaluate the object (it can be a variable or another index expression)
@@ -114,6 +227,11 @@ func (e *ExecutionEngine) executeIndexedAssignment(indexExpr *ast.IndexExpressio
 		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to extract variable name: %v", err), indexExpr.Object.Position())
 	}
 
+	// A range index assigns a slice: arr[2..<5] = [7, 8, 9]
+	if _, ok := indexValue.(*shared.RangeObject); ok {
+		return e.assignPath(varName, []interface{}{indexValue}, rightValue, indexExpr.Index.Position())
+	}
+
 	// Check mutability for unqualified (global) variables
 	if varName.language == "" {
 		if varInfo, exists := e.getGlobalVariableInfo(varName.name); exists && !varInfo.IsMutable {
@@ -267,6 +385,7 @@ func (e *ExecutionEngine) setVariableInRuntimeWithError(language, name string, v
 		e.setGlobalVariable(name, value)
 		return nil
 	}
+	value = runtime.ConvertToRuntime(language, value)
 
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(language)
@@ -309,12 +428,27 @@ func (e *ExecutionEngine) extractNestedPath(expr ast.Expression) ([]interface{},
 			if err != nil {
 				return nil, nil, fmt.Errorf("root expression is not a qualified variable: %v", err)
 			}
+			// py.state.cache[...]: the path starts at state
+			if ident := current.(*ast.Identifier); len(ident.Path) > 0 {
+				root, steps := qualifiedPath(ident)
+				return append(steps, path...), &VariableName{language: varName.language, name: root}, nil
+			}
 
 			return path, varName, nil
 		}
 	}
 }
 
+// qualifiedPath splits py.state.cache.users into the root variable state and
+// the keys cache and users below it
+func qualifiedPath(ident *ast.Identifier) (string, []interface{}) {
+	steps := make([]interface{}, 0, len(ident.Path))
+	for _, key := range ident.Path[1:] {
+		steps = append(steps, key)
+	}
+	return ident.Path[0], append(steps, ident.Name)
+}
+
 // executeNestedIndexedAssignment handles nested indexed assignments like dict["a"]["b"]["c"] = value
 func (e *ExecutionEngine) executeNestedIndexedAssignment(rootVarName *VariableName, nestedPath []interface{}, finalIndex interface{}, value interface{}, pos ast.Position) (interface{}, error) {
 	// Check mutability for unqualified (global) variables
@@ -462,6 +596,11 @@ func (e *ExecutionEngine) executeIndexExpression(indexExpr *ast.IndexExpression)
 		fmt.Printf("DEBUG: executeIndexExpression - evaluated object type: %T, value: %v, evaluated index type: %T, value: %v\n", objectValue, objectValue, indexValue, indexValue)
 	}
 
+	// A range index reads a slice: arr[1..3]
+	if rng, ok := indexValue.(*shared.RangeObject); ok {
+		return e.sliceByRange(objectValue, rng, indexExpr.Index.Position())
+	}
+
 	// 3. Handle different object types
 	switch obj := objectValue.(type) {
 	case map[string]interface{}:
@@ -555,6 +694,42 @@ func (e *ExecutionEngine) executeIndexExpression(indexExpr *ast.IndexExpression)
 	}
 }
 
+// sliceByRange returns the items of an array, or the bytes of a bitstring,
+// at the positions of a range
+func (e *ExecutionEngine) sliceByRange(object interface{}, rng *shared.RangeObject, pos ast.Position) (interface{}, error) {
+	var length int64
+	switch obj := object.(type) {
+	case []interface{}:
+		length = int64(len(obj))
+	case *shared.BitstringObject:
+		length = int64(obj.Len() / 8)
+	default:
+		return nil, errors.NewUserErrorWithASTPos("INDEX_EXPR_ERROR", fmt.Sprintf("cannot slice %s", valueTypeName(object)), pos)
+	}
+	count := rng.Len()
+	if count > 0 && (rng.Start < 0 || rng.Last() >= length) {
+		return nil, errors.NewUserErrorWithASTPos("INDEX_EXPR_ERROR", fmt.Sprintf("range %s is out of bounds for length %d", rng, length), pos)
+	}
+
+	switch obj := object.(type) {
+	case []interface{}:
+		if count == 0 {
+			return []interface{}{}, nil
+		}
+		return append([]interface{}{}, obj[rng.Start:rng.Last()+1]...), nil
+	default:
+		bits := obj.(*shared.BitstringObject)
+		if count == 0 {
+			return shared.NewBitstringObjectFromBytes(nil), nil
+		}
+		slice, err := bits.Slice(uint(rng.Start*8), uint(count*8))
+		if err != nil {
+			return nil, errors.NewUserErrorWithASTPos("INDEX_EXPR_ERROR", err.Error(), pos)
+		}
+		return slice, nil
+	}
+}
+
 // executeFieldAccess executes a field access expression like lua.data.name
 func (e *ExecutionEngine) executeFieldAccess(fieldAccess *ast.FieldAccess) (interface{}, error) {
 	// 1. Evaluate the object (it can be an identifier or another field access)
@@ -575,19 +750,13 @@ func (e *ExecutionEngine) executeFieldAccess(fieldAccess *ast.FieldAccess) (inte
 		return nil, errors.NewUserErrorWithASTPos("FIELD_ACCESS_ERROR", fmt.Sprintf("failed to evaluate object: %v", err), fieldAccess.Object.Position())
 	}
 
-	// 2. Handle different object types
-	switch obj := objectValue.(type) {
-	case map[string]interface{}:
-		// Dictionary/object field access
-		value, exists := obj[fieldAccess.Field]
-		if !exists {
-			return nil, errors.NewUserErrorWithASTPos("FIELD_ACCESS_ERROR", fmt.Sprintf("field '%s' not found in object", fieldAccess.Field), fieldAccess.Position())
-		}
-		return value, nil
-
-	default:
-		return nil, errors.NewUserErrorWithASTPos("FIELD_ACCESS_ERROR", fmt.Sprintf("cannot access field '%s' on type %T", fieldAccess.Field, objectValue), fieldAccess.Position())
+	// 2. Read the field the same way qualified paths are read
+	value, err := e.readField("", objectValue, fieldAccess.Field)
+	if execErr, ok := errors.AsExecutionError(err); ok {
+		pos := fieldAccess.Position()
+		return nil, execErr.WithPosition(pos.Line, pos.Column)
 	}
+	return value, err
 }
 
 // extractVariableName extracts language and variable name from an expression
@@ -620,6 +789,9 @@ func (e *ExecutionEngine) getVariableFromRuntime(language, name string) (interfa
 		}
 		return nil, fmt.Errorf("global variable '%s' not found", name)
 	}
+	if err := e.checkLanguage(language, ast.Position{}); err != nil {
+		return nil, err
+	}
 
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(language)
@@ -639,10 +811,10 @@ func (e *ExecutionEngine) getVariableFromRuntime(language, name string) (interfa
 	return nil, fmt.Errorf("runtime '%s' not available", language)
 }
 
-// isLanguageIdentifier checks if an identifier is a language name (lua, python, py, go, js, node)
+// isLanguageIdentifier checks if an identifier is a language name (lua, python, py, go, js, node, perl, pl, erlang, erl, elixir, ex)
 func (e *ExecutionEngine) isLanguageIdentifier(ident *ast.Identifier) bool {
 	switch ident.Name {
-	case "lua", "python", "py", "go", "js", "node":
+	case "lua", "python", "py", "go", "js", "node", "perl", "pl", "erlang", "erl", "elixir", "ex":
 		return true
 	default:
 		return false
@@ -659,6 +831,18 @@ func (e *ExecutionEngine) executeLanguageFieldAccess(ident *ast.Identifier, fiel
 	if language == "js" {
 		language = "node"
 	}
+	if language == "pl" {
+		language = "perl"
+	}
+	if language == "erl" {
+		language = "erlang"
+	}
+	if language == "ex" {
+		language = "elixir"
+	}
+	if err := e.checkLanguage(language, ident.Position()); err != nil {
+		return nil, err
+	}
 
 	// Try to get the runtime
 	rt, err := e.runtimeManager.GetRuntime(language)
@@ -700,292 +884,206 @@ func (e *ExecutionEngine) executeLanguageFieldAccess(ident *ast.Identifier, fiel
 	return nil, errors.NewRuntimeError(language, "VARIABLE_NOT_FOUND", fmt.Sprintf("variable '%s' not found in %s runtime", field, language))
 }
 
-// executeNestedIndexedAssignmentWithExpansion handles nested indexed assignments with array expansion
-func (e *ExecutionEngine) executeNestedIndexedAssignmentWithExpansion(nestedIndexExpr *ast.IndexExpression, finalIndex ast.Expression, rightValue interface{}) (interface{}, error) {
-	if e.verbose {
-		fmt.Printf("DEBUG: executeNestedIndexedAssignmentWithExpansion - handling nested assignment with expansion\n")
+// assignPath assigns value below the root variable, at a path of keys and
+// array indexes. The root is read once, changed here and written back with a
+// single SetVariable. Missing maps and arrays along the path are created,
+// an array when the next step is a number, unless the autovivification
+// strict rule forbids it; arrays grow with nils up to the assigned index
+func (e *ExecutionEngine) assignPath(root *VariableName, path []interface{}, value interface{}, pos ast.Position) (interface{}, error) {
+	if root.language == "" {
+		if varInfo, exists := e.getGlobalVariableInfo(root.name); exists && !varInfo.IsMutable {
+			return nil, errors.NewUserErrorWithASTPos("IMMUTABLE_VARIABLE_ERROR", fmt.Sprintf("cannot modify immutable variable '%s'", root.name), pos)
+		}
 	}
 
-	// Build the full path from the nested index expressions
-	var path []interface{}
-	var rootVarName *VariableName
-	var err error
-
-	// Extract the path from the nested index expression
-	path, rootVarName, err = e.extractNestedPath(nestedIndexExpr)
-	if err != nil {
-		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to extract nested path: %v", err), nestedIndexExpr.Position())
+	rootObject, err := e.getVariableFromRuntime(root.language, root.name)
+	if err != nil || rootObject == nil {
+		if err := e.coerce(StrictAutovivification, pos, "assignment creates the missing variable '%s'", root.name); err != nil {
+			return nil, err
+		}
+		rootObject = newPathContainer(path[0])
 	}
 
-	// Add the final index to the path
-	finalIndexValue, err := e.convertExpressionToValue(finalIndex)
+	updated, err := e.assignAtPath(rootObject, path, value, pos)
 	if err != nil {
-		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to evaluate final index: %v", err), finalIndex.Position())
+		return nil, err
 	}
-	path = append(path, finalIndexValue)
-
-	if e.verbose {
-		fmt.Printf("DEBUG: executeNestedIndexedAssignmentWithExpansion - root variable: %s.%s, path: %v\n", rootVarName.language, rootVarName.name, path)
-	}
-
-	// Check mutability for unqualified (global) variables
-	if rootVarName.language == "" {
-		if varInfo, exists := e.getGlobalVariableInfo(rootVarName.name); exists && !varInfo.IsMutable {
-			return nil, errors.NewUserErrorWithASTPos("IMMUTABLE_VARIABLE_ERROR", fmt.Sprintf("cannot modify immutable variable '%s'", rootVarName.name), finalIndex.Position())
-		}
+	if err := e.setVariableInRuntimeWithError(root.language, root.name, updated); err != nil {
+		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", err.Error(), pos)
 	}
+	return value, nil
+}
 
-	// Get the root object from runtime
-	rootObject, err := e.getVariableFromRuntime(rootVarName.language, rootVarName.name)
-	if err != nil {
-		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to get root variable %s.%s: %v", rootVarName.language, rootVarName.name, err), finalIndex.Position())
+// sliceLength returns the length of an array in items, or of a bitstring
+// in units of unitBits bits; ok is false for other values
+func sliceLength(container interface{}, unitBits int) (int64, bool) {
+	switch obj := container.(type) {
+	case []interface{}:
+		return int64(len(obj)), true
+	case *shared.BitstringObject:
+		return int64(obj.Len() / unitBits), true
 	}
+	return 0, false
+}
 
-	// Navigate through the nested path and create/update the structure with expansion
-	current := rootObject
-	for i, pathIndex := range path {
-		if i == len(path)-1 {
-			// This is the final level - perform the assignment
-			return e.executeFinalAssignmentWithExpansion(current, pathIndex, rightValue, rootVarName, path[:i])
+// replaceSlice returns a copy of an array, or of a bitstring, with the items
+// or units of unitBits bits from start to end replaced: arr[2..<5] = [7, 8, 9]
+// counts bytes of a bitstring, bits[0:8] = <<0xFF>> bits. The replacement may
+// differ in length, so the collection can grow or shrink; an empty slice
+// like 2..<2 inserts. The bounds are checked by the caller
+func (e *ExecutionEngine) replaceSlice(container interface{}, start, end int64, unitBits int, value interface{}, pos ast.Position) (interface{}, error) {
+	switch obj := container.(type) {
+	case []interface{}:
+		values, ok := value.([]interface{})
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("array slice requires an array, got %s", valueTypeName(value)), pos)
 		}
-
-		// Navigate to the next level
-		switch obj := current.(type) {
-		case map[string]interface{}:
-			key, ok := pathIndex.(string)
-			if !ok {
-				return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("nested path index must be string, got %T", pathIndex), finalIndex.Position())
-			}
-
-			if nextObj, exists := obj[key]; exists {
-				current = nextObj
-			} else {
-				// Create new nested structure based on the next path index type
-				if i+1 < len(path) {
-					nextPathIndex := path[i+1]
-					switch nextPathIndex.(type) {
-					case string:
-						// Next level is a map
-						newMap := make(map[string]interface{})
-						obj[key] = newMap
-						current = newMap
-					case float64, int64, int:
-						// Next level is an array
-						newArray := make([]interface{}, 0)
-						obj[key] = newArray
-						current = newArray
-					default:
-						return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("unsupported path index type: %T", nextPathIndex), finalIndex.Position())
-					}
-				}
+		result := make([]interface{}, 0, int64(len(obj))-(end-start)+int64(len(values)))
+		result = append(result, obj[:start]...)
+		result = append(result, values...)
+		return append(result, obj[end:]...), nil
+	default:
+		bits := obj.(*shared.BitstringObject)
+		replacement, ok := value.(*shared.BitstringObject)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("bitstring slice requires a bitstring, got %s", valueTypeName(value)), pos)
+		}
+		// Empty prefixes and suffixes are skipped: funbit can't build them
+		var parts []interface{}
+		if start > 0 {
+			prefix, err := bits.Slice(0, uint(start)*uint(unitBits))
+			if err != nil {
+				return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", err.Error(), pos)
 			}
-
-		case []interface{}:
-			// Array navigation with expansion
-			var idx float64
-			switch i := pathIndex.(type) {
-			case float64:
-				idx = i
-			case int64:
-				idx = float64(i)
-			case int:
-				idx = float64(i)
-			default:
-				return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index must be number, got %T", pathIndex), finalIndex.Position())
+			parts = append(parts, prefix)
+		}
+		if replacement.Len() > 0 {
+			parts = append(parts, replacement)
+		}
+		if rest := bits.Len() - int(end)*unitBits; rest > 0 {
+			suffix, err := bits.Slice(uint(end)*uint(unitBits), uint(rest))
+			if err != nil {
+				return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", err.Error(), pos)
 			}
-			intIdx := int(idx)
-
-			// Expand array if index is beyond current length
-			if intIdx >= len(obj) {
-				expanded := make([]interface{}, intIdx+1)
-				copy(expanded, obj)
-				// Fill gaps with nil
-				for i := len(obj); i < intIdx; i++ {
-					expanded[i] = nil
-				}
-				// Update the array in the parent structure
-				err = e.updateNestedStructure(rootVarName, path[:i], expanded)
-				if err != nil {
-					return nil, err
-				}
-				obj = expanded
+			parts = append(parts, suffix)
+		}
+		var updated interface{} = shared.NewBitstringObjectFromBytes(nil)
+		for i, part := range parts {
+			if i == 0 {
+				updated = part
+				continue
 			}
-
-			if intIdx < len(obj) {
-				if obj[intIdx] != nil {
-					current = obj[intIdx]
-				} else {
-					// Create new nested structure based on the next path index type
-					if i+1 < len(path) {
-						nextPathIndex := path[i+1]
-						switch nextPathIndex.(type) {
-						case string:
-							// Next level is a map
-							newMap := make(map[string]interface{})
-							obj[intIdx] = newMap
-							current = newMap
-						case float64, int64, int:
-							// Next level is an array
-							newArray := make([]interface{}, 0)
-							obj[intIdx] = newArray
-							current = newArray
-						default:
-							return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("unsupported path index type: %T", nextPathIndex), finalIndex.Position())
-						}
-					} else {
-						// This is the last level, but we're not at the final assignment yet
-						// This shouldn't happen in normal flow
-						return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("unexpected end of path at index %d", intIdx), finalIndex.Position())
-					}
-				}
-			} else {
-				return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index %d out of bounds after expansion", intIdx), finalIndex.Position())
+			var err error
+			if updated, err = e.executeStringConcat(updated, part); err != nil {
+				return nil, err
 			}
-
-		default:
-			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("cannot navigate nested path at level %d, type %T", i, current), finalIndex.Position())
 		}
+		return updated, nil
 	}
-
-	return rightValue, nil
 }
 
-// executeFinalAssignmentWithExpansion performs the final assignment with expansion
-func (e *ExecutionEngine) executeFinalAssignmentWithExpansion(current interface{}, finalIndex interface{}, rightValue interface{}, rootVarName *VariableName, path []interface{}) (interface{}, error) {
-	switch obj := current.(type) {
-	case map[string]interface{}:
-		key, ok := finalIndex.(string)
+// assignAtPath returns a copy of container with value set at path; only the
+// maps and arrays along the path are copied
+func (e *ExecutionEngine) assignAtPath(container interface{}, path []interface{}, value interface{}, pos ast.Position) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	step, rest := path[0], path[1:]
+
+	if rng, ok := step.(*shared.RangeObject); ok {
+		if len(rest) > 0 {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("cannot index into the slice %s", rng), pos)
+		}
+		length, ok := sliceLength(container, 8)
 		if !ok {
-			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("final index must be string, got %T", finalIndex), ast.Position{})
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("cannot assign to a slice of %s", valueTypeName(container)), pos)
 		}
-		// Create a copy of the map and modify it
-		newObj := make(map[string]interface{})
-		for k, v := range obj {
-			newObj[k] = v
+		start, end := rng.Start, rng.Start+rng.Len()
+		if start < 0 || end > length {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("range %s is out of bounds for length %d", rng, length), pos)
 		}
-		newObj[key] = rightValue
+		return e.replaceSlice(container, start, end, 8, value, pos)
+	}
+	if slice, ok := step.(*sliceStep); ok {
+		if len(rest) > 0 {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("cannot index into the slice %s", slice), pos)
+		}
+		length, ok := sliceLength(container, 1)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("cannot assign to a slice of %s", valueTypeName(container)), pos)
+		}
+		start, end := slice.bounds(length)
+		if start < 0 || start > end || end > length {
+			return nil, errors.NewUserErrorWithASTPos("SLICE_ASSIGNMENT_ERROR", fmt.Sprintf("slice %s is out of range for length %d", slice, length), pos)
+		}
+		return e.replaceSlice(container, start, end, 1, value, pos)
+	}
 
-		// Update the nested structure
-		err := e.updateNestedStructure(rootVarName, path, newObj)
+	switch obj := container.(type) {
+	case map[string]interface{}:
+		key, ok := step.(string)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("map key must be a string, got %s", valueTypeName(step)), pos)
+		}
+		child, err := e.pathChild(obj[key], rest, key, pos)
 		if err != nil {
 			return nil, err
 		}
-		return rightValue, nil
+		if child, err = e.assignAtPath(child, rest, value, pos); err != nil {
+			return nil, err
+		}
+		updated := make(map[string]interface{}, len(obj)+1)
+		for k, v := range obj {
+			updated[k] = v
+		}
+		updated[key] = child
+		return updated, nil
 
 	case []interface{}:
-		// Array assignment with expansion
-		var idx float64
-		switch i := finalIndex.(type) {
-		case float64:
-			idx = i
-		case int64:
-			idx = float64(i)
-		case int:
-			idx = float64(i)
-		default:
-			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index must be number, got %T", finalIndex), ast.Position{})
+		index, ok := toInt64(step)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index must be a number, got %s", valueTypeName(step)), pos)
 		}
-		intIdx := int(idx)
-
-		// Expand array if index is beyond current length
-		newObj := make([]interface{}, len(obj))
-		copy(newObj, obj)
-
-		if intIdx >= len(newObj) {
-			// Expand array to accommodate the new index
-			expanded := make([]interface{}, intIdx+1)
-			copy(expanded, newObj)
-			// Fill gaps with nil
-			for i := len(newObj); i < intIdx; i++ {
-				expanded[i] = nil
-			}
-			newObj = expanded
+		if index < 0 {
+			return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index %d cannot be negative", index), pos)
 		}
-
-		newObj[intIdx] = rightValue
-
-		// Update the nested structure
-		err := e.updateNestedStructure(rootVarName, path, newObj)
+		size := len(obj)
+		if int(index) >= size {
+			size = int(index) + 1
+		}
+		updated := make([]interface{}, size)
+		copy(updated, obj)
+		child, err := e.pathChild(updated[index], rest, fmt.Sprintf("[%d]", index), pos)
 		if err != nil {
 			return nil, err
 		}
-		return rightValue, nil
+		if updated[index], err = e.assignAtPath(child, rest, value, pos); err != nil {
+			return nil, err
+		}
+		return updated, nil
 
 	default:
-		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("cannot assign to indexed expression of type %T", current), ast.Position{})
+		return nil, errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("cannot assign %v into %s", step, valueTypeName(container)), pos)
 	}
 }
 
-// updateNestedStructure updates a nested structure in the runtime
-func (e *ExecutionEngine) updateNestedStructure(rootVarName *VariableName, path []interface{}, newValue interface{}) error {
-	// Get the root object from runtime
-	rootObject, err := e.getVariableFromRuntime(rootVarName.language, rootVarName.name)
-	if err != nil {
-		return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("failed to get root variable %s.%s: %v", rootVarName.language, rootVarName.name, err), ast.Position{})
+// pathChild returns the value at a step of the path, or creates the
+// container the rest of the path goes into when the step is missing
+func (e *ExecutionEngine) pathChild(child interface{}, rest []interface{}, name string, pos ast.Position) (interface{}, error) {
+	if child != nil || len(rest) == 0 {
+		return child, nil
 	}
-
-	// Navigate through the path and update the structure
-	current := rootObject
-	for i, pathIndex := range path {
-		switch obj := current.(type) {
-		case map[string]interface{}:
-			key, ok := pathIndex.(string)
-			if !ok {
-				return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("nested path index must be string, got %T", pathIndex), ast.Position{})
-			}
-
-			if i == len(path)-1 {
-				// This is the last level - update the value
-				obj[key] = newValue
-			} else {
-				// Navigate deeper
-				if nextObj, exists := obj[key]; exists {
-					current = nextObj
-				} else {
-					return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("path %v does not exist in object", path[:i+1]), ast.Position{})
-				}
-			}
-
-		case []interface{}:
-			var idx float64
-			switch i := pathIndex.(type) {
-			case float64:
-				idx = i
-			case int64:
-				idx = float64(i)
-			case int:
-				idx = float64(i)
-			default:
-				return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index must be number, got %T", pathIndex), ast.Position{})
-			}
-			intIdx := int(idx)
-
-			if i == len(path)-1 {
-				// This is the last level - update the array
-				if intIdx < len(obj) {
-					obj[intIdx] = newValue
-				} else {
-					return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("array index %d out of bounds", intIdx), ast.Position{})
-				}
-			} else {
-				// Navigate deeper
-				if intIdx < len(obj) && obj[intIdx] != nil {
-					current = obj[intIdx]
-				} else {
-					return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("path %v does not exist in array", path[:i+1]), ast.Position{})
-				}
-			}
-
-		default:
-			return errors.NewUserErrorWithASTPos("INDEXED_ASSIGNMENT_ERROR", fmt.Sprintf("cannot navigate nested path at level %d, type %T", i, current), ast.Position{})
-		}
+	if err := e.coerce(StrictAutovivification, pos, "assignment creates the missing '%s'", name); err != nil {
+		return nil, err
 	}
+	return newPathContainer(rest[0]), nil
+}
 
-	// Save the modified root object back to runtime
-	err = e.setVariableInRuntimeWithError(rootVarName.language, rootVarName.name, rootObject)
-	if err != nil {
-		return err
+// newPathContainer creates the container a path step goes into: an array
+// for an index, a map for a key
+func newPathContainer(step interface{}) interface{} {
+	if _, ok := toInt64(step); ok {
+		return []interface{}{}
 	}
-
-	return nil
+	return map[string]interface{}{}
 }
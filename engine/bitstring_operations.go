@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"math/big"
 	"strings"
 
 	"funterm/errors"
@@ -63,6 +64,161 @@ func (e *ExecutionEngine) executeBitstringExpression(expr *ast.BitstringExpressi
 	return bitstringObject, nil
 }
 
+// executeBitSizeFunction implements bit_size(x) and byte_size(x) for
+// bitstrings, strings and arrays of them, like Erlang's BIFs. Arrays count as
+// the concatenation of their elements, with integers 0-255 taken as one byte.
+// byte_size() rounds a trailing partial byte up.
+func (e *ExecutionEngine) executeBitSizeFunction(function string, args []interface{}) (interface{}, error) {
+	code := strings.ToUpper(function)
+	if len(args) != 1 {
+		return nil, errors.NewUserError(code+"_ARGUMENT_ERROR", fmt.Sprintf("%s() requires exactly one argument", function))
+	}
+	bits, err := bitSizeOf(args[0])
+	if err != nil {
+		return nil, errors.NewUserError(code+"_TYPE_ERROR", fmt.Sprintf("%s(): %v", function, err))
+	}
+	if function == "byte_size" {
+		return (bits + 7) / 8, nil
+	}
+	return bits, nil
+}
+
+// bitSizeOf returns the size of a value in bits
+func bitSizeOf(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case *shared.BitstringObject:
+		return int64(v.Len()), nil
+	case string:
+		return int64(len(v)) * 8, nil
+	case []byte:
+		return int64(len(v)) * 8, nil
+	case []interface{}:
+		total := int64(0)
+		for i, element := range v {
+			if n, ok := toInt64(element); ok {
+				if _, isBool := element.(bool); !isBool && n >= 0 && n <= 255 {
+					total += 8
+					continue
+				}
+				return 0, fmt.Errorf("element %d is %v, not a byte (0-255)", i+1, element)
+			}
+			bits, err := bitSizeOf(element)
+			if err != nil {
+				return 0, fmt.Errorf("element %d: %v", i+1, err)
+			}
+			total += bits
+		}
+		return total, nil
+	}
+	return 0, fmt.Errorf("expected a bitstring, string or array, got %s", orderTypeName(value))
+}
+
+// resolveSizeCalls evaluates builtin calls in the size expressions of a
+// pattern, e.g. <<head:byte_size(magic)/binary, ...>>, before matching: the
+// matcher only understands arithmetic on variables. Calls see the variables
+// in scope, not the ones the pattern binds. The pattern is copied, not changed.
+func (fa *FunbitAdapter) resolveSizeCalls(pattern *ast.BitstringExpression) (*ast.BitstringExpression, error) {
+	var resolved *ast.BitstringExpression
+	for i := range pattern.Segments {
+		segment := &pattern.Segments[i]
+		if segment.Size == nil || !usesCall(segment.Size) {
+			continue
+		}
+		size, err := fa.replaceCalls(segment.Size)
+		if err != nil {
+			return nil, err
+		}
+		if resolved == nil {
+			copied := *pattern
+			copied.Segments = append([]ast.BitstringSegment(nil), pattern.Segments...)
+			resolved = &copied
+		}
+		target := &resolved.Segments[i]
+		target.Size = size
+		if _, isLiteral := size.(*ast.NumberLiteral); isLiteral {
+			target.IsDynamicSize = false
+			target.SizeExpression = nil
+			continue
+		}
+		text, err := ast.ExpressionToString(size)
+		if err != nil {
+			return nil, err
+		}
+		sizeExpression := ast.NewSizeExpression()
+		sizeExpression.Pos = size.Position()
+		sizeExpression.ExprType = "expression"
+		sizeExpression.Expression = size
+		sizeExpression.Variable = text
+		target.SizeExpression = sizeExpression
+		target.IsDynamicSize = true
+	}
+	if resolved == nil {
+		return pattern, nil
+	}
+	return resolved, nil
+}
+
+// usesCall reports whether a size expression contains a builtin call
+func usesCall(expr ast.Expression) bool {
+	switch v := expr.(type) {
+	case *ast.BuiltinFunctionCall:
+		return true
+	case *ast.BinaryExpression:
+		return usesCall(v.Left) || usesCall(v.Right)
+	case *ast.UnaryExpression:
+		return usesCall(v.Right)
+	case *ast.NestedExpression:
+		return usesCall(v.Inner)
+	}
+	return false
+}
+
+// replaceCalls returns a copy of a size expression with every builtin call
+// replaced by its integer result
+func (fa *FunbitAdapter) replaceCalls(expr ast.Expression) (ast.Expression, error) {
+	switch v := expr.(type) {
+	case *ast.BuiltinFunctionCall:
+		value, err := fa.engine.evaluateExpression(v)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := schemaInteger(value)
+		if !ok {
+			return nil, fmt.Errorf("size %s() returned %s, not an integer", v.Function, orderTypeName(value))
+		}
+		return &ast.NumberLiteral{IntValue: new(big.Int).Set(n), IsInt: true, Pos: v.Position()}, nil
+	case *ast.BinaryExpression:
+		left, err := fa.replaceCalls(v.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := fa.replaceCalls(v.Right)
+		if err != nil {
+			return nil, err
+		}
+		copied := *v
+		copied.Left, copied.Right = left, right
+		return &copied, nil
+	case *ast.UnaryExpression:
+		right, err := fa.replaceCalls(v.Right)
+		if err != nil {
+			return nil, err
+		}
+		copied := *v
+		copied.Right = right
+		return &copied, nil
+	case *ast.NestedExpression:
+		inner, err := fa.replaceCalls(v.Inner)
+		if err != nil {
+			return nil, err
+		}
+		copied := *v
+		copied.Inner = inner
+		return &copied, nil
+	}
+	return expr, nil
+}
+
 // formatBitstringOutput formats a bitstring object as a bitstring in Erlang style
 func (e *ExecutionEngine) formatBitstringOutput(bitstringObj interface{}) string {
 	// Check if we have a BitstringObject
@@ -72,7 +228,7 @@ func (e *ExecutionEngine) formatBitstringOutput(bitstringObj interface{}) string
 		}
 
 		// Convert to bytes and format
-		bytes := bitstringObj.BitString.ToBytes()
+		bytes := bitstringObj.Bytes()
 		var result strings.Builder
 		result.WriteString("<<")
 
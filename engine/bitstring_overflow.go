@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// What happens to an integer that doesn't fit its bitstring segment
+const (
+	OverflowTruncate = "truncate" // keep the low bits, <<300:8>> is <<44>> (default)
+	OverflowSaturate = "saturate" // clamp to the nearest value that fits, <<300:8>> is <<255>>
+	OverflowError    = "error"    // fail the construction
+)
+
+var overflowModes = []string{OverflowTruncate, OverflowSaturate, OverflowError}
+
+// SetSegmentOverflow sets the overflow mode of segments without an
+// overflow: specifier; empty means truncate
+func (e *ExecutionEngine) SetSegmentOverflow(mode string) error {
+	if err := ValidateSegmentOverflow(mode); err != nil {
+		return err
+	}
+	e.segmentOverflow = mode
+	return nil
+}
+
+// ValidateSegmentOverflow checks an overflow mode from the config or a specifier
+func ValidateSegmentOverflow(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, known := range overflowModes {
+		if mode == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown overflow mode '%s', expected %s", mode, strings.Join(overflowModes, ", "))
+}
+
+// cutOverflow splits the overflow:mode ending a specifier, as in
+// integer-overflow:error, from the rest of it
+func cutOverflow(spec string) (rest, mode string, found bool) {
+	i := strings.LastIndex(spec, "overflow:")
+	if i < 0 || (i > 0 && spec[i-1] != '-') {
+		return spec, "", false
+	}
+	return strings.TrimSuffix(spec[:i], "-"), strings.TrimSpace(spec[i+len("overflow:"):]), true
+}
+
+// integerValue returns an integer segment value as a big.Int
+func integerValue(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case *big.Int:
+		if v != nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// segmentRange returns the smallest and largest integer of a segment
+func segmentRange(size uint, signed bool) (*big.Int, *big.Int) {
+	if signed {
+		half := new(big.Int).Lsh(big.NewInt(1), size-1)
+		return new(big.Int).Neg(half), new(big.Int).Sub(half, big.NewInt(1))
+	}
+	return big.NewInt(0), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), size), big.NewInt(1))
+}
+
+// signedness names the kind of an integer segment in messages
+func signedness(signed bool) string {
+	if signed {
+		return "signed"
+	}
+	return "unsigned"
+}
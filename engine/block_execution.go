@@ -89,6 +89,13 @@ func (e *ExecutionEngine) executeBlockStatement(block *ast.BlockStatement) (inte
 				if e.verbose {
 					fmt.Printf("DEBUG: executeBlockStatement - skipping output collection from variable assignment\n")
 				}
+				// Вывод блока кода, присвоенного переменной, показываем на своём месте
+				if output := e.takeCodeBlockOutput(); output != "" {
+					if collectedOutput.Len() > 0 {
+						collectedOutput.WriteString("\n")
+					}
+					collectedOutput.WriteString(output)
+				}
 			} else if _, isExpressionAssignment := stmt.(*ast.ExpressionAssignment); isExpressionAssignment {
 				if e.verbose {
 					fmt.Printf("DEBUG: executeBlockStatement - skipping output collection from expression assignment\n")
@@ -130,8 +137,8 @@ func (e *ExecutionEngine) executeBlockStatement(block *ast.BlockStatement) (inte
 						// For pre-formatted results (like from print function), use the value directly
 						outputStr = preFormatted.Value
 					} else {
-						// Convert other types to string (for function calls that return values)
-						outputStr = fmt.Sprintf("%v", lastResult)
+						// Other values are shown the way print shows them, bitstrings as <<...>>
+						outputStr = shared.FormatValueForDisplay(lastResult)
 					}
 
 					if e.verbose {
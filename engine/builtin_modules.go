@@ -0,0 +1,35 @@
+package engine
+
+// builtinModuleFunc runs function of a builtin module called as module.function(args)
+type builtinModuleFunc func(function string, args []interface{}) (interface{}, error)
+
+// lookupBuiltinModule returns the builtin module with the given name, such as
+// gzip or aes. Variables and runtimes with the same name take precedence.
+func (e *ExecutionEngine) lookupBuiltinModule(name string) (builtinModuleFunc, bool) {
+	if codec, ok := compressionCodecs[name]; ok {
+		return func(function string, args []interface{}) (interface{}, error) {
+			return callCompressionFunction(name, codec, function, args)
+		}, true
+	}
+	switch name {
+	case "aes":
+		return callAESFunction, true
+	case "rsa":
+		return callRSAFunction, true
+	case "keys":
+		return callKeysFunction, true
+	case "pcap":
+		return callPcapFunction, true
+	case "asn1":
+		return callASN1Function, true
+	case "sh":
+		return e.callShFunction, true
+	case "table":
+		return callTableFunction, true
+	case "gen":
+		return e.callGenFunction, true
+	case "store":
+		return e.callStoreFunction, true
+	}
+	return nil, false
+}
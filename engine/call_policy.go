@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// executeLanguageCallWithPolicy applies the call's result policy (!nowait, !raw)
+// around the regular language call execution
+func (e *ExecutionEngine) executeLanguageCallWithPolicy(call *ast.LanguageCall) (interface{}, error) {
+	// The call itself always runs with the default policy
+	plain := *call
+	plain.Result = ast.ResultWait
+
+	switch call.Result {
+	case ast.ResultNoWait:
+		e.startNoWaitCall(&plain)
+		return nil, nil
+	case ast.ResultRaw:
+		result, err := e.executeLanguageCallNew(&plain)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, errors.NewUserErrorWithASTPos("RAW_RESULT_ERROR", fmt.Sprintf("cannot encode result of %s.%s as JSON: %v", call.Language, call.Function, err), call.Position())
+		}
+		return string(data), nil
+	default:
+		return nil, errors.NewUserErrorWithASTPos("UNKNOWN_RESULT_POLICY", fmt.Sprintf("unknown call option '!%s'", call.Result), call.Position())
+	}
+}
+
+// outputCapturer is implemented by runtimes that buffer what a call prints
+type outputCapturer interface {
+	GetCapturedOutput() string
+}
+
+// startNoWaitCall runs the call on a background copy of the engine and discards
+// its result and printed output; a failure is reported as a warning since
+// nobody waits for it
+func (e *ExecutionEngine) startNoWaitCall(call *ast.LanguageCall) {
+	backgroundEngine := e.newBackgroundEngine(e.cloneCurrentScope(), e.cloneSharedVariables())
+	label := call.Language + "." + call.Function
+
+	e.pendingCalls.Add(1)
+	go func() {
+		defer e.pendingCalls.Done()
+		_, err := backgroundEngine.executeLanguageCallNew(call)
+		// Drain the captured output so it does not show up in the next call's output
+		if rt, rtErr := backgroundEngine.getRuntimeByName(call.Language); rtErr == nil {
+			if capturer, ok := rt.(outputCapturer); ok {
+				capturer.GetCapturedOutput()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s !nowait failed: %v\n", label, err)
+		} else if e.verbose {
+			fmt.Printf("DEBUG: %s !nowait finished, result discarded\n", label)
+		}
+	}()
+}
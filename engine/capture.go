@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"funterm/errors"
+	"funterm/shared"
+	"go-parser/pkg/ast"
+)
+
+// executeCaptureExpression runs the body of capture { ... } and gives the
+// text it would have printed, lines joined by newlines
+func (e *ExecutionEngine) executeCaptureExpression(capture *ast.CaptureExpression) (interface{}, error) {
+	if err := e.checkBuiltin("capture", capture.Pos); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, stmt := range capture.Body.Statements {
+		output, err := e.captureStatement(stmt)
+		if err != nil {
+			return nil, err
+		}
+		if output != "" {
+			lines = append(lines, output)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// executeRedirectStatement runs call() > "file" or call() >> "file": what the
+// call would have printed goes to the file instead. '>' replaces the file,
+// '>>' appends to it; the file is opened before the call, as a shell does
+func (e *ExecutionEngine) executeRedirectStatement(statement *ast.RedirectStatement) (interface{}, error) {
+	if err := e.checkBuiltin("redirect", statement.Pos); err != nil {
+		return nil, err
+	}
+	target, err := e.convertExpressionToValue(statement.Target)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := target.(string)
+	if !ok || path == "" {
+		return nil, errors.NewUserErrorWithASTPos("REDIRECT_ERROR", fmt.Sprintf("redirect expects a file name, got %s", orderTypeName(target)), statement.Target.Position())
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if statement.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("REDIRECT_ERROR", fmt.Sprintf("cannot open %s: %v", path, err), statement.Pos)
+	}
+	defer file.Close()
+
+	output, err := e.captureStatement(statement.Statement)
+	if err != nil {
+		return nil, err
+	}
+	if output != "" {
+		if _, err := fmt.Fprintln(file, output); err != nil {
+			return nil, errors.NewUserErrorWithASTPos("REDIRECT_ERROR", fmt.Sprintf("cannot write %s: %v", path, err), statement.Pos)
+		}
+	}
+	return nil, nil
+}
+
+// captureStatement runs a statement with stdout going to a pipe and gives
+// what it wrote there, such as the output of a loop, followed by the text
+// of its result. As in a script, the results of assignments and background
+// calls aren't output. Stdout belongs to the process, so pipeline stages
+// printing at the same time end up in the text too
+func (e *ExecutionEngine) captureStatement(stmt ast.Statement) (string, error) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return "", errors.NewSystemError("CAPTURE_FAILED", fmt.Sprintf("cannot capture output: %v", err))
+	}
+	var printed bytes.Buffer
+	copied := make(chan struct{})
+	go func() {
+		io.Copy(&printed, reader)
+		close(copied)
+	}()
+	stdout := os.Stdout
+	os.Stdout = writer
+	result, err := func() (interface{}, error) {
+		defer func() {
+			os.Stdout = stdout
+			writer.Close()
+			<-copied
+			reader.Close()
+		}()
+		return e.executeStatement(stmt)
+	}()
+	if err != nil {
+		return "", err
+	}
+
+	switch s := stmt.(type) {
+	case *ast.VariableAssignment:
+		result = e.takeCodeBlockOutput()
+	case *ast.ExpressionAssignment:
+		result = nil
+	case *ast.LanguageCallStatement:
+		if s.IsBackground {
+			result = nil
+		}
+	}
+	var parts []string
+	for _, text := range []string{printed.String(), outputText(result)} {
+		if text = strings.TrimSuffix(text, "\n"); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// outputText is what the result of a statement shows when printed: text as
+// is, other values formatted for display and nothing for nil
+func outputText(result interface{}) string {
+	switch output := result.(type) {
+	case nil:
+		return ""
+	case string:
+		return output
+	case *shared.PreFormattedResult:
+		return output.Value
+	}
+	return shared.FormatValueForDisplay(result)
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// Channel is the value returned by channel(). It passes values between the
+// stages of a pipeline and other parts of a script running at the same time
+type Channel struct {
+	id     int64
+	ch     chan interface{}
+	mu     sync.Mutex
+	closed bool
+}
+
+// channelIDs numbers channels for display
+var channelIDs int64
+
+// String is used when the channel itself is displayed
+func (c *Channel) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := ""
+	if cap(c.ch) > 0 {
+		state = fmt.Sprintf(", %d of %d queued", len(c.ch), cap(c.ch))
+	}
+	if c.closed {
+		state += ", closed"
+	}
+	return fmt.Sprintf("<channel %d%s>", c.id, state)
+}
+
+// isClosed reports whether close() was called on the channel
+func (c *Channel) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// executeChannelFunction creates a channel: channel() hands each value
+// straight to a receiver, channel(n) queues up to n values
+func (e *ExecutionEngine) executeChannelFunction(args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, errors.NewUserError("CHANNEL_ERROR", "channel() accepts at most one argument, the number of values to queue")
+	}
+	size := int64(0)
+	if len(args) == 1 {
+		n, ok := toInt64(args[0])
+		if f, isFloat := args[0].(float64); isFloat && f != float64(n) {
+			ok = false
+		}
+		if !ok || n < 0 {
+			return nil, errors.NewUserError("CHANNEL_ERROR", fmt.Sprintf("channel() size must be a whole number of at least 0, got %v", args[0]))
+		}
+		size = n
+	}
+	return &Channel{id: atomic.AddInt64(&channelIDs, 1), ch: make(chan interface{}, size)}, nil
+}
+
+// executeSendFunction runs send(channel, value), which waits until a
+// receiver takes the value or there is room for it in the queue
+func (e *ExecutionEngine) executeSendFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("CHANNEL_ERROR", "send() requires a channel and a value")
+	}
+	channel, err := channelArgument("send", args[0])
+	if err != nil {
+		return nil, err
+	}
+	_, _, err = e.waitChannels([]reflect.SelectCase{sendCase(channel, args[1])}, []*Channel{channel})
+	return nil, err
+}
+
+// executeRecvFunction runs recv(channel), which waits for a value. A closed
+// channel gives the values still queued in it, then nil
+func (e *ExecutionEngine) executeRecvFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("CHANNEL_ERROR", "recv() requires a channel")
+	}
+	channel, err := channelArgument("recv", args[0])
+	if err != nil {
+		return nil, err
+	}
+	_, value, err := e.waitChannels([]reflect.SelectCase{recvCase(channel)}, []*Channel{channel})
+	return value, err
+}
+
+// executeCloseFunction runs close(channel): receivers get nil once the queue
+// is empty, and sending fails
+func (e *ExecutionEngine) executeCloseFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("CHANNEL_ERROR", "close() requires a channel")
+	}
+	channel, err := channelArgument("close", args[0])
+	if err != nil {
+		return nil, err
+	}
+	channel.mu.Lock()
+	defer channel.mu.Unlock()
+	if channel.closed {
+		return nil, errors.NewUserError("CHANNEL_ERROR", fmt.Sprintf("%s is already closed", channel.describe()))
+	}
+	channel.closed = true
+	close(channel.ch)
+	return nil, nil
+}
+
+// executeSelectStatement waits until one of the cases of a select can go
+// ahead and runs its body. With a default case it doesn't wait, and with a
+// timeout it waits no longer than that. A value received into a variable is
+// seen only in the body of its case
+func (e *ExecutionEngine) executeSelectStatement(statement *ast.SelectStatement) (interface{}, error) {
+	cases := make([]reflect.SelectCase, len(statement.Cases))
+	channels := make([]*Channel, len(statement.Cases))
+	for i, selectCase := range statement.Cases {
+		switch selectCase.Kind {
+		case "recv", "send":
+			value, err := e.convertExpressionToValue(selectCase.Channel)
+			if err != nil {
+				return nil, err
+			}
+			channel, ok := value.(*Channel)
+			if !ok {
+				return nil, errors.NewUserErrorWithASTPos("CHANNEL_ERROR", fmt.Sprintf("select %s expects a channel, got %s", selectCase.Kind, orderTypeName(value)), selectCase.Pos)
+			}
+			channels[i] = channel
+			if selectCase.Kind == "recv" {
+				cases[i] = recvCase(channel)
+				break
+			}
+			if value, err = e.convertExpressionToValue(selectCase.Value); err != nil {
+				return nil, err
+			}
+			cases[i] = sendCase(channel, value)
+		case "timeout":
+			value, err := e.convertExpressionToValue(selectCase.Value)
+			if err != nil {
+				return nil, err
+			}
+			timeout, ok := durationValue(value)
+			if !ok {
+				return nil, errors.NewUserErrorWithASTPos("CHANNEL_ERROR", fmt.Sprintf("select timeout expects a duration such as 500ms, 2s or \"1m\", got %v", value), selectCase.Pos)
+			}
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)}
+		case "default":
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectDefault}
+		}
+	}
+
+	chosen, value, err := e.waitChannels(cases, channels)
+	if err != nil {
+		if channelErr, ok := errors.AsExecutionError(err); ok && channelErr.Code == "CHANNEL_ERROR" {
+			return nil, errors.NewUserErrorWithASTPos("CHANNEL_ERROR", channelErr.Message, statement.Cases[chosen].Pos)
+		}
+		return nil, err
+	}
+	selectCase := statement.Cases[chosen]
+	if selectCase.Variable != "" {
+		return e.executeStatementWithBindings(selectCase.Body, map[string]interface{}{selectCase.Variable: value})
+	}
+	return e.executeBlockStatement(selectCase.Body)
+}
+
+// waitChannels waits until one of the cases can go ahead, but no longer
+// than the time limit of the command. channels holds the channel of each
+// case, nil for cases without one. Sending on a closed channel is an error
+func (e *ExecutionEngine) waitChannels(cases []reflect.SelectCase, channels []*Channel) (chosen int, value interface{}, err error) {
+	for i, channel := range channels {
+		if channel != nil && cases[i].Dir == reflect.SelectSend && channel.isClosed() {
+			return i, nil, errors.NewUserError("CHANNEL_ERROR", fmt.Sprintf("can't send on %s, it is closed", channel.describe()))
+		}
+	}
+	if !e.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(e.deadline))
+		defer timer.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+	}
+
+	// The channel can still be closed while a send waits on it
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			for i, channel := range channels {
+				if channel != nil && cases[i].Dir == reflect.SelectSend && channel.isClosed() {
+					chosen, err = i, errors.NewUserError("CHANNEL_ERROR", fmt.Sprintf("can't send on %s, it was closed while waiting", channel.describe()))
+					return
+				}
+			}
+			panic(recovered)
+		}
+	}()
+	chosen, received, _ := reflect.Select(cases)
+	if chosen == len(channels) {
+		return 0, nil, e.checkDeadline()
+	}
+	if received.IsValid() && cases[chosen].Dir == reflect.SelectRecv && channels[chosen] != nil {
+		value = received.Interface()
+	}
+	return chosen, value, nil
+}
+
+// channelArgument checks that a builtin got a channel
+func channelArgument(function string, value interface{}) (*Channel, error) {
+	channel, ok := value.(*Channel)
+	if !ok {
+		return nil, errors.NewUserError("CHANNEL_ERROR", fmt.Sprintf("%s() expects a channel, got %s", function, orderTypeName(value)))
+	}
+	return channel, nil
+}
+
+func sendCase(channel *Channel, value interface{}) reflect.SelectCase {
+	send := reflect.ValueOf(&value).Elem()
+	return reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(channel.ch), Send: send}
+}
+
+func recvCase(channel *Channel) reflect.SelectCase {
+	return reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channel.ch)}
+}
+
+// describe names the channel in errors
+func (c *Channel) describe() string {
+	return fmt.Sprintf("channel %d", c.id)
+}
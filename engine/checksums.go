@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash/adler32"
+	"hash/crc32"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// defaultCRC16Polynomial is the CCITT polynomial; with the zero initial value
+// used by crc16() it gives CRC-16/XMODEM
+const defaultCRC16Polynomial = 0x1021
+
+// wholeBytes returns the bytes of a bitstring or string argument, which must be byte-aligned
+func wholeBytes(function string, value interface{}) ([]byte, error) {
+	bits, err := binaryArgument(function, value)
+	if err != nil {
+		return nil, err
+	}
+	if bits.Len()%8 != 0 {
+		return nil, errors.NewUserError("BINARY_ARGUMENT_ERROR", fmt.Sprintf("%s() requires whole bytes, got %d bits", function, bits.Len()))
+	}
+	return bits.Bytes(), nil
+}
+
+// executeChecksumFunction implements crc32(), adler32(), md5(), sha1() and
+// sha256(). CRCs are returned as numbers, digests as bitstrings.
+func (e *ExecutionEngine) executeChecksumFunction(name string, args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("CHECKSUM_ARGUMENT_ERROR", fmt.Sprintf("%s() requires exactly one argument", name))
+	}
+	data, err := wholeBytes(name, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "crc32":
+		return int64(crc32.ChecksumIEEE(data)), nil
+	case "adler32":
+		return int64(adler32.Checksum(data)), nil
+	case "md5":
+		sum := md5.Sum(data)
+		return shared.NewBitstringObjectFromBytes(sum[:]), nil
+	case "sha1":
+		sum := sha1.Sum(data)
+		return shared.NewBitstringObjectFromBytes(sum[:]), nil
+	case "sha256":
+		sum := sha256.Sum256(data)
+		return shared.NewBitstringObjectFromBytes(sum[:]), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum function: %s", name)
+}
+
+// executeCRC16Function implements crc16(bits, poly?). The CRC is computed most
+// significant bit first from a zero initial value over every bit of the input,
+// so bitstrings that are not whole bytes are accepted.
+func (e *ExecutionEngine) executeCRC16Function(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("CHECKSUM_ARGUMENT_ERROR", "crc16() requires a bitstring and an optional polynomial")
+	}
+	bits, err := binaryArgument("crc16", args[0])
+	if err != nil {
+		return nil, err
+	}
+	poly := int64(defaultCRC16Polynomial)
+	if len(args) == 2 {
+		n, ok := toInt64(args[1])
+		if !ok || n <= 0 || n > 0xFFFF {
+			return nil, errors.NewUserError("CHECKSUM_ARGUMENT_ERROR", fmt.Sprintf("crc16() polynomial must be an integer in 1..0xFFFF, got %v", args[1]))
+		}
+		poly = n
+	}
+	return int64(crc16(bits.Bytes(), bits.Len(), uint16(poly))), nil
+}
+
+// crc16 computes a non-reflected CRC-16 over the first bitLength bits of data
+func crc16(data []byte, bitLength int, poly uint16) uint16 {
+	var crc uint16
+	for i := 0; i < bitLength; i++ {
+		top := crc>>15 ^ uint16(bitAt(data, i))
+		crc <<= 1
+		if top == 1 {
+			crc ^= poly
+		}
+	}
+	return crc
+}
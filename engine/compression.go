@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	"funterm/errors"
+	"funterm/shared"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultMaxDecompressedSize is how many bytes decompress() returns at most
+// unless the call gives another limit, so a small bomb can't fill the memory
+const defaultMaxDecompressedSize = 256 << 20
+
+// compressionCodec compresses and decompresses byte payloads; level 0 means
+// the codec's default level, limit is the largest output decompress accepts
+type compressionCodec struct {
+	compress   func(data []byte, level int) ([]byte, error)
+	decompress func(data []byte, limit int64) ([]byte, error)
+}
+
+// compressionCodecs are the modules callable as gzip.compress(bits), zlib.decompress(bits), ...
+var compressionCodecs = map[string]compressionCodec{
+	"gzip": {
+		compress: func(data []byte, level int) ([]byte, error) {
+			var buf bytes.Buffer
+			w, err := gzip.NewWriterLevel(&buf, flateLevel(level))
+			if err != nil {
+				return nil, err
+			}
+			return finishWriter(&buf, w, data)
+		},
+		decompress: func(data []byte, limit int64) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readLimited(r, limit)
+		},
+	},
+	"zlib": {
+		compress: func(data []byte, level int) ([]byte, error) {
+			var buf bytes.Buffer
+			w, err := zlib.NewWriterLevel(&buf, flateLevel(level))
+			if err != nil {
+				return nil, err
+			}
+			return finishWriter(&buf, w, data)
+		},
+		decompress: func(data []byte, limit int64) ([]byte, error) {
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readLimited(r, limit)
+		},
+	},
+	"zstd": {
+		compress: func(data []byte, level int) ([]byte, error) {
+			options := []zstd.EOption{}
+			if level != 0 {
+				if level < 1 || level > 22 {
+					return nil, fmt.Errorf("invalid compression level: %d", level)
+				}
+				options = append(options, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+			}
+			w, err := zstd.NewWriter(nil, options...)
+			if err != nil {
+				return nil, err
+			}
+			defer w.Close()
+			return w.EncodeAll(data, nil), nil
+		},
+		decompress: func(data []byte, limit int64) ([]byte, error) {
+			r, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return readLimited(r, limit)
+		},
+	},
+}
+
+// flateLevel maps level 0 to the default compression of gzip and zlib
+func flateLevel(level int) int {
+	if level == 0 {
+		return gzip.DefaultCompression
+	}
+	return level
+}
+
+// finishWriter writes data through a compressing writer and returns the output
+func finishWriter(buf *bytes.Buffer, w io.WriteCloser, data []byte) ([]byte, error) {
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readLimited reads r to the end, failing once it yields more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// callCompressionFunction implements <codec>.compress(bits, level?) and
+// <codec>.decompress(bits, max_bytes?); both return bitstrings
+func callCompressionFunction(module string, codec compressionCodec, method string, args []interface{}) (interface{}, error) {
+	name := module + "." + method
+	switch method {
+	case "compress":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errors.NewUserError("COMPRESSION_ARGUMENT_ERROR", fmt.Sprintf("%s() requires a bitstring and an optional level", name))
+		}
+	case "decompress":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errors.NewUserError("COMPRESSION_ARGUMENT_ERROR", fmt.Sprintf("%s() requires a bitstring and an optional size limit", name))
+		}
+	default:
+		return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("%s has no function '%s'; use compress or decompress", module, method))
+	}
+
+	data, err := wholeBytes(name, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	if method == "compress" {
+		level := 0
+		if len(args) == 2 {
+			n, ok := toInt64(args[1])
+			if !ok {
+				return nil, errors.NewUserError("COMPRESSION_ARGUMENT_ERROR", fmt.Sprintf("%s() level must be an integer, got %v", name, args[1]))
+			}
+			level = int(n)
+		}
+		result, err = codec.compress(data, level)
+	} else {
+		limit := int64(defaultMaxDecompressedSize)
+		if len(args) == 2 {
+			n, ok := toInt64(args[1])
+			if !ok || n < 0 {
+				return nil, errors.NewUserError("COMPRESSION_ARGUMENT_ERROR", fmt.Sprintf("%s() size limit must be a non-negative integer, got %v", name, args[1]))
+			}
+			limit = n
+		}
+		result, err = codec.decompress(data, limit)
+	}
+	if err != nil {
+		return nil, errors.NewUserError("COMPRESSION_ERROR", fmt.Sprintf("%s() failed: %v", name, err))
+	}
+	return shared.NewBitstringObjectFromBytes(result), nil
+}
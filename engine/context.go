@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// executeContextStatement runs the body of cd(dir) { ... } or
+// with_env(vars) { ... }. The working directory and the environment belong to
+// the whole process: builtins and sh.daemon() see the change, runtimes
+// started in the body inherit it and running ones are told by contextCode.
+// Afterwards the previous values come back, also when the body fails
+func (e *ExecutionEngine) executeContextStatement(statement *ast.ContextStatement) (interface{}, error) {
+	if err := e.checkBuiltin(statement.Function, statement.Pos); err != nil {
+		return nil, err
+	}
+	value, err := e.convertExpressionToValue(statement.Argument)
+	if err != nil {
+		return nil, err
+	}
+
+	var restore func()
+	if statement.Function == "cd" {
+		restore, err = e.enterDirectory(value)
+	} else {
+		restore, err = e.enterEnvironment(value)
+	}
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("CONTEXT_ERROR", fmt.Sprintf("%s() %v", statement.Function, err), statement.Pos)
+	}
+	defer restore()
+	return e.executeBlockStatement(statement.Body)
+}
+
+// enterDirectory makes dir, relative to the current one, the working
+// directory and returns the function going back
+func (e *ExecutionEngine) enterDirectory(value interface{}) (func(), error) {
+	dir, ok := value.(string)
+	if !ok || dir == "" {
+		return nil, fmt.Errorf("expects a directory, got %s", orderTypeName(value))
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	target := dir
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(previous, target)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%q is not a directory", dir)
+	}
+
+	change := func(dir string) error {
+		if err := os.Chdir(dir); err != nil {
+			return err
+		}
+		return e.tellRuntimes(func(language string) (string, bool) { return chdirCode(language, dir) })
+	}
+	if err := change(target); err != nil {
+		change(previous)
+		return nil, err
+	}
+	return func() { change(previous) }, nil
+}
+
+// enterEnvironment sets the variables of an object, a nil value removes the
+// variable, and returns the function restoring the previous values
+func (e *ExecutionEngine) enterEnvironment(value interface{}) (func(), error) {
+	vars, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expects an object of variables, got %s", orderTypeName(value))
+	}
+	changes := make(map[string]*string, len(vars))
+	previous := make(map[string]*string, len(vars))
+	for name, v := range vars {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, fmt.Errorf("%q is not a valid variable name", name)
+		}
+		text, ok := envValue(v)
+		if !ok {
+			return nil, fmt.Errorf("value of %s must be a string, number, boolean or nil, got %s", name, orderTypeName(v))
+		}
+		changes[name] = text
+		if old, found := os.LookupEnv(name); found {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+	}
+
+	change := func(vars map[string]*string) error {
+		for name, value := range vars {
+			if value == nil {
+				os.Unsetenv(name)
+			} else if err := os.Setenv(name, *value); err != nil {
+				return err
+			}
+		}
+		return e.tellRuntimes(func(language string) (string, bool) { return envCode(language, vars) })
+	}
+	if err := change(changes); err != nil {
+		change(previous)
+		return nil, err
+	}
+	return func() { change(previous) }, nil
+}
+
+// envValue is the text of an environment variable, nil for a removed one
+func envValue(value interface{}) (*string, bool) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case string:
+		text = v
+	case bool:
+		text = strconv.FormatBool(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		n, ok := toInt64(value)
+		if !ok {
+			return nil, false
+		}
+		text = strconv.FormatInt(n, 10)
+	}
+	return &text, true
+}
+
+// tellRuntimes runs the snippet of code for each ready runtime, like
+// set_seed(). Runtimes without a snippet, such as lua, run inside funterm
+// and see the change anyway
+func (e *ExecutionEngine) tellRuntimes(code func(language string) (string, bool)) error {
+	for _, rt := range e.runtimeManager.GetAllRuntimes() {
+		if !rt.IsReady() {
+			continue
+		}
+		snippet, ok := code(rt.GetName())
+		if !ok {
+			continue
+		}
+		if _, err := rt.Eval(snippet); err != nil {
+			return fmt.Errorf("failed in the %s runtime: %v", rt.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// chdirCode returns the snippet changing the working directory of a runtime
+func chdirCode(language, dir string) (string, bool) {
+	switch language {
+	case "python":
+		return "import os\nos.chdir(" + quoteJSON(dir) + ")", true
+	case "node":
+		return "void process.chdir(" + quoteJSON(dir) + ")", true
+	case "perl":
+		return "chdir(" + quotePerl(dir) + ") or die \"$!\\n\";", true
+	}
+	return "", false
+}
+
+// envCode returns the snippet setting and removing environment variables in
+// a runtime; a nil value removes the variable
+func envCode(language string, vars map[string]*string) (string, bool) {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	switch language {
+	case "python":
+		lines = append(lines, "import os")
+		for _, name := range names {
+			if value := vars[name]; value != nil {
+				lines = append(lines, fmt.Sprintf("os.environ[%s] = %s", quoteJSON(name), quoteJSON(*value)))
+			} else {
+				lines = append(lines, fmt.Sprintf("os.environ.pop(%s, None)", quoteJSON(name)))
+			}
+		}
+		return strings.Join(lines, "\n"), true
+	case "node":
+		for _, name := range names {
+			if value := vars[name]; value != nil {
+				lines = append(lines, fmt.Sprintf("process.env[%s] = %s", quoteJSON(name), quoteJSON(*value)))
+			} else {
+				lines = append(lines, fmt.Sprintf("delete process.env[%s]", quoteJSON(name)))
+			}
+		}
+		return "void (" + strings.Join(lines, ", ") + ")", true
+	case "perl":
+		for _, name := range names {
+			if value := vars[name]; value != nil {
+				lines = append(lines, fmt.Sprintf("$ENV{%s} = %s;", quotePerl(name), quotePerl(*value)))
+			} else {
+				lines = append(lines, fmt.Sprintf("delete $ENV{%s};", quotePerl(name)))
+			}
+		}
+		return strings.Join(lines, " "), true
+	}
+	return "", false
+}
+
+// quoteJSON quotes a string for Python and JavaScript, which both read JSON strings
+func quoteJSON(text string) string {
+	quoted, _ := json.Marshal(text)
+	return string(quoted)
+}
+
+// quotePerl quotes a string for Perl without interpolation
+func quotePerl(text string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(text) + "'"
+}
@@ -497,6 +497,10 @@ func (e *ExecutionEngine) executeWhileStatement(whileStmt *ast.WhileStatement) (
 			return nil, errors.NewUserErrorWithASTPos("EXECUTION_CANCELLED", "execution cancelled by user", whileStmt.Position())
 		default:
 		}
+		// An empty body never reaches the check in executeStatement
+		if err := e.checkDeadline(); err != nil {
+			return nil, err
+		}
 
 		// Evaluate the condition
 		conditionResult, err := e.evaluateExpression(whileStmt.Condition)
@@ -606,6 +610,18 @@ func (e *ExecutionEngine) executeContinueStatement(continueStmt *ast.ContinueSta
 	return nil, ErrContinue
 }
 
+// sliceIterator returns the items of a slice one at a time
+func sliceIterator(items []interface{}) func() (interface{}, bool, error) {
+	i := 0
+	return func() (interface{}, bool, error) {
+		if i >= len(items) {
+			return nil, false, nil
+		}
+		i++
+		return items[i-1], true, nil
+	}
+}
+
 // executeForInLoop executes a for-in loop (Python-style)
 func (e *ExecutionEngine) executeForInLoop(forLoop *ast.ForInLoopStatement) (interface{}, error) {
 	// Create a context for cancellation
@@ -620,18 +636,26 @@ func (e *ExecutionEngine) executeForInLoop(forLoop *ast.ForInLoopStatement) (int
 		return nil, errors.NewUserErrorWithASTPos("ITERABLE_EVAL_ERROR", fmt.Sprintf("failed to evaluate iterable: %v", err), forLoop.Iterable.Position())
 	}
 
-	// Convert iterable to a slice we can iterate over
-	var items []interface{}
+	// Convert iterable to a function returning one item at a time
+	var next func() (interface{}, bool, error)
 	switch v := iterableValue.(type) {
 	case []interface{}:
-		items = v
+		next = sliceIterator(v)
 	case map[string]interface{}:
 		// For maps, iterate over keys
+		var keys []interface{}
 		for key := range v {
-			items = append(items, key)
-		}
+			keys = append(keys, key)
+		}
+		next = sliceIterator(keys)
+	case *shared.RangeObject:
+		// Ranges produce their items as the loop reads them
+		next = v.Next()
+	case shared.Iterator:
+		// Iterators such as packet captures are read lazily
+		next = v.Next
 	default:
-		return nil, errors.NewUserErrorWithASTPos("INVALID_ITERABLE", "iterable must be an array or object", forLoop.Iterable.Position())
+		return nil, errors.NewUserErrorWithASTPos("INVALID_ITERABLE", "iterable must be an array, object, range or iterator", forLoop.Iterable.Position())
 	}
 
 	// Determine the language for the loop (same for all iterations)
@@ -640,8 +664,9 @@ func (e *ExecutionEngine) executeForInLoop(forLoop *ast.ForInLoopStatement) (int
 	// If language is not specified, infer it from the context
 	if tempLanguage == "" {
 		// Try to infer language from the iterable
+		// unless it names a variable, as in "for child in tree.children"
 		if variableRead, ok := forLoop.Iterable.(*ast.VariableRead); ok {
-			if variableRead.Variable.Language != "" {
+			if _, isVariable := e.getVariable(variableRead.Variable.Language); variableRead.Variable.Language != "" && !isVariable {
 				tempLanguage = variableRead.Variable.Language
 			}
 		}
@@ -662,7 +687,14 @@ func (e *ExecutionEngine) executeForInLoop(forLoop *ast.ForInLoopStatement) (int
 	// Iterate over items
 	var shouldBreak bool
 	var hasControlFlowStatement bool
-	for _, item := range items {
+	for {
+		item, ok, err := next()
+		if err != nil {
+			return nil, errors.NewUserErrorWithASTPos("ITERATION_ERROR", err.Error(), forLoop.Iterable.Position())
+		}
+		if !ok {
+			break
+		}
 		if e.verbose {
 			fmt.Printf("DEBUG: executeForInLoop - processing item: %v\n", item)
 		}
@@ -673,6 +705,9 @@ func (e *ExecutionEngine) executeForInLoop(forLoop *ast.ForInLoopStatement) (int
 			return nil, errors.NewUserErrorWithASTPos("EXECUTION_CANCELLED", "execution cancelled by user", forLoop.Position())
 		default:
 		}
+		if err := e.checkDeadline(); err != nil {
+			return nil, err
+		}
 
 		// Create a nested scope for each iteration to isolate variables
 		e.pushScope()
@@ -936,6 +971,9 @@ func (e *ExecutionEngine) executeNumericForLoop(forLoop *ast.NumericForLoopState
 			return nil, errors.NewUserErrorWithASTPos("EXECUTION_CANCELLED", "execution cancelled by user", forLoop.Position())
 		default:
 		}
+		if err := e.checkDeadline(); err != nil {
+			return nil, err
+		}
 
 		// Create a nested scope for each iteration to isolate variables
 		e.pushScope()
@@ -1110,6 +1148,12 @@ func (e *ExecutionEngine) executeCStyleForLoop(forLoop *ast.CStyleForLoopStateme
 			return nil, errors.NewUserErrorWithASTPos("EXECUTION_CANCELLED", "execution cancelled by user", forLoop.Position())
 		default:
 		}
+		if err := e.checkDeadline(); err != nil {
+			if hasBlockScope {
+				e.popScope()
+			}
+			return nil, err
+		}
 
 		// Check condition if present (if no condition, loop runs forever like for(;;))
 		if forLoop.Condition != nil {
@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	// Registers sha384 and sha512; checksums.go registers the others
+	_ "crypto/sha512"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// cryptoHashes are the hash names accepted by hmac() and rsa.verify()
+var cryptoHashes = map[string]crypto.Hash{
+	"md5":    crypto.MD5,
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+// hashArgument resolves an optional hash name argument, defaulting to sha256
+func hashArgument(function string, args []interface{}, index int) (crypto.Hash, string, error) {
+	name := "sha256"
+	if len(args) > index {
+		s, ok := args[index].(string)
+		if !ok {
+			return 0, "", errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() hash must be a string, got %s", function, orderTypeName(args[index])))
+		}
+		name = strings.ToLower(s)
+	}
+	h, ok := cryptoHashes[strings.TrimPrefix(name, "pss-")]
+	if !ok {
+		return 0, "", errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() does not support hash '%s'; use md5, sha1, sha256, sha384 or sha512", function, name))
+	}
+	return h, name, nil
+}
+
+// executeHMACFunction implements hmac(key, bits, hash?), returning the MAC as a bitstring
+func (e *ExecutionEngine) executeHMACFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", "hmac() requires a key, the data and an optional hash name")
+	}
+	key, err := wholeBytes("hmac", args[0])
+	if err != nil {
+		return nil, err
+	}
+	data, err := wholeBytes("hmac", args[1])
+	if err != nil {
+		return nil, err
+	}
+	h, _, err := hashArgument("hmac", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(func() hash.Hash { return h.New() }, key)
+	mac.Write(data)
+	return shared.NewBitstringObjectFromBytes(mac.Sum(nil)), nil
+}
+
+// callAESFunction implements aes.gcm_encrypt(key, nonce, plaintext, aad?) and
+// aes.gcm_decrypt(key, nonce, ciphertext, aad?). The ciphertext carries the
+// 16-byte authentication tag at its end, as in most protocols.
+func callAESFunction(function string, args []interface{}) (interface{}, error) {
+	name := "aes." + function
+	if function != "gcm_encrypt" && function != "gcm_decrypt" {
+		return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("aes has no function '%s'; use gcm_encrypt or gcm_decrypt", function))
+	}
+	if len(args) < 3 || len(args) > 4 {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() requires a key, a nonce, the data and optional additional data", name))
+	}
+
+	parts := make([][]byte, len(args))
+	for i, arg := range args {
+		data, err := wholeBytes(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = data
+	}
+	key, nonce, data := parts[0], parts[1], parts[2]
+	var additional []byte
+	if len(parts) == 4 {
+		additional = parts[3]
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s(): key must be 16, 24 or 32 bytes, got %d", name, len(key)))
+	}
+	if len(nonce) == 0 {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s(): nonce must not be empty", name))
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, errors.NewUserError("CRYPTO_ERROR", fmt.Sprintf("%s() failed: %v", name, err))
+	}
+
+	if function == "gcm_encrypt" {
+		return shared.NewBitstringObjectFromBytes(gcm.Seal(nil, nonce, data, additional)), nil
+	}
+	plaintext, err := gcm.Open(nil, nonce, data, additional)
+	if err != nil {
+		return nil, errors.NewUserError("CRYPTO_ERROR", fmt.Sprintf("%s() failed: %v", name, err))
+	}
+	return shared.NewBitstringObjectFromBytes(plaintext), nil
+}
+
+// callRSAFunction implements rsa.verify(public_key, data, signature, hash?).
+// The key is PEM text (a public key or a certificate); signatures are PKCS #1
+// v1.5 unless the hash is given as "pss-sha256" and the like.
+func callRSAFunction(function string, args []interface{}) (interface{}, error) {
+	if function != "verify" {
+		return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("rsa has no function '%s'; use verify", function))
+	}
+	if len(args) < 3 || len(args) > 4 {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", "rsa.verify() requires a public key, the data, the signature and an optional hash name")
+	}
+	keyPEM, err := wholeBytes("rsa.verify", args[0])
+	if err != nil {
+		return nil, err
+	}
+	data, err := wholeBytes("rsa.verify", args[1])
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wholeBytes("rsa.verify", args[2])
+	if err != nil {
+		return nil, err
+	}
+	h, hashName, err := hashArgument("rsa.verify", args, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := parseRSAPublicKey(keyPEM)
+	if err != nil {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("rsa.verify(): %v", err))
+	}
+
+	digest := h.New()
+	digest.Write(data)
+	if strings.HasPrefix(hashName, "pss-") {
+		err = rsa.VerifyPSS(publicKey, h, digest.Sum(nil), signature, nil)
+	} else {
+		err = rsa.VerifyPKCS1v15(publicKey, h, digest.Sum(nil), signature)
+	}
+	return err == nil, nil
+}
+
+// parseRSAPublicKey reads the first PUBLIC KEY, RSA PUBLIC KEY or CERTIFICATE block of PEM text
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no RSA public key or certificate found in PEM data")
+		}
+		data = rest
+
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an RSA key", strings.ToLower(block.Type))
+		}
+		return publicKey, nil
+	}
+}
+
+// callKeysFunction implements keys.file(path, encoding?) and keys.env(name, encoding?),
+// which load key material as a bitstring. The encoding is "raw" (default), "hex" or "base64".
+func callKeysFunction(function string, args []interface{}) (interface{}, error) {
+	name := "keys." + function
+	if function != "file" && function != "env" {
+		return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("keys has no function '%s'; use file or env", function))
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() requires a name and an optional encoding", name))
+	}
+	source, ok := args[0].(string)
+	if !ok {
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() expects a string, got %s", name, orderTypeName(args[0])))
+	}
+	encoding := "raw"
+	if len(args) == 2 {
+		if encoding, ok = args[1].(string); !ok {
+			return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() encoding must be a string, got %s", name, orderTypeName(args[1])))
+		}
+	}
+
+	var data []byte
+	if function == "file" {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, errors.NewUserError("KEY_NOT_FOUND", fmt.Sprintf("%s(): %v", name, err))
+		}
+		data = content
+	} else {
+		value, found := os.LookupEnv(source)
+		if !found {
+			return nil, errors.NewUserError("KEY_NOT_FOUND", fmt.Sprintf("%s(): environment variable %s is not set", name, source))
+		}
+		data = []byte(value)
+	}
+
+	switch encoding {
+	case "raw":
+	case "hex":
+		decoded, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s(): invalid hex: %v", name, err))
+		}
+		data = decoded
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s(): invalid base64: %v", name, err))
+		}
+		data = decoded
+	default:
+		return nil, errors.NewUserError("CRYPTO_ARGUMENT_ERROR", fmt.Sprintf("%s() encoding must be raw, hex or base64, got '%s'", name, encoding))
+	}
+	return shared.NewBitstringObjectFromBytes(data), nil
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// maxLineDiffCells bounds the table of the line diff of two strings; longer
+// strings are reported as changed without the lines that differ
+const maxLineDiffCells = 1 << 20
+
+// executeDiffFunction implements diff(a, b): the paths where b differs from
+// a, one {op, path, old, new} object per added, removed or changed value in
+// the order of the paths. Objects are compared key by key and arrays index
+// by index; changed multi-line strings and bitstrings carry a detail with
+// the lines or bits that differ. Equal values give []
+func (e *ExecutionEngine) executeDiffFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("DIFF_ARGUMENT_ERROR", "diff() requires exactly two values")
+	}
+	return diffValues(args[0], args[1]), nil
+}
+
+// executeAssertNoDiffFunction implements assert_no_diff(actual, expected):
+// nil when the values are equal, otherwise an ASSERTION_ERROR listing the
+// differences the way the REPL shows a diff() result
+func (e *ExecutionEngine) executeAssertNoDiffFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, errors.NewUserError("DIFF_ARGUMENT_ERROR", "assert_no_diff() requires two values and an optional message")
+	}
+	changes := diffValues(args[0], args[1])
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	message := "values differ"
+	if len(args) == 3 {
+		text, ok := args[2].(string)
+		if !ok {
+			return nil, errors.NewUserError("DIFF_ARGUMENT_ERROR", fmt.Sprintf("assert_no_diff() expects the message as a string, got %s", orderTypeName(args[2])))
+		}
+		message = text
+	}
+	return nil, errors.NewUserError("ASSERTION_ERROR", fmt.Sprintf("%s (%d %s):\n%s", message, len(changes), pluralChanges(len(changes)), shared.FormatDiff(changes, false)))
+}
+
+// diffValues lists the changes from a to b
+func diffValues(a, b interface{}) []interface{} {
+	changes := []interface{}{}
+	collectDiff(a, b, "$", &changes)
+	return changes
+}
+
+// collectDiff appends the changes from a to b at path to changes
+func collectDiff(a, b interface{}, path string, changes *[]interface{}) {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(x)+len(y))
+			for key := range x {
+				keys = append(keys, key)
+			}
+			for key := range y {
+				if _, present := x[key]; !present {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				old, inA := x[key]
+				value, inB := y[key]
+				switch {
+				case !inB:
+					*changes = append(*changes, diffChange(shared.DiffRemoved, propertyPath(path, key), old, nil))
+				case !inA:
+					*changes = append(*changes, diffChange(shared.DiffAdded, propertyPath(path, key), nil, value))
+				default:
+					collectDiff(old, value, propertyPath(path, key), changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok {
+			for i := 0; i < len(x) || i < len(y); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(y):
+					*changes = append(*changes, diffChange(shared.DiffRemoved, itemPath, x[i], nil))
+				case i >= len(x):
+					*changes = append(*changes, diffChange(shared.DiffAdded, itemPath, nil, y[i]))
+				default:
+					collectDiff(x[i], y[i], itemPath, changes)
+				}
+			}
+			return
+		}
+	}
+	// Values JSON has no form for, ok(...) results among them, are compared as they are
+	if jsonEqual(a, b) || reflect.DeepEqual(a, b) {
+		return
+	}
+
+	change := diffChange(shared.DiffChanged, path, a, b)
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok && (strings.Contains(x, "\n") || strings.Contains(y, "\n")) {
+			if detail, ok := lineDiff(x, y); ok {
+				change["detail"] = detail
+			}
+		}
+	case *shared.BitstringObject:
+		if y, ok := b.(*shared.BitstringObject); ok {
+			change["detail"] = bindiff(x.Bytes(), x.Len(), y.Bytes(), y.Len())
+		}
+	}
+	*changes = append(*changes, change)
+}
+
+// diffChange builds one entry of the result of diff()
+func diffChange(op, path string, old, value interface{}) map[string]interface{} {
+	return map[string]interface{}{"op": op, "path": path, "old": old, "new": value}
+}
+
+// lineDiff shows the lines removed from a and added in b, numbered by their
+// line in a and b: "-2: old text", "+2: new text". ok is false when the
+// strings are too long to compare line by line
+func lineDiff(a, b string) (string, bool) {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(x)*len(y) > maxLineDiffCells {
+		return "", false
+	}
+
+	// common[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	common := make([][]int, len(x)+1)
+	for i := range common {
+		common[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, "-"+strconv.Itoa(i+1)+": "+x[i])
+			i++
+		default:
+			lines = append(lines, "+"+strconv.Itoa(j+1)+": "+y[j])
+			j++
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+func pluralChanges(n int) string {
+	if n == 1 {
+		return "difference"
+	}
+	return "differences"
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-parser/pkg/ast"
+)
+
+// docRegistry holds the docstrings of the functions defined in code blocks:
+// the comments right above py (name) { ... }. The functions live in the
+// runtimes, which the sessions share, so the sessions share the docstrings
+type docRegistry struct {
+	mu   sync.Mutex
+	docs map[string]string // language.name -> docstring
+}
+
+func newDocRegistry() *docRegistry {
+	return &docRegistry{docs: make(map[string]string)}
+}
+
+// functionDefinition finds the functions a code block defines when it
+// doesn't list its names: def, function, sub and func at the start of a line
+var functionDefinition = regexp.MustCompile(`(?m)^\s*(?:local\s+)?(?:async\s+)?(?:def|function|sub|func)\s+([A-Za-z_][A-Za-z0-9_]*)`)
+
+// recordCodeBlockDoc attaches the comments above a code block to the names
+// in its parentheses, or to the functions it defines if it lists none
+func (e *ExecutionEngine) recordCodeBlockDoc(language string, codeBlock *ast.CodeBlockStatement) {
+	doc := codeBlock.Doc()
+	if doc == "" {
+		return
+	}
+	names := codeBlock.GetVariableNames()
+	if len(names) == 0 {
+		for _, match := range functionDefinition.FindAllStringSubmatch(codeBlock.Code, -1) {
+			names = append(names, match[1])
+		}
+	}
+	e.docs.mu.Lock()
+	defer e.docs.mu.Unlock()
+	for _, name := range names {
+		e.docs.docs[language+"."+name] = doc
+	}
+}
+
+// Doc returns the docstring of a function defined in a code block, named
+// language.function; the language may be an alias, py.send for python.send
+func (e *ExecutionEngine) Doc(name string) (string, bool) {
+	language, function, ok := strings.Cut(name, ".")
+	if !ok {
+		return "", false
+	}
+	if canonical, alias := guardLanguages[language]; alias {
+		language = canonical
+	}
+	e.docs.mu.Lock()
+	defer e.docs.mu.Unlock()
+	doc, found := e.docs.docs[language+"."+function]
+	return doc, found
+}
+
+// DocNames returns the sorted names of the functions that have a docstring
+func (e *ExecutionEngine) DocNames() []string {
+	e.docs.mu.Lock()
+	defer e.docs.mu.Unlock()
+	names := make([]string, 0, len(e.docs.docs))
+	for name := range e.docs.docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -4,17 +4,18 @@ import (
 	goerrors "errors"
 	"fmt"
 	"os"
-	"reflect"
 	"strings"
-	"sync"
 	"time"
 
 	"funterm/container"
 	"funterm/errors"
 	"funterm/jobmanager"
 	"funterm/runtime"
+	"funterm/runtime/beam"
+	"funterm/runtime/goja"
 	"funterm/runtime/lua"
 	"funterm/runtime/node"
+	"funterm/runtime/perl"
 	"funterm/runtime/python"
 	"funterm/shared"
 	"go-parser/pkg/ast"
@@ -60,13 +61,17 @@ func (e *ExecutionEngine) executeLenFunction(args []interface{}) (interface{}, e
 	// Handle different types
 	switch v := arg.(type) {
 	case string:
-		return float64(len(v)), nil
+		// Characters as the user sees them, not bytes
+		return float64(graphemeCount(v)), nil
 	case []interface{}:
 		return float64(len(v)), nil
 	case []byte:
 		return float64(len(v)), nil
 	case map[string]interface{}:
 		return float64(len(v)), nil
+	case *shared.RangeObject:
+		// An exact count, even past the integers a float64 holds
+		return v.Len(), nil
 	case nil:
 		return float64(0), nil
 	default:
@@ -100,24 +105,50 @@ func (e *ExecutionEngine) Execute(command string) (interface{}, bool, bool, erro
 		fmt.Printf("DEBUG: Executing command: '%s'\n", command)
 	}
 
+	start := time.Now()
+	runtimeBefore := e.runtimeTime
+	callsBefore := len(e.calls)
+
 	// Parse the command
+	statement, err := e.Parse(command)
+	parsed := time.Now()
+	if err != nil {
+		e.timeCommand(start, parsed, runtimeBefore, callsBefore)
+		return nil, false, false, err
+	}
+	result, isPrint, hasResult, err := e.ExecuteParsed(statement)
+	e.timeCommand(start, parsed, runtimeBefore, callsBefore)
+	return result, isPrint, hasResult, err
+}
+
+// Parse parses a command string without executing it, so the tree can be
+// cached and later run with ExecuteParsed
+func (e *ExecutionEngine) Parse(command string) (ast.Statement, error) {
+	e.parsing = true
 	statement, parseErrors := e.parser.Parse(command)
+	e.parsing = false
 	if len(parseErrors) > 0 {
 		if e.verbose {
 			fmt.Printf("DEBUG: Parser errors: %v\n", parseErrors)
 		}
 		// Return the first parsing error with position information
 		firstError := parseErrors[0]
-		return nil, false, false, errors.NewUserErrorWithASTPos("PARSING_ERROR", firstError.Message, firstError.Position)
+		err := errors.NewUserErrorWithASTPos("PARSING_ERROR", firstError.Message, firstError.Position)
+		e.countError(err)
+		return nil, err
 	}
 
 	if statement == nil {
 		if e.verbose {
 			fmt.Printf("DEBUG: Statement is nil\n")
 		}
-		return nil, false, false, errors.NewUserError("UNSUPPORTED_COMMAND", "unsupported command")
+		return nil, errors.NewUserError("UNSUPPORTED_COMMAND", "unsupported command")
 	}
+	return statement, nil
+}
 
+// ExecuteParsed executes a statement returned by Parse, with the same results as Execute
+func (e *ExecutionEngine) ExecuteParsed(statement ast.Statement) (interface{}, bool, bool, error) {
 	if e.verbose {
 		fmt.Printf("DEBUG: Statement type: %T\n", statement)
 	}
@@ -138,12 +169,25 @@ func (e *ExecutionEngine) Execute(command string) (interface{}, bool, bool, erro
 		hasResult = true // Always show the result of expressions
 	}
 
+	defer e.startDeadline()()
+
+	// Match statements are remembered by pointer, and the nodes of a released
+	// tree are reused by later parses, so the warnings are kept per input
+	e.lintedMatches = nil
+
 	// Execute the statement and collect output
 	result, err := e.executeStatement(statement)
 	if err != nil {
+		err = e.labelStatementError(statement, err)
+		e.countError(err)
 		return nil, isPrint, hasResult, err
 	}
 
+	// A code block assigned at the top level reports its printed output like a print
+	if output := e.takeCodeBlockOutput(); output != "" {
+		return output, true, false, nil
+	}
+
 	// Check if the result indicates a print function was executed
 	if e.isPrintResult(result) {
 		isPrint = true
@@ -222,7 +266,7 @@ func (e *ExecutionEngine) SetSharedVariable(language, name string, value interfa
 	}
 
 	// Устанавливаем значение
-	e.sharedVariables[language][name] = value
+	e.sharedVariables[language][name] = e.normalizeNumbers(language, value)
 	if e.verbose {
 		fmt.Printf("DEBUG: SetSharedVariable - SET language=%s, name=%s, value=%v\n", language, name, value)
 	}
@@ -255,6 +299,11 @@ func (e *ExecutionEngine) executeStatement(stmt ast.Statement) (interface{}, err
 	if e.verbose {
 		fmt.Printf("DEBUG: executeStatement called with type %T\n", stmt)
 	}
+	if err := e.checkDeadline(); err != nil {
+		return nil, err
+	}
+	e.current = stmt
+	e.countFeature(stmt)
 	switch s := stmt.(type) {
 	case *ast.LanguageCall:
 		// For LanguageCall, we need to wrap it in a LanguageCallStatement to handle print functions properly
@@ -308,6 +357,28 @@ func (e *ExecutionEngine) executeStatement(stmt ast.Statement) (interface{}, err
 		return e.executeBuiltinFunctionCall(s)
 	case *ast.ExpressionStatement:
 		return e.convertExpressionToValue(s.Expression)
+	case *ast.CustomStatement:
+		return e.executeCustomStatement(s)
+	case *ast.PipelineStatement:
+		return e.executePipelineStatement(s)
+	case *ast.RetryStatement:
+		return e.executeRetryStatement(s)
+	case *ast.ForallStatement:
+		return e.executeForallStatement(s)
+	case *ast.LimitStatement:
+		return e.executeLimitStatement(s)
+	case *ast.SelectStatement:
+		return e.executeSelectStatement(s)
+	case *ast.HookStatement:
+		return e.executeHookStatement(s)
+	case *ast.ContextStatement:
+		return e.executeContextStatement(s)
+	case *ast.InteractiveStatement:
+		return e.executeInteractiveStatement(s)
+	case *ast.RedirectStatement:
+		return e.executeRedirectStatement(s)
+	case *ast.PragmaStatement:
+		return e.executePragmaStatement(s)
 	default:
 		return nil, errors.NewUserError("UNSUPPORTED_STATEMENT", fmt.Sprintf("unsupported statement type: %T", stmt))
 	}
@@ -322,10 +393,17 @@ func (e *ExecutionEngine) executeLanguageCallStatement(stmt *ast.LanguageCallSta
 	if stmt.IsBackground {
 		// This is a background task, delegate to JobManager
 		return e.executeBackgroundLanguageCall(stmt)
+	} else if stmt.LanguageCall.Result == ast.ResultNoWait {
+		// The call reports its own output when it finishes
+		return e.executeLanguageCallNew(stmt.LanguageCall)
 	} else {
 		// For all language calls, get the runtime first (needed for output capture)
 		rt, err := e.getRuntimeByName(stmt.LanguageCall.Language)
 		if err != nil {
+			// obj.method(...).next(...) on a variable parses as a language call
+			if _, found := e.getVariable(stmt.LanguageCall.Language); found {
+				return e.executeLanguageCallNew(stmt.LanguageCall)
+			}
 			return nil, err
 		}
 
@@ -377,6 +455,30 @@ func (e *ExecutionEngine) executeLanguageCallStatement(stmt *ast.LanguageCallSta
 			}
 		}
 
+		// For Perl runtime, return what the subroutine printed
+		if stmt.LanguageCall.Language == "perl" {
+			if perlRuntime, ok := rt.(*perl.PerlRuntime); ok {
+				if capturedOutput := perlRuntime.GetCapturedOutput(); capturedOutput != "" {
+					return capturedOutput, nil
+				}
+			}
+		}
+
+		// Same for Erlang and Elixir, whose output goes through a capturing group leader
+		if beamRuntime, ok := rt.(*beam.BeamRuntime); ok {
+			if capturedOutput := beamRuntime.GetCapturedOutput(); capturedOutput != "" {
+				return capturedOutput, nil
+			}
+		}
+
+		// Same for embedded JavaScript and an external lua interpreter
+		switch rt.(type) {
+		case *goja.GojaRuntime, *lua.LuaProcessRuntime:
+			if capturedOutput := rt.(outputCapturer).GetCapturedOutput(); capturedOutput != "" {
+				return capturedOutput, nil
+			}
+		}
+
 		// Special handling for print functions - they should return their printed value
 		if e.isPrintFunction(stmt.LanguageCall) {
 			if e.verbose {
@@ -402,6 +504,12 @@ func (e *ExecutionEngine) isPrintFunction(call *ast.LanguageCall) bool {
 		return call.Function == "print"
 	case "node", "js":
 		return call.Function == "console.log"
+	case "perl", "pl":
+		return call.Function == "print" || call.Function == "say"
+	case "erlang", "erl":
+		return call.Function == "io.format"
+	case "elixir", "ex":
+		return call.Function == "IO.puts"
 	default:
 		return false
 	}
@@ -426,22 +534,7 @@ func (e *ExecutionEngine) executeBackgroundLanguageCall(stmt *ast.LanguageCallSt
 	// Submit the job to the JobManager
 	jobID, err := e.jobManager.Submit(func() (interface{}, error) {
 		// Create a temporary engine instance for this background task
-		backgroundEngine := &ExecutionEngine{
-			parser:            e.parser,
-			runtimeManager:    e.runtimeManager,
-			runtimeRegistry:   e.runtimeRegistry,
-			container:         e.container,
-			jobManager:        e.jobManager, // Share the same job manager
-			sharedVariables:   clonedSharedVariables,
-			variablesMutex:    sync.RWMutex{},
-			verbose:           e.verbose,
-			jobFinished:       e.jobFinished,
-			localScope:        clonedScope,
-			scopeStack:        []*sharedparser.Scope{clonedScope},
-			backgroundOutput:  "",
-			runtimeCache:      e.runtimeCache,
-			runtimeCacheMutex: sync.RWMutex{},
-		}
+		backgroundEngine := e.newBackgroundEngine(clonedScope, clonedSharedVariables)
 
 		// This function will be executed in the background with isolated scope
 		result, err := backgroundEngine.executeLanguageCallNew(stmt.LanguageCall)
@@ -491,6 +584,27 @@ func (e *ExecutionEngine) executeBackgroundLanguageCall(stmt *ast.LanguageCallSt
 			}
 		}
 
+		if stmt.LanguageCall.Language == "perl" {
+			if perlRuntime, ok := rt.(*perl.PerlRuntime); ok {
+				if capturedOutput := perlRuntime.GetCapturedOutput(); capturedOutput != "" {
+					return capturedOutput, nil
+				}
+			}
+		}
+
+		if beamRuntime, ok := rt.(*beam.BeamRuntime); ok {
+			if capturedOutput := beamRuntime.GetCapturedOutput(); capturedOutput != "" {
+				return capturedOutput, nil
+			}
+		}
+
+		switch rt.(type) {
+		case *goja.GojaRuntime, *lua.LuaProcessRuntime:
+			if capturedOutput := rt.(outputCapturer).GetCapturedOutput(); capturedOutput != "" {
+				return capturedOutput, nil
+			}
+		}
+
 		return result, nil
 	}, command)
 
@@ -502,6 +616,43 @@ func (e *ExecutionEngine) executeBackgroundLanguageCall(stmt *ast.LanguageCallSt
 	return jobID, nil
 }
 
+// newBackgroundEngine creates an engine sharing runtimes and the job manager
+// with e but working on its own copy of the scope, shared and global variables
+func (e *ExecutionEngine) newBackgroundEngine(scope *sharedparser.Scope, sharedVariables map[string]map[string]interface{}) *ExecutionEngine {
+	background := &ExecutionEngine{
+		parser:           e.parser,
+		runtimeManager:   e.runtimeManager,
+		runtimeRegistry:  e.runtimeRegistry,
+		container:        e.container,
+		jobManager:       e.jobManager, // Share the same job manager
+		sharedVariables:  sharedVariables,
+		globals:          e.globals.clone(),
+		verbose:          e.verbose,
+		jobFinished:      e.jobFinished,
+		localScope:       scope,
+		scopeStack:       []*sharedparser.Scope{scope},
+		backgroundOutput: "",
+		runtimes:         e.runtimes,
+		handles:          e.handles, // Handles created in the background stay visible to :gc
+		customStatements: e.customStatements,
+		pendingCalls:     e.pendingCalls,
+		numberPolicy:     e.numberPolicy,
+		guardRules:       e.guardRules, // Background calls can't ask, so guarded ones are denied
+		policy:           e.policy,
+		resultCacheDir:   e.resultCacheDir,
+		storePath:        e.storePath,
+		strict:           e.strict,
+		segmentOverflow:  e.segmentOverflow,
+		limits:           e.limits,
+		hooks:            e.hooks,
+		docs:             e.docs,
+		rng:              e.rng,
+	}
+	// Background work outlives the command, so it gets a time limit of its own
+	background.startDeadline()
+	return background
+}
+
 // formatArgumentsForCommand formats arguments for display in command strings
 func (e *ExecutionEngine) formatArgumentsForCommand(args []ast.Expression) string {
 	if len(args) == 0 {
@@ -561,6 +712,9 @@ func (e *ExecutionEngine) WaitForAllJobs() error {
 		fmt.Printf("DEBUG: WaitForAllJobs called - waiting for all background jobs to complete\n")
 	}
 
+	// Calls started with !nowait are not jobs, but must finish before the runtimes go away
+	e.pendingCalls.Wait()
+
 	// Get the number of running jobs
 	runningJobs := e.jobManager.GetRunningJobsCount()
 	if e.verbose {
@@ -663,6 +817,9 @@ func (e *ExecutionEngine) executeImportStatement(importStmt *ast.ImportStatement
 		fmt.Printf("DEBUG: executeImportStatement called with runtime=%s, path=%s\n", runtimeName, filePath)
 	}
 
+	if err := e.checkLanguage(runtimeName, importStmt.Position()); err != nil {
+		return nil, err
+	}
 	// Get or create the runtime
 	rt, err := e.getRuntimeByName(runtimeName)
 	if err != nil {
@@ -679,6 +836,7 @@ func (e *ExecutionEngine) executeImportStatement(importStmt *ast.ImportStatement
 	if e.verbose {
 		fmt.Printf("DEBUG: Evaluating file content in runtime %s\n", runtimeName)
 	}
+	defer e.measureRuntime()()
 
 	// For JavaScript runtime, we need to execute the code in global scope
 	// to make imported functions available for subsequent calls
@@ -723,6 +881,69 @@ func (e *ExecutionEngine) executeImportStatement(importStmt *ast.ImportStatement
 	return result, nil
 }
 
+// blockEvaluator is implemented by runtimes whose code blocks can be used as values
+type blockEvaluator interface {
+	EvaluateBlock(code string) (interface{}, error)
+	outputCapturer
+}
+
+// evaluateCodeBlockValue runs a code block used as a value (result = py { ... })
+// and returns its final expression. The block's printed output is kept until the
+// enclosing statement reports it.
+func (e *ExecutionEngine) evaluateCodeBlockValue(codeBlock *ast.CodeBlockStatement) (interface{}, error) {
+	runtimeName := codeBlock.RuntimeToken.Value
+	switch runtimeName {
+	case "py":
+		runtimeName = "python"
+	case "js":
+		runtimeName = "node"
+	case "pl":
+		runtimeName = "perl"
+	case "erl":
+		runtimeName = "erlang"
+	case "ex":
+		runtimeName = "elixir"
+	}
+
+	if err := e.checkLanguage(runtimeName, codeBlock.Position()); err != nil {
+		return nil, err
+	}
+	rt, err := e.getRuntimeByName(runtimeName)
+	if err != nil {
+		return nil, errors.NewSystemError("RUNTIME_NOT_FOUND", fmt.Sprintf("failed to get runtime '%s': %v", runtimeName, err))
+	}
+	evaluator, ok := rt.(blockEvaluator)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("CODE_BLOCK_VALUE_UNSUPPORTED", fmt.Sprintf("%s code blocks cannot be used as values", runtimeName), codeBlock.Position())
+	}
+
+	e.countLanguage(runtimeName)
+	stopSpinner := e.startSpinner(runtimeName + " block")
+	stopMeasure := e.measureRuntime()
+	stopCall := e.measureCall(runtimeName+" block", rt)
+	value, err := evaluator.EvaluateBlock(codeBlock.Code)
+	stopCall()
+	stopMeasure()
+	stopSpinner()
+	if output := evaluator.GetCapturedOutput(); output != "" {
+		if e.codeBlockOutput != "" {
+			e.codeBlockOutput += "\n"
+		}
+		e.codeBlockOutput += output
+	}
+	if err != nil {
+		return nil, errors.NewRuntimeError(runtimeName, "CODE_BLOCK_EVAL_ERROR", fmt.Sprintf("failed to evaluate code block: %v", err))
+	}
+	return e.normalizeNumbers(runtimeName, value), nil
+}
+
+// takeCodeBlockOutput returns and clears output printed by value code blocks
+func (e *ExecutionEngine) takeCodeBlockOutput() string {
+	output := e.codeBlockOutput
+	e.codeBlockOutput = ""
+	return output
+}
+
 // executeCodeBlockStatement executes a code block by evaluating the code directly
 func (e *ExecutionEngine) executeCodeBlockStatement(codeBlock *ast.CodeBlockStatement) (interface{}, error) {
 	// Extract runtime name
@@ -734,6 +955,17 @@ func (e *ExecutionEngine) executeCodeBlockStatement(codeBlock *ast.CodeBlockStat
 	if runtimeName == "js" {
 		runtimeName = "node"
 	}
+	// Handle alias 'pl' for 'perl'
+	if runtimeName == "pl" {
+		runtimeName = "perl"
+	}
+	// Handle aliases 'erl' and 'ex' for the BEAM languages
+	if runtimeName == "erl" {
+		runtimeName = "erlang"
+	}
+	if runtimeName == "ex" {
+		runtimeName = "elixir"
+	}
 
 	// Extract code
 	code := codeBlock.Code
@@ -746,11 +978,18 @@ func (e *ExecutionEngine) executeCodeBlockStatement(codeBlock *ast.CodeBlockStat
 		}
 	}
 
+	if err := e.checkLanguage(runtimeName, codeBlock.Position()); err != nil {
+		return nil, err
+	}
+	e.recordCodeBlockDoc(runtimeName, codeBlock)
 	// Get or create the runtime
 	rt, err := e.getRuntimeByName(runtimeName)
 	if err != nil {
 		return nil, errors.NewSystemError("RUNTIME_NOT_FOUND", fmt.Sprintf("failed to get runtime '%s': %v", runtimeName, err))
 	}
+	defer e.measureRuntime()()
+	defer e.measureCall(runtimeName+" block", rt)()
+	e.countLanguage(runtimeName)
 
 	// For Python runtime, use hybrid approach based on variable specifications
 	if pythonRuntime, ok := rt.(*python.PythonRuntime); ok {
@@ -1000,6 +1239,42 @@ func (e *ExecutionEngine) executeCodeBlockStatement(codeBlock *ast.CodeBlockStat
 		return result, nil
 	}
 
+	// For Perl runtime, the block's printed output is its result
+	if perlRuntime, ok := rt.(*perl.PerlRuntime); ok {
+		result, err := perlRuntime.ExecuteCodeBlock(code)
+		if err != nil {
+			return nil, errors.NewRuntimeError(runtimeName, "CODE_BLOCK_EVAL_ERROR", fmt.Sprintf("failed to evaluate code block: %v", err))
+		}
+		return result, nil
+	}
+
+	// For Erlang and Elixir, the block's printed output is its result
+	if beamRuntime, ok := rt.(*beam.BeamRuntime); ok {
+		result, err := beamRuntime.ExecuteCodeBlock(code)
+		if err != nil {
+			return nil, errors.NewRuntimeError(runtimeName, "CODE_BLOCK_EVAL_ERROR", fmt.Sprintf("failed to evaluate code block: %v", err))
+		}
+		return result, nil
+	}
+
+	// Likewise for embedded JavaScript
+	if gojaRuntime, ok := rt.(*goja.GojaRuntime); ok {
+		result, err := gojaRuntime.ExecuteCodeBlock(code)
+		if err != nil {
+			return nil, errors.NewRuntimeError(runtimeName, "CODE_BLOCK_EVAL_ERROR", fmt.Sprintf("failed to evaluate code block: %v", err))
+		}
+		return result, nil
+	}
+
+	// And for an external lua interpreter
+	if luaProcess, ok := rt.(*lua.LuaProcessRuntime); ok {
+		result, err := luaProcess.ExecuteCodeBlock(code)
+		if err != nil {
+			return nil, errors.NewRuntimeError(runtimeName, "CODE_BLOCK_EVAL_ERROR", fmt.Sprintf("failed to evaluate code block: %v", err))
+		}
+		return result, nil
+	}
+
 	// For other runtimes, use the standard Eval method
 	if e.verbose {
 		fmt.Printf("DEBUG: Evaluating code block in runtime %s\n", runtimeName)
@@ -1049,7 +1324,7 @@ func (e *ExecutionEngine) evaluateExpression(expr ast.Expression) (interface{},
 		if ex.Language == "" {
 			// Return nil instead of error for undefined unqualified variables
 			// This prevents accessing runtime variables without qualification
-			return nil, nil
+			return e.undefinedVariable(ex.Name, ex.Position())
 		}
 
 		// For qualified identifiers, first check shared variables
@@ -1086,8 +1361,14 @@ func (e *ExecutionEngine) evaluateExpression(expr ast.Expression) (interface{},
 		return e.executeTernaryExpression(ex)
 	case *ast.ElvisExpression:
 		return e.executeElvisExpression(ex)
+	case *ast.TryExpression:
+		return e.executeTryExpression(ex)
+	case *ast.ValueMethodCall:
+		return e.executeValueMethodCall(ex)
 	case *ast.IndexExpression:
 		return e.executeIndexExpression(ex)
+	case *ast.SliceExpression:
+		return e.executeSliceExpression(ex)
 	case *ast.FieldAccess:
 		return e.executeFieldAccess(ex)
 	case *ast.SizeExpression:
@@ -1134,25 +1415,24 @@ func (e *ExecutionEngine) executeSizeExpression(expr *ast.SizeExpression) (inter
 		bitstringData = v
 	case *funbit.BitString:
 		// Оборачиваем в BitstringObject
-		bitstringData = &shared.BitstringObject{BitString: v}
+		bitstringData = shared.NewBitstringObject(v)
 	case shared.BitstringByte:
 		// Convert BitstringByte to BitstringObject (single byte)
 		bitString := funbit.NewBitStringFromBytes([]byte{v.Value})
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 	case string:
 		// Convert string (from binary patterns) to BitstringObject
-		bitString := funbit.NewBitStringFromBytes([]byte(v))
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObjectFromBytes([]byte(v))
 	case []byte:
 		// Convert byte slice to BitstringObject
 		bitString := funbit.NewBitStringFromBytes(v)
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 	default:
 		return nil, fmt.Errorf("size operator (@) can only be applied to bitstrings, got %T", value)
 	}
 
 	// Return size in bytes
-	return int(bitstringData.BitString.Length() / 8), nil
+	return int(bitstringData.Bits().Length() / 8), nil
 }
 
 // executeBuiltinFunctionCall executes a builtin function call (like id())
@@ -1160,6 +1440,26 @@ func (e *ExecutionEngine) executeBuiltinFunctionCall(call *ast.BuiltinFunctionCa
 	if e.verbose {
 		fmt.Printf("DEBUG: executeBuiltinFunctionCall called with function: %s, args: %v\n", call.Function, call.Arguments)
 	}
+	if err := e.checkBuiltinCall(call); err != nil {
+		return nil, err
+	}
+
+	// proxy() needs the runtime call itself, not its copied result
+	if call.Function == "proxy" {
+		return e.executeProxyFunction(call)
+	}
+	// size_of() names a segment of the bitstring being built, not a value
+	if call.Function == "size_of" {
+		return e.executeSizeOfFunction(call)
+	}
+	// cache_result() evaluates its expression only when there is no result
+	if call.Function == "cache_result" {
+		return e.executeCacheResultFunction(call)
+	}
+	// rate_limit() reads its rate as written, 5/second isn't a division
+	if call.Function == "rate_limit" || call.Function == "semaphore" {
+		return e.executeLimitFunction(call)
+	}
 
 	// Convert arguments from AST expressions to Go values
 	args := make([]interface{}, len(call.Arguments))
@@ -1180,11 +1480,101 @@ func (e *ExecutionEngine) executeBuiltinFunctionCall(call *ast.BuiltinFunctionCa
 		return e.executeConcatFunction(args)
 	case "print":
 		return e.executePrintFunction(args)
+	case "progress":
+		return e.executeProgressFunction(args)
+	case "random":
+		return e.executeRandomFunction(args)
+	case "set_seed":
+		return e.executeSetSeedFunction(args)
+	case "release":
+		return e.executeReleaseFunction(args)
+	case "sort":
+		return e.executeSortFunction(args)
+	case "min", "max":
+		return e.executeExtremumFunction(call.Function, args)
+	case "join":
+		return e.executeJoinFunction(args)
+	case "hexdump":
+		return e.executeHexdumpFunction(args)
+	case "export_vars":
+		return e.executeExportVarsFunction(args)
+	case "import_vars":
+		return e.executeImportVarsFunction(args)
+	case "bindiff":
+		return e.executeBindiffFunction(args)
+	case "validate_pattern":
+		return e.executeValidatePatternFunction(args)
+	case "validate":
+		return e.executeValidateFunction(args)
+	case "diff":
+		return e.executeDiffFunction(args)
+	case "assert_no_diff":
+		return e.executeAssertNoDiffFunction(args)
+	case "crc32", "adler32", "md5", "sha1", "sha256":
+		return e.executeChecksumFunction(call.Function, args)
+	case "crc16":
+		return e.executeCRC16Function(args)
+	case "hmac":
+		return e.executeHMACFunction(args)
+	case "byte_size", "bit_size":
+		return e.executeBitSizeFunction(call.Function, args)
+	case "pack":
+		return e.executePackFunction(args)
+	case "unpack":
+		return e.executeUnpackFunction(args)
+	case "channel":
+		return e.executeChannelFunction(args)
+	case "send":
+		return e.executeSendFunction(args)
+	case "recv":
+		return e.executeRecvFunction(args)
+	case "close":
+		return e.executeCloseFunction(args)
+	case "supervise":
+		return e.executeSuperviseFunction(args)
 	default:
+		if strings.Contains(call.Function, ".") {
+			return e.executeMethodCall(call, args)
+		}
 		return nil, fmt.Errorf("unsupported builtin function: %s", call.Function)
 	}
 }
 
+// executeMethodCall calls obj.method(args) on a variable holding a string, a proxy handle or a shared.MethodObject
+func (e *ExecutionEngine) executeMethodCall(call *ast.BuiltinFunctionCall, args []interface{}) (interface{}, error) {
+	dot := strings.Index(call.Function, ".")
+	objectName, method := call.Function[:dot], call.Function[dot+1:]
+
+	value, found := e.getVariable(objectName)
+	if !found {
+		if module, ok := e.lookupBuiltinModule(objectName); ok {
+			if err := e.guardCall(objectName, method, args, call.Position()); err != nil {
+				return nil, err
+			}
+			return module(method, args)
+		}
+		return nil, errors.NewUserErrorWithASTPos("UNDEFINED_VARIABLE", fmt.Sprintf("undefined variable: %s", objectName), call.Position())
+	}
+	return e.callValueMethod(objectName, value, method, args, call.Position())
+}
+
+// callValueMethod dispatches a method call on a variable's value
+func (e *ExecutionEngine) callValueMethod(objectName string, value interface{}, method string, args []interface{}, pos ast.Position) (interface{}, error) {
+	if str, ok := value.(string); ok {
+		return e.callStringMethod(str, method, args)
+	}
+
+	if handle, ok := value.(*runtime.Handle); ok {
+		return e.callHandleMethod(handle, method, args, false, pos)
+	}
+
+	object, ok := value.(shared.MethodObject)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("METHOD_CALL_ERROR", fmt.Sprintf("value of '%s' (%T) has no methods", objectName, value), pos)
+	}
+	return object.CallMethod(method, args)
+}
+
 // pushScope creates a new nested scope and pushes it onto the stack
 func (e *ExecutionEngine) pushScope() {
 	if e.verbose {
@@ -1321,21 +1711,8 @@ func (e *ExecutionEngine) getVariable(name string) (interface{}, bool) {
 
 // setGlobalVariable sets a global variable accessible from all runtimes
 func (e *ExecutionEngine) setGlobalVariable(name string, value interface{}) {
-	e.globalMutex.Lock()
-	defer e.globalMutex.Unlock()
-
-	// Convert to VariableInfo structure (mutable by default for backward compatibility)
-	varInfo := &sharedparser.VariableInfo{
-		Value:     value,
-		IsMutable: true,
-	}
-
-	e.globalVariables[name] = varInfo
-
-	// Инвалидируем кэш синхронизации для этой переменной
-	e.syncedGlobalMutex.Lock()
-	delete(e.lastSyncedGlobals, name)
-	e.syncedGlobalMutex.Unlock()
+	// Mutable by default for backward compatibility
+	e.globals.set(name, value, true)
 
 	if e.verbose {
 		fmt.Printf("DEBUG: Set global variable '%s' = %v (mutable)\n", name, value)
@@ -1344,10 +1721,7 @@ func (e *ExecutionEngine) setGlobalVariable(name string, value interface{}) {
 
 // getGlobalVariable retrieves a global variable value
 func (e *ExecutionEngine) getGlobalVariable(name string) (interface{}, bool) {
-	e.globalMutex.RLock()
-	defer e.globalMutex.RUnlock()
-
-	varInfo, found := e.globalVariables[name]
+	varInfo, found := e.globals.get(name)
 	var value interface{}
 	if found {
 		value = varInfo.Value
@@ -1362,76 +1736,26 @@ func (e *ExecutionEngine) getGlobalVariable(name string) (interface{}, bool) {
 
 // getAllGlobalVariables returns a copy of all global variables
 func (e *ExecutionEngine) getAllGlobalVariables() map[string]interface{} {
-	e.globalMutex.RLock()
-	defer e.globalMutex.RUnlock()
+	return e.globals.values()
+}
 
-	// Return a copy to avoid race conditions
-	result := make(map[string]interface{})
-	for k, varInfo := range e.globalVariables {
-		result[k] = varInfo.Value
-	}
+// UndoneAssignment is an assignment reverted by UndoAssignments
+type UndoneAssignment struct {
+	Name string
+	// Removed is set when the assignment had created the variable
+	Removed bool
+}
 
-	return result
+// UndoAssignments reverts the last n assignments of global variables, newest
+// first, restoring their previous values. Only the last undoLogSize
+// assignments are remembered; variables of runtimes (lua.x) are not covered
+func (e *ExecutionEngine) UndoAssignments(n int) []UndoneAssignment {
+	return e.globals.undo(n)
 }
 
 // syncGlobalVariablesToRuntime synchronizes all global variables to a specific runtime
 func (e *ExecutionEngine) syncGlobalVariablesToRuntime(rt runtime.LanguageRuntime) error {
-	e.globalMutex.RLock()
-	globals := make(map[string]interface{})
-	for name, varInfo := range e.globalVariables {
-		globals[name] = varInfo.Value
-	}
-	e.globalMutex.RUnlock()
-
-	// Используем кэш для отслеживания изменений и избегаем повторной синхронизации
-	e.syncedGlobalMutex.Lock()
-	defer e.syncedGlobalMutex.Unlock()
-
-	// Счетчик синхронизированных переменных для debug
-	var syncCount int
-	
-	for name, value := range globals {
-		// Проверяем, изменилась ли переменная или это новая переменная
-		lastValue, exists := e.lastSyncedGlobals[name]
-		if exists && reflect.DeepEqual(lastValue, value) {
-			// Переменная не изменилась, пропускаем
-			if e.verbose {
-				fmt.Printf("DEBUG: Skipping sync for unchanged global variable '%s'\n", name)
-			}
-			continue
-		}
-
-		// Переменная изменилась или это новая переменная - синхронизируем
-		if err := rt.SetVariable(name, value); err != nil {
-			if e.verbose {
-				fmt.Printf("DEBUG: Warning - failed to sync global variable '%s' to runtime: %v\n", name, err)
-			}
-			// Continue with other variables even if one fails
-		} else {
-			syncCount++
-			if e.verbose {
-				fmt.Printf("DEBUG: Synced global variable '%s' = %v to runtime\n", name, value)
-			}
-		}
-		
-		// Обновляем кэш
-		e.lastSyncedGlobals[name] = value
-	}
-
-	// Удаляем переменные из кэша если их больше нет в globals
-	for name := range e.lastSyncedGlobals {
-		if _, exists := globals[name]; !exists {
-			delete(e.lastSyncedGlobals, name)
-			if e.verbose {
-				fmt.Printf("DEBUG: Removed deleted global variable '%s' from sync cache\n", name)
-			}
-		}
-	}
-
-	if e.verbose && syncCount == 0 && len(globals) > 0 {
-		fmt.Printf("DEBUG: All %d global variables already synced (no changes detected)\n", len(globals))
-	}
-
+	e.globals.syncTo(rt, e.verbose)
 	return nil
 }
 
@@ -1443,6 +1767,8 @@ func (e *ExecutionEngine) convertExpressionToValue(expr ast.Expression) (interfa
 	case *ast.NamedArgument:
 		// NamedArgument should not be converted directly - it should be handled by convertExpressionsWithNamedArgs
 		return nil, errors.NewSystemError("UNSUPPORTED_EXPRESSION", "unsupported expression type: *ast.NamedArgument")
+	case *ast.SpreadArgument:
+		return nil, errors.NewUserErrorWithASTPos("SPREAD_ERROR", "arguments can only be spread into calls to language functions", typedExpr.Position())
 	case *ast.BooleanLiteral:
 		return typedExpr.Value, nil
 	case *ast.NilLiteral:
@@ -1462,6 +1788,8 @@ func (e *ExecutionEngine) convertExpressionToValue(expr ast.Expression) (interfa
 	case *ast.VariableRead:
 		// For VariableRead expressions, execute the variable read
 		return e.executeVariableRead(typedExpr)
+	case *ast.CaptureExpression:
+		return e.executeCaptureExpression(typedExpr)
 	case *ast.Identifier:
 		// For identifiers in pattern matching context, we need to handle them specially
 		// In pattern matching, identifiers represent variables that should be bound
@@ -1485,7 +1813,7 @@ func (e *ExecutionEngine) convertExpressionToValue(expr ast.Expression) (interfa
 
 			// If not found in local or global scope, return nil instead of error
 			// This prevents accessing runtime variables without qualification
-			return nil, nil
+			return e.undefinedVariable(typedExpr.Name, typedExpr.Position())
 		}
 	case *ast.ArrayLiteral:
 		// Convert array elements to []interface{}
@@ -1515,6 +1843,8 @@ func (e *ExecutionEngine) convertExpressionToValue(expr ast.Expression) (interfa
 		return result, nil
 	case *ast.IndexExpression:
 		return e.executeIndexExpression(typedExpr)
+	case *ast.SliceExpression:
+		return e.executeSliceExpression(typedExpr)
 	case *ast.FieldAccess:
 		return e.executeFieldAccess(typedExpr)
 	case *ast.BinaryExpression:
@@ -1523,12 +1853,18 @@ func (e *ExecutionEngine) convertExpressionToValue(expr ast.Expression) (interfa
 		return e.executeUnaryExpression(typedExpr)
 	case *ast.ElvisExpression:
 		return e.executeElvisExpression(typedExpr)
+	case *ast.TryExpression:
+		return e.executeTryExpression(typedExpr)
+	case *ast.ValueMethodCall:
+		return e.executeValueMethodCall(typedExpr)
 	case *ast.TernaryExpression:
 		return e.executeTernaryExpression(typedExpr)
 	case *ast.PipeExpression:
 		return e.executePipeExpression(typedExpr)
 	case *ast.LanguageCall:
 		return e.executeLanguageCallNew(typedExpr)
+	case *ast.CodeBlockStatement:
+		return e.evaluateCodeBlockValue(typedExpr)
 	case *ast.BitstringExpression:
 		return e.executeBitstringExpression(typedExpr)
 	case *ast.SizeExpression:
@@ -1624,12 +1960,13 @@ func (e *ExecutionEngine) setVariableInRuntime(rt runtime.LanguageRuntime, langu
 		if byteSlice, ok := value.([]byte); ok {
 			// Convert legacy []byte to BitstringObject
 			bitString := funbit.NewBitStringFromBytes(byteSlice)
-			value = &shared.BitstringObject{BitString: bitString}
+			value = shared.NewBitstringObject(bitString)
 		}
 		// Note: if value is already *shared.BitstringObject, it will be passed as-is
 	}
 
 	// Set the variable in the runtime
+	value = runtime.ConvertToRuntime(language, value)
 	err := rt.SetVariable(variableName, value)
 	if err != nil {
 		var execErr *errors.ExecutionError
@@ -1742,7 +2079,7 @@ func (e *ExecutionEngine) readVariableFromRuntime(rt runtime.LanguageRuntime, la
 	if e.verbose {
 		fmt.Printf("DEBUG: readVariableFromRuntime - SUCCESSFULLY got variable '%s' from runtime, value: %v\n", variableName, value)
 	}
-	return value, nil
+	return e.normalizeNumbers(language, value), nil
 }
 
 // setVariableWithMutability sets a variable with explicit mutability flag in the current scope
@@ -1776,21 +2113,7 @@ func (e *ExecutionEngine) getVariableInfo(name string) (*sharedparser.VariableIn
 
 // setGlobalVariableWithMutability sets a global variable with explicit mutability flag
 func (e *ExecutionEngine) setGlobalVariableWithMutability(name string, value interface{}, isMutable bool) {
-	e.globalMutex.Lock()
-	defer e.globalMutex.Unlock()
-
-	// Convert to VariableInfo structure
-	varInfo := &sharedparser.VariableInfo{
-		Value:     value,
-		IsMutable: isMutable,
-	}
-
-	e.globalVariables[name] = varInfo
-
-	// Инвалидируем кэш синхронизации для этой переменной
-	e.syncedGlobalMutex.Lock()
-	delete(e.lastSyncedGlobals, name)
-	e.syncedGlobalMutex.Unlock()
+	e.globals.set(name, value, isMutable)
 
 	if e.verbose {
 		mutabilityStr := "immutable"
@@ -1803,10 +2126,7 @@ func (e *ExecutionEngine) setGlobalVariableWithMutability(name string, value int
 
 // getGlobalVariableInfo retrieves global variable information
 func (e *ExecutionEngine) getGlobalVariableInfo(name string) (*sharedparser.VariableInfo, bool) {
-	e.globalMutex.RLock()
-	defer e.globalMutex.RUnlock()
-
-	varInfo, found := e.globalVariables[name]
+	varInfo, found := e.globals.get(name)
 
 	if e.verbose {
 		if found {
@@ -1856,7 +2176,7 @@ func (e *ExecutionEngine) convertExpressionToValueForCStyleForLoop(expr ast.Expr
 		}
 
 		// If not found anywhere, return nil instead of error
-		return nil, nil
+		return e.undefinedVariable(typedExpr.Name, typedExpr.Position())
 	case *ast.ArrayLiteral:
 		// Convert array elements to []interface{}
 		result := make([]interface{}, len(typedExpr.Elements))
@@ -1885,6 +2205,8 @@ func (e *ExecutionEngine) convertExpressionToValueForCStyleForLoop(expr ast.Expr
 		return result, nil
 	case *ast.IndexExpression:
 		return e.executeIndexExpression(typedExpr)
+	case *ast.SliceExpression:
+		return e.executeSliceExpression(typedExpr)
 	case *ast.FieldAccess:
 		return e.executeFieldAccess(typedExpr)
 	case *ast.BinaryExpression:
@@ -1893,6 +2215,10 @@ func (e *ExecutionEngine) convertExpressionToValueForCStyleForLoop(expr ast.Expr
 		return e.executeUnaryExpression(typedExpr)
 	case *ast.ElvisExpression:
 		return e.executeElvisExpression(typedExpr)
+	case *ast.TryExpression:
+		return e.executeTryExpression(typedExpr)
+	case *ast.ValueMethodCall:
+		return e.executeValueMethodCall(typedExpr)
 	case *ast.TernaryExpression:
 		return e.executeTernaryExpression(typedExpr)
 	case *ast.PipeExpression:
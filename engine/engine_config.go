@@ -3,12 +3,14 @@ package engine
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"funterm/container"
 	"funterm/errors"
 	"funterm/factory"
 	"funterm/jobmanager"
 	"funterm/runtime"
+	"go-parser/pkg/ast"
 	"go-parser/pkg/parser"
 	sharedparser "go-parser/pkg/shared"
 )
@@ -30,19 +32,69 @@ type ExecutionEngine struct {
 	sharedVariables map[string]map[string]interface{} // language -> variable -> value
 	variablesMutex  sync.RWMutex                      // для потокобезопасности
 	// Глобальные неквалифицированные переменные (доступны во всех runtimes)
-	globalVariables  map[string]*sharedparser.VariableInfo // name -> VariableInfo
-	globalMutex      sync.RWMutex                          // для потокобезопасности глобальных переменных
-	verbose          bool                                  // Enable verbose/debug output
+	globals          *globalStore
+	verbose          bool // Enable verbose/debug output
 	jobFinished      chan struct{}
 	localScope       *sharedparser.Scope   // Local scope for variables
 	scopeStack       []*sharedparser.Scope // Stack of nested scopes
 	backgroundOutput string                // Output from completed background jobs
-	// Кэш рантаймов для переиспользования
-	runtimeCache      map[string]runtime.LanguageRuntime // language -> runtime instance
-	runtimeCacheMutex sync.RWMutex                       // для потокобезопасности кэша
-	// Кэш для отслеживания последней синхронизированной версии глобальных переменных
-	lastSyncedGlobals map[string]interface{} // name -> value
-	syncedGlobalMutex sync.RWMutex           // для потокобезопасности кэша синхронизации
+	codeBlockOutput  string                // Вывод блока кода, использованного как значение (result = py { ... })
+	// Кэш рантаймов для переиспользования, общий для копий движка
+	runtimes         *runtimeCache
+	spinnerThreshold time.Duration   // Порог, после которого для долгих вызовов рантаймов показывается спиннер
+	rng              *randomSource   // Генератор для random() и gen.*, общий с фоновыми копиями и сессиями
+	handles          *handleRegistry // Живые прокси-объекты рантаймов, созданные через proxy(); общие с фоновыми копиями
+	// Учёт памяти переменных сессии
+	memoryBudget int64           // Порог в байтах для предупреждения, 0 - без ограничения
+	budgetWarned bool            // Предупреждение о превышении уже показано
+	leakWarned   map[string]bool // Хэндлы, о потере которых уже предупредили
+	// Исполнители инструкций, добавленных пользовательскими обработчиками парсера
+	customStatements map[string]CustomStatementFunc
+	// Вызовы с !nowait, которые еще выполняются (общий счетчик для фоновых копий движка)
+	pendingCalls *sync.WaitGroup
+	// Представление чисел, приходящих из рантаймов
+	numberPolicy runtime.NumberPolicy
+	// Проверка match-выражений при первом выполнении
+	matchWarnings bool
+	lintedMatches map[*ast.MatchStatement]bool // Match-выражения текущей команды, уже проверенные
+	// Размеры сегментов собираемой битовой строки в байтах для size_of()
+	segmentSizes map[string]int
+	// Время в рантаймах для :time: всего, глубина вложенных вызовов и разбивка последней команды
+	runtimeTime  time.Duration
+	runtimeDepth int
+	lastTiming   CommandTiming
+	// Затраты процессора и памяти интерпретаторов на вызовы (собираются, если callStats)
+	callStats bool
+	calls     []RuntimeCall
+	// Вызовы, требующие подтверждения пользователя, и функция, которая его спрашивает
+	guardRules  []guardRule
+	confirmCall func(call string) bool // nil - подтвердить нельзя, вызовы запрещаются
+	spinnerHeld int32                  // Спиннер не рисуется, пока задан вопрос
+	// Ограничения сессии и срок, до которого должна завершиться текущая команда
+	policy        *compiledPolicy
+	deadline      time.Time
+	deadlineLimit string // что задало срок, для сообщения об ошибке
+	// Счетчики использования для телеметрии (nil - не собираются)
+	usage *usageCounter
+	// Каталог результатов cache_result(), пустой - кэш отключен
+	resultCacheDir string
+	// Файл базы store.put() и store.get(), пустой - хранилище отключено
+	storePath string
+	// Ограничения rate_limit() и semaphore(), общие для фоновых копий движка
+	limits *limitRegistry
+	// Обработчики on_signal() и on_exit, общие для фоновых копий движка
+	hooks *scriptHooks
+	// Комментарии над блоками кода для :doc, общие с сессиями, как и рантаймы
+	docs *docRegistry
+	// Данные для stdin следующего вызова рантайма, py.input_feed()
+	inputFeeds map[string][]byte
+	// Правила строгого режима, nil - все неявные преобразования разрешены
+	strict *strictMode
+	// Что делать с целым, не помещающимся в сегмент без overflow:, пустое - truncate
+	segmentOverflow string
+	// Где движок был, если команда упала с паникой: разбор или последняя начатая инструкция
+	parsing bool
+	current ast.Statement
 }
 
 // NewExecutionEngine creates a new execution engine with default dependencies
@@ -63,6 +115,35 @@ type ExecutionEngineConfig struct {
 	RuntimeRegistry *factory.RuntimeRegistry
 	JobManager      *jobmanager.JobManager // Optional: if nil, a default one will be created
 	Verbose         bool                   // Enable verbose/debug output
+	// SpinnerThreshold is how long a runtime call may run before a spinner is shown
+	// (0 means default, negative disables)
+	SpinnerThreshold time.Duration
+	// MemoryBudget is the size of session variables in bytes above which a warning is shown (0 disables)
+	MemoryBudget int64
+	// ParserHandlers disables parser constructs or changes their priorities
+	ParserHandlers parser.HandlerOptions
+	// Numbers controls how numbers coming from runtimes are represented
+	Numbers runtime.NumberPolicy
+	// MatchWarnings reports unreachable and non-exhaustive match arms when a match first runs
+	MatchWarnings bool
+	// GuardedCalls are patterns of calls that run only after the user confirms them
+	GuardedCalls []string
+	// CollectUsage counts the statements, languages and errors of the session, see Usage
+	CollectUsage bool
+	// ResultCacheDir keeps the results of cache_result() between runs (empty disables)
+	ResultCacheDir string
+	// StorePath is the database of store.put() and store.get() (empty disables)
+	StorePath string
+	// Strict turns the silent coercions of the strict rules into errors
+	Strict bool
+	// StrictRules sets single rules to "error", "warn" or "allow"
+	StrictRules map[string]string
+	// SegmentOverflow is what happens to integers too large for their
+	// bitstring segment: "truncate" (default), "saturate" or "error"
+	SegmentOverflow string
+	// Policy limits the languages, builtins and time of the session (nil
+	// limits nothing)
+	Policy *SessionPolicy
 }
 
 // NewExecutionEngineWithConfig creates a new execution engine with configuration
@@ -132,6 +213,10 @@ func NewExecutionEngineWithConfig(config ExecutionEngineConfig) (*ExecutionEngin
 		return nil, errors.NewSystemError("INVALID_PARSER_TYPE", "resolved parser is not of correct type")
 	}
 
+	if err := p.ApplyHandlerOptions(config.ParserHandlers); err != nil {
+		return nil, errors.NewSystemError("PARSER_CONFIG_FAILED", fmt.Sprintf("failed to configure parser: %v", err))
+	}
+
 	rm, ok := runtimeManagerInstance.(*runtime.RuntimeManager)
 	if !ok {
 		return nil, errors.NewSystemError("INVALID_RUNTIME_MANAGER_TYPE", "resolved runtime manager is not of correct type")
@@ -155,70 +240,120 @@ func NewExecutionEngineWithConfig(config ExecutionEngineConfig) (*ExecutionEngin
 	rootScope := sharedparser.NewScope(nil)
 
 	engine := &ExecutionEngine{
-		parser:            p,
-		runtimeManager:    rm,
-		runtimeRegistry:   rr,
-		container:         diContainer,
-		jobManager:        jm,
-		sharedVariables:   make(map[string]map[string]interface{}),
-		globalVariables:   make(map[string]*sharedparser.VariableInfo), // Initialize global variables
-		verbose:           config.Verbose,
-		jobFinished:       make(chan struct{}),
-		localScope:        rootScope,                                // Use the same root scope
-		scopeStack:        []*sharedparser.Scope{rootScope},         // Initialize scope stack with the same root scope
-		runtimeCache:      make(map[string]runtime.LanguageRuntime), // Initialize runtime cache
-		lastSyncedGlobals: make(map[string]interface{}),             // Initialize sync cache
+		parser:           p,
+		runtimeManager:   rm,
+		runtimeRegistry:  rr,
+		container:        diContainer,
+		jobManager:       jm,
+		sharedVariables:  make(map[string]map[string]interface{}),
+		globals:          newGlobalStore(),
+		verbose:          config.Verbose,
+		jobFinished:      make(chan struct{}),
+		localScope:       rootScope,                        // Use the same root scope
+		scopeStack:       []*sharedparser.Scope{rootScope}, // Initialize scope stack with the same root scope
+		runtimes:         newRuntimeCache(),
+		handles:          newHandleRegistry(),                  // Initialize proxy handles
+		customStatements: make(map[string]CustomStatementFunc), // Initialize custom statement executors
+		pendingCalls:     &sync.WaitGroup{},                    // Initialize !nowait call tracking
+		limits:           newLimitRegistry(),
+		hooks:            newScriptHooks(),
+		docs:             newDocRegistry(),
+		rng:              newRandomSource(time.Now().UnixNano()),
+	}
+	engine.SetSpinnerThreshold(config.SpinnerThreshold)
+	engine.SetMemoryBudget(config.MemoryBudget)
+	engine.SetMatchWarnings(config.MatchWarnings)
+	engine.SetCollectUsage(config.CollectUsage)
+	engine.SetResultCacheDir(config.ResultCacheDir)
+	engine.SetStorePath(config.StorePath)
+	if err := engine.SetGuardedCalls(config.GuardedCalls); err != nil {
+		return nil, errors.NewUserError("INVALID_GUARDED_CALL", err.Error())
+	}
+	if err := engine.SetNumberPolicy(config.Numbers); err != nil {
+		return nil, errors.NewUserError("INVALID_NUMBER_POLICY", err.Error())
+	}
+	if err := engine.SetStrictMode(config.Strict, config.StrictRules); err != nil {
+		return nil, errors.NewUserError("INVALID_STRICT_RULE", err.Error())
+	}
+	if err := engine.SetSegmentOverflow(config.SegmentOverflow); err != nil {
+		return nil, errors.NewUserError("INVALID_SEGMENT_OVERFLOW", err.Error())
+	}
+	if err := engine.SetPolicy(config.Policy); err != nil {
+		return nil, err
 	}
 
 	return engine, nil
 }
 
+// NewSession creates an engine for another session, e.g. another client of a
+// server. The session shares the parser, runtimes and job manager with e and
+// can run concurrently with it, but has its own scope, global and shared
+// variables, proxy handles and !nowait calls. Variables set inside a runtime
+// itself (py, lua, ...) are still seen by every session using that runtime
+func (e *ExecutionEngine) NewSession() *ExecutionEngine {
+	rootScope := sharedparser.NewScope(nil)
+	return &ExecutionEngine{
+		parser:           e.parser,
+		runtimeManager:   e.runtimeManager,
+		runtimeRegistry:  e.runtimeRegistry,
+		container:        e.container,
+		jobManager:       e.jobManager,
+		sharedVariables:  make(map[string]map[string]interface{}),
+		globals:          newGlobalStore(),
+		verbose:          e.verbose,
+		jobFinished:      make(chan struct{}),
+		localScope:       rootScope,
+		scopeStack:       []*sharedparser.Scope{rootScope},
+		runtimes:         e.runtimes,
+		spinnerThreshold: e.spinnerThreshold,
+		handles:          newHandleRegistry(),
+		memoryBudget:     e.memoryBudget,
+		customStatements: e.customStatements,
+		pendingCalls:     &sync.WaitGroup{},
+		numberPolicy:     e.numberPolicy,
+		matchWarnings:    e.matchWarnings,
+		guardRules:       e.guardRules,
+		policy:           e.policy,
+		usage:            e.usage,
+		resultCacheDir:   e.resultCacheDir,
+		storePath:        e.storePath,
+		strict:           e.strict,
+		segmentOverflow:  e.segmentOverflow,
+		limits:           newLimitRegistry(),
+		hooks:            newScriptHooks(),
+		docs:             e.docs,
+		rng:              e.rng,
+	}
+}
+
 // GetOrCreateRuntime получает рантайм из кэша или создает новый, если его нет
 func (e *ExecutionEngine) GetOrCreateRuntime(language string) (runtime.LanguageRuntime, error) {
-	// Сначала проверяем кэш
-	e.runtimeCacheMutex.RLock()
-	if cachedRuntime, exists := e.runtimeCache[language]; exists {
-		e.runtimeCacheMutex.RUnlock()
-		if e.verbose {
-			fmt.Printf("DEBUG: Using cached runtime for language '%s'\n", language)
-		}
-		return cachedRuntime, nil
+	if err := e.checkLanguage(language, ast.Position{}); err != nil {
+		return nil, err
 	}
-	e.runtimeCacheMutex.RUnlock()
-
-	// Если в кэше нет, создаем новый
-	e.runtimeCacheMutex.Lock()
-	defer e.runtimeCacheMutex.Unlock()
-
-	// Двойная проверка после получения блокировки записи
-	if cachedRuntime, exists := e.runtimeCache[language]; exists {
+	rt, cached, err := e.runtimes.get(language, func() (runtime.LanguageRuntime, error) {
 		if e.verbose {
-			fmt.Printf("DEBUG: Using cached runtime for language '%s' (double-check)\n", language)
+			fmt.Printf("DEBUG: Creating new runtime for language '%s'\n", language)
 		}
-		return cachedRuntime, nil
-	}
-
-	if e.verbose {
-		fmt.Printf("DEBUG: Creating new runtime for language '%s'\n", language)
-	}
-
-	// Создаем новый рантайм
-	newRuntime, err := e.runtimeRegistry.CreateRuntimeForLanguage(language)
-	if err != nil {
-		return nil, err
-	}
 
-	// Инициализируем рантайм
-	if err := newRuntime.Initialize(); err != nil {
-		return nil, fmt.Errorf("failed to initialize runtime for language '%s': %w", language, err)
-	}
+		// Создаем новый рантайм
+		newRuntime, err := e.runtimeRegistry.CreateRuntimeForLanguage(language)
+		if err != nil {
+			return nil, err
+		}
 
-	// Сохраняем в кэш
-	e.runtimeCache[language] = newRuntime
+		// Инициализируем рантайм
+		if err := newRuntime.Initialize(); err != nil {
+			return nil, fmt.Errorf("failed to initialize runtime for language '%s': %w", language, err)
+		}
 
-	if e.verbose {
-		fmt.Printf("DEBUG: Successfully created and cached runtime for language '%s'\n", language)
+		if e.verbose {
+			fmt.Printf("DEBUG: Successfully created and cached runtime for language '%s'\n", language)
+		}
+		return newRuntime, nil
+	})
+	if cached && err == nil && e.verbose {
+		fmt.Printf("DEBUG: Using cached runtime for language '%s'\n", language)
 	}
-
-	return newRuntime, nil
+	return rt, err
 }
@@ -1,9 +1,11 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"math/big"
+	"strings"
 
 	"funterm/errors"
 	"funterm/shared"
@@ -69,6 +71,20 @@ func (e *ExecutionEngine) executeBinaryExpression(binaryExpr *ast.BinaryExpressi
 	case ">=":
 		return e.executeComparisonGreaterEqual(leftValue, rightValue, binaryExpr.Position())
 
+	// Ranges
+	case "..", "..<":
+		return e.executeRange(leftValue, rightValue, binaryExpr.Operator == "..<", binaryExpr.Position())
+
+	// Membership operators
+	case "in":
+		return e.executeMembership(leftValue, rightValue, binaryExpr.Position())
+	case "not in":
+		found, err := e.executeMembership(leftValue, rightValue, binaryExpr.Position())
+		if err != nil {
+			return nil, err
+		}
+		return !found, nil
+
 	// Bitwise operators
 	case "&":
 		return e.executeBitwiseAnd(leftValue, rightValue, binaryExpr.Position())
@@ -101,6 +117,11 @@ func (e *ExecutionEngine) executeArithmeticAdd(left, right interface{}, pos ast.
 		fmt.Printf("DEBUG: executeArithmeticAdd - left type: %T, value: %v, right type: %T, value: %v\n", left, left, right, right)
 	}
 
+	// Exact integers beyond int64, e.g. big Python ints
+	if l, r, ok := bigIntOperands(left, right); ok {
+		return compactBigInt(new(big.Int).Add(l, r)), nil
+	}
+
 	// Handle numeric types
 	switch l := left.(type) {
 	case int64:
@@ -153,6 +174,10 @@ func (e *ExecutionEngine) executeArithmeticAdd(left, right interface{}, pos ast.
 
 // executeArithmeticSubtract handles subtraction operation
 func (e *ExecutionEngine) executeArithmeticSubtract(left, right interface{}, pos ast.Position) (interface{}, error) {
+	if l, r, ok := bigIntOperands(left, right); ok {
+		return compactBigInt(new(big.Int).Sub(l, r)), nil
+	}
+
 	switch l := left.(type) {
 	case int64:
 		switch r := right.(type) {
@@ -369,6 +394,9 @@ func (e *ExecutionEngine) executeArithmeticModulo(left, right interface{}, pos a
 
 // executeComparisonEqual handles equality comparison
 func (e *ExecutionEngine) executeComparisonEqual(left, right interface{}) (interface{}, error) {
+	if cmp, ok := compareBigNumbers(left, right); ok {
+		return cmp == 0, nil
+	}
 	// Handle different types with type conversion for numeric types
 	switch l := left.(type) {
 	case int64:
@@ -411,8 +439,8 @@ func (e *ExecutionEngine) executeComparisonEqual(left, right interface{}) (inter
 		return right == nil, nil
 	case *shared.BitstringObject:
 		if rBs, ok := right.(*shared.BitstringObject); ok {
-			lBytes := l.BitString.ToBytes()
-			rBytes := rBs.BitString.ToBytes()
+			lBytes := l.Bytes()
+			rBytes := rBs.Bytes()
 			if len(lBytes) != len(rBytes) {
 				return false, nil
 			}
@@ -438,8 +466,78 @@ func (e *ExecutionEngine) executeComparisonNotEqual(left, right interface{}) (in
 	return !(equal.(bool)), nil
 }
 
+// executeMembership checks item in collection: an element of an array (equal
+// as with ==) or a range, a key of a map, a substring of a string or a byte
+// sequence of a bitstring
+func (e *ExecutionEngine) executeMembership(item, collection interface{}, pos ast.Position) (bool, error) {
+	switch c := collection.(type) {
+	case []interface{}:
+		for _, element := range c {
+			equal, err := e.executeComparisonEqual(item, element)
+			if err != nil {
+				return false, err
+			}
+			if equal.(bool) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := c[key]
+		return found, nil
+	case string:
+		sub, ok := item.(string)
+		if !ok {
+			return false, errors.NewUserErrorWithASTPos("MEMBERSHIP_ERROR", fmt.Sprintf("'in <string>' requires a string on the left, got %s", valueTypeName(item)), pos)
+		}
+		return strings.Contains(c, sub), nil
+	case *shared.RangeObject:
+		switch n := item.(type) {
+		case int64:
+			return c.Contains(n), nil
+		case float64:
+			return n == math.Trunc(n) && n >= float64(c.Start) && n <= float64(c.Last()), nil
+		}
+		return false, nil
+	case *shared.BitstringObject:
+		sub, ok := item.(*shared.BitstringObject)
+		if !ok {
+			return false, errors.NewUserErrorWithASTPos("MEMBERSHIP_ERROR", fmt.Sprintf("'in <bitstring>' requires a bitstring on the left, got %s", valueTypeName(item)), pos)
+		}
+		if c.Len()%8 != 0 || sub.Len()%8 != 0 {
+			return false, errors.NewUserErrorWithASTPos("MEMBERSHIP_ERROR", "'in <bitstring>' requires whole bytes", pos)
+		}
+		return bytes.Contains(c.Bytes(), sub.Bytes()), nil
+	}
+	return false, errors.NewUserErrorWithASTPos("MEMBERSHIP_ERROR", fmt.Sprintf("cannot check membership in %s", valueTypeName(collection)), pos)
+}
+
+// executeRange builds the lazy value of start..end or start..<end; the
+// bounds must be integers, or whole floats such as len() returns
+func (e *ExecutionEngine) executeRange(start, end interface{}, exclusive bool, pos ast.Position) (interface{}, error) {
+	bounds := [2]int64{}
+	for i, bound := range []interface{}{start, end} {
+		n, ok := bound.(int64)
+		if f, isFloat := bound.(float64); isFloat && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			n, ok = int64(f), true
+		}
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("RANGE_ERROR", fmt.Sprintf("range bounds must be integers, got %s", valueTypeName(bound)), pos)
+		}
+		bounds[i] = n
+	}
+	return &shared.RangeObject{Start: bounds[0], End: bounds[1], Exclusive: exclusive}, nil
+}
+
 // executeComparisonLess handles less than comparison
 func (e *ExecutionEngine) executeComparisonLess(left, right interface{}, pos ast.Position) (interface{}, error) {
+	if cmp, ok := compareBigNumbers(left, right); ok {
+		return cmp < 0, nil
+	}
 	switch l := left.(type) {
 	case int64:
 		switch r := right.(type) {
@@ -479,6 +577,9 @@ func (e *ExecutionEngine) executeComparisonLess(left, right interface{}, pos ast
 
 // executeComparisonLessEqual handles less than or equal comparison
 func (e *ExecutionEngine) executeComparisonLessEqual(left, right interface{}, pos ast.Position) (interface{}, error) {
+	if cmp, ok := compareBigNumbers(left, right); ok {
+		return cmp <= 0, nil
+	}
 	switch l := left.(type) {
 	case int64:
 		switch r := right.(type) {
@@ -518,6 +619,9 @@ func (e *ExecutionEngine) executeComparisonLessEqual(left, right interface{}, po
 
 // executeComparisonGreater handles greater than comparison
 func (e *ExecutionEngine) executeComparisonGreater(left, right interface{}, pos ast.Position) (interface{}, error) {
+	if cmp, ok := compareBigNumbers(left, right); ok {
+		return cmp > 0, nil
+	}
 	switch l := left.(type) {
 	case int64:
 		switch r := right.(type) {
@@ -557,6 +661,9 @@ func (e *ExecutionEngine) executeComparisonGreater(left, right interface{}, pos
 
 // executeComparisonGreaterEqual handles greater than or equal comparison
 func (e *ExecutionEngine) executeComparisonGreaterEqual(left, right interface{}, pos ast.Position) (interface{}, error) {
+	if cmp, ok := compareBigNumbers(left, right); ok {
+		return cmp >= 0, nil
+	}
 	switch l := left.(type) {
 	case int64:
 		switch r := right.(type) {
@@ -870,15 +977,23 @@ func (e *ExecutionEngine) executeStringConcat(left, right interface{}) (interfac
 	leftBS, leftBSOk := left.(*shared.BitstringObject)
 	rightBS, rightBSOk := right.(*shared.BitstringObject)
 	if leftBSOk && rightBSOk {
+		// Whole-byte bitstrings are joined directly from their shared storage
+		if leftBS.Len()%8 == 0 && rightBS.Len()%8 == 0 {
+			leftBytes, rightBytes := leftBS.Bytes(), rightBS.Bytes()
+			combined := make([]byte, 0, len(leftBytes)+len(rightBytes))
+			combined = append(append(combined, leftBytes...), rightBytes...)
+			return shared.NewBitstringObjectFromBytes(combined), nil
+		}
+
 		// Concatenate bitstrings using funbit Builder
 		builder := funbit.NewBuilder()
-		funbit.AddBitstring(builder, leftBS.BitString, nil)
-		funbit.AddBitstring(builder, rightBS.BitString, nil)
+		funbit.AddBitstring(builder, leftBS.Bits())
+		funbit.AddBitstring(builder, rightBS.Bits())
 		combined, err := funbit.Build(builder)
 		if err != nil {
 			return nil, errors.NewUserError("BITSTRING_CONCAT_ERROR", fmt.Sprintf("failed to concatenate bitstrings: %v", err))
 		}
-		return &shared.BitstringObject{BitString: combined}, nil
+		return shared.NewBitstringObject(combined), nil
 	}
 
 	// Handle string concatenation (fallback for other types)
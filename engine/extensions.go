@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/parser"
+)
+
+// CustomStatementFunc executes a statement produced by a custom parser handler
+type CustomStatementFunc func(e *ExecutionEngine, stmt *ast.CustomStatement) (interface{}, error)
+
+// Parser returns the engine's parser, e.g. to inspect HandlerNames()
+func (e *ExecutionEngine) Parser() *parser.UnifiedParser {
+	return e.parser
+}
+
+// RegisterStatementHandler adds a parser handler for a new construct together with
+// the function that executes the *ast.CustomStatement values of the given kind it returns
+func (e *ExecutionEngine) RegisterStatementHandler(h common.Handler, cfg config.ConstructHandlerConfig, kind string, exec CustomStatementFunc) error {
+	if exec == nil {
+		return errors.NewSystemError("CUSTOM_STATEMENT_ERROR", fmt.Sprintf("no executor given for custom statement %q", kind))
+	}
+	if _, exists := e.customStatements[kind]; exists {
+		return errors.NewSystemError("CUSTOM_STATEMENT_ERROR", fmt.Sprintf("custom statement %q is already registered", kind))
+	}
+	if err := e.parser.RegisterHandler(h, cfg); err != nil {
+		return errors.NewSystemError("PARSER_HANDLER_ERROR", err.Error())
+	}
+	e.customStatements[kind] = exec
+	return nil
+}
+
+// Evaluate computes the value of an expression, for use by custom statement executors
+func (e *ExecutionEngine) Evaluate(expr ast.Expression) (interface{}, error) {
+	return e.convertExpressionToValue(expr)
+}
+
+// executeCustomStatement dispatches a custom statement to its registered executor
+func (e *ExecutionEngine) executeCustomStatement(stmt *ast.CustomStatement) (interface{}, error) {
+	exec, ok := e.customStatements[stmt.Kind]
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("UNSUPPORTED_STATEMENT", fmt.Sprintf("no executor registered for custom statement %q", stmt.Kind), stmt.Position())
+	}
+	if e.verbose {
+		fmt.Printf("DEBUG: executeCustomStatement - running %s\n", stmt.Kind)
+	}
+	return exec(e, stmt)
+}
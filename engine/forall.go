@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"funterm/errors"
+	"funterm/shared"
+	"go-parser/pkg/ast"
+)
+
+// defaultForallRuns is how many times forall runs its body without runs=
+const defaultForallRuns = 100
+
+// Variables through which funterm test --runs and --seed reach forall in the
+// scripts it runs; runs= and seed= in the script take precedence
+const (
+	forallRunsEnv = "FUNTERM_FORALL_RUNS"
+	forallSeedEnv = "FUNTERM_FORALL_SEED"
+)
+
+// executeForallStatement runs the body of forall(name = generator, ...) { ... }
+// runs times, evaluating every generator again and assigning its value to
+// name before each run. The engine's generator is seeded with seed for the
+// duration, so a failure comes back with the same seed. The first failing
+// run stops it with a PROPERTY_ERROR naming the run, the seed and the values
+func (e *ExecutionEngine) executeForallStatement(forall *ast.ForallStatement) (interface{}, error) {
+	runs, seed, err := e.forallOptions(forall)
+	if err != nil {
+		return nil, err
+	}
+	var bindings []*ast.NamedArgument
+	for _, argument := range forall.Arguments {
+		if argument.Name != "runs" && argument.Name != "seed" {
+			bindings = append(bindings, argument)
+		}
+	}
+
+	saved := e.rng.swap(rand.NewSource(seed).(rand.Source64))
+	defer e.rng.swap(saved)
+
+	for run := 1; run <= runs; run++ {
+		if err := e.checkDeadline(); err != nil {
+			return nil, err
+		}
+		values := make([]string, len(bindings))
+		for i, binding := range bindings {
+			value, err := e.convertExpressionToValue(binding.Value)
+			if err != nil {
+				return nil, err
+			}
+			e.setVariable(binding.Name, value)
+			values[i] = binding.Name + " = " + formatForallValue(value)
+		}
+		if _, err := e.executeBlockStatement(forall.Body); err != nil {
+			message := fmt.Sprintf("property failed on run %d of %d (seed=%d)", run, runs, seed)
+			if len(values) > 0 {
+				message += " with " + strings.Join(values, ", ")
+			}
+			return nil, errors.NewUserErrorWithASTPos("PROPERTY_ERROR", message+": "+err.Error(), forall.Pos)
+		}
+	}
+	return nil, nil
+}
+
+// forallOptions evaluates runs= and seed=, falling back to the variables
+// funterm test sets and then to 100 runs with a seed from the clock
+func (e *ExecutionEngine) forallOptions(forall *ast.ForallStatement) (int, int64, error) {
+	runs, seed := int64(defaultForallRuns), time.Now().UnixNano()
+	if text := os.Getenv(forallRunsEnv); text != "" {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil && n > 0 {
+			runs = n
+		}
+	}
+	if text := os.Getenv(forallSeedEnv); text != "" {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			seed = n
+		}
+	}
+
+	for _, argument := range forall.Arguments {
+		if argument.Name != "runs" && argument.Name != "seed" {
+			continue
+		}
+		value, err := e.convertExpressionToValue(argument.Value)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, ok := toInt64(value)
+		if f, isFloat := value.(float64); isFloat && f != float64(n) {
+			ok = false
+		}
+		if !ok || (argument.Name == "runs" && n < 1) {
+			expected := "a whole number"
+			if argument.Name == "runs" {
+				expected = "a positive whole number"
+			}
+			return 0, 0, errors.NewUserErrorWithASTPos("PROPERTY_ERROR", fmt.Sprintf("forall() option %s expects %s, got %v", argument.Name, expected, value), argument.Pos)
+		}
+		if argument.Name == "runs" {
+			runs = n
+		} else {
+			seed = n
+		}
+	}
+	return int(runs), seed, nil
+}
+
+// formatForallValue shows a generated value in a failure, strings quoted
+func formatForallValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	if value == nil {
+		return "nil"
+	}
+	return shared.FormatValueForDisplay(value)
+}
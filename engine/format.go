@@ -0,0 +1,349 @@
+package engine
+
+import (
+	"strings"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+	"go-parser/pkg/lexer"
+)
+
+// formatIndent is one level of indentation in formatted scripts
+const formatIndent = "    "
+
+// Format returns the script with consistent indentation. A script that
+// doesn't parse is not formatted
+func (e *ExecutionEngine) Format(code string) (string, error) {
+	statement, parseErrors := e.parser.Parse(code)
+	if len(parseErrors) > 0 {
+		return "", errors.NewUserErrorWithASTPos("PARSING_ERROR", parseErrors[0].Message, parseErrors[0].Position)
+	}
+	ast.ReleaseTree(statement)
+	return FormatSource(code), nil
+}
+
+// formatToken is a token with the end of its text in the source
+type formatToken struct {
+	lexer.Token
+	end int
+}
+
+// nativeBlock is a runtime code block spanning several lines
+type nativeBlock struct {
+	openLine, closeLine int // lines of the braces
+	openEnd             int // offset after the opening brace
+}
+
+// FormatSource indents a script by its brackets, four spaces a level, with
+// one more level for a line continuing an expression. Only whitespace at the
+// start and end of lines and runs of blank lines change:
+//   - the body of a native block is shifted as a whole, which the block
+//     handler undoes when it strips the common indentation
+//   - lines inside multi-line strings and block comments stay as they are
+//
+// Line endings are kept, so CRLF scripts stay CRLF
+func FormatSource(code string) string {
+	lines := strings.Split(code, "\n")
+	lineStarts := make([]int, len(lines))
+	for i := 1; i < len(lines); i++ {
+		lineStarts[i] = lineStarts[i-1] + len(lines[i-1]) + 1
+	}
+	lineOf := func(pos int) int {
+		low, high := 0, len(lineStarts)-1
+		for low < high {
+			mid := (low + high + 1) / 2
+			if lineStarts[mid] <= pos {
+				low = mid
+			} else {
+				high = mid - 1
+			}
+		}
+		return low
+	}
+
+	var tokens []formatToken
+	lex := lexer.NewLexer(code)
+	for {
+		token := lex.NextToken()
+		if token.Type == lexer.TokenEOF {
+			break
+		}
+		tokens = append(tokens, formatToken{Token: token, end: lex.Position()})
+	}
+
+	// Text spanning lines: lines starting inside it keep their text, the line
+	// it starts on keeps its end
+	verbatim := make([]bool, len(lines))
+	keepTail := make([]bool, len(lines))
+	span := func(from, to int) {
+		first, last := lineOf(from), lineOf(to)
+		if first == last {
+			return
+		}
+		keepTail[first] = true
+		for line := first + 1; line <= last; line++ {
+			verbatim[line] = true
+		}
+	}
+	gapStart := 0
+	for _, token := range tokens {
+		spanBlockComments(code, gapStart, token.Position, span)
+		if token.Type != lexer.TokenNewline {
+			span(token.Position, token.end-1)
+		}
+		gapStart = token.end
+	}
+	spanBlockComments(code, gapStart, len(code), span)
+
+	// Bracket depth and the tokens that start and end each line
+	depthBefore := make([]int, len(lines))
+	hasTokens := make([]bool, len(lines))
+	firstType := make([]lexer.TokenType, len(lines))
+	lastType := make([]lexer.TokenType, len(lines))
+	closers := make([]int, len(lines))
+	leading := make([]bool, len(lines))
+	blockClose := make([]bool, len(lines))
+	var blocks []nativeBlock
+
+	depth, nextLine := 0, 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Type == lexer.TokenNewline {
+			continue
+		}
+		line := lineOf(token.Position)
+		for ; nextLine <= line; nextLine++ {
+			depthBefore[nextLine] = depth
+		}
+		if !hasTokens[line] {
+			hasTokens[line] = true
+			firstType[line] = token.Type
+			leading[line] = true
+		}
+		if leading[line] && isCloser(token.Type) {
+			closers[line]++
+		} else {
+			leading[line] = false
+		}
+		lastType[line] = token.Type
+
+		switch {
+		case isOpener(token.Type):
+			depth++
+		case isCloser(token.Type):
+			if depth > 0 {
+				depth--
+			}
+		case isRuntimeToken(token.Type):
+			open, close := findNativeBlock(tokens, i)
+			if close < 0 {
+				break
+			}
+			// The code inside is not funterm's, its brackets don't count
+			openLine, closeLine := lineOf(tokens[open].Position), lineOf(tokens[close].Position)
+			lastType[openLine] = lexer.TokenLBrace
+			if openLine != closeLine {
+				blocks = append(blocks, nativeBlock{openLine, closeLine, tokens[open].end})
+				for ; nextLine <= closeLine; nextLine++ {
+					depthBefore[nextLine] = depth
+				}
+				if strings.TrimSpace(code[lineStarts[closeLine]:tokens[close].Position]) == "" {
+					hasTokens[closeLine] = true
+					firstType[closeLine] = lexer.TokenRBrace
+					blockClose[closeLine] = true
+				} else {
+					verbatim[closeLine] = true
+				}
+				lastType[closeLine] = lexer.TokenRBrace
+			}
+			i = close
+		}
+	}
+	for ; nextLine < len(lines); nextLine++ {
+		depthBefore[nextLine] = depth
+	}
+
+	// Indentation level of every line
+	levels := make([]int, len(lines))
+	previous := -1
+	for line := range lines {
+		levels[line] = depthBefore[line]
+		if !hasTokens[line] {
+			continue
+		}
+		switch {
+		case blockClose[line]:
+		case closers[line] > 0:
+			levels[line] -= closers[line]
+		case firstType[line] == lexer.TokenPipe || previous >= 0 && continuesLine(lastType[previous]):
+			levels[line]++
+		}
+		if levels[line] < 0 {
+			levels[line] = 0
+		}
+		previous = line
+	}
+
+	// Bodies of native blocks move with the statement holding them
+	body := make(map[int]string)
+	for _, block := range blocks {
+		openLine, closeLine := block.openLine, block.closeLine
+		tail := code[block.openEnd : lineStarts[openLine]+len(lines[openLine])]
+		shift := strings.TrimSpace(tail) == "" && blockClose[closeLine]
+		minIndent := -1
+		for line := openLine + 1; line < closeLine; line++ {
+			if strings.TrimSpace(lines[line]) == "" {
+				continue
+			}
+			indent := len(lines[line]) - len(strings.TrimLeft(lines[line], " "))
+			if minIndent < 0 || indent < minIndent {
+				minIndent = indent
+			}
+		}
+		if !shift {
+			keepTail[openLine] = true
+		}
+		for line := openLine + 1; line < closeLine; line++ {
+			text := lines[line]
+			if shift && strings.TrimSpace(text) != "" {
+				text = strings.Repeat(formatIndent, levels[openLine]+1) + text[minIndent:]
+			}
+			body[line] = text
+		}
+	}
+
+	var out []string
+	blank := false
+	for line, text := range lines {
+		if bodyText, inBody := body[line]; inBody {
+			out = append(out, bodyText)
+			blank = false
+			continue
+		}
+		if verbatim[line] {
+			out = append(out, text)
+			blank = false
+			continue
+		}
+		cr := strings.HasSuffix(text, "\r")
+		content := strings.TrimSpace(text)
+		if content == "" {
+			// At most one blank line in a row, none at the start
+			if !blank && len(out) > 0 {
+				if cr {
+					out = append(out, "\r")
+				} else {
+					out = append(out, "")
+				}
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		indent := strings.Repeat(formatIndent, levels[line])
+		switch {
+		case keepTail[line]:
+			out = append(out, indent+strings.TrimLeft(text, " \t"))
+		case cr:
+			out = append(out, indent+content+"\r")
+		default:
+			out = append(out, indent+content)
+		}
+	}
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// spanBlockComments reports the block comments between from and to, where
+// the lexer found only whitespace and comments
+func spanBlockComments(code string, from, to int, span func(from, to int)) {
+	for i := from; i < to; i++ {
+		switch {
+		case code[i] == '#' || strings.HasPrefix(code[i:to], "//") || strings.HasPrefix(code[i:to], "--"):
+			for i < to && code[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(code[i:to], "/*"):
+			end := strings.Index(code[i+2:to], "*/")
+			if end < 0 {
+				span(i, to-1)
+				return
+			}
+			span(i, i+2+end+1)
+			i += 2 + end + 1
+		}
+	}
+}
+
+// findNativeBlock returns the tokens of the braces of the code block started
+// by the runtime token at i, matched the way the block handler matches them;
+// close is -1 when the token doesn't start a block
+func findNativeBlock(tokens []formatToken, i int) (open, close int) {
+	j := skipNewlineTokens(tokens, i+1)
+	if j < len(tokens) && tokens[j].Type == lexer.TokenLeftParen {
+		for j++; j < len(tokens) && tokens[j].Type != lexer.TokenRightParen; j++ {
+			if t := tokens[j].Type; t != lexer.TokenIdentifier && t != lexer.TokenComma && t != lexer.TokenNewline {
+				return 0, -1
+			}
+		}
+		j = skipNewlineTokens(tokens, j+1)
+	}
+	if j >= len(tokens) || tokens[j].Type != lexer.TokenLBrace {
+		return 0, -1
+	}
+	level := 0
+	for k := j; k < len(tokens); k++ {
+		switch tokens[k].Type {
+		case lexer.TokenLBrace:
+			level++
+		case lexer.TokenRBrace:
+			level--
+			if level == 0 {
+				return j, k
+			}
+		}
+	}
+	return 0, -1
+}
+
+func skipNewlineTokens(tokens []formatToken, i int) int {
+	for i < len(tokens) && tokens[i].Type == lexer.TokenNewline {
+		i++
+	}
+	return i
+}
+
+func isOpener(t lexer.TokenType) bool {
+	return t == lexer.TokenLeftParen || t == lexer.TokenLParen || t == lexer.TokenLBracket || t == lexer.TokenLBrace
+}
+
+func isCloser(t lexer.TokenType) bool {
+	return t == lexer.TokenRightParen || t == lexer.TokenRParen || t == lexer.TokenRBracket || t == lexer.TokenRBrace
+}
+
+func isRuntimeToken(t lexer.TokenType) bool {
+	switch t {
+	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS,
+		lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
+		return true
+	}
+	return false
+}
+
+// continuesLine reports whether a line ending with the token goes on on the next line
+func continuesLine(t lexer.TokenType) bool {
+	switch t {
+	case lexer.TokenPlus, lexer.TokenMinus, lexer.TokenMultiply, lexer.TokenSlash, lexer.TokenModulo,
+		lexer.TokenConcat, lexer.TokenPower, lexer.TokenAnd, lexer.TokenOr, lexer.TokenEqual, lexer.TokenNotEqual,
+		lexer.TokenLess, lexer.TokenGreater, lexer.TokenLessEqual, lexer.TokenGreaterEqual, lexer.TokenAssign,
+		lexer.TokenColonEquals, lexer.TokenColon, lexer.TokenPipe, lexer.TokenBitwiseOr, lexer.TokenCaret,
+		lexer.TokenQuestion, lexer.TokenArrow, lexer.TokenDot:
+		return true
+	}
+	return false
+}
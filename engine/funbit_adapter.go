@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ type FunbitBitstringSpecifiers struct {
 	Signed     bool
 	Endianness string
 	Unit       uint
+	Overflow   string // overflow:error|truncate|saturate, empty for the engine default
 }
 
 // FunbitAdapter provides a bridge between funterm AST and funbit API
@@ -48,8 +50,10 @@ func NewFunbitAdapterWithEngine(engine *ExecutionEngine) *FunbitAdapter {
 	}
 }
 
-// addIntegerWithOverflowHandling safely adds an integer to the builder, handling overflow by truncation
-func (fa *FunbitAdapter) addIntegerWithOverflowHandling(builder *funbit.Builder, value interface{}, options ...funbit.SegmentOption) error {
+// addIntegerWithOverflowHandling safely adds an integer to the builder. A value
+// that doesn't fit the segment is truncated to its low bits, clamped to the
+// nearest value that fits or rejected, as the overflow mode says
+func (fa *FunbitAdapter) addIntegerWithOverflowHandling(builder *funbit.Builder, value interface{}, overflow string, options ...funbit.SegmentOption) error {
 	// Extract size and signedness from options
 	var segmentSize uint = 8 // Default size for integers
 	var isSigned bool = false
@@ -66,6 +70,31 @@ func (fa *FunbitAdapter) addIntegerWithOverflowHandling(builder *funbit.Builder,
 	}
 	isSigned = tempSegment.Signed
 
+	if overflow == "" && fa.engine != nil {
+		overflow = fa.engine.segmentOverflow
+	}
+	if overflow == OverflowError || overflow == OverflowSaturate {
+		if n, ok := integerValue(value); ok && segmentSize > 0 {
+			minValue, maxValue := segmentRange(segmentSize, isSigned)
+			if n.Cmp(minValue) < 0 || n.Cmp(maxValue) > 0 {
+				if overflow == OverflowError {
+					return fmt.Errorf("value %s does not fit in %d-bit %s segment (%s..%s)", n, segmentSize, signedness(isSigned), minValue, maxValue)
+				}
+				if n.Cmp(minValue) < 0 {
+					n = minValue
+				} else {
+					n = maxValue
+				}
+				if n.IsInt64() {
+					funbit.AddInteger(builder, n.Int64(), options...)
+				} else {
+					funbit.AddInteger(builder, n, options...)
+				}
+				return nil
+			}
+		}
+	}
+
 	// Handle *big.Int specially for negative values
 	if bigInt, ok := value.(*big.Int); ok && bigInt != nil && bigInt.Sign() < 0 {
 		if isSigned {
@@ -202,12 +231,20 @@ func (fa *FunbitAdapter) ExecuteBitstringExpression(expr *ast.BitstringExpressio
 	if len(expr.Segments) == 0 {
 		// Create empty bitstring using funbit.NewBitString()
 		emptyBitstring := funbit.NewBitString()
-		bitstringObject := &shared.BitstringObject{
-			BitString: emptyBitstring,
-		}
+		bitstringObject := shared.NewBitstringObject(emptyBitstring)
 		return bitstringObject, nil
 	}
 
+	// <<data/binary>> of an existing bitstring shares its storage instead of rebuilding it
+	if view, ok := fa.bitstringView(expr); ok {
+		return view, nil
+	}
+
+	// Length fields computed with size_of() need the later segments built first
+	if bitstringUsesSizeOf(expr) {
+		return fa.buildWithSizeOf(expr)
+	}
+
 	builder := funbit.NewBuilder()
 	totalBits := uint(0)
 
@@ -227,13 +264,59 @@ func (fa *FunbitAdapter) ExecuteBitstringExpression(expr *ast.BitstringExpressio
 	}
 
 	// Create BitstringObject
-	bitstringObject := &shared.BitstringObject{
-		BitString: bitstring,
-	}
+	bitstringObject := shared.NewBitstringObject(bitstring)
 
 	return bitstringObject, nil
 }
 
+// bitstringView handles <<v>>, <<v/binary>> and <<v/bitstring>> where v is a
+// variable holding a bitstring: the result is a view over v, not a copy.
+// Only plain variable reads qualify, so falling back never evaluates twice.
+func (fa *FunbitAdapter) bitstringView(expr *ast.BitstringExpression) (*shared.BitstringObject, bool) {
+	if len(expr.Segments) != 1 {
+		return nil, false
+	}
+	segment := expr.Segments[0]
+	if segment.Size != nil || segment.SizeExpression != nil || len(segment.Specifiers) > 1 {
+		return nil, false
+	}
+	ident, ok := segment.Value.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	value, err := fa.convertValue(ident)
+	if err != nil {
+		return nil, false
+	}
+	source, ok := value.(*shared.BitstringObject)
+	if !ok {
+		return nil, false
+	}
+
+	segmentType := "bitstring"
+	if len(segment.Specifiers) == 1 {
+		segmentType = segment.Specifiers[0]
+	}
+	switch segmentType {
+	case "bitstring", "bits":
+	case "binary", "bytes":
+		if source.Len()%8 != 0 {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	view, err := source.Slice(0, uint(source.Len()))
+	if err != nil {
+		return nil, false
+	}
+	if fa.verbose {
+		fmt.Printf("DEBUG: bitstringView - sharing %d bits of %s\n", source.Len(), ident.Name)
+	}
+	return view, true
+}
+
 // addSegment adds a single segment to the funbit builder and returns bits added
 func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.BitstringSegment) (uint, error) {
 	if fa.verbose {
@@ -265,21 +348,9 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 		}
 
 		// Convert size to uint to check if it's zero
-		var size uint
-		switch v := sizeValue.(type) {
-		case int64:
-			size = uint(v)
-		case float64:
-			size = uint(v)
-		case int:
-			size = uint(v)
-		case *big.Int:
-			if !v.IsUint64() {
-				return 0, fmt.Errorf("size value %s is too large (max: %d)", v.String(), ^uint64(0))
-			}
-			size = uint(v.Uint64())
-		default:
-			return 0, fmt.Errorf("unsupported size type: %T", sizeValue)
+		size, err := fa.segmentSize(sizeValue, segment.Size)
+		if err != nil {
+			return 0, err
 		}
 
 		// Skip zero-size segments entirely (padding/no-op)
@@ -359,20 +430,9 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 				}
 
 				// Convert size to uint
-				switch v := sizeValue.(type) {
-				case int64:
-					size = uint(v)
-				case float64:
-					size = uint(v)
-				case int:
-					size = uint(v)
-				case *big.Int:
-					if !v.IsUint64() {
-						return 0, fmt.Errorf("size value %s is too large (max: %d)", v.String(), ^uint64(0))
-					}
-					size = uint(v.Uint64())
-				default:
-					return 0, fmt.Errorf("unsupported size type: %T", sizeValue)
+				size, err = fa.segmentSize(sizeValue, segment.Size)
+				if err != nil {
+					return 0, err
 				}
 			}
 
@@ -431,7 +491,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 
 			switch v := value.(type) {
 			case int, int64, *big.Int:
-				err := fa.addIntegerWithOverflowHandling(builder, value, sizeOptions...)
+				err := fa.addIntegerWithOverflowHandling(builder, value, specs.Overflow, sizeOptions...)
 				if err != nil {
 					if segment.Value != nil {
 						if valExpr, ok := segment.Value.(ast.Expression); ok {
@@ -444,7 +504,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 			case float64:
 				// Check if the float value is actually a whole number
 				if v == float64(int(v)) {
-					err := fa.addIntegerWithOverflowHandling(builder, int(v), sizeOptions...)
+					err := fa.addIntegerWithOverflowHandling(builder, int(v), specs.Overflow, sizeOptions...)
 					if err != nil {
 						return 0, fmt.Errorf("failed to add integer: %v", err)
 					}
@@ -489,7 +549,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 				// Skip empty bitstrings - they cause "size must be positive" error in funbit.Build
 				if v.Len() > 0 {
 					// Don't pass size options when adding existing bitstring
-					funbit.AddBitstring(builder, v.BitString)
+					funbit.AddBitstring(builder, v.Bits())
 				}
 			default:
 				return 0, fmt.Errorf("unsupported value type for auto-detection: %T", value)
@@ -548,7 +608,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 					if floatVal == float64(int(floatVal)) {
 						// It's a whole number, convert to int
 						intVal := int(floatVal)
-						err := fa.addIntegerWithOverflowHandling(builder, intVal, integerOptions...)
+						err := fa.addIntegerWithOverflowHandling(builder, intVal, specs.Overflow, integerOptions...)
 						if err != nil {
 							return 0, fmt.Errorf("failed to add integer: %v", err)
 						}
@@ -561,7 +621,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 						return 0, fmt.Errorf("integer type requires whole number, got float %f", floatVal)
 					}
 				} else {
-					err := fa.addIntegerWithOverflowHandling(builder, value, integerOptions...)
+					err := fa.addIntegerWithOverflowHandling(builder, value, specs.Overflow, integerOptions...)
 					if err != nil {
 						if segment.Value != nil {
 							if valExpr, ok := segment.Value.(ast.Expression); ok {
@@ -583,7 +643,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 					// Skip empty bitstrings - they cause "size must be positive" error in funbit.Build
 					if bitstringObj.Len() > 0 {
 						// Don't pass size options when adding existing bitstring
-						funbit.AddBitstring(builder, bitstringObj.BitString)
+						funbit.AddBitstring(builder, bitstringObj.Bits())
 					}
 				} else {
 					return 0, fmt.Errorf("bitstring type requires *funbit.BitString or *shared.BitstringObject value, got %T", value)
@@ -690,7 +750,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 					// Skip empty bitstrings - they cause "size must be positive" error in funbit.Build
 					if bitstringObj.Len() > 0 {
 						// Don't pass size options when adding existing bitstring
-						funbit.AddBitstring(builder, bitstringObj.BitString)
+						funbit.AddBitstring(builder, bitstringObj.Bits())
 					}
 				} else if intValue, ok := value.(int); ok {
 					// For binary type, provide exactly effectiveSize bytes
@@ -764,7 +824,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 				if bitstring, ok := value.(*funbit.BitString); ok {
 					funbit.AddBitstring(builder, bitstring, options...)
 				} else if bitstringObj, ok := value.(*shared.BitstringObject); ok {
-					funbit.AddBitstring(builder, bitstringObj.BitString, options...)
+					funbit.AddBitstring(builder, bitstringObj.Bits(), options...)
 				} else {
 					if segment.Value != nil {
 						if valExpr, ok := segment.Value.(ast.Expression); ok {
@@ -846,7 +906,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 
 		switch v := value.(type) {
 		case int, int64, *big.Int:
-			err := fa.addIntegerWithOverflowHandling(builder, value, defaultOptions...)
+			err := fa.addIntegerWithOverflowHandling(builder, value, specs.Overflow, defaultOptions...)
 			if err != nil {
 				if segment.Value != nil {
 					if valExpr, ok := segment.Value.(ast.Expression); ok {
@@ -860,7 +920,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 			// Check if the float value is actually a whole number
 			if v == float64(int(v)) {
 				// It's a whole number, treat as integer
-				err := fa.addIntegerWithOverflowHandling(builder, int(v), defaultOptions...)
+				err := fa.addIntegerWithOverflowHandling(builder, int(v), specs.Overflow, defaultOptions...)
 				if err != nil {
 					if segment.Value != nil {
 						if valExpr, ok := segment.Value.(ast.Expression); ok {
@@ -887,7 +947,7 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 			}
 		case *shared.BitstringObject:
 			if v.Len() > 0 {
-				funbit.AddBitstring(builder, v.BitString)
+				funbit.AddBitstring(builder, v.Bits())
 				bitsAdded = uint(v.Len())
 			}
 		default:
@@ -903,6 +963,54 @@ func (fa *FunbitAdapter) addSegment(builder *funbit.Builder, segment *ast.Bitstr
 	return bitsAdded, nil
 }
 
+// segmentSize converts the value of a static segment size to bits or bytes.
+// A fractional size is truncated and a negative one wraps around, unless the
+// strict rules of the engine forbid it
+func (fa *FunbitAdapter) segmentSize(value interface{}, sizeExpr ast.Expression) (uint, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			if err := fa.coerce(StrictNegativeSizes, sizeExpr, "segment size %d is negative", v); err != nil {
+				return 0, err
+			}
+		}
+		return uint(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			if err := fa.coerce(StrictFloatTruncation, sizeExpr, "segment size %g is not a whole number", v); err != nil {
+				return 0, err
+			}
+		}
+		if v < 0 {
+			if err := fa.coerce(StrictNegativeSizes, sizeExpr, "segment size %g is negative", v); err != nil {
+				return 0, err
+			}
+		}
+		return uint(v), nil
+	case int:
+		return fa.segmentSize(int64(v), sizeExpr)
+	case *big.Int:
+		if !v.IsUint64() {
+			return 0, fmt.Errorf("size value %s is too large (max: %d)", v.String(), ^uint64(0))
+		}
+		return uint(v.Uint64()), nil
+	default:
+		return 0, fmt.Errorf("unsupported size type: %T", value)
+	}
+}
+
+// coerce applies a strict rule of the engine, if the adapter has one
+func (fa *FunbitAdapter) coerce(rule string, expr ast.Expression, format string, args ...interface{}) error {
+	if fa.engine == nil {
+		return nil
+	}
+	var pos ast.Position
+	if expr != nil {
+		pos = expr.Position()
+	}
+	return fa.engine.coerce(rule, pos, format, args...)
+}
+
 // convertValue converts an AST expression to a Go interface{} value
 func (fa *FunbitAdapter) convertValue(expr ast.Expression) (interface{}, error) {
 	switch e := expr.(type) {
@@ -989,6 +1097,12 @@ func (fa *FunbitAdapter) convertValue(expr ast.Expression) (interface{}, error)
 			return nil, fmt.Errorf("cannot evaluate ternary expressions without ExecutionEngine")
 		}
 		return fa.engine.convertExpressionToValue(e)
+	case *ast.BuiltinFunctionCall:
+		// Handle builtin calls such as size_of(body)
+		if fa.engine == nil {
+			return nil, fmt.Errorf("cannot evaluate builtin calls without ExecutionEngine")
+		}
+		return fa.engine.convertExpressionToValue(e)
 	default:
 		return nil, fmt.Errorf("unsupported expression type: %T", expr)
 	}
@@ -1012,6 +1126,18 @@ func (fa *FunbitAdapter) parseSpecifiers(specifiers []string) (FunbitBitstringSp
 	}
 
 	for _, spec := range specifiers {
+		// overflow:mode may close any specifier, e.g. "integer-overflow:error"
+		if rest, mode, found := cutOverflow(spec); found {
+			if err := ValidateSegmentOverflow(mode); err != nil || mode == "" {
+				return result, fmt.Errorf("invalid overflow value: %s, expected %s", mode, strings.Join(overflowModes, ", "))
+			}
+			result.Overflow = mode
+			if rest == "" {
+				continue
+			}
+			spec = rest
+		}
+
 		// Handle specifiers with parameters (e.g., "unit:8", "integer-unit:1", "little-signed-integer-unit:8")
 		if strings.Contains(spec, ":") {
 			parts := strings.Split(spec, ":")
@@ -1243,7 +1369,7 @@ func (fa *FunbitAdapter) calculatePatternSize(patternExpr *ast.BitstringExpressi
 
 		// Calculate segment size
 		segmentSize := uint(8) // Default size
-		if segment.Size != nil {
+		if segment.Size != nil && !fa.boundInPattern(segment.Size) {
 			if sizeValue, err := fa.convertValue(segment.Size); err == nil {
 				switch v := sizeValue.(type) {
 				case int64:
@@ -1278,6 +1404,17 @@ func (fa *FunbitAdapter) calculatePatternSize(patternExpr *ast.BitstringExpressi
 	return totalSize, nil
 }
 
+// boundInPattern reports a size naming a variable that doesn't exist yet,
+// such as size in <<size:8, data:size/binary>>: the pattern itself binds it
+func (fa *FunbitAdapter) boundInPattern(size ast.Expression) bool {
+	ident, ok := size.(*ast.Identifier)
+	if !ok || ident.Qualified || fa.engine == nil {
+		return false
+	}
+	_, found := fa.engine.getVariable(ident.Name)
+	return !found
+}
+
 // hasRestPattern checks if the pattern contains any rest patterns
 func (fa *FunbitAdapter) hasRestPattern(patternExpr *ast.BitstringExpression) bool {
 	// Only the last segment can be a rest pattern (without size)
@@ -1330,23 +1467,34 @@ func (fa *FunbitAdapter) patternHasBinarySegments(patternExpr *ast.BitstringExpr
 // if false, returns error on failure (for match statements)
 func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpression, data *shared.BitstringObject, returnFalseOnError bool) (map[string]interface{}, error) {
 	if fa.verbose {
-		fmt.Printf("DEBUG: MatchBitstringWithFunbit - input data size: %d bits, data: %s\n", data.BitString.Length(), funbit.ToBinaryString(data.BitString))
+		fmt.Printf("DEBUG: MatchBitstringWithFunbit - input data size: %d bits, data: %s\n", data.Bits().Length(), funbit.ToBinaryString(data.Bits()))
 		fmt.Printf("DEBUG: MatchBitstringWithFunbit - pattern has %d segments\n", len(patternExpr.Segments))
 	}
 
-	// Collect global variables for dynamic sizing if we have an ExecutionEngine
+	// Builtin calls in sizes are evaluated up front
+	if fa.engine != nil {
+		resolved, err := fa.resolveSizeCalls(patternExpr)
+		if err != nil {
+			if returnFalseOnError {
+				return map[string]interface{}{}, nil
+			}
+			return nil, err
+		}
+		patternExpr = resolved
+	}
+
+	// Collect the global variables the dynamic sizes refer to, if we have an ExecutionEngine.
+	// Patterns without dynamic sizes need none, so the globals are not touched at all.
 	globalVars := make(map[string]interface{})
 	bigIntVars := make(map[string]*big.Int)
-	if fa.engine != nil {
-		fa.engine.globalMutex.RLock()
-		for name, varInfo := range fa.engine.globalVariables {
-			globalVars[name] = varInfo.Value
+	if referenced := fa.sizeVariableNames(patternExpr); fa.engine != nil && len(referenced) > 0 {
+		globalVars = fa.engine.globals.lookup(referenced)
+		for name, value := range globalVars {
 			// Also collect big.Int variables separately for big int expression evaluation
-			if bigInt, ok := varInfo.Value.(*big.Int); ok {
+			if bigInt, ok := value.(*big.Int); ok {
 				bigIntVars[name] = bigInt
 			}
 		}
-		fa.engine.globalMutex.RUnlock()
 
 		if fa.verbose {
 			fmt.Printf("DEBUG: MatchBitstringWithFunbit - collected %d global variables: %v\n", len(globalVars), getMapKeys(globalVars))
@@ -1405,7 +1553,7 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 	}
 
 	// Execute the match
-	results, err := funbit.Match(matcher, data.BitString)
+	results, err := funbit.Match(matcher, data.Bits())
 	if err != nil {
 		if returnFalseOnError {
 			// For assignments: return empty bindings (false) instead of error
@@ -1503,8 +1651,8 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 										fmt.Printf("DEBUG: returning UTF-8 string for rest pattern field %s\n", variableNames[i])
 									}
 								} else {
-									bitString := funbit.NewBitStringFromBytes(bytes)
-									resultBindings[variableNames[i]] = &shared.BitstringObject{BitString: bitString}
+									// funbit returns a fresh slice, so the object can own it without another copy
+									resultBindings[variableNames[i]] = shared.NewBitstringObjectFromBytes(bytes)
 									if fa.verbose {
 										fmt.Printf("DEBUG: returning BitstringObject for rest pattern field %s\n", variableNames[i])
 									}
@@ -1523,8 +1671,8 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 										fmt.Printf("DEBUG: returning UTF-8 string for rest pattern field %s ([]uint8)\n", variableNames[i])
 									}
 								} else {
-									bitString := funbit.NewBitStringFromBytes(byteSlice)
-									resultBindings[variableNames[i]] = &shared.BitstringObject{BitString: bitString}
+									// funbit returns a fresh slice, so the object can own it without another copy
+									resultBindings[variableNames[i]] = shared.NewBitstringObjectFromBytes(byteSlice)
 									if fa.verbose {
 										fmt.Printf("DEBUG: returning BitstringObject for rest pattern field %s ([]uint8)\n", variableNames[i])
 									}
@@ -1538,7 +1686,7 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 								fmt.Printf("DEBUG: converting BitstringObject to string for field %s\n", variableNames[i])
 							}
 							// Convert BitstringObject back to string
-							bytes := bitstringObj.BitString.ToBytes()
+							bytes := bitstringObj.Bytes()
 							resultBindings[variableNames[i]] = string(bytes)
 						} else {
 							resultBindings[variableNames[i]] = result.Value
@@ -1553,7 +1701,7 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 					} else if specs.Type == "bitstring" {
 						// Convert *funbit.BitString to *shared.BitstringObject for proper Lua handling
 						if bitstring, ok := result.Value.(*funbit.BitString); ok {
-							resultBindings[variableNames[i]] = &shared.BitstringObject{BitString: bitstring}
+							resultBindings[variableNames[i]] = shared.NewBitstringObject(bitstring)
 						} else {
 							resultBindings[variableNames[i]] = result.Value
 						}
@@ -1583,6 +1731,28 @@ func (fa *FunbitAdapter) MatchBitstringWithFunbit(patternExpr *ast.BitstringExpr
 	return resultBindings, nil
 }
 
+// sizeVariableNames returns the names used by the dynamic sizes of a pattern,
+// including names bound by earlier segments
+func (fa *FunbitAdapter) sizeVariableNames(patternExpr *ast.BitstringExpression) []string {
+	var names []string
+	for _, segment := range patternExpr.Segments {
+		if !segment.IsDynamicSize || segment.SizeExpression == nil {
+			continue
+		}
+		switch {
+		case segment.SizeExpression.ExprType == "variable":
+			names = append(names, segment.SizeExpression.Variable)
+		case segment.SizeExpression.Variable != "":
+			names = append(names, fa.extractVariablesFromExpression(segment.SizeExpression.Variable)...)
+		case segment.SizeExpression.Expression != nil:
+			if text, err := ast.ExpressionToString(segment.SizeExpression.Expression); err == nil {
+				names = append(names, fa.extractVariablesFromExpression(text)...)
+			}
+		}
+	}
+	return names
+}
+
 // convertASTPatternToMatcher converts AST BitstringExpression to funbit matcher with variable names
 func (fa *FunbitAdapter) convertASTPatternToMatcher(patternExpr *ast.BitstringExpression) (*funbit.Matcher, []string, error) {
 	return fa.convertASTPatternToMatcherWithVars(patternExpr, make(map[string]interface{}))
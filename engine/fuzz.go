@@ -0,0 +1,23 @@
+package engine
+
+import "time"
+
+// fuzzBuiltins are the builtins the fuzzing sandbox allows: functions that
+// compute on values. Files, processes, the network, channels that can block
+// and runtimes stay out of reach
+var fuzzBuiltins = []string{
+	"id", "len", "concat", "print", "sort", "min", "max", "join", "random", "set_seed",
+	"hexdump", "bindiff", "validate_pattern", "validate", "diff", "assert_no_diff",
+	"crc32", "adler32", "crc16", "md5", "sha1", "sha256", "hmac", "byte_size", "bit_size",
+	"pack", "unpack", "capture", "gzip", "zlib", "asn1", "table", "gen",
+}
+
+// FuzzPolicy is the policy of an engine that runs random scripts: no
+// runtimes, only the builtins in fuzzBuiltins and limit per command
+func FuzzPolicy(limit time.Duration) *SessionPolicy {
+	return &SessionPolicy{
+		Languages:        []string{},
+		Builtins:         append([]string(nil), fuzzBuiltins...),
+		MaxExecutionTime: limit,
+	}
+}
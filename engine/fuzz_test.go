@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// FuzzExecute runs random scripts in an engine under FuzzPolicy, in an empty
+// directory. Script errors are expected, only a panic fails. The seed corpus
+// is tests/*.su, so go test runs every script once
+func FuzzExecute(f *testing.F) {
+	scripts, err := filepath.Glob(filepath.Join("..", "tests", "*.su"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, script := range scripts {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+	f.Chdir(f.TempDir())
+
+	f.Fuzz(func(t *testing.T, script string) {
+		eng, err := NewExecutionEngineWithConfig(ExecutionEngineConfig{SpinnerThreshold: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer eng.CleanupRuntimes()
+		if err := eng.SetPolicy(FuzzPolicy(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		eng.Execute(script)
+	})
+}
@@ -0,0 +1,474 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"funterm/errors"
+	"funterm/shared"
+	"github.com/funvibe/funbit/pkg/funbit"
+	"go-parser/pkg/ast"
+)
+
+// Limits of the random data gen.* makes where the caller gives none
+const (
+	genMaxRepeat    = 8  // extra repetitions of *, + and {n,} in gen.string
+	genMaxRestBytes = 16 // bytes of a binary segment without a size in gen.bitstring
+	genMaxSizeValue = 32 // largest value of a segment that sizes a later one
+)
+
+// callGenFunction runs the gen module, random data for tests drawn from the
+// engine's generator, which set_seed() and the seed of forall set: gen.bytes(n),
+// gen.bytes(min, max), gen.int(min, max), gen.string(pattern) and
+// gen.bitstring(pattern)
+func (e *ExecutionEngine) callGenFunction(function string, args []interface{}) (interface{}, error) {
+	name := "gen." + function
+	switch function {
+	case "bytes":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, genError("%s() requires a length or a minimum and maximum length", name)
+		}
+		low, high, err := genRange(name, args)
+		if err != nil {
+			return nil, err
+		}
+		if low.Sign() < 0 || !high.IsInt64() || high.Int64() > maxGeneratedBytes {
+			return nil, genError("%s() length must be between 0 and %d", name, maxGeneratedBytes)
+		}
+		data := make([]byte, e.randomBetween(low, high).Int64())
+		e.random().Read(data)
+		return shared.NewBitstringObjectFromBytes(data), nil
+	case "int":
+		if len(args) != 2 {
+			return nil, genError("%s() requires a minimum and a maximum", name)
+		}
+		low, high, err := genRange(name, args)
+		if err != nil {
+			return nil, err
+		}
+		return compactBigInt(e.randomBetween(low, high)), nil
+	case "string":
+		if len(args) != 1 {
+			return nil, genError("%s() requires a pattern", name)
+		}
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, genError("%s() expects the pattern as a string, got %s", name, orderTypeName(args[0]))
+		}
+		re, err := syntax.Parse(pattern, syntax.Perl)
+		if err != nil {
+			return nil, genError("%s(): invalid pattern %q: %v", name, pattern, err)
+		}
+		var b strings.Builder
+		e.generateString(&b, re.Simplify())
+		return b.String(), nil
+	case "bitstring":
+		if len(args) != 1 {
+			return nil, genError("%s() requires a pattern such as \"<<size:8, data:size/binary>>\"", name)
+		}
+		text, ok := args[0].(string)
+		if !ok {
+			return nil, genError("%s() expects the pattern as a string, got %s", name, orderTypeName(args[0]))
+		}
+		return e.generateBitstring(name, text)
+	}
+	return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("gen has no function '%s'; use bytes, int, string or bitstring", function))
+}
+
+// maxGeneratedBytes is the longest gen.bytes() result
+const maxGeneratedBytes = 1 << 24
+
+// genRange reads one bound, 0 to it, or two bounds
+func genRange(name string, args []interface{}) (*big.Int, *big.Int, error) {
+	bounds := make([]*big.Int, len(args))
+	for i, arg := range args {
+		n, ok := toBigInt(arg)
+		if f, isFloat := arg.(float64); isFloat && f == float64(int64(f)) {
+			n, ok = big.NewInt(int64(f)), true
+		}
+		if !ok {
+			return nil, nil, genError("%s() expects whole numbers, got %s", name, orderTypeName(arg))
+		}
+		bounds[i] = n
+	}
+	if len(bounds) == 1 {
+		return bounds[0], bounds[0], nil
+	}
+	if bounds[0].Cmp(bounds[1]) > 0 {
+		return nil, nil, genError("%s() requires min <= max, got %s > %s", name, bounds[0], bounds[1])
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// randomBetween returns a random integer in [low, high]
+func (e *ExecutionEngine) randomBetween(low, high *big.Int) *big.Int {
+	span := new(big.Int).Sub(high, low)
+	span.Add(span, big.NewInt(1))
+	n := new(big.Int).Rand(e.random(), span)
+	return n.Add(n, low)
+}
+
+// randomInt returns a random int in [low, high]
+func (e *ExecutionEngine) randomInt(low, high int) int {
+	return low + e.random().Intn(high-low+1)
+}
+
+// generateString writes a random string the regular expression re matches
+func (e *ExecutionEngine) generateString(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && e.random().Intn(2) == 0 {
+				r = swapCase(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(e.randomRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune(rune(e.randomInt(' ', '~')))
+	case syntax.OpCapture:
+		e.generateString(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			e.generateString(b, sub)
+		}
+	case syntax.OpAlternate:
+		e.generateString(b, re.Sub[e.random().Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		low, high := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			low, high = 0, -1
+		case syntax.OpPlus:
+			low, high = 1, -1
+		case syntax.OpQuest:
+			low, high = 0, 1
+		}
+		if high < 0 {
+			high = low + genMaxRepeat
+		}
+		for i := e.randomInt(low, high); i > 0; i-- {
+			e.generateString(b, re.Sub[0])
+		}
+	}
+	// Empty matches and anchors such as ^, $ and \b add nothing
+}
+
+// randomRune picks a rune from a character class, given as pairs of bounds;
+// classes that take in most of Unicode, such as [^a], are kept to printable ASCII
+func (e *ExecutionEngine) randomRune(ranges []rune) rune {
+	var total int64
+	for i := 0; i < len(ranges); i += 2 {
+		total += int64(ranges[i+1]-ranges[i]) + 1
+	}
+	if total == 0 {
+		return '?'
+	}
+	if total > 0x10000 {
+		var printable []rune
+		for i := 0; i < len(ranges); i += 2 {
+			lo, hi := max(ranges[i], ' '), min(ranges[i+1], '~')
+			if lo <= hi {
+				printable = append(printable, lo, hi)
+			}
+		}
+		if len(printable) > 0 {
+			ranges, total = printable, 0
+			for i := 0; i < len(ranges); i += 2 {
+				total += int64(ranges[i+1]-ranges[i]) + 1
+			}
+		}
+	}
+	pick := e.random().Int63n(total)
+	for i := 0; i < len(ranges); i += 2 {
+		size := int64(ranges[i+1]-ranges[i]) + 1
+		if pick < size {
+			return ranges[i] + rune(pick)
+		}
+		pick -= size
+	}
+	return ranges[0]
+}
+
+func swapCase(r rune) rune {
+	if upper := []rune(strings.ToUpper(string(r)))[0]; upper != r {
+		return upper
+	}
+	return []rune(strings.ToLower(string(r)))[0]
+}
+
+// bitWriter collects bits most significant first
+type bitWriter struct {
+	data   []byte
+	length int
+}
+
+// write appends the low bits of value, most significant first
+func (w *bitWriter) write(value *big.Int, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		w.writeBit(value.Bit(i))
+	}
+}
+
+// writeBytes appends whole bytes
+func (w *bitWriter) writeBytes(data []byte) {
+	for _, b := range data {
+		w.write(big.NewInt(int64(b)), 8)
+	}
+}
+
+func (w *bitWriter) writeBit(bit uint) {
+	if w.length%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if bit != 0 {
+		w.data[w.length/8] |= 1 << (7 - uint(w.length%8))
+	}
+	w.length++
+}
+
+// generateBitstring builds a random bitstring the pattern matches: literal
+// segments as written, variables random, and a variable that sizes a later
+// segment small enough to keep the data short
+func (e *ExecutionEngine) generateBitstring(name, text string) (interface{}, error) {
+	statement, parseErrors := e.parser.Parse(text)
+	if len(parseErrors) > 0 {
+		return nil, genError("%s() can't parse the pattern: %s", name, parseErrors[0].Message)
+	}
+	defer ast.ReleaseTree(statement)
+	pattern, ok := statement.(*ast.BitstringExpression)
+	if !ok {
+		return nil, genError("%s() expects a bitstring pattern such as \"<<size:8, data:size/binary>>\", got %q", name, text)
+	}
+
+	sizing := map[string]bool{}
+	for i := range pattern.Segments {
+		segment := &pattern.Segments[i]
+		if segment.SizeExpression != nil && segment.SizeExpression.ExprType == "variable" {
+			sizing[segment.SizeExpression.Variable] = true
+		}
+		if sizeExpr := segmentSizeExpression(segment); sizeExpr != nil {
+			ast.Inspect(sizeExpr, func(node ast.ProtoNode) {
+				if ident, ok := node.(*ast.Identifier); ok {
+					sizing[ident.Name] = true
+				}
+			})
+		}
+	}
+
+	bound := map[string]*big.Int{}
+	var w bitWriter
+	for i := range pattern.Segments {
+		segment := &pattern.Segments[i]
+		specs, problems := checkPatternSpecifiers(segment.Specifiers)
+		if len(problems) > 0 {
+			return nil, genError("%s(): %s: %s", name, patternSegmentName(i, segment), problems[0])
+		}
+		segmentType := specs.Type
+		if segmentType == "" {
+			segmentType = "integer"
+			if _, ok := segment.Value.(*ast.StringLiteral); ok {
+				segmentType = "binary"
+			}
+		}
+
+		bits := -1
+		if segment.Size != nil || segment.SizeExpression != nil {
+			var size int64
+			var err error
+			if sizeExpr := segmentSizeExpression(segment); sizeExpr != nil {
+				size, err = e.generatedSize(name, sizeExpr, bound)
+			} else {
+				size, err = e.generatedSizeOf(name, segment.SizeExpression.Variable, bound)
+			}
+			if err != nil {
+				return nil, err
+			}
+			unit := int64(specs.Unit)
+			if unit == 0 {
+				unit = 1
+				if segmentType == "binary" {
+					unit = 8
+				}
+			}
+			if size < 0 || size*unit > maxGeneratedBytes*8 {
+				return nil, genError("%s(): %s has size %d", name, patternSegmentName(i, segment), size)
+			}
+			bits = int(size * unit)
+		}
+
+		switch value := segment.Value.(type) {
+		case *ast.StringLiteral:
+			w.writeBytes([]byte(value.Value))
+			continue
+		case *ast.NumberLiteral, *ast.UnaryExpression:
+			n, ok := literalNumber(value)
+			if !ok || !n.IsInt() || segmentType != "integer" {
+				return nil, genError("%s(): %s: only integer literals can be generated", name, patternSegmentName(i, segment))
+			}
+			if bits < 0 {
+				bits = 8
+			}
+			integer, _ := n.Int(nil)
+			if integer.Sign() < 0 {
+				integer.Add(integer, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+			}
+			e.writeInteger(&w, integer, bits, specs.Endianness)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(segmentType, "utf"):
+			e.writeRandomRune(&w, segmentType)
+		case segmentType == "binary" || segmentType == "bitstring":
+			if bits < 0 {
+				bits = e.randomInt(0, genMaxRestBytes) * 8
+				if segmentType == "bitstring" {
+					bits = e.randomInt(0, genMaxRestBytes*8)
+				}
+			}
+			for j := 0; j < bits; j++ {
+				w.writeBit(uint(e.random().Intn(2)))
+			}
+		default:
+			if bits < 0 {
+				bits = 8
+				if segmentType == "float" {
+					bits = 64
+				}
+			}
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+			limit.Sub(limit, big.NewInt(1))
+			ident, isIdent := segment.Value.(*ast.Identifier)
+			if isIdent && sizing[ident.Name] && limit.Cmp(big.NewInt(genMaxSizeValue)) > 0 {
+				limit = big.NewInt(genMaxSizeValue)
+			}
+			n := e.randomBetween(new(big.Int), limit)
+			if isIdent {
+				bound[ident.Name] = n
+			}
+			e.writeInteger(&w, n, bits, specs.Endianness)
+		}
+	}
+
+	if w.length%8 == 0 {
+		return shared.NewBitstringObjectFromBytes(w.data), nil
+	}
+	return shared.NewBitstringObject(funbit.NewBitStringFromBits(w.data, uint(w.length))), nil
+}
+
+// segmentSizeExpression returns the size of a segment as written; nil
+// without one or when it is just a variable name
+func segmentSizeExpression(segment *ast.BitstringSegment) ast.Expression {
+	if segment.Size != nil {
+		return segment.Size
+	}
+	if segment.SizeExpression == nil {
+		return nil
+	}
+	switch segment.SizeExpression.ExprType {
+	case "literal":
+		return segment.SizeExpression.Literal
+	case "variable":
+		return nil
+	}
+	return segment.SizeExpression.Expression
+}
+
+// generatedSizeOf returns the value of the variable that sizes a segment
+func (e *ExecutionEngine) generatedSizeOf(name, variable string, bound map[string]*big.Int) (int64, error) {
+	if n, ok := bound[variable]; ok {
+		return n.Int64(), nil
+	}
+	value, found := e.getVariable(variable)
+	if !found {
+		return 0, genError("%s(): the size %s is not bound before it is used", name, variable)
+	}
+	size, ok := toInt64(value)
+	if !ok {
+		return 0, genError("%s() expects sizes to be whole numbers, got %v", name, value)
+	}
+	return size, nil
+}
+
+// generatedSize evaluates the size of a segment: a number, a variable bound
+// earlier in the pattern or in the script, or arithmetic on them
+func (e *ExecutionEngine) generatedSize(name string, expr ast.Expression, bound map[string]*big.Int) (int64, error) {
+	var value interface{}
+	switch x := expr.(type) {
+	case *ast.Identifier:
+		return e.generatedSizeOf(name, x.Name, bound)
+	case *ast.BinaryExpression:
+		left, err := e.generatedSize(name, x.Left, bound)
+		if err != nil {
+			return 0, err
+		}
+		right, err := e.generatedSize(name, x.Right, bound)
+		if err != nil {
+			return 0, err
+		}
+		switch x.Operator {
+		case "+":
+			return left + right, nil
+		case "-":
+			return left - right, nil
+		case "*":
+			return left * right, nil
+		case "/":
+			if right != 0 {
+				return left / right, nil
+			}
+		}
+		return 0, genError("%s() can't compute the size %s", name, x.String())
+	}
+	if value == nil {
+		var err error
+		if value, err = e.convertExpressionToValue(expr); err != nil {
+			return 0, err
+		}
+	}
+	size, ok := toInt64(value)
+	if !ok {
+		return 0, genError("%s() expects sizes to be whole numbers, got %v", name, value)
+	}
+	return size, nil
+}
+
+// writeInteger writes n in bits bits, byte-swapped for little endian
+func (e *ExecutionEngine) writeInteger(w *bitWriter, n *big.Int, bits int, endianness string) {
+	if endianness != "little" || bits%8 != 0 {
+		w.write(n, bits)
+		return
+	}
+	for i := 0; i < bits/8; i++ {
+		w.write(new(big.Int).And(new(big.Int).Rsh(n, uint(i*8)), big.NewInt(0xff)), 8)
+	}
+}
+
+// writeRandomRune writes a random printable character in the encoding of a utf segment
+func (e *ExecutionEngine) writeRandomRune(w *bitWriter, segmentType string) {
+	runes := []rune("azAZ09 é€𝄞")
+	r := runes[e.random().Intn(len(runes))]
+	if e.random().Intn(2) == 0 {
+		r = rune(e.randomInt('a', 'z'))
+	}
+	switch segmentType {
+	case "utf16":
+		for _, unit := range utf16.Encode([]rune{r}) {
+			w.write(big.NewInt(int64(unit)), 16)
+		}
+	case "utf32":
+		w.write(big.NewInt(int64(r)), 32)
+	default:
+		w.writeBytes(utf8.AppendRune(nil, r))
+	}
+}
+
+func genError(format string, args ...interface{}) error {
+	return errors.NewUserError("GEN_ARGUMENT_ERROR", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// guardRule is a guarded call pattern such as sh.*, py.os.remove or
+// sql.execute("DROP%")
+type guardRule struct {
+	pattern string         // the pattern as configured
+	name    string         // glob over language.function, with the language alias resolved
+	arg     *regexp.Regexp // pattern of the first argument, nil matches any call
+}
+
+// guardLanguages resolves language aliases in patterns, as calls do
+var guardLanguages = map[string]string{"py": "python", "js": "node", "pl": "perl", "erl": "erlang", "ex": "elixir"}
+
+// parseGuardRule parses language.function, where * matches any part of the
+// name, optionally followed by ("...") with an SQL LIKE pattern of the first
+// argument: % matches any text, _ one character, case is ignored
+func parseGuardRule(pattern string) (guardRule, error) {
+	rule := guardRule{pattern: pattern}
+	name := strings.TrimSpace(pattern)
+	if open := strings.Index(name, "("); open >= 0 {
+		arg := strings.TrimSpace(name[open:])
+		name = strings.TrimSpace(name[:open])
+		if len(arg) < 4 || arg[len(arg)-1] != ')' || (arg[1] != '"' && arg[1] != '\'') || arg[len(arg)-2] != arg[1] {
+			return rule, fmt.Errorf("invalid guarded call %q: expected name(\"pattern\")", pattern)
+		}
+		rule.arg = likePattern(arg[2 : len(arg)-2])
+	}
+
+	dot := strings.Index(name, ".")
+	if dot <= 0 || dot == len(name)-1 {
+		return rule, fmt.Errorf("invalid guarded call %q: expected language.function", pattern)
+	}
+	language := name[:dot]
+	if canonical, isAlias := guardLanguages[language]; isAlias {
+		language = canonical
+	}
+	rule.name = language + name[dot:]
+	if _, err := path.Match(rule.name, ""); err != nil {
+		return rule, fmt.Errorf("invalid guarded call %q: %v", pattern, err)
+	}
+	return rule, nil
+}
+
+// likePattern converts an SQL LIKE pattern to a case-insensitive regexp
+func likePattern(like string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for _, r := range like {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// matches reports whether the call of language.function with args is guarded
+func (rule guardRule) matches(language, function string, args []interface{}) bool {
+	if matched, _ := path.Match(rule.name, language+"."+function); !matched {
+		return false
+	}
+	if rule.arg == nil {
+		return true
+	}
+	if len(args) == 0 {
+		return false
+	}
+	text, ok := args[0].(string)
+	return ok && rule.arg.MatchString(text)
+}
+
+// SetGuardedCalls sets the patterns of calls that run only after the user
+// confirms them, see parseGuardRule for the syntax
+func (e *ExecutionEngine) SetGuardedCalls(patterns []string) error {
+	rules := make([]guardRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		rule, err := parseGuardRule(pattern)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	e.guardRules = rules
+	return nil
+}
+
+// ValidateGuardedCalls checks guarded call patterns without an engine, for
+// configuration loading
+func ValidateGuardedCalls(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseGuardRule(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCallConfirmation sets the function asking the user whether a guarded call
+// may run. Without one, as in batch mode, guarded calls are denied
+func (e *ExecutionEngine) SetCallConfirmation(confirm func(call string) bool) {
+	e.confirmCall = confirm
+}
+
+// guardCall returns an error unless the call matches no guarded pattern or the
+// user confirms it
+func (e *ExecutionEngine) guardCall(language, function string, args []interface{}, pos ast.Position) error {
+	for _, rule := range e.guardRules {
+		if !rule.matches(language, function, args) {
+			continue
+		}
+		call := describeCall(language, function, args)
+		if e.confirmCall == nil {
+			return errors.NewUserErrorWithASTPos("CALL_DENIED", fmt.Sprintf("%s matches guarded call %s and can only run after confirmation in an interactive session", call, rule.pattern), pos)
+		}
+		// The spinner of the call would draw over the question
+		atomic.AddInt32(&e.spinnerHeld, 1)
+		if isTerminal(os.Stderr) {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+		confirmed := e.confirmCall(call)
+		atomic.AddInt32(&e.spinnerHeld, -1)
+		if !confirmed {
+			return errors.NewUserErrorWithASTPos("CALL_DENIED", fmt.Sprintf("%s was not confirmed", call), pos)
+		}
+		return nil
+	}
+	return nil
+}
+
+// describeCall formats a call for the confirmation question, shortening long arguments
+func describeCall(language, function string, args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		text := fmt.Sprintf("%#v", arg)
+		if s, ok := arg.(string); ok {
+			text = fmt.Sprintf("%q", s)
+		}
+		if len(text) > 40 {
+			text = text[:37] + "..."
+		}
+		parts[i] = text
+	}
+	return fmt.Sprintf("%s.%s(%s)", language, function, strings.Join(parts, ", "))
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+
+	"funterm/errors"
+	"funterm/runtime"
+	"go-parser/pkg/ast"
+)
+
+// handleRegistry holds the live proxy handles of a session. Background tasks
+// share it with the engine that started them, so :gc and the leak warnings
+// also see handles created in the background
+type handleRegistry struct {
+	mu      sync.Mutex
+	handles map[string]*runtime.Handle
+}
+
+// newHandleRegistry creates an empty registry
+func newHandleRegistry() *handleRegistry {
+	return &handleRegistry{handles: make(map[string]*runtime.Handle)}
+}
+
+// add remembers a live handle
+func (r *handleRegistry) add(handle *runtime.Handle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handles[handleKey(handle)] = handle
+}
+
+// remove forgets a released handle
+func (r *handleRegistry) remove(handle *runtime.Handle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.handles, handleKey(handle))
+}
+
+// list returns a snapshot of the live handles
+func (r *handleRegistry) list() []*runtime.Handle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	handles := make([]*runtime.Handle, 0, len(r.handles))
+	for _, handle := range r.handles {
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// executeProxyFunction runs proxy(lang.func(args)): the call result stays in
+// the runtime and a handle to it is returned instead of a deep copy
+func (e *ExecutionEngine) executeProxyFunction(call *ast.BuiltinFunctionCall) (interface{}, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.NewUserErrorWithASTPos("PROXY_ARGUMENT_ERROR", "proxy() requires exactly one argument", call.Position())
+	}
+	langCall, ok := call.Arguments[0].(*ast.LanguageCall)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("PROXY_ARGUMENT_ERROR", "proxy() argument must be a runtime call, e.g. proxy(py.load(path))", call.Position())
+	}
+
+	language := langCall.Language
+	if language == "py" {
+		language = "python"
+	}
+	rt, err := e.getRuntimeByName(language)
+	if err != nil {
+		return nil, err
+	}
+	handleRuntime, ok := rt.(runtime.HandleRuntime)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("PROXY_NOT_SUPPORTED", fmt.Sprintf("%s runtime does not support proxy handles", rt.GetName()), call.Position())
+	}
+
+	args, err := e.convertCallArguments(rt.GetName(), langCall.Arguments, call.Position())
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("ARGUMENT_CONVERSION_ERROR", fmt.Sprintf("argument conversion error: %v", err), call.Position())
+	}
+	if err := e.checkHandleArgs(rt.GetName(), args); err != nil {
+		return nil, err
+	}
+
+	handle, err := handleRuntime.ExecuteFunctionAsHandle(langCall.Function, args)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("EXECUTION_ERROR", fmt.Sprintf("execution error: %v", err), call.Position())
+	}
+
+	if e.handles == nil {
+		e.handles = newHandleRegistry()
+	}
+	e.handles.add(handle)
+	if e.verbose {
+		fmt.Printf("DEBUG: proxy - created handle %s\n", handle)
+	}
+	return handle, nil
+}
+
+// executeReleaseFunction frees the runtime objects behind handles: release(h, ...)
+func (e *ExecutionEngine) executeReleaseFunction(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.NewUserError("RELEASE_ARGUMENT_ERROR", "release() requires at least one handle")
+	}
+	for _, arg := range args {
+		handle, ok := arg.(*runtime.Handle)
+		if !ok {
+			return nil, errors.NewUserError("RELEASE_ARGUMENT_ERROR", fmt.Sprintf("release() expects a proxy handle, got %T", arg))
+		}
+		if handle.Released() {
+			return nil, errors.NewUserError("HANDLE_RELEASED", fmt.Sprintf("handle %s is already released", handle.ID))
+		}
+		if err := e.releaseHandle(handle); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// releaseHandle frees a single handle in its runtime and forgets it
+func (e *ExecutionEngine) releaseHandle(handle *runtime.Handle) error {
+	rt, err := e.getRuntimeByName(handle.Language)
+	if err != nil {
+		return err
+	}
+	if handleRuntime, ok := rt.(runtime.HandleRuntime); ok {
+		if err := handleRuntime.ReleaseHandle(handle); err != nil {
+			return errors.NewRuntimeError(handle.Language, "RELEASE_FAILED", fmt.Sprintf("failed to release handle %s: %v", handle.ID, err))
+		}
+	}
+	handle.MarkReleased()
+	if e.handles != nil {
+		e.handles.remove(handle)
+	}
+	return nil
+}
+
+// checkHandleArgs rejects handles that were released or belong to another runtime
+func (e *ExecutionEngine) checkHandleArgs(language string, args []interface{}) error {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case *runtime.Handle:
+			if v.Released() {
+				return errors.NewUserError("HANDLE_RELEASED", fmt.Sprintf("handle %s was released and can no longer be used", v.ID))
+			}
+			if v.Language != language {
+				return errors.NewUserError("HANDLE_LANGUAGE_MISMATCH", fmt.Sprintf("handle %s lives in %s and cannot be passed to %s", v.ID, v.Language, language))
+			}
+		case []interface{}:
+			if err := e.checkHandleArgs(language, v); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			for _, item := range v {
+				if err := e.checkHandleArgs(language, []interface{}{item}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// executeMethodChain runs lang.func(args).m1(...).m2(...): every call but
+// the last keeps its result in the runtime behind a temporary handle, so the
+// methods run on the runtime object rather than on a copy of it
+func (e *ExecutionEngine) executeMethodChain(rt runtime.LanguageRuntime, call *ast.LanguageCall, args []interface{}) (interface{}, error) {
+	handleRuntime, ok := rt.(runtime.HandleRuntime)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("METHOD_CHAIN_NOT_SUPPORTED", fmt.Sprintf("%s runtime does not support calling methods on returned objects", rt.GetName()), call.Position())
+	}
+	handle, err := handleRuntime.ExecuteFunctionAsHandle(call.Function, args)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("EXECUTION_ERROR", fmt.Sprintf("execution error: %v", err), call.Position())
+	}
+	return e.callMethodChain(handle, call.Chain, true)
+}
+
+// callMethodChain calls the chained methods one after another on a handle.
+// A temporary handle is released as soon as the next method has run
+func (e *ExecutionEngine) callMethodChain(handle *runtime.Handle, chain []*ast.MethodCall, temporary bool) (interface{}, error) {
+	var result interface{}
+	for i, method := range chain {
+		args, err := e.convertCallArguments(handle.Language, method.Arguments, method.Pos)
+		if err == nil {
+			result, err = e.callHandleMethod(handle, method.Method, args, i < len(chain)-1, method.Pos)
+		}
+		if temporary {
+			if releaseErr := e.releaseHandle(handle); releaseErr != nil && e.verbose {
+				fmt.Printf("DEBUG: failed to release temporary handle %s: %v\n", handle, releaseErr)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i < len(chain)-1 {
+			handle, temporary = result.(*runtime.Handle), true
+		}
+	}
+	return result, nil
+}
+
+// callHandleMethod calls a method of the runtime object behind a handle;
+// with keep the result stays in the runtime behind a new handle
+func (e *ExecutionEngine) callHandleMethod(handle *runtime.Handle, method string, args []interface{}, keep bool, pos ast.Position) (interface{}, error) {
+	if handle.Released() {
+		return nil, errors.NewUserErrorWithASTPos("HANDLE_RELEASED", fmt.Sprintf("handle %s was released and can no longer be used", handle.ID), pos)
+	}
+	rt, err := e.getRuntimeByName(handle.Language)
+	if err != nil {
+		return nil, err
+	}
+	handleRuntime, ok := rt.(runtime.HandleRuntime)
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("PROXY_NOT_SUPPORTED", fmt.Sprintf("%s runtime does not support proxy handles", rt.GetName()), pos)
+	}
+	if err := e.checkHandleArgs(handle.Language, args); err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		args[i] = runtime.ConvertToRuntime(handle.Language, arg)
+	}
+	result, err := handleRuntime.CallHandleMethod(handle, method, args, keep)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("EXECUTION_ERROR", fmt.Sprintf("execution error: %v", err), pos)
+	}
+	if keep {
+		return result, nil
+	}
+	return e.normalizeNumbers(handle.Language, result), nil
+}
+
+// handleKey identifies a handle across runtimes
+func handleKey(handle *runtime.Handle) string {
+	return handle.Language + ":" + handle.ID
+}
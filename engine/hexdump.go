@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"funterm/errors"
+	"funterm/shared"
+
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// defaultHexdumpWidth is the number of bytes per hexdump() line
+const defaultHexdumpWidth = 16
+
+// binaryArgument accepts a bitstring or a string (as its UTF-8 bytes)
+func binaryArgument(function string, value interface{}) (*shared.BitstringObject, error) {
+	switch v := value.(type) {
+	case *shared.BitstringObject:
+		return v, nil
+	case *funbit.BitString:
+		return shared.NewBitstringObject(v), nil
+	case shared.BitstringByte:
+		return shared.NewBitstringObjectFromBytes([]byte{v.Value}), nil
+	case string:
+		return shared.NewBitstringObjectFromBytes([]byte(v)), nil
+	case []byte:
+		return shared.NewBitstringObjectFromBytes(v), nil
+	}
+	return nil, errors.NewUserError("BINARY_ARGUMENT_ERROR", fmt.Sprintf("%s() expects a bitstring or a string, got %s", function, orderTypeName(value)))
+}
+
+// executeHexdumpFunction implements hexdump(bits, width?): lines of offset,
+// hex bytes and printable ASCII, followed by the total length
+func (e *ExecutionEngine) executeHexdumpFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("HEXDUMP_ARGUMENT_ERROR", "hexdump() requires a bitstring and an optional line width")
+	}
+	bits, err := binaryArgument("hexdump", args[0])
+	if err != nil {
+		return nil, err
+	}
+	width := defaultHexdumpWidth
+	if len(args) == 2 {
+		n, ok := toInt64(args[1])
+		if !ok || n < 1 {
+			return nil, errors.NewUserError("HEXDUMP_ARGUMENT_ERROR", fmt.Sprintf("hexdump() width must be a positive integer, got %v", args[1]))
+		}
+		width = int(n)
+	}
+	return hexdump(bits.Bytes(), bits.Len(), width), nil
+}
+
+// hexdump formats data in the style of hexdump -C. A trailing partial byte
+// is shown padded with zero bits and noted in the final length line.
+func hexdump(data []byte, bitLength, width int) string {
+	if bitLength == 0 {
+		return ""
+	}
+
+	// Ширина шестнадцатеричной колонки: 3 символа на байт и пробел между группами по 8
+	hexWidth := width*3 + (width-1)/8
+
+	var builder strings.Builder
+	for offset := 0; offset < len(data); offset += width {
+		end := offset + width
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[offset:end]
+
+		var hex strings.Builder
+		for i, b := range line {
+			if i > 0 && i%8 == 0 {
+				hex.WriteByte(' ')
+			}
+			fmt.Fprintf(&hex, "%02x ", b)
+		}
+
+		ascii := make([]byte, len(line))
+		for i, b := range line {
+			if b >= 0x20 && b < 0x7f {
+				ascii[i] = b
+			} else {
+				ascii[i] = '.'
+			}
+		}
+
+		fmt.Fprintf(&builder, "%08x  %-*s |%s|\n", offset, hexWidth, hex.String(), ascii)
+	}
+
+	fmt.Fprintf(&builder, "%08x", bitLength/8)
+	if extra := bitLength % 8; extra != 0 {
+		fmt.Fprintf(&builder, " +%d %s", extra, pluralBits(extra))
+	}
+	return builder.String()
+}
+
+// executeBindiffFunction implements bindiff(a, b): one line per run of
+// differing bits, plus a line for bits present in only one of the values
+func (e *ExecutionEngine) executeBindiffFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("BINDIFF_ARGUMENT_ERROR", "bindiff() requires exactly two bitstrings")
+	}
+	a, err := binaryArgument("bindiff", args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, err := binaryArgument("bindiff", args[1])
+	if err != nil {
+		return nil, err
+	}
+	return bindiff(a.Bytes(), a.Len(), b.Bytes(), b.Len()), nil
+}
+
+// bindiff describes where two bit sequences differ
+func bindiff(a []byte, aLength int, b []byte, bLength int) string {
+	common := aLength
+	if bLength < common {
+		common = bLength
+	}
+
+	var lines []string
+	for start := 0; start < common; start++ {
+		if bitAt(a, start) == bitAt(b, start) {
+			continue
+		}
+		end := start
+		for end+1 < common && bitAt(a, end+1) != bitAt(b, end+1) {
+			end++
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s vs %s", describeBitRange(start, end),
+			formatBitRange(a, start, end), formatBitRange(b, start, end)))
+		start = end
+	}
+
+	if aLength != bLength {
+		longer := "a"
+		if bLength > aLength {
+			longer = "b"
+		}
+		longest := aLength
+		if bLength > longest {
+			longest = bLength
+		}
+		lines = append(lines, fmt.Sprintf("length: %d vs %d bits, %s only in %s",
+			aLength, bLength, describeBitRange(common, longest-1), longer))
+	}
+
+	if len(lines) == 0 {
+		return fmt.Sprintf("identical (%d %s)", aLength, pluralBits(aLength))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// bitAt returns bit i of data, most significant bit first
+func bitAt(data []byte, i int) byte {
+	return (data[i/8] >> (7 - uint(i%8))) & 1
+}
+
+// describeBitRange names an inclusive bit range and the bytes it falls in
+func describeBitRange(start, end int) string {
+	var bits string
+	if start == end {
+		bits = fmt.Sprintf("bit %d", start)
+	} else {
+		bits = fmt.Sprintf("bits %d..%d", start, end)
+	}
+	if start/8 == end/8 {
+		return fmt.Sprintf("%s (byte %d)", bits, start/8)
+	}
+	return fmt.Sprintf("%s (bytes %d..%d)", bits, start/8, end/8)
+}
+
+// formatBitRange shows whole bytes in hex and other ranges of up to 32 bits in binary
+func formatBitRange(data []byte, start, end int) string {
+	length := end - start + 1
+	if start%8 == 0 && length%8 == 0 {
+		hex := make([]string, length/8)
+		for i := range hex {
+			hex[i] = fmt.Sprintf("%02x", data[start/8+i])
+		}
+		return strings.Join(hex, " ")
+	}
+	if length > 32 {
+		return fmt.Sprintf("(%d bits)", length)
+	}
+	var builder strings.Builder
+	for i := start; i <= end; i++ {
+		builder.WriteByte('0' + bitAt(data, i))
+	}
+	return builder.String()
+}
+
+func pluralBits(n int) string {
+	if n == 1 {
+		return "bit"
+	}
+	return "bits"
+}
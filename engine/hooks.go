@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"funterm/errors"
+	"funterm/runtime"
+	"go-parser/pkg/ast"
+	sharedparser "go-parser/pkg/shared"
+)
+
+// scriptHooks holds the on_signal and on_exit handlers of a session. Engine
+// copies share it, so a handler registered in a pipeline stage counts too
+type scriptHooks struct {
+	mu       sync.Mutex
+	signals  map[os.Signal][]scriptHook
+	exit     []scriptHook
+	incoming chan os.Signal // signals the engine was notified of, nil until the first handler
+	watched  map[os.Signal]bool
+	exited   bool // on_exit handlers already ran
+	cleanups []func()
+}
+
+// scriptHook is the body of a handler and the engine that registered it
+type scriptHook struct {
+	body   *ast.BlockStatement
+	engine *ExecutionEngine
+}
+
+func newScriptHooks() *scriptHooks {
+	return &scriptHooks{signals: make(map[os.Signal][]scriptHook), watched: make(map[os.Signal]bool)}
+}
+
+// executeHookStatement registers the body of on_signal(...) { ... } or
+// on_exit { ... }; it runs later, when a signal comes or the script ends
+func (e *ExecutionEngine) executeHookStatement(statement *ast.HookStatement) (interface{}, error) {
+	name := "on_" + statement.Event
+	if err := e.checkBuiltin(name, statement.Pos); err != nil {
+		return nil, err
+	}
+	hook := scriptHook{body: statement.Body, engine: e}
+	if statement.Event == "exit" {
+		e.hooks.mu.Lock()
+		e.hooks.exit = append(e.hooks.exit, hook)
+		e.hooks.mu.Unlock()
+		// A script stopped by a signal still runs its on_exit handlers
+		e.hooks.watch(terminatingSignals)
+		return nil, nil
+	}
+
+	var signals []os.Signal
+	for _, expr := range statement.Signals {
+		value, err := e.convertExpressionToValue(expr)
+		if err != nil {
+			return nil, err
+		}
+		text, _ := value.(string)
+		sig, ok := lookupSignal(text)
+		if !ok {
+			return nil, errors.NewUserErrorWithASTPos("SIGNAL_ERROR", fmt.Sprintf("on_signal(): unknown signal %v, expected one of %s", value, strings.Join(signalNames(), ", ")), expr.Position())
+		}
+		signals = append(signals, sig)
+	}
+	e.hooks.mu.Lock()
+	for _, sig := range signals {
+		e.hooks.signals[sig] = append(e.hooks.signals[sig], hook)
+	}
+	e.hooks.mu.Unlock()
+	e.hooks.watch(signals)
+	return nil, nil
+}
+
+// watch starts catching the signals that aren't caught yet
+func (h *scriptHooks) watch(signals []os.Signal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var fresh []os.Signal
+	for _, sig := range signals {
+		if !h.watched[sig] {
+			h.watched[sig] = true
+			fresh = append(fresh, sig)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+	if h.incoming == nil {
+		h.incoming = make(chan os.Signal, 1)
+		go func() {
+			for sig := range h.incoming {
+				h.handle(sig)
+			}
+		}()
+	}
+	// The handlers decide what the signals do, not the shutdown manager
+	runtime.ClaimSignals(fresh...)
+	signal.Notify(h.incoming, fresh...)
+}
+
+// handle runs the handlers of a signal. A signal that stops the script
+// (SIGINT, SIGTERM and SIGQUIT) still does so after its handlers and the
+// on_exit handlers ran, with the exit status a shell reports for it
+func (h *scriptHooks) handle(sig os.Signal) {
+	h.mu.Lock()
+	handlers := append([]scriptHook(nil), h.signals[sig]...)
+	h.mu.Unlock()
+	for _, hook := range handlers {
+		hook.run(fmt.Sprintf("on_signal(%q)", signalName(sig)))
+	}
+	for _, terminating := range terminatingSignals {
+		if sig == terminating {
+			h.runExit()
+			number, _ := sig.(syscall.Signal)
+			runtime.Exit(128 + int(number))
+		}
+	}
+}
+
+// RunExitHooks runs the on_exit handlers of the session, the last registered
+// first, and then stops the processes the session started with sh.daemon().
+// They run once: later calls, e.g. after a signal, do nothing
+func (e *ExecutionEngine) RunExitHooks() {
+	e.hooks.runExit()
+}
+
+// atExit adds work of the engine itself to do after the on_exit handlers.
+// The work runs concurrently, so processes stopping take their grace
+// periods together
+func (h *scriptHooks) atExit(cleanup func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cleanups = append(h.cleanups, cleanup)
+}
+
+func (h *scriptHooks) runExit() {
+	h.mu.Lock()
+	if h.exited {
+		h.mu.Unlock()
+		return
+	}
+	h.exited = true
+	handlers, cleanups := h.exit, h.cleanups
+	h.mu.Unlock()
+	for i := len(handlers) - 1; i >= 0; i-- {
+		handlers[i].run("on_exit")
+	}
+	var running sync.WaitGroup
+	for _, cleanup := range cleanups {
+		running.Add(1)
+		go func(cleanup func()) {
+			defer running.Done()
+			cleanup()
+		}(cleanup)
+	}
+	running.Wait()
+}
+
+// run executes the body of a handler on an engine sharing the globals of
+// the script, as a pipeline stage does, and prints what it printed. A
+// failing handler is reported and doesn't stop the others
+func (hook scriptHook) run(what string) {
+	hookEngine := hook.engine.newBackgroundEngine(sharedparser.NewScope(nil), hook.engine.cloneSharedVariables())
+	hookEngine.globals = hook.engine.globals
+	result, err := hookEngine.executeBlockStatement(hook.body)
+	if output := stageOutput(result); output != "" {
+		fmt.Println(output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s failed: %v\n", what, err)
+	}
+}
+
+// lookupSignal finds a signal by name, with or without the SIG prefix and
+// in any case
+func lookupSignal(name string) (os.Signal, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	sig, ok := scriptSignals[strings.TrimPrefix(name, "SIG")]
+	return sig, ok
+}
+
+// signalName is the name of a signal as scripts write it
+func signalName(sig os.Signal) string {
+	for name, known := range scriptSignals {
+		if known == sig {
+			return "SIG" + name
+		}
+	}
+	return sig.String()
+}
+
+// signalNames lists the signals scripts can handle
+func signalNames() []string {
+	names := make([]string, 0, len(scriptSignals))
+	for name := range scriptSignals {
+		names = append(names, "SIG"+name)
+	}
+	sort.Strings(names)
+	return names
+}
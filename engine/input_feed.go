@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"funterm/errors"
+	"funterm/runtime"
+	"funterm/shared"
+	"go-parser/pkg/ast"
+)
+
+// executeInputFeed implements py.input_feed(data): the next call to the
+// runtime reads data from its stdin, so a prompt of input() gets an answer.
+// Feeds given before the same call add up
+func (e *ExecutionEngine) executeInputFeed(rt runtime.LanguageRuntime, call *ast.LanguageCall) (interface{}, error) {
+	feedError := func(format string, args ...interface{}) error {
+		return errors.NewUserErrorWithASTPos("INPUT_FEED_ERROR", fmt.Sprintf(call.Language+".input_feed() "+format, args...), call.Position())
+	}
+	if _, ok := feedCode(rt.GetName(), nil); !ok {
+		return nil, feedError("is not supported by the %s runtime; python and perl calls can be fed input", rt.GetName())
+	}
+	args, err := e.convertExpressionsToArgs(call.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 1 {
+		return nil, feedError("requires exactly one string or bitstring")
+	}
+	var data []byte
+	switch v := args[0].(type) {
+	case string:
+		data = []byte(v)
+	case *shared.BitstringObject:
+		data = v.Bytes()
+	default:
+		return nil, feedError("expects a string or bitstring, got %s", orderTypeName(args[0]))
+	}
+
+	if e.inputFeeds == nil {
+		e.inputFeeds = make(map[string][]byte)
+	}
+	e.inputFeeds[rt.GetName()] = append(e.inputFeeds[rt.GetName()], data...)
+	return nil, nil
+}
+
+// takeInputFeed hands the input fed to the runtime to the call about to run
+// and returns the function taking it back after the call
+func (e *ExecutionEngine) takeInputFeed(rt runtime.LanguageRuntime) (func(), error) {
+	data, ok := e.inputFeeds[rt.GetName()]
+	if !ok {
+		return func() {}, nil
+	}
+	delete(e.inputFeeds, rt.GetName())
+
+	code, _ := feedCode(rt.GetName(), data)
+	if _, err := rt.Eval(code); err != nil {
+		return nil, errors.NewRuntimeError(rt.GetName(), "INPUT_FEED_FAILED", fmt.Sprintf("failed to feed input to the %s runtime: %v", rt.GetName(), err))
+	}
+	return func() {
+		code, _ := unfeedCode(rt.GetName())
+		rt.Eval(code)
+	}, nil
+}
+
+// pythonFeedTemplate replaces stdin with the data. input() shows the prompt
+// and the answer it read on a line, as a terminal would, since a prompt left
+// without a newline would run into the result of the call
+const pythonFeedTemplate = `import sys, io, base64, builtins
+sys.stdin = io.TextIOWrapper(io.BytesIO(base64.b64decode("%s")), encoding="utf-8")
+_funterm_input = builtins.input
+def _funterm_fed_input(prompt=""):
+    line = sys.stdin.readline()
+    if not line:
+        raise EOFError
+    line = line.rstrip("\n")
+    print(str(prompt) + line)
+    return line
+builtins.input = _funterm_fed_input`
+
+// feedCode returns the snippet making data the stdin of a runtime
+func feedCode(language string, data []byte) (string, bool) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch language {
+	case "python":
+		return fmt.Sprintf(pythonFeedTemplate, encoded), true
+	case "perl":
+		return "require MIME::Base64; my $data = MIME::Base64::decode_base64('" + encoded + "'); close(STDIN); open(STDIN, '<', \\$data) or die \"$!\\n\"; 1", true
+	}
+	return "", false
+}
+
+// unfeedCode returns the snippet ending the fed input. The original stdin
+// carries the requests of funterm, so afterwards stdin is empty instead
+func unfeedCode(language string) (string, bool) {
+	switch language {
+	case "python":
+		return "import sys, io, builtins\nsys.stdin = io.StringIO()\nbuiltins.input = _funterm_input", true
+	case "perl":
+		return "require File::Spec; close(STDIN); open(STDIN, '<', File::Spec->devnull) or die \"$!\\n\"; 1", true
+	}
+	return "", false
+}
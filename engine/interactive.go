@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/chzyer/readline"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// executeInteractiveStatement runs interactive sh.run(...): the program reads
+// and writes the terminal of funterm itself, so raw mode, Ctrl+C and resizes
+// reach it as in a shell. Afterwards the terminal is back in the state the
+// REPL left it in. Like sh.run(), a non-zero exit status is an error
+func (e *ExecutionEngine) executeInteractiveStatement(statement *ast.InteractiveStatement) (interface{}, error) {
+	call := statement.Call
+	if err := e.checkBuiltin(call.Language, call.Position()); err != nil {
+		return nil, err
+	}
+	args, err := e.convertExpressionsToArgs(call.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.guardCall(call.Language, call.Function, args, call.Position()); err != nil {
+		return nil, err
+	}
+	name, path, arguments, err := commandLine("run", args)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(path, arguments...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	takeBack := func() {}
+	if fd := int(os.Stdin.Fd()); readline.IsTerminal(fd) {
+		state, stateErr := readline.GetState(fd)
+		takeTerminal := handTerminal(cmd)
+		takeBack = func() {
+			takeTerminal()
+			if stateErr == nil {
+				readline.Restore(fd, state)
+			}
+		}
+	}
+	err = cmd.Run()
+	takeBack()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, errors.NewUserErrorWithASTPos("PROCESS_EXIT_ERROR", fmt.Sprintf("sh.run(): %s failed with exit status %d", name, exitErr.ExitCode()), statement.Pos)
+	}
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("PROCESS_START_ERROR", fmt.Sprintf("sh.run(): cannot start %s: %v", name, err), statement.Pos)
+	}
+	return nil, nil
+}
@@ -0,0 +1,629 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"funterm/errors"
+	"funterm/runtime"
+	"funterm/shared"
+)
+
+// executeValidateFunction implements validate(value, schema): ok(value) when
+// value conforms to the JSON Schema, error([violations]) otherwise, each
+// violation an object {path, keyword, message}. The schema is an object or
+// its JSON text
+func (e *ExecutionEngine) executeValidateFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("VALIDATE_ERROR", "validate() requires a value and a schema")
+	}
+	schema := args[1]
+	if text, ok := schema.(string); ok {
+		if err := runtime.UnmarshalJSONNumbers([]byte(text), &schema); err != nil {
+			return nil, errors.NewUserError("SCHEMA_ERROR", fmt.Sprintf("validate() can't parse the schema: %v", err))
+		}
+	}
+	if _, ok := schema.(map[string]interface{}); !ok {
+		if _, ok := schema.(bool); !ok {
+			return nil, errors.NewUserError("SCHEMA_ERROR", fmt.Sprintf("validate() expects the schema as an object or JSON text, got %s", orderTypeName(schema)))
+		}
+	}
+
+	v := &schemaValidator{root: schema, patterns: make(map[string]*regexp.Regexp)}
+	if err := v.validate(args[0], schema, "$"); err != nil {
+		return nil, err
+	}
+	if len(v.violations) == 0 {
+		return &shared.ResultObject{Ok: true, Value: args[0]}, nil
+	}
+	return &shared.ResultObject{Ok: false, Value: v.violations}, nil
+}
+
+// schemaValidator checks a value against a JSON Schema. It knows the
+// keywords that constrain values; annotations such as title, description
+// and format are ignored, as the specification allows
+type schemaValidator struct {
+	root       interface{}
+	patterns   map[string]*regexp.Regexp
+	violations []interface{}
+	depth      int
+}
+
+// maxSchemaDepth stops $ref cycles that never reach a value
+const maxSchemaDepth = 512
+
+func (v *schemaValidator) report(path, keyword, format string, args ...interface{}) {
+	v.violations = append(v.violations, map[string]interface{}{
+		"path":    path,
+		"keyword": keyword,
+		"message": fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks value at path against schema, adding violations; the
+// error is for schemas that are themselves invalid
+func (v *schemaValidator) validate(value, schema interface{}, path string) error {
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			v.report(path, "false", "no value is allowed here")
+		}
+		return nil
+	}
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return jsonSchemaError("a schema must be an object or a boolean, got %s", orderTypeName(schema))
+	}
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > maxSchemaDepth {
+		return jsonSchemaError("schema nesting is too deep, is there a $ref cycle?")
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			return err
+		}
+		if err := v.validate(value, target, path); err != nil {
+			return err
+		}
+	}
+	if err := v.checkType(value, s, path); err != nil {
+		return err
+	}
+	if allowed, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range allowed {
+			found = found || jsonEqual(value, candidate)
+		}
+		if !found {
+			v.report(path, "enum", "%s is not one of %s", shared.FormatValueForDisplay(value), shared.FormatValueForDisplay(allowed))
+		}
+	}
+	if expected, ok := s["const"]; ok && !jsonEqual(value, expected) {
+		v.report(path, "const", "expected %s, got %s", shared.FormatValueForDisplay(expected), shared.FormatValueForDisplay(value))
+	}
+
+	switch val := value.(type) {
+	case string:
+		if err := v.checkString(val, s, path); err != nil {
+			return err
+		}
+	case []interface{}:
+		if err := v.checkArray(val, s, path); err != nil {
+			return err
+		}
+	case map[string]interface{}:
+		if err := v.checkObject(val, s, path); err != nil {
+			return err
+		}
+	default:
+		if _, ok := exactSchemaNumber(value); ok {
+			if err := v.checkNumber(value, s, path); err != nil {
+				return err
+			}
+		}
+	}
+	return v.checkCombinators(value, s, path)
+}
+
+// resolve finds the schema a local $ref such as "#/$defs/item" points to
+func (v *schemaValidator) resolve(ref string) (interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, jsonSchemaError("only local $ref values such as \"#/$defs/name\" are supported, got %q", ref)
+	}
+	current := v.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, jsonSchemaError("$ref %q points to nothing", ref)
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, jsonSchemaError("$ref %q points to nothing", ref)
+			}
+			current = node[index]
+		default:
+			return nil, jsonSchemaError("$ref %q points to nothing", ref)
+		}
+	}
+	return current, nil
+}
+
+func (v *schemaValidator) checkType(value interface{}, s map[string]interface{}, path string) error {
+	declared, ok := s["type"]
+	if !ok {
+		return nil
+	}
+	var types []string
+	switch t := declared.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return jsonSchemaError("type must be a string or an array of strings")
+			}
+			types = append(types, name)
+		}
+	default:
+		return jsonSchemaError("type must be a string or an array of strings")
+	}
+	actual := jsonTypeName(value)
+	for _, name := range types {
+		switch name {
+		case "null", "boolean", "string", "array", "object":
+		case "number":
+			if actual == "integer" {
+				return nil
+			}
+		case "integer":
+		default:
+			return jsonSchemaError("unknown type %q", name)
+		}
+		if name == actual {
+			return nil
+		}
+	}
+	v.report(path, "type", "expected %s, got %s", strings.Join(types, " or "), actual)
+	return nil
+}
+
+func (v *schemaValidator) checkString(value string, s map[string]interface{}, path string) error {
+	length := utf8.RuneCountInString(value)
+	if limit, ok, err := schemaCount(s, "minLength"); err != nil {
+		return err
+	} else if ok && length < limit {
+		v.report(path, "minLength", "string of %d characters is shorter than %d", length, limit)
+	}
+	if limit, ok, err := schemaCount(s, "maxLength"); err != nil {
+		return err
+	} else if ok && length > limit {
+		v.report(path, "maxLength", "string of %d characters is longer than %d", length, limit)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := v.compile(pattern)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(value) {
+			v.report(path, "pattern", "%q does not match %q", value, pattern)
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) checkNumber(value interface{}, s map[string]interface{}, path string) error {
+	n, _ := exactSchemaNumber(value)
+	bound := func(keyword string) (*big.Float, bool, error) {
+		limit, ok := s[keyword]
+		if !ok {
+			return nil, false, nil
+		}
+		if _, isBool := limit.(bool); isBool {
+			return nil, false, nil
+		}
+		number, ok := exactSchemaNumber(limit)
+		if !ok {
+			return nil, false, jsonSchemaError("%s must be a number", keyword)
+		}
+		return number, true, nil
+	}
+	shown := shared.FormatValueForDisplay(value)
+
+	// Draft 4 writes exclusiveMinimum: true next to minimum
+	exclusiveMin, _ := s["exclusiveMinimum"].(bool)
+	exclusiveMax, _ := s["exclusiveMaximum"].(bool)
+	if limit, ok, err := bound("minimum"); err != nil {
+		return err
+	} else if ok && (n.Cmp(limit) < 0 || (exclusiveMin && n.Cmp(limit) == 0)) {
+		v.report(path, "minimum", "%s is less than %s%s", shown, orEqual(exclusiveMin), limit.Text('g', -1))
+	}
+	if limit, ok, err := bound("maximum"); err != nil {
+		return err
+	} else if ok && (n.Cmp(limit) > 0 || (exclusiveMax && n.Cmp(limit) == 0)) {
+		v.report(path, "maximum", "%s is greater than %s%s", shown, orEqual(exclusiveMax), limit.Text('g', -1))
+	}
+	if limit, ok, err := bound("exclusiveMinimum"); err != nil {
+		return err
+	} else if ok && n.Cmp(limit) <= 0 {
+		v.report(path, "exclusiveMinimum", "%s is not greater than %s", shown, limit.Text('g', -1))
+	}
+	if limit, ok, err := bound("exclusiveMaximum"); err != nil {
+		return err
+	} else if ok && n.Cmp(limit) >= 0 {
+		v.report(path, "exclusiveMaximum", "%s is not less than %s", shown, limit.Text('g', -1))
+	}
+	if divisor, ok, err := bound("multipleOf"); err != nil {
+		return err
+	} else if ok {
+		if divisor.Sign() <= 0 {
+			return jsonSchemaError("multipleOf must be greater than 0")
+		}
+		quotient := new(big.Float).SetPrec(200).Quo(n, divisor)
+		if !quotient.IsInt() {
+			v.report(path, "multipleOf", "%s is not a multiple of %s", shown, divisor.Text('g', -1))
+		}
+	}
+	return nil
+}
+
+// orEqual words a draft 4 exclusive bound in messages
+func orEqual(exclusive bool) string {
+	if exclusive {
+		return "or equal to "
+	}
+	return ""
+}
+
+func (v *schemaValidator) checkArray(value []interface{}, s map[string]interface{}, path string) error {
+	if limit, ok, err := schemaCount(s, "minItems"); err != nil {
+		return err
+	} else if ok && len(value) < limit {
+		v.report(path, "minItems", "array of %d items has fewer than %d", len(value), limit)
+	}
+	if limit, ok, err := schemaCount(s, "maxItems"); err != nil {
+		return err
+	} else if ok && len(value) > limit {
+		v.report(path, "maxItems", "array of %d items has more than %d", len(value), limit)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	duplicates:
+		for i := range value {
+			for j := 0; j < i; j++ {
+				if jsonEqual(value[i], value[j]) {
+					v.report(path, "uniqueItems", "items %d and %d are equal", j, i)
+					break duplicates
+				}
+			}
+		}
+	}
+
+	// prefixItems (2020-12) or an array of items (earlier drafts) give the
+	// schemas of the first items, items or additionalItems those of the rest
+	prefix, _ := s["prefixItems"].([]interface{})
+	rest, hasRest := s["items"]
+	if tuple, ok := rest.([]interface{}); ok {
+		prefix = tuple
+		rest, hasRest = s["additionalItems"]
+	}
+	for i, item := range value {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i < len(prefix):
+			if err := v.validate(item, prefix[i], itemPath); err != nil {
+				return err
+			}
+		case hasRest:
+			if err := v.validate(item, rest, itemPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		found := false
+		for _, item := range value {
+			probe := &schemaValidator{root: v.root, patterns: v.patterns, depth: v.depth}
+			if err := probe.validate(item, contains, path); err != nil {
+				return err
+			}
+			found = found || len(probe.violations) == 0
+		}
+		if !found {
+			v.report(path, "contains", "no item matches the contains schema")
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) checkObject(value map[string]interface{}, s map[string]interface{}, path string) error {
+	if limit, ok, err := schemaCount(s, "minProperties"); err != nil {
+		return err
+	} else if ok && len(value) < limit {
+		v.report(path, "minProperties", "object of %d properties has fewer than %d", len(value), limit)
+	}
+	if limit, ok, err := schemaCount(s, "maxProperties"); err != nil {
+		return err
+	} else if ok && len(value) > limit {
+		v.report(path, "maxProperties", "object of %d properties has more than %d", len(value), limit)
+	}
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, item := range required {
+			name, ok := item.(string)
+			if !ok {
+				return jsonSchemaError("required must be an array of strings")
+			}
+			if _, present := value[name]; !present {
+				v.report(propertyPath(path, name), "required", "required property %q is missing", name)
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyPath := propertyPath(path, key)
+		matched := false
+		if schema, ok := properties[key]; ok {
+			matched = true
+			if err := v.validate(value[key], schema, keyPath); err != nil {
+				return err
+			}
+		}
+		for pattern, schema := range patternProperties {
+			re, err := v.compile(pattern)
+			if err != nil {
+				return err
+			}
+			if re.MatchString(key) {
+				matched = true
+				if err := v.validate(value[key], schema, keyPath); err != nil {
+					return err
+				}
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			v.report(keyPath, "additionalProperties", "property %q is not allowed", key)
+			continue
+		}
+		if err := v.validate(value[key], additional, keyPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCombinators handles allOf, anyOf, oneOf, not and if/then/else
+func (v *schemaValidator) checkCombinators(value interface{}, s map[string]interface{}, path string) error {
+	// matches reports whether value conforms to schema without reporting
+	matches := func(schema interface{}) (bool, error) {
+		probe := &schemaValidator{root: v.root, patterns: v.patterns, depth: v.depth}
+		err := probe.validate(value, schema, path)
+		return len(probe.violations) == 0, err
+	}
+	list := func(keyword string) ([]interface{}, error) {
+		schemas, ok := s[keyword]
+		if !ok {
+			return nil, nil
+		}
+		items, ok := schemas.([]interface{})
+		if !ok || len(items) == 0 {
+			return nil, jsonSchemaError("%s must be a non-empty array of schemas", keyword)
+		}
+		return items, nil
+	}
+
+	allOf, err := list("allOf")
+	if err != nil {
+		return err
+	}
+	for _, schema := range allOf {
+		if err := v.validate(value, schema, path); err != nil {
+			return err
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		schemas, err := list(keyword)
+		if err != nil {
+			return err
+		}
+		if schemas == nil {
+			continue
+		}
+		count := 0
+		for _, schema := range schemas {
+			ok, err := matches(schema)
+			if err != nil {
+				return err
+			}
+			if ok {
+				count++
+			}
+		}
+		switch {
+		case count == 0:
+			v.report(path, keyword, "value matches none of the %d schemas", len(schemas))
+		case keyword == "oneOf" && count > 1:
+			v.report(path, keyword, "value matches %d of the schemas instead of exactly one", count)
+		}
+	}
+	if schema, ok := s["not"]; ok {
+		matched, err := matches(schema)
+		if err != nil {
+			return err
+		}
+		if matched {
+			v.report(path, "not", "value matches the schema it must not match")
+		}
+	}
+	if condition, ok := s["if"]; ok {
+		matched, err := matches(condition)
+		if err != nil {
+			return err
+		}
+		branch, hasBranch := s["else"]
+		if matched {
+			branch, hasBranch = s["then"]
+		}
+		if hasBranch {
+			return v.validate(value, branch, path)
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, jsonSchemaError("invalid pattern %q: %v", pattern, err)
+	}
+	v.patterns[pattern] = re
+	return re, nil
+}
+
+// schemaCount reads a non-negative integer keyword such as minLength
+func schemaCount(s map[string]interface{}, keyword string) (int, bool, error) {
+	limit, ok := s[keyword]
+	if !ok {
+		return 0, false, nil
+	}
+	n, isNumber := exactSchemaNumber(limit)
+	if !isNumber || !n.IsInt() || n.Sign() < 0 {
+		return 0, false, jsonSchemaError("%s must be a non-negative integer", keyword)
+	}
+	count, _ := n.Int64()
+	if count > math.MaxInt32 {
+		count = math.MaxInt32
+	}
+	return int(count), true, nil
+}
+
+// jsonTypeName names the JSON Schema type of a value; numbers without a
+// fraction are integers, as the specification says
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if n, ok := exactSchemaNumber(value); ok {
+		if n.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return orderTypeName(value)
+}
+
+// exactSchemaNumber returns the exact value of a number; NaN and infinities
+// are not JSON numbers
+func exactSchemaNumber(value interface{}) (*big.Float, bool) {
+	switch v := value.(type) {
+	case int, int32, int64, uint64, *big.Int:
+		n, _ := exactNumber(v)
+		return n, true
+	case float32:
+		return exactSchemaNumber(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		n, _ := exactNumber(v)
+		return n, true
+	}
+	return nil, false
+}
+
+// jsonEqual compares values the way JSON Schema does: numbers by value,
+// arrays and objects element by element
+func jsonEqual(a, b interface{}) bool {
+	if x, ok := exactSchemaNumber(a); ok {
+		y, ok := exactSchemaNumber(b)
+		return ok && x.Cmp(y) == 0
+	}
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool, string:
+		return a == b
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, item := range x {
+			other, present := y[key]
+			if !present || !jsonEqual(item, other) {
+				return false
+			}
+		}
+		return true
+	case *shared.BitstringObject:
+		y, ok := b.(*shared.BitstringObject)
+		return ok && x.Len() == y.Len() && string(x.Bytes()) == string(y.Bytes())
+	}
+	return false
+}
+
+// propertyPath extends a path such as $.user with a property name
+func propertyPath(path, name string) string {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Sprintf("%s[%q]", path, name)
+		}
+	}
+	if name == "" {
+		return path + `[""]`
+	}
+	return path + "." + name
+}
+
+func jsonSchemaError(format string, args ...interface{}) error {
+	return errors.NewUserError("SCHEMA_ERROR", "invalid schema: "+fmt.Sprintf(format, args...))
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"funterm/errors"
@@ -15,6 +16,10 @@ import (
 
 // executeLanguageCallNew executes a language.function() call using new parser AST
 func (e *ExecutionEngine) executeLanguageCallNew(call *ast.LanguageCall) (interface{}, error) {
+	if call.Result != ast.ResultWait {
+		return e.executeLanguageCallWithPolicy(call)
+	}
+
 	// Handle alias 'py' for 'python'
 	if call.Language == "py" {
 		call.Language = "python"
@@ -23,16 +28,64 @@ func (e *ExecutionEngine) executeLanguageCallNew(call *ast.LanguageCall) (interf
 	if call.Language == "js" {
 		call.Language = "node"
 	}
+	// Handle alias 'pl' for 'perl'
+	if call.Language == "pl" {
+		call.Language = "perl"
+	}
+	// Handle aliases 'erl' and 'ex' for the BEAM languages
+	if call.Language == "erl" {
+		call.Language = "erlang"
+	}
+	if call.Language == "ex" {
+		call.Language = "elixir"
+	}
+
+	if err := e.checkDeadline(); err != nil {
+		return nil, err
+	}
+
+	// Show a spinner if the call takes longer than the configured threshold
+	stopSpinner := e.startSpinner(call.Language + "." + call.Function)
+	defer stopSpinner()
+	defer e.measureRuntime()()
 
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(call.Language)
 	if err == nil {
+		e.countLanguage(call.Language)
+		if err := e.checkLanguage(call.Language, call.Position()); err != nil {
+			return nil, err
+		}
 		// Runtime found in manager, use it
 		return e.executeWithRuntimeNew(rt, call)
 	}
 
+	// In expressions obj.method(args) on a variable parses as a language call
+	if value, found := e.getVariable(call.Language); found {
+		chain := append([]*ast.MethodCall{{Method: call.Function, Arguments: call.Arguments, Pos: call.Pos}}, call.Chain...)
+		return e.callValueMethodChain(call.Language, value, chain)
+	}
+
+	// Builtin modules such as gzip.decompress(payload) or aes.gcm_decrypt(...)
+	if module, found := e.lookupBuiltinModule(call.Language); found {
+		if err := e.checkBuiltin(call.Language, call.Position()); err != nil {
+			return nil, err
+		}
+		args, err := e.convertExpressionsToArgs(call.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.guardCall(call.Language, call.Function, args, call.Position()); err != nil {
+			return nil, err
+		}
+		return module(call.Function, args)
+	}
+
 	// Try to get or create runtime from cache
 	if e.runtimeRegistry != nil {
+		if err := e.checkLanguage(call.Language, call.Position()); err != nil {
+			return nil, err
+		}
 		runtime, err := e.GetOrCreateRuntime(call.Language)
 		if err == nil {
 			return e.executeWithRuntimeNew(runtime, call)
@@ -42,6 +95,20 @@ func (e *ExecutionEngine) executeLanguageCallNew(call *ast.LanguageCall) (interf
 	return nil, errors.NewUserErrorWithASTPos("UNSUPPORTED_COMMAND", "unsupported command", call.Position())
 }
 
+// convertMethodArguments converts the arguments of a method called on an
+// engine value
+func (e *ExecutionEngine) convertMethodArguments(exprs []ast.Expression) ([]interface{}, error) {
+	args := make([]interface{}, len(exprs))
+	for i, arg := range exprs {
+		converted, err := e.convertExpressionToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert argument %d: %v", i, err)
+		}
+		args[i] = converted
+	}
+	return args, nil
+}
+
 // executeWithRuntimeNew executes a language call with a specific runtime using new parser AST
 func (e *ExecutionEngine) executeWithRuntimeNew(rt runtime.LanguageRuntime, call *ast.LanguageCall) (interface{}, error) {
 	if e.verbose {
@@ -83,6 +150,10 @@ func (e *ExecutionEngine) executeWithRuntimeNew(rt runtime.LanguageRuntime, call
 		return e.executeIdFunction(args)
 	}
 
+	if call.Function == "input_feed" {
+		return e.executeInputFeed(rt, call)
+	}
+
 	// Handle special eval function
 	if call.Function == "eval" {
 		if e.verbose {
@@ -108,6 +179,15 @@ func (e *ExecutionEngine) executeWithRuntimeNew(rt runtime.LanguageRuntime, call
 			return nil, errors.NewUserErrorWithASTPos("EVAL_ARGUMENT_ERROR", "eval() requires exactly one argument", call.Position())
 		}
 
+		if err := e.guardCall(call.Language, call.Function, args, call.Position()); err != nil {
+			return nil, err
+		}
+		release, err := e.limitCall(call.Language, call.Function, args, call.Position())
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
 		// Convert argument to string
 		code, ok := args[0].(string)
 		if !ok {
@@ -124,48 +204,84 @@ func (e *ExecutionEngine) executeWithRuntimeNew(rt runtime.LanguageRuntime, call
 		if e.verbose {
 			fmt.Printf("DEBUG: Calling rt.Eval()...\n")
 		}
-		result, err := rt.Eval(code)
+		runtime.TraceCall(call.Language, call.Function, args)
+		stopCall := e.measureCall(call.Language+"."+call.Function, rt)
+		result, err := e.withinDeadline(func() (interface{}, error) { return rt.Eval(code) })
+		stopCall()
+		runtime.TraceResult(result, err)
 		if err != nil {
 			if e.verbose {
 				fmt.Printf("DEBUG: Error from rt.Eval(): %v\n", err)
 			}
+			if timeout := e.checkDeadline(); timeout != nil {
+				return nil, timeout
+			}
 			return nil, errors.NewUserErrorWithASTPos("EVAL_ERROR", fmt.Sprintf("eval error: %v", err), call.Position())
 		}
 		if e.verbose {
 			fmt.Printf("DEBUG: Eval result: %v\n", result)
 		}
 
-		return result, nil
+		return e.normalizeNumbers(rt.GetName(), result), nil
 	}
 
 	if e.verbose {
 		fmt.Printf("DEBUG: Handling regular function call\n")
 	}
 	// Convert new parser expressions to old interface arguments
-	args, err := e.convertExpressionsToArgs(call.Arguments)
+	args, err := e.convertCallArguments(rt.GetName(), call.Arguments, call.Position())
 	if err != nil {
 		if e.verbose {
 			fmt.Printf("DEBUG: Error converting arguments: %v\n", err)
 		}
 		return nil, errors.NewUserErrorWithASTPos("ARGUMENT_CONVERSION_ERROR", fmt.Sprintf("argument conversion error: %v", err), call.Position())
 	}
+	if err := e.checkHandleArgs(rt.GetName(), args); err != nil {
+		return nil, err
+	}
+	if err := e.guardCall(call.Language, call.Function, args, call.Position()); err != nil {
+		return nil, err
+	}
+	release, err := e.limitCall(call.Language, call.Function, args, call.Position())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	takeBack, err := e.takeInputFeed(rt)
+	if err != nil {
+		return nil, err
+	}
+	defer takeBack()
 
 	// Execute the function (call.Function already contains the full name including module)
 	if e.verbose {
 		fmt.Printf("DEBUG: Calling rt.ExecuteFunction()...\n")
 	}
-	result, err := rt.ExecuteFunction(call.Function, args)
+	for i, arg := range args {
+		args[i] = runtime.ConvertToRuntime(rt.GetName(), arg)
+	}
+	if len(call.Chain) > 0 {
+		return e.executeMethodChain(rt, call, args)
+	}
+	runtime.TraceCall(call.Language, call.Function, args)
+	stopCall := e.measureCall(call.Language+"."+call.Function, rt)
+	result, err := e.withinDeadline(func() (interface{}, error) { return rt.ExecuteFunction(call.Function, args) })
+	stopCall()
+	runtime.TraceResult(result, err)
 	if err != nil {
 		if e.verbose {
 			fmt.Printf("DEBUG: Error from rt.ExecuteFunction(): %v\n", err)
 		}
+		if timeout := e.checkDeadline(); timeout != nil {
+			return nil, timeout
+		}
 		return nil, errors.NewUserErrorWithASTPos("EXECUTION_ERROR", fmt.Sprintf("execution error: %v", err), call.Position())
 	}
 	if e.verbose {
 		fmt.Printf("DEBUG: ExecuteFunction result: %v\n", result)
 	}
 
-	return result, nil
+	return e.normalizeNumbers(rt.GetName(), result), nil
 }
 
 // executeVariableRead executes a variable read operation
@@ -195,7 +311,7 @@ func (e *ExecutionEngine) executeVariableRead(variableRead *ast.VariableRead) (i
 		if e.verbose {
 			fmt.Printf("DEBUG: executeVariableRead - unqualified variable '%s' not found, returning nil\n", varName)
 		}
-		return nil, nil
+		return e.undefinedVariable(varName, variableRead.Variable.Position())
 	}
 
 	language := variableRead.Variable.Language
@@ -211,6 +327,17 @@ func (e *ExecutionEngine) executeVariableRead(variableRead *ast.VariableRead) (i
 		language = "node"
 	}
 
+	// A variable holding an object takes precedence, so tree.children reads a field
+	if object, found := e.getVariable(variableRead.Variable.Language); found {
+		if value, ok := objectField(object, append(append([]string{}, path...), variableName)); ok {
+			return value, nil
+		}
+	}
+
+	if err := e.checkLanguage(language, variableRead.Position()); err != nil {
+		return nil, err
+	}
+
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(language)
 	if err == nil {
@@ -354,6 +481,9 @@ func (e *ExecutionEngine) executeVariableAssignment(variableAssignment *ast.Vari
 	if language == "js" {
 		language = "node"
 	}
+	if err := e.checkLanguage(language, variableAssignment.Position()); err != nil {
+		return nil, err
+	}
 
 	// Convert the value to the appropriate format
 	value, err := e.convertExpressionToValue(variableAssignment.Value)
@@ -361,6 +491,12 @@ func (e *ExecutionEngine) executeVariableAssignment(variableAssignment *ast.Vari
 		return nil, errors.NewUserErrorWithASTPos("VALUE_CONVERSION_ERROR", fmt.Sprintf("failed to convert value for assignment: %v", err), variableAssignment.Value.Position())
 	}
 
+	// py.config.server.port = value changes port inside config
+	if len(variableAssignment.Variable.Path) > 0 {
+		root, path := qualifiedPath(variableAssignment.Variable)
+		return e.assignPath(&VariableName{language: language, name: root}, path, value, variableAssignment.Position())
+	}
+
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(language)
 	if err == nil {
@@ -380,7 +516,9 @@ func (e *ExecutionEngine) executeVariableAssignment(variableAssignment *ast.Vari
 	return nil, errors.NewUserErrorWithASTPos("UNSUPPORTED_LANGUAGE", fmt.Sprintf("unsupported language '%s' for variable assignment", language), variableAssignment.Position())
 }
 
-// readVariableFromRuntimeWithPath reads a variable from runtime, handling path-based field access
+// readVariableFromRuntimeWithPath reads a variable from runtime, handling
+// path-based field access: py.config.server.port reads config from Python
+// and follows server and port through the value
 func (e *ExecutionEngine) readVariableFromRuntimeWithPath(rt runtime.LanguageRuntime, language string, path []string, variableName string) (interface{}, error) {
 	// Check if runtime is ready
 	if !rt.IsReady() {
@@ -392,52 +530,43 @@ func (e *ExecutionEngine) readVariableFromRuntimeWithPath(rt runtime.LanguageRun
 		return e.readVariableFromRuntime(rt, language, variableName)
 	}
 
-	// Handle path-based access (e.g., lua.dns_query_packet.bytes)
-	// First, get the base object using the path
-	baseObject, err := e.getVariableWithPath(rt, language, path)
+	value, err := e.readVariableFromRuntime(rt, language, path[0])
 	if err != nil {
 		return nil, err
 	}
-
-	// Now access the field/method on the base object
-	// For Lua, we can use the metatable methods if available
-	if language == "lua" {
-		return e.accessLuaObjectField(baseObject, variableName)
+	for _, field := range append(append([]string{}, path[1:]...), variableName) {
+		if value, err = e.readField(language, value, field); err != nil {
+			return nil, err
+		}
 	}
-
-	// For other languages, we might need different approaches
-	// For now, return an error for unsupported path access
-	return nil, errors.NewUserError("UNSUPPORTED_PATH_ACCESS", fmt.Sprintf("path-based field access not supported for language '%s'", language))
+	return e.normalizeNumbers(language, value), nil
 }
 
-// getVariableWithPath gets a variable following a path (e.g., ["dns_query_packet"] for lua.dns_query_packet.bytes)
-func (e *ExecutionEngine) getVariableWithPath(rt runtime.LanguageRuntime, language string, path []string) (interface{}, error) {
-	if len(path) == 0 {
-		return nil, errors.NewUserError("INVALID_PATH", "path cannot be empty")
-	}
-
-	// Start with the first element in the path
-	currentName := path[0]
-	currentValue, err := e.readVariableFromRuntime(rt, language, currentName)
-	if err != nil {
-		return nil, err
-	}
-
-	// For single-element paths, return the value directly
-	if len(path) == 1 {
-		return currentValue, nil
+// readField reads a field of a value: a key of an object, or a field or
+// method of a Lua object (language is "lua" for values read from Lua)
+func (e *ExecutionEngine) readField(language string, object interface{}, field string) (interface{}, error) {
+	switch v := object.(type) {
+	case map[string]interface{}:
+		value, exists := v[field]
+		if !exists {
+			return nil, errors.NewUserError("FIELD_ACCESS_ERROR", fmt.Sprintf("field '%s' not found in object", field))
+		}
+		return value, nil
+	case *runtime.ForeignValue:
+		return e.readField(language, v.Value, field)
+	case *shared.BitstringObject, *lua.LUserData:
+		if language == "lua" {
+			return e.accessLuaObjectField(object, field)
+		}
 	}
-
-	// For multi-element paths, we'd need to navigate deeper
-	// But for now, let's assume single-element paths (object.field)
-	return currentValue, nil
+	return nil, errors.NewUserError("FIELD_ACCESS_ERROR", fmt.Sprintf("cannot access field '%s' on %s", field, valueTypeName(object)))
 }
 
 // accessLuaObjectField accesses a field/method on a Lua object
 func (e *ExecutionEngine) accessLuaObjectField(obj interface{}, fieldName string) (interface{}, error) {
 	// First check if this is a BitstringObject and we're accessing 'bytes'
 	if bs, ok := obj.(*shared.BitstringObject); ok && fieldName == "bytes" {
-		bytes := bs.BitString.ToBytes()
+		bytes := bs.ToBytes()
 		// Return as []byte - the Python runtime should handle this properly
 		return bytes, nil
 	}
@@ -553,6 +682,86 @@ func (e *ExecutionEngine) convertExpressionsWithNamedArgs(exprs []ast.Expression
 	return []interface{}{result}, nil
 }
 
+// convertCallArguments converts the arguments of a call to a runtime. Named
+// arguments reach each language the way it takes options: keyword arguments
+// in Python, a trailing options object in JavaScript, a trailing table in Lua
+// and trailing key => value pairs in Perl. *items and ...items spread an
+// array into positional arguments, **options spreads a map into named ones
+func (e *ExecutionEngine) convertCallArguments(language string, exprs []ast.Expression, pos ast.Position) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(exprs))
+	var names []string
+	options := make(map[string]interface{})
+	addOption := func(name string, value interface{}, pos ast.Position) error {
+		if _, repeated := options[name]; repeated {
+			return errors.NewUserErrorWithASTPos("NAMED_ARGUMENT_ERROR", fmt.Sprintf("named argument '%s' repeated", name), pos)
+		}
+		names = append(names, name)
+		options[name] = value
+		return nil
+	}
+	for _, expr := range exprs {
+		switch arg := expr.(type) {
+		case *ast.NamedArgument:
+			value, err := e.convertExpressionToValue(arg.Value)
+			if err != nil {
+				return nil, errors.NewUserErrorWithASTPos("NAMED_ARGUMENT_ERROR", fmt.Sprintf("failed to convert named argument value for '%s': %v", arg.Name, err), arg.Position())
+			}
+			if err := addOption(arg.Name, value, arg.Position()); err != nil {
+				return nil, err
+			}
+		case *ast.SpreadArgument:
+			value, err := e.convertExpressionToValue(arg.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !arg.Keyword {
+				items, ok := value.([]interface{})
+				if !ok {
+					return nil, errors.NewUserErrorWithASTPos("SPREAD_ERROR", fmt.Sprintf("cannot spread %s into arguments, need an array", valueTypeName(value)), arg.Position())
+				}
+				args = append(args, items...)
+				continue
+			}
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, errors.NewUserErrorWithASTPos("SPREAD_ERROR", fmt.Sprintf("cannot spread %s into named arguments, need a map", valueTypeName(value)), arg.Position())
+			}
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if err := addOption(key, object[key], arg.Position()); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			value, err := e.convertExpressionToValue(expr)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, value)
+		}
+	}
+	if len(names) == 0 {
+		return args, nil
+	}
+
+	switch language {
+	case "python":
+		return []interface{}{map[string]interface{}{"positional": args, "keyword": options}}, nil
+	case "node", "lua":
+		return append(args, options), nil
+	case "perl":
+		for _, name := range names {
+			args = append(args, name, options[name])
+		}
+		return args, nil
+	}
+	return nil, errors.NewUserErrorWithASTPos("NAMED_ARGUMENT_ERROR", fmt.Sprintf("%s functions do not take named arguments", language), pos)
+}
+
 // executeBitstringPatternAssignment выполняет присваивание с bitstring pattern слева
 func (e *ExecutionEngine) executeBitstringPatternAssignment(assignment *ast.BitstringPatternAssignment) (interface{}, error) {
 	if e.verbose {
@@ -572,12 +781,11 @@ func (e *ExecutionEngine) executeBitstringPatternAssignment(assignment *ast.Bits
 		bitstringData = v
 	case string:
 		// Создаем bitstring из байтов строки
-		bitString := funbit.NewBitStringFromBytes([]byte(v))
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObjectFromBytes([]byte(v))
 	case []byte:
 		// Создаем bitstring из байтов
 		bitString := funbit.NewBitStringFromBytes(v)
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 	default:
 		return nil, errors.NewUserErrorWithASTPos("BITSTRING_PATTERN_ERROR", fmt.Sprintf("cannot match bitstring pattern against type %T", value), assignment.Value.Position())
 	}
@@ -662,12 +870,11 @@ func (e *ExecutionEngine) executeBitstringPatternMatchExpression(matchExpr *ast.
 		bitstringData = v
 	case string:
 		// Создаем bitstring из байтов строки
-		bitString := funbit.NewBitStringFromBytes([]byte(v))
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObjectFromBytes([]byte(v))
 	case []byte:
 		// Создаем bitstring из байтов
 		bitString := funbit.NewBitStringFromBytes(v)
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 	default:
 		return nil, errors.NewUserErrorWithASTPos("BITSTRING_PATTERN_ERROR", fmt.Sprintf("cannot match bitstring pattern against type %T", value), matchExpr.Value.Position())
 	}
@@ -732,3 +939,47 @@ func (e *ExecutionEngine) executeBitstringPatternMatchExpression(matchExpr *ast.
 	// Возвращаем результат матчинга: true если успешно, false если нет
 	return true, nil
 }
+
+// objectField follows a path of keys through nested objects
+func objectField(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// executeValueMethodCall calls methods on the value of an expression, as in "a,b".split(",")
+func (e *ExecutionEngine) executeValueMethodCall(call *ast.ValueMethodCall) (interface{}, error) {
+	value, err := e.convertExpressionToValue(call.Receiver)
+	if err != nil {
+		return nil, err
+	}
+	return e.callValueMethodChain("value", value, call.Chain)
+}
+
+// callValueMethodChain calls the methods of chain one after another, each on
+// the result of the previous one, as in name.lower().split(","). receiver names
+// the first value in errors
+func (e *ExecutionEngine) callValueMethodChain(receiver string, value interface{}, chain []*ast.MethodCall) (interface{}, error) {
+	result := value
+	for i, method := range chain {
+		if handle, ok := result.(*runtime.Handle); ok {
+			return e.callMethodChain(handle, chain[i:], false)
+		}
+		args, err := e.convertMethodArguments(method.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		if result, err = e.callValueMethod(receiver, result, method.Method, args, method.Pos); err != nil {
+			return nil, err
+		}
+		receiver = method.Method + "()"
+	}
+	return result, nil
+}
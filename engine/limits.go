@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// rateUnits are the periods a rate can be given per: 5/second, "100/m"
+var rateUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hour": time.Hour,
+}
+
+// limitRegistry holds the named rate limits and semaphores of a session.
+// It is shared by the engine copies running & tasks, !nowait calls and
+// pipeline stages, so a limit holds across everything the script started
+type limitRegistry struct {
+	mu         sync.Mutex
+	rates      map[string]*rateLimit
+	semaphores map[string]*semaphore
+}
+
+// rateLimit is a token bucket: up to count calls at once, then count calls
+// per period, spread evenly
+type rateLimit struct {
+	count  float64
+	period time.Duration
+	tokens float64
+	last   time.Time
+	rule   *guardRule // calls the limit applies to by itself, nil for none
+}
+
+// semaphore lets at most cap(slots) holders run at once
+type semaphore struct {
+	slots chan struct{}
+	rule  *guardRule
+}
+
+func newLimitRegistry() *limitRegistry {
+	return &limitRegistry{rates: make(map[string]*rateLimit), semaphores: make(map[string]*semaphore)}
+}
+
+// executeLimitFunction runs rate_limit(name, rate) and semaphore(name, n),
+// which declare a limit or change it. rate_limit(name) without a rate waits
+// for a permit of a declared limit
+func (e *ExecutionEngine) executeLimitFunction(call *ast.BuiltinFunctionCall) (interface{}, error) {
+	if len(call.Arguments) == 0 || len(call.Arguments) > 2 {
+		return nil, errors.NewUserErrorWithASTPos("LIMIT_ERROR", fmt.Sprintf("%s() requires a name and a limit", call.Function), call.Position())
+	}
+	name, err := e.declareLimit(call.Function, call.Arguments, call.Position())
+	if err != nil {
+		return nil, err
+	}
+	if len(call.Arguments) == 2 {
+		return nil, nil
+	}
+	if call.Function == "semaphore" {
+		return nil, errors.NewUserErrorWithASTPos("LIMIT_ERROR", "semaphore() needs a body to hold it for, e.g. semaphore(\"db\") { ... }", call.Position())
+	}
+	release, err := e.acquireLimit(call.Function, name, call.Position())
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return nil, nil
+}
+
+// executeLimitStatement runs the body of rate_limit(name) { ... } after a
+// permit of the limit, or of semaphore(name) { ... } while holding a slot.
+// The body runs in the current scope
+func (e *ExecutionEngine) executeLimitStatement(limit *ast.LimitStatement) (interface{}, error) {
+	if err := e.checkBuiltin(limit.Function, limit.Pos); err != nil {
+		return nil, err
+	}
+	name, err := e.declareLimit(limit.Function, limit.Arguments, limit.Pos)
+	if err != nil {
+		return nil, err
+	}
+	release, err := e.acquireLimit(limit.Function, name, limit.Pos)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return e.executeBlockStatement(limit.Body)
+}
+
+// declareLimit evaluates the name of a limit and, when given, its value, and
+// creates or updates the limit. Without a value the limit must exist
+func (e *ExecutionEngine) declareLimit(function string, arguments []ast.Expression, pos ast.Position) (string, error) {
+	limitError := func(format string, args ...interface{}) error {
+		return errors.NewUserErrorWithASTPos("LIMIT_ERROR", fmt.Sprintf(function+"() "+format, args...), pos)
+	}
+	value, err := e.convertExpressionToValue(arguments[0])
+	if err != nil {
+		return "", err
+	}
+	name, ok := value.(string)
+	if !ok || name == "" {
+		return "", limitError("name must be a non-empty string, got %s", orderTypeName(value))
+	}
+	var rule *guardRule
+	if strings.Contains(name, ".") {
+		parsed, err := parseGuardRule(name)
+		if err != nil {
+			return "", limitError("name %q is not a call pattern such as py.requests.*", name)
+		}
+		rule = &parsed
+	}
+
+	registry := e.limits
+	if len(arguments) < 2 {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		if (function == "rate_limit" && registry.rates[name] == nil) || (function == "semaphore" && registry.semaphores[name] == nil) {
+			return "", limitError("%q is not declared, give its limit the first time, e.g. %s(%q, %s)", name, function, name, map[string]string{"rate_limit": "5/second", "semaphore": "2"}[function])
+		}
+		return name, nil
+	}
+
+	if function == "semaphore" {
+		value, err := e.convertExpressionToValue(arguments[1])
+		if err != nil {
+			return "", err
+		}
+		size, ok := toInt64(value)
+		if f, isFloat := value.(float64); isFloat && f != float64(int64(f)) {
+			ok = false
+		}
+		if !ok || size < 1 {
+			return "", limitError("expects a positive whole number of slots, got %v", value)
+		}
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		if current := registry.semaphores[name]; current == nil || cap(current.slots) != int(size) {
+			// Holders of the old slots release them there, new ones wait here
+			registry.semaphores[name] = &semaphore{slots: make(chan struct{}, size), rule: rule}
+		}
+		return name, nil
+	}
+
+	count, period, err := e.limitRate(arguments[1])
+	if err != nil {
+		return "", limitError("%v", err)
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if current := registry.rates[name]; current != nil {
+		current.count, current.period = count, period
+		if current.tokens > count {
+			current.tokens = count
+		}
+		return name, nil
+	}
+	registry.rates[name] = &rateLimit{count: count, period: period, tokens: count, last: time.Now(), rule: rule}
+	return name, nil
+}
+
+// limitRate reads a rate: 5/second, 100/minute, a string such as "5/s" or a
+// number of calls per second
+func (e *ExecutionEngine) limitRate(expr ast.Expression) (float64, time.Duration, error) {
+	if binary, ok := expr.(*ast.BinaryExpression); ok && binary.Operator == "/" {
+		var unit string
+		switch right := binary.Right.(type) {
+		case *ast.Identifier:
+			unit = right.Name
+		case *ast.VariableRead:
+			unit = right.Variable.Name
+		}
+		if period, isUnit := rateUnits[unit]; isUnit {
+			value, err := e.convertExpressionToValue(binary.Left)
+			if err != nil {
+				return 0, 0, err
+			}
+			return rateCount(value, period)
+		}
+	}
+
+	value, err := e.convertExpressionToValue(expr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("expects a rate such as 5/second, 100/minute or 1/hour: %v", err)
+	}
+	text, ok := value.(string)
+	if !ok {
+		return rateCount(value, time.Second)
+	}
+	count, unit, found := strings.Cut(strings.ReplaceAll(text, " ", ""), "/")
+	period, isUnit := rateUnits[unit]
+	number, err := strconv.ParseFloat(count, 64)
+	if !found || !isUnit || err != nil {
+		return 0, 0, fmt.Errorf("expects a rate such as 5/second or \"100/minute\", got %q", text)
+	}
+	return rateCount(number, period)
+}
+
+// rateCount checks the number of calls of a rate
+func rateCount(value interface{}, period time.Duration) (float64, time.Duration, error) {
+	count, ok := value.(float64)
+	if !ok {
+		whole, isInt := toInt64(value)
+		count, ok = float64(whole), isInt
+	}
+	if !ok || count <= 0 {
+		return 0, 0, fmt.Errorf("expects a positive number of calls, got %v", value)
+	}
+	return count, period, nil
+}
+
+// acquireLimit waits for a permit of the named rate limit or a slot of the
+// named semaphore. The returned function gives the slot back
+func (e *ExecutionEngine) acquireLimit(function, name string, pos ast.Position) (func(), error) {
+	var err error
+	if function == "rate_limit" {
+		err = e.waitRate(name)
+	} else {
+		var release func()
+		if release, err = e.holdSemaphore(name); err == nil {
+			return release, nil
+		}
+	}
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("LIMIT_ERROR", fmt.Sprintf("%s(%q): %v", function, name, err), pos)
+	}
+	return func() {}, nil
+}
+
+// waitRate takes a permit of a rate limit, sleeping until there is one.
+// A permit that would come after the time limit of the command is not taken
+func (e *ExecutionEngine) waitRate(name string) error {
+	registry := e.limits
+	registry.mu.Lock()
+	rate := registry.rates[name]
+	now := time.Now()
+	rate.tokens += now.Sub(rate.last).Seconds() * rate.count / rate.period.Seconds()
+	if rate.tokens > rate.count {
+		rate.tokens = rate.count
+	}
+	rate.last = now
+	rate.tokens--
+	wait := time.Duration(-rate.tokens * float64(rate.period) / rate.count)
+	if wait > 0 && !e.deadline.IsZero() && now.Add(wait).After(e.deadline) {
+		rate.tokens++
+		wait = time.Until(e.deadline)
+	}
+	registry.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return e.checkDeadline()
+}
+
+// holdSemaphore takes a slot of a semaphore, waiting no longer than the time
+// limit of the command
+func (e *ExecutionEngine) holdSemaphore(name string) (func(), error) {
+	e.limits.mu.Lock()
+	slots := e.limits.semaphores[name].slots
+	e.limits.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !e.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(e.deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-timeout:
+		return nil, e.checkDeadline()
+	}
+}
+
+// limitCall applies the limits named after call patterns, such as
+// rate_limit("py.requests.*", 5/second), to a runtime call. The returned
+// function gives the semaphore slots back after the call
+func (e *ExecutionEngine) limitCall(language, function string, args []interface{}, pos ast.Position) (func(), error) {
+	if e.limits == nil {
+		return func() {}, nil
+	}
+	var rates, semaphores []string
+	e.limits.mu.Lock()
+	for name, rate := range e.limits.rates {
+		if rate.rule != nil && rate.rule.matches(language, function, args) {
+			rates = append(rates, name)
+		}
+	}
+	for name, sem := range e.limits.semaphores {
+		if sem.rule != nil && sem.rule.matches(language, function, args) {
+			semaphores = append(semaphores, name)
+		}
+	}
+	e.limits.mu.Unlock()
+	// Taken in one order, so two calls can't each hold what the other waits for
+	sort.Strings(rates)
+	sort.Strings(semaphores)
+
+	var releases []func()
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	for _, name := range semaphores {
+		held, err := e.acquireLimit("semaphore", name, pos)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, held)
+	}
+	for _, name := range rates {
+		if _, err := e.acquireLimit("rate_limit", name, pos); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,355 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"funterm/shared"
+	"go-parser/pkg/ast"
+)
+
+// maxDiagnosticBytes limits the bytes shown around a failing offset
+const maxDiagnosticBytes = 8
+
+// explainNoMatch describes why the bitstring arms of a match did not match
+// the subject, one line per arm, or returns "" for non-bitstring subjects
+func (e *ExecutionEngine) explainNoMatch(matchStmt *ast.MatchStatement, subject interface{}) string {
+	if _, isString := subject.(string); isString {
+		return ""
+	}
+	data, err := binaryArgument("match", subject)
+	if err != nil {
+		return ""
+	}
+
+	var lines []string
+	for i, arm := range matchStmt.Arms {
+		pattern, ok := arm.Pattern.(*ast.BitstringPattern)
+		if !ok {
+			continue
+		}
+		if reason := e.explainBitstringMismatch(pattern, data); reason != "" {
+			lines = append(lines, fmt.Sprintf("  arm %d (line %d): %s", i+1, pattern.Position().Line, reason))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s):\n%s", countBits(data.Len()), strings.Join(lines, "\n"))
+}
+
+// explainBitstringMismatch walks the segments of a pattern over the data the
+// way the matcher does and reports the first segment that cannot match
+func (e *ExecutionEngine) explainBitstringMismatch(pattern *ast.BitstringPattern, data *shared.BitstringObject) string {
+	adapter := NewFunbitAdapterWithEngine(e)
+	bytes := data.Bytes()
+	total := data.Len()
+	bindings := map[string]interface{}{}
+	offset := 0
+
+	for i := range pattern.Elements {
+		segment := &pattern.Elements[i]
+		source := segmentSource(segment)
+		specs, err := adapter.parseSpecifiers(segment.Specifiers)
+		if err != nil {
+			return ""
+		}
+		literal, isLiteral := segmentLiteral(adapter, segment)
+		kind := specs.Type
+		if kind == "" {
+			kind = "integer"
+			if _, isText := literal.(string); isText {
+				kind = "binary"
+			}
+		}
+		available := total - offset
+
+		size, sized, err := segmentSize(adapter, segment, specs, bindings)
+		if err != nil {
+			return ""
+		}
+		if !sized {
+			switch kind {
+			case "integer":
+				size = 8
+			case "float":
+				size = 64
+			case "utf8", "utf16", "utf32", "utf":
+				size = utfSegmentSize(kind, bytes, offset, available)
+			case "binary", "bitstring":
+				if text, isText := literal.(string); isText {
+					size = len(text) * 8
+				} else if i == len(pattern.Elements)-1 {
+					// An unsized final segment takes the rest
+					if unit := int(specs.Unit); kind == "binary" && available%unit != 0 {
+						return fmt.Sprintf("segment %d `%s` at bit %d takes the rest as whole bytes, but %d bits remain", i+1, source, offset, available)
+					}
+					size = available
+				} else {
+					return ""
+				}
+			default:
+				return ""
+			}
+		}
+
+		if size > available {
+			detail := ""
+			if sizeExpr := segment.SizeExpression; sizeExpr != nil && sizeExpr.ExprType == "variable" {
+				if value, bound := bindings[sizeExpr.Variable]; bound {
+					detail = fmt.Sprintf(" (%s = %v)", sizeExpr.Variable, value)
+				}
+			}
+			return fmt.Sprintf("segment %d `%s`%s needs %s at bit %d, only %s available%s", i+1, source, detail, countBits(size), offset, countBits(available), bytesAround(bytes, offset, total))
+		}
+		if kind == "binary" && offset%8 != 0 && isLiteral {
+			return fmt.Sprintf("segment %d `%s` starts at bit %d, which is not byte-aligned%s", i+1, source, offset, bytesAround(bytes, offset, total))
+		}
+
+		if isLiteral {
+			if report := compareLiteral(literal, kind, specs, bytes, offset, size); report != "" {
+				return fmt.Sprintf("segment %d `%s` %s at bit %d%s%s", i+1, source, report, offset, bytesAround(bytes, offset, total), endiannessHint(literal, kind, specs, bytes, offset, size, segment))
+			}
+		} else if id, ok := segment.Value.(*ast.Identifier); ok && id.Name != "_" && kind == "integer" && size <= 64 {
+			// Later segments may use the value as their size
+			bindings[id.Name] = readSegmentInteger(bytes, offset, size, specs.Endianness == "little", specs.Signed).Int64()
+		}
+		offset += size
+	}
+
+	if offset < total {
+		return fmt.Sprintf("all segments matched, but %s are left over at bit %d; end the pattern with `rest/binary` or `rest/bits` to accept them%s", countBits(total-offset), offset, bytesAround(bytes, offset, total))
+	}
+	return ""
+}
+
+// segmentSize resolves the size of a segment in bits; sized is false when the
+// segment has no size and the type's default applies
+func segmentSize(adapter *FunbitAdapter, segment *ast.BitstringSegment, specs FunbitBitstringSpecifiers, bindings map[string]interface{}) (int, bool, error) {
+	var units uint
+	var err error
+	switch {
+	case segment.SizeExpression != nil:
+		units, err = adapter.resolveDynamicSize(segment.SizeExpression, bindings)
+	case segment.Size != nil:
+		var value interface{}
+		if value, err = adapter.convertValue(segment.Size); err == nil {
+			units, err = adapter.convertToUint(value)
+		}
+	default:
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return int(units * specs.Unit), true, nil
+}
+
+// segmentLiteral returns the constant a segment must equal, if it has one
+func segmentLiteral(adapter *FunbitAdapter, segment *ast.BitstringSegment) (interface{}, bool) {
+	switch segment.Value.(type) {
+	case *ast.Identifier:
+		return nil, false
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.UnaryExpression:
+		value, err := adapter.convertValue(segment.Value)
+		if err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// segmentSource renders a segment the way it is written in a pattern
+func segmentSource(segment *ast.BitstringSegment) string {
+	var b strings.Builder
+	b.WriteString(expressionSource(segment.Value))
+	if segment.SizeExpression != nil && segment.SizeExpression.ExprType == "variable" {
+		b.WriteString(":" + segment.SizeExpression.Variable)
+	} else if segment.Size != nil {
+		b.WriteString(":" + expressionSource(segment.Size))
+	} else if segment.SizeExpression != nil {
+		b.WriteString(":(...)")
+	}
+	if len(segment.Specifiers) > 0 {
+		b.WriteString("/" + strings.Join(segment.Specifiers, "-"))
+	}
+	return b.String()
+}
+
+// expressionSource renders the simple expressions found in segments
+func expressionSource(expr ast.Expression) string {
+	switch v := expr.(type) {
+	case *ast.Identifier:
+		return v.Name
+	case *ast.NumberLiteral:
+		return v.String()
+	case *ast.StringLiteral:
+		return fmt.Sprintf("%q", v.Value)
+	case *ast.UnaryExpression:
+		return v.Operator + expressionSource(v.Right)
+	case *ast.SizeExpression:
+		if v.ExprType == "variable" {
+			return v.Variable
+		}
+	}
+	return "(...)"
+}
+
+// utfSegmentSize returns the encoded length of the character at offset
+func utfSegmentSize(kind string, data []byte, offset, available int) int {
+	switch kind {
+	case "utf16":
+		if available >= 16 && offset%8 == 0 {
+			if unit := int(data[offset/8])<<8 | int(data[offset/8+1]); unit >= 0xD800 && unit < 0xDC00 {
+				return 32
+			}
+		}
+		return 16
+	case "utf32":
+		return 32
+	}
+	if available < 8 || offset%8 != 0 {
+		return 8
+	}
+	first := data[offset/8]
+	switch {
+	case first < 0x80:
+		return 8
+	case first>>5 == 0x6:
+		return 16
+	case first>>4 == 0xE:
+		return 24
+	case first>>3 == 0x1E:
+		return 32
+	}
+	return 8
+}
+
+// compareLiteral checks a constant segment and describes the difference, or returns ""
+func compareLiteral(literal interface{}, kind string, specs FunbitBitstringSpecifiers, data []byte, offset, size int) string {
+	switch expected := literal.(type) {
+	case string:
+		if offset%8 != 0 || size%8 != 0 {
+			return ""
+		}
+		found := data[offset/8 : offset/8+size/8]
+		if string(found) == expected {
+			return ""
+		}
+		return fmt.Sprintf("expected %q, found %q", expected, string(found))
+	case int, int64, *big.Int:
+		if kind != "integer" || size == 0 {
+			return ""
+		}
+		want := literalBigInt(expected)
+		found := readSegmentInteger(data, offset, size, specs.Endianness == "little", specs.Signed)
+		if !specs.Signed && want.Sign() < 0 {
+			// Negative constants match their two's complement
+			want = new(big.Int).Add(want, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+		}
+		if found.Cmp(want) == 0 {
+			return ""
+		}
+		withHex := size%8 == 0 && (want.Cmp(big.NewInt(10)) >= 0 || found.Cmp(big.NewInt(10)) >= 0)
+		return fmt.Sprintf("expected %s, found %s", formatSegmentInteger(want, size, withHex), formatSegmentInteger(found, size, withHex))
+	}
+	return ""
+}
+
+// endiannessHint suggests the opposite byte order when it would have matched
+func endiannessHint(literal interface{}, kind string, specs FunbitBitstringSpecifiers, data []byte, offset, size int, segment *ast.BitstringSegment) string {
+	if kind != "integer" || size < 16 || size%8 != 0 {
+		return ""
+	}
+	if _, isText := literal.(string); isText {
+		return ""
+	}
+	flipped := "little"
+	if specs.Endianness == "little" {
+		flipped = "big"
+	}
+	other := specs
+	other.Endianness = flipped
+	if compareLiteral(literal, kind, other, data, offset, size) != "" {
+		return ""
+	}
+	var specifiers []string
+	for _, spec := range segment.Specifiers {
+		if spec != "big" && spec != "little" && spec != "native" {
+			specifiers = append(specifiers, spec)
+		}
+	}
+	hinted := *segment
+	hinted.Specifiers = append(specifiers, flipped)
+	return fmt.Sprintf("; `%s` would match", segmentSource(&hinted))
+}
+
+// readSegmentInteger reads size bits at offset as an integer
+func readSegmentInteger(data []byte, offset, size int, little, signed bool) *big.Int {
+	n := new(big.Int)
+	if little && size%8 == 0 {
+		for i := size/8 - 1; i >= 0; i-- {
+			for bit := 0; bit < 8; bit++ {
+				n.Lsh(n, 1).Or(n, big.NewInt(int64(bitAt(data, offset+i*8+bit))))
+			}
+		}
+	} else {
+		for i := 0; i < size; i++ {
+			n.Lsh(n, 1).Or(n, big.NewInt(int64(bitAt(data, offset+i))))
+		}
+	}
+	if signed && size > 0 && n.Bit(size-1) == 1 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+	}
+	return n
+}
+
+// literalBigInt converts an integer literal value to a big.Int
+func literalBigInt(value interface{}) *big.Int {
+	if n, ok := value.(*big.Int); ok {
+		return n
+	}
+	n, _ := toInt64(value)
+	return big.NewInt(n)
+}
+
+// formatSegmentInteger shows a value in decimal, followed by hex if asked
+func formatSegmentInteger(n *big.Int, size int, withHex bool) string {
+	if !withHex || n.Sign() < 0 {
+		return n.String()
+	}
+	return fmt.Sprintf("%s (0x%0*x)", n.String(), size/4, n)
+}
+
+// bytesAround shows the data at a bit offset as hex bytes, or as bits when unaligned
+func bytesAround(data []byte, offset, total int) string {
+	if offset >= total {
+		return ""
+	}
+	if offset%8 != 0 {
+		end := offset + 16
+		if end > total {
+			end = total
+		}
+		return fmt.Sprintf("; bits there: %s", formatBitRange(data, offset, end-1))
+	}
+	start := offset / 8
+	end := (total + 7) / 8
+	more := ""
+	if end-start > maxDiagnosticBytes {
+		end = start + maxDiagnosticBytes
+		more = " ..."
+	}
+	hex := make([]string, 0, end-start)
+	for _, b := range data[start:end] {
+		hex = append(hex, fmt.Sprintf("%02x", b))
+	}
+	return fmt.Sprintf("; bytes at offset %d: %s%s", start, strings.Join(hex, " "), more)
+}
+
+// countBits formats a number of bits
+func countBits(n int) string {
+	return fmt.Sprintf("%d %s", n, pluralBits(n))
+}
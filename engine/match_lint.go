@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// LintWarning is a problem in a script found without running it
+type LintWarning struct {
+	Pos     ast.Position
+	Message string
+}
+
+// String formats the warning as "line N col M: message"
+func (w LintWarning) String() string {
+	return fmt.Sprintf("line %d col %d: %s", w.Pos.Line, w.Pos.Column, w.Message)
+}
+
+// Lint parses code without executing it and checks its match statements
+func (e *ExecutionEngine) Lint(code string) ([]LintWarning, error) {
+	statement, parseErrors := e.parser.Parse(code)
+	if len(parseErrors) > 0 {
+		return nil, errors.NewUserErrorWithASTPos("PARSING_ERROR", parseErrors[0].Message, parseErrors[0].Position)
+	}
+	// Nothing keeps the tree once it is linted
+	defer ast.ReleaseTree(statement)
+	return LintStatement(statement), nil
+}
+
+// SetMatchWarnings enables checking each match statement when it first runs
+func (e *ExecutionEngine) SetMatchWarnings(enabled bool) {
+	e.matchWarnings = enabled
+}
+
+// warnAboutMatch prints the lint warnings of a match statement the first time it runs
+func (e *ExecutionEngine) warnAboutMatch(matchStmt *ast.MatchStatement) {
+	if e.lintedMatches == nil {
+		e.lintedMatches = make(map[*ast.MatchStatement]bool)
+	}
+	if e.lintedMatches[matchStmt] {
+		return
+	}
+	e.lintedMatches[matchStmt] = true
+	for _, warning := range lintMatch(matchStmt) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// LintStatement checks the match statements in stmt and in every body nested in it
+func LintStatement(stmt ast.Statement) []LintWarning {
+	var warnings []LintWarning
+	switch s := stmt.(type) {
+	case *ast.BlockStatement:
+		if s == nil {
+			return nil
+		}
+		for _, child := range s.Statements {
+			warnings = append(warnings, LintStatement(child)...)
+		}
+	case *ast.IfStatement:
+		warnings = append(warnings, LintStatement(s.Consequent)...)
+		if s.Alternate != nil {
+			warnings = append(warnings, LintStatement(s.Alternate)...)
+		}
+	case *ast.WhileStatement:
+		warnings = append(warnings, LintStatement(s.Body)...)
+	case *ast.ForInLoopStatement:
+		warnings = append(warnings, lintStatements(s.Body)...)
+	case *ast.NumericForLoopStatement:
+		warnings = append(warnings, lintStatements(s.Body)...)
+	case *ast.CStyleForLoopStatement:
+		warnings = append(warnings, lintStatements(s.Body)...)
+	case *ast.MatchStatement:
+		warnings = append(warnings, lintMatch(s)...)
+		for _, arm := range s.Arms {
+			warnings = append(warnings, LintStatement(arm.Statement)...)
+		}
+	}
+	return warnings
+}
+
+func lintStatements(stmts []ast.Statement) []LintWarning {
+	var warnings []LintWarning
+	for _, stmt := range stmts {
+		warnings = append(warnings, LintStatement(stmt)...)
+	}
+	return warnings
+}
+
+// lintMatch reports arms that can never match because an earlier arm already
+// matches everything they do, and matches without a catch-all arm
+func lintMatch(matchStmt *ast.MatchStatement) []LintWarning {
+	var warnings []LintWarning
+
+	for j, arm := range matchStmt.Arms {
+		for i := 0; i < j; i++ {
+			// A guarded arm may decline values its pattern matches
+			if matchStmt.Arms[i].Guard != nil {
+				continue
+			}
+			earlier := matchStmt.Arms[i].Pattern
+			if patternCovers(earlier, arm.Pattern) {
+				warnings = append(warnings, LintWarning{
+					Pos: arm.Pattern.Position(),
+					Message: fmt.Sprintf("match arm %d is unreachable: arm %d at line %d already matches everything it does",
+						j+1, i+1, earlier.Position().Line),
+				})
+				break
+			}
+		}
+	}
+
+	if !armsAreExhaustive(matchStmt.Arms) {
+		warnings = append(warnings, LintWarning{
+			Pos:     matchStmt.Position(),
+			Message: "match has no wildcard arm; values no arm matches raise NO_PATTERN_MATCH",
+		})
+	}
+
+	return warnings
+}
+
+// armsAreExhaustive reports whether every value matches some arm: there is a
+// catch-all arm, or both ok(...) and error(...) are matched completely
+func armsAreExhaustive(arms []ast.MatchArm) bool {
+	okCovered, errorCovered := false, false
+	for _, arm := range arms {
+		if arm.Guard != nil {
+			continue
+		}
+		if isIrrefutable(arm.Pattern) {
+			return true
+		}
+		if result, ok := arm.Pattern.(*ast.ResultPattern); ok && isIrrefutable(result.Inner) {
+			if result.Ok {
+				okCovered = true
+			} else {
+				errorCovered = true
+			}
+		}
+	}
+	return okCovered && errorCovered
+}
+
+// isIrrefutable reports whether a pattern matches any value
+func isIrrefutable(pattern ast.Pattern) bool {
+	switch pattern.(type) {
+	case *ast.WildcardPattern, *ast.VariablePattern:
+		return true
+	}
+	return false
+}
+
+// patternCovers reports whether every value matched by later is also matched
+// by earlier. It errs on the side of false: an arm is only reported as
+// shadowed when that is certain.
+func patternCovers(earlier, later ast.Pattern) bool {
+	if isIrrefutable(earlier) {
+		return true
+	}
+
+	switch e := earlier.(type) {
+	case *ast.LiteralPattern:
+		if l, ok := later.(*ast.LiteralPattern); ok {
+			return sameLiteral(e.Value, l.Value)
+		}
+	case *ast.RangePattern:
+		switch l := later.(type) {
+		case *ast.LiteralPattern:
+			return inRange(e, l.Value)
+		case *ast.RangePattern:
+			return inRange(e, l.Low) && inRange(e, l.High)
+		}
+	case *ast.ResultPattern:
+		if l, ok := later.(*ast.ResultPattern); ok {
+			return e.Ok == l.Ok && patternCovers(e.Inner, l.Inner)
+		}
+	case *ast.ArrayPattern:
+		if l, ok := later.(*ast.ArrayPattern); ok {
+			return arrayPatternCovers(e, l)
+		}
+	case *ast.ObjectPattern:
+		if l, ok := later.(*ast.ObjectPattern); ok {
+			return objectPatternCovers(e, l)
+		}
+	case *ast.BitstringPattern:
+		// Bitstring patterns are compared segment by segment as written
+		if l, ok := later.(*ast.BitstringPattern); ok {
+			return e.String() == l.String()
+		}
+	}
+	return false
+}
+
+// sameLiteral compares two literal pattern values of the same kind
+func sameLiteral(a, b interface{}) bool {
+	aKind, aOk := orderKind(a)
+	bKind, bOk := orderKind(b)
+	if !aOk || !bOk || aKind != bKind {
+		return false
+	}
+	cmp, err := compareOrdered(a, b)
+	return err == nil && cmp == 0
+}
+
+// inRange reports whether a literal value lies inside a range pattern
+func inRange(pattern *ast.RangePattern, value interface{}) bool {
+	lowKind, _ := orderKind(pattern.Low)
+	if valueKind, ok := orderKind(value); !ok || valueKind != lowKind {
+		return false
+	}
+	low, err := compareOrdered(pattern.Low, value)
+	if err != nil || low > 0 {
+		return false
+	}
+	high, err := compareOrdered(value, pattern.High)
+	return err == nil && (high < 0 || high == 0 && !pattern.Exclusive)
+}
+
+// arrayPatternCovers compares array patterns of the same length element by
+// element, the way matchesArrayPattern matches them
+func arrayPatternCovers(earlier, later *ast.ArrayPattern) bool {
+	if len(earlier.Elements) != len(later.Elements) {
+		return false
+	}
+	for i, element := range earlier.Elements {
+		if !patternCovers(element, later.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectPatternCovers requires every key of earlier to be matched at least as
+// broadly in later; an empty earlier pattern matches only empty objects
+func objectPatternCovers(earlier, later *ast.ObjectPattern) bool {
+	if len(earlier.Properties) == 0 {
+		return len(later.Properties) == 0
+	}
+	for key, pattern := range earlier.Properties {
+		if key == "_" {
+			return false
+		}
+		laterPattern, ok := later.Properties[key]
+		if !ok || !patternCovers(pattern, laterPattern) {
+			return false
+		}
+	}
+	return true
+}
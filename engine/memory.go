@@ -0,0 +1,240 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	goruntime "runtime"
+	"sort"
+
+	"funterm/runtime"
+	"funterm/shared"
+)
+
+// VariableUsage describes a session variable and its approximate footprint
+type VariableUsage struct {
+	Name  string
+	Type  string
+	Bytes int64
+}
+
+// GCReport summarizes what CollectGarbage freed
+type GCReport struct {
+	ReleasedHandles []string
+	HeapBefore      uint64
+	HeapAfter       uint64
+}
+
+// SetMemoryBudget sets the variable footprint after which a warning is shown; 0 disables it
+func (e *ExecutionEngine) SetMemoryBudget(bytes int64) {
+	e.memoryBudget = bytes
+}
+
+// MemoryBudget returns the configured variable footprint limit in bytes
+func (e *ExecutionEngine) MemoryBudget() int64 {
+	return e.memoryBudget
+}
+
+// sessionVariables returns the top-level variables of the session,
+// qualified runtime variables are listed as "lang.name"
+func (e *ExecutionEngine) sessionVariables() map[string]interface{} {
+	vars := e.getAllGlobalVariables()
+	if len(e.scopeStack) > 0 {
+		for name, value := range e.scopeStack[0].GetAll() {
+			vars[name] = value
+		}
+	}
+
+	e.variablesMutex.RLock()
+	for language, languageVars := range e.sharedVariables {
+		for name, value := range languageVars {
+			vars[language+"."+name] = value
+		}
+	}
+	e.variablesMutex.RUnlock()
+
+	return vars
+}
+
+// VariableUsage lists session variables with their approximate size, sorted by name
+func (e *ExecutionEngine) VariableUsage() []VariableUsage {
+	vars := e.sessionVariables()
+	usage := make([]VariableUsage, 0, len(vars))
+	for name, value := range vars {
+		usage = append(usage, VariableUsage{Name: name, Type: valueTypeName(value), Bytes: estimateSize(value)})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Name < usage[j].Name })
+	return usage
+}
+
+// VariableBytes is the total footprint of all session variables
+func (e *ExecutionEngine) VariableBytes() int64 {
+	var total int64
+	for _, value := range e.sessionVariables() {
+		total += estimateSize(value)
+	}
+	return total
+}
+
+// unreferencedHandles returns live proxy handles no variable refers to any more
+func (e *ExecutionEngine) unreferencedHandles() []*runtime.Handle {
+	if e.handles == nil {
+		return nil
+	}
+	handles := e.handles.list()
+	if len(handles) == 0 {
+		return nil
+	}
+	referenced := make(map[*runtime.Handle]bool)
+	for _, value := range e.sessionVariables() {
+		collectHandles(value, referenced)
+	}
+
+	var leaked []*runtime.Handle
+	for _, handle := range handles {
+		if !referenced[handle] {
+			leaked = append(leaked, handle)
+		}
+	}
+	sort.Slice(leaked, func(i, j int) bool { return handleKey(leaked[i]) < handleKey(leaked[j]) })
+	return leaked
+}
+
+// CollectGarbage releases proxy handles that are no longer referenced and runs the Go GC
+func (e *ExecutionEngine) CollectGarbage() (GCReport, error) {
+	var report GCReport
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	report.HeapBefore = stats.HeapAlloc
+
+	for _, handle := range e.unreferencedHandles() {
+		if err := e.releaseHandle(handle); err != nil {
+			return report, err
+		}
+		delete(e.leakWarned, handleKey(handle))
+		report.ReleasedHandles = append(report.ReleasedHandles, handle.String())
+	}
+
+	goruntime.GC()
+	goruntime.ReadMemStats(&stats)
+	report.HeapAfter = stats.HeapAlloc
+	return report, nil
+}
+
+// CheckMemory returns warnings about leaked handles and an exceeded memory budget.
+// Each leak and each crossing of the budget is reported only once.
+func (e *ExecutionEngine) CheckMemory() []string {
+	var warnings []string
+
+	if e.leakWarned == nil {
+		e.leakWarned = make(map[string]bool)
+	}
+	for _, handle := range e.unreferencedHandles() {
+		key := handleKey(handle)
+		if e.leakWarned[key] {
+			continue
+		}
+		e.leakWarned[key] = true
+		warnings = append(warnings, fmt.Sprintf("handle %s is no longer referenced; run :gc or release() to free it", handle))
+	}
+
+	if e.memoryBudget > 0 {
+		total := e.VariableBytes()
+		if total > e.memoryBudget && !e.budgetWarned {
+			e.budgetWarned = true
+			warnings = append(warnings, fmt.Sprintf("session variables use %s, exceeding the memory budget of %s; see :vars", FormatBytes(total), FormatBytes(e.memoryBudget)))
+		} else if total <= e.memoryBudget {
+			e.budgetWarned = false
+		}
+	}
+
+	return warnings
+}
+
+// collectHandles marks every proxy handle reachable from value
+func collectHandles(value interface{}, seen map[*runtime.Handle]bool) {
+	switch v := value.(type) {
+	case *runtime.Handle:
+		seen[v] = true
+	case []interface{}:
+		for _, item := range v {
+			collectHandles(item, seen)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectHandles(item, seen)
+		}
+	}
+}
+
+// estimateSize approximates how many bytes a value keeps alive in the engine.
+// Handles count as zero: their objects live in the owning runtime.
+func estimateSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil, *runtime.Handle:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case *big.Int:
+		return int64(v.BitLen()+7) / 8
+	case *shared.BitstringObject:
+		if v == nil {
+			return 0
+		}
+		return int64((v.Len() + 7) / 8)
+	case []interface{}:
+		total := int64(len(v)) * 16
+		for _, item := range v {
+			total += estimateSize(item)
+		}
+		return total
+	case map[string]interface{}:
+		var total int64
+		for key, item := range v {
+			total += int64(len(key)) + 16 + estimateSize(item)
+		}
+		return total
+	default:
+		return 8
+	}
+}
+
+// valueTypeName is the user-facing type name shown by :vars
+func valueTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int32, int64, float32, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "map"
+	case *shared.BitstringObject:
+		return "bitstring"
+	case *runtime.Handle:
+		return "handle"
+	case *Channel:
+		return "channel"
+	case *Process:
+		return "process"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// FormatBytes renders a byte count as B, KB or MB
+func FormatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"math"
+	"math/big"
+
+	"funterm/runtime"
+)
+
+// SetNumberPolicy sets how numbers coming from runtimes are represented;
+// empty fields of the policy keep their defaults
+func (e *ExecutionEngine) SetNumberPolicy(policy runtime.NumberPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	e.numberPolicy = policy.WithDefaults()
+	return nil
+}
+
+// NumberPolicy returns the number policy in effect
+func (e *ExecutionEngine) NumberPolicy() runtime.NumberPolicy {
+	return e.numberPolicy
+}
+
+// normalizeNumbers applies the registered converters and the number policy
+// to a value that came from the given runtime, so the same number always
+// reaches scripts in the same form whether it was returned, read as a
+// variable or captured from a code block
+func (e *ExecutionEngine) normalizeNumbers(language string, value interface{}) interface{} {
+	value = runtime.ConvertFromRuntime(language, value)
+	var convert func(interface{}) (interface{}, bool)
+	switch language {
+	case "lua":
+		convert = integralConverter(e.numberPolicy.LuaIntegers)
+	case "node", "js":
+		convert = integralConverter(e.numberPolicy.JSIntegers)
+	case "python", "py":
+		if e.numberPolicy.PythonBigInts != runtime.BigIntsAsStrings {
+			return value
+		}
+		convert = bigIntToString
+	default:
+		return value
+	}
+	converted, _ := mapNumbers(value, convert)
+	return converted
+}
+
+// integralConverter returns the conversion for Lua/JS numbers under the given mode
+func integralConverter(mode string) func(interface{}) (interface{}, bool) {
+	if mode == runtime.IntegersAsInt {
+		return func(value interface{}) (interface{}, bool) {
+			// 2^63 and beyond don't fit into int64 and stay float64
+			if f, ok := value.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+				return int64(f), true
+			}
+			return value, false
+		}
+	}
+	return func(value interface{}) (interface{}, bool) {
+		if i, ok := value.(int64); ok {
+			return float64(i), true
+		}
+		return value, false
+	}
+}
+
+// bigIntToString turns integers beyond 2^53 into their decimal text
+func bigIntToString(value interface{}) (interface{}, bool) {
+	if n, ok := value.(*big.Int); ok && n.CmpAbs(big.NewInt(runtime.MaxSafeInteger)) > 0 {
+		return n.String(), true
+	}
+	return value, false
+}
+
+// mapNumbers applies convert to every scalar inside arrays and objects.
+// Containers are copied only when something in them changed, because the
+// runtime may still hold on to the originals.
+func mapNumbers(value interface{}, convert func(interface{}) (interface{}, bool)) (interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		var out []interface{}
+		for i, item := range v {
+			if converted, changed := mapNumbers(item, convert); changed {
+				if out == nil {
+					out = append([]interface{}(nil), v...)
+				}
+				out[i] = converted
+			}
+		}
+		if out == nil {
+			return value, false
+		}
+		return out, true
+	case map[string]interface{}:
+		var out map[string]interface{}
+		for key, item := range v {
+			if converted, changed := mapNumbers(item, convert); changed {
+				if out == nil {
+					out = make(map[string]interface{}, len(v))
+					for k, original := range v {
+						out[k] = original
+					}
+				}
+				out[key] = converted
+			}
+		}
+		if out == nil {
+			return value, false
+		}
+		return out, true
+	default:
+		return convert(value)
+	}
+}
+
+// bigIntOperands converts a pair of integers to *big.Int when at least one
+// of them already is one
+func bigIntOperands(left, right interface{}) (*big.Int, *big.Int, bool) {
+	_, leftBig := left.(*big.Int)
+	_, rightBig := right.(*big.Int)
+	if !leftBig && !rightBig {
+		return nil, nil, false
+	}
+	l, ok := toBigInt(left)
+	if !ok {
+		return nil, nil, false
+	}
+	r, ok := toBigInt(right)
+	if !ok {
+		return nil, nil, false
+	}
+	return l, r, true
+}
+
+// toBigInt converts an integer value to *big.Int
+func toBigInt(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, true
+	case int64:
+		return big.NewInt(v), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	case int:
+		return big.NewInt(int64(v)), true
+	}
+	return nil, false
+}
+
+// compactBigInt returns results that fit into int64 as int64, like integer literals
+func compactBigInt(n *big.Int) interface{} {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n
+}
+
+// compareBigNumbers orders two numbers when at least one of them is *big.Int
+func compareBigNumbers(left, right interface{}) (int, bool) {
+	_, leftBig := left.(*big.Int)
+	_, rightBig := right.(*big.Int)
+	if !leftBig && !rightBig {
+		return 0, false
+	}
+	leftKind, ok := orderKind(left)
+	if !ok || leftKind != orderNumber {
+		return 0, false
+	}
+	rightKind, ok := orderKind(right)
+	if !ok || rightKind != orderNumber {
+		return 0, false
+	}
+	return compareNumbers(left, right), true
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"funterm/errors"
+)
+
+// Виды упорядочиваемых значений: упорядочиваются только значения одного вида.
+// Массивы, объекты, битстринги и хэндлы не упорядочиваются.
+const (
+	orderNil = iota
+	orderBool
+	orderNumber
+	orderString
+)
+
+// orderKind returns the ordering class of a value and false for incomparable values
+func orderKind(value interface{}) (int, bool) {
+	switch value.(type) {
+	case nil:
+		return orderNil, true
+	case bool:
+		return orderBool, true
+	case int, int32, int64, uint64, float32, float64, *big.Int:
+		return orderNumber, true
+	case string:
+		return orderString, true
+	}
+	return 0, false
+}
+
+// orderTypeName names a value in ordering errors
+func orderTypeName(value interface{}) string {
+	switch value.(type) {
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return valueTypeName(value)
+}
+
+// compareOrdered returns -1, 0 or 1 according to the order of engine values of one kind.
+// Numbers compare exactly across int64, *big.Int and float64; NaN sorts after every number.
+// Values of different kinds, like a number and a string, are an error.
+func compareOrdered(a, b interface{}) (int, error) {
+	kindA, okA := orderKind(a)
+	kindB, okB := orderKind(b)
+	if !okA || !okB {
+		return 0, errors.NewUserError("INCOMPARABLE_VALUES", fmt.Sprintf("cannot compare %s with %s", orderTypeName(a), orderTypeName(b)))
+	}
+	if kindA != kindB {
+		return 0, errors.NewUserError("MIXED_TYPE_COMPARISON", fmt.Sprintf("cannot order %s with %s: values of different types have no order", orderTypeName(a), orderTypeName(b)))
+	}
+
+	switch kindA {
+	case orderBool:
+		x, y := a.(bool), b.(bool)
+		switch {
+		case x == y:
+			return 0, nil
+		case !x:
+			return -1, nil
+		}
+		return 1, nil
+	case orderNumber:
+		return compareNumbers(a, b), nil
+	case orderString:
+		x, y := a.(string), b.(string)
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, nil
+}
+
+// compareNumbers compares two numeric values without losing precision
+func compareNumbers(a, b interface{}) int {
+	x, xNaN := exactNumber(a)
+	y, yNaN := exactNumber(b)
+	switch {
+	case xNaN && yNaN:
+		return 0
+	case xNaN:
+		return 1
+	case yNaN:
+		return -1
+	}
+	return x.Cmp(y)
+}
+
+// exactNumber converts a number to a big.Float holding its exact value; NaN is reported separately
+func exactNumber(value interface{}) (*big.Float, bool) {
+	switch v := value.(type) {
+	case int:
+		return new(big.Float).SetInt64(int64(v)), false
+	case int32:
+		return new(big.Float).SetInt64(int64(v)), false
+	case int64:
+		return new(big.Float).SetInt64(v), false
+	case uint64:
+		return new(big.Float).SetUint64(v), false
+	case *big.Int:
+		return new(big.Float).SetInt(v), false
+	case float32:
+		return exactNumber(float64(v))
+	case float64:
+		if math.IsNaN(v) {
+			return nil, true
+		}
+		return new(big.Float).SetFloat64(v), false
+	}
+	return new(big.Float), false
+}
+
+// sortValues returns a sorted copy of values, failing on incomparable elements
+func sortValues(values []interface{}) ([]interface{}, error) {
+	sorted := make([]interface{}, len(values))
+	copy(sorted, values)
+
+	var sortErr error
+	sort.SliceStable(sorted, func(i, j int) bool {
+		cmp, err := compareOrdered(sorted[i], sorted[j])
+		if err != nil && sortErr == nil {
+			sortErr = err
+		}
+		return cmp < 0
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+	return sorted, nil
+}
+
+// executeSortFunction returns a sorted copy of an array: sort(list)
+func (e *ExecutionEngine) executeSortFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("SORT_ARGUMENT_ERROR", "sort() requires exactly one argument")
+	}
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.NewUserError("SORT_ARGUMENT_ERROR", fmt.Sprintf("sort() expects an array, got %s", orderTypeName(args[0])))
+	}
+	return sortValues(list)
+}
+
+// executeExtremumFunction implements min() and max(): either one array or several values
+func (e *ExecutionEngine) executeExtremumFunction(name string, args []interface{}) (interface{}, error) {
+	values := args
+	if len(args) == 1 {
+		if list, ok := args[0].([]interface{}); ok {
+			values = list
+		}
+	}
+	if len(values) == 0 {
+		return nil, errors.NewUserError("EXTREMUM_ARGUMENT_ERROR", fmt.Sprintf("%s() requires a non-empty array or at least one value", name))
+	}
+
+	best := values[0]
+	for _, value := range values[1:] {
+		cmp, err := compareOrdered(value, best)
+		if err != nil {
+			return nil, err
+		}
+		if (name == "min" && cmp < 0) || (name == "max" && cmp > 0) {
+			best = value
+		}
+	}
+	// Одиночное значение тоже должно быть упорядочиваемым
+	if _, err := compareOrdered(best, best); err != nil {
+		return nil, err
+	}
+	return best, nil
+}
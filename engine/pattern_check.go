@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// CheckPatterns parses code without executing it and checks its bitstring patterns
+func (e *ExecutionEngine) CheckPatterns(code string) ([]LintWarning, error) {
+	statement, parseErrors := e.parser.Parse(code)
+	if len(parseErrors) > 0 {
+		return nil, errors.NewUserErrorWithASTPos("PARSING_ERROR", parseErrors[0].Message, parseErrors[0].Position)
+	}
+	// Nothing keeps the tree once it is checked
+	defer ast.ReleaseTree(statement)
+	return CheckPatternStatement(statement), nil
+}
+
+// CheckPatternStatement checks every bitstring pattern in stmt, in match arms
+// and in <<...>> = value, for impossible sizes, conflicting specifiers and
+// segments after a rest segment, which can never match
+func CheckPatternStatement(stmt ast.Statement) []LintWarning {
+	var warnings []LintWarning
+	ast.Inspect(stmt, func(node ast.ProtoNode) {
+		switch n := node.(type) {
+		case *ast.BitstringPattern:
+			warnings = append(warnings, checkPatternSegments(n.Elements, n.Position())...)
+		case *ast.BitstringPatternAssignment:
+			warnings = append(warnings, checkPatternSegments(n.Pattern.Segments, n.Pattern.Position())...)
+		case *ast.BitstringPatternMatchExpression:
+			warnings = append(warnings, checkPatternSegments(n.Pattern.Segments, n.Pattern.Position())...)
+		}
+	})
+	sort.SliceStable(warnings, func(i, j int) bool {
+		a, b := warnings[i].Pos, warnings[j].Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return warnings
+}
+
+// patternSpecifiers is what the specifiers of a pattern segment ask for
+type patternSpecifiers struct {
+	Type       string
+	Signedness string
+	Endianness string
+	Unit       uint // 0 when not given
+	Overflow   bool
+}
+
+// checkPatternSegments checks the segments of one pattern at pos
+func checkPatternSegments(segments []ast.BitstringSegment, pos ast.Position) []LintWarning {
+	var warnings []LintWarning
+	restIndex := -1
+	for i := range segments {
+		segment := &segments[i]
+		name := patternSegmentName(i, segment)
+		segmentPos := pos
+		if segment.Value != nil {
+			segmentPos = segment.Value.Position()
+		}
+		report := func(format string, args ...interface{}) {
+			warnings = append(warnings, LintWarning{Pos: segmentPos, Message: name + ": " + fmt.Sprintf(format, args...)})
+		}
+
+		if restIndex >= 0 {
+			report("unreachable, %s before it takes all remaining bits", patternSegmentName(restIndex, &segments[restIndex]))
+			break
+		}
+
+		specs, problems := checkPatternSpecifiers(segment.Specifiers)
+		for _, problem := range problems {
+			report("%s", problem)
+		}
+		segmentType := specs.Type
+		if segmentType == "" {
+			segmentType = "integer"
+			if _, ok := segment.Value.(*ast.StringLiteral); ok {
+				segmentType = "binary"
+			}
+		}
+
+		if segment.Size == nil && segment.SizeExpression == nil {
+			if segmentType == "binary" || segmentType == "bitstring" {
+				if _, ok := segment.Value.(*ast.StringLiteral); !ok && i < len(segments)-1 {
+					restIndex = i
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(segmentType, "utf") {
+			continue
+		}
+
+		sizeExpr := segment.Size
+		if sizeExpr == nil && segment.SizeExpression.ExprType == "literal" {
+			sizeExpr = segment.SizeExpression.Literal
+		}
+		size, ok := literalNumber(sizeExpr)
+		if !ok {
+			continue
+		}
+		if size.Sign() < 0 {
+			report("size %s is negative, the segment can never match", size.Text('g', -1))
+			continue
+		}
+		if !size.IsInt() {
+			report("size %s is not a whole number", size.Text('g', -1))
+			continue
+		}
+		bits, _ := size.Int(nil)
+		unit := int64(specs.Unit)
+		if unit == 0 {
+			unit = 1
+			if segmentType == "binary" {
+				unit = 8
+			}
+		}
+		bits.Mul(bits, big.NewInt(unit))
+		switch segmentType {
+		case "float":
+			if n := bits.Int64(); n != 16 && n != 32 && n != 64 {
+				report("float segments are 16, 32 or 64 bits, not %s", bits)
+			}
+		case "binary":
+			if new(big.Int).Mod(bits, big.NewInt(8)).Sign() != 0 {
+				report("binary segment of %s bits is not a whole number of bytes, use bitstring", bits)
+			}
+		case "integer":
+			if value, ok := literalNumber(segment.Value); ok && value.IsInt() && bits.IsInt64() && bits.Int64() > 0 && bits.Int64() <= math.MaxUint16 {
+				n, _ := value.Int(nil)
+				minValue, maxValue := segmentRange(uint(bits.Int64()), specs.Signedness == "signed")
+				if n.Cmp(minValue) < 0 || n.Cmp(maxValue) > 0 {
+					report("%s can never match a %s-bit %s segment (%s..%s)", n, bits, signedness(specs.Signedness == "signed"), minValue, maxValue)
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// patternSegmentName names a segment in messages, "segment 2 (data)"
+func patternSegmentName(i int, segment *ast.BitstringSegment) string {
+	if ident, ok := segment.Value.(*ast.Identifier); ok {
+		return fmt.Sprintf("segment %d (%s)", i+1, ident.Name)
+	}
+	return fmt.Sprintf("segment %d", i+1)
+}
+
+// checkPatternSpecifiers reads the specifiers of a pattern segment and
+// reports the ones that are unknown or contradict each other
+func checkPatternSpecifiers(specifiers []string) (patternSpecifiers, []string) {
+	var specs patternSpecifiers
+	var problems []string
+	set := func(field *string, kind, value string) {
+		if *field != "" && *field != value {
+			problems = append(problems, fmt.Sprintf("conflicting %s specifiers %s and %s", kind, *field, value))
+		}
+		*field = value
+	}
+
+	for _, spec := range specifiers {
+		if rest, _, found := cutOverflow(spec); found {
+			specs.Overflow = true
+			spec = rest
+		}
+		if left, value, found := strings.Cut(spec, ":"); found {
+			parts := strings.Split(left, "-")
+			if parts[len(parts)-1] != "unit" {
+				problems = append(problems, fmt.Sprintf("unknown specifier %s", spec))
+				continue
+			}
+			unit, err := strconv.ParseUint(value, 10, 32)
+			if err != nil || unit < 1 || unit > 256 {
+				problems = append(problems, fmt.Sprintf("unit must be between 1 and 256, got %s", value))
+			} else {
+				if specs.Unit != 0 && specs.Unit != uint(unit) {
+					problems = append(problems, fmt.Sprintf("conflicting unit specifiers unit:%d and unit:%d", specs.Unit, unit))
+				}
+				specs.Unit = uint(unit)
+			}
+			spec = strings.Join(parts[:len(parts)-1], "-")
+		}
+		spec = strings.NewReplacer("big-endian", "big", "little-endian", "little", "native-endian", "native").Replace(spec)
+		if spec == "" {
+			continue
+		}
+		for _, part := range strings.Split(spec, "-") {
+			switch part {
+			case "big", "little", "native":
+				set(&specs.Endianness, "endianness", part)
+			case "signed", "unsigned":
+				set(&specs.Signedness, "signedness", part)
+			case "integer", "float", "binary", "bitstring", "utf8", "utf16", "utf32":
+				set(&specs.Type, "type", part)
+			case "bytes":
+				set(&specs.Type, "type", "binary")
+			case "bits":
+				set(&specs.Type, "type", "bitstring")
+			case "utf":
+				set(&specs.Type, "type", "utf8")
+			default:
+				problems = append(problems, fmt.Sprintf("unknown specifier %s", part))
+			}
+		}
+	}
+
+	if specs.Signedness != "" && specs.Type != "" && specs.Type != "integer" {
+		problems = append(problems, fmt.Sprintf("%s applies only to integer segments, not %s", specs.Signedness, specs.Type))
+	}
+	switch specs.Type {
+	case "binary", "bitstring", "utf8":
+		if specs.Endianness != "" {
+			problems = append(problems, fmt.Sprintf("%s endianness has no effect on %s segments", specs.Endianness, specs.Type))
+		}
+	}
+	if specs.Overflow {
+		problems = append(problems, "overflow: only applies when building a bitstring")
+	}
+	return specs, problems
+}
+
+// literalNumber returns the value of a number literal, negated or not
+func literalNumber(expr ast.Expression) (*big.Float, bool) {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if e.IsInt && e.IntValue != nil {
+			return new(big.Float).SetInt(e.IntValue), true
+		}
+		if math.IsNaN(e.FloatValue) || math.IsInf(e.FloatValue, 0) {
+			return nil, false
+		}
+		return big.NewFloat(e.FloatValue), true
+	case *ast.UnaryExpression:
+		if e.Operator == "-" {
+			if value, ok := literalNumber(e.Right); ok {
+				return value.Neg(value), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// executeValidatePatternFunction implements validate_pattern(pattern, sample):
+// the static checks of --check-patterns on a pattern given as text, and
+// whether it matches the sample. The result is an object with valid,
+// problems, matches, bindings and reason, why the sample didn't match
+func (e *ExecutionEngine) executeValidatePatternFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("VALIDATE_PATTERN_ERROR", "validate_pattern() requires a pattern string and a sample bitstring")
+	}
+	text, ok := args[0].(string)
+	if !ok {
+		return nil, errors.NewUserError("VALIDATE_PATTERN_ERROR", fmt.Sprintf("validate_pattern() expects the pattern as a string, got %s", orderTypeName(args[0])))
+	}
+	sample, err := binaryArgument("validate_pattern", args[1])
+	if err != nil {
+		return nil, err
+	}
+	statement, pattern, err := e.parsePatternText(text)
+	if err != nil {
+		return nil, err
+	}
+	defer ast.ReleaseTree(statement)
+
+	problems := []interface{}{}
+	for _, warning := range checkPatternSegments(pattern.Segments, pattern.Position()) {
+		problems = append(problems, warning.Message)
+	}
+	result := map[string]interface{}{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+		"matches":  false,
+		"bindings": nil,
+		"reason":   "",
+	}
+	if len(problems) > 0 {
+		result["reason"] = "the pattern is invalid"
+		return result, nil
+	}
+
+	bindings, err := NewFunbitAdapterWithEngine(e).MatchBitstringWithFunbit(pattern, sample, false)
+	switch {
+	case err != nil:
+		result["reason"] = err.Error()
+	case bindings == nil:
+		result["reason"] = fmt.Sprintf("the sample of %d bits doesn't match", sample.Len())
+	default:
+		result["matches"] = true
+		result["bindings"] = bindings
+	}
+	return result, nil
+}
+
+// parsePatternText parses the text of a bitstring pattern such as
+// "<<size:8, data:size/binary>>"; the statement holding it is released by
+// the caller
+func (e *ExecutionEngine) parsePatternText(text string) (ast.Statement, *ast.BitstringExpression, error) {
+	statement, parseErrors := e.parser.Parse(text)
+	if len(parseErrors) > 0 {
+		return nil, nil, errors.NewUserError("VALIDATE_PATTERN_ERROR", fmt.Sprintf("validate_pattern() can't parse the pattern: %s", parseErrors[0].Message))
+	}
+	if pattern, ok := statement.(*ast.BitstringExpression); ok {
+		return statement, pattern, nil
+	}
+	ast.ReleaseTree(statement)
+	return nil, nil, errors.NewUserError("VALIDATE_PATTERN_ERROR", fmt.Sprintf("validate_pattern() expects a bitstring pattern such as \"<<size:8, data:size/binary>>\", got %q", text))
+}
@@ -14,6 +14,10 @@ import (
 
 // executeMatchStatement executes a match statement with pattern matching
 func (e *ExecutionEngine) executeMatchStatement(matchStmt *ast.MatchStatement) (interface{}, error) {
+	if e.matchWarnings {
+		e.warnAboutMatch(matchStmt)
+	}
+
 	// Evaluate the expression to be matched
 	var subject interface{}
 	var err error
@@ -42,7 +46,14 @@ func (e *ExecutionEngine) executeMatchStatement(matchStmt *ast.MatchStatement) (
 	case *ast.LanguageCall:
 		// For language calls, check if they're actually variable references
 		// This can happen when the parser misidentifies a variable as a function call
-		if len(expr.Arguments) == 0 {
+		if object, ok := e.objectVariable(expr); ok {
+			// packet.data on a variable holding an object
+			field, exists := object[expr.Function]
+			if !exists {
+				return nil, errors.NewUserErrorWithASTPos("FIELD_ACCESS_ERROR", fmt.Sprintf("field '%s' not found in object", expr.Function), expr.Position())
+			}
+			subject = field
+		} else if len(expr.Arguments) == 0 {
 			// This might be a variable reference, try to read it as a variable
 			languages := e.ListAvailableLanguages()
 			for _, lang := range languages {
@@ -111,15 +122,25 @@ func (e *ExecutionEngine) executeMatchStatement(matchStmt *ast.MatchStatement) (
 		}
 
 		if matches {
-			// Pattern matched, execute the body with any variable bindings
-			if len(bindings) > 0 {
-				// Filter out the __error__ key from bindings before executing
-				cleanBindings := make(map[string]interface{})
-				for k, v := range bindings {
-					if k != "__error__" {
-						cleanBindings[k] = v
-					}
+			// Filter out the __error__ key from bindings before executing
+			cleanBindings := make(map[string]interface{})
+			for k, v := range bindings {
+				if k != "__error__" {
+					cleanBindings[k] = v
+				}
+			}
+			// A guarded arm matches only when its condition holds
+			if arm.Guard != nil {
+				allowed, err := e.guardAllows(arm.Guard, cleanBindings)
+				if err != nil {
+					return nil, err
 				}
+				if !allowed {
+					continue
+				}
+			}
+			// Pattern matched, execute the body with any variable bindings
+			if len(cleanBindings) > 0 {
 				// Create a temporary scope for bound variables
 				return e.executeStatementWithBindings(arm.Statement, cleanBindings)
 			} else {
@@ -129,8 +150,8 @@ func (e *ExecutionEngine) executeMatchStatement(matchStmt *ast.MatchStatement) (
 		}
 	}
 
-	// No pattern matched
-	return nil, errors.NewUserErrorWithASTPos("NO_PATTERN_MATCH", "no pattern in match statement matched the value", matchStmt.Position())
+	// No pattern matched; for bitstrings, say where each bitstring arm failed
+	return nil, errors.NewUserErrorWithASTPos("NO_PATTERN_MATCH", "no pattern in match statement matched the value"+e.explainNoMatch(matchStmt, subject), matchStmt.Position())
 }
 
 // matchesPattern checks if a pattern matches a value and returns any variable bindings
@@ -140,6 +161,10 @@ func (e *ExecutionEngine) matchesPattern(pattern ast.Pattern, value interface{})
 		// Compare literal values
 		return e.compareValues(p.Value, value), nil
 
+	case *ast.RangePattern:
+		// Inclusive range of numbers or strings
+		return e.matchesRangePattern(p, value), nil
+
 	case *ast.VariablePattern:
 		// Variable pattern always matches and binds the value
 		return true, map[string]interface{}{p.Name: value}
@@ -160,12 +185,34 @@ func (e *ExecutionEngine) matchesPattern(pattern ast.Pattern, value interface{})
 		// Bitstring pattern matching
 		return e.matchesBitstringPattern(p, value)
 
+	case *ast.ResultPattern:
+		// ok(...) / error(...) match the matching variant of a try result
+		result, ok := value.(*shared.ResultObject)
+		if !ok || result.Ok != p.Ok {
+			return false, nil
+		}
+		return e.matchesPattern(p.Inner, result.Value)
+
 	default:
 		// Unsupported pattern type
 		return false, nil
 	}
 }
 
+// matchesRangePattern checks low <= value <= high; values of another kind never match
+func (e *ExecutionEngine) matchesRangePattern(pattern *ast.RangePattern, value interface{}) bool {
+	lowKind, _ := orderKind(pattern.Low)
+	if valueKind, ok := orderKind(value); !ok || valueKind != lowKind {
+		return false
+	}
+	low, err := compareOrdered(pattern.Low, value)
+	if err != nil || low > 0 {
+		return false
+	}
+	high, err := compareOrdered(value, pattern.High)
+	return err == nil && (high < 0 || high == 0 && !pattern.Exclusive)
+}
+
 // compareValues compares two values for equality
 func (e *ExecutionEngine) compareValues(a, b interface{}) bool {
 	if e.verbose {
@@ -311,6 +358,20 @@ func (e *ExecutionEngine) compareValues(a, b interface{}) bool {
 	return a == b
 }
 
+// objectVariable returns the object held by the variable a call without
+// arguments is made on, for obj.field parsed as a language call
+func (e *ExecutionEngine) objectVariable(call *ast.LanguageCall) (map[string]interface{}, bool) {
+	if len(call.Arguments) != 0 {
+		return nil, false
+	}
+	value, found := e.getVariable(call.Language)
+	if !found {
+		return nil, false
+	}
+	object, ok := value.(map[string]interface{})
+	return object, ok
+}
+
 // matchesArrayPattern checks if an array pattern matches a value and returns any variable bindings
 func (e *ExecutionEngine) matchesArrayPattern(pattern *ast.ArrayPattern, value interface{}) (bool, map[string]interface{}) {
 	// Check if the value is an array
@@ -411,25 +472,18 @@ func (e *ExecutionEngine) matchesBitstringPattern(pattern *ast.BitstringPattern,
 	case []byte:
 		// Convert byte slice to BitstringObject
 		bitString := funbit.NewBitStringFromBytes(v)
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 		ok = true
 	case string:
-		// Convert string to BitstringObject using UTF-8 encoding
-		builder := funbit.NewBuilder()
-		funbit.AddUTF8(builder, v)
-		bitString, err := funbit.Build(builder)
-		if err != nil {
-			if e.verbose {
-				fmt.Printf("DEBUG: matchesBitstringPattern - failed to build UTF-8 bitstring: %v\n", err)
-			}
-			return false, nil
-		}
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		// Strings hold their bytes as they are: a /binary field bound by
+		// another pattern may not be valid UTF-8
+		bitString := funbit.NewBitStringFromBytes([]byte(v))
+		bitstringData = shared.NewBitstringObject(bitString)
 		ok = true
 	case shared.BitstringByte:
 		// Convert BitstringByte to BitstringObject (single byte)
 		bitString := funbit.NewBitStringFromBytes([]byte{v.Value})
-		bitstringData = &shared.BitstringObject{BitString: bitString}
+		bitstringData = shared.NewBitstringObject(bitString)
 		ok = true
 	default:
 		if e.verbose {
@@ -541,6 +595,21 @@ func (e *ExecutionEngine) executeStatementWithBindings(stmt ast.Statement, bindi
 	return result, err
 }
 
+// guardAllows evaluates the guard of a match arm with the pattern's bindings
+// in scope
+func (e *ExecutionEngine) guardAllows(guard ast.Expression, bindings map[string]interface{}) (bool, error) {
+	e.pushScope()
+	defer e.popScope()
+	for name, value := range bindings {
+		e.setVariable(name, value)
+	}
+	value, err := e.convertExpressionToValue(guard)
+	if err != nil {
+		return false, err
+	}
+	return e.isTruthy(value), nil
+}
+
 func (e *ExecutionEngine) executeStatementWithLocalScope(stmt ast.Statement) (interface{}, error) {
 	// Create a new nested scope for local variables
 	// This ensures local variables in match arms don't interfere with outer scope
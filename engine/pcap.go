@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// Magic numbers of the capture formats read by pcap.open()
+const (
+	pcapMagicMicros = 0xa1b2c3d4
+	pcapMagicNanos  = 0xa1b23c4d
+	pcapngBlockSHB  = 0x0a0d0d0a
+	pcapngByteOrder = 0x1a2b3c4d
+)
+
+// pcapng block types
+const (
+	pcapngBlockIDB = 0x00000001
+	pcapngBlockSPB = 0x00000003
+	pcapngBlockEPB = 0x00000006
+)
+
+// maxPcapPacket guards against corrupt length fields
+const maxPcapPacket = 256 << 20
+
+// linkTypeNames are the common LINKTYPE_* values, see tcpdump.org/linktypes.html
+var linkTypeNames = map[int64]string{
+	0:   "null",
+	1:   "ethernet",
+	101: "raw",
+	105: "ieee802_11",
+	113: "linux_sll",
+	127: "ieee802_11_radiotap",
+	228: "ipv4",
+	229: "ipv6",
+	276: "linux_sll2",
+}
+
+// pcapInterface is a capture interface: the single one of a pcap file or an IDB of pcapng
+type pcapInterface struct {
+	linkType int64
+	tsUnit   int64 // nanoseconds per timestamp tick
+	tsOffset int64 // seconds added to every timestamp
+}
+
+// PcapReader is the value returned by pcap.open(). for-in loops read one
+// packet per iteration; each packet is an object with the captured bytes in
+// "data" and its timestamp and link-type metadata.
+type PcapReader struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	reader     *bufio.Reader
+	order      binary.ByteOrder
+	ng         bool
+	interfaces []pcapInterface
+	count      int64
+}
+
+// OpenPcap opens a pcap or pcapng capture and reads its header
+func OpenPcap(path string) (*PcapReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &PcapReader{path: path, file: file, reader: bufio.NewReader(file)}
+	if err := r.readHeader(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// readHeader detects the format and byte order from the magic number
+func (r *PcapReader) readHeader() error {
+	magic, err := r.reader.Peek(4)
+	if err != nil {
+		return fmt.Errorf("not a capture file: %v", err)
+	}
+
+	if binary.BigEndian.Uint32(magic) == pcapngBlockSHB {
+		r.ng = true
+		// Byte order is decided by the section header itself
+		return nil
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		return fmt.Errorf("truncated pcap header")
+	}
+	var tsUnit int64
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header) {
+		case pcapMagicMicros:
+			r.order, tsUnit = order, 1000
+		case pcapMagicNanos:
+			r.order, tsUnit = order, 1
+		}
+		if r.order != nil {
+			break
+		}
+	}
+	if r.order == nil {
+		return fmt.Errorf("not a pcap or pcapng file")
+	}
+	// Старшие биты поля linktype содержат FCS-флаги, сам тип - младшие 16 бит
+	linkType := int64(r.order.Uint32(header[20:]) & 0xffff)
+	r.interfaces = []pcapInterface{{linkType: linkType, tsUnit: tsUnit}}
+	return nil
+}
+
+// Next implements shared.Iterator
+func (r *PcapReader) Next() (interface{}, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil, false, nil
+	}
+
+	var packet map[string]interface{}
+	var err error
+	if r.ng {
+		packet, err = r.nextPcapng()
+	} else {
+		packet, err = r.nextPcap()
+	}
+	if err != nil || packet == nil {
+		r.closeLocked()
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: packet %d: %v", r.path, r.count+1, err)
+		}
+		return nil, false, nil
+	}
+	r.count++
+	packet["index"] = r.count
+	return packet, true, nil
+}
+
+// nextPcap reads a classic pcap record; nil means end of file
+func (r *PcapReader) nextPcap() (map[string]interface{}, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("truncated record header")
+	}
+	seconds := int64(r.order.Uint32(header[0:]))
+	fraction := int64(r.order.Uint32(header[4:]))
+	capLen := r.order.Uint32(header[8:])
+	origLen := int64(r.order.Uint32(header[12:]))
+
+	data, err := r.readData(capLen)
+	if err != nil {
+		return nil, err
+	}
+	iface := r.interfaces[0]
+	return pcapPacket(data, seconds*1_000_000_000+fraction*iface.tsUnit, origLen, iface.linkType), nil
+}
+
+// nextPcapng reads blocks until the next packet block; nil means end of file
+func (r *PcapReader) nextPcapng() (map[string]interface{}, error) {
+	for {
+		head := make([]byte, 8)
+		if _, err := io.ReadFull(r.reader, head); err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("truncated block header")
+		}
+
+		if binary.BigEndian.Uint32(head) == pcapngBlockSHB {
+			// A new section may switch byte order and resets the interfaces
+			bom := make([]byte, 4)
+			if _, err := io.ReadFull(r.reader, bom); err != nil {
+				return nil, fmt.Errorf("truncated section header")
+			}
+			if binary.LittleEndian.Uint32(bom) == pcapngByteOrder {
+				r.order = binary.LittleEndian
+			} else if binary.BigEndian.Uint32(bom) == pcapngByteOrder {
+				r.order = binary.BigEndian
+			} else {
+				return nil, fmt.Errorf("invalid pcapng byte-order magic")
+			}
+			r.interfaces = nil
+			if _, err := r.readBlockBody(r.order.Uint32(head[4:]), 12); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if r.order == nil {
+			return nil, fmt.Errorf("pcapng file does not start with a section header")
+		}
+
+		blockType := r.order.Uint32(head)
+		body, err := r.readBlockBody(r.order.Uint32(head[4:]), 8)
+		if err != nil {
+			return nil, err
+		}
+
+		switch blockType {
+		case pcapngBlockIDB:
+			if len(body) < 8 {
+				return nil, fmt.Errorf("truncated interface description")
+			}
+			iface := pcapInterface{linkType: int64(r.order.Uint16(body)), tsUnit: 1000}
+			r.readInterfaceOptions(body[8:], &iface)
+			r.interfaces = append(r.interfaces, iface)
+		case pcapngBlockEPB:
+			if len(body) < 20 {
+				return nil, fmt.Errorf("truncated packet block")
+			}
+			id := int(r.order.Uint32(body))
+			if id >= len(r.interfaces) {
+				return nil, fmt.Errorf("packet refers to unknown interface %d", id)
+			}
+			iface := r.interfaces[id]
+			ticks := int64(r.order.Uint32(body[4:]))<<32 | int64(r.order.Uint32(body[8:]))
+			capLen := r.order.Uint32(body[12:])
+			origLen := int64(r.order.Uint32(body[16:]))
+			if int(capLen) > len(body)-20 {
+				return nil, fmt.Errorf("packet length exceeds its block")
+			}
+			data := body[20 : 20+capLen]
+			packet := pcapPacket(data, iface.tsOffset*1_000_000_000+ticks*iface.tsUnit, origLen, iface.linkType)
+			packet["interface"] = int64(id)
+			return packet, nil
+		case pcapngBlockSPB:
+			if len(r.interfaces) == 0 || len(body) < 4 {
+				return nil, fmt.Errorf("simple packet block without an interface")
+			}
+			origLen := int64(r.order.Uint32(body))
+			capLen := origLen
+			if capLen > int64(len(body)-4) {
+				capLen = int64(len(body) - 4)
+			}
+			packet := pcapPacket(body[4:4+capLen], 0, origLen, r.interfaces[0].linkType)
+			delete(packet, "ts")
+			delete(packet, "ts_ns")
+			return packet, nil
+		}
+		// Other blocks (statistics, name resolution, ...) are skipped
+	}
+}
+
+// readBlockBody reads the rest of a pcapng block of totalLen bytes after the
+// consumed header bytes, without the trailing length copy
+func (r *PcapReader) readBlockBody(totalLen uint32, consumed int) ([]byte, error) {
+	if totalLen < uint32(consumed)+4 || totalLen%4 != 0 || totalLen > maxPcapPacket {
+		return nil, fmt.Errorf("invalid block length %d", totalLen)
+	}
+	rest := make([]byte, int(totalLen)-consumed)
+	if _, err := io.ReadFull(r.reader, rest); err != nil {
+		return nil, fmt.Errorf("truncated block")
+	}
+	return rest[:len(rest)-4], nil
+}
+
+// readInterfaceOptions applies the if_tsresol and if_tsoffset options of an IDB
+func (r *PcapReader) readInterfaceOptions(options []byte, iface *pcapInterface) {
+	for len(options) >= 4 {
+		code := r.order.Uint16(options)
+		length := int(r.order.Uint16(options[2:]))
+		if code == 0 || 4+length > len(options) {
+			return
+		}
+		value := options[4 : 4+length]
+		switch {
+		case code == 9 && length == 1: // if_tsresol
+			iface.tsUnit = tsResolutionUnit(value[0])
+		case code == 14 && length == 8: // if_tsoffset
+			iface.tsOffset = int64(r.order.Uint64(value))
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+}
+
+// tsResolutionUnit converts if_tsresol to nanoseconds per tick; sub-nanosecond
+// resolutions are not representable and fall back to one nanosecond
+func tsResolutionUnit(resolution byte) int64 {
+	if resolution&0x80 != 0 {
+		exponent := int(resolution & 0x7f)
+		if exponent > 30 {
+			return 1
+		}
+		unit := int64(1_000_000_000) >> exponent
+		if unit == 0 {
+			return 1
+		}
+		return unit
+	}
+	unit := int64(1_000_000_000)
+	for i := 0; i < int(resolution) && unit > 1; i++ {
+		unit /= 10
+	}
+	return unit
+}
+
+// readData reads the captured bytes of a pcap record
+func (r *PcapReader) readData(length uint32) ([]byte, error) {
+	if length > maxPcapPacket {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return nil, fmt.Errorf("truncated packet data")
+	}
+	return data, nil
+}
+
+// pcapPacket builds the object a packet is represented by
+func pcapPacket(data []byte, tsNanos, origLen, linkType int64) map[string]interface{} {
+	link, ok := linkTypeNames[linkType]
+	if !ok {
+		link = fmt.Sprintf("linktype_%d", linkType)
+	}
+	return map[string]interface{}{
+		"data":      shared.NewBitstringObjectFromBytes(data),
+		"ts":        float64(tsNanos) / 1e9,
+		"ts_ns":     tsNanos,
+		"length":    origLen,
+		"truncated": int64(len(data)) < origLen,
+		"link_type": linkType,
+		"link":      link,
+	}
+}
+
+// CallMethod implements shared.MethodObject
+func (r *PcapReader) CallMethod(name string, args []interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, errors.NewUserError("PCAP_ARGUMENT_ERROR", fmt.Sprintf("%s() takes no arguments", name))
+	}
+	switch name {
+	case "next":
+		packet, ok, err := r.Next()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return packet, nil
+	case "close":
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closeLocked()
+		return nil, nil
+	default:
+		return nil, errors.NewUserError("UNKNOWN_METHOD", fmt.Sprintf("pcap reader has no method '%s' (available: next, close)", name))
+	}
+}
+
+func (r *PcapReader) closeLocked() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// String describes the reader for display
+func (r *PcapReader) String() string {
+	return fmt.Sprintf("<pcap %s, %d packets read>", r.path, r.count)
+}
+
+// callPcapFunction implements pcap.open(path)
+func callPcapFunction(function string, args []interface{}) (interface{}, error) {
+	if function != "open" {
+		return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("pcap has no function '%s'; use open", function))
+	}
+	if len(args) != 1 {
+		return nil, errors.NewUserError("PCAP_ARGUMENT_ERROR", "pcap.open() requires exactly one path")
+	}
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, errors.NewUserError("PCAP_ARGUMENT_ERROR", fmt.Sprintf("pcap.open() expects a path string, got %s", orderTypeName(args[0])))
+	}
+	reader, err := OpenPcap(path)
+	if err != nil {
+		return nil, errors.NewUserError("PCAP_OPEN_ERROR", err.Error())
+	}
+	return reader, nil
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"funterm/errors"
+	"funterm/shared"
+	"go-parser/pkg/ast"
+	sharedparser "go-parser/pkg/shared"
+)
+
+// stageResult is the outcome of one run of a pipeline stage
+type stageResult struct {
+	stage  *ast.PipelineStage
+	output string
+	err    error
+}
+
+// executePipelineStatement runs the stages of a pipeline as soon as the
+// stages they come after have finished, so independent stages run in
+// parallel. After a stage fails no new stages start; the running ones are
+// waited for and the first failure is returned. The printed output of the
+// stages is returned in the order they are declared
+func (e *ExecutionEngine) executePipelineStatement(pipeline *ast.PipelineStatement) (interface{}, error) {
+	waiting := make(map[string]int, len(pipeline.Stages)) // stage -> stages it still waits for
+	dependents := make(map[string][]*ast.PipelineStage)
+	for _, stage := range pipeline.Stages {
+		waiting[stage.Name] = len(stage.After)
+		for _, dependency := range stage.After {
+			dependents[dependency] = append(dependents[dependency], stage)
+		}
+	}
+
+	results := make(chan stageResult)
+	running := 0
+	start := func(stage *ast.PipelineStage) {
+		running++
+		go func() {
+			output, err := e.runStage(stage)
+			results <- stageResult{stage: stage, output: output, err: err}
+		}()
+	}
+	for _, stage := range pipeline.Stages {
+		if waiting[stage.Name] == 0 {
+			start(stage)
+		}
+	}
+
+	outputs := make(map[string]string, len(pipeline.Stages))
+	var failure error
+	for running > 0 {
+		result := <-results
+		running--
+		if result.err != nil {
+			if failure == nil {
+				failure = result.err
+			}
+			continue
+		}
+		outputs[result.stage.Name] = result.output
+		if failure != nil {
+			continue
+		}
+		for _, dependent := range dependents[result.stage.Name] {
+			waiting[dependent.Name]--
+			if waiting[dependent.Name] == 0 {
+				start(dependent)
+			}
+		}
+	}
+	if failure != nil {
+		return nil, failure
+	}
+
+	var collected []string
+	for _, stage := range pipeline.Stages {
+		if output := outputs[stage.Name]; output != "" {
+			collected = append(collected, output)
+		}
+	}
+	if len(collected) == 0 {
+		return nil, nil
+	}
+	return strings.Join(collected, "\n"), nil
+}
+
+// runStage runs a stage, once more for each of its retries while it fails
+func (e *ExecutionEngine) runStage(stage *ast.PipelineStage) (string, error) {
+	var err error
+	for attempt := 0; attempt <= stage.Retries; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: stage \"%s\" failed, retrying (%d of %d): %v\n", stage.Name, attempt, stage.Retries, err)
+		}
+		var output string
+		if output, err = e.runStageAttempt(stage); err == nil {
+			return output, nil
+		}
+	}
+	return "", errors.NewUserErrorWithASTPos("PIPELINE_ERROR", fmt.Sprintf("stage \"%s\" failed: %v", stage.Name, err), stage.Pos)
+}
+
+// runStageAttempt runs the body of a stage once on an engine of its own. A
+// stage with a timeout fails when its time is up; a runtime call that is
+// already running is not interrupted, but the rest of the attempt is skipped
+func (e *ExecutionEngine) runStageAttempt(stage *ast.PipelineStage) (string, error) {
+	stageEngine := e.newStageEngine(stage)
+	done := make(chan stageResult, 1)
+	go func() {
+		result, err := stageEngine.executeBlockStatement(stage.Body)
+		done <- stageResult{stage: stage, output: stageOutput(result), err: err}
+	}()
+
+	var result stageResult
+	if stage.Timeout == 0 {
+		result = <-done
+	} else {
+		timeout := stageTimeout(stage)
+		select {
+		case result = <-done:
+		case <-time.After(timeout):
+			return "", errors.NewUserErrorWithASTPos("EXECUTION_TIMEOUT", fmt.Sprintf("stage \"%s\" did not finish within %s", stage.Name, timeout), stage.Pos)
+		}
+	}
+	if result.err != nil {
+		return "", result.err
+	}
+
+	// Variables the stage set in the runtimes become visible to the script
+	for language, variables := range stageEngine.cloneSharedVariables() {
+		for name, value := range variables {
+			e.SetSharedVariable(language, name, value)
+		}
+	}
+	return result.output, nil
+}
+
+// newStageEngine returns the engine for one attempt of a stage. It shares
+// the globals with e, so what a stage assigns at its top level is seen by
+// the stages after it and by the rest of the script. The stage keeps the
+// deadline of the command, shortened to its own timeout
+func (e *ExecutionEngine) newStageEngine(stage *ast.PipelineStage) *ExecutionEngine {
+	stageEngine := e.newBackgroundEngine(sharedparser.NewScope(nil), e.cloneSharedVariables())
+	stageEngine.globals = e.globals
+	stageEngine.deadline, stageEngine.deadlineLimit = e.deadline, e.deadlineLimit
+	if stage.Timeout > 0 {
+		timeout := stageTimeout(stage)
+		if deadline := time.Now().Add(timeout); stageEngine.deadline.IsZero() || deadline.Before(stageEngine.deadline) {
+			stageEngine.deadline = deadline
+			stageEngine.deadlineLimit = fmt.Sprintf("the timeout of stage \"%s\" (%s)", stage.Name, timeout)
+		}
+	}
+	return stageEngine
+}
+
+func stageTimeout(stage *ast.PipelineStage) time.Duration {
+	return time.Duration(stage.Timeout * float64(time.Second))
+}
+
+// stageOutput returns what the body of a stage printed
+func stageOutput(result interface{}) string {
+	switch output := result.(type) {
+	case string:
+		return output
+	case *shared.PreFormattedResult:
+		return output.Value
+	}
+	return ""
+}
+
+// PipelineGraph renders the pipelines of a parsed script as a graph in the
+// DOT language of Graphviz ("dot") or as a Mermaid flowchart ("mermaid").
+// Edges point from a stage to the stages that run after it
+func PipelineGraph(statement ast.Statement, format string) (string, error) {
+	var pipelines []*ast.PipelineStatement
+	statements := []ast.Statement{statement}
+	if block, ok := statement.(*ast.BlockStatement); ok {
+		statements = block.Statements
+	}
+	for _, stmt := range statements {
+		if pipeline, ok := stmt.(*ast.PipelineStatement); ok {
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+	if len(pipelines) == 0 {
+		return "", errors.NewUserError("PIPELINE_ERROR", "the script has no pipeline")
+	}
+
+	var graph strings.Builder
+	switch format {
+	case "dot":
+		graph.WriteString("digraph pipeline {\n    rankdir=LR;\n")
+		for i, pipeline := range pipelines {
+			indent, prefix := "    ", ""
+			if len(pipelines) > 1 {
+				fmt.Fprintf(&graph, "    subgraph cluster_%d {\n        label=\"pipeline %d (line %d)\";\n", i+1, i+1, pipeline.Pos.Line)
+				indent, prefix = "        ", fmt.Sprintf("%d:", i+1)
+			}
+			for _, stage := range pipeline.Stages {
+				fmt.Fprintf(&graph, "%s%s [label=%s];\n", indent, dotQuote(prefix+stage.Name), dotQuote(stageLabel(stage, "\n")))
+			}
+			for _, stage := range pipeline.Stages {
+				for _, dependency := range stage.After {
+					fmt.Fprintf(&graph, "%s%s -> %s;\n", indent, dotQuote(prefix+dependency), dotQuote(prefix+stage.Name))
+				}
+			}
+			if len(pipelines) > 1 {
+				graph.WriteString("    }\n")
+			}
+		}
+		graph.WriteString("}\n")
+	case "mermaid":
+		graph.WriteString("flowchart LR\n")
+		for i, pipeline := range pipelines {
+			indent := "    "
+			if len(pipelines) > 1 {
+				fmt.Fprintf(&graph, "    subgraph pipeline%d [\"pipeline %d (line %d)\"]\n", i+1, i+1, pipeline.Pos.Line)
+				indent = "        "
+			}
+			ids := make(map[string]string, len(pipeline.Stages))
+			for j, stage := range pipeline.Stages {
+				ids[stage.Name] = fmt.Sprintf("p%ds%d", i+1, j+1)
+				fmt.Fprintf(&graph, "%s%s[\"%s\"]\n", indent, ids[stage.Name], mermaidEscape(stageLabel(stage, "<br/>")))
+			}
+			for _, stage := range pipeline.Stages {
+				for _, dependency := range stage.After {
+					fmt.Fprintf(&graph, "%s%s --> %s\n", indent, ids[dependency], ids[stage.Name])
+				}
+			}
+			if len(pipelines) > 1 {
+				graph.WriteString("    end\n")
+			}
+		}
+	default:
+		return "", errors.NewUserError("PIPELINE_ERROR", fmt.Sprintf("unknown graph format '%s', expected dot or mermaid", format))
+	}
+	return graph.String(), nil
+}
+
+// stageLabel is the name of a stage with its retries and timeout
+func stageLabel(stage *ast.PipelineStage, separator string) string {
+	var settings []string
+	if stage.Retries > 0 {
+		settings = append(settings, fmt.Sprintf("retries %d", stage.Retries))
+	}
+	if stage.Timeout > 0 {
+		settings = append(settings, fmt.Sprintf("timeout %s", stageTimeout(stage)))
+	}
+	if len(settings) == 0 {
+		return stage.Name
+	}
+	return stage.Name + separator + strings.Join(settings, ", ")
+}
+
+// dotQuote quotes a DOT identifier
+func dotQuote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(text) + `"`
+}
+
+// mermaidEscape escapes the text of a quoted Mermaid label
+func mermaidEscape(text string) string {
+	return strings.ReplaceAll(text, `"`, "#quot;")
+}
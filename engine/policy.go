@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// SessionPolicy limits what a session may run, so a server can give each
+// client token only the capabilities it needs. Limits are checked before a
+// call is dispatched
+type SessionPolicy struct {
+	// Languages are the runtimes the session may use, with aliases such as
+	// py allowed. Nil allows every runtime
+	Languages []string
+	// Builtins are the builtin functions and modules the session may call,
+	// e.g. print, len or gzip. Nil allows every builtin
+	Builtins []string
+	// MaxExecutionTime limits a single Execute call, zero means no limit.
+	// A runtime call still running at the limit fails the command, but it
+	// is not interrupted: the interpreter keeps running it, and the next
+	// call on that runtime waits behind it with no time limit. A host that
+	// must get the runtime back has to interrupt or restart it itself
+	MaxExecutionTime time.Duration
+}
+
+// compiledPolicy is a SessionPolicy prepared for lookups
+type compiledPolicy struct {
+	languages        map[string]bool // nil allows all
+	builtins         map[string]bool // nil allows all
+	maxExecutionTime time.Duration
+}
+
+// SetPolicy limits the engine to policy; nil lifts all limits. Sessions and
+// background copies created afterwards inherit the policy
+func (e *ExecutionEngine) SetPolicy(policy *SessionPolicy) error {
+	if policy == nil {
+		e.policy = nil
+		return nil
+	}
+	if policy.MaxExecutionTime < 0 {
+		return errors.NewUserError("INVALID_POLICY", fmt.Sprintf("max execution time must not be negative, got %s", policy.MaxExecutionTime))
+	}
+	compiled := &compiledPolicy{maxExecutionTime: policy.MaxExecutionTime}
+	if policy.Languages != nil {
+		compiled.languages = make(map[string]bool, len(policy.Languages))
+		for _, language := range policy.Languages {
+			if canonical, isAlias := guardLanguages[language]; isAlias {
+				language = canonical
+			}
+			compiled.languages[language] = true
+		}
+	}
+	if policy.Builtins != nil {
+		compiled.builtins = make(map[string]bool, len(policy.Builtins))
+		for _, builtin := range policy.Builtins {
+			compiled.builtins[builtin] = true
+		}
+	}
+	e.policy = compiled
+	return nil
+}
+
+// checkLanguage fails if the policy doesn't allow the runtime
+func (e *ExecutionEngine) checkLanguage(language string, pos ast.Position) error {
+	if e.policy == nil || e.policy.languages == nil {
+		return nil
+	}
+	if canonical, isAlias := guardLanguages[language]; isAlias {
+		language = canonical
+	}
+	if !e.policy.languages[language] {
+		return errors.NewUserErrorWithASTPos("POLICY_DENIED", fmt.Sprintf("language '%s' is not allowed in this session", language), pos)
+	}
+	return nil
+}
+
+// checkBuiltin fails if the policy doesn't allow the builtin function or module
+func (e *ExecutionEngine) checkBuiltin(name string, pos ast.Position) error {
+	if e.policy == nil || e.policy.builtins == nil {
+		return nil
+	}
+	if !e.policy.builtins[name] {
+		return errors.NewUserErrorWithASTPos("POLICY_DENIED", fmt.Sprintf("builtin '%s' is not allowed in this session", name), pos)
+	}
+	return nil
+}
+
+// checkBuiltinCall fails if the policy doesn't allow the builtin a call
+// names. It runs before anything of the call is evaluated; a dotted name is
+// checked as its module, and a method of a variable is no builtin
+func (e *ExecutionEngine) checkBuiltinCall(call *ast.BuiltinFunctionCall) error {
+	if e.policy == nil || e.policy.builtins == nil {
+		return nil
+	}
+	name := call.Function
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+		if _, isVariable := e.getVariable(name); isVariable {
+			return nil
+		}
+		if _, isModule := e.lookupBuiltinModule(name); !isModule {
+			return nil
+		}
+	}
+	return e.checkBuiltin(name, call.Position())
+}
+
+// startDeadline starts the time limit of a command and returns the function
+// ending it. Nested calls keep the outer deadline
+func (e *ExecutionEngine) startDeadline() func() {
+	if e.policy == nil || e.policy.maxExecutionTime == 0 || !e.deadline.IsZero() {
+		return func() {}
+	}
+	e.deadline = time.Now().Add(e.policy.maxExecutionTime)
+	e.deadlineLimit = fmt.Sprintf("the session limit of %s", e.policy.maxExecutionTime)
+	return func() { e.deadline, e.deadlineLimit = time.Time{}, "" }
+}
+
+// checkDeadline fails once the command has run longer than the policy or
+// the timeout of a pipeline stage allows
+func (e *ExecutionEngine) checkDeadline() error {
+	if e.deadline.IsZero() || time.Now().Before(e.deadline) {
+		return nil
+	}
+	return errors.NewUserError("EXECUTION_TIMEOUT", fmt.Sprintf("command exceeded %s", e.deadlineLimit))
+}
+
+// withinDeadline runs a runtime call, failing when the deadline of the
+// command passes first. The call can't be stopped inside its interpreter, so
+// it goes on and the runtime stays busy until it returns
+func (e *ExecutionEngine) withinDeadline(call func() (interface{}, error)) (interface{}, error) {
+	if e.deadline.IsZero() {
+		return call()
+	}
+	type callResult struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		value, err := call()
+		done <- callResult{value, err}
+	}()
+	timer := time.NewTimer(time.Until(e.deadline))
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-timer.C:
+		return nil, e.checkDeadline()
+	}
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"go-parser/pkg/ast"
+)
+
+// executePragmaStatement runs a statement or block marked @quiet or
+// @verbose. @verbose turns on the debug output of the engine and the
+// runtimes for it, as --verbose does for the whole script. @quiet turns it
+// off and drops what the statements print; errors still stop the script.
+// Afterwards the global setting applies again
+func (e *ExecutionEngine) executePragmaStatement(statement *ast.PragmaStatement) (interface{}, error) {
+	defer e.setVerbosity(statement.Name == "verbose")()
+
+	if statement.Name == "verbose" {
+		return e.executeBlockStatement(statement.Body)
+	}
+	for _, stmt := range statement.Body.Statements {
+		if _, err := e.captureStatement(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// setVerbosity switches the debug output of the engine and of the runtimes
+// that have one, and returns the function switching back
+func (e *ExecutionEngine) setVerbosity(verbose bool) func() {
+	previous := e.verbose
+	switchTo := func(verbose bool) {
+		e.verbose = verbose
+		for _, rt := range e.runtimeManager.GetAllRuntimes() {
+			if verboseRuntime, ok := rt.(interface{ SetVerbose(bool) }); ok {
+				verboseRuntime.SetVerbose(verbose)
+			}
+		}
+	}
+	switchTo(verbose)
+	return func() { switchTo(previous) }
+}
@@ -0,0 +1,410 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"funterm/errors"
+	"funterm/runtime"
+)
+
+const (
+	// processLogLines is how many lines of output logs() keeps per process
+	processLogLines     = 1000
+	defaultMaxRestarts  = 5
+	defaultRestartDelay = time.Second
+	// processReapTimeout is how long stop() waits for a killed process to be reaped
+	processReapTimeout = 5 * time.Second
+)
+
+// Process is a helper program started with sh.daemon(), such as a database
+// for an integration test. Its output is kept for logs(); supervise() makes
+// it restart after a crash. The session stops it when it ends
+type Process struct {
+	mu          sync.Mutex
+	name        string // command as written, for display
+	path        string
+	args        []string
+	cmd         *exec.Cmd
+	log         *processLog
+	supervised  bool
+	maxRestarts int
+	delay       time.Duration
+	restarts    int
+	running     bool
+	stopped     bool          // stop() was called, the process isn't restarted
+	lastExit    error         // how the last run ended
+	done        chan struct{} // closed once the process is gone for good
+}
+
+// processLog keeps the last lines a process wrote to stdout and stderr
+type processLog struct {
+	mu      sync.Mutex
+	lines   []string
+	partial string
+}
+
+// Write implements io.Writer for the output of the process
+func (l *processLog) Write(data []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	text := l.partial + string(data)
+	parts := strings.Split(text, "\n")
+	l.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		l.appendLocked(strings.TrimSuffix(line, "\r"))
+	}
+	return len(data), nil
+}
+
+// note adds a line of funterm's own, e.g. about a restart
+func (l *processLog) note(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.appendLocked(line)
+}
+
+func (l *processLog) appendLocked(line string) {
+	l.lines = append(l.lines, line)
+	if len(l.lines) > processLogLines {
+		l.lines = l.lines[len(l.lines)-processLogLines:]
+	}
+}
+
+// last returns the last n lines kept, all of them for n <= 0
+func (l *processLog) last(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := append([]string(nil), l.lines...)
+	if l.partial != "" {
+		lines = append(lines, l.partial)
+	}
+	if n > 0 && n < len(lines) {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// callShFunction implements sh.daemon(command, args...) and
+// sh.run(command, args...)
+func (e *ExecutionEngine) callShFunction(function string, args []interface{}) (interface{}, error) {
+	switch function {
+	case "daemon":
+		return e.startDaemon(args)
+	case "run":
+		return e.runCommand(args)
+	}
+	return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("sh has no function '%s'; use daemon or run", function))
+}
+
+// commandLine finds the program to start for the arguments of an sh
+// function. A single string is run by the system shell, more arguments
+// start the program directly
+func commandLine(function string, args []interface{}) (name, path string, arguments []string, err error) {
+	if len(args) == 0 {
+		return "", "", nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("sh.%s() requires a command", function))
+	}
+	words := make([]string, len(args))
+	for i, arg := range args {
+		word, ok := arg.(string)
+		if !ok {
+			return "", "", nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("sh.%s() expects strings, got %s for argument %d", function, orderTypeName(arg), i+1))
+		}
+		words[i] = word
+	}
+	name = strings.Join(words, " ")
+	if len(words) == 1 {
+		path, arguments = shellCommand(words[0])
+		return name, path, arguments, nil
+	}
+	path, lookErr := runtime.ResolveExecutable(words[0])
+	if lookErr != nil {
+		return "", "", nil, errors.NewUserError("PROCESS_START_ERROR", fmt.Sprintf("sh.%s(): %v", function, lookErr))
+	}
+	return name, path, words[1:], nil
+}
+
+// runCommand implements sh.run(command, args...): it waits for the program
+// and returns what it wrote to stdout, without the last newline. Its stderr
+// goes to the terminal; a non-zero exit status is an error. The program is
+// killed when the command runs out of time
+func (e *ExecutionEngine) runCommand(args []interface{}) (interface{}, error) {
+	name, path, arguments, err := commandLine("run", args)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if !e.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, e.deadline)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, path, arguments...)
+	cmd.Stderr = os.Stderr
+	if !e.deadline.IsZero() {
+		// The program is stopped at the deadline with what it started
+		runtime.PrepareCommand(cmd)
+		cmd.Cancel = func() error { return runtime.KillProcessTree(cmd) }
+	}
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, e.checkDeadline()
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return nil, errors.NewUserError("PROCESS_EXIT_ERROR", fmt.Sprintf("sh.run(): %s failed with exit status %d", name, exitErr.ExitCode()))
+	}
+	if err != nil {
+		return nil, errors.NewUserError("PROCESS_START_ERROR", fmt.Sprintf("sh.run(): cannot start %s: %v", name, err))
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(output), "\n"), "\r"), nil
+}
+
+// startDaemon implements sh.daemon(command, args...)
+func (e *ExecutionEngine) startDaemon(args []interface{}) (interface{}, error) {
+	name, path, arguments, err := commandLine("daemon", args)
+	if err != nil {
+		return nil, err
+	}
+	process := &Process{name: name, path: path, args: arguments, log: &processLog{}, done: make(chan struct{})}
+	if err := process.start(); err != nil {
+		return nil, errors.NewUserError("PROCESS_START_ERROR", fmt.Sprintf("sh.daemon(): cannot start %s: %v", process.name, err))
+	}
+
+	// The session stops its helpers when it ends, also when a signal stops it
+	e.hooks.atExit(process.stop)
+	e.hooks.watch(terminatingSignals)
+	return process, nil
+}
+
+// shellCommand runs a command line with the shell of the system
+func shellCommand(command string) (string, []string) {
+	if goruntime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
+
+// executeSuperviseFunction implements supervise(process, options?): a
+// process that crashes is started again, at most max_restarts times and
+// after waiting delay seconds
+func (e *ExecutionEngine) executeSuperviseFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", "supervise() requires a process from sh.daemon() and optional options")
+	}
+	process, ok := args[0].(*Process)
+	if !ok {
+		return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("supervise() expects a process from sh.daemon(), got %s", orderTypeName(args[0])))
+	}
+	maxRestarts, delay := defaultMaxRestarts, defaultRestartDelay
+	if len(args) == 2 {
+		options, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("supervise() options must be an object, got %s", orderTypeName(args[1])))
+		}
+		for key, value := range options {
+			switch key {
+			case "max_restarts":
+				n, ok := toInt64(value)
+				if !ok || n < 0 {
+					return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("supervise(): max_restarts must be a non-negative number, got %v", value))
+				}
+				maxRestarts = int(n)
+			case "delay":
+				d, ok := durationValue(value)
+				if !ok {
+					return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("supervise(): delay must be seconds or a duration such as \"500ms\", got %v", value))
+				}
+				delay = d
+			default:
+				return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("supervise(): unknown option '%s' (available: max_restarts, delay)", key))
+			}
+		}
+	}
+
+	process.mu.Lock()
+	process.supervised, process.maxRestarts, process.delay = true, maxRestarts, delay
+	// A process that crashed before supervise() is restarted right away
+	crashed := !process.running && !process.stopped && process.lastExit != nil
+	if crashed {
+		process.done = make(chan struct{})
+	}
+	process.mu.Unlock()
+	if crashed {
+		go process.restart()
+	}
+	return process, nil
+}
+
+// start runs the program once more and watches it
+func (p *Process) start() error {
+	cmd := exec.Command(p.path, p.args...)
+	runtime.PrepareCommand(cmd)
+	cmd.Stdout = p.log
+	cmd.Stderr = p.log
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	runtime.TrackProcess(cmd)
+	p.mu.Lock()
+	p.cmd, p.running = cmd, true
+	p.mu.Unlock()
+	go p.watch(cmd)
+	return nil
+}
+
+// watch waits for a run of the process to end and restarts a supervised
+// process that crashed. Exiting with status 0 isn't a crash
+func (p *Process) watch(cmd *exec.Cmd) {
+	err := cmd.Wait()
+	p.mu.Lock()
+	p.running, p.lastExit = false, err
+	restart := err != nil && p.supervised && !p.stopped
+	p.mu.Unlock()
+
+	if restart {
+		p.restart()
+		return
+	}
+	p.finish()
+}
+
+// restart starts a crashed process again unless it used up its restarts
+func (p *Process) restart() {
+	p.mu.Lock()
+	if p.restarts >= p.maxRestarts {
+		p.mu.Unlock()
+		p.log.note(fmt.Sprintf("[supervise] %s exited (%v), giving up after %d restarts", p.name, p.lastExit, p.maxRestarts))
+		p.finish()
+		return
+	}
+	p.restarts++
+	attempt, delay, lastExit := p.restarts, p.delay, p.lastExit
+	p.mu.Unlock()
+
+	p.log.note(fmt.Sprintf("[supervise] %s exited (%v), restart %d of %d", p.name, lastExit, attempt, p.maxRestarts))
+	time.Sleep(delay)
+
+	p.mu.Lock()
+	stopped := p.stopped
+	p.mu.Unlock()
+	if stopped {
+		p.finish()
+		return
+	}
+	if err := p.start(); err != nil {
+		p.log.note(fmt.Sprintf("[supervise] cannot restart %s: %v", p.name, err))
+		p.finish()
+	}
+}
+
+// finish marks the process as gone for good
+func (p *Process) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+}
+
+// stop ends the process and the processes it started, for good. They get
+// SIGTERM and runtime.StopGrace to shut down cleanly before SIGKILL; a
+// supervised process isn't restarted after either
+func (p *Process) stop() {
+	p.mu.Lock()
+	p.stopped = true
+	cmd, running, done := p.cmd, p.running, p.done
+	p.mu.Unlock()
+	if running {
+		runtime.KillProcessTree(cmd)
+	}
+	select {
+	case <-done:
+	case <-time.After(processReapTimeout):
+	}
+}
+
+// wait waits until the process is gone for good, i.e. it exited and isn't
+// restarted, and returns its exit status; nil if it was stopped
+func (p *Process) wait() interface{} {
+	p.mu.Lock()
+	done := p.done
+	p.mu.Unlock()
+	<-done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return nil
+	}
+	if exitErr, ok := p.lastExit.(*exec.ExitError); ok {
+		return float64(exitErr.ExitCode())
+	}
+	return float64(0)
+}
+
+// CallMethod implements shared.MethodObject
+func (p *Process) CallMethod(name string, args []interface{}) (interface{}, error) {
+	if name == "logs" {
+		if len(args) > 1 {
+			return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", "logs() accepts at most the number of lines")
+		}
+		n := int64(0)
+		if len(args) == 1 {
+			var ok bool
+			if n, ok = toInt64(args[0]); !ok || n < 1 {
+				return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("logs() expects a positive number of lines, got %v", args[0]))
+			}
+		}
+		return strings.Join(p.log.last(int(n)), "\n"), nil
+	}
+	if len(args) != 0 {
+		return nil, errors.NewUserError("PROCESS_ARGUMENT_ERROR", fmt.Sprintf("%s() takes no arguments", name))
+	}
+	if name == "stop" {
+		p.stop()
+		return nil, nil
+	}
+	if name == "wait" {
+		return p.wait(), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch name {
+	case "pid":
+		if !p.running {
+			return nil, nil
+		}
+		return float64(p.cmd.Process.Pid), nil
+	case "alive":
+		return p.running, nil
+	case "restarts":
+		return float64(p.restarts), nil
+	default:
+		return nil, errors.NewUserError("UNKNOWN_METHOD", fmt.Sprintf("process has no method '%s' (available: logs, pid, alive, restarts, wait, stop)", name))
+	}
+}
+
+// String describes the process for display
+func (p *Process) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.running:
+		return fmt.Sprintf("<process %s, pid %d>", p.name, p.cmd.Process.Pid)
+	case p.stopped:
+		return fmt.Sprintf("<process %s, stopped>", p.name)
+	case p.lastExit != nil:
+		return fmt.Sprintf("<process %s, exited: %v>", p.name, p.lastExit)
+	default:
+		return fmt.Sprintf("<process %s, exited>", p.name)
+	}
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"funterm/errors"
+)
+
+const (
+	progressBarWidth        = 30
+	defaultSpinnerThreshold = time.Second
+	spinnerInterval         = 100 * time.Millisecond
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// isTerminal reports whether f is attached to a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
+
+// ProgressBar is the value returned by the progress() builtin.
+// It is drawn on stderr so it never mixes with captured stdout results.
+type ProgressBar struct {
+	mu          sync.Mutex
+	total       int64
+	current     int64
+	label       string
+	started     time.Time
+	out         io.Writer
+	interactive bool // redraw in place on every tick
+	finished    bool
+}
+
+// NewProgressBar creates a progress bar for total steps
+func NewProgressBar(total int64, label string) *ProgressBar {
+	return &ProgressBar{
+		total:       total,
+		label:       label,
+		started:     time.Now(),
+		out:         os.Stderr,
+		interactive: isTerminal(os.Stderr),
+	}
+}
+
+// CallMethod implements shared.MethodObject
+func (p *ProgressBar) CallMethod(name string, args []interface{}) (interface{}, error) {
+	switch name {
+	case "tick":
+		step := int64(1)
+		if len(args) > 1 {
+			return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", "tick() accepts at most one argument")
+		}
+		if len(args) == 1 {
+			n, ok := toInt64(args[0])
+			if !ok {
+				return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", fmt.Sprintf("tick() step must be a number, got %T", args[0]))
+			}
+			if n < 0 {
+				return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", fmt.Sprintf("tick() step must not be negative, got %d", n))
+			}
+			step = n
+		}
+		p.Tick(step)
+		return nil, nil
+	case "done":
+		if len(args) != 0 {
+			return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", "done() takes no arguments")
+		}
+		p.Done()
+		return nil, nil
+	default:
+		return nil, errors.NewUserError("UNKNOWN_METHOD", fmt.Sprintf("progress has no method '%s' (available: tick, done)", name))
+	}
+}
+
+// Tick advances the bar by step and returns the new position
+func (p *ProgressBar) Tick(step int64) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.finished {
+		return p.current
+	}
+	p.current += step
+	if p.current > p.total {
+		p.current = p.total
+	}
+	if p.current < 0 {
+		p.current = 0
+	}
+	if p.interactive {
+		fmt.Fprintf(p.out, "\r%s", p.render())
+	}
+	return p.current
+}
+
+// Done completes the bar and moves to the next line; repeated calls are ignored
+func (p *ProgressBar) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.finished {
+		return
+	}
+	p.finished = true
+	if p.interactive {
+		fmt.Fprintf(p.out, "\r%s\n", p.render())
+	} else {
+		// Without a terminal only the final line is drawn, to keep logs clean
+		fmt.Fprintln(p.out, p.render())
+	}
+}
+
+// render builds a line like "label [#####.....] 50% 5/10 1.2s"
+func (p *ProgressBar) render() string {
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = float64(p.current) / float64(p.total)
+	}
+	filled := min(max(int(ratio*progressBarWidth), 0), progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled)
+
+	line := fmt.Sprintf("[%s] %3d%% %d/%d %.1fs", bar, int(ratio*100), p.current, p.total, time.Since(p.started).Seconds())
+	if p.label != "" {
+		line = p.label + " " + line
+	}
+	return line
+}
+
+// String is used when the progress value itself is displayed
+func (p *ProgressBar) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return fmt.Sprintf("<progress %d/%d>", p.current, p.total)
+}
+
+// executeProgressFunction creates a progress bar: progress(total) or progress(total, label)
+func (e *ExecutionEngine) executeProgressFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", "progress() requires a total and an optional label")
+	}
+	total, ok := toInt64(args[0])
+	if !ok || total < 0 {
+		return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", fmt.Sprintf("progress() total must be a non-negative number, got %v", args[0]))
+	}
+	label := ""
+	if len(args) == 2 {
+		label, ok = args[1].(string)
+		if !ok {
+			return nil, errors.NewUserError("PROGRESS_ARGUMENT_ERROR", "progress() label must be a string")
+		}
+	}
+	return NewProgressBar(total, label), nil
+}
+
+// SetSpinnerThreshold sets how long a runtime call may run before a spinner
+// is shown; zero restores the default and a negative value disables it
+func (e *ExecutionEngine) SetSpinnerThreshold(threshold time.Duration) {
+	if threshold == 0 {
+		threshold = defaultSpinnerThreshold
+	}
+	e.spinnerThreshold = threshold
+}
+
+// startSpinner shows a spinner with elapsed time on stderr if the call
+// outlives the threshold. The returned function stops and erases it.
+func (e *ExecutionEngine) startSpinner(label string) func() {
+	if e.spinnerThreshold <= 0 || e.verbose || !isTerminal(os.Stderr) {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	started := time.Now()
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(e.spinnerThreshold):
+		}
+
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			// While the call waits for a confirmation the question owns the line
+			if atomic.LoadInt32(&e.spinnerHeld) == 0 {
+				fmt.Fprintf(os.Stderr, "\r%s %s %.1fs", spinnerFrames[frame%len(spinnerFrames)], label, time.Since(started).Seconds())
+			}
+			select {
+			case <-stop:
+				fmt.Fprint(os.Stderr, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// toInt64 converts numeric values coming from literals or runtimes
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float32:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"time"
+
+	"go-parser/pkg/ast"
+)
+
+// PanicPosition is where a command that panicked was: the statement the
+// parser was reading, or the last statement the engine started to run
+func (e *ExecutionEngine) PanicPosition() (pos ast.Position) {
+	if e.parsing {
+		return e.parser.StatementPosition()
+	}
+	if e.current == nil {
+		return ast.Position{}
+	}
+	// The statement may be what broke, a nil node among them
+	defer func() {
+		if recover() != nil {
+			pos = ast.Position{}
+		}
+	}()
+	return e.current.Position()
+}
+
+// RecoverFromPanic makes the engine usable again after a command panicked
+// part way: the scopes the command entered are left, so the next command
+// sees the session variables, with what the command assigned before it
+// panicked
+func (e *ExecutionEngine) RecoverFromPanic() {
+	if len(e.scopeStack) > 1 {
+		e.scopeStack = e.scopeStack[:1]
+		e.localScope = e.scopeStack[0]
+	}
+	e.parsing = false
+	e.current = nil
+	e.deadline, e.deadlineLimit = time.Time{}, ""
+}
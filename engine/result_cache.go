@@ -0,0 +1,316 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"funterm/errors"
+	"funterm/shared"
+	"github.com/funvibe/funbit/pkg/funbit"
+	"go-parser/pkg/ast"
+)
+
+// cachedResult is the file cache_result() keeps for a key: the fingerprint of
+// the inputs it was computed from, its value and the files the value names
+type cachedResult struct {
+	Key     string      `json:"key"`
+	Inputs  string      `json:"inputs"`
+	Value   cachedValue `json:"value"`
+	Outputs []string    `json:"outputs,omitempty"`
+}
+
+// cachedValue is a value with its type, so that integers, big integers,
+// bytes and bitstrings come back as they were
+type cachedValue struct {
+	Type   string                 `json:"type"`
+	Value  interface{}            `json:"value,omitempty"`
+	Bits   int                    `json:"bits,omitempty"`
+	Items  []cachedValue          `json:"items,omitempty"`
+	Fields map[string]cachedValue `json:"fields,omitempty"`
+}
+
+// SetResultCacheDir sets the directory of cache_result(); empty disables the
+// cache and every expression is evaluated
+func (e *ExecutionEngine) SetResultCacheDir(dir string) {
+	e.resultCacheDir = dir
+}
+
+// executeCacheResultFunction implements cache_result(key, expression, inputs?).
+// The expression is evaluated only when the key has no result yet or its
+// inputs changed since: files named by the inputs are compared by content,
+// other inputs by value. A result naming files is reused only while they
+// still exist, so a deleted output is built again
+func (e *ExecutionEngine) executeCacheResultFunction(call *ast.BuiltinFunctionCall) (interface{}, error) {
+	if len(call.Arguments) < 2 || len(call.Arguments) > 3 {
+		return nil, errors.NewUserErrorWithASTPos("CACHE_ERROR", "cache_result() requires a key, an expression and optional inputs", call.Position())
+	}
+	keyValue, err := e.convertExpressionToValue(call.Arguments[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert argument 0: %v", err)
+	}
+	key, ok := keyValue.(string)
+	if !ok || key == "" {
+		return nil, errors.NewUserErrorWithASTPos("CACHE_ERROR", fmt.Sprintf("cache_result() key must be a non-empty string, got %s", orderTypeName(keyValue)), call.Position())
+	}
+	var inputs interface{}
+	if len(call.Arguments) == 3 {
+		if inputs, err = e.convertExpressionToValue(call.Arguments[2]); err != nil {
+			return nil, fmt.Errorf("failed to convert argument 2: %v", err)
+		}
+	}
+	evaluate := func() (interface{}, error) { return e.convertExpressionToValue(call.Arguments[1]) }
+	if e.resultCacheDir == "" {
+		return evaluate()
+	}
+
+	fingerprint, err := inputsFingerprint(inputs)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("CACHE_ERROR", fmt.Sprintf("cache_result(): %v", err), call.Position())
+	}
+	path := filepath.Join(e.resultCacheDir, hashString(key)+".json")
+	if cached, ok := loadCachedResult(path); ok && cached.Key == key && cached.Inputs == fingerprint {
+		if value, err := decodeCachedValue(cached.Value); err == nil && filesExist(cached.Outputs) {
+			if e.verbose {
+				fmt.Printf("DEBUG: cache_result(%q) reused\n", key)
+			}
+			return value, nil
+		}
+	}
+
+	value, err := evaluate()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodeCachedValue(value)
+	if err != nil {
+		return nil, errors.NewUserErrorWithASTPos("CACHE_ERROR", fmt.Sprintf("cache_result(): %v", err), call.Position())
+	}
+	cached := cachedResult{Key: key, Inputs: fingerprint, Value: encoded, Outputs: namedFiles(value)}
+	if err := saveCachedResult(path, cached); err != nil {
+		return nil, errors.NewUserErrorWithASTPos("CACHE_ERROR", fmt.Sprintf("cache_result(): cannot save the result: %v", err), call.Position())
+	}
+	return value, nil
+}
+
+// inputsFingerprint hashes the inputs of a cached result. Strings naming a
+// file stand for its content, so touching a file without changing it keeps
+// the result
+func inputsFingerprint(inputs interface{}) (string, error) {
+	if inputs == nil {
+		return "", nil
+	}
+	list, ok := inputs.([]interface{})
+	if !ok {
+		list = []interface{}{inputs}
+	}
+	hash := sha256.New()
+	for _, input := range list {
+		if path, ok := input.(string); ok {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				file, err := os.Open(path)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(hash, "file %s\n", path)
+				_, err = io.Copy(hash, file)
+				file.Close()
+				if err != nil {
+					return "", err
+				}
+				hash.Write([]byte{0})
+				continue
+			}
+		}
+		encoded, err := encodeCachedValue(input)
+		if err != nil {
+			return "", fmt.Errorf("input %v: %v", input, err)
+		}
+		data, err := json.Marshal(encoded)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "value %s\n", data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// namedFiles returns the existing files a result names: a path or a list of paths
+func namedFiles(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+	var files []string
+	for _, item := range list {
+		if path, ok := item.(string); ok {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+		}
+	}
+	return files
+}
+
+func filesExist(paths []string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func hashString(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadCachedResult(path string) (cachedResult, bool) {
+	var cached cachedResult
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cached, false
+	}
+	return cached, json.Unmarshal(data, &cached) == nil
+}
+
+// saveCachedResult writes the result through a temporary file, so that an
+// interrupted run never leaves half a result behind
+func saveCachedResult(path string, cached cachedResult) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// encodeCachedValue turns a value into its cached form; handles, objects
+// and other values that only live in this session can't be cached
+func encodeCachedValue(value interface{}) (cachedValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return cachedValue{Type: "nil"}, nil
+	case bool:
+		return cachedValue{Type: "bool", Value: v}, nil
+	case string:
+		return cachedValue{Type: "string", Value: v}, nil
+	case int:
+		return cachedValue{Type: "int", Value: fmt.Sprint(v)}, nil
+	case int64:
+		return cachedValue{Type: "int", Value: fmt.Sprint(v)}, nil
+	case *big.Int:
+		return cachedValue{Type: "int", Value: v.String()}, nil
+	case float64:
+		return cachedValue{Type: "float", Value: v}, nil
+	case []byte:
+		return cachedValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(v)}, nil
+	case *shared.BitstringObject:
+		return cachedValue{Type: "bitstring", Value: base64.StdEncoding.EncodeToString(v.Bytes()), Bits: v.Len()}, nil
+	case []interface{}:
+		items := make([]cachedValue, len(v))
+		for i, item := range v {
+			encoded, err := encodeCachedValue(item)
+			if err != nil {
+				return cachedValue{}, err
+			}
+			items[i] = encoded
+		}
+		return cachedValue{Type: "array", Items: items}, nil
+	case map[string]interface{}:
+		fields := make(map[string]cachedValue, len(v))
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			encoded, err := encodeCachedValue(v[name])
+			if err != nil {
+				return cachedValue{}, err
+			}
+			fields[name] = encoded
+		}
+		return cachedValue{Type: "object", Fields: fields}, nil
+	}
+	return cachedValue{}, fmt.Errorf("a value of type %s can't be cached", orderTypeName(value))
+}
+
+// decodeCachedValue restores a value from its cached form
+func decodeCachedValue(cached cachedValue) (interface{}, error) {
+	switch cached.Type {
+	case "nil":
+		return nil, nil
+	case "bool":
+		if b, ok := cached.Value.(bool); ok {
+			return b, nil
+		}
+	case "string":
+		if s, ok := cached.Value.(string); ok {
+			return s, nil
+		}
+	case "int":
+		if s, ok := cached.Value.(string); ok {
+			n, ok := new(big.Int).SetString(s, 10)
+			if !ok {
+				break
+			}
+			if n.IsInt64() {
+				return n.Int64(), nil
+			}
+			return n, nil
+		}
+	case "float":
+		if f, ok := cached.Value.(float64); ok {
+			return f, nil
+		}
+	case "bytes", "bitstring":
+		s, _ := cached.Value.(string)
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			break
+		}
+		if cached.Type == "bytes" {
+			return data, nil
+		}
+		if cached.Bits > len(data)*8 {
+			break
+		}
+		return shared.NewBitstringObject(funbit.NewBitStringFromBits(data, uint(cached.Bits))), nil
+	case "array":
+		items := make([]interface{}, len(cached.Items))
+		for i, item := range cached.Items {
+			value, err := decodeCachedValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	case "object":
+		fields := make(map[string]interface{}, len(cached.Fields))
+		for name, field := range cached.Fields {
+			value, err := decodeCachedValue(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = value
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("damaged cached value of type %s", cached.Type)
+}
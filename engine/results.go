@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+
+	"funterm/shared"
+	"go-parser/pkg/ast"
+)
+
+// exceptionLinePattern finds "ValueError: message" style lines in interpreter
+// output; Node prefixes uncaught exceptions with "Uncaught "
+var exceptionLinePattern = regexp.MustCompile(`^(?:Uncaught )?([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt|Iteration)):?(?: (.*))?$`)
+
+// errorTagPattern matches the [TYPE][CODE] prefix of wrapped execution errors
+var errorTagPattern = regexp.MustCompile(`\[[A-Z]+\]\[[A-Z_]+\] `)
+
+// errorPositionPattern matches the position suffix of execution errors
+var errorPositionPattern = regexp.MustCompile(`(?: line \d+ col \d+)+$`)
+
+// executeTryExpression evaluates the wrapped expression and returns ok(value),
+// or error({type, msg, language}) when it fails
+func (e *ExecutionEngine) executeTryExpression(tryExpr *ast.TryExpression) (interface{}, error) {
+	value, err := e.convertExpressionToValue(tryExpr.Expression)
+	if err != nil {
+		return &shared.ResultObject{Ok: false, Value: describeError(tryLanguage(tryExpr.Expression), err)}, nil
+	}
+	return &shared.ResultObject{Ok: true, Value: value}, nil
+}
+
+// tryLanguage returns the runtime a try expression calls into, if any
+func tryLanguage(expr ast.Expression) string {
+	var language string
+	switch ex := expr.(type) {
+	case *ast.LanguageCall:
+		language = ex.Language
+	case *ast.FieldAccess:
+		if id, ok := ex.Object.(*ast.Identifier); ok {
+			language = id.Name
+		}
+	case *ast.Identifier:
+		language = ex.Language
+	}
+
+	switch language {
+	case "py":
+		return "python"
+	case "js":
+		return "node"
+	case "pl":
+		return "perl"
+	case "erl":
+		return "erlang"
+	case "ex":
+		return "elixir"
+	}
+	return language
+}
+
+// describeError turns an error into the {type, msg} object matched by error(...)
+// patterns. Exceptions raised by a runtime keep their own type, e.g. ValueError
+// or RangeError; anything else is reported as type "Error".
+func describeError(language string, err error) map[string]interface{} {
+	text := err.Error()
+	text = strings.ReplaceAll(text, "python stderr: ", "")
+	text = strings.ReplaceAll(text, "node stderr: ", "")
+
+	description := map[string]interface{}{}
+	if language != "" {
+		description["language"] = language
+	}
+
+	// Keep the innermost message of a wrapped error
+	if tags := errorTagPattern.FindAllStringIndex(text, -1); len(tags) > 0 {
+		text = text[tags[len(tags)-1][1]:]
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = errorPositionPattern.ReplaceAllString(strings.TrimSpace(line), "")
+		if match := exceptionLinePattern.FindStringSubmatch(line); match != nil {
+			description["type"] = match[1]
+			description["msg"] = strings.TrimSpace(match[2])
+			return description
+		}
+	}
+
+	text = strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	text = errorPositionPattern.ReplaceAllString(text, "")
+	for _, prefix := range []string{"function call error:", "execution error:"} {
+		text = strings.TrimSpace(strings.TrimPrefix(text, prefix))
+	}
+
+	description["type"] = "Error"
+	description["msg"] = text
+	return description
+}
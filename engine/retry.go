@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"regexp"
+	"time"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// retryPolicy is how a retry statement repeats its body
+type retryPolicy struct {
+	attempts int
+	backoff  string        // fixed, linear or exp
+	base     time.Duration // delay before the second attempt
+	max      time.Duration // longest delay, 0 for no limit
+	jitter   bool          // wait a random time between half and the whole delay
+	when     *regexp.Regexp
+}
+
+// executeRetryStatement runs the body of retry(n, ...) { ... } until it
+// succeeds, at most n times, waiting between attempts as the backoff says.
+// An error the `when` pattern doesn't match is returned at once. The body
+// runs in the current scope, so what an attempt assigns stays after it
+func (e *ExecutionEngine) executeRetryStatement(retry *ast.RetryStatement) (interface{}, error) {
+	policy, err := e.retryPolicy(retry)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := e.executeBlockStatement(retry.Body)
+		if err == nil {
+			return result, nil
+		}
+		if policy.when != nil && !policy.when.MatchString(err.Error()) {
+			return nil, err
+		}
+		if attempt == policy.attempts {
+			return nil, errors.NewUserErrorWithASTPos("RETRY_ERROR", fmt.Sprintf("gave up after %d attempts: %v", attempt, err), retry.Pos)
+		}
+
+		delay := policy.delay(attempt)
+		fmt.Fprintf(os.Stderr, "Warning: attempt %d of %d failed, retrying in %s: %v\n", attempt, policy.attempts, delay, err)
+		if !e.deadline.IsZero() && time.Now().Add(delay).After(e.deadline) {
+			delay = time.Until(e.deadline)
+		}
+		time.Sleep(delay)
+		if err := e.checkDeadline(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// delay is the wait after the given failed attempt
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	delay := p.base
+	switch p.backoff {
+	case "linear":
+		delay = p.base * time.Duration(attempt)
+	case "exp":
+		delay = time.Duration(float64(p.base) * math.Pow(2, float64(attempt-1)))
+	}
+	if p.max > 0 && delay > p.max {
+		delay = p.max
+	}
+	if p.jitter {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay
+}
+
+// retryPolicy evaluates the arguments of a retry statement
+func (e *ExecutionEngine) retryPolicy(retry *ast.RetryStatement) (*retryPolicy, error) {
+	policy := &retryPolicy{backoff: "exp", base: time.Second}
+
+	value, err := e.convertExpressionToValue(retry.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	attempts, ok := toInt64(value)
+	if f, isFloat := value.(float64); isFloat && f != math.Trunc(f) {
+		ok = false
+	}
+	if !ok || attempts < 1 {
+		return nil, errors.NewUserErrorWithASTPos("RETRY_ERROR", fmt.Sprintf("retry() expects a positive whole number of attempts, got %v", value), retry.Pos)
+	}
+	policy.attempts = int(attempts)
+
+	for _, option := range retry.Options {
+		value, err := e.convertExpressionToValue(option.Value)
+		if err != nil {
+			return nil, err
+		}
+		optionError := func(expected string) error {
+			return errors.NewUserErrorWithASTPos("RETRY_ERROR", fmt.Sprintf("retry() option %s expects %s, got %v", option.Name, expected, value), option.Pos)
+		}
+		switch option.Name {
+		case "backoff":
+			backoff, ok := value.(string)
+			if !ok || (backoff != "fixed" && backoff != "linear" && backoff != "exp") {
+				return nil, optionError(`"fixed", "linear" or "exp"`)
+			}
+			policy.backoff = backoff
+		case "base", "max":
+			duration, ok := durationValue(value)
+			if !ok {
+				return nil, optionError("a duration such as 500ms, 2s or \"1m\"")
+			}
+			if option.Name == "base" {
+				policy.base = duration
+			} else {
+				policy.max = duration
+			}
+		case "jitter":
+			jitter, ok := value.(bool)
+			if !ok {
+				return nil, optionError("true or false")
+			}
+			policy.jitter = jitter
+		case "when":
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, optionError("a regular expression as a string")
+			}
+			when, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, optionError(fmt.Sprintf("a valid regular expression (%v)", err))
+			}
+			policy.when = when
+		}
+	}
+	return policy, nil
+}
+
+// durationValue reads a duration: a number of seconds, which is also what
+// 500ms and 2s literals become, or a string such as "1m30s"
+func durationValue(value interface{}) (time.Duration, bool) {
+	if text, ok := value.(string); ok {
+		duration, err := time.ParseDuration(text)
+		return duration, err == nil && duration >= 0
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		whole, isInt := toInt64(value)
+		seconds, ok = float64(whole), isInt
+	}
+	return time.Duration(seconds * float64(time.Second)), ok && seconds >= 0
+}
@@ -2,12 +2,14 @@ package engine
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"funterm/errors"
 	"funterm/factory"
 	"funterm/runtime"
 	"funterm/runtime/python"
+	"go-parser/pkg/ast"
 )
 
 // GetRuntimeManager returns the runtime manager
@@ -77,6 +79,19 @@ func (e *ExecutionEngine) ListAvailableLanguages() []string {
 	return languages
 }
 
+// ActiveRuntimes returns the sorted names of the registered runtimes that are
+// ready to run code
+func (e *ExecutionEngine) ActiveRuntimes() []string {
+	var active []string
+	for _, name := range e.runtimeManager.ListRuntimes() {
+		if e.runtimeManager.IsRuntimeReady(name) {
+			active = append(active, name)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
+
 // IsLanguageAvailable checks if a language runtime is available
 func (e *ExecutionEngine) IsLanguageAvailable(language string) bool {
 	// Check runtime manager first
@@ -101,6 +116,20 @@ func (e *ExecutionEngine) getRuntimeByName(runtimeName string) (runtime.Language
 	if runtimeName == "js" {
 		runtimeName = "node"
 	}
+	// Handle alias 'pl' for 'perl'
+	if runtimeName == "pl" {
+		runtimeName = "perl"
+	}
+	// Handle aliases 'erl' and 'ex' for the BEAM languages
+	if runtimeName == "erl" {
+		runtimeName = "erlang"
+	}
+	if runtimeName == "ex" {
+		runtimeName = "elixir"
+	}
+	if err := e.checkLanguage(runtimeName, ast.Position{}); err != nil {
+		return nil, err
+	}
 
 	// Try to get the runtime from the runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(runtimeName)
@@ -129,6 +158,20 @@ func (e *ExecutionEngine) getRuntimeForLanguage(language string) (runtime.Langua
 	if language == "js" {
 		language = "node"
 	}
+	// Handle alias 'pl' for 'perl'
+	if language == "pl" {
+		language = "perl"
+	}
+	// Handle aliases 'erl' and 'ex' for the BEAM languages
+	if language == "erl" {
+		language = "erlang"
+	}
+	if language == "ex" {
+		language = "elixir"
+	}
+	if err := e.checkLanguage(language, ast.Position{}); err != nil {
+		return nil, err
+	}
 
 	// Try to get from runtime manager first
 	rt, err := e.runtimeManager.GetRuntime(language)
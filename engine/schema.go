@@ -0,0 +1,414 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"funterm/errors"
+	"funterm/shared"
+
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// schemaField is one field of a pack()/unpack() schema
+type schemaField struct {
+	name       string
+	kind       string // integer, float, binary, bits, utf8, utf16 or utf32
+	size       uint   // in bits for integers, floats and bits, in bytes for binaries
+	sized      bool
+	endianness string
+	signed     bool
+	value      interface{} // fixed value such as a magic number, nil if none
+}
+
+// schemaFieldKeys are the keys a field definition may have
+var schemaFieldKeys = map[string]bool{
+	"name": true, "order": true, "size": true, "type": true, "endianness": true, "signed": true, "value": true,
+}
+
+// parseSchema reads a schema given as an array of field definitions with a
+// "name", or as an object of definitions keyed by name. Objects are unordered,
+// so with more than one field each definition needs an "order" number.
+func parseSchema(function string, schema interface{}) ([]schemaField, error) {
+	type entry struct {
+		name  string
+		order int64
+		def   interface{}
+	}
+	var entries []entry
+
+	switch s := schema.(type) {
+	case []interface{}:
+		for i, def := range s {
+			m, ok := def.(map[string]interface{})
+			if !ok {
+				return nil, schemaError(function, "field %d must be an object, got %s", i+1, orderTypeName(def))
+			}
+			name, ok := m["name"].(string)
+			if !ok || name == "" {
+				return nil, schemaError(function, "field %d needs a \"name\"", i+1)
+			}
+			entries = append(entries, entry{name: name, order: int64(i), def: def})
+		}
+	case map[string]interface{}:
+		for name, def := range s {
+			m, ok := def.(map[string]interface{})
+			if !ok {
+				return nil, schemaError(function, "field %s must be an object, got %s", name, orderTypeName(def))
+			}
+			order, hasOrder := toInt64(m["order"])
+			if !hasOrder && len(s) > 1 {
+				return nil, schemaError(function, "field %s needs an \"order\"; objects don't keep their key order, so pass an array of fields to avoid numbering them", name)
+			}
+			entries = append(entries, entry{name: name, order: order, def: def})
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].order < entries[j].order })
+	default:
+		return nil, schemaError(function, "schema must be an array or an object, got %s", orderTypeName(schema))
+	}
+
+	fields := make([]schemaField, 0, len(entries))
+	seen := map[string]bool{}
+	for _, en := range entries {
+		if seen[en.name] {
+			return nil, schemaError(function, "field %s is defined twice", en.name)
+		}
+		seen[en.name] = true
+		field, err := parseSchemaField(function, en.name, en.def.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parseSchemaField reads one field definition
+func parseSchemaField(function, name string, def map[string]interface{}) (schemaField, error) {
+	field := schemaField{name: name, kind: "integer", endianness: "big", value: def["value"]}
+	for key := range def {
+		if !schemaFieldKeys[key] {
+			return field, schemaError(function, "field %s has unknown key \"%s\"; use size, type, endianness, signed or value", name, key)
+		}
+	}
+
+	if t, exists := def["type"]; exists {
+		kind, ok := t.(string)
+		switch kind {
+		case "bytes":
+			kind = "binary"
+		case "bitstring":
+			kind = "bits"
+		}
+		switch kind {
+		case "integer", "float", "binary", "bits", "utf8", "utf16", "utf32":
+		default:
+			ok = false
+		}
+		if !ok {
+			return field, schemaError(function, "field %s has type %v; use integer, float, binary, bits, utf8, utf16 or utf32", name, t)
+		}
+		field.kind = kind
+	}
+
+	if s, exists := def["size"]; exists {
+		n, ok := toInt64(s)
+		if !ok || n <= 0 {
+			return field, schemaError(function, "field %s size must be a positive integer, got %v", name, s)
+		}
+		field.size, field.sized = uint(n), true
+	}
+	switch field.kind {
+	case "integer":
+		if !field.sized {
+			field.size, field.sized = 8, true
+		}
+	case "float":
+		if !field.sized {
+			field.size, field.sized = 64, true
+		}
+		if field.size != 16 && field.size != 32 && field.size != 64 {
+			return field, schemaError(function, "field %s: floats are 16, 32 or 64 bits, got %d", name, field.size)
+		}
+	case "utf8", "utf16", "utf32":
+		if field.sized {
+			return field, schemaError(function, "field %s: %s fields take one character and have no size", name, field.kind)
+		}
+	}
+
+	if e, exists := def["endianness"]; exists {
+		switch e {
+		case "big", "little":
+			field.endianness = e.(string)
+		case "native":
+			field.endianness = funbit.GetNativeEndianness()
+		default:
+			return field, schemaError(function, "field %s endianness must be big, little or native, got %v", name, e)
+		}
+	}
+	if s, exists := def["signed"]; exists {
+		signed, ok := s.(bool)
+		if !ok {
+			return field, schemaError(function, "field %s signed must be true or false, got %v", name, s)
+		}
+		field.signed = signed
+	}
+	return field, nil
+}
+
+// schemaError builds a SCHEMA_ERROR for pack() or unpack()
+func schemaError(function, format string, args ...interface{}) error {
+	return errors.NewUserError("SCHEMA_ERROR", fmt.Sprintf("%s(): %s", function, fmt.Sprintf(format, args...)))
+}
+
+// options returns the funbit segment options of a field
+func (f schemaField) options() []funbit.SegmentOption {
+	options := []funbit.SegmentOption{funbit.WithEndianness(f.endianness), funbit.WithSigned(f.signed)}
+	if f.sized {
+		options = append(options, funbit.WithSize(f.size))
+	}
+	return options
+}
+
+// executePackFunction implements pack(schema, values): the values object is
+// encoded field by field; fields with a fixed "value" may be left out
+func (e *ExecutionEngine) executePackFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("SCHEMA_ERROR", "pack() requires a schema and an object of values")
+	}
+	fields, err := parseSchema("pack", args[0])
+	if err != nil {
+		return nil, err
+	}
+	values, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, schemaError("pack", "values must be an object, got %s", orderTypeName(args[1]))
+	}
+
+	builder := funbit.NewBuilder()
+	for _, field := range fields {
+		value, exists := values[field.name]
+		if !exists {
+			if field.value == nil {
+				return nil, schemaError("pack", "no value for field %s", field.name)
+			}
+			value = field.value
+		}
+		if err := field.add(builder, value); err != nil {
+			return nil, err
+		}
+	}
+	bits, err := funbit.Build(builder)
+	if err != nil {
+		return nil, errors.NewUserError("BITSTRING_BUILD_ERROR", fmt.Sprintf("pack(): %v", err))
+	}
+	return shared.NewBitstringObject(bits), nil
+}
+
+// add appends a field value to the builder, checking that it fits
+func (f schemaField) add(builder *funbit.Builder, value interface{}) error {
+	switch f.kind {
+	case "integer":
+		n, ok := schemaInteger(value)
+		if !ok {
+			return schemaError("pack", "field %s expects an integer, got %s", f.name, orderTypeName(value))
+		}
+		low, high := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), f.size)
+		if f.signed {
+			high.Rsh(high, 1)
+			low.Neg(high)
+		}
+		if n.Cmp(low) < 0 || n.Cmp(high) >= 0 {
+			return schemaError("pack", "field %s: %s does not fit in %d %s bits", f.name, n, f.size, map[bool]string{true: "signed", false: "unsigned"}[f.signed])
+		}
+		if n.IsInt64() {
+			funbit.AddInteger(builder, n.Int64(), f.options()...)
+		} else {
+			funbit.AddInteger(builder, n.Uint64(), f.options()...)
+		}
+	case "float":
+		var x float64
+		switch v := value.(type) {
+		case float64:
+			x = v
+		default:
+			n, ok := toInt64(value)
+			if !ok {
+				return schemaError("pack", "field %s expects a number, got %s", f.name, orderTypeName(value))
+			}
+			x = float64(n)
+		}
+		funbit.AddFloat(builder, x, f.options()...)
+	case "binary", "bits":
+		bits, err := binaryArgument("pack", value)
+		if err != nil {
+			return err
+		}
+		if f.kind == "binary" && bits.Len()%8 != 0 {
+			return schemaError("pack", "field %s expects whole bytes, got %d bits", f.name, bits.Len())
+		}
+		size := uint(bits.Len())
+		if f.kind == "binary" {
+			size /= 8
+		}
+		if f.sized && size != f.size {
+			return schemaError("pack", "field %s expects %d %s, got %d", f.name, f.size, map[bool]string{true: "bytes", false: "bits"}[f.kind == "binary"], size)
+		}
+		funbit.AddBitstring(builder, bits.Bits())
+	default:
+		text, isText := value.(string)
+		if !isText {
+			codepoint, ok := toInt64(value)
+			if !ok {
+				return schemaError("pack", "field %s expects a character, got %s", f.name, orderTypeName(value))
+			}
+			text = string(rune(codepoint))
+		}
+		if len([]rune(text)) != 1 {
+			return schemaError("pack", "field %s expects one character, got %q", f.name, text)
+		}
+		switch f.kind {
+		case "utf8":
+			funbit.AddUTF8(builder, text)
+		case "utf16":
+			funbit.AddUTF16(builder, text, funbit.WithEndianness(f.endianness))
+		default:
+			funbit.AddUTF32(builder, text, funbit.WithEndianness(f.endianness))
+		}
+	}
+	return nil
+}
+
+// schemaInteger converts integer values, including big integers, to a big.Int
+func schemaInteger(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, true
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, false
+		}
+	case bool:
+		return nil, false
+	}
+	n, ok := toInt64(value)
+	if !ok {
+		return nil, false
+	}
+	return big.NewInt(n), true
+}
+
+// executeUnpackFunction implements unpack(schema, bits), returning an object
+// of field values. The fields must cover the bits exactly; a final binary or
+// bits field without a size takes whatever is left.
+func (e *ExecutionEngine) executeUnpackFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.NewUserError("SCHEMA_ERROR", "unpack() requires a schema and a bitstring")
+	}
+	fields, err := parseSchema("unpack", args[0])
+	if err != nil {
+		return nil, err
+	}
+	data, err := binaryArgument("unpack", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	bytes := data.Bytes()
+	total := data.Len()
+	offset := 0
+	result := make(map[string]interface{}, len(fields))
+	for i, field := range fields {
+		available := total - offset
+		var size int
+		switch field.kind {
+		case "integer", "float", "bits":
+			size = int(field.size)
+		case "binary":
+			size = int(field.size) * 8
+		case "utf8":
+			size = utfSegmentSize("utf8", bytes, offset, available)
+		case "utf16":
+			if field.endianness == "little" && available >= 16 && offset%8 == 0 {
+				// A little-endian high surrogate has its top byte second
+				size = 16
+				if unit := int(bytes[offset/8+1]); unit >= 0xD8 && unit < 0xDC {
+					size = 32
+				}
+			} else {
+				size = utfSegmentSize("utf16", bytes, offset, available)
+			}
+		case "utf32":
+			size = 32
+		}
+		if (field.kind == "binary" || field.kind == "bits") && !field.sized {
+			if i != len(fields)-1 {
+				return nil, schemaError("unpack", "field %s has no size; only the last field can take the rest", field.name)
+			}
+			size = available
+			if field.kind == "binary" && size%8 != 0 {
+				return nil, schemaError("unpack", "field %s takes the rest as whole bytes, but %d bits remain", field.name, size)
+			}
+		}
+		if size > available {
+			return nil, schemaError("unpack", "field %s needs %d bits at bit %d, only %d left", field.name, size, offset, available)
+		}
+
+		value, err := field.read(data, bytes, offset, size)
+		if err != nil {
+			return nil, err
+		}
+		if field.value != nil {
+			if cmp, err := compareOrdered(value, field.value); err != nil || cmp != 0 {
+				return nil, schemaError("unpack", "field %s is %s, expected %s", field.name, shared.FormatValueForDisplay(value), shared.FormatValueForDisplay(field.value))
+			}
+		}
+		result[field.name] = value
+		offset += size
+	}
+	if offset < total {
+		return nil, schemaError("unpack", "the schema covers %d bits, but the data has %d", offset, total)
+	}
+	return result, nil
+}
+
+// read decodes size bits at offset
+func (f schemaField) read(data *shared.BitstringObject, bytes []byte, offset, size int) (interface{}, error) {
+	little := f.endianness == "little"
+	if little && size%8 != 0 && f.kind != "binary" && f.kind != "bits" {
+		return nil, schemaError("unpack", "field %s: little-endian fields must be whole bytes, got %d bits", f.name, size)
+	}
+	switch f.kind {
+	case "integer":
+		return compactBigInt(readSegmentInteger(bytes, offset, size, little, f.signed)), nil
+	case "float":
+		bits := readSegmentInteger(bytes, offset, size, little, false).Uint64()
+		switch size {
+		case 16:
+			return shared.Float16ToFloat64(uint16(bits)), nil
+		case 32:
+			return float64(math.Float32frombits(uint32(bits))), nil
+		}
+		return math.Float64frombits(bits), nil
+	case "binary", "bits":
+		return data.Slice(uint(offset), uint(size))
+	}
+	codepoint := readSegmentInteger(bytes, offset, size, little, false).Int64()
+	switch f.kind {
+	case "utf8":
+		raw := make([]byte, size/8)
+		for i := range raw {
+			raw[i] = byte(readSegmentInteger(bytes, offset+i*8, 8, false, false).Int64())
+		}
+		return string(raw), nil
+	case "utf16":
+		if size == 32 {
+			high := readSegmentInteger(bytes, offset, 16, little, false).Int64()
+			low := readSegmentInteger(bytes, offset+16, 16, little, false).Int64()
+			codepoint = (high-0xD800)<<10 + (low - 0xDC00) + 0x10000
+		}
+	}
+	return string(rune(codepoint)), nil
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"funterm/errors"
+)
+
+// luaSeedTemplate replaces math.random with a Park-Miller generator: gopher-lua's
+// math.randomseed delegates to rand.Seed, which is a no-op in current Go releases
+const luaSeedTemplate = `do
+  local state
+  local function next()
+    state = (state * 16807) %% 2147483647
+    return (state - 1) / 2147483646
+  end
+  math.randomseed = function(s)
+    state = (math.floor(s) * 48271 + 12345) %% 2147483646 + 1
+    for _ = 1, 4 do next() end
+  end
+  math.random = function(m, n)
+    local r = next()
+    if m == nil then return r end
+    if n == nil then m, n = 1, m end
+    return m + math.floor(r * (n - m + 1))
+  end
+  math.randomseed(%d)
+end`
+
+// seedCode returns the snippet that seeds a runtime's random generators.
+// Node has no seedable Math.random, so it is replaced by a mulberry32 generator.
+func seedCode(language string, seed int64) (string, bool) {
+	switch language {
+	case "python":
+		return fmt.Sprintf(`import random
+random.seed(%d)
+try:
+    import numpy
+    numpy.random.seed(%d %% (2**32))
+except ImportError:
+    pass`, seed, seed), true
+	case "lua":
+		return fmt.Sprintf(luaSeedTemplate, seed), true
+	case "node":
+		return fmt.Sprintf("void (Math.random = (function (a) { return function () { a |= 0; a = a + 0x6D2B79F5 | 0; var t = Math.imul(a ^ a >>> 15, 1 | a); t = t + Math.imul(t ^ t >>> 7, 61 | t) ^ t; return ((t ^ t >>> 14) >>> 0) / 4294967296; }; })(%d))", uint32(seed)), true
+	}
+	return "", false
+}
+
+// randomSource is the generator behind random() and gen.*. An engine shares
+// it with its background copies and sessions, which may draw from it at the
+// same time, so it is guarded by a mutex; set_seed replaces what it draws from
+type randomSource struct {
+	mu     sync.Mutex
+	source rand.Source64
+}
+
+// newRandomSource creates a generator seeded with seed
+func newRandomSource(seed int64) *randomSource {
+	return &randomSource{source: rand.NewSource(seed).(rand.Source64)}
+}
+
+// Int63, Uint64 and Seed make randomSource a rand.Source64
+func (s *randomSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source.Int63()
+}
+
+func (s *randomSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source.Uint64()
+}
+
+func (s *randomSource) Seed(seed int64) {
+	s.swap(rand.NewSource(seed).(rand.Source64))
+}
+
+// swap makes the generator draw from source and returns what it drew from
+func (s *randomSource) swap(source rand.Source64) rand.Source64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.source
+	s.source = source
+	return previous
+}
+
+// random returns a generator drawing from the engine's source. A rand.Rand
+// keeps state of its own, so each caller gets a fresh one
+func (e *ExecutionEngine) random() *rand.Rand {
+	return rand.New(e.rng)
+}
+
+// executeSetSeedFunction seeds the engine's random() and every ready runtime: set_seed(n)
+func (e *ExecutionEngine) executeSetSeedFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("SET_SEED_ARGUMENT_ERROR", "set_seed() requires exactly one argument")
+	}
+	seed, ok := toInt64(args[0])
+	if !ok {
+		return nil, errors.NewUserError("SET_SEED_ARGUMENT_ERROR", fmt.Sprintf("set_seed() seed must be a number, got %T", args[0]))
+	}
+
+	e.rng.Seed(seed)
+
+	for _, rt := range e.runtimeManager.GetAllRuntimes() {
+		if !rt.IsReady() {
+			continue
+		}
+		code, ok := seedCode(rt.GetName(), seed)
+		if !ok {
+			continue
+		}
+		if e.verbose {
+			fmt.Printf("DEBUG: set_seed - seeding %s runtime with %d\n", rt.GetName(), seed)
+		}
+		if _, err := rt.Eval(code); err != nil {
+			return nil, errors.NewRuntimeError(rt.GetName(), "SET_SEED_FAILED", fmt.Sprintf("failed to seed %s runtime: %v", rt.GetName(), err))
+		}
+	}
+
+	return nil, nil
+}
+
+// executeRandomFunction returns random() in [0, 1), random(n) in [0, n) or random(a, b) in [a, b]
+func (e *ExecutionEngine) executeRandomFunction(args []interface{}) (interface{}, error) {
+	switch len(args) {
+	case 0:
+		return e.random().Float64(), nil
+	case 1:
+		n, ok := toInt64(args[0])
+		if !ok || n <= 0 {
+			return nil, errors.NewUserError("RANDOM_ARGUMENT_ERROR", fmt.Sprintf("random(n) requires a positive number, got %v", args[0]))
+		}
+		return e.random().Int63n(n), nil
+	case 2:
+		low, okLow := toInt64(args[0])
+		high, okHigh := toInt64(args[1])
+		if !okLow || !okHigh {
+			return nil, errors.NewUserError("RANDOM_ARGUMENT_ERROR", "random(a, b) requires two numbers")
+		}
+		if low > high {
+			return nil, errors.NewUserError("RANDOM_ARGUMENT_ERROR", fmt.Sprintf("random(a, b) requires a <= b, got %d > %d", low, high))
+		}
+		// b - a + 1 must fit into an int64
+		span := high - low
+		if span < 0 || span == math.MaxInt64 {
+			return nil, errors.NewUserError("RANDOM_ARGUMENT_ERROR", fmt.Sprintf("random(a, b) range %d to %d is too large", low, high))
+		}
+		return low + e.random().Int63n(span+1), nil
+	}
+	return nil, errors.NewUserError("RANDOM_ARGUMENT_ERROR", "random() accepts at most two arguments")
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"syscall"
+)
+
+// scriptSignals are the signals on_signal can handle, by name without SIG.
+// Windows delivers only Ctrl+C (SIGINT) and closing the console (SIGTERM)
+var scriptSignals = map[string]os.Signal{"INT": os.Interrupt, "TERM": syscall.SIGTERM}
+
+// terminatingSignals stop the script after their handlers ran
+var terminatingSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"fmt"
+
+	"funterm/errors"
+	"funterm/shared"
+	"go-parser/pkg/ast"
+
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// bitstringUsesSizeOf reports whether a segment of the expression computes
+// its value with size_of()
+func bitstringUsesSizeOf(expr *ast.BitstringExpression) bool {
+	for i := range expr.Segments {
+		if usesSizeOf(expr.Segments[i].Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesSizeOf looks for a size_of() call in a segment value
+func usesSizeOf(expr ast.Expression) bool {
+	switch v := expr.(type) {
+	case *ast.BuiltinFunctionCall:
+		if v.Function == "size_of" {
+			return true
+		}
+		for _, arg := range v.Arguments {
+			if usesSizeOf(arg) {
+				return true
+			}
+		}
+	case *ast.BinaryExpression:
+		return usesSizeOf(v.Left) || usesSizeOf(v.Right)
+	case *ast.UnaryExpression:
+		return usesSizeOf(v.Right)
+	case *ast.TernaryExpression:
+		return usesSizeOf(v.Condition) || usesSizeOf(v.TrueExpr) || usesSizeOf(v.FalseExpr)
+	case *ast.NestedExpression:
+		return usesSizeOf(v.Inner)
+	}
+	return false
+}
+
+// segmentName is the name size_of() refers to a segment by: the variable it
+// is built from
+func segmentName(expr ast.Expression) (string, bool) {
+	id, ok := expr.(*ast.Identifier)
+	if !ok || id.Name == "_" {
+		return "", false
+	}
+	if id.Qualified {
+		return id.Language + "." + id.Name, true
+	}
+	return id.Name, true
+}
+
+// buildWithSizeOf builds a bitstring in two passes: every segment that does
+// not use size_of() is built first, so that length fields placed before the
+// data they describe can be computed from it, e.g. <<size_of(body):16, body/binary>>
+func (fa *FunbitAdapter) buildWithSizeOf(expr *ast.BitstringExpression) (*shared.BitstringObject, error) {
+	built := make([]*funbit.BitString, len(expr.Segments))
+	sizes := map[string]int{}
+	for i := range expr.Segments {
+		segment := &expr.Segments[i]
+		if usesSizeOf(segment.Value) {
+			continue
+		}
+		builder := funbit.NewBuilder()
+		if _, err := fa.addSegment(builder, segment); err != nil {
+			return nil, errors.NewUserErrorWithASTPos("BITSTRING_SEGMENT_ERROR", fmt.Sprintf("failed to add segment: %v", err), expr.Position())
+		}
+		bits, err := funbit.Build(builder)
+		if err != nil {
+			return nil, errors.NewUserError("BITSTRING_BUILD_ERROR", fmt.Sprintf("failed to build bitstring: %v", err))
+		}
+		built[i] = bits
+		if name, ok := segmentName(segment.Value); ok {
+			sizes[name] += int(bits.Length())
+		}
+	}
+
+	// Nested bitstrings have their own segments
+	outer := fa.engine.segmentSizes
+	fa.engine.segmentSizes = sizes
+	defer func() { fa.engine.segmentSizes = outer }()
+
+	builder := funbit.NewBuilder()
+	for i := range expr.Segments {
+		if built[i] != nil {
+			// funbit rejects empty segments
+			if built[i].Length() > 0 {
+				funbit.AddBitstring(builder, built[i])
+			}
+			continue
+		}
+		if _, err := fa.addSegment(builder, &expr.Segments[i]); err != nil {
+			return nil, errors.NewUserErrorWithASTPos("BITSTRING_SEGMENT_ERROR", fmt.Sprintf("failed to add segment: %v", err), expr.Position())
+		}
+	}
+	bitstring, err := funbit.Build(builder)
+	if err != nil {
+		return nil, errors.NewUserError("BITSTRING_BUILD_ERROR", fmt.Sprintf("failed to build bitstring: %v", err))
+	}
+	return shared.NewBitstringObject(bitstring), nil
+}
+
+// executeSizeOfFunction implements size_of(name): the size in bytes of the
+// segments built from the variable name in the bitstring under construction
+func (e *ExecutionEngine) executeSizeOfFunction(call *ast.BuiltinFunctionCall) (interface{}, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.NewUserErrorWithASTPos("SIZE_OF_ERROR", "size_of() requires one segment variable", call.Position())
+	}
+	name, ok := segmentName(call.Arguments[0])
+	if !ok {
+		return nil, errors.NewUserErrorWithASTPos("SIZE_OF_ERROR", "size_of() takes the variable a segment is built from, e.g. size_of(body)", call.Position())
+	}
+	if e.segmentSizes == nil {
+		return nil, errors.NewUserErrorWithASTPos("SIZE_OF_ERROR", fmt.Sprintf("size_of(%s) can only be used in a segment of a bitstring construction", name), call.Position())
+	}
+	bits, found := e.segmentSizes[name]
+	if !found {
+		return nil, errors.NewUserErrorWithASTPos("SIZE_OF_ERROR", fmt.Sprintf("size_of(%s): no segment of this bitstring is built from %s", name, name), call.Position())
+	}
+	if bits%8 != 0 {
+		return nil, errors.NewUserErrorWithASTPos("SIZE_OF_ERROR", fmt.Sprintf("size_of(%s): the segment is %s, not whole bytes", name, countBits(bits)), call.Position())
+	}
+	return int64(bits / 8), nil
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"funterm/runtime"
+	sharedparser "go-parser/pkg/shared"
+)
+
+// Состояние движка, к которому обращаются из нескольких горутин: фоновые задачи,
+// вызовы с !nowait и сессии (NewSession). Каждое хранилище защищено своей
+// блокировкой и держит ее только на время работы с картой, а не на время
+// вызовов рантаймов
+
+// undoLogSize is how many assignments of globals :undo can revert
+const undoLogSize = 100
+
+// globalStore holds the unqualified global variables visible to all runtimes,
+// together with the values last synchronized into the runtimes. Pipeline
+// stages and handlers share it, so reads take no lock: a lookup, e.g. while
+// matching, neither waits for an assignment nor for the other readers. The
+// stored VariableInfo is never changed, an assignment stores a new one
+type globalStore struct {
+	vars sync.Map // name -> *sharedparser.VariableInfo
+	// mu orders the assignments, which also keep the undo log
+	mu sync.Mutex
+	// undoLog holds the previous state of the last assigned globals, oldest first
+	undoLog []undoEntry
+
+	syncMu sync.Mutex
+	synced map[string]interface{} // name -> value last set in the runtimes
+}
+
+func newGlobalStore() *globalStore {
+	return &globalStore{synced: make(map[string]interface{})}
+}
+
+// undoEntry is the state of a global before an assignment
+type undoEntry struct {
+	name     string
+	previous *sharedparser.VariableInfo // nil if the assignment created the global
+}
+
+// set stores a global and invalidates its synchronized value
+func (g *globalStore) set(name string, value interface{}, isMutable bool) {
+	g.mu.Lock()
+	if len(g.undoLog) == undoLogSize {
+		copy(g.undoLog, g.undoLog[1:])
+		g.undoLog = g.undoLog[:undoLogSize-1]
+	}
+	previous, _ := g.get(name)
+	g.undoLog = append(g.undoLog, undoEntry{name: name, previous: previous})
+	g.vars.Store(name, &sharedparser.VariableInfo{Value: value, IsMutable: isMutable})
+	g.mu.Unlock()
+
+	g.invalidate(name)
+}
+
+// invalidate forgets the synchronized value of a global, so the next sync sets it again
+func (g *globalStore) invalidate(name string) {
+	g.syncMu.Lock()
+	delete(g.synced, name)
+	g.syncMu.Unlock()
+}
+
+// undo reverts the last n assignments, newest first
+func (g *globalStore) undo(n int) []UndoneAssignment {
+	g.mu.Lock()
+	if n > len(g.undoLog) {
+		n = len(g.undoLog)
+	}
+	undone := make([]UndoneAssignment, 0, n)
+	for i := 0; i < n; i++ {
+		entry := g.undoLog[len(g.undoLog)-1]
+		g.undoLog = g.undoLog[:len(g.undoLog)-1]
+		if entry.previous == nil {
+			g.vars.Delete(entry.name)
+		} else {
+			g.vars.Store(entry.name, entry.previous)
+		}
+		undone = append(undone, UndoneAssignment{Name: entry.name, Removed: entry.previous == nil})
+	}
+	g.mu.Unlock()
+
+	for _, assignment := range undone {
+		g.invalidate(assignment.Name)
+	}
+	return undone
+}
+
+// get returns the variable info of a global
+func (g *globalStore) get(name string) (*sharedparser.VariableInfo, bool) {
+	varInfo, found := g.vars.Load(name)
+	if !found {
+		return nil, false
+	}
+	return varInfo.(*sharedparser.VariableInfo), true
+}
+
+// values returns a copy of all global values
+func (g *globalStore) values() map[string]interface{} {
+	result := make(map[string]interface{})
+	g.vars.Range(func(name, varInfo interface{}) bool {
+		result[name.(string)] = varInfo.(*sharedparser.VariableInfo).Value
+		return true
+	})
+	return result
+}
+
+// lookup returns the values of the given globals that exist
+func (g *globalStore) lookup(names []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if varInfo, found := g.get(name); found {
+			result[name] = varInfo.Value
+		}
+	}
+	return result
+}
+
+// clone copies the globals for an engine that must not see later changes
+func (g *globalStore) clone() *globalStore {
+	clone := newGlobalStore()
+	// Under mu the copy is a state some assignment left, not one in between
+	g.mu.Lock()
+	g.vars.Range(func(name, varInfo interface{}) bool {
+		copied := *varInfo.(*sharedparser.VariableInfo)
+		clone.vars.Store(name, &copied)
+		return true
+	})
+	g.mu.Unlock()
+
+	g.syncMu.Lock()
+	for name, value := range g.synced {
+		clone.synced[name] = value
+	}
+	g.syncMu.Unlock()
+	return clone
+}
+
+// syncTo sets the globals that changed since the last synchronization in rt
+func (g *globalStore) syncTo(rt runtime.LanguageRuntime, verbose bool) {
+	globals := g.values()
+
+	// Используем кэш для отслеживания изменений и избегаем повторной синхронизации
+	g.syncMu.Lock()
+	defer g.syncMu.Unlock()
+
+	// Счетчик синхронизированных переменных для debug
+	var syncCount int
+
+	for name, value := range globals {
+		// Проверяем, изменилась ли переменная или это новая переменная
+		lastValue, exists := g.synced[name]
+		if exists && reflect.DeepEqual(lastValue, value) {
+			// Переменная не изменилась, пропускаем
+			if verbose {
+				fmt.Printf("DEBUG: Skipping sync for unchanged global variable '%s'\n", name)
+			}
+			continue
+		}
+
+		// Переменная изменилась или это новая переменная - синхронизируем
+		if err := rt.SetVariable(name, value); err != nil {
+			if verbose {
+				fmt.Printf("DEBUG: Warning - failed to sync global variable '%s' to runtime: %v\n", name, err)
+			}
+			// Continue with other variables even if one fails
+		} else {
+			syncCount++
+			if verbose {
+				fmt.Printf("DEBUG: Synced global variable '%s' = %v to runtime\n", name, value)
+			}
+		}
+
+		// Обновляем кэш
+		g.synced[name] = value
+	}
+
+	// Удаляем переменные из кэша если их больше нет в globals
+	for name := range g.synced {
+		if _, exists := globals[name]; !exists {
+			delete(g.synced, name)
+			if verbose {
+				fmt.Printf("DEBUG: Removed deleted global variable '%s' from sync cache\n", name)
+			}
+		}
+	}
+
+	if verbose && syncCount == 0 && len(globals) > 0 {
+		fmt.Printf("DEBUG: All %d global variables already synced (no changes detected)\n", len(globals))
+	}
+}
+
+// runtimeCache maps languages to their runtime instances. Engine copies share
+// it, so a runtime is created once per language no matter which session or
+// background task asks for it first
+type runtimeCache struct {
+	mu      sync.Mutex
+	entries map[string]*runtimeEntry
+}
+
+// runtimeEntry is a runtime that is created or being created; ready is closed
+// once runtime or err is set
+type runtimeEntry struct {
+	ready   chan struct{}
+	runtime runtime.LanguageRuntime
+	err     error
+}
+
+func newRuntimeCache() *runtimeCache {
+	return &runtimeCache{entries: make(map[string]*runtimeEntry)}
+}
+
+// get returns the runtime of language, calling create if there is none yet.
+// Only callers asking for the same language wait for its initialization, so
+// a slow runtime start does not block the others. A failed creation is not
+// cached and is retried by the next call; a panic in create is returned as
+// its error
+func (c *runtimeCache) get(language string, create func() (runtime.LanguageRuntime, error)) (runtime.LanguageRuntime, bool, error) {
+	c.mu.Lock()
+	entry, exists := c.entries[language]
+	if !exists {
+		entry = &runtimeEntry{ready: make(chan struct{})}
+		c.entries[language] = entry
+	}
+	c.mu.Unlock()
+
+	if exists {
+		<-entry.ready
+		return entry.runtime, true, entry.err
+	}
+
+	c.create(language, entry, create)
+	return entry.runtime, false, entry.err
+}
+
+// create fills entry by calling create. The callers waiting for the entry
+// are released even if create panics, and a failed entry is removed
+func (c *runtimeCache) create(language string, entry *runtimeEntry, create func() (runtime.LanguageRuntime, error)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			entry.runtime, entry.err = nil, fmt.Errorf("creating the %s runtime panicked: %v", language, recovered)
+		}
+		if entry.err != nil {
+			c.mu.Lock()
+			delete(c.entries, language)
+			c.mu.Unlock()
+		}
+		close(entry.ready)
+	}()
+	entry.runtime, entry.err = create()
+}
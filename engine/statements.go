@@ -0,0 +1,63 @@
+package engine
+
+import (
+	goerrors "errors"
+	"fmt"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// labelStatementError starts the message of an error in a command of several
+// statements with the index of the failing one among those on its line, as
+// in "statement 2 on line 1: ...", when the line holds more than one. The
+// failing statement is the one holding the last statement the engine started
+func (e *ExecutionEngine) labelStatementError(statement ast.Statement, err error) error {
+	block, ok := statement.(*ast.BlockStatement)
+	if !ok || e.current == nil {
+		return err
+	}
+	var execErr *errors.ExecutionError
+	if goerrors.As(err, &execErr) {
+		if _, labelled := execErr.Context["statement"]; labelled {
+			return err
+		}
+	}
+
+	// The failing statement is the last one starting before the current one
+	pos := e.current.Position()
+	failing := -1
+	for i, stmt := range block.Statements {
+		start := stmt.Position()
+		if start.Line > pos.Line || (start.Line == pos.Line && start.Column > pos.Column) {
+			break
+		}
+		failing = i
+	}
+	if failing < 0 {
+		return err
+	}
+
+	line := block.Statements[failing].Position().Line
+	index, count := 0, 0
+	for i, stmt := range block.Statements {
+		if stmt.Position().Line != line {
+			continue
+		}
+		count++
+		if i <= failing {
+			index = count
+		}
+	}
+	if count < 2 {
+		return err
+	}
+
+	label := fmt.Sprintf("statement %d on line %d", index, line)
+	if execErr != nil && execErr == err {
+		execErr.Message = label + ": " + execErr.Message
+		execErr.WithContext("statement", index)
+		return err
+	}
+	return fmt.Errorf("%s: %w", label, err)
+}
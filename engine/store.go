@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"funterm/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// storeBucket holds the values of store.put() in the database
+var storeBucket = []byte("values")
+
+// storeTimeout bounds the wait for another funterm using the database
+const storeTimeout = 5 * time.Second
+
+// SetStorePath sets the database of store.put() and store.get(); empty
+// turns the store off
+func (e *ExecutionEngine) SetStorePath(path string) {
+	e.storePath = path
+}
+
+// callStoreFunction implements store.put(key, value), store.get(key,
+// default?), store.delete(key) and store.keys(prefix?). Values are kept with
+// their type, as cache_result() keeps them. The database is opened for each
+// call only, so several sessions can use it one after the other
+func (e *ExecutionEngine) callStoreFunction(function string, args []interface{}) (interface{}, error) {
+	name := "store." + function
+	if e.storePath == "" {
+		return nil, storeError("%s(): the store is turned off (engine.store is empty)", name)
+	}
+	switch function {
+	case "put":
+		if len(args) != 2 {
+			return nil, storeError("%s() requires a key and a value", name)
+		}
+		key, err := storeKey(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeCachedValue(args[1])
+		if err != nil {
+			return nil, storeError("%s(): %v", name, err)
+		}
+		data, err := json.Marshal(encoded)
+		if err != nil {
+			return nil, storeError("%s(): %v", name, err)
+		}
+		return nil, e.updateStore(name, func(bucket *bolt.Bucket) error {
+			return bucket.Put([]byte(key), data)
+		})
+	case "get":
+		if len(args) < 1 || len(args) > 2 {
+			return nil, storeError("%s() requires a key and an optional default", name)
+		}
+		key, err := storeKey(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		if err := e.viewStore(name, func(bucket *bolt.Bucket) error {
+			data = append([]byte(nil), bucket.Get([]byte(key))...)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return nil, nil
+		}
+		var cached cachedValue
+		if err := json.Unmarshal(data, &cached); err != nil {
+			return nil, storeError("%s(): the value of '%s' is damaged: %v", name, key, err)
+		}
+		value, err := decodeCachedValue(cached)
+		if err != nil {
+			return nil, storeError("%s(): the value of '%s' is damaged: %v", name, key, err)
+		}
+		return value, nil
+	case "delete":
+		if len(args) != 1 {
+			return nil, storeError("%s() requires a key", name)
+		}
+		key, err := storeKey(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		existed := false
+		err = e.updateStore(name, func(bucket *bolt.Bucket) error {
+			existed = bucket.Get([]byte(key)) != nil
+			return bucket.Delete([]byte(key))
+		})
+		return existed, err
+	case "keys":
+		if len(args) > 1 {
+			return nil, storeError("%s() takes an optional prefix", name)
+		}
+		prefix := ""
+		if len(args) == 1 {
+			text, ok := args[0].(string)
+			if !ok {
+				return nil, storeError("%s() prefix must be a string, got %s", name, orderTypeName(args[0]))
+			}
+			prefix = text
+		}
+		keys := []interface{}{}
+		err := e.viewStore(name, func(bucket *bolt.Bucket) error {
+			// Keys are kept sorted, so the prefix is a range
+			cursor := bucket.Cursor()
+			for key, _ := cursor.Seek([]byte(prefix)); key != nil && strings.HasPrefix(string(key), prefix); key, _ = cursor.Next() {
+				keys = append(keys, string(key))
+			}
+			return nil
+		})
+		return keys, err
+	}
+	return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("store has no function '%s'; use put, get, delete or keys", function))
+}
+
+// storeKey checks the key argument of a store function
+func storeKey(name string, arg interface{}) (string, error) {
+	key, ok := arg.(string)
+	if !ok || key == "" {
+		return "", storeError("%s() key must be a non-empty string, got %s", name, orderTypeName(arg))
+	}
+	return key, nil
+}
+
+// viewStore reads the store in a transaction; a store that doesn't exist
+// yet reads as empty
+func (e *ExecutionEngine) viewStore(name string, read func(*bolt.Bucket) error) error {
+	if _, err := os.Stat(e.storePath); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := e.openStore(name, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(storeBucket)
+		if bucket == nil {
+			return nil
+		}
+		return read(bucket)
+	})
+}
+
+// updateStore changes the store in a transaction, creating the database
+func (e *ExecutionEngine) updateStore(name string, write func(*bolt.Bucket) error) error {
+	if err := os.MkdirAll(filepath.Dir(e.storePath), 0700); err != nil {
+		return storeError("%s(): %v", name, err)
+	}
+	db, err := e.openStore(name, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(storeBucket)
+		if err != nil {
+			return err
+		}
+		return write(bucket)
+	}); err != nil {
+		return storeError("%s(): %v", name, err)
+	}
+	return nil
+}
+
+// openStore opens the database, waiting up to storeTimeout for a session
+// that is writing to it
+func (e *ExecutionEngine) openStore(name string, readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(e.storePath, 0600, &bolt.Options{Timeout: storeTimeout, ReadOnly: readOnly})
+	if err == bolt.ErrTimeout {
+		return nil, storeError("%s(): %s is busy, another funterm keeps it open", name, e.storePath)
+	}
+	if err != nil {
+		return nil, storeError("%s(): cannot open %s: %v", name, e.storePath, err)
+	}
+	return db, nil
+}
+
+func storeError(format string, args ...interface{}) error {
+	return errors.NewUserError("STORE_ERROR", fmt.Sprintf(format, args...))
+}
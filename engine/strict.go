@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// Rules of strict mode, each naming a coercion the engine does silently
+const (
+	// StrictFloatTruncation: a fractional segment size is truncated, <<v:7.9>> has 7 bits
+	StrictFloatTruncation = "float_truncation"
+	// StrictNegativeSizes: a negative segment size wraps around to a huge unsigned one
+	StrictNegativeSizes = "negative_sizes"
+	// StrictUndefinedVariables: reading a variable that was never set gives nil
+	StrictUndefinedVariables = "undefined_variables"
+	// StrictAutovivification: assigning below a missing key or index creates
+	// the maps and arrays on the way, py.state.cache.users[3].name = "x"
+	StrictAutovivification = "autovivification"
+)
+
+// What a rule does with its coercion
+const (
+	StrictAllow = "allow" // coerce silently, the default
+	StrictWarn  = "warn"  // coerce and print a deprecation warning once per place
+	StrictError = "error" // fail, the default with engine.strict
+)
+
+var strictRules = []string{StrictFloatTruncation, StrictNegativeSizes, StrictUndefinedVariables, StrictAutovivification}
+
+// strictMode holds the action of each rule. It is shared by sessions and
+// background copies of the engine, so warnings are only shown once
+type strictMode struct {
+	actions map[string]string
+	mu      sync.Mutex
+	warned  map[string]bool
+}
+
+// SetStrictMode makes every rule an error when strict is set; rules then
+// changes single rules to "error", "warn" or "allow", so a script base can be
+// migrated one rule at a time
+func (e *ExecutionEngine) SetStrictMode(strict bool, rules map[string]string) error {
+	if err := ValidateStrictRules(rules); err != nil {
+		return err
+	}
+	actions := make(map[string]string, len(strictRules))
+	for _, rule := range strictRules {
+		actions[rule] = StrictAllow
+		if strict {
+			actions[rule] = StrictError
+		}
+		if action, ok := rules[rule]; ok {
+			actions[rule] = action
+		}
+	}
+	e.strict = nil
+	for _, action := range actions {
+		if action != StrictAllow {
+			e.strict = &strictMode{actions: actions, warned: make(map[string]bool)}
+			break
+		}
+	}
+	return nil
+}
+
+// ValidateStrictRules checks the rule names and actions of engine.strict_rules
+func ValidateStrictRules(rules map[string]string) error {
+	names := make([]string, 0, len(rules))
+	for rule := range rules {
+		names = append(names, rule)
+	}
+	sort.Strings(names)
+	for _, rule := range names {
+		known := false
+		for _, name := range strictRules {
+			known = known || name == rule
+		}
+		if !known {
+			return fmt.Errorf("unknown strict rule '%s', expected one of %s", rule, strings.Join(strictRules, ", "))
+		}
+		switch rules[rule] {
+		case StrictAllow, StrictWarn, StrictError:
+		default:
+			return fmt.Errorf("invalid action '%s' for strict rule %s, expected error, warn or allow", rules[rule], rule)
+		}
+	}
+	return nil
+}
+
+// StrictRules returns the action of each rule in effect
+func (e *ExecutionEngine) StrictRules() map[string]string {
+	actions := make(map[string]string, len(strictRules))
+	for _, rule := range strictRules {
+		actions[rule] = StrictAllow
+		if e.strict != nil {
+			actions[rule] = e.strict.actions[rule]
+		}
+	}
+	return actions
+}
+
+// coerce is called before a coercion of rule at pos. It returns nil when the
+// coercion may go on, after printing a deprecation warning if the rule warns,
+// or the error the rule asks for
+func (e *ExecutionEngine) coerce(rule string, pos ast.Position, format string, args ...interface{}) error {
+	if e.strict == nil {
+		return nil
+	}
+	message := fmt.Sprintf(format, args...)
+	switch e.strict.actions[rule] {
+	case StrictError:
+		return errors.NewUserErrorWithASTPos("STRICT_MODE_ERROR", fmt.Sprintf("%s (strict rule %s)", message, rule), pos)
+	case StrictWarn:
+		place := fmt.Sprintf("%s:%d:%d", rule, pos.Line, pos.Column)
+		e.strict.mu.Lock()
+		warned := e.strict.warned[place]
+		e.strict.warned[place] = true
+		e.strict.mu.Unlock()
+		if !warned {
+			fmt.Fprintf(os.Stderr, "Deprecation: line %d col %d: %s; strict rule %s will make this an error\n", pos.Line, pos.Column, message, rule)
+		}
+	}
+	return nil
+}
+
+// undefinedVariable is the value of a variable read that found nothing
+func (e *ExecutionEngine) undefinedVariable(name string, pos ast.Position) (interface{}, error) {
+	if err := e.coerce(StrictUndefinedVariables, pos, "undefined variable: %s", name); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
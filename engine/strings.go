@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"funterm/errors"
+)
+
+// stringMethodNames lists the methods available on string values
+const stringMethodNames = "upper, lower, trim, trim_start, trim_end, split, starts_with, ends_with, contains, replace, format, chars, codepoints, bytes"
+
+// callStringMethod implements s.method(args) for string values
+func (e *ExecutionEngine) callStringMethod(s, name string, args []interface{}) (interface{}, error) {
+	switch name {
+	case "upper", "lower", "trim_start", "trim_end":
+		if len(args) != 0 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", fmt.Sprintf("%s() takes no arguments", name))
+		}
+		switch name {
+		case "upper":
+			return strings.ToUpper(s), nil
+		case "lower":
+			return strings.ToLower(s), nil
+		case "trim_start":
+			return strings.TrimLeft(s, " \t\r\n"), nil
+		}
+		return strings.TrimRight(s, " \t\r\n"), nil
+	case "trim":
+		// trim() strips whitespace, trim(chars) strips the given characters
+		if len(args) > 1 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", "trim() accepts at most one argument")
+		}
+		if len(args) == 0 {
+			return strings.TrimSpace(s), nil
+		}
+		chars, err := stringArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Trim(s, chars), nil
+	case "split":
+		// split() splits on runs of whitespace, split(sep) on every separator
+		if len(args) > 1 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", "split() accepts at most one argument")
+		}
+		var parts []string
+		if len(args) == 0 {
+			parts = strings.Fields(s)
+		} else {
+			sep, err := stringArgument(name, args, 0)
+			if err != nil {
+				return nil, err
+			}
+			if sep == "" {
+				return nil, errors.NewUserError("STRING_METHOD_ERROR", "split() separator must not be empty")
+			}
+			parts = strings.Split(s, sep)
+		}
+		result := make([]interface{}, len(parts))
+		for i, part := range parts {
+			result[i] = part
+		}
+		return result, nil
+	case "starts_with", "ends_with", "contains":
+		if len(args) != 1 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", fmt.Sprintf("%s() requires exactly one argument", name))
+		}
+		sub, err := stringArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "starts_with":
+			return strings.HasPrefix(s, sub), nil
+		case "ends_with":
+			return strings.HasSuffix(s, sub), nil
+		}
+		return strings.Contains(s, sub), nil
+	case "replace":
+		if len(args) != 2 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", "replace() requires two arguments: old and new")
+		}
+		old, err := stringArgument(name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		replacement, err := stringArgument(name, args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(s, old, replacement), nil
+	case "format":
+		return e.formatString(s, args)
+	case "chars", "codepoints", "bytes":
+		if len(args) != 0 {
+			return nil, errors.NewUserError("STRING_METHOD_ERROR", fmt.Sprintf("%s() takes no arguments", name))
+		}
+		var result []interface{}
+		switch name {
+		case "chars":
+			// User-perceived characters, the units len() counts
+			for _, cluster := range graphemeClusters(s) {
+				result = append(result, cluster)
+			}
+		case "codepoints":
+			for _, r := range s {
+				result = append(result, int64(r))
+			}
+		default:
+			for i := 0; i < len(s); i++ {
+				result = append(result, int64(s[i]))
+			}
+		}
+		if result == nil {
+			result = []interface{}{}
+		}
+		return result, nil
+	default:
+		return nil, errors.NewUserError("UNKNOWN_METHOD", fmt.Sprintf("string has no method '%s' (available: %s)", name, stringMethodNames))
+	}
+}
+
+// stringArgument returns args[i] as a string or a user error naming the method
+func stringArgument(method string, args []interface{}, i int) (string, error) {
+	value, ok := args[i].(string)
+	if !ok {
+		return "", errors.NewUserError("STRING_METHOD_ERROR", fmt.Sprintf("%s() argument %d must be a string, got %s", method, i+1, orderTypeName(args[i])))
+	}
+	return value, nil
+}
+
+// formatString substitutes {} (next argument) and {N} (argument N) placeholders;
+// {{ and }} produce literal braces
+func (e *ExecutionEngine) formatString(template string, args []interface{}) (string, error) {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '{' && i+1 < len(template) && template[i+1] == '{':
+			b.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(template) && template[i+1] == '}':
+			b.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return "", errors.NewUserError("STRING_FORMAT_ERROR", fmt.Sprintf("unclosed '{' at position %d", i))
+			}
+			spec := template[i+1 : i+end]
+			index := next
+			if spec == "" {
+				next++
+			} else {
+				n, err := strconv.Atoi(spec)
+				if err != nil || n < 0 {
+					return "", errors.NewUserError("STRING_FORMAT_ERROR", fmt.Sprintf("invalid placeholder '{%s}', expected {} or {N}", spec))
+				}
+				index = n
+			}
+			if index >= len(args) {
+				return "", errors.NewUserError("STRING_FORMAT_ERROR", fmt.Sprintf("placeholder {%d} has no argument (%d given)", index, len(args)))
+			}
+			b.WriteString(e.convertToString(args[index]))
+			i += end
+		case c == '}':
+			return "", errors.NewUserError("STRING_FORMAT_ERROR", fmt.Sprintf("unmatched '}' at position %d", i))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// executeJoinFunction joins array elements with a separator: join(list, sep?)
+func (e *ExecutionEngine) executeJoinFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("JOIN_ARGUMENT_ERROR", "join() requires an array and an optional separator")
+	}
+	list, ok := args[0].([]interface{})
+	if !ok {
+		return nil, errors.NewUserError("JOIN_ARGUMENT_ERROR", fmt.Sprintf("join() expects an array, got %s", orderTypeName(args[0])))
+	}
+	sep := ""
+	if len(args) == 2 {
+		if sep, ok = args[1].(string); !ok {
+			return nil, errors.NewUserError("JOIN_ARGUMENT_ERROR", fmt.Sprintf("join() separator must be a string, got %s", orderTypeName(args[1])))
+		}
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = e.convertToString(item)
+	}
+	return strings.Join(parts, sep), nil
+}
@@ -0,0 +1,560 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// tableAggregates are the aggregate functions of table.group_by
+var tableAggregates = []string{"count", "sum", "avg", "min", "max", "first", "last", "list"}
+
+// tableOperators are the comparisons a table.filter condition can use
+var tableOperators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "in": true, "contains": true, "matches": true}
+
+// callTableFunction runs the table module, operations on arrays of objects
+// such as the rows a query returns: table.select(rows, columns),
+// table.filter(rows, where), table.group_by(rows, keys, aggregates?) and
+// table.join(left, right, on, how?)
+func callTableFunction(function string, args []interface{}) (interface{}, error) {
+	name := "table." + function
+	switch function {
+	case "select":
+		if len(args) != 2 {
+			return nil, tableError("%s() requires rows and columns", name)
+		}
+		rows, err := tableRows(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return tableSelect(name, rows, args[1])
+	case "filter":
+		if len(args) != 2 {
+			return nil, tableError("%s() requires rows and a condition object", name)
+		}
+		rows, err := tableRows(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return tableFilter(name, rows, args[1])
+	case "group_by":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, tableError("%s() requires rows, the key columns and optional aggregates", name)
+		}
+		rows, err := tableRows(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		var aggregates interface{}
+		if len(args) == 3 {
+			aggregates = args[2]
+		}
+		return tableGroupBy(name, rows, args[1], aggregates)
+	case "join":
+		if len(args) < 3 || len(args) > 4 {
+			return nil, tableError("%s() requires two tables, the join columns and an optional kind", name)
+		}
+		left, err := tableRows(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := tableRows(name, args[1])
+		if err != nil {
+			return nil, err
+		}
+		how := "inner"
+		if len(args) == 4 {
+			text, ok := args[3].(string)
+			if !ok {
+				return nil, tableError("%s() expects the kind of join as a string, got %s", name, orderTypeName(args[3]))
+			}
+			how = text
+		}
+		return tableJoin(name, left, right, args[2], how)
+	}
+	return nil, errors.NewUserError("UNKNOWN_FUNCTION", fmt.Sprintf("table has no function '%s'; use select, filter, group_by or join", function))
+}
+
+// tableSelect keeps the given columns of every row: an array of names, or an
+// object of new name to column for renaming. A column a row lacks is nil
+func tableSelect(name string, rows []map[string]interface{}, spec interface{}) (interface{}, error) {
+	var targets, sources []string
+	switch columns := spec.(type) {
+	case []interface{}:
+		for _, column := range columns {
+			text, ok := column.(string)
+			if !ok {
+				return nil, tableError("%s() expects column names as strings, got %s", name, orderTypeName(column))
+			}
+			targets, sources = append(targets, text), append(sources, text)
+		}
+	case map[string]interface{}:
+		for _, target := range sortedKeys(columns) {
+			text, ok := columns[target].(string)
+			if !ok {
+				return nil, tableError("%s() expects the column for '%s' as a string, got %s", name, target, orderTypeName(columns[target]))
+			}
+			targets, sources = append(targets, target), append(sources, text)
+		}
+	default:
+		return nil, tableError("%s() expects an array of column names or an object of new names, got %s", name, orderTypeName(spec))
+	}
+
+	result := make([]interface{}, len(rows))
+	for i, row := range rows {
+		selected := make(map[string]interface{}, len(targets))
+		for j, target := range targets {
+			selected[target] = row[sources[j]]
+		}
+		result[i] = selected
+	}
+	return result, nil
+}
+
+// tableFilter keeps the rows that meet every condition of where. A condition
+// is a value the column must equal, or an object of operators such as
+// {">=": 18, "<": 65}
+func tableFilter(name string, rows []map[string]interface{}, where interface{}) (interface{}, error) {
+	conditions, ok := where.(map[string]interface{})
+	if !ok {
+		return nil, tableError("%s() expects the condition as an object of column to value, got %s", name, orderTypeName(where))
+	}
+	columns := sortedKeys(conditions)
+	patterns := map[string]*regexp.Regexp{}
+	for _, column := range columns {
+		ops, ok := tableOperatorsOf(conditions[column])
+		if !ok {
+			continue
+		}
+		for op, operand := range ops {
+			if op != "matches" {
+				continue
+			}
+			text, ok := operand.(string)
+			if !ok {
+				return nil, tableError("%s(): matches expects a regular expression string, got %s", name, orderTypeName(operand))
+			}
+			re, err := regexp.Compile(text)
+			if err != nil {
+				return nil, tableError("%s(): invalid regular expression %q: %v", name, text, err)
+			}
+			patterns[text] = re
+		}
+	}
+
+	result := []interface{}{}
+	for _, row := range rows {
+		keep := true
+		for _, column := range columns {
+			value := row[column]
+			ops, isOps := tableOperatorsOf(conditions[column])
+			if !isOps {
+				keep = jsonEqual(value, conditions[column])
+			} else {
+				for op, operand := range ops {
+					met, err := tableCompare(name, value, op, operand, patterns)
+					if err != nil {
+						return nil, err
+					}
+					if !met {
+						keep = false
+						break
+					}
+				}
+			}
+			if !keep {
+				break
+			}
+		}
+		if keep {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+// tableOperatorsOf returns a condition that is an object of operators
+func tableOperatorsOf(condition interface{}) (map[string]interface{}, bool) {
+	ops, ok := condition.(map[string]interface{})
+	if !ok || len(ops) == 0 {
+		return nil, false
+	}
+	for op := range ops {
+		if !tableOperators[op] {
+			return nil, false
+		}
+	}
+	return ops, true
+}
+
+// tableCompare checks value op operand; ordering a missing column or values
+// of different kinds is false rather than an error
+func tableCompare(name string, value interface{}, op string, operand interface{}, patterns map[string]*regexp.Regexp) (bool, error) {
+	switch op {
+	case "==":
+		return jsonEqual(value, operand), nil
+	case "!=":
+		return !jsonEqual(value, operand), nil
+	case "in":
+		list, ok := operand.([]interface{})
+		if !ok {
+			return false, tableError("%s(): in expects an array, got %s", name, orderTypeName(operand))
+		}
+		for _, item := range list {
+			if jsonEqual(value, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		switch v := value.(type) {
+		case string:
+			text, ok := operand.(string)
+			return ok && strings.Contains(v, text), nil
+		case []interface{}:
+			for _, item := range v {
+				if jsonEqual(item, operand) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case "matches":
+		text, ok := value.(string)
+		return ok && patterns[operand.(string)].MatchString(text), nil
+	}
+
+	valueKind, okValue := orderKind(value)
+	operandKind, okOperand := orderKind(operand)
+	if !okOperand {
+		return false, tableError("%s(): %s needs a number, string or bool, got %s", name, op, orderTypeName(operand))
+	}
+	if !okValue || value == nil || valueKind != operandKind {
+		return false, nil
+	}
+	cmp, err := compareOrdered(value, operand)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// tableGroupBy groups rows by the values of the key columns, in the order
+// the groups first appear. Without aggregates a group has its key columns,
+// count and rows; with them, its key columns and one column per aggregate,
+// given as {"total": ["sum", "price"], "orders": "count"}
+func tableGroupBy(name string, rows []map[string]interface{}, keySpec, aggregateSpec interface{}) (interface{}, error) {
+	keys, err := tableColumns(name, "key columns", keySpec)
+	if err != nil {
+		return nil, err
+	}
+	type aggregate struct {
+		target, op, column string
+	}
+	var aggregates []aggregate
+	if aggregateSpec != nil {
+		spec, ok := aggregateSpec.(map[string]interface{})
+		if !ok {
+			return nil, tableError("%s() expects aggregates as an object such as {\"total\": [\"sum\", \"price\"]}, got %s", name, orderTypeName(aggregateSpec))
+		}
+		for _, target := range sortedKeys(spec) {
+			op, column := "", ""
+			switch v := spec[target].(type) {
+			case string:
+				op = v
+			case []interface{}:
+				if len(v) == 2 {
+					op, _ = v[0].(string)
+					column, _ = v[1].(string)
+				}
+			}
+			if op == "" || (op != "count" && column == "") {
+				return nil, tableError("%s(): aggregate '%s' must be \"count\" or [function, column]", name, target)
+			}
+			if !slices.Contains(tableAggregates, op) {
+				return nil, tableError("%s(): unknown aggregate function '%s', use %s", name, op, strings.Join(tableAggregates, ", "))
+			}
+			aggregates = append(aggregates, aggregate{target, op, column})
+		}
+	}
+
+	type group struct {
+		key  map[string]interface{}
+		rows []interface{}
+	}
+	var groups []*group
+	index := map[string]*group{}
+	for _, row := range rows {
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = row[key]
+		}
+		id := tableKey(values)
+		g, ok := index[id]
+		if !ok {
+			g = &group{key: make(map[string]interface{}, len(keys))}
+			for i, key := range keys {
+				g.key[key] = values[i]
+			}
+			index[id] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, row)
+	}
+
+	result := make([]interface{}, len(groups))
+	for i, g := range groups {
+		out := make(map[string]interface{}, len(keys)+len(aggregates)+2)
+		for key, value := range g.key {
+			out[key] = value
+		}
+		if aggregates == nil {
+			out["count"] = int64(len(g.rows))
+			out["rows"] = g.rows
+		}
+		for _, agg := range aggregates {
+			value, err := tableAggregate(name, agg.op, agg.column, g.rows)
+			if err != nil {
+				return nil, err
+			}
+			out[agg.target] = value
+		}
+		result[i] = out
+	}
+	return result, nil
+}
+
+// tableAggregate computes one aggregate over the column of rows; nil values
+// are skipped by sum, avg, min and max
+func tableAggregate(name, op, column string, rows []interface{}) (interface{}, error) {
+	if op == "count" {
+		return int64(len(rows)), nil
+	}
+	values := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		value := row.(map[string]interface{})[column]
+		if value == nil && op != "first" && op != "last" && op != "list" {
+			continue
+		}
+		values = append(values, value)
+	}
+
+	switch op {
+	case "first":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[0], nil
+	case "last":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[len(values)-1], nil
+	case "list":
+		return values, nil
+	case "min", "max":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		best := values[0]
+		for _, value := range values[1:] {
+			cmp, err := compareOrdered(value, best)
+			if err != nil {
+				return nil, err
+			}
+			if (op == "min" && cmp < 0) || (op == "max" && cmp > 0) {
+				best = value
+			}
+		}
+		return best, nil
+	}
+
+	// sum and avg: integers add up exactly, a float makes the sum a float
+	intSum, floatSum, isFloat := new(big.Int), 0.0, false
+	for _, value := range values {
+		if n, ok := toBigInt(value); ok {
+			intSum.Add(intSum, n)
+			continue
+		}
+		kind, ok := orderKind(value)
+		if !ok || kind != orderNumber {
+			return nil, tableError("%s(): %s of '%s' needs numbers, got %s", name, op, column, orderTypeName(value))
+		}
+		x := math.NaN()
+		if f, isNaN := exactNumber(value); !isNaN {
+			x, _ = f.Float64()
+		}
+		floatSum, isFloat = floatSum+x, true
+	}
+	if op == "avg" {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		total, _ := new(big.Float).SetInt(intSum).Float64()
+		return (total + floatSum) / float64(len(values)), nil
+	}
+	if isFloat {
+		total, _ := new(big.Float).SetInt(intSum).Float64()
+		return total + floatSum, nil
+	}
+	return compactBigInt(intSum), nil
+}
+
+// tableJoin joins the rows of left and right whose join columns are equal.
+// on is a column both tables have or [left column, right column]; how is
+// inner, left, right or outer. A joined row has the columns of both rows, a
+// column of right that left also has named with the suffix _right
+func tableJoin(name string, left, right []map[string]interface{}, on interface{}, how string) (interface{}, error) {
+	var leftKey, rightKey string
+	switch v := on.(type) {
+	case string:
+		leftKey, rightKey = v, v
+	case []interface{}:
+		if len(v) == 2 {
+			leftKey, _ = v[0].(string)
+			rightKey, _ = v[1].(string)
+		}
+	}
+	if leftKey == "" || rightKey == "" {
+		return nil, tableError("%s() expects the join column as a string or [left column, right column]", name)
+	}
+	switch how {
+	case "inner", "left", "right", "outer":
+	default:
+		return nil, tableError("%s(): unknown kind of join '%s', use inner, left, right or outer", name, how)
+	}
+
+	index := map[string][]int{}
+	for i, row := range right {
+		if value, ok := row[rightKey]; ok && value != nil {
+			id := tableKey([]interface{}{value})
+			index[id] = append(index[id], i)
+		}
+	}
+
+	merge := func(l, r map[string]interface{}) map[string]interface{} {
+		joined := make(map[string]interface{}, len(l)+len(r))
+		for key, value := range l {
+			joined[key] = value
+		}
+		for key, value := range r {
+			if _, taken := l[key]; taken {
+				if key == rightKey && leftKey == rightKey {
+					continue
+				}
+				key += "_right"
+			}
+			joined[key] = value
+		}
+		return joined
+	}
+
+	result := []interface{}{}
+	matched := make([]bool, len(right))
+	for _, l := range left {
+		var matches []int
+		if value, ok := l[leftKey]; ok && value != nil {
+			matches = index[tableKey([]interface{}{value})]
+		}
+		for _, i := range matches {
+			matched[i] = true
+			result = append(result, merge(l, right[i]))
+		}
+		if len(matches) == 0 && (how == "left" || how == "outer") {
+			result = append(result, merge(l, nil))
+		}
+	}
+	if how == "right" || how == "outer" {
+		for i, r := range right {
+			if !matched[i] {
+				result = append(result, merge(nil, r))
+			}
+		}
+	}
+	return result, nil
+}
+
+// tableRows checks that value is an array of objects
+func tableRows(name string, value interface{}) ([]map[string]interface{}, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, tableError("%s() expects an array of objects, got %s", name, orderTypeName(value))
+	}
+	rows := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		row, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, tableError("%s() expects an array of objects, element %d is %s", name, i, orderTypeName(item))
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// tableColumns reads a column name or an array of them
+func tableColumns(name, what string, spec interface{}) ([]string, error) {
+	switch v := spec.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		columns := make([]string, 0, len(v))
+		for _, column := range v {
+			text, ok := column.(string)
+			if !ok {
+				return nil, tableError("%s() expects %s as strings, got %s", name, what, orderTypeName(column))
+			}
+			columns = append(columns, text)
+		}
+		if len(columns) > 0 {
+			return columns, nil
+		}
+	}
+	return nil, tableError("%s() expects %s as a name or an array of names", name, what)
+}
+
+// tableKey identifies a combination of column values; numbers that are
+// equal, such as 1 and 1.0, give the same key
+func tableKey(values []interface{}) string {
+	var b strings.Builder
+	for _, value := range values {
+		if kind, ok := orderKind(value); ok && kind == orderNumber {
+			if f, isNaN := exactNumber(value); !isNaN {
+				b.WriteString("n:" + f.Text('g', -1))
+			} else {
+				b.WriteString("n:NaN")
+			}
+		} else {
+			fmt.Fprintf(&b, "%T:%s", value, shared.FormatValueForDisplay(value))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func tableError(format string, args ...interface{}) error {
+	return errors.NewUserError("TABLE_ARGUMENT_ERROR", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// handTerminal makes the program the foreground process group of the
+// terminal, so the keys it reads, Ctrl+C and SIGWINCH go to it and not to
+// funterm. The returned function makes funterm the foreground group again
+func handTerminal(cmd *exec.Cmd) func() {
+	fd := os.Stdin.Fd()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: int(fd)}
+	return func() {
+		// A background group changing the foreground group gets SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		defer signal.Reset(syscall.SIGTTOU)
+		pgrp := int32(syscall.Getpgrp())
+		syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	}
+}
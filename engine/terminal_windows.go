@@ -0,0 +1,23 @@
+//go:build windows
+
+package engine
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"funterm/runtime"
+)
+
+// handTerminal lets the program have Ctrl+C: the console sends it to every
+// process attached to it, so funterm ignores it until the program ends
+func handTerminal(cmd *exec.Cmd) func() {
+	interrupts := make(chan os.Signal, 1)
+	release := runtime.ClaimSignals(os.Interrupt)
+	signal.Notify(interrupts, os.Interrupt)
+	return func() {
+		signal.Stop(interrupts)
+		release()
+	}
+}
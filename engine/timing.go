@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"time"
+
+	"funterm/runtime"
+)
+
+// CommandTiming splits the run time of the last Execute call between parsing,
+// the engine itself and the language runtimes it called
+type CommandTiming struct {
+	Parse   time.Duration
+	Engine  time.Duration
+	Runtime time.Duration
+	// Calls are the runtime calls of the command with what they cost their
+	// interpreters, collected when SetCallStats is on
+	Calls []RuntimeCall
+}
+
+// RuntimeCall is one call into a runtime, e.g. python.load or "lua block"
+type RuntimeCall struct {
+	Name     string
+	Duration time.Duration
+	runtime.CallStats
+}
+
+// Total is the whole run time of the command
+func (t CommandTiming) Total() time.Duration {
+	return t.Parse + t.Engine + t.Runtime
+}
+
+// LastTiming returns the timing of the last Execute call
+func (e *ExecutionEngine) LastTiming() CommandTiming {
+	return e.lastTiming
+}
+
+// SetCallStats turns on measuring the CPU time and peak memory growth of
+// every runtime call. Runtimes with their own process are measured by that
+// process, the others by funterm's. Only Linux reports them
+func (e *ExecutionEngine) SetCallStats(enabled bool) {
+	e.callStats = enabled
+}
+
+// measureRuntime counts the time until the returned function is called as
+// time spent in a runtime. Calls nested in a runtime call, such as a language
+// call in the arguments of another, are counted once
+func (e *ExecutionEngine) measureRuntime() func() {
+	e.runtimeDepth++
+	if e.runtimeDepth > 1 {
+		return func() { e.runtimeDepth-- }
+	}
+	start := time.Now()
+	return func() {
+		e.runtimeTime += time.Since(start)
+		e.runtimeDepth--
+	}
+}
+
+// measureCall records what the call named name costs rt's interpreter until
+// the returned function is called, when SetCallStats is on
+func (e *ExecutionEngine) measureCall(name string, rt runtime.LanguageRuntime) func() {
+	if !e.callStats {
+		return func() {}
+	}
+	pid := 0
+	if process, ok := rt.(runtime.ProcessRuntime); ok {
+		// A process started by the call itself has nothing to compare with
+		if pid = process.ProcessID(); pid == 0 {
+			return func() {}
+		}
+	}
+	before, ok := runtime.ReadUsage(pid)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		after, ok := runtime.ReadUsage(pid)
+		if !ok {
+			return // the interpreter has exited
+		}
+		e.calls = append(e.calls, RuntimeCall{Name: name, Duration: time.Since(start), CallStats: after.Since(before)})
+	}
+}
+
+// timeCommand records the timing of a command that started at start, parsed
+// until parsed and had spent runtimeBefore in runtimes and made callsBefore
+// calls before it started
+func (e *ExecutionEngine) timeCommand(start, parsed time.Time, runtimeBefore time.Duration, callsBefore int) {
+	total := time.Since(start)
+	parse := parsed.Sub(start)
+	runtimeTime := e.runtimeTime - runtimeBefore
+	calls := append([]RuntimeCall(nil), e.calls[callsBefore:]...)
+	e.calls = e.calls[:callsBefore]
+	e.lastTiming = CommandTiming{Parse: parse, Runtime: runtimeTime, Engine: total - parse - runtimeTime, Calls: calls}
+}
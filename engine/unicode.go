@@ -0,0 +1,22 @@
+package engine
+
+import "github.com/rivo/uniseg"
+
+// graphemeClusters splits s into user-perceived characters as Unicode
+// defines them (UAX #29): a base character with its combining marks, emoji
+// sequences, flags and CR LF each stay together
+func graphemeClusters(s string) []string {
+	var clusters []string
+	state := -1
+	for len(s) > 0 {
+		var cluster string
+		cluster, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// graphemeCount returns the number of grapheme clusters in s
+func graphemeCount(s string) int {
+	return uniseg.GraphemeClusterCount(s)
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"funterm/errors"
+	"go-parser/pkg/ast"
+)
+
+// UsageCounts is what a session used: statements by kind, calls and code
+// blocks by language and errors by code. Only counts are kept, never code,
+// values or names from the scripts
+type UsageCounts struct {
+	Features  map[string]int `json:"features"`
+	Languages map[string]int `json:"languages"`
+	Errors    map[string]int `json:"errors"`
+}
+
+// NewUsageCounts returns empty counts
+func NewUsageCounts() UsageCounts {
+	return UsageCounts{
+		Features:  make(map[string]int),
+		Languages: make(map[string]int),
+		Errors:    make(map[string]int),
+	}
+}
+
+// Add adds other to the counts
+func (u UsageCounts) Add(other UsageCounts) {
+	for name, count := range other.Features {
+		u.Features[name] += count
+	}
+	for name, count := range other.Languages {
+		u.Languages[name] += count
+	}
+	for name, count := range other.Errors {
+		u.Errors[name] += count
+	}
+}
+
+// usageCounter collects the counts of an engine and the copies of it running
+// background jobs
+type usageCounter struct {
+	mu     sync.Mutex
+	counts UsageCounts
+}
+
+// SetCollectUsage starts or stops counting what the session uses
+func (e *ExecutionEngine) SetCollectUsage(enabled bool) {
+	if !enabled {
+		e.usage = nil
+	} else if e.usage == nil {
+		e.usage = &usageCounter{counts: NewUsageCounts()}
+	}
+}
+
+// Usage returns a copy of the counts collected so far; they are empty when
+// counting is off
+func (e *ExecutionEngine) Usage() UsageCounts {
+	counts := NewUsageCounts()
+	if e.usage != nil {
+		e.usage.mu.Lock()
+		counts.Add(e.usage.counts)
+		e.usage.mu.Unlock()
+	}
+	return counts
+}
+
+// countUsage adds one to a count when counting is on
+func (e *ExecutionEngine) countUsage(counts func(UsageCounts) map[string]int, name string) {
+	if e.usage == nil {
+		return
+	}
+	e.usage.mu.Lock()
+	counts(e.usage.counts)[name]++
+	e.usage.mu.Unlock()
+}
+
+// countFeature counts a statement run; blocks only hold other statements and
+// are not counted
+func (e *ExecutionEngine) countFeature(statement ast.Statement) {
+	if _, isBlock := statement.(*ast.BlockStatement); e.usage != nil && !isBlock {
+		e.countUsage(func(u UsageCounts) map[string]int { return u.Features }, statementKind(statement))
+	}
+}
+
+func (e *ExecutionEngine) countLanguage(language string) {
+	e.countUsage(func(u UsageCounts) map[string]int { return u.Languages }, language)
+}
+
+func (e *ExecutionEngine) countError(err error) {
+	if e.usage == nil || err == nil {
+		return
+	}
+	code := "OTHER"
+	if execErr, ok := err.(*errors.ExecutionError); ok && execErr.Code != "" {
+		code = execErr.Code
+	}
+	e.countUsage(func(u UsageCounts) map[string]int { return u.Errors }, code)
+}
+
+// statementKind names the kind of a statement, e.g. "match" for
+// *ast.MatchStatement and "language_call" for *ast.LanguageCallStatement
+func statementKind(statement ast.Statement) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", statement), "*ast.")
+	if name != "ExpressionStatement" {
+		name = strings.TrimSuffix(name, "Statement")
+	}
+	var kind strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				kind.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		kind.WriteRune(r)
+	}
+	return kind.String()
+}
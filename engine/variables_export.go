@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"funterm/errors"
+)
+
+// variablesFile is the JSON written by ExportVariables
+type variablesFile struct {
+	Exported  time.Time                   `json:"exported"`
+	Variables map[string]exportedVariable `json:"variables"`
+}
+
+// exportedVariable is a variable with its type, as cache_result() keeps
+// values, and whether it can be reassigned
+type exportedVariable struct {
+	cachedValue
+	Mutable bool `json:"mutable,omitempty"`
+}
+
+// SkippedVariable is a variable ExportVariables left out, with its type
+type SkippedVariable struct {
+	Name string
+	Type string
+}
+
+// ExportVariables writes the session variables to a JSON file that
+// ImportVariables reads back with their types. Runtime variables are written
+// as "lang.name". With names only those variables are written, and each of
+// them must be one that can be exported; otherwise variables that only live
+// in this session, like handles, are skipped and returned
+func (e *ExecutionEngine) ExportVariables(path string, names []string) ([]string, []SkippedVariable, error) {
+	vars := e.sessionVariables()
+	explicit := len(names) > 0
+	if !explicit {
+		for name := range vars {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	file := variablesFile{Exported: time.Now().UTC(), Variables: make(map[string]exportedVariable, len(names))}
+	var exported []string
+	var skipped []SkippedVariable
+	for i, name := range names {
+		// py.data names the variable kept as python.data
+		if language, variable, qualified := strings.Cut(name, "."); qualified {
+			if canonical, alias := guardLanguages[language]; alias {
+				name = canonical + "." + variable
+				names[i] = name
+			}
+		}
+		value, exists := vars[name]
+		if !exists {
+			return nil, nil, errors.NewUserError("VARIABLE_NOT_FOUND", fmt.Sprintf("export_vars(): there is no variable '%s'", name))
+		}
+		encoded, err := encodeCachedValue(value)
+		if err != nil {
+			if explicit {
+				return nil, nil, errors.NewUserError("EXPORT_ERROR", fmt.Sprintf("export_vars(): '%s' is a %s, which only lives in this session", name, orderTypeName(value)))
+			}
+			skipped = append(skipped, SkippedVariable{Name: name, Type: orderTypeName(value)})
+			continue
+		}
+		file.Variables[name] = exportedVariable{cachedValue: encoded, Mutable: e.isMutableVariable(name)}
+		exported = append(exported, name)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, nil, errors.NewSystemError("EXPORT_ERROR", fmt.Sprintf("export_vars(): %v", err))
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, nil, errors.NewSystemError("FILE_WRITE_ERROR", fmt.Sprintf("failed to write %s: %v", path, err))
+	}
+	return exported, skipped, nil
+}
+
+// ImportVariables sets the variables of a file written by ExportVariables
+// and returns their names. Top-level variables keep the mutability they were
+// exported with; "lang.name" variables are set in their runtime. Nothing is
+// set when a variable can't be, e.g. because it is immutable in this session
+func (e *ExecutionEngine) ImportVariables(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewSystemError("FILE_READ_ERROR", fmt.Sprintf("failed to read %s: %v", path, err))
+	}
+	var file variablesFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Variables == nil {
+		return nil, errors.NewUserError("IMPORT_ERROR", fmt.Sprintf("import_vars(): %s is not a file written by export_vars()", path))
+	}
+
+	names := make([]string, 0, len(file.Variables))
+	for name := range file.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		value, err := decodeCachedValue(file.Variables[name].cachedValue)
+		if err != nil {
+			return nil, errors.NewUserError("IMPORT_ERROR", fmt.Sprintf("import_vars(): the value of '%s' is damaged: %v", name, err))
+		}
+		values[name] = value
+		if strings.Contains(name, ".") {
+			continue
+		}
+		if info, exists := e.getGlobalVariableInfo(name); exists && !info.IsMutable {
+			return nil, errors.NewUserError("IMMUTABLE_VARIABLE_ERROR", fmt.Sprintf("import_vars(): cannot reassign immutable variable '%s'", name))
+		}
+	}
+
+	for _, name := range names {
+		language, variable, qualified := strings.Cut(name, ".")
+		if !qualified {
+			e.setGlobalVariableWithMutability(name, values[name], file.Variables[name].Mutable)
+			continue
+		}
+		rt, err := e.getRuntimeByName(language)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := e.setVariableInRuntime(rt, language, variable, values[name]); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// isMutableVariable reports whether a top-level variable can be reassigned
+func (e *ExecutionEngine) isMutableVariable(name string) bool {
+	if info, exists := e.getGlobalVariableInfo(name); exists {
+		return info.IsMutable
+	}
+	if len(e.scopeStack) > 0 {
+		if info, exists := e.scopeStack[0].GetVariableInfoLocal(name); exists {
+			return info.IsMutable
+		}
+	}
+	return false
+}
+
+// executeExportVarsFunction implements export_vars(path, names?): it returns
+// the names it wrote and warns about the variables it skipped
+func (e *ExecutionEngine) executeExportVarsFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.NewUserError("ARGUMENT_ERROR", "export_vars() requires a path and an optional array of names")
+	}
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, errors.NewUserError("ARGUMENT_ERROR", fmt.Sprintf("export_vars() path must be a string, got %s", orderTypeName(args[0])))
+	}
+	var names []string
+	if len(args) == 2 {
+		list, ok := args[1].([]interface{})
+		if !ok {
+			return nil, errors.NewUserError("ARGUMENT_ERROR", fmt.Sprintf("export_vars() names must be an array, got %s", orderTypeName(args[1])))
+		}
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return nil, errors.NewUserError("ARGUMENT_ERROR", fmt.Sprintf("export_vars() names must be strings, got %s", orderTypeName(item)))
+			}
+			names = append(names, name)
+		}
+	}
+
+	exported, skipped, err := e.ExportVariables(path, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, variable := range skipped {
+		fmt.Printf("Warning: export_vars() skipped '%s', a %s only lives in this session\n", variable.Name, variable.Type)
+	}
+	return stringsToValues(exported), nil
+}
+
+// executeImportVarsFunction implements import_vars(path): it returns the
+// names it set
+func (e *ExecutionEngine) executeImportVarsFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.NewUserError("ARGUMENT_ERROR", "import_vars() requires a path")
+	}
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, errors.NewUserError("ARGUMENT_ERROR", fmt.Sprintf("import_vars() path must be a string, got %s", orderTypeName(args[0])))
+	}
+	names, err := e.ImportVariables(path)
+	if err != nil {
+		return nil, err
+	}
+	return stringsToValues(names), nil
+}
+
+// stringsToValues turns names into an array of the language
+func stringsToValues(names []string) []interface{} {
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = name
+	}
+	return values
+}
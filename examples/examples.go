@@ -0,0 +1,75 @@
+// Package examples ships runnable funterm scripts inside the binary, so
+// `funterm examples` can list, show and run them without a source checkout
+package examples
+
+import (
+	"embed"
+	"sort"
+	"strings"
+)
+
+//go:embed *.su
+var scripts embed.FS
+
+// Example is one embedded script
+type Example struct {
+	Name        string // file name without the number prefix and extension, e.g. "png_header"
+	File        string // file name, e.g. "003_png_header.su"
+	Description string // the comment lines at the top of the script, joined
+	Source      string
+}
+
+// All returns the examples in file order
+func All() []Example {
+	entries, err := scripts.ReadDir(".")
+	if err != nil {
+		return nil
+	}
+	var examples []Example
+	for _, entry := range entries {
+		data, err := scripts.ReadFile(entry.Name())
+		if err != nil {
+			continue
+		}
+		examples = append(examples, Example{
+			Name:        exampleName(entry.Name()),
+			File:        entry.Name(),
+			Description: description(string(data)),
+			Source:      string(data),
+		})
+	}
+	sort.Slice(examples, func(i, j int) bool { return examples[i].File < examples[j].File })
+	return examples
+}
+
+// Find looks an example up by name or file name, with or without the
+// number prefix and extension
+func Find(name string) (Example, bool) {
+	for _, example := range All() {
+		if name == example.Name || name == example.File || name+".su" == example.File {
+			return example, true
+		}
+	}
+	return Example{}, false
+}
+
+// exampleName drops the "NNN_" prefix and the extension of a file name
+func exampleName(file string) string {
+	name := strings.TrimSuffix(file, ".su")
+	if prefix, rest, found := strings.Cut(name, "_"); found && strings.Trim(prefix, "0123456789") == "" {
+		return rest
+	}
+	return name
+}
+
+// description joins the leading comment lines of a script
+func description(source string) string {
+	var lines []string
+	for _, line := range strings.Split(source, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		lines = append(lines, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+	}
+	return strings.Join(lines, " ")
+}
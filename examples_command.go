@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"funterm/examples"
+)
+
+// exampleCommands are the commands of `funterm examples`
+var exampleCommands = []string{"list", "show", "run"}
+
+// RunExamplesCommand lists, prints or runs the examples built into funterm.
+// Examples run like `funterm run` with a copy of the script in a temporary
+// directory, so errors name the example file
+func RunExamplesCommand(command, name, configPath string, noConfig, verbose bool) (bool, error) {
+	if command == "list" {
+		if name != "" {
+			return false, usageErrorf("examples", "unexpected argument '%s'", name)
+		}
+		for _, example := range examples.All() {
+			fmt.Printf("  %-16s %s\n", example.Name, example.Description)
+		}
+		fmt.Println("\nShow one with `funterm examples show <name>`, run it with `funterm examples run <name>`")
+		return true, nil
+	}
+	if command != "show" && command != "run" {
+		return false, usageErrorf("examples", "unknown command '%s', expected one of %s", command, strings.Join(exampleCommands, ", "))
+	}
+	if name == "" {
+		return false, usageErrorf("examples", "no example specified, expected one of %s", strings.Join(exampleNames(), ", "))
+	}
+	example, found := examples.Find(name)
+	if !found {
+		return false, usageErrorf("examples", "unknown example '%s', expected one of %s", name, strings.Join(exampleNames(), ", "))
+	}
+
+	if command == "show" {
+		fmt.Print(example.Source)
+		return true, nil
+	}
+	dir, err := os.MkdirTemp("", "funterm-example-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, example.File)
+	if err := os.WriteFile(path, []byte(example.Source), 0644); err != nil {
+		return false, err
+	}
+	if noConfig {
+		configPath = ""
+	}
+	return true, BatchMode(path, "", configPath, verbose, nil)
+}
@@ -4,16 +4,21 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"funterm/errors"
 	"funterm/runtime"
+	"funterm/runtime/beam"
 	go_runtime "funterm/runtime/go"
+	"funterm/runtime/goja"
 	"funterm/runtime/lua"
 	"funterm/runtime/node"
+	"funterm/runtime/perl"
 	"funterm/runtime/python"
+	"funterm/runtime/starlark"
 )
 
 // RuntimeFactory defines the interface for creating language runtimes
@@ -99,16 +104,66 @@ func (rr *RuntimeRegistry) CreateRuntime(factoryName string) (runtime.LanguageRu
 }
 
 // LuaRuntimeFactory creates Lua runtime instances
-type LuaRuntimeFactory struct{}
+type LuaRuntimeFactory struct {
+	processOptions   runtime.ProcessOptions
+	external         bool // an external lua interpreter instead of gopher-lua
+	luaPath          string
+	executionTimeout time.Duration
+	cassette         *runtime.Cassette
+}
 
 // NewLuaRuntimeFactory creates a new Lua runtime factory
 func NewLuaRuntimeFactory() *LuaRuntimeFactory {
-	return &LuaRuntimeFactory{}
+	return &LuaRuntimeFactory{
+		executionTimeout: 30 * time.Second,
+	}
 }
 
 // CreateRuntime creates a new Lua runtime instance
 func (lf *LuaRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error) {
-	return lua.NewLuaRuntime(), nil
+	if lf.external {
+		processRuntime := lua.NewLuaProcessRuntime()
+		processRuntime.SetLuaPath(lf.luaPath)
+		processRuntime.SetExecutionTimeout(lf.executionTimeout)
+		if lf.cassette != nil {
+			processRuntime.SetCassette(lf.cassette)
+		}
+		return processRuntime, nil
+	}
+
+	luaRuntime := lua.NewLuaRuntime()
+	if err := luaRuntime.SetProcessOptions(lf.processOptions); err != nil {
+		return nil, err
+	}
+	return luaRuntime, nil
+}
+
+// SetProcessOptions sets the encoding of strings in created runtimes
+func (lf *LuaRuntimeFactory) SetProcessOptions(options runtime.ProcessOptions) {
+	lf.processOptions = options
+}
+
+// SetExternal makes the factory run an external lua interpreter, for C
+// modules and Lua versions after 5.1
+func (lf *LuaRuntimeFactory) SetExternal(external bool) {
+	lf.external = external
+}
+
+// SetLuaPath sets the external lua executable
+func (lf *LuaRuntimeFactory) SetLuaPath(path string) {
+	lf.luaPath = path
+}
+
+// SetExecutionTimeout limits the run time of a single external lua request
+func (lf *LuaRuntimeFactory) SetExecutionTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		lf.executionTimeout = timeout
+	}
+}
+
+// SetCassette makes external runtimes record to or replay from the cassette
+func (lf *LuaRuntimeFactory) SetCassette(cassette *runtime.Cassette) {
+	lf.cassette = cassette
 }
 
 // GetSupportedLanguages returns the languages supported by this factory
@@ -116,10 +171,19 @@ func (lf *LuaRuntimeFactory) GetSupportedLanguages() []string {
 	return []string{"lua"}
 }
 
-// ValidateEnvironment checks if Lua environment is available
+// ValidateEnvironment checks if Lua environment is available: gopher-lua
+// always is, an external lua has to be installed
 func (lf *LuaRuntimeFactory) ValidateEnvironment() error {
-	// For Lua, we assume it's available since we're using gopher-lua
-	// In a real implementation, we might check for specific Lua versions or libraries
+	if !lf.external {
+		return nil
+	}
+	path := lf.luaPath
+	if path == "" {
+		path = "lua"
+	}
+	if _, err := runtime.ResolveExecutable(path); err != nil {
+		return errors.NewSystemError("LUA_NOT_FOUND", fmt.Sprintf("'%s' not found in PATH", path))
+	}
 	return nil
 }
 
@@ -189,6 +253,11 @@ type PythonRuntimeFactory struct {
 	pythonPath       string
 	verbose          bool
 	executionTimeout time.Duration
+	cassette         *runtime.Cassette
+	processOptions   runtime.ProcessOptions
+	embedded         bool // the embedded Starlark interpreter instead of a python3 process
+	preload          []string
+	snapshot         bool
 }
 
 // NewPythonRuntimeFactory creates a new Python runtime factory
@@ -217,6 +286,15 @@ func NewPythonRuntimeFactoryWithConfig(pythonPath string, verbose bool, executio
 
 // CreateRuntime creates a new Python runtime instance
 func (pf *PythonRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error) {
+	if pf.embedded {
+		embeddedRuntime := starlark.NewStarlarkRuntime()
+		embeddedRuntime.SetExecutionTimeout(pf.executionTimeout)
+		if err := embeddedRuntime.Initialize(); err != nil {
+			return nil, err
+		}
+		return embeddedRuntime, nil
+	}
+
 	// Check if we're running in test mode
 	if isTestMode() {
 		return python.GetSharedTestRuntime(), nil
@@ -224,6 +302,13 @@ func (pf *PythonRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error)
 
 	// Create new runtime and initialize with configuration
 	runtime := python.NewPythonRuntime()
+	if pf.cassette != nil {
+		runtime.SetCassette(pf.cassette)
+	}
+	if err := runtime.SetProcessOptions(pf.processOptions); err != nil {
+		return nil, err
+	}
+	runtime.SetWarmStart(pf.preload, pf.snapshot)
 	if err := runtime.InitializeWithConfig(pf.pythonPath, pf.verbose); err != nil {
 		return nil, err
 	}
@@ -234,13 +319,36 @@ func (pf *PythonRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error)
 	return runtime, nil
 }
 
+// SetCassette makes created runtimes record to or replay from the cassette
+func (pf *PythonRuntimeFactory) SetCassette(cassette *runtime.Cassette) {
+	pf.cassette = cassette
+}
+
+// SetProcessOptions sets the environment, locale and encoding of created interpreters
+func (pf *PythonRuntimeFactory) SetProcessOptions(options runtime.ProcessOptions) {
+	pf.processOptions = options
+}
+
+// SetWarmStart makes created interpreters import the preload modules when
+// they start, forking them from a snapshot that has the modules imported
+// when snapshot is set (Linux only)
+func (pf *PythonRuntimeFactory) SetWarmStart(preload []string, snapshot bool) {
+	pf.preload = preload
+	pf.snapshot = snapshot
+}
+
+// SetEmbedded makes the factory create the embedded Starlark runtime, which
+// needs no Python installation and supports a subset of the language
+func (pf *PythonRuntimeFactory) SetEmbedded(embedded bool) {
+	pf.embedded = embedded
+}
+
 // isTestMode checks if we're running in test environment
 func isTestMode() bool {
-	// Check if we're running under 'go test'
-	for _, arg := range os.Args {
-		if strings.Contains(arg, "test") || strings.HasSuffix(arg, ".test") {
-			return true
-		}
+	// Check if we're running under 'go test'. Only the binary name counts: a
+	// script or cassette path with "test" in it is not a test run
+	if len(os.Args) > 0 && strings.HasSuffix(strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe"), ".test") {
+		return true
 	}
 	// Check for test environment variable
 	return os.Getenv("FUNTERM_TEST_MODE") == "true"
@@ -253,16 +361,20 @@ func (pf *PythonRuntimeFactory) GetSupportedLanguages() []string {
 
 // ValidateEnvironment checks if Python environment is available
 func (pf *PythonRuntimeFactory) ValidateEnvironment() error {
-	// Check if Python is available
-	cmd := exec.Command("python3", "--version")
-	if err := cmd.Run(); err != nil {
-		// Try python as fallback
-		cmd = exec.Command("python", "--version")
-		if err := cmd.Run(); err != nil {
-			return errors.NewSystemError("PYTHON_NOT_FOUND", "neither python3 nor python found in PATH")
+	if pf.embedded {
+		return nil
+	}
+	// Check if Python is available; Windows has python.exe and the py launcher instead of python3
+	for _, candidate := range []string{pf.pythonPath, "python", "py"} {
+		path, err := runtime.ResolveExecutable(candidate)
+		if err != nil {
+			continue
+		}
+		if err := exec.Command(path, "--version").Run(); err == nil {
+			return nil
 		}
 	}
-	return nil
+	return errors.NewSystemError("PYTHON_NOT_FOUND", "neither python3 nor python found in PATH")
 }
 
 // GetName returns the name of the runtime factory
@@ -271,16 +383,70 @@ func (pf *PythonRuntimeFactory) GetName() string {
 }
 
 // NodeRuntimeFactory creates Node.js runtime instances
-type NodeRuntimeFactory struct{}
+type NodeRuntimeFactory struct {
+	nodePath         string
+	executionTimeout time.Duration
+	cassette         *runtime.Cassette
+	processOptions   runtime.ProcessOptions
+	embedded         bool // the embedded goja interpreter instead of a node process
+}
 
 // NewNodeRuntimeFactory creates a new Node.js runtime factory
 func NewNodeRuntimeFactory() *NodeRuntimeFactory {
-	return &NodeRuntimeFactory{}
+	return &NodeRuntimeFactory{
+		executionTimeout: 30 * time.Second,
+	}
 }
 
 // CreateRuntime creates a new Node.js runtime instance
 func (nf *NodeRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error) {
-	return node.NewNodeRuntime(), nil
+	if nf.embedded {
+		embeddedRuntime := goja.NewGojaRuntime()
+		embeddedRuntime.SetExecutionTimeout(nf.executionTimeout)
+		if err := embeddedRuntime.Initialize(); err != nil {
+			return nil, err
+		}
+		return embeddedRuntime, nil
+	}
+
+	nodeRuntime := node.NewNodeRuntime()
+	nodeRuntime.SetNodePath(nf.nodePath)
+	nodeRuntime.SetExecutionTimeout(nf.executionTimeout)
+	if nf.cassette != nil {
+		nodeRuntime.SetCassette(nf.cassette)
+	}
+	if err := nodeRuntime.SetProcessOptions(nf.processOptions); err != nil {
+		return nil, err
+	}
+	return nodeRuntime, nil
+}
+
+// SetCassette makes created runtimes record to or replay from the cassette
+func (nf *NodeRuntimeFactory) SetCassette(cassette *runtime.Cassette) {
+	nf.cassette = cassette
+}
+
+// SetNodePath sets the node executable used by created runtimes
+func (nf *NodeRuntimeFactory) SetNodePath(path string) {
+	nf.nodePath = path
+}
+
+// SetProcessOptions sets the environment and locale of created interpreters
+func (nf *NodeRuntimeFactory) SetProcessOptions(options runtime.ProcessOptions) {
+	nf.processOptions = options
+}
+
+// SetExecutionTimeout limits the run time of a single call; zero keeps the default
+func (nf *NodeRuntimeFactory) SetExecutionTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		nf.executionTimeout = timeout
+	}
+}
+
+// SetEmbedded makes the factory create the embedded goja runtime, which
+// needs no Node.js installation and has no Node.js modules
+func (nf *NodeRuntimeFactory) SetEmbedded(embedded bool) {
+	nf.embedded = embedded
 }
 
 // GetSupportedLanguages returns the languages supported by this factory
@@ -300,6 +466,176 @@ func (nf *NodeRuntimeFactory) GetName() string {
 	return "node"
 }
 
+// PerlRuntimeFactory creates Perl runtime instances
+type PerlRuntimeFactory struct {
+	perlPath         string
+	executionTimeout time.Duration
+	cassette         *runtime.Cassette
+	processOptions   runtime.ProcessOptions
+}
+
+// NewPerlRuntimeFactory creates a new Perl runtime factory
+func NewPerlRuntimeFactory() *PerlRuntimeFactory {
+	return &PerlRuntimeFactory{
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// CreateRuntime creates a new Perl runtime instance
+func (pf *PerlRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error) {
+	perlRuntime := perl.NewPerlRuntime()
+	perlRuntime.SetPerlPath(pf.perlPath)
+	perlRuntime.SetExecutionTimeout(pf.executionTimeout)
+	if pf.cassette != nil {
+		perlRuntime.SetCassette(pf.cassette)
+	}
+	if err := perlRuntime.SetProcessOptions(pf.processOptions); err != nil {
+		return nil, err
+	}
+	return perlRuntime, nil
+}
+
+// SetPerlPath sets the perl executable used by created runtimes
+func (pf *PerlRuntimeFactory) SetPerlPath(path string) {
+	pf.perlPath = path
+}
+
+// SetExecutionTimeout limits the run time of a single perl request
+func (pf *PerlRuntimeFactory) SetExecutionTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		pf.executionTimeout = timeout
+	}
+}
+
+// SetCassette makes created runtimes record to or replay from the cassette
+func (pf *PerlRuntimeFactory) SetCassette(cassette *runtime.Cassette) {
+	pf.cassette = cassette
+}
+
+// SetProcessOptions sets the environment and locale of created interpreters
+func (pf *PerlRuntimeFactory) SetProcessOptions(options runtime.ProcessOptions) {
+	pf.processOptions = options
+}
+
+// GetSupportedLanguages returns the languages supported by this factory
+func (pf *PerlRuntimeFactory) GetSupportedLanguages() []string {
+	return []string{"perl", "pl"}
+}
+
+// ValidateEnvironment checks if Perl is available. The runtime itself starts
+// perl on first use, so a missing perl only matters to scripts that call it.
+func (pf *PerlRuntimeFactory) ValidateEnvironment() error {
+	path := pf.perlPath
+	if path == "" {
+		path = "perl"
+	}
+	if _, err := runtime.ResolveExecutable(path); err != nil {
+		return errors.NewSystemError("PERL_NOT_FOUND", fmt.Sprintf("'%s' not found in PATH", path))
+	}
+	return nil
+}
+
+// GetName returns the name of the runtime factory
+func (pf *PerlRuntimeFactory) GetName() string {
+	return "perl"
+}
+
+// BeamRuntimeFactory creates Erlang or Elixir runtime instances
+type BeamRuntimeFactory struct {
+	dialect          beam.Dialect
+	executable       string
+	executionTimeout time.Duration
+	cassette         *runtime.Cassette
+	processOptions   runtime.ProcessOptions
+}
+
+// NewErlangRuntimeFactory creates a factory for Erlang runtimes
+func NewErlangRuntimeFactory() *BeamRuntimeFactory {
+	return &BeamRuntimeFactory{
+		dialect:          beam.Erlang,
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// NewElixirRuntimeFactory creates a factory for Elixir runtimes
+func NewElixirRuntimeFactory() *BeamRuntimeFactory {
+	return &BeamRuntimeFactory{
+		dialect:          beam.Elixir,
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// CreateRuntime creates a new Erlang or Elixir runtime instance
+func (bf *BeamRuntimeFactory) CreateRuntime() (runtime.LanguageRuntime, error) {
+	var beamRuntime *beam.BeamRuntime
+	if bf.dialect == beam.Elixir {
+		beamRuntime = beam.NewElixirRuntime()
+	} else {
+		beamRuntime = beam.NewErlangRuntime()
+	}
+	beamRuntime.SetExecutable(bf.executable)
+	beamRuntime.SetExecutionTimeout(bf.executionTimeout)
+	if bf.cassette != nil {
+		beamRuntime.SetCassette(bf.cassette)
+	}
+	if err := beamRuntime.SetProcessOptions(bf.processOptions); err != nil {
+		return nil, err
+	}
+	return beamRuntime, nil
+}
+
+// SetExecutable sets the erl or elixir executable used by created runtimes
+func (bf *BeamRuntimeFactory) SetExecutable(path string) {
+	bf.executable = path
+}
+
+// SetExecutionTimeout limits the run time of a single request
+func (bf *BeamRuntimeFactory) SetExecutionTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		bf.executionTimeout = timeout
+	}
+}
+
+// SetCassette makes created runtimes record to or replay from the cassette
+func (bf *BeamRuntimeFactory) SetCassette(cassette *runtime.Cassette) {
+	bf.cassette = cassette
+}
+
+// SetProcessOptions sets the environment and locale of created nodes
+func (bf *BeamRuntimeFactory) SetProcessOptions(options runtime.ProcessOptions) {
+	bf.processOptions = options
+}
+
+// GetSupportedLanguages returns the languages supported by this factory
+func (bf *BeamRuntimeFactory) GetSupportedLanguages() []string {
+	if bf.dialect == beam.Elixir {
+		return []string{"elixir", "ex"}
+	}
+	return []string{"erlang", "erl"}
+}
+
+// ValidateEnvironment checks if erl or elixir is available. The node is
+// started on first use, so a missing installation only matters to scripts
+// that call it.
+func (bf *BeamRuntimeFactory) ValidateEnvironment() error {
+	path := bf.executable
+	if path == "" {
+		path = "erl"
+		if bf.dialect == beam.Elixir {
+			path = "elixir"
+		}
+	}
+	if _, err := runtime.ResolveExecutable(path); err != nil {
+		return errors.NewSystemError(strings.ToUpper(string(bf.dialect))+"_NOT_FOUND", fmt.Sprintf("'%s' not found in PATH", path))
+	}
+	return nil
+}
+
+// GetName returns the name of the runtime factory
+func (bf *BeamRuntimeFactory) GetName() string {
+	return string(bf.dialect)
+}
+
 // GoRuntimeFactory creates Go runtime instances
 type GoRuntimeFactory struct{}
 
@@ -343,6 +679,9 @@ func DefaultRuntimeRegistryWithConfig(config RuntimeRegistryConfig) *RuntimeRegi
 	pythonFactory := NewPythonRuntimeFactory()
 	goFactory := NewGoRuntimeFactory()
 	nodeFactory := NewNodeRuntimeFactory()
+	perlFactory := NewPerlRuntimeFactory()
+	erlangFactory := NewErlangRuntimeFactory()
+	elixirFactory := NewElixirRuntimeFactory()
 
 	if err := registry.RegisterFactory(luaFactory); err != nil {
 		// Log error but continue with other factories
@@ -356,6 +695,15 @@ func DefaultRuntimeRegistryWithConfig(config RuntimeRegistryConfig) *RuntimeRegi
 	if err := registry.RegisterFactory(nodeFactory); err != nil {
 		// Log error but continue with other factories
 	}
+	if err := registry.RegisterFactory(perlFactory); err != nil {
+		// Log error but continue with other factories
+	}
+	if err := registry.RegisterFactory(erlangFactory); err != nil {
+		// Log error but continue with other factories
+	}
+	if err := registry.RegisterFactory(elixirFactory); err != nil {
+		// Log error but continue with other factories
+	}
 
 	return registry
 }
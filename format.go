@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"funterm/engine"
+)
+
+// FormatFiles implements `funterm fmt`: it prints the scripts with consistent
+// indentation, rewrites them with write or lists the ones that are not
+// formatted with check. It returns false if check found such a file or a
+// script doesn't parse.
+func FormatFiles(paths []string, configPath string, write, check bool) (bool, error) {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+		ParserHandlers: cfg.Parser,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	formatted := true
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false, fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+
+		// A script that doesn't parse is reported and the others still formatted
+		result, err := eng.Format(string(content))
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			formatted = false
+			continue
+		}
+		changed := result != string(content)
+		if check && changed {
+			fmt.Println(path)
+			formatted = false
+		}
+		if write && changed {
+			info, err := os.Stat(path)
+			if err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(path, []byte(result), info.Mode().Perm()); err != nil {
+				return false, fmt.Errorf("cannot write %s: %v", path, err)
+			}
+		}
+		if !write && !check {
+			fmt.Print(result)
+		}
+	}
+	return formatted, nil
+}
@@ -225,6 +225,12 @@ func (m *Matcher) matchSegmentWithContext(segment *bitstringpkg.Segment, bs *bit
 		segmentCopy.Size = evaluatedSize
 		segmentCopy.SizeSpecified = true
 
+		// A binary whose size evaluates to 0 is empty, unlike an explicit
+		// size 0, which matchBinary treats as the rest of the bytes
+		if segment.Type == bitstringpkg.TypeBinary && evaluatedSize == 0 {
+			return m.matchEmptyBinary(segment, bs, offset)
+		}
+
 		// For binary type, dynamic size is already in bytes, no conversion needed
 		// The unit multiplication will be handled in matchBinary
 
@@ -509,6 +515,26 @@ func (m *Matcher) matchBinary(segment *bitstringpkg.Segment, bs *bitstringpkg.Bi
 	return result, offset + effectiveSize, nil
 }
 
+// matchEmptyBinary binds an empty binary without consuming any bits
+func (m *Matcher) matchEmptyBinary(segment *bitstringpkg.Segment, bs *bitstringpkg.BitString, offset uint) (*bitstringpkg.SegmentResult, uint, error) {
+	if err := m.bindBinaryValue(segment.Value, []byte{}); err != nil {
+		return nil, 0, fmt.Errorf("failed to bind binary value: %v", err)
+	}
+
+	var remaining *bitstringpkg.BitString
+	if offset < bs.Length() {
+		remaining = m.extractRemainingBits(bs, offset)
+	} else {
+		remaining = bitstringpkg.NewBitString()
+	}
+
+	return &bitstringpkg.SegmentResult{
+		Value:     []byte{},
+		Matched:   true,
+		Remaining: remaining,
+	}, offset, nil
+}
+
 // matchBitstring matches a bitstring segment against the bitstring
 func (m *Matcher) matchBitstring(segment *bitstringpkg.Segment, bs *bitstringpkg.BitString, offset uint) (*bitstringpkg.SegmentResult, uint, error) {
 	effectiveSize, err := m.calculateBitstringEffectiveSize(segment, bs, offset)
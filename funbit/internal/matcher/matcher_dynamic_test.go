@@ -667,3 +667,68 @@ func TestMatcher_isOperator(t *testing.T) {
 		}
 	})
 }
+
+// A binary whose dynamic size evaluates to 0 matches an empty binary, it must
+// not take the rest of the bytes the way an explicit size 0 does
+func TestMatcher_DynamicSizeZeroBinary(t *testing.T) {
+	t.Run("Empty remainder", func(t *testing.T) {
+		// <<size:8, data:size/binary>> against <<0>>
+		var size uint
+		data := []byte("unset")
+
+		results, err := NewMatcher().
+			RegisterVariable("size", &size).
+			Integer(&size, bitstringpkg.WithSize(8)).
+			Binary(&data, bitstringpkg.WithDynamicSizeExpression("size")).
+			Match(bitstringpkg.NewBitStringFromBytes([]byte{0}))
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+
+		if size != 0 {
+			t.Errorf("Expected size 0, got %d", size)
+		}
+
+		if len(data) != 0 {
+			t.Errorf("Expected empty data, got %v", data)
+		}
+
+		if !results[1].Matched {
+			t.Error("Expected data segment to match")
+		}
+
+		if results[1].Remaining.Length() != 0 {
+			t.Errorf("Expected nothing to remain, got %d bits", results[1].Remaining.Length())
+		}
+	})
+
+	t.Run("Bytes left for the next segment", func(t *testing.T) {
+		// <<size:8, data:size/binary, rest/binary>> against <<0, "AB">>
+		var size uint
+		var data, rest []byte
+
+		_, err := NewMatcher().
+			RegisterVariable("size", &size).
+			Integer(&size, bitstringpkg.WithSize(8)).
+			Binary(&data, bitstringpkg.WithDynamicSizeExpression("size")).
+			RestBinary(&rest).
+			Match(bitstringpkg.NewBitStringFromBytes([]byte{0, 'A', 'B'}))
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(data) != 0 {
+			t.Errorf("Expected empty data, got %v", data)
+		}
+
+		if string(rest) != "AB" {
+			t.Errorf("Expected rest AB, got %q", rest)
+		}
+	})
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"funterm/engine"
+	"funterm/examples"
+	"go-parser/pkg/ast"
+	"go-parser/pkg/parser"
+)
+
+// Defaults of funterm fuzz-parse
+const (
+	fuzzDefaultRuns    = 10000
+	fuzzDefaultTimeout = 5 * time.Second
+	fuzzDefaultMemory  = 512 // MB of heap
+	fuzzDefaultOutput  = "fuzz-failures"
+	fuzzMaxInput       = 64 << 10
+	fuzzMinimizeTries  = 500
+)
+
+// fuzzTokens are spliced into inputs: the punctuation and keywords the
+// handlers look for, so mutations reach them more often than random bytes
+var fuzzTokens = []string{
+	"<<", ">>", "{", "}", "(", ")", "[", "]", ",", ":", "/", "=", "->", "..", "...",
+	"\"", "'", "\n", " ", "#", "//", "$", "@quiet ", "?", "|>", "&&", "!",
+	"match ", "if ", "else ", "for ", "in ", "while ", "break", "continue",
+	"py.", "lua.", "js.", "lua { ", "py { ", "pipeline { ", "stage \"a\" ",
+	"retry(2) ", "forall(x = 1) ", "capture ", "ok(", "error(", "_",
+	"/binary", "/bitstring", "/utf8", "-little", "-signed", "/float", "unit:8", "x:8",
+	"0", "1", "255", "-1", "0x", "1.5", "99999999999999999999", "nil", "true",
+}
+
+// fuzzFailure is an input the parser or the engine panicked, hung or ran
+// out of memory on
+type fuzzFailure struct {
+	kind   string // panic, hang or memory
+	stage  string // parser or engine
+	detail string // the panic value or what was exceeded
+	stack  string
+}
+
+// fuzzHarness runs inputs through the parser and the sandboxed engine
+type fuzzHarness struct {
+	parser    *parser.UnifiedParser
+	engine    *engine.ExecutionEngine
+	timeout   time.Duration
+	maxHeap   uint64
+	parseOnly bool
+	devNull   *os.File
+	parsed    int // inputs without parse errors
+	executed  int // inputs the engine ran to the end
+}
+
+// RunFuzzParse implements `funterm fuzz-parse [corpus...]`: it feeds the
+// scripts of the corpus, the built-in examples by default, and random
+// mutations of them to the parser and then to an engine without runtimes
+// that may only call builtins computing on values. Inputs that make either
+// panic, run longer than --timeout or grow the heap past --max-memory are
+// shrunk and saved to the --output directory. It returns false if any did
+func RunFuzzParse(paths []string, options *cliOptions) (bool, error) {
+	runs := fuzzDefaultRuns
+	if options.runs != "" {
+		n, err := strconv.Atoi(options.runs)
+		if err != nil || n < 1 {
+			return false, usageErrorf("fuzz-parse", "invalid --runs '%s'", options.runs)
+		}
+		runs = n
+	}
+	seed := time.Now().UnixNano()
+	if options.seed != "" {
+		n, err := strconv.ParseInt(options.seed, 10, 64)
+		if err != nil {
+			return false, usageErrorf("fuzz-parse", "invalid --seed '%s'", options.seed)
+		}
+		seed = n
+	}
+	timeout := fuzzDefaultTimeout
+	if options.timeout != "" {
+		d, err := time.ParseDuration(options.timeout)
+		if err != nil || d <= 0 {
+			return false, usageErrorf("fuzz-parse", "invalid --timeout '%s'", options.timeout)
+		}
+		timeout = d
+	}
+	memory := fuzzDefaultMemory
+	if options.maxMemory != "" {
+		n, err := strconv.Atoi(options.maxMemory)
+		if err != nil || n < 1 {
+			return false, usageErrorf("fuzz-parse", "invalid --max-memory '%s', expected megabytes", options.maxMemory)
+		}
+		memory = n
+	}
+	output := options.output
+	if output == "" {
+		output = fuzzDefaultOutput
+	}
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return false, err
+	}
+
+	corpus, err := loadFuzzCorpus(paths)
+	if err != nil {
+		return false, err
+	}
+
+	// The engine runs in an empty directory, so what a script manages to
+	// write stays there
+	sandbox, err := os.MkdirTemp("", "funterm-fuzz-")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(sandbox)
+	previousDir, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+	if err := os.Chdir(sandbox); err != nil {
+		return false, err
+	}
+	defer os.Chdir(previousDir)
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return false, err
+	}
+	defer devNull.Close()
+
+	h := &fuzzHarness{timeout: timeout, maxHeap: uint64(memory) << 20, parseOnly: options.parseOnly, devNull: devNull}
+	if err := h.reset(); err != nil {
+		return false, err
+	}
+	defer func() {
+		if h.engine != nil {
+			h.engine.CleanupRuntimes()
+		}
+	}()
+
+	fmt.Printf("Fuzzing the parser%s with %d inputs from a corpus of %d scripts (seed %d)\n", map[bool]string{true: "", false: " and the engine"}[h.parseOnly], runs, len(corpus), seed)
+	rng := rand.New(rand.NewSource(seed))
+	seen := map[string]bool{}
+	failures := 0
+	start := time.Now()
+	tried := 0
+	for i := 0; i < runs; i++ {
+		tried++
+		input := corpus[i%len(corpus)]
+		if i >= len(corpus) {
+			input = mutateFuzzInput(rng, input, corpus)
+		}
+		failure := h.check(input)
+		if failure == nil {
+			continue
+		}
+		if failure.kind == "panic" {
+			input = h.minimize(input, failure)
+		}
+		key := failure.kind + failure.stage + firstLine(failure.detail)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		failures++
+		path, err := saveFuzzFailure(output, input, failure)
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("%s in the %s: %s\n    saved to %s\n", strings.ToUpper(failure.kind), failure.stage, firstLine(failure.detail), path)
+		if failure.kind != "panic" {
+			// A hung parse or run still holds its goroutine, and the numbers
+			// of everything after it would be off
+			fmt.Println("Stopping: the input that hung or grew the heap is still running")
+			break
+		}
+		if err := h.reset(); err != nil {
+			return false, err
+		}
+	}
+
+	fmt.Printf("\n%d inputs, %d parsed, %d executed, %d distinct failures (%.2fs)\n", tried, h.parsed, h.executed, failures, time.Since(start).Seconds())
+	if failures > 0 {
+		fmt.Printf("Repeat with `funterm fuzz-parse --seed %d`\n", seed)
+	}
+	return failures == 0, nil
+}
+
+// reset creates a fresh parser and engine, after a panic may have left them
+// in a broken state
+func (h *fuzzHarness) reset() error {
+	h.parser = parser.NewUnifiedParser()
+	if h.parseOnly {
+		return nil
+	}
+	if h.engine != nil {
+		h.engine.CleanupRuntimes()
+	}
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{SpinnerThreshold: -1})
+	if err != nil {
+		return err
+	}
+	if err := eng.SetPolicy(engine.FuzzPolicy(h.timeout / 2)); err != nil {
+		return err
+	}
+	h.engine = eng
+	return nil
+}
+
+// check parses input and, when it parses, runs it
+func (h *fuzzHarness) check(input string) *fuzzFailure {
+	// What the parser and the scripts print is dropped. Restored here rather
+	// than in the goroutine, which keeps running when the input hangs
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = h.devNull, h.devNull
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	parsed := false
+	failure := h.guard("parser", func() {
+		statement, parseErrors := h.parser.Parse(input)
+		if len(parseErrors) == 0 {
+			parsed = true
+			ast.ReleaseTree(statement)
+		}
+	})
+	if failure != nil || !parsed {
+		return failure
+	}
+	h.parsed++
+	if h.parseOnly {
+		return nil
+	}
+	failure = h.guard("engine", func() { h.engine.Execute(input) })
+	if failure == nil {
+		h.executed++
+	}
+	return failure
+}
+
+// guard runs fn in a goroutine and reports a panic, a run longer than the
+// timeout or a heap larger than the limit
+func (h *fuzzHarness) guard(stage string, fn func()) *fuzzFailure {
+	done := make(chan *fuzzFailure, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- &fuzzFailure{kind: "panic", stage: stage, detail: fmt.Sprint(recovered), stack: string(debug.Stack())}
+			}
+		}()
+		fn()
+		done <- nil
+	}()
+
+	deadline := time.NewTimer(h.timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case failure := <-done:
+			return failure
+		case <-deadline.C:
+			return &fuzzFailure{kind: "hang", stage: stage, detail: fmt.Sprintf("still running after %s", h.timeout)}
+		case <-ticker.C:
+			if heap := heapBytes(); heap > h.maxHeap {
+				return &fuzzFailure{kind: "memory", stage: stage, detail: fmt.Sprintf("heap grew to %d MB", heap>>20)}
+			}
+		}
+	}
+}
+
+// minimize removes parts of an input that panics while it still panics the
+// same way, so the saved input shows the cause
+func (h *fuzzHarness) minimize(input string, failure *fuzzFailure) string {
+	want := firstLine(failure.detail)
+	tries := 0
+	for chunk := len(input) / 2; chunk >= 1 && tries < fuzzMinimizeTries; chunk /= 2 {
+		for start := 0; start+chunk <= len(input) && tries < fuzzMinimizeTries; {
+			tries++
+			candidate := input[:start] + input[start+chunk:]
+			if err := h.reset(); err != nil {
+				return input
+			}
+			if f := h.check(candidate); f != nil && f.kind == "panic" && firstLine(f.detail) == want {
+				input = candidate
+				continue
+			}
+			start += chunk
+		}
+	}
+	return input
+}
+
+// heapBytes is the size of the live heap objects, read without stopping the world
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// loadFuzzCorpus reads the .su files in paths, the built-in examples when
+// there are none
+func loadFuzzCorpus(paths []string) ([]string, error) {
+	var corpus []string
+	if len(paths) == 0 {
+		for _, example := range examples.All() {
+			corpus = append(corpus, example.Source)
+		}
+		return corpus, nil
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(path, "*.su")); err != nil {
+				return nil, err
+			}
+			sort.Strings(files)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			corpus = append(corpus, string(data))
+		}
+	}
+	if len(corpus) == 0 {
+		return nil, fmt.Errorf("no scripts in %s", strings.Join(paths, ", "))
+	}
+	return corpus, nil
+}
+
+// mutateFuzzInput changes input in one to four places: flipping, deleting,
+// duplicating or inserting bytes and tokens, or splicing in part of
+// another script of the corpus
+func mutateFuzzInput(rng *rand.Rand, input string, corpus []string) string {
+	data := []byte(input)
+	for n := 1 + rng.Intn(4); n > 0; n-- {
+		at := 0
+		if len(data) > 0 {
+			at = rng.Intn(len(data) + 1)
+		}
+		switch rng.Intn(7) {
+		case 0: // flip a byte
+			if at < len(data) {
+				data[at] ^= byte(1 << rng.Intn(8))
+			}
+		case 1: // delete a span
+			end := min(len(data), at+1+rng.Intn(16))
+			data = append(data[:at:at], data[end:]...)
+		case 2: // duplicate a span
+			end := min(len(data), at+1+rng.Intn(32))
+			data = append(data[:end:end], append(append([]byte(nil), data[at:end]...), data[end:]...)...)
+		case 3, 4: // insert a token
+			token := fuzzTokens[rng.Intn(len(fuzzTokens))]
+			data = append(data[:at:at], append([]byte(token), data[at:]...)...)
+		case 5: // insert a random byte
+			data = append(data[:at:at], append([]byte{byte(rng.Intn(256))}, data[at:]...)...)
+		case 6: // splice in a line of another script
+			lines := strings.Split(corpus[rng.Intn(len(corpus))], "\n")
+			line := lines[rng.Intn(len(lines))] + "\n"
+			data = append(data[:at:at], append([]byte(line), data[at:]...)...)
+		}
+		if len(data) > fuzzMaxInput {
+			data = data[:fuzzMaxInput]
+		}
+	}
+	return string(data)
+}
+
+// saveFuzzFailure writes the input and a report of the failure next to it,
+// named by the kind of failure and a hash of the input
+func saveFuzzFailure(dir, input string, failure *fuzzFailure) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(input))
+	base := filepath.Join(dir, failure.kind+"-"+hex.EncodeToString(sum[:6]))
+	if err := os.WriteFile(base+".su", []byte(input), 0644); err != nil {
+		return "", err
+	}
+	report := fmt.Sprintf("%s in the %s: %s\n\n%s", failure.kind, failure.stage, failure.detail, failure.stack)
+	if err := os.WriteFile(base+".txt", []byte(report), 0644); err != nil {
+		return "", err
+	}
+	return base + ".su", nil
+}
+
+// firstLine returns the first line of text
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -0,0 +1,151 @@
+package ast
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Пулы для самых частых узлов дерева. Разбор большого файла создает
+// множество мелких идентификаторов и литералов; когда дерево больше не нужно
+// (повторный разбор в LSP или watch-режиме), ReleaseTree возвращает их в пулы,
+// и следующий разбор берет узлы оттуда, не нагружая GC
+var (
+	identifierPool    = sync.Pool{New: func() interface{} { return new(Identifier) }}
+	numberLiteralPool = sync.Pool{New: func() interface{} { return new(NumberLiteral) }}
+	stringLiteralPool = sync.Pool{New: func() interface{} { return new(StringLiteral) }}
+)
+
+// astPackage - путь пакета ast: обход дерева спускается только в его типы
+var astPackage = reflect.TypeOf(Identifier{}).PkgPath()
+
+// AcquireIdentifier берет из пула пустой идентификатор
+func AcquireIdentifier() *Identifier {
+	return identifierPool.Get().(*Identifier)
+}
+
+// AcquireNumberLiteral берет из пула пустой числовой литерал
+func AcquireNumberLiteral() *NumberLiteral {
+	return numberLiteralPool.Get().(*NumberLiteral)
+}
+
+// AcquireStringLiteral берет из пула пустой строковый литерал
+func AcquireStringLiteral() *StringLiteral {
+	return stringLiteralPool.Get().(*StringLiteral)
+}
+
+// ReleaseTree возвращает в пулы узлы дерева с корнем root. После вызова ни
+// дерево, ни ссылки на его узлы использовать нельзя, поэтому он подходит только
+// для деревьев, которые больше нигде не хранятся: движок держит тела функций
+// и выполненные match, их освобождать нельзя
+func ReleaseTree(root ProtoNode) {
+	if root == nil {
+		return
+	}
+	r := releasers.Get().(*treeReleaser)
+	defer r.reset()
+	r.walk(reflect.ValueOf(root))
+	for _, node := range r.nodes {
+		switch n := node.(type) {
+		case *Identifier:
+			*n = Identifier{}
+			identifierPool.Put(n)
+		case *NumberLiteral:
+			*n = NumberLiteral{}
+			numberLiteralPool.Put(n)
+		case *StringLiteral:
+			*n = StringLiteral{}
+			stringLiteralPool.Put(n)
+		}
+	}
+}
+
+// releasedNode - ключ посещенного узла: тип нужен, потому что встроенная
+// первым полем структура имеет тот же адрес, что и она сама
+type releasedNode struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// treeReleaser собирает узлы дерева для ReleaseTree. Один узел может быть
+// доступен по нескольким путям (например, размер сегмента bitstring), а в пул
+// он должен попасть только один раз
+type treeReleaser struct {
+	seen  map[releasedNode]bool
+	nodes []ProtoNode
+}
+
+// releasers переиспользует treeReleaser, чтобы освобождение дерева само не
+// выделяло память на каждый вызов
+var releasers = sync.Pool{New: func() interface{} {
+	return &treeReleaser{seen: make(map[releasedNode]bool)}
+}}
+
+// reset очищает treeReleaser и возвращает его в пул
+func (r *treeReleaser) reset() {
+	for key := range r.seen {
+		delete(r.seen, key)
+	}
+	for i := range r.nodes {
+		r.nodes[i] = nil
+	}
+	r.nodes = r.nodes[:0]
+	releasers.Put(r)
+}
+
+// walk обходит значение через reflect, чтобы не перечислять поля каждого типа
+// узла: новые узлы освобождаются без изменений в этом файле
+func (r *treeReleaser) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			r.walk(v.Elem())
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().PkgPath() != astPackage {
+			return
+		}
+		key := releasedNode{typ: v.Type(), ptr: v.Pointer()}
+		if r.seen[key] {
+			return
+		}
+		r.seen[key] = true
+		r.walk(v.Elem())
+		// Узлы из неэкспортированных полей не освобождаются, их просто соберет GC
+		if v.CanInterface() {
+			if node, ok := v.Interface().(ProtoNode); ok {
+				r.nodes = append(r.nodes, node)
+			}
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPackage {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			r.walk(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if !holdsNodes(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i))
+		}
+	case reflect.Map:
+		if !holdsNodes(v.Type().Elem()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			r.walk(iter.Value())
+		}
+	}
+}
+
+// holdsNodes отсеивает срезы строк, байтов и чисел, в которых узлов быть не может
+func holdsNodes(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package ast
+
+import "fmt"
+
+// CaptureExpression - перехват вывода блока:
+//
+//	out = capture { py.print(x); lua.print(y) }
+//
+// Значение - текст, который тело вывело бы, вместо вывода
+type CaptureExpression struct {
+	BaseNode
+	Body    *BlockStatement // тело, разобранное как отдельный скрипт
+	Source  string          // текст тела между фигурными скобками
+	BodyPos Position        // позиция начала текста тела
+	Pos     Position        // позиция capture
+}
+
+// NewCaptureExpression создает новый узел перехвата вывода
+func NewCaptureExpression(pos Position) *CaptureExpression {
+	return &CaptureExpression{
+		Pos: pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *CaptureExpression) Type() NodeType {
+	return NodeCaptureExpression
+}
+
+// expressionMarker реализует интерфейс Expression
+func (n *CaptureExpression) expressionMarker() {}
+
+// Position возвращает позицию узла
+func (n *CaptureExpression) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *CaptureExpression) String() string {
+	return "capture { ... }"
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *CaptureExpression) ToMap() map[string]interface{} {
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":     "capture",
+		"body":     body,
+		"position": n.Pos.ToMap(),
+	}
+}
+
+// RedirectStatement - вызов, вывод которого пишется в файл:
+//
+//	py.report() > "out.txt"
+//	py.report() >> "log.txt"
+//
+// '>' перезаписывает файл, '>>' дописывает в конец
+type RedirectStatement struct {
+	BaseNode
+	Statement Statement  // вызов, вывод которого перенаправляется
+	Target    Expression // имя файла
+	Append    bool       // '>>' вместо '>'
+	Pos       Position   // позиция оператора
+}
+
+// NewRedirectStatement создает новый узел перенаправления вывода
+func NewRedirectStatement(statement Statement, target Expression, append bool, pos Position) *RedirectStatement {
+	return &RedirectStatement{
+		Statement: statement,
+		Target:    target,
+		Append:    append,
+		Pos:       pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *RedirectStatement) Type() NodeType {
+	return NodeRedirectStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *RedirectStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *RedirectStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *RedirectStatement) String() string {
+	operator := ">"
+	if n.Append {
+		operator = ">>"
+	}
+	return fmt.Sprintf("%s %s %s", n.Statement, operator, n.Target)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *RedirectStatement) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "redirect",
+		"statement": n.Statement.ToMap(),
+		"target":    n.Target.ToMap(),
+		"append":    n.Append,
+		"position":  n.Pos.ToMap(),
+	}
+}
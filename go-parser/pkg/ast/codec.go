@@ -0,0 +1,469 @@
+package ast
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Сериализация дерева для кэша разобранных скриптов: разобранный один раз
+// большой скрипт (например, прелюдия REPL) читается с диска быстрее, чем
+// разбирается заново. Поля узлов обходятся через reflect и пишутся по порядку
+// в компактном двоичном виде, значения интерфейсов (Expression, Statement,
+// Pattern) сохраняются вместе с типом. Неэкспортированные поля (дети BaseNode
+// для Walk) не сохраняются. Формат зависит от раскладки структур, поэтому кэш
+// должен сбрасываться при смене версии программы
+
+// codecMagic начинает сериализованное дерево, последний байт - версия формата
+var codecMagic = []byte{'F', 'T', 'A', 'S', 'T', 10}
+
+// Метки значений в полях-интерфейсах
+const (
+	tagNil byte = iota
+	tagNode
+	tagNilNode
+	tagBigInt
+	tagString
+	tagBool
+	tagInt
+	tagFloat
+)
+
+var (
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+	// codecTypes - типы узлов, которые могут стоять в полях-интерфейсах, по
+	// порядку имен; номер в этом списке и записывается в дерево
+	codecTypes   []reflect.Type
+	codecIndexes = map[reflect.Type]int{}
+
+	// codecFields - номера сохраняемых полей структур
+	codecFields sync.Map // reflect.Type -> []int
+)
+
+var errCorruptTree = errors.New("corrupt tree")
+
+func init() {
+	for _, node := range []ProtoNode{
+		&ArrayLiteral{}, &ArrayPattern{}, &BinaryExpression{}, &BitstringExpression{},
+		&BitstringPattern{}, &BitstringPatternAssignment{}, &BitstringPatternMatchExpression{},
+		&BlockStatement{}, &BooleanLiteral{}, &BreakStatement{}, &BuiltinFunctionCall{},
+		&CaptureExpression{}, &CStyleForLoopStatement{}, &CodeBlockStatement{}, &ContextStatement{},
+		&ContinueStatement{}, &ElvisExpression{}, &ExpressionAssignment{}, &ExpressionStatement{},
+		&FieldAccess{}, &ForallStatement{}, &ForInLoopStatement{}, &HookStatement{}, &Identifier{},
+		&IfStatement{}, &ImportStatement{}, &IndexExpression{}, &InteractiveStatement{}, &LanguageCall{},
+		&LanguageCallStatement{}, &LimitStatement{}, &LiteralPattern{}, &MatchStatement{},
+		&NamedArgument{}, &NestedExpression{}, &NilLiteral{}, &NumberLiteral{},
+		&NumericForLoopStatement{}, &ObjectLiteral{}, &ObjectPattern{}, &PipeExpression{},
+		&PipelineStatement{}, &PragmaStatement{}, &RangePattern{}, &RedirectStatement{},
+		&ResultPattern{}, &RetryStatement{}, &SelectStatement{}, &SizeExpression{},
+		&SliceExpression{}, &SpreadArgument{}, &StringLiteral{}, &TernaryExpression{}, &TryExpression{}, &UnaryExpression{},
+		&ValueMethodCall{}, &VariableAssignment{}, &VariablePattern{}, &VariableRead{}, &WhileStatement{},
+		&WildcardPattern{},
+	} {
+		codecTypes = append(codecTypes, reflect.TypeOf(node))
+	}
+	sort.Slice(codecTypes, func(i, j int) bool { return codecTypes[i].Elem().Name() < codecTypes[j].Elem().Name() })
+	for i, t := range codecTypes {
+		codecIndexes[t] = i
+	}
+}
+
+// MarshalTree сериализует дерево разобранного скрипта. Деревья с данными,
+// которые кодек не умеет сохранять (например, Data пользовательской
+// инструкции), возвращают ошибку: такой скрипт просто не кэшируется
+func MarshalTree(root Statement) ([]byte, error) {
+	e := &treeEncoder{buf: append([]byte(nil), codecMagic...)}
+	if err := e.encode(reflect.ValueOf(&root).Elem()); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalTree восстанавливает дерево, сохраненное MarshalTree
+func UnmarshalTree(data []byte) (Statement, error) {
+	if len(data) < len(codecMagic) || string(data[:len(codecMagic)]) != string(codecMagic) {
+		return nil, fmt.Errorf("not a tree of format version %d", codecMagic[len(codecMagic)-1])
+	}
+	d := &treeDecoder{buf: data[len(codecMagic):]}
+	var root Statement
+	if err := d.decode(reflect.ValueOf(&root).Elem()); err != nil {
+		return nil, err
+	}
+	if len(d.buf) != 0 {
+		return nil, errCorruptTree
+	}
+	return root, nil
+}
+
+// fieldsOf возвращает номера экспортированных полей структуры, кроме
+// служебных вроде BaseNode, у которых нечего сохранять
+func fieldsOf(t reflect.Type) []int {
+	if cached, ok := codecFields.Load(t); ok {
+		return cached.([]int)
+	}
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath == "" && hasExportedFields(field.Type) {
+			fields = append(fields, i)
+		}
+	}
+	codecFields.Store(t, fields)
+	return fields
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+type treeEncoder struct {
+	buf []byte
+}
+
+func (e *treeEncoder) uint(n uint64) { e.buf = binary.AppendUvarint(e.buf, n) }
+
+func (e *treeEncoder) string(s string) {
+	e.uint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// length пишет длину среза или карты, 0 означает nil
+func (e *treeEncoder) length(v reflect.Value) bool {
+	if v.IsNil() {
+		e.uint(0)
+		return false
+	}
+	e.uint(uint64(v.Len()) + 1)
+	return true
+}
+
+func (e *treeEncoder) encode(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		return e.encodeInterface(v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0)
+			return nil
+		}
+		e.buf = append(e.buf, 1)
+		if v.Type() == bigIntType {
+			e.string(v.Interface().(*big.Int).String())
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		for _, i := range fieldsOf(v.Type()) {
+			if err := e.encode(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if !e.length(v) {
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("cannot encode %s", v.Type())
+		}
+		if !e.length(v) {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			e.string(iter.Key().String())
+			if err := e.encode(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf = binary.AppendVarint(e.buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.uint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.string(v.String())
+	default:
+		return fmt.Errorf("cannot encode %s", v.Type())
+	}
+	return nil
+}
+
+// encodeInterface пишет значение поля-интерфейса с меткой его типа: узлы
+// дерева и простые значения литералов и образцов
+func (e *treeEncoder) encodeInterface(v reflect.Value) error {
+	if v.IsNil() {
+		e.buf = append(e.buf, tagNil)
+		return nil
+	}
+	elem := v.Elem()
+	if index, ok := codecIndexes[elem.Type()]; ok {
+		if elem.IsNil() {
+			e.buf = append(e.buf, tagNilNode)
+			e.uint(uint64(index))
+			return nil
+		}
+		e.buf = append(e.buf, tagNode)
+		e.uint(uint64(index))
+		return e.encode(elem.Elem())
+	}
+	switch value := elem.Interface().(type) {
+	case *big.Int:
+		if value == nil {
+			break
+		}
+		e.buf = append(e.buf, tagBigInt)
+		e.string(value.String())
+		return nil
+	case string:
+		e.buf = append(e.buf, tagString)
+		e.string(value)
+		return nil
+	case bool:
+		e.buf = append(e.buf, tagBool)
+		return e.encode(elem)
+	case int64:
+		e.buf = append(e.buf, tagInt)
+		return e.encode(elem)
+	case float64:
+		e.buf = append(e.buf, tagFloat)
+		return e.encode(elem)
+	}
+	return fmt.Errorf("cannot encode %s", elem.Type())
+}
+
+type treeDecoder struct {
+	buf []byte
+}
+
+func (d *treeDecoder) byte() (byte, error) {
+	if len(d.buf) == 0 {
+		return 0, errCorruptTree
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b, nil
+}
+
+func (d *treeDecoder) uint() (uint64, error) {
+	n, size := binary.Uvarint(d.buf)
+	if size <= 0 {
+		return 0, errCorruptTree
+	}
+	d.buf = d.buf[size:]
+	return n, nil
+}
+
+func (d *treeDecoder) string() (string, error) {
+	n, err := d.uint()
+	if err != nil || uint64(len(d.buf)) < n {
+		return "", errCorruptTree
+	}
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return s, nil
+}
+
+func (d *treeDecoder) bigInt() (*big.Int, error) {
+	text, err := d.string()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, errCorruptTree
+	}
+	return value, nil
+}
+
+func (d *treeDecoder) decode(dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Interface:
+		return d.decodeInterface(dst)
+	case reflect.Ptr:
+		present, err := d.byte()
+		if err != nil || present == 0 {
+			return err
+		}
+		if dst.Type() == bigIntType {
+			value, err := d.bigInt()
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(value))
+			return nil
+		}
+		target := reflect.New(dst.Type().Elem())
+		if err := d.decode(target.Elem()); err != nil {
+			return err
+		}
+		dst.Set(target)
+	case reflect.Struct:
+		for _, i := range fieldsOf(dst.Type()) {
+			if err := d.decode(dst.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		n, err := d.uint()
+		if err != nil || n == 0 {
+			return err
+		}
+		if n-1 > uint64(len(d.buf)) {
+			return errCorruptTree
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), int(n-1), int(n-1)))
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < dst.Len(); i++ {
+			if err := d.decode(dst.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		n, err := d.uint()
+		if err != nil || n == 0 {
+			return err
+		}
+		if n-1 > uint64(len(d.buf)) {
+			return errCorruptTree
+		}
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), int(n-1)))
+		for i := uint64(1); i < n; i++ {
+			key, err := d.string()
+			if err != nil {
+				return err
+			}
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := d.decode(value); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), value)
+		}
+	case reflect.Bool:
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, size := binary.Varint(d.buf)
+		if size <= 0 {
+			return errCorruptTree
+		}
+		d.buf = d.buf[size:]
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := d.uint()
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if len(d.buf) < 8 {
+			return errCorruptTree
+		}
+		dst.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(d.buf)))
+		d.buf = d.buf[8:]
+	case reflect.String:
+		s, err := d.string()
+		if err != nil {
+			return err
+		}
+		dst.SetString(s)
+	default:
+		return fmt.Errorf("cannot decode %s", dst.Type())
+	}
+	return nil
+}
+
+func (d *treeDecoder) decodeInterface(dst reflect.Value) error {
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+	var value reflect.Value
+	switch tag {
+	case tagNil:
+		return nil
+	case tagNode, tagNilNode:
+		index, err := d.uint()
+		if err != nil || index >= uint64(len(codecTypes)) {
+			return errCorruptTree
+		}
+		t := codecTypes[index]
+		if tag == tagNilNode {
+			value = reflect.Zero(t)
+		} else {
+			value = reflect.New(t.Elem())
+			if err := d.decode(value.Elem()); err != nil {
+				return err
+			}
+		}
+	case tagBigInt:
+		n, err := d.bigInt()
+		if err != nil {
+			return err
+		}
+		value = reflect.ValueOf(n)
+	case tagString:
+		var s string
+		value = reflect.ValueOf(&s).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+	case tagBool:
+		var b bool
+		value = reflect.ValueOf(&b).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+	case tagInt:
+		var n int64
+		value = reflect.ValueOf(&n).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+	case tagFloat:
+		var f float64
+		value = reflect.ValueOf(&f).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+	default:
+		return errCorruptTree
+	}
+	if !value.Type().AssignableTo(dst.Type()) {
+		return errCorruptTree
+	}
+	dst.Set(value)
+	return nil
+}
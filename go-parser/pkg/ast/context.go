@@ -0,0 +1,64 @@
+package ast
+
+import "fmt"
+
+// ContextStatement - блок с другим рабочим каталогом или окружением:
+//
+//	cd("build") { py.run_tests() }
+//	with_env({DEBUG: "1"}) { py.main() }
+//
+// Изменение действует на builtin функции и на вызовы всех рантаймов внутри
+// тела, после тела движок возвращает прежние значения
+type ContextStatement struct {
+	BaseNode
+	Function string          // cd или with_env
+	Argument Expression      // каталог или объект с переменными окружения
+	Body     *BlockStatement // тело, разобранное как отдельный скрипт
+	Source   string          // текст тела между фигурными скобками
+	BodyPos  Position        // позиция начала текста тела
+	Pos      Position        // позиция имени функции
+}
+
+// NewContextStatement создает новый узел блока с рабочим каталогом или окружением
+func NewContextStatement(function string, argument Expression, pos Position) *ContextStatement {
+	return &ContextStatement{
+		Function: function,
+		Argument: argument,
+		Pos:      pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *ContextStatement) Type() NodeType {
+	return NodeContextStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *ContextStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *ContextStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *ContextStatement) String() string {
+	return fmt.Sprintf("%s(%v) { ... }", n.Function, n.Argument)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *ContextStatement) ToMap() map[string]interface{} {
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":     "context",
+		"function": n.Function,
+		"argument": n.Argument.ToMap(),
+		"body":     body,
+		"position": n.Pos.ToMap(),
+	}
+}
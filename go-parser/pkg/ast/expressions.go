@@ -128,6 +128,50 @@ func NewElvisExpression(left Expression, question, colon lexer.Token, right Expr
 	}
 }
 
+// TryExpression - выражение try: превращает результат или ошибку вызова в ok(...) / error(...)
+type TryExpression struct {
+	BaseNode
+	Expression Expression  // Выражение, ошибки которого перехватываются
+	TryToken   lexer.Token // Токен 'try'
+	Pos        Position
+}
+
+// expressionMarker реализует интерфейс Expression
+func (te *TryExpression) expressionMarker() {}
+
+// Position возвращает позицию узла в коде
+func (te *TryExpression) Position() Position {
+	return te.Pos
+}
+
+// Type возвращает тип узла
+func (te *TryExpression) Type() NodeType {
+	return NodeInvalid // Используем NodeInvalid т.к. нет отдельного типа для try выражения
+}
+
+// String возвращает строковое представление
+func (te *TryExpression) String() string {
+	return fmt.Sprintf("TryExpression(%s)", te.Expression)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (te *TryExpression) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "TryExpression",
+		"expression": te.Expression.ToMap(),
+		"pos":        te.Pos.ToMap(),
+	}
+}
+
+// NewTryExpression создает новый узел try выражения
+func NewTryExpression(tryToken lexer.Token, expression Expression, pos Position) *TryExpression {
+	return &TryExpression{
+		Expression: expression,
+		TryToken:   tryToken,
+		Pos:        pos,
+	}
+}
+
 // NewBinaryExpression создает новый узел бинарного выражения
 func NewBinaryExpression(left Expression, operator string, right Expression, pos Position) *BinaryExpression {
 	return &BinaryExpression{
@@ -240,6 +284,68 @@ func NewIndexExpression(object Expression, index Expression, pos Position) *Inde
 	}
 }
 
+// SliceExpression представляет срез arr[2:5]: элементы массива или биты
+// битовой строки от Start до End, не включая End. Пропущенная граница -
+// начало или конец коллекции
+type SliceExpression struct {
+	BaseNode
+	Object Expression // Коллекция
+	Start  Expression // Начало среза, nil для arr[:5]
+	End    Expression // Конец среза, nil для arr[2:]
+	Pos    Position
+}
+
+// expressionMarker реализует интерфейс Expression
+func (se *SliceExpression) expressionMarker() {}
+
+// Position возвращает позицию узла в коде
+func (se *SliceExpression) Position() Position {
+	return se.Pos
+}
+
+// Type возвращает тип узла
+func (se *SliceExpression) Type() NodeType {
+	return NodeInvalid
+}
+
+// String возвращает строковое представление
+func (se *SliceExpression) String() string {
+	var start, end interface{} = "", ""
+	if se.Start != nil {
+		start = se.Start
+	}
+	if se.End != nil {
+		end = se.End
+	}
+	return fmt.Sprintf("SliceExpression(%v[%v:%v])", se.Object, start, end)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (se *SliceExpression) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"type":     "SliceExpression",
+		"object":   se.Object.ToMap(),
+		"position": se.Pos.ToMap(),
+	}
+	if se.Start != nil {
+		result["start"] = se.Start.ToMap()
+	}
+	if se.End != nil {
+		result["end"] = se.End.ToMap()
+	}
+	return result
+}
+
+// NewSliceExpression создает новый узел среза
+func NewSliceExpression(object, start, end Expression, pos Position) *SliceExpression {
+	return &SliceExpression{
+		Object: object,
+		Start:  start,
+		End:    end,
+		Pos:    pos,
+	}
+}
+
 // NamedArgument представляет именованный аргумент функции (например, days=-1)
 type NamedArgument struct {
 	BaseNode
@@ -285,6 +391,56 @@ func NewNamedArgument(name string, value Expression, pos Position) *NamedArgumen
 	}
 }
 
+// SpreadArgument представляет раскрытие коллекции в аргументы вызова:
+// *items или ...items раскрывает массив в позиционные аргументы, **options
+// раскрывает объект в именованные
+type SpreadArgument struct {
+	BaseNode
+	Value   Expression // Раскрываемое выражение
+	Keyword bool       // true для **options
+	Pos     Position
+}
+
+// expressionMarker реализует интерфейс Expression
+func (sa *SpreadArgument) expressionMarker() {}
+
+// Position возвращает позицию узла в коде
+func (sa *SpreadArgument) Position() Position {
+	return sa.Pos
+}
+
+// Type возвращает тип узла
+func (sa *SpreadArgument) Type() NodeType {
+	return NodeInvalid
+}
+
+// String возвращает строковое представление
+func (sa *SpreadArgument) String() string {
+	if sa.Keyword {
+		return fmt.Sprintf("SpreadArgument(**%s)", sa.Value)
+	}
+	return fmt.Sprintf("SpreadArgument(*%s)", sa.Value)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (sa *SpreadArgument) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "SpreadArgument",
+		"value":    sa.Value.ToMap(),
+		"keyword":  sa.Keyword,
+		"position": sa.Pos.ToMap(),
+	}
+}
+
+// NewSpreadArgument создает новый узел раскрытия аргументов
+func NewSpreadArgument(value Expression, keyword bool, pos Position) *SpreadArgument {
+	return &SpreadArgument{
+		Value:   value,
+		Keyword: keyword,
+		Pos:     pos,
+	}
+}
+
 // BuiltinFunctionCall представляет вызов builtin функции (без квалификатора языка)
 type BuiltinFunctionCall struct {
 	BaseNode
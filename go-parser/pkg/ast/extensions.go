@@ -0,0 +1,48 @@
+package ast
+
+// CustomStatement - инструкция, созданная пользовательским обработчиком парсера.
+// Kind определяет, какой исполнитель встраивающего приложения её выполнит,
+// Data содержит любые разобранные обработчиком данные.
+type CustomStatement struct {
+	BaseNode
+	Kind string      // имя вида инструкции, по нему выбирается исполнитель
+	Data interface{} // данные, разобранные обработчиком
+	Pos  Position    // позиция инструкции
+}
+
+// NewCustomStatement создает новую пользовательскую инструкцию
+func NewCustomStatement(kind string, data interface{}, pos Position) *CustomStatement {
+	return &CustomStatement{
+		Kind: kind,
+		Data: data,
+		Pos:  pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *CustomStatement) Type() NodeType {
+	return NodeCustomStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *CustomStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *CustomStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *CustomStatement) String() string {
+	return "custom(" + n.Kind + ")"
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *CustomStatement) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "custom",
+		"kind":     n.Kind,
+		"data":     n.Data,
+		"position": n.Pos.ToMap(),
+	}
+}
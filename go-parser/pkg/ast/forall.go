@@ -0,0 +1,70 @@
+package ast
+
+import "fmt"
+
+// ForallStatement - проверка свойства на случайных данных:
+//
+//	forall(data = gen.bytes(0, 64), runs=200, seed=7) {
+//	    assert_no_diff(py.decode(py.encode(data)), data)
+//	}
+//
+// Каждый именованный аргумент, кроме runs и seed, - переменная, выражение
+// которой вычисляется заново перед каждым прогоном тела
+type ForallStatement struct {
+	BaseNode
+	Arguments []*NamedArgument // переменные и параметры в порядке записи
+	Body      *BlockStatement  // тело, разобранное как отдельный скрипт
+	Source    string           // текст тела между фигурными скобками
+	BodyPos   Position         // позиция начала текста тела
+	Pos       Position         // позиция 'forall'
+}
+
+// NewForallStatement создает новый узел проверки свойства
+func NewForallStatement(arguments []*NamedArgument, pos Position) *ForallStatement {
+	return &ForallStatement{
+		Arguments: arguments,
+		Pos:       pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *ForallStatement) Type() NodeType {
+	return NodeForallStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *ForallStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *ForallStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *ForallStatement) String() string {
+	names := make([]string, len(n.Arguments))
+	for i, argument := range n.Arguments {
+		names[i] = argument.Name
+	}
+	return fmt.Sprintf("forall(%v)", names)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *ForallStatement) ToMap() map[string]interface{} {
+	arguments := make([]interface{}, len(n.Arguments))
+	for i, argument := range n.Arguments {
+		arguments[i] = argument.ToMap()
+	}
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":      "forall",
+		"arguments": arguments,
+		"body":      body,
+		"position":  n.Pos.ToMap(),
+	}
+}
@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HookStatement - обработчик сигнала или завершения скрипта:
+//
+//	on_signal("SIGTERM") { py.close_connections() }
+//	on_exit { py.flush() }
+//
+// Выполнение инструкции только регистрирует тело, движок выполняет его,
+// когда придет сигнал или скрипт завершится
+type HookStatement struct {
+	BaseNode
+	Event   string          // signal или exit
+	Signals []Expression    // имена сигналов для on_signal
+	Body    *BlockStatement // тело, разобранное как отдельный скрипт
+	Source  string          // текст тела между фигурными скобками
+	BodyPos Position        // позиция начала текста тела
+	Pos     Position        // позиция on_signal или on_exit
+}
+
+// NewHookStatement создает новый узел обработчика
+func NewHookStatement(event string, signals []Expression, pos Position) *HookStatement {
+	return &HookStatement{
+		Event:   event,
+		Signals: signals,
+		Pos:     pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *HookStatement) Type() NodeType {
+	return NodeHookStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *HookStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *HookStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *HookStatement) String() string {
+	if n.Event == "exit" {
+		return "on_exit { ... }"
+	}
+	signals := make([]string, len(n.Signals))
+	for i, signal := range n.Signals {
+		signals[i] = fmt.Sprint(signal)
+	}
+	return fmt.Sprintf("on_signal(%s) { ... }", strings.Join(signals, ", "))
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *HookStatement) ToMap() map[string]interface{} {
+	signals := make([]interface{}, len(n.Signals))
+	for i, signal := range n.Signals {
+		signals[i] = signal.ToMap()
+	}
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":     "hook",
+		"event":    n.Event,
+		"signals":  signals,
+		"body":     body,
+		"position": n.Pos.ToMap(),
+	}
+}
@@ -0,0 +1,67 @@
+package ast
+
+import "reflect"
+
+// Inspect вызывает visit для каждого узла дерева с корнем root, родителя
+// раньше детей. Как и ReleaseTree, обход идет через reflect, поэтому видит
+// узлы любых конструкций, в том числе тела блоков, без списка их полей.
+// Узел, доступный по нескольким путям, посещается один раз
+func Inspect(root ProtoNode, visit func(node ProtoNode)) {
+	if root == nil {
+		return
+	}
+	i := &inspector{seen: make(map[releasedNode]bool), visit: visit}
+	i.walk(reflect.ValueOf(root))
+}
+
+// inspector обходит дерево для Inspect
+type inspector struct {
+	seen  map[releasedNode]bool
+	visit func(node ProtoNode)
+}
+
+func (i *inspector) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			i.walk(v.Elem())
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().PkgPath() != astPackage {
+			return
+		}
+		key := releasedNode{typ: v.Type(), ptr: v.Pointer()}
+		if i.seen[key] {
+			return
+		}
+		i.seen[key] = true
+		if v.CanInterface() {
+			if node, ok := v.Interface().(ProtoNode); ok {
+				i.visit(node)
+			}
+		}
+		i.walk(v.Elem())
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPackage {
+			return
+		}
+		for n := 0; n < v.NumField(); n++ {
+			i.walk(v.Field(n))
+		}
+	case reflect.Slice, reflect.Array:
+		if !holdsNodes(v.Type().Elem()) {
+			return
+		}
+		for n := 0; n < v.Len(); n++ {
+			i.walk(v.Index(n))
+		}
+	case reflect.Map:
+		if !holdsNodes(v.Type().Elem()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			i.walk(iter.Value())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ast
+
+import "fmt"
+
+// InteractiveStatement - вызов, которому передается терминал:
+//
+//	interactive sh.run("htop")
+//
+// Пока программа работает, терминал (raw режим, сигналы, изменение размера)
+// принадлежит ей, потом движок возвращает его REPL
+type InteractiveStatement struct {
+	BaseNode
+	Call *LanguageCall // вызов sh.run(...)
+	Pos  Position      // позиция interactive
+}
+
+// NewInteractiveStatement создает новый узел вызова с передачей терминала
+func NewInteractiveStatement(call *LanguageCall, pos Position) *InteractiveStatement {
+	return &InteractiveStatement{
+		Call: call,
+		Pos:  pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *InteractiveStatement) Type() NodeType {
+	return NodeInteractiveStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *InteractiveStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *InteractiveStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *InteractiveStatement) String() string {
+	return fmt.Sprintf("interactive %v", n.Call)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *InteractiveStatement) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "interactive",
+		"call":     n.Call.ToMap(),
+		"position": n.Pos.ToMap(),
+	}
+}
@@ -1,11 +1,29 @@
 package ast
 
+// ResultPolicy - политика обработки результата вызова (суффикс !nowait / !raw)
+type ResultPolicy string
+
+const (
+	ResultWait   ResultPolicy = ""       // ждать результат и конвертировать его (по умолчанию)
+	ResultNoWait ResultPolicy = "nowait" // запустить вызов и не ждать результата
+	ResultRaw    ResultPolicy = "raw"    // вернуть результат как JSON-строку без конвертации
+)
+
 // LanguageCall - узел для вызова функции другого языка
 type LanguageCall struct {
-	Language  string       // "lua", "python"
-	Function  string       // "print", "math.sqrt"
-	Arguments []Expression // Аргументы функции
-	Pos       Position     // Позиция в коде
+	Language  string        // "lua", "python"
+	Function  string        // "print", "math.sqrt"
+	Arguments []Expression  // Аргументы функции
+	Chain     []*MethodCall // Методы, вызываемые по цепочке на результате: .fetch("x").json()
+	Result    ResultPolicy  // Политика результата (!nowait, !raw)
+	Pos       Position      // Позиция в коде
+}
+
+// MethodCall - вызов метода в цепочке после вызова функции языка
+type MethodCall struct {
+	Method    string       // Имя метода
+	Arguments []Expression // Аргументы метода
+	Pos       Position     // Позиция имени метода
 }
 
 // statementMarker реализует интерфейс Statement
@@ -16,13 +34,20 @@ func (lc *LanguageCall) expressionMarker() {}
 
 // ToMap преобразует узел в map для сериализации
 func (lc *LanguageCall) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"type":      "LanguageCall",
 		"language":  lc.Language,
 		"function":  lc.Function,
 		"arguments": lc.argumentsToSlice(),
 		"position":  lc.Pos.ToMap(),
 	}
+	if len(lc.Chain) > 0 {
+		result["chain"] = methodChainToSlice(lc.Chain)
+	}
+	if lc.Result != ResultWait {
+		result["result"] = string(lc.Result)
+	}
+	return result
 }
 
 // Position возвращает позицию узла в коде
@@ -38,3 +63,55 @@ func (lc *LanguageCall) argumentsToSlice() []interface{} {
 	}
 	return result
 }
+
+// methodChainToSlice конвертирует цепочку методов для ToMap
+func methodChainToSlice(chain []*MethodCall) []interface{} {
+	result := make([]interface{}, len(chain))
+	for i, method := range chain {
+		arguments := make([]interface{}, len(method.Arguments))
+		for j, arg := range method.Arguments {
+			arguments[j] = arg.ToMap()
+		}
+		result[i] = map[string]interface{}{
+			"method":    method.Method,
+			"arguments": arguments,
+			"position":  method.Pos.ToMap(),
+		}
+	}
+	return result
+}
+
+// ValueMethodCall - цепочка методов на значении выражения, например "a,b".split(",").
+// Методы на переменной разбираются как LanguageCall с именем переменной вместо языка
+type ValueMethodCall struct {
+	Receiver Expression    // Значение, на котором вызывается первый метод
+	Chain    []*MethodCall // Методы по порядку вызова
+	Pos      Position      // Позиция в коде
+}
+
+// NewValueMethodCall создает узел вызова методов на значении
+func NewValueMethodCall(receiver Expression, chain []*MethodCall, pos Position) *ValueMethodCall {
+	return &ValueMethodCall{
+		Receiver: receiver,
+		Chain:    chain,
+		Pos:      pos,
+	}
+}
+
+// expressionMarker реализует интерфейс Expression
+func (vmc *ValueMethodCall) expressionMarker() {}
+
+// ToMap преобразует узел в map для сериализации
+func (vmc *ValueMethodCall) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "ValueMethodCall",
+		"receiver": vmc.Receiver.ToMap(),
+		"chain":    methodChainToSlice(vmc.Chain),
+		"position": vmc.Pos.ToMap(),
+	}
+}
+
+// Position возвращает позицию узла в коде
+func (vmc *ValueMethodCall) Position() Position {
+	return vmc.Pos
+}
@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LimitStatement - блок под ограничением rate_limit или semaphore:
+//
+//	rate_limit("api") { py.fetch(url) }
+//	semaphore("db", 2) { py.query(sql) }
+//
+// Тело выполняется, когда ограничение с этим именем дает разрешение
+type LimitStatement struct {
+	BaseNode
+	Function  string          // rate_limit или semaphore
+	Arguments []Expression    // имя ограничения и, если есть, его значение
+	Body      *BlockStatement // тело, разобранное как отдельный скрипт
+	Source    string          // текст тела между фигурными скобками
+	BodyPos   Position        // позиция начала текста тела
+	Pos       Position        // позиция имени функции
+}
+
+// NewLimitStatement создает новый узел блока под ограничением
+func NewLimitStatement(function string, arguments []Expression, pos Position) *LimitStatement {
+	return &LimitStatement{
+		Function:  function,
+		Arguments: arguments,
+		Pos:       pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *LimitStatement) Type() NodeType {
+	return NodeLimitStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *LimitStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *LimitStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *LimitStatement) String() string {
+	args := make([]string, len(n.Arguments))
+	for i, arg := range n.Arguments {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("%s(%s) { ... }", n.Function, strings.Join(args, ", "))
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *LimitStatement) ToMap() map[string]interface{} {
+	args := make([]interface{}, len(n.Arguments))
+	for i, arg := range n.Arguments {
+		args[i] = arg.ToMap()
+	}
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":      "limit",
+		"function":  n.Function,
+		"arguments": args,
+		"body":      body,
+		"position":  n.Pos.ToMap(),
+	}
+}
@@ -22,11 +22,13 @@ func NewStringLiteral(token lexer.Token, value, raw string) *StringLiteral {
 		Column: token.Column,
 		Offset: token.Position,
 	}
-	return &StringLiteral{
+	sl := AcquireStringLiteral()
+	*sl = StringLiteral{
 		Value: value,
 		Raw:   raw,
 		Pos:   pos,
 	}
+	return sl
 }
 
 // expressionMarker реализует интерфейс Expression
@@ -91,6 +91,11 @@ func (n *CodeBlockStatement) GetVariableNames() []string {
 	return names
 }
 
+// Doc возвращает комментарии над блоком - документацию его функций
+func (n *CodeBlockStatement) Doc() string {
+	return n.RuntimeToken.Doc
+}
+
 // HasVariables проверяет, есть ли переменные для сохранения
 func (n *CodeBlockStatement) HasVariables() bool {
 	return len(n.VariableTokens) > 0
@@ -104,6 +109,9 @@ func (n *CodeBlockStatement) Type() NodeType {
 // statementMarker реализует интерфейс Statement
 func (n *CodeBlockStatement) statementMarker() {}
 
+// expressionMarker позволяет использовать блок как значение: result = py { ... }
+func (n *CodeBlockStatement) expressionMarker() {}
+
 // Position возвращает позицию узла
 func (n *CodeBlockStatement) Position() Position {
 	return n.Pos
@@ -52,6 +52,34 @@ const (
 	NodeCodeBlockStatement
 	// Ternary expressions
 	NodeTernaryExpression
+	// Инструкции, созданные пользовательскими обработчиками
+	NodeCustomStatement
+	// Паттерн диапазона low..high
+	NodeRangePattern
+	// Паттерн результата ok(...) / error(...)
+	NodeResultPattern
+	// Конвейер стадий pipeline { stage ... }
+	NodePipelineStatement
+	// Повтор блока после ошибки retry(n) { ... }
+	NodeRetryStatement
+	// Блок под ограничением rate_limit(...) { ... } / semaphore(...) { ... }
+	NodeLimitStatement
+	// Ожидание на каналах select { ... }
+	NodeSelectStatement
+	// Обработчик сигнала или завершения on_signal(...) { ... } / on_exit { ... }
+	NodeHookStatement
+	// Блок с другим рабочим каталогом или окружением cd(...) { ... } / with_env(...) { ... }
+	NodeContextStatement
+	// Вызов, которому передается терминал interactive sh.run(...)
+	NodeInteractiveStatement
+	// Перехват вывода блока capture { ... }
+	NodeCaptureExpression
+	// Вывод вызова в файл call() > "file"
+	NodeRedirectStatement
+	// Оператор или блок с другой подробностью вывода @quiet / @verbose
+	NodePragmaStatement
+	// Проверка свойства на случайных данных forall(x = gen...) { ... }
+	NodeForallStatement
 )
 
 // String возвращает строковое представление типа узла
@@ -119,6 +147,34 @@ func (t NodeType) String() string {
 		return "CodeBlockStatement"
 	case NodeTernaryExpression:
 		return "TernaryExpression"
+	case NodeCustomStatement:
+		return "CustomStatement"
+	case NodeRangePattern:
+		return "RangePattern"
+	case NodeResultPattern:
+		return "ResultPattern"
+	case NodePipelineStatement:
+		return "PipelineStatement"
+	case NodeRetryStatement:
+		return "RetryStatement"
+	case NodeLimitStatement:
+		return "LimitStatement"
+	case NodeSelectStatement:
+		return "SelectStatement"
+	case NodeHookStatement:
+		return "HookStatement"
+	case NodeContextStatement:
+		return "ContextStatement"
+	case NodeInteractiveStatement:
+		return "InteractiveStatement"
+	case NodeCaptureExpression:
+		return "CaptureExpression"
+	case NodeRedirectStatement:
+		return "RedirectStatement"
+	case NodePragmaStatement:
+		return "PragmaStatement"
+	case NodeForallStatement:
+		return "ForallStatement"
 	default:
 		return "Unknown"
 	}
@@ -74,6 +74,7 @@ func (n *MatchStatement) ToMap() map[string]interface{} {
 type MatchArm struct {
 	BaseNode
 	Pattern    Pattern     // Паттерн
+	Guard      Expression  // Условие после if, nil без условия
 	ArrowToken lexer.Token // Токен '->'
 	Statement  Statement   // Выполняемый код
 }
@@ -92,6 +93,9 @@ func (n *MatchArm) String() string {
 	} else {
 		builder.WriteString(fmt.Sprintf("%v", n.Pattern.ToMap()))
 	}
+	if n.Guard != nil {
+		builder.WriteString(fmt.Sprintf(" if %v", n.Guard))
+	}
 	builder.WriteString(" -> ")
 	if stmtNode, ok := n.Statement.(Node); ok {
 		builder.WriteString(stmtNode.String())
@@ -103,12 +107,16 @@ func (n *MatchArm) String() string {
 
 // ToMap преобразует узел в map для сериализации
 func (n *MatchArm) ToMap() map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"type":      "match_arm",
 		"pattern":   n.Pattern.ToMap(),
 		"statement": n.Statement.ToMap(),
 		"position":  n.Position().ToMap(),
 	}
+	if n.Guard != nil {
+		result["guard"] = n.Guard.ToMap()
+	}
+	return result
 }
 
 // LiteralPattern - литеральный паттерн
@@ -141,6 +149,86 @@ func (n *LiteralPattern) ToMap() map[string]interface{} {
 	}
 }
 
+// RangePattern - паттерн диапазона low..high (обе границы включительно)
+type RangePattern struct {
+	BaseNode
+	Low       interface{} // нижняя граница: число или строка
+	High      interface{} // верхняя граница: число или строка
+	Exclusive bool        // low..<high: верхняя граница не входит
+	Pos       Position
+}
+
+// patternMarker реализует интерфейс Pattern
+func (n *RangePattern) patternMarker() {}
+
+// Type возвращает тип узла
+func (n *RangePattern) Type() NodeType { return NodeRangePattern }
+
+// Position возвращает позицию узла в коде
+func (n *RangePattern) Position() Position { return n.Pos }
+
+// String возвращает строковое представление
+func (n *RangePattern) String() string {
+	if n.Exclusive {
+		return fmt.Sprintf("Range(%v..<%v)", n.Low, n.High)
+	}
+	return fmt.Sprintf("Range(%v..%v)", n.Low, n.High)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *RangePattern) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "range_pattern",
+		"low":       n.Low,
+		"high":      n.High,
+		"exclusive": n.Exclusive,
+		"position":  n.Pos.ToMap(),
+	}
+}
+
+// ResultPattern - паттерн результата try: ok(pattern) или error(pattern)
+type ResultPattern struct {
+	BaseNode
+	Ok    bool    // true для ok(...), false для error(...)
+	Inner Pattern // Паттерн для значения или описания ошибки
+	Pos   Position
+}
+
+// patternMarker реализует интерфейс Pattern
+func (n *ResultPattern) patternMarker() {}
+
+// Type возвращает тип узла
+func (n *ResultPattern) Type() NodeType { return NodeResultPattern }
+
+// Position возвращает позицию узла в коде
+func (n *ResultPattern) Position() Position { return n.Pos }
+
+// Tag возвращает имя варианта: "ok" или "error"
+func (n *ResultPattern) Tag() string {
+	if n.Ok {
+		return "ok"
+	}
+	return "error"
+}
+
+// String возвращает строковое представление
+func (n *ResultPattern) String() string {
+	if innerNode, ok := n.Inner.(Node); ok {
+		return fmt.Sprintf("%s(%s)", n.Tag(), innerNode.String())
+	}
+	return fmt.Sprintf("%s(%v)", n.Tag(), n.Inner.ToMap())
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *ResultPattern) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "result_pattern",
+		"tag":      n.Tag(),
+		"inner":    n.Inner.ToMap(),
+		"position": n.Pos.ToMap(),
+	}
+}
+
 // ArrayPattern - массивный паттерн
 type ArrayPattern struct {
 	BaseNode
@@ -0,0 +1,98 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PipelineStatement - конвейер стадий, выполняемых по графу зависимостей:
+//
+//	pipeline {
+//	    stage "fetch" { ... }
+//	    stage "report" after "fetch" retries 2 timeout 30 { ... }
+//	}
+//
+// Независимые стадии выполняются параллельно
+type PipelineStatement struct {
+	BaseNode
+	Stages []*PipelineStage // стадии в порядке объявления
+	Pos    Position         // позиция 'pipeline'
+}
+
+// PipelineStage - одна стадия конвейера
+type PipelineStage struct {
+	Name    string
+	After   []string        // стадии, которые должны завершиться до начала этой
+	Retries int             // сколько раз повторить стадию после ошибки
+	Timeout float64         // ограничение одной попытки в секундах, 0 - без ограничения
+	Body    *BlockStatement // тело стадии, разобранное как отдельный скрипт
+	Source  string          // текст тела между фигурными скобками
+	BodyPos Position        // позиция начала текста тела
+	Pos     Position        // позиция 'stage'
+}
+
+// NewPipelineStatement создает новый узел конвейера
+func NewPipelineStatement(stages []*PipelineStage, pos Position) *PipelineStatement {
+	return &PipelineStatement{
+		Stages: stages,
+		Pos:    pos,
+	}
+}
+
+// Stage возвращает стадию по имени
+func (n *PipelineStatement) Stage(name string) (*PipelineStage, bool) {
+	for _, stage := range n.Stages {
+		if stage.Name == name {
+			return stage, true
+		}
+	}
+	return nil, false
+}
+
+// Type возвращает тип узла
+func (n *PipelineStatement) Type() NodeType {
+	return NodePipelineStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *PipelineStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *PipelineStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *PipelineStatement) String() string {
+	names := make([]string, len(n.Stages))
+	for i, stage := range n.Stages {
+		names[i] = fmt.Sprintf("%q", stage.Name)
+	}
+	return "pipeline(" + strings.Join(names, ", ") + ")"
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *PipelineStatement) ToMap() map[string]interface{} {
+	stages := make([]interface{}, len(n.Stages))
+	for i, stage := range n.Stages {
+		var body []interface{}
+		if stage.Body != nil {
+			for _, stmt := range stage.Body.Statements {
+				body = append(body, stmt.ToMap())
+			}
+		}
+		stages[i] = map[string]interface{}{
+			"name":     stage.Name,
+			"after":    stage.After,
+			"retries":  stage.Retries,
+			"timeout":  stage.Timeout,
+			"body":     body,
+			"position": stage.Pos.ToMap(),
+		}
+	}
+	return map[string]interface{}{
+		"type":     "pipeline",
+		"stages":   stages,
+		"position": n.Pos.ToMap(),
+	}
+}
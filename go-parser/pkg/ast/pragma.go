@@ -0,0 +1,61 @@
+package ast
+
+import "fmt"
+
+// PragmaStatement - оператор или блок с другой подробностью вывода:
+//
+//	@quiet py.noisy_setup()
+//	@verbose { py.step_one(); py.step_two() }
+//
+// @quiet отбрасывает вывод и отладочные сообщения, @verbose включает
+// отладочные сообщения только для этого места
+type PragmaStatement struct {
+	BaseNode
+	Name    string          // quiet или verbose
+	Body    *BlockStatement // оператор или тело, разобранные как отдельный скрипт
+	Source  string          // текст оператора или тела между фигурными скобками
+	BodyPos Position        // позиция начала текста
+	Pos     Position        // позиция '@'
+}
+
+// NewPragmaStatement создает новый узел оператора с подробностью вывода
+func NewPragmaStatement(name string, pos Position) *PragmaStatement {
+	return &PragmaStatement{
+		Name: name,
+		Pos:  pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *PragmaStatement) Type() NodeType {
+	return NodePragmaStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *PragmaStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *PragmaStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *PragmaStatement) String() string {
+	return fmt.Sprintf("@%s { ... }", n.Name)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *PragmaStatement) ToMap() map[string]interface{} {
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	return map[string]interface{}{
+		"type":     "pragma",
+		"name":     n.Name,
+		"body":     body,
+		"position": n.Pos.ToMap(),
+	}
+}
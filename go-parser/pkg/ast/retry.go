@@ -0,0 +1,82 @@
+package ast
+
+import "fmt"
+
+// RetryStatement - повтор блока после ошибки:
+//
+//	retry(3, backoff="exp", base=1s, jitter=true, when="timed out") {
+//	    data = py.fetch(url)
+//	}
+//
+// Параметры вычисляются при выполнении, движок проверяет их значения
+type RetryStatement struct {
+	BaseNode
+	Attempts Expression       // сколько раз всего выполнить блок
+	Options  []*NamedArgument // именованные параметры в порядке записи
+	Body     *BlockStatement  // тело, разобранное как отдельный скрипт
+	Source   string           // текст тела между фигурными скобками
+	BodyPos  Position         // позиция начала текста тела
+	Pos      Position         // позиция 'retry'
+}
+
+// NewRetryStatement создает новый узел повтора
+func NewRetryStatement(attempts Expression, options []*NamedArgument, pos Position) *RetryStatement {
+	return &RetryStatement{
+		Attempts: attempts,
+		Options:  options,
+		Pos:      pos,
+	}
+}
+
+// Option возвращает именованный параметр или nil
+func (n *RetryStatement) Option(name string) *NamedArgument {
+	for _, option := range n.Options {
+		if option.Name == name {
+			return option
+		}
+	}
+	return nil
+}
+
+// Type возвращает тип узла
+func (n *RetryStatement) Type() NodeType {
+	return NodeRetryStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *RetryStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *RetryStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *RetryStatement) String() string {
+	return fmt.Sprintf("retry(%v)", n.Attempts)
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *RetryStatement) ToMap() map[string]interface{} {
+	options := make([]interface{}, len(n.Options))
+	for i, option := range n.Options {
+		options[i] = option.ToMap()
+	}
+	var body []interface{}
+	if n.Body != nil {
+		for _, stmt := range n.Body.Statements {
+			body = append(body, stmt.ToMap())
+		}
+	}
+	var attempts interface{}
+	if n.Attempts != nil {
+		attempts = n.Attempts.ToMap()
+	}
+	return map[string]interface{}{
+		"type":     "retry",
+		"attempts": attempts,
+		"options":  options,
+		"body":     body,
+		"position": n.Pos.ToMap(),
+	}
+}
@@ -0,0 +1,96 @@
+package ast
+
+import (
+	"strings"
+)
+
+// SelectStatement - ожидание первого готового действия с каналами:
+//
+//	select {
+//	    msg = recv(events) { print("got", msg) }
+//	    send(results, total) { print("sent") }
+//	    timeout 1s { print("nothing within a second") }
+//	    default { print("nothing ready") }
+//	}
+//
+// Выполняется тело ровно одной ветки
+type SelectStatement struct {
+	BaseNode
+	Cases []*SelectCase // ветки в порядке объявления
+	Pos   Position      // позиция 'select'
+}
+
+// SelectCase - одна ветка select
+type SelectCase struct {
+	Kind     string          // recv, send, timeout или default
+	Variable string          // переменная для полученного значения, пустая - значение не нужно
+	Channel  Expression      // канал для recv и send
+	Value    Expression      // отправляемое значение для send, длительность в секундах для timeout
+	Body     *BlockStatement // тело ветки, разобранное как отдельный скрипт
+	Source   string          // текст тела между фигурными скобками
+	BodyPos  Position        // позиция начала текста тела
+	Pos      Position        // позиция начала ветки
+}
+
+// NewSelectStatement создает новый узел select
+func NewSelectStatement(cases []*SelectCase, pos Position) *SelectStatement {
+	return &SelectStatement{
+		Cases: cases,
+		Pos:   pos,
+	}
+}
+
+// Type возвращает тип узла
+func (n *SelectStatement) Type() NodeType {
+	return NodeSelectStatement
+}
+
+// statementMarker реализует интерфейс Statement
+func (n *SelectStatement) statementMarker() {}
+
+// Position возвращает позицию узла
+func (n *SelectStatement) Position() Position {
+	return n.Pos
+}
+
+// String возвращает строковое представление узла
+func (n *SelectStatement) String() string {
+	kinds := make([]string, len(n.Cases))
+	for i, c := range n.Cases {
+		kinds[i] = c.Kind
+	}
+	return "select(" + strings.Join(kinds, ", ") + ")"
+}
+
+// ToMap преобразует узел в map для сериализации
+func (n *SelectStatement) ToMap() map[string]interface{} {
+	cases := make([]interface{}, len(n.Cases))
+	for i, c := range n.Cases {
+		var body []interface{}
+		if c.Body != nil {
+			for _, stmt := range c.Body.Statements {
+				body = append(body, stmt.ToMap())
+			}
+		}
+		selectCase := map[string]interface{}{
+			"kind":     c.Kind,
+			"body":     body,
+			"position": c.Pos.ToMap(),
+		}
+		if c.Variable != "" {
+			selectCase["variable"] = c.Variable
+		}
+		if c.Channel != nil {
+			selectCase["channel"] = c.Channel.ToMap()
+		}
+		if c.Value != nil {
+			selectCase["value"] = c.Value.ToMap()
+		}
+		cases[i] = selectCase
+	}
+	return map[string]interface{}{
+		"type":     "select",
+		"cases":    cases,
+		"position": n.Pos.ToMap(),
+	}
+}
@@ -135,12 +135,14 @@ func NewIdentifier(token lexer.Token, name string) *Identifier {
 		Column: token.Column,
 		Offset: token.Position,
 	}
-	return &Identifier{
+	id := AcquireIdentifier()
+	*id = Identifier{
 		Token:     token,
 		Name:      name,
 		Pos:       pos,
 		Qualified: false,
 	}
+	return id
 }
 
 // NewQualifiedIdentifier создает новый узел квалифицированного идентификатора
@@ -150,13 +152,15 @@ func NewQualifiedIdentifier(languageToken, nameToken lexer.Token, language, name
 		Column: languageToken.Column,
 		Offset: languageToken.Position,
 	}
-	return &Identifier{
+	id := AcquireIdentifier()
+	*id = Identifier{
 		Token:     nameToken,
 		Name:      name,
 		Pos:       pos,
 		Language:  language,
 		Qualified: true,
 	}
+	return id
 }
 
 // NewQualifiedIdentifierWithPath создает новый узел квалифицированного идентификатора с путем
@@ -166,7 +170,8 @@ func NewQualifiedIdentifierWithPath(languageToken, nameToken lexer.Token, langua
 		Column: languageToken.Column,
 		Offset: languageToken.Position,
 	}
-	return &Identifier{
+	id := AcquireIdentifier()
+	*id = Identifier{
 		Token:     nameToken,
 		Name:      name,
 		Pos:       pos,
@@ -174,6 +179,7 @@ func NewQualifiedIdentifierWithPath(languageToken, nameToken lexer.Token, langua
 		Path:      path,
 		Qualified: true,
 	}
+	return id
 }
 
 // expressionMarker реализует интерфейс Expression
@@ -31,6 +31,20 @@ const (
 	// Native Code Integration конструкции (Task 25)
 	ConstructImportStatement ConstructType = "import_statement" // Import конструкции
 	ConstructCodeBlock       ConstructType = "code_block"       // Code block конструкции
+	// Конвейеры стадий
+	ConstructPipeline ConstructType = "pipeline" // pipeline { stage ... }
+	ConstructRetry    ConstructType = "retry"    // retry(n) { ... }
+	ConstructForall   ConstructType = "forall"   // forall(x = gen.int(0, 9)) { ... }
+	ConstructLimit    ConstructType = "limit"    // rate_limit(...) { ... }, semaphore(...) { ... }
+	ConstructSelect   ConstructType = "select"   // select { recv(ch) { ... } ... }
+	ConstructHook     ConstructType = "hook"     // on_signal(...) { ... }, on_exit { ... }
+	ConstructContext  ConstructType = "context"  // cd(...) { ... }, with_env(...) { ... }
+	// Вызов, которому передается терминал
+	ConstructInteractive ConstructType = "interactive" // interactive sh.run(...)
+	// Перехват вывода
+	ConstructCapture ConstructType = "capture" // name = capture { ... }
+	// Подробность вывода
+	ConstructPragma ConstructType = "pragma" // @quiet ..., @verbose { ... }
 )
 
 // String возвращает строковое представление типа конструкции
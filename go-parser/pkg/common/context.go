@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fmt"
+
+	"go-parser/pkg/stream"
+)
+
+// DefaultMaxDepth - глубина вложенности, которую допускает контекст из NewParseContext
+const DefaultMaxDepth = 100
+
+// NewParseContext создает корневой контекст разбора потока со своей защитой от
+// рекурсии. Контексты вложенных конструкций создаются из него через WithStream,
+// чтобы вся глубина считалась одной защитой
+func NewParseContext(tokenStream stream.TokenStream) *ParseContext {
+	return &ParseContext{
+		TokenStream: tokenStream,
+		MaxDepth:    DefaultMaxDepth,
+		Guard:       NewRecursionGuard(DefaultMaxDepth),
+	}
+}
+
+// WithStream создает контекст вложенной конструкции, которая читает tokenStream
+// (часто клон потока ctx). Защита от рекурсии, глубина циклов и исходный код
+// наследуются; частичный разбор включает сам вызывающий
+func (ctx *ParseContext) WithStream(tokenStream stream.TokenStream) *ParseContext {
+	return &ParseContext{
+		TokenStream: tokenStream,
+		Lexer:       ctx.Lexer,
+		Parser:      ctx.Parser,
+		Depth:       ctx.Depth + 1,
+		MaxDepth:    ctx.MaxDepth,
+		Guard:       ctx.Guard,
+		LoopDepth:   ctx.LoopDepth,
+		InputStream: ctx.InputStream,
+	}
+}
+
+// depthGuard - RecursionGuard, считающий глубину входов
+type depthGuard struct {
+	maxDepth     int
+	currentDepth int
+}
+
+// NewRecursionGuard создает защиту, которая не пускает глубже maxDepth
+func NewRecursionGuard(maxDepth int) RecursionGuard {
+	return &depthGuard{maxDepth: maxDepth}
+}
+
+func (g *depthGuard) Enter() error {
+	if g.currentDepth >= g.maxDepth {
+		return fmt.Errorf("maximum recursion depth exceeded: %d", g.maxDepth)
+	}
+	g.currentDepth++
+	return nil
+}
+
+func (g *depthGuard) Exit() {
+	if g.currentDepth > 0 {
+		g.currentDepth--
+	}
+}
+
+func (g *depthGuard) CurrentDepth() int {
+	return g.currentDepth
+}
+
+func (g *depthGuard) MaxDepth() int {
+	return g.maxDepth
+}
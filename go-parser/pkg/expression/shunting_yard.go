@@ -25,11 +25,12 @@ const (
 	PrecedenceBitwiseAnd = 5  // &
 	PrecedenceEqual      = 6  // ==, !=
 	PrecedenceCompare    = 7  // <, >, <=, >=
-	PrecedenceAdd        = 8  // +, -
-	PrecedenceMul        = 9  // *, /, %
-	PrecedencePower      = 10 // **
-	PrecedenceUnary      = 11 // ~, ! (unary)
-	PrecedenceTernary    = 12 // ? :
+	PrecedenceRange      = 8  // .., ..<
+	PrecedenceAdd        = 9  // +, -
+	PrecedenceMul        = 10 // *, /, %
+	PrecedencePower      = 11 // **
+	PrecedenceUnary      = 12 // ~, ! (unary)
+	PrecedenceTernary    = 13 // ? :
 )
 
 // operatorPrecedence карта приоритетов операторов
@@ -45,6 +46,9 @@ var operatorPrecedence = map[lexer.TokenType]OperatorInfo{
 	lexer.TokenGreater:      {precedence: PrecedenceCompare, associative: true},
 	lexer.TokenLessEqual:    {precedence: PrecedenceCompare, associative: true},
 	lexer.TokenGreaterEqual: {precedence: PrecedenceCompare, associative: true},
+	lexer.TokenIn:           {precedence: PrecedenceCompare, associative: true},
+	lexer.TokenNotIn:        {precedence: PrecedenceCompare, associative: true},
+	lexer.TokenRange:        {precedence: PrecedenceRange, associative: true},
 	lexer.TokenPlus:         {precedence: PrecedenceAdd, associative: true},
 	lexer.TokenMinus:        {precedence: PrecedenceAdd, associative: true},
 	lexer.TokenMultiply:     {precedence: PrecedenceMul, associative: true},
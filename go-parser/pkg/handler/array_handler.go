@@ -67,15 +67,7 @@ func (h *ArrayHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
 
 		// Используем AssignmentHandler для парсинга сложных выражений в элементах массива
 		assignmentHandler := NewAssignmentHandler(100, 0)
-		assignmentCtx := &common.ParseContext{
-			TokenStream: ctx.TokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		assignmentCtx := ctx.WithStream(ctx.TokenStream)
 
 		element, err := assignmentHandler.parseComplexExpression(assignmentCtx)
 		if err != nil {
@@ -218,9 +218,16 @@ func (h *AssignmentHandler) Handle(ctx *common.ParseContext) (interface{}, error
 		fmt.Printf("DEBUG: AssignmentHandler - parsing value, currentToken: %v (%s)\n", currentToken, currentToken.Type)
 	}
 
-	// ПЕРВОЕ - проверяем, может ли быть присваивание (идентификатор/язык + =)
+	// Блок кода как значение: result = py { ... } или result = py (vars) { ... }
+	if isCodeBlockValue(ctx.TokenStream) {
+		block, err := NewCodeBlockHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose).Handle(ctx)
+		if err != nil {
+			return nil, err
+		}
+		value = block.(*ast.CodeBlockStatement)
+	} else if currentToken.Type == lexer.TokenIdentifier || currentToken.IsLanguageToken() {
+		// ПЕРВОЕ - проверяем, может ли быть присваивание (идентификатор/язык + =)
 	// Это нужно для цепочного присваивания (a = b = 3) и (lua.x = lua.y = 5)
-	if currentToken.Type == lexer.TokenIdentifier || currentToken.IsLanguageToken() {
 		if h.verbose {
 			fmt.Printf("DEBUG: AssignmentHandler - currentToken is identifier or language token, checking for chained assignment\n")
 		}
@@ -429,43 +436,21 @@ func (h *AssignmentHandler) Handle(ctx *common.ParseContext) (interface{}, error
 		// Для этого восстанавливаем позицию потока и парсим индекс
 		ctx.TokenStream.SetPosition(indexStart)
 
-		// Потребляем '['
-		ctx.TokenStream.Consume()
-
-		// Используем UnifiedExpressionParser для парсинга индексного выражения
-		exprParser := NewUnifiedExpressionParser(h.verbose)
-		indexExpr, err := exprParser.ParseExpression(ctx)
+		target, err := h.parseAssignmentIndex(ctx, identifier)
 		if err != nil {
-			return nil, newErrorWithPos(ctx.TokenStream, "failed to parse index expression: %v", err)
-		}
-
-		if h.verbose {
-			fmt.Printf("DEBUG: AssignmentHandler - successfully parsed index expression: %T\n", indexExpr)
-		}
-
-		// Проверяем и потребляем ']'
-		if !ctx.TokenStream.HasMore() || ctx.TokenStream.Current().Type != lexer.TokenRBracket {
-			return nil, newErrorWithPos(ctx.TokenStream, "expected ']' after index expression")
+			return nil, err
 		}
-		ctx.TokenStream.Consume()
 
 		// Восстанавливаем позицию ПОСЛЕ значения, чтобы корректно продвинуть поток
 		ctx.TokenStream.SetPosition(finalPos)
 
-		// Создаем IndexExpression
-		indexExpression := &ast.IndexExpression{
-			Object: identifier,
-			Index:  indexExpr,
-			Pos:    identifier.Position(),
-		}
-
 		if h.verbose {
 			fmt.Printf("DEBUG: AssignmentHandler - returning ExpressionAssignment with IndexExpression\n")
 		}
 
 		// Создаем и возвращаем ExpressionAssignment
 		return &ast.ExpressionAssignment{
-			Left:   indexExpression,
+			Left:   target,
 			Assign: assignToken,
 			Value:  value,
 		}, nil
@@ -474,6 +459,55 @@ func (h *AssignmentHandler) Handle(ctx *common.ParseContext) (interface{}, error
 	return ast.NewVariableAssignment(identifier, assignToken, value), nil
 }
 
+// parseAssignmentIndex парсит [index] или срез [start:end] слева от '=';
+// любая из границ среза может отсутствовать: arr[:2], arr[3:]. Индекс-диапазон
+// тоже присваивает срез: arr[2..<5] = [7, 8, 9]
+func (h *AssignmentHandler) parseAssignmentIndex(ctx *common.ParseContext, object ast.Expression) (ast.Expression, error) {
+	// Потребляем '['
+	ctx.TokenStream.Consume()
+
+	exprParser := NewUnifiedExpressionParser(h.verbose)
+	var start ast.Expression
+	if ctx.TokenStream.Current().Type != lexer.TokenColon {
+		index, err := exprParser.ParseExpression(ctx)
+		if err != nil {
+			return nil, newErrorWithPos(ctx.TokenStream, "failed to parse index expression: %v", err)
+		}
+		start = index
+	}
+
+	isSlice := ctx.TokenStream.HasMore() && ctx.TokenStream.Current().Type == lexer.TokenColon
+	var end ast.Expression
+	if isSlice {
+		ctx.TokenStream.Consume()
+		if ctx.TokenStream.HasMore() && ctx.TokenStream.Current().Type != lexer.TokenRBracket {
+			bound, err := exprParser.ParseExpression(ctx)
+			if err != nil {
+				return nil, newErrorWithPos(ctx.TokenStream, "failed to parse slice end: %v", err)
+			}
+			end = bound
+		}
+	}
+
+	// Проверяем и потребляем ']'
+	if !ctx.TokenStream.HasMore() || ctx.TokenStream.Current().Type != lexer.TokenRBracket {
+		return nil, newErrorWithPos(ctx.TokenStream, "expected ']' after index expression")
+	}
+	ctx.TokenStream.Consume()
+
+	if isSlice {
+		return ast.NewSliceExpression(object, start, end, object.Position()), nil
+	}
+	if start == nil {
+		return nil, newErrorWithPos(ctx.TokenStream, "expected index expression")
+	}
+	return &ast.IndexExpression{
+		Object: object,
+		Index:  start,
+		Pos:    object.Position(),
+	}, nil
+}
+
 // Config возвращает конфигурацию обработчика
 func (h *AssignmentHandler) Config() common.HandlerConfig {
 	return h.config
@@ -799,7 +833,8 @@ func (h *AssignmentHandler) parseLanguageCallValue(tokenStream stream.TokenStrea
 				return nil, fmt.Errorf("unexpected EOF in arguments")
 			}
 
-			// Читаем аргумент
+			// Читаем аргумент, именованный аргумент начинается с name=
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			argToken := tokenStream.Current()
 			var arg ast.Expression
 
@@ -834,15 +869,7 @@ func (h *AssignmentHandler) parseLanguageCallValue(tokenStream stream.TokenStrea
 			case lexer.TokenLBrace:
 				// Обработка объектных литералов как аргументов функции
 				// Создаем временный контекст для парсинга объекта
-				tempCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       0,
-					MaxDepth:    100,
-					Guard:       &simpleRecursionGuard{maxDepth: 100},
-					LoopDepth:   0,
-					InputStream: "",
-				}
+				tempCtx := common.NewParseContext(tokenStream)
 				// Используем ObjectHandler для парсинга объекта
 				// Важно: не потребляем LBRACE здесь, ObjectHandler сделает это сам
 				objectHandler := NewObjectHandler(100, 0)
@@ -861,7 +888,9 @@ func (h *AssignmentHandler) parseLanguageCallValue(tokenStream stream.TokenStrea
 				return nil, fmt.Errorf("unsupported argument type: %s", argToken.Type)
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
@@ -902,6 +931,14 @@ func (h *AssignmentHandler) parseLanguageCallValue(tokenStream stream.TokenStrea
 		Pos:       startPos,
 	}
 
+	// 9. Цепочка методов и необязательный суффикс политики результата (!nowait, !raw)
+	if err := parseCallMethodChain(tokenStream, node); err != nil {
+		return nil, err
+	}
+	if err := parseResultPolicy(tokenStream, node); err != nil {
+		return nil, err
+	}
+
 	return node, nil
 }
 
@@ -1051,15 +1088,7 @@ func (h *AssignmentHandler) parseExpression(tokenStream stream.TokenStream, toke
 // parseParenthesizedExpression парсит выражение в скобках
 func (h *AssignmentHandler) parseParenthesizedExpression(tokenStream stream.TokenStream, leftParenToken lexer.Token) (ast.Expression, error) {
 	// Создаем временный контекст для парсинга выражения в скобках
-	tempCtx := &common.ParseContext{
-		TokenStream: tokenStream,
-		Parser:      nil,
-		Depth:       0,
-		MaxDepth:    100,
-		Guard:       &simpleRecursionGuard{maxDepth: 100},
-		LoopDepth:   0,
-		InputStream: "",
-	}
+	tempCtx := common.NewParseContext(tokenStream)
 
 	// Используем BinaryExpressionHandler для парсинга выражения внутри скобок
 	binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
@@ -1091,6 +1120,10 @@ func (h *AssignmentHandler) parseComplexIdentifier(tokenStream stream.TokenStrea
 	if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenDot {
 		// Это может быть квалифицированная переменная или вызов функции
 		return h.parseQualifiedIdentifierOrFunctionCall(tokenStream, firstToken)
+	} else if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLeftParen {
+		// Вызов встроенной функции в сегменте (например, size_of(body))
+		bitstringHandler := NewBitstringHandler(config.ConstructHandlerConfig{})
+		return bitstringHandler.parseBuiltinCall(tokenStream, firstToken)
 	} else {
 		// Простой идентификатор
 		return ast.NewIdentifier(firstToken, firstToken.Value), nil
@@ -1216,13 +1249,19 @@ func (h *AssignmentHandler) parseFunctionCall(tokenStream stream.TokenStream, la
 				return nil, fmt.Errorf("unexpected EOF in function arguments")
 			}
 
-			// Читаем аргумент
+			// Читаем аргумент, именованный аргумент начинается с name=
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			argToken := tokenStream.Consume()
 			arg, err := h.parseExpression(tokenStream, argToken)
+			if err == nil {
+				arg, err = continueOperandExpression(tokenStream, arg)
+			}
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse function argument: %v", err)
 			}
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель
 			if !tokenStream.HasMore() {
@@ -1247,12 +1286,16 @@ func (h *AssignmentHandler) parseFunctionCall(tokenStream stream.TokenStream, la
 
 	// Создаем узел LanguageCall
 	startPos := tokenToPosition(languageToken)
-	return &ast.LanguageCall{
+	node := &ast.LanguageCall{
 		Language:  resolvedLanguage,
 		Function:  functionName,
 		Arguments: arguments,
 		Pos:       startPos,
-	}, nil
+	}
+	if err := parseCallMethodChain(tokenStream, node); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // parseFunctionCallWithParts парсит вызов функции с уже собранными частями имени
@@ -1282,7 +1325,8 @@ func (h *AssignmentHandler) parseFunctionCallWithParts(tokenStream stream.TokenS
 				return nil, fmt.Errorf("unexpected EOF in function arguments")
 			}
 
-			// Читаем аргумент
+			// Читаем аргумент, именованный аргумент начинается с name=
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			argToken := tokenStream.Current()
 			var arg ast.Expression
 			var err error
@@ -1290,15 +1334,7 @@ func (h *AssignmentHandler) parseFunctionCallWithParts(tokenStream stream.TokenS
 			// Проверяем, не является ли это унарным оператором
 			if h.isUnaryOperator(argToken.Type) {
 				// Создаем временный контекст для парсинга унарного выражения
-				tempCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       0,
-					MaxDepth:    100,
-					Guard:       &simpleRecursionGuard{maxDepth: 100},
-					LoopDepth:   0,
-					InputStream: "",
-				}
+				tempCtx := common.NewParseContext(tokenStream)
 				// Используем UnaryExpressionHandler для парсинга унарных выражений
 				unaryHandler := NewUnaryExpressionHandler(config.ConstructHandlerConfig{ConstructType: common.ConstructUnaryExpression})
 				result, err := unaryHandler.Handle(tempCtx)
@@ -1320,7 +1356,12 @@ func (h *AssignmentHandler) parseFunctionCallWithParts(tokenStream stream.TokenS
 					return nil, fmt.Errorf("failed to parse function argument: %v", err)
 				}
 			}
-			arguments = append(arguments, arg)
+			if arg, err = continueOperandExpression(tokenStream, arg); err != nil {
+				return nil, fmt.Errorf("failed to parse function argument: %v", err)
+			}
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель
 			if !tokenStream.HasMore() {
@@ -1345,12 +1386,16 @@ func (h *AssignmentHandler) parseFunctionCallWithParts(tokenStream stream.TokenS
 
 	// Создаем узел LanguageCall
 	startPos := tokenToPosition(languageToken)
-	return &ast.LanguageCall{
+	node := &ast.LanguageCall{
 		Language:  resolvedLanguage,
 		Function:  functionName,
 		Arguments: arguments,
 		Pos:       startPos,
-	}, nil
+	}
+	if err := parseCallMethodChain(tokenStream, node); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // parseQualifiedVariableWithPath парсит квалифицированную переменную с путем (language.part1.part2.variable)
@@ -1398,34 +1443,6 @@ func (h *AssignmentHandler) parseQualifiedVariableWithPath(tokenStream stream.To
 	return identifier, nil
 }
 
-// simpleRecursionGuard - простая реализация защиты от рекурсии
-type simpleRecursionGuard struct {
-	maxDepth     int
-	currentDepth int
-}
-
-func (rg *simpleRecursionGuard) Enter() error {
-	if rg.currentDepth >= rg.maxDepth {
-		return fmt.Errorf("maximum recursion depth exceeded: %d", rg.maxDepth)
-	}
-	rg.currentDepth++
-	return nil
-}
-
-func (rg *simpleRecursionGuard) Exit() {
-	if rg.currentDepth > 0 {
-		rg.currentDepth--
-	}
-}
-
-func (rg *simpleRecursionGuard) CurrentDepth() int {
-	return rg.currentDepth
-}
-
-func (rg *simpleRecursionGuard) MaxDepth() int {
-	return rg.maxDepth
-}
-
 // isBitstringConcatenation проверяет, является ли битовая строка конкатенацией
 // (переменные без спецификаторов размера и типа)
 func (h *AssignmentHandler) isBitstringConcatenation(tokenStream stream.TokenStream) bool {
@@ -1583,7 +1600,7 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 	switch currentToken.Type {
 	case lexer.TokenIdentifier:
 		// Проверяем, не является ли это вызовом builtin функции
-		if tokenStream.HasMore() && tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if tokenStream.HasMore() && isBuiltinCallStart(tokenStream) {
 			// Это вызов builtin функции
 			builtinHandler := NewBuiltinFunctionHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose)
 			result, err := builtinHandler.Handle(ctx)
@@ -1591,7 +1608,7 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 				return nil, fmt.Errorf("failed to parse builtin function call: %v", err)
 			}
 
-			builtinCall, ok := result.(*ast.BuiltinFunctionCall)
+			builtinCall, ok := result.(ast.Expression)
 			if !ok {
 				return nil, fmt.Errorf("expected BuiltinFunctionCall, got %T", result)
 			}
@@ -1601,10 +1618,28 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 			// Простой идентификатор
 			leftExpr = ast.NewIdentifier(currentToken, currentToken.Value)
 			tokenStream.Consume()
+
+			// Индексы после идентификатора: arr[1], arr[1..3]
+			binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
+			for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLBracket {
+				indexed, err := binaryHandler.ParseIndexExpression(ctx, leftExpr)
+				if err != nil {
+					return nil, err
+				}
+				leftExpr = indexed
+			}
 		}
 	case lexer.TokenNil:
 		leftExpr = ast.NewNilLiteral(currentToken)
 		tokenStream.Consume()
+	case lexer.TokenTry:
+		// try py.call(): ok(результат) или error(описание ошибки)
+		binaryHandler := NewBinaryExpressionHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose)
+		tryExpr, err := binaryHandler.parseBasicOperand(ctx)
+		if err != nil {
+			return nil, err
+		}
+		leftExpr = tryExpr
 	default:
 		// Проверяем, является ли токен языковым токеном
 		if currentToken.IsLanguageToken() {
@@ -1645,15 +1680,7 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 
 				// Парсим индексное выражение
 				binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
-				tempCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      ctx.Parser,
-					Depth:       ctx.Depth,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				tempCtx := ctx.WithStream(tokenStream)
 
 				// Сначала парсим первый операнд индексного выражения
 				leftOperand, err := binaryHandler.parseOperand(tempCtx)
@@ -1692,15 +1719,19 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 			return nil, fmt.Errorf("unsupported expression start: %s", currentToken.Type)
 		}
 	case lexer.TokenString:
-		leftExpr = &ast.StringLiteral{
+		tokenStream.Consume()
+		stringExpr, err := parseValueMethodCall(tokenStream, &ast.StringLiteral{
 			Value: currentToken.Value,
 			Pos: ast.Position{
 				Line:   currentToken.Line,
 				Column: currentToken.Column,
 				Offset: currentToken.Position,
 			},
+		})
+		if err != nil {
+			return nil, err
 		}
-		tokenStream.Consume()
+		leftExpr = stringExpr
 	case lexer.TokenNumber:
 		// Используем parseNumber для поддержки big.Int, hex, binary и decimal форматов
 		numValue, err := parseNumber(currentToken.Value)
@@ -1750,15 +1781,7 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 	case lexer.TokenLBrace:
 		// Обработка объектов как сложных выражений
 		objectHandler := NewObjectHandler(100, 0)
-		objectCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		objectCtx := ctx.WithStream(tokenStream)
 		objectResult, err := objectHandler.Handle(objectCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse object expression: %v", err)
@@ -1771,15 +1794,7 @@ func (h *AssignmentHandler) parseComplexExpression(ctx *common.ParseContext) (as
 	case lexer.TokenLBracket:
 		// Обработка массивов как сложных выражений
 		arrayHandler := NewArrayHandler(100, 0)
-		arrayCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		arrayCtx := ctx.WithStream(tokenStream)
 		arrayResult, err := arrayHandler.Handle(arrayCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse array expression: %v", err)
@@ -1939,7 +1954,8 @@ func (h *AssignmentHandler) parseNestedFunctionCall(tokenStream stream.TokenStre
 				return nil, fmt.Errorf("unexpected EOF in arguments")
 			}
 
-			// Читаем аргумент
+			// Читаем аргумент, именованный аргумент начинается с name=
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			argToken := tokenStream.Consume()
 			var arg ast.Expression
 
@@ -1961,7 +1977,9 @@ func (h *AssignmentHandler) parseNestedFunctionCall(tokenStream stream.TokenStre
 				return nil, fmt.Errorf("unsupported argument type: %s", argToken.Type)
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
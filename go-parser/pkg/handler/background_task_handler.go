@@ -47,7 +47,13 @@ func (h *BackgroundTaskHandler) CanHandle(token lexer.Token) bool {
 		token.Type == lexer.TokenPy ||
 		token.Type == lexer.TokenGo ||
 		token.Type == lexer.TokenNode ||
-		token.Type == lexer.TokenJS
+		token.Type == lexer.TokenJS ||
+		token.Type == lexer.TokenPerl ||
+		token.Type == lexer.TokenPl ||
+		token.Type == lexer.TokenErlang ||
+		token.Type == lexer.TokenErl ||
+		token.Type == lexer.TokenElixir ||
+		token.Type == lexer.TokenEx
 }
 
 // Handle обрабатывает background task
@@ -67,11 +73,15 @@ func (h *BackgroundTaskHandler) Handle(ctx *common.ParseContext) (interface{}, e
 			}
 		}
 
-		// Возвращаемся к началу потока для анализа
+		// Возвращаемся к началу потока для анализа, а затем снова встаем
+		// после &: иначе парсер вернется к началу и зациклится
+		afterAmpersand := tokenStream.Position()
 		tokenStream.SetPosition(0)
 
 		// Создаем простую реализацию для теста
-		return h.parseBackgroundTaskFromBeginning(tokenStream, ampersandToken)
+		result, err := h.parseBackgroundTaskFromBeginning(tokenStream, ampersandToken)
+		tokenStream.SetPosition(afterAmpersand)
+		return result, err
 	}
 
 	// Проверяем, не является ли это кодовым блоком
@@ -217,7 +227,7 @@ func (h *BackgroundTaskHandler) createLanguageCallStatementFromTokens(tokens []l
 	}
 
 	// Проверяем, является ли это простым language call
-	if len(tokens) >= 3 && (tokens[0].Type == lexer.TokenLua || tokens[0].Type == lexer.TokenPython || tokens[0].Type == lexer.TokenPy || tokens[0].Type == lexer.TokenGo || tokens[0].Type == lexer.TokenNode || tokens[0].Type == lexer.TokenJS) && tokens[1].Type == lexer.TokenDot {
+	if len(tokens) >= 3 && (tokens[0].Type == lexer.TokenLua || tokens[0].Type == lexer.TokenPython || tokens[0].Type == lexer.TokenPy || tokens[0].Type == lexer.TokenGo || tokens[0].Type == lexer.TokenNode || tokens[0].Type == lexer.TokenJS || tokens[0].Type == lexer.TokenPerl || tokens[0].Type == lexer.TokenPl || tokens[0].Type == lexer.TokenErlang || tokens[0].Type == lexer.TokenErl || tokens[0].Type == lexer.TokenElixir || tokens[0].Type == lexer.TokenEx) && tokens[1].Type == lexer.TokenDot {
 		return h.createSimpleLanguageCallStatement(tokens[0], tokens[2], ampersandToken)
 	}
 
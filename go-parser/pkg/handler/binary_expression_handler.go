@@ -102,15 +102,7 @@ func (h *BinaryExpressionHandler) Handle(ctx *common.ParseContext) (interface{},
 		// Это может быть language call или qualified variable другого языка
 		// Клонируем поток для проверки
 		tempStream := tokenStream.Clone()
-		tempCtx := &common.ParseContext{
-			TokenStream: tempStream,
-			Parser:      ctx.Parser,
-			Depth:       ctx.Depth,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		tempCtx := ctx.WithStream(tempStream)
 		_, err := h.parseLanguageCallOrField(tempCtx)
 		if err != nil {
 			// Если это не валидный language call, позволяем другим handlers попробовать
@@ -147,15 +139,7 @@ func (h *BinaryExpressionHandler) Handle(ctx *common.ParseContext) (interface{},
 		// Это квалифицированная переменная
 		// Клонируем поток для проверки
 		tempStream := tokenStream.Clone()
-		tempCtx := &common.ParseContext{
-			TokenStream: tempStream,
-			Parser:      ctx.Parser,
-			Depth:       ctx.Depth,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		tempCtx := ctx.WithStream(tempStream)
 		_, err := h.parseQualifiedVariable(tempCtx)
 		if err != nil {
 			// Если это не валидная qualified variable, позволяем другим handlers попробовать
@@ -658,7 +642,7 @@ func (h *BinaryExpressionHandler) parseBasicOperand(ctx *common.ParseContext) (a
 	switch token.Type {
 	case lexer.TokenIdentifier:
 		// Проверяем, не является ли это вызовом builtin функции
-		if tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			// Это вызов builtin функции типа len(x)
 			builtinHandler := NewBuiltinFunctionHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose)
 			result, err := builtinHandler.Handle(ctx)
@@ -666,7 +650,7 @@ func (h *BinaryExpressionHandler) parseBasicOperand(ctx *common.ParseContext) (a
 				return nil, fmt.Errorf("failed to parse builtin function call: %v", err)
 			}
 
-			builtinCall, ok := result.(*ast.BuiltinFunctionCall)
+			builtinCall, ok := result.(ast.Expression)
 			if !ok {
 				return nil, fmt.Errorf("expected BuiltinFunctionCall, got %T", result)
 			}
@@ -764,6 +748,17 @@ func (h *BinaryExpressionHandler) parseBasicOperand(ctx *common.ParseContext) (a
 		}
 		return nil, newErrorWithTokenPos(token, "unsupported operand type: %s", token.Type)
 
+	case lexer.TokenTry:
+		// try: ошибка операнда становится значением error(...), результат - ok(...)
+		tokenStream.Consume() // потребляем 'try'
+
+		operand, err := h.parseBasicOperand(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse operand after try: %v", err)
+		}
+
+		return ast.NewTryExpression(token, operand, tokenToPosition(token)), nil
+
 	case lexer.TokenMinus:
 		// Унарный минус
 		tokenStream.Consume() // потребляем '-'
@@ -808,16 +803,16 @@ func (h *BinaryExpressionHandler) parseBasicOperand(ctx *common.ParseContext) (a
 		return createNumberLiteral(token, numValue), nil
 
 	case lexer.TokenString:
-		// Строковой литерал
+		// Строковой литерал, возможно с вызовом метода: "a,b".split(",")
 		tokenStream.Consume()
-		return &ast.StringLiteral{
+		return parseValueMethodCall(tokenStream, &ast.StringLiteral{
 			Value: token.Value,
 			Pos: ast.Position{
 				Line:   token.Line,
 				Column: token.Column,
 				Offset: token.Position,
 			},
-		}, nil
+		})
 
 	case lexer.TokenTrue, lexer.TokenFalse:
 		// Булев литерал
@@ -861,6 +856,31 @@ func (h *BinaryExpressionHandler) parseBasicOperand(ctx *common.ParseContext) (a
 
 		return currentExpr, nil
 
+	case lexer.TokenLBrace:
+		// Object literal - используем ObjectHandler (например, правая часть "k" in {"k": 1})
+		objectHandler := NewObjectHandler(200, 10)
+		result, err := objectHandler.Handle(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse object literal: %v", err)
+		}
+
+		objectLiteral, ok := result.(*ast.ObjectLiteral)
+		if !ok {
+			return nil, fmt.Errorf("expected ObjectLiteral, got %T", result)
+		}
+
+		// Парсим все индексные выражения [key][key]... в цикле
+		currentExpr := ast.Expression(objectLiteral)
+		for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLBracket {
+			indexExpr, err := h.ParseIndexExpression(ctx, currentExpr)
+			if err != nil {
+				return nil, err
+			}
+			currentExpr = indexExpr
+		}
+
+		return currentExpr, nil
+
 	case lexer.TokenLeftParen:
 		// Выражение в скобках
 		tokenStream.Consume() // потребляем '('
@@ -936,12 +956,15 @@ func (h *BinaryExpressionHandler) parseLanguageCall(ctx *common.ParseContext) (a
 			}
 
 			// Читаем аргумент
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			arg, err := h.parseOperand(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse argument: %v", err)
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
@@ -980,12 +1003,19 @@ func (h *BinaryExpressionHandler) parseLanguageCall(ctx *common.ParseContext) (a
 		Offset: languageToken.Position,
 	}
 
-	return &ast.LanguageCall{
+	node := &ast.LanguageCall{
 		Language:  language,
 		Function:  functionName,
 		Arguments: arguments,
 		Pos:       startPos,
-	}, nil
+	}
+	if err := parseCallMethodChain(tokenStream, node); err != nil {
+		return nil, err
+	}
+	if err := parseResultPolicy(tokenStream, node); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // parseQualifiedVariable парсит квалифицированную переменную или вызов функции
@@ -1111,12 +1141,15 @@ func (h *BinaryExpressionHandler) parseQualifiedVariable(ctx *common.ParseContex
 					}
 
 					// Читаем аргумент
+					argName, kind := consumeArgumentPrefix(tokenStream)
 					arg, err := h.parseOperand(ctx)
 					if err != nil {
 						return nil, fmt.Errorf("failed to parse argument: %v", err)
 					}
 
-					arguments = append(arguments, arg)
+					if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+						return nil, err
+					}
 
 					// Проверяем разделитель или конец
 					if !tokenStream.HasMore() {
@@ -1155,12 +1188,19 @@ func (h *BinaryExpressionHandler) parseQualifiedVariable(ctx *common.ParseContex
 				Offset: firstToken.Position,
 			}
 
-			return &ast.LanguageCall{
+			node := &ast.LanguageCall{
 				Language:  language,
 				Function:  functionName,
 				Arguments: arguments,
 				Pos:       startPos,
-			}, nil
+			}
+			if err := parseCallMethodChain(tokenStream, node); err != nil {
+				return nil, err
+			}
+			if err := parseResultPolicy(tokenStream, node); err != nil {
+				return nil, err
+			}
+			return node, nil
 		}
 
 		// Если следующий токен - точка, то это часть пути
@@ -1210,18 +1250,26 @@ func (h *BinaryExpressionHandler) ParseIndexExpression(ctx *common.ParseContext,
 	// Потребляем открывающую скобку
 	tokenStream.Consume()
 
-	// Парсим индексное выражение
-	// Используем parseOperand чтобы получить первый операнд
-	indexExpr, err := h.parseOperand(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse index expression: %v", err)
+	// Парсим индексное выражение; в срезе arr[:5] начала нет
+	var indexExpr ast.Expression
+	if tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenColon {
+		var err error
+		if indexExpr, err = h.parseIndexBound(ctx); err != nil {
+			return nil, fmt.Errorf("failed to parse index expression: %v", err)
+		}
 	}
 
-	// Потом парсим полное выражение, включая бинарные операторы
-	// Это позволяет обработать выражения типа (1 + 2) * 0
-	indexExpr, err = h.ParseFullExpression(ctx, indexExpr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse index expression: %v", err)
+	// Срез arr[2:5], конец тоже может отсутствовать: arr[2:]
+	isSlice := tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenColon
+	var endExpr ast.Expression
+	if isSlice {
+		tokenStream.Consume()
+		if tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRBracket {
+			var err error
+			if endExpr, err = h.parseIndexBound(ctx); err != nil {
+				return nil, fmt.Errorf("failed to parse slice end: %v", err)
+			}
+		}
 	}
 
 	// Проверяем наличие закрывающей квадратной скобки
@@ -1232,10 +1280,30 @@ func (h *BinaryExpressionHandler) ParseIndexExpression(ctx *common.ParseContext,
 	// Потребляем закрывающую скобку
 	tokenStream.Consume()
 
+	if isSlice {
+		return ast.NewSliceExpression(object, indexExpr, endExpr, object.Position()), nil
+	}
+	if indexExpr == nil {
+		return nil, fmt.Errorf("expected index expression")
+	}
+
 	// Создаем индексное выражение
 	return ast.NewIndexExpression(object, indexExpr, object.Position()), nil
 }
 
+// parseIndexBound парсит индекс или границу среза внутри [...]
+func (h *BinaryExpressionHandler) parseIndexBound(ctx *common.ParseContext) (ast.Expression, error) {
+	// Используем parseOperand чтобы получить первый операнд
+	operand, err := h.parseOperand(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Потом парсим полное выражение, включая бинарные операторы
+	// Это позволяет обработать выражения типа (1 + 2) * 0
+	return h.ParseFullExpression(ctx, operand)
+}
+
 // isBitstringPattern проверяет, является ли << началом битовой строки или оператором сдвига
 func (h *BinaryExpressionHandler) isBitstringPattern(tokenStream stream.TokenStream) bool {
 	// Сохраняем текущую позицию
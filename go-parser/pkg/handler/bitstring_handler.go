@@ -355,15 +355,7 @@ func (h *BitstringHandler) parseParenthesizedExpression(tokenStream stream.Token
 	}
 
 	// Создаем временный контекст для парсинга выражения в скобках
-	tempCtx := &common.ParseContext{
-		TokenStream: tokenStream,
-		Parser:      nil,
-		Depth:       0,
-		MaxDepth:    100,
-		Guard:       &simpleRecursionGuard{maxDepth: 100},
-		LoopDepth:   0,
-		InputStream: "",
-	}
+	tempCtx := common.NewParseContext(tokenStream)
 
 	// Используем BinaryExpressionHandler для парсинга выражения внутри скобок
 	binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
@@ -395,6 +387,9 @@ func (h *BitstringHandler) parseComplexIdentifier(tokenStream stream.TokenStream
 	if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenDot {
 		// Это может быть квалифицированная переменная или вызов функции
 		return h.parseQualifiedIdentifierOrFunctionCall(tokenStream, firstToken)
+	} else if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLeftParen {
+		// Вызов встроенной функции
+		return h.parseBuiltinCall(tokenStream, firstToken)
 	} else {
 		// Простой идентификатор
 		return ast.NewIdentifier(firstToken, firstToken.Value), nil
@@ -471,7 +466,34 @@ func (h *BitstringHandler) parseFunctionCall(tokenStream stream.TokenStream, lan
 		functionName += part
 	}
 
-	// Парсим аргументы
+	arguments, err := h.parseCallArguments(tokenStream)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем узел LanguageCall
+	startPos := tokenToPosition(languageToken)
+	return &ast.LanguageCall{
+		Language:  resolvedLanguage,
+		Function:  functionName,
+		Arguments: arguments,
+		Pos:       startPos,
+	}, nil
+}
+
+// parseBuiltinCall парсит вызов встроенной функции в сегменте (например, size_of(body))
+func (h *BitstringHandler) parseBuiltinCall(tokenStream stream.TokenStream, functionToken lexer.Token) (ast.Expression, error) {
+	tokenStream.Consume() // потребляем '('
+
+	arguments, err := h.parseCallArguments(tokenStream)
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewBuiltinFunctionCall(functionToken.Value, arguments, tokenToPosition(functionToken)), nil
+}
+
+// parseCallArguments парсит аргументы вызова после '(' вместе с закрывающей скобкой
+func (h *BitstringHandler) parseCallArguments(tokenStream stream.TokenStream) ([]ast.Expression, error) {
 	arguments := make([]ast.Expression, 0)
 
 	if tokenStream.Current().Type != lexer.TokenRightParen {
@@ -510,14 +532,7 @@ func (h *BitstringHandler) parseFunctionCall(tokenStream stream.TokenStream, lan
 	}
 	tokenStream.Consume()
 
-	// Создаем узел LanguageCall
-	startPos := tokenToPosition(languageToken)
-	return &ast.LanguageCall{
-		Language:  resolvedLanguage,
-		Function:  functionName,
-		Arguments: arguments,
-		Pos:       startPos,
-	}, nil
+	return arguments, nil
 }
 
 // parseQualifiedVariableWithPath парсит квалифицированную переменную с путем (language.part1.part2.variable)
@@ -629,6 +644,16 @@ func (h *BitstringHandler) parseExpressionWithShuntingYard(tokens []lexer.Token)
 			i++
 
 		case lexer.TokenIdentifier:
+			if i+1 < len(tokens) && tokens[i+1].Type == lexer.TokenLeftParen {
+				// Вызов встроенной функции (например, byte_size(magic)) - тоже операнд
+				call, next, err := h.parseSizeCall(tokens, i)
+				if err != nil {
+					return nil, err
+				}
+				outputQueue = append(outputQueue, call)
+				i = next
+				continue
+			}
 			// Идентификаторы идут в output queue
 			expr := h.createIdentifier(token)
 			outputQueue = append(outputQueue, expr)
@@ -723,6 +748,43 @@ func (h *BitstringHandler) parseExpressionWithShuntingYard(tokens []lexer.Token)
 	return outputQueue[0], nil
 }
 
+// parseSizeCall разбирает вызов функции tokens[start](...) в выражении размера
+// и возвращает его вместе с индексом токена после закрывающей скобки
+func (h *BitstringHandler) parseSizeCall(tokens []lexer.Token, start int) (ast.Expression, int, error) {
+	var arguments []ast.Expression
+	depth := 0
+	argStart := start + 2
+	for i := start + 1; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case lexer.TokenLeftParen:
+			depth++
+		case lexer.TokenRightParen:
+			depth--
+			if depth == 0 {
+				if i > argStart {
+					arg, err := h.parseExpressionWithShuntingYard(tokens[argStart:i])
+					if err != nil {
+						return nil, 0, err
+					}
+					arguments = append(arguments, arg)
+				}
+				return ast.NewBuiltinFunctionCall(tokens[start].Value, arguments, tokenToPosition(tokens[start])), i + 1, nil
+			}
+		case lexer.TokenComma:
+			if depth == 1 {
+				arg, err := h.parseExpressionWithShuntingYard(tokens[argStart:i])
+				if err != nil {
+					return nil, 0, err
+				}
+				arguments = append(arguments, arg)
+				argStart = i + 1
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("несбалансированная скобка: отсутствует ')' после %s в строке %d, колонка %d",
+		tokens[start].Value, tokens[start].Line, tokens[start].Column)
+}
+
 // BitstringOperatorInfo содержит информацию об операторе
 type BitstringOperatorInfo struct {
 	precedence  int
@@ -51,6 +51,13 @@ func (h *BuiltinFunctionHandler) Handle(ctx *common.ParseContext) (interface{},
 		fmt.Printf("DEBUG: BuiltinFunctionHandler - function name: %s\n", functionName)
 	}
 
+	// 1.1. Вызов метода объекта вида obj.method(...) - имя функции становится "obj.method"
+	if isMethodCallStart(tokenStream, functionName) {
+		tokenStream.Consume() // Consuming '.'
+		methodToken := tokenStream.Consume()
+		functionName = functionName + "." + methodToken.Value
+	}
+
 	// 2. Проверяем и потребляем открывающую скобку
 	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenLeftParen {
 		// Если после идентификатора нет скобки, это не вызов функции - позволяем другим обработчикам попробовать
@@ -136,6 +143,21 @@ func (h *BuiltinFunctionHandler) Handle(ctx *common.ParseContext) (interface{},
 		Offset: functionToken.Position,
 	}
 
+	// 5.1. Цепочка методов на результате obj.method(...) разбирается как вызов
+	// языка, где вместо языка стоит имя переменной, как в выражениях
+	if objectName, method, isMethod := strings.Cut(functionName, "."); isMethod && isMethodCallStart(tokenStream, objectName) {
+		call := &ast.LanguageCall{
+			Language:  objectName,
+			Function:  method,
+			Arguments: arguments,
+			Pos:       startPos,
+		}
+		if err := parseCallMethodChain(tokenStream, call); err != nil {
+			return nil, err
+		}
+		return call, nil
+	}
+
 	node := ast.NewBuiltinFunctionCall(functionName, arguments, startPos)
 
 	if h.verbose {
@@ -145,6 +167,35 @@ func (h *BuiltinFunctionHandler) Handle(ctx *common.ParseContext) (interface{},
 	return node, nil
 }
 
+// isMethodCallStart проверяет, что после имени объекта идет ".method(".
+// Алиасы языков (например, l.print()) остаются за LanguageCallHandler
+func isMethodCallStart(tokenStream stream.TokenStream, objectName string) bool {
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenDot ||
+		tokenStream.Peek().Type != lexer.TokenIdentifier ||
+		tokenStream.PeekN(2).Type != lexer.TokenLeftParen {
+		return false
+	}
+	return !CreateDefaultLanguageRegistry().IsLanguageSupported(objectName)
+}
+
+// isBuiltinCallStart проверяет, начинается ли с текущего идентификатора вызов
+// builtin функции name(...) или метода объекта obj.method(...)
+func isBuiltinCallStart(tokenStream stream.TokenStream) bool {
+	current := tokenStream.Current()
+	if current.Type != lexer.TokenIdentifier {
+		return false
+	}
+	if tokenStream.Peek().Type == lexer.TokenLeftParen {
+		return true
+	}
+	if tokenStream.Peek().Type != lexer.TokenDot ||
+		tokenStream.PeekN(2).Type != lexer.TokenIdentifier ||
+		tokenStream.PeekN(3).Type != lexer.TokenLeftParen {
+		return false
+	}
+	return !CreateDefaultLanguageRegistry().IsLanguageSupported(current.Value)
+}
+
 // extractFunctionNameFromFieldAccess извлекает полное имя функции из FieldAccess
 func (h *BuiltinFunctionHandler) extractFunctionNameFromFieldAccess(fieldAccess *ast.FieldAccess) string {
 	var parts []string
@@ -173,7 +224,7 @@ func (h *BuiltinFunctionHandler) extractFunctionNameFromFieldAccess(fieldAccess
 func (h *BuiltinFunctionHandler) isLiteralToken(tokenType lexer.TokenType) bool {
 	switch tokenType {
 	case lexer.TokenNumber, lexer.TokenString, lexer.TokenTrue, lexer.TokenFalse, lexer.TokenNil,
-		lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS,
+		lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx,
 		lexer.TokenLBracket, lexer.TokenLBrace, lexer.TokenDoubleLeftAngle, lexer.TokenLeftParen, lexer.TokenAt, lexer.TokenMinus:
 		return true
 	default:
@@ -217,7 +268,7 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 	switch token.Type {
 	case lexer.TokenString:
 		tokenStream.Consume()
-		return &ast.StringLiteral{Value: token.Value, Raw: token.Value, Pos: tokenToPosition(token)}, nil
+		return parseValueMethodCall(tokenStream, &ast.StringLiteral{Value: token.Value, Raw: token.Value, Pos: tokenToPosition(token)})
 
 	case lexer.TokenNumber:
 		tokenStream.Consume()
@@ -227,7 +278,7 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 		}
 		return createNumberLiteral(token, numValue), nil
 
-	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		// First check if this is a function call (has opening paren)
 		if tokenStream.HasMore() && tokenStream.Peek().Type == lexer.TokenLeftParen {
 			// This is a function call - use BinaryExpressionHandler to parse it
@@ -239,6 +290,16 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 			return expr, nil
 		}
 
+		// An index or a slice of a variable, maybe inside an expression: arr[1] + 1, arr[2:5]
+		if token.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenLBracket {
+			exprParser := NewUnifiedExpressionParser(h.verbose)
+			expr, err := exprParser.ParseExpression(ctx)
+			if err != nil {
+				return nil, newErrorWithPos(ctx.TokenStream, "failed to parse index expression argument: %v", err)
+			}
+			return expr, nil
+		}
+
 		// Check if this is a field access or language call
 		if tokenStream.HasMore() && tokenStream.Peek().Type == lexer.TokenDot {
 			// Check if this is a language call (lua.something(...))
@@ -251,11 +312,7 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 					// This is a language call: lua.something(...)
 					tokenStream.SetPosition(savedPos) // restore position
 					languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{})
-					languageCallCtx := &common.ParseContext{
-						TokenStream: tokenStream,
-						Depth:       ctx.Depth + 1,
-						MaxDepth:    ctx.MaxDepth,
-					}
+					languageCallCtx := ctx.WithStream(tokenStream)
 					languageCallResult, err := languageCallHandler.Handle(languageCallCtx)
 					if err != nil {
 						return nil, newErrorWithPos(tokenStream, "failed to parse language call argument: %v", err)
@@ -281,11 +338,7 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 
 			// This is a field access expression like lua.v or lua.string.format(...)
 			fieldAccessHandler := NewFieldAccessHandler(config.ConstructHandlerConfig{})
-			fieldAccessCtx := &common.ParseContext{
-				TokenStream: tokenStream,
-				Depth:       ctx.Depth + 1,
-				MaxDepth:    ctx.MaxDepth,
-			}
+			fieldAccessCtx := ctx.WithStream(tokenStream)
 			fieldAccessResult, err := fieldAccessHandler.Handle(fieldAccessCtx)
 			if err != nil {
 				return nil, newErrorWithPos(tokenStream, "failed to parse field access argument: %v", err)
@@ -327,11 +380,14 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 						continue
 					}
 
+					argName, kind := consumeArgumentPrefix(tokenStream)
 					arg, err := h.parseArgument(ctx)
 					if err != nil {
 						return nil, newErrorWithPos(tokenStream, "failed to parse language call argument: %v", err)
 					}
-					arguments = append(arguments, arg)
+					if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+						return nil, err
+					}
 				}
 
 				if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
@@ -346,6 +402,12 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 					Arguments: arguments,
 					Pos:       fieldAccess.Pos,
 				}
+				if err := parseCallMethodChain(tokenStream, languageCall); err != nil {
+					return nil, err
+				}
+				if err := parseResultPolicy(tokenStream, languageCall); err != nil {
+					return nil, err
+				}
 				return languageCall, nil
 			}
 
@@ -443,15 +505,7 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 	case lexer.TokenDoubleLeftAngle:
 		// Битстринг как аргумент
 		bitstringHandler := NewBitstringHandler(config.ConstructHandlerConfig{})
-		bitstringCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		bitstringCtx := ctx.WithStream(tokenStream)
 		bitstringResult, err := bitstringHandler.Handle(bitstringCtx)
 		if err != nil {
 			return nil, newErrorWithPos(ctx.TokenStream, "failed to parse bitstring argument: %v", err)
@@ -477,44 +531,24 @@ func (h *BuiltinFunctionHandler) parseArgument(ctx *common.ParseContext) (ast.Ex
 		}
 		return expr, nil
 
+	case lexer.TokenTry:
+		// try как аргумент: print(try py.risky())
+		exprParser := NewUnifiedExpressionParser(h.verbose)
+		expr, err := exprParser.ParseExpression(ctx)
+		if err != nil {
+			return nil, newErrorWithPos(ctx.TokenStream, "failed to parse try expression argument: %v", err)
+		}
+		return expr, nil
+
 	default:
 		return nil, newErrorWithTokenPos(token, "unsupported argument type: %s", token.Type)
 	}
 }
 
-// parseIndexExpression парсит индексное выражение вида object[index]
+// parseIndexExpression парсит индексное выражение вида object[index] или срез object[start:end]
 func (h *BuiltinFunctionHandler) parseIndexExpression(object ast.Expression, tokenStream stream.TokenStream) (ast.Expression, error) {
-	// Проверяем наличие открывающей квадратной скобки
-	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenLBracket {
-		return nil, fmt.Errorf("expected '[' for index expression")
-	}
-
-	// Потребляем открывающую скобку
-	tokenStream.Consume()
-
-	// Парсим индексное выражение
-	index, err := h.parseArgument(&common.ParseContext{
-		TokenStream: tokenStream,
-		Depth:       0,
-		MaxDepth:    100,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse index expression: %v", err)
-	}
-
-	// Проверяем и потребляем закрывающую скобку
-	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRBracket {
-		return nil, fmt.Errorf("expected ']' after index expression")
-	}
-	tokenStream.Consume()
-
-	// Создаем узел индексного доступа
-	result := &ast.IndexExpression{
-		Object: object,
-		Index:  index,
-		Pos:    object.Position(),
-	}
-	return result, nil
+	binaryHandler := NewBinaryExpressionHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose)
+	return binaryHandler.ParseIndexExpression(common.NewParseContext(tokenStream), object)
 }
 
 // Config возвращает конфигурацию обработчика
@@ -10,34 +10,6 @@ import (
 	"go-parser/pkg/stream"
 )
 
-// protoRecursionGuard реализует защиту от рекурсии для временных контекстов
-type protoRecursionGuard struct {
-	maxDepth     int
-	currentDepth int
-}
-
-func (rg *protoRecursionGuard) Enter() error {
-	if rg.currentDepth >= rg.maxDepth {
-		return fmt.Errorf("maximum recursion depth exceeded: %d", rg.maxDepth)
-	}
-	rg.currentDepth++
-	return nil
-}
-
-func (rg *protoRecursionGuard) Exit() {
-	if rg.currentDepth > 0 {
-		rg.currentDepth--
-	}
-}
-
-func (rg *protoRecursionGuard) CurrentDepth() int {
-	return rg.currentDepth
-}
-
-func (rg *protoRecursionGuard) MaxDepth() int {
-	return rg.maxDepth
-}
-
 // CStyleForLoopHandler - обработчик C-style for циклов
 type CStyleForLoopHandler struct {
 	config  config.ConstructHandlerConfig
@@ -227,15 +199,7 @@ func (h *CStyleForLoopHandler) parseInitializer(tokenStream stream.TokenStream)
 
 	// Пробуем распарсить как присваивание переменной
 	// Создаем временный контекст для парсинга
-	tempCtx := &common.ParseContext{
-		TokenStream: tokenStream,
-		Parser:      nil,
-		Depth:       0,
-		MaxDepth:    100,
-		Guard:       &protoRecursionGuard{maxDepth: 100, currentDepth: 0},
-		LoopDepth:   0,
-		InputStream: "", // Не нужен для парсинга выражений
-	}
+	tempCtx := common.NewParseContext(tokenStream)
 
 	// Используем AssignmentHandler для парсинга инициализации
 	assignmentHandler := NewAssignmentHandler(0, 0)
@@ -294,22 +258,14 @@ func (h *CStyleForLoopHandler) parseIncrement(tokenStream stream.TokenStream, ha
 		tokenStream.Current().Type == lexer.TokenPy ||
 		tokenStream.Current().Type == lexer.TokenGo ||
 		tokenStream.Current().Type == lexer.TokenNode ||
-		tokenStream.Current().Type == lexer.TokenJS {
+		tokenStream.Current().Type == lexer.TokenJS || tokenStream.Current().Type == lexer.TokenPerl || tokenStream.Current().Type == lexer.TokenPl || tokenStream.Current().Type == lexer.TokenErlang || tokenStream.Current().Type == lexer.TokenErl || tokenStream.Current().Type == lexer.TokenElixir || tokenStream.Current().Type == lexer.TokenEx {
 
 		// Проверяем следующий токен на наличие оператора присваивания
 		if tokenStream.HasMore() {
 			nextToken := tokenStream.Peek()
 			if nextToken.Type == lexer.TokenAssign || nextToken.Type == lexer.TokenColonEquals {
 				// Это присваивание - используем AssignmentHandler
-				tempCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       0,
-					MaxDepth:    100,
-					Guard:       &protoRecursionGuard{maxDepth: 100, currentDepth: 0},
-					LoopDepth:   0,
-					InputStream: "", // Не нужен для парсинга выражений
-				}
+				tempCtx := common.NewParseContext(tokenStream)
 
 				assignmentHandler := NewAssignmentHandlerWithVerbose(80, 4, h.verbose)
 				result, err := assignmentHandler.Handle(tempCtx)
@@ -481,7 +437,7 @@ func (h *CStyleForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.St
 		// Обрабатываем вызовы функций других языков
 		if current.Type == lexer.TokenIdentifier || current.Type == lexer.TokenLua ||
 			current.Type == lexer.TokenPython || current.Type == lexer.TokenPy || current.Type == lexer.TokenGo ||
-			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS {
+			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx {
 
 			// Проверяем, не является ли это присваиванием
 			if tokenStream.Peek().Type == lexer.TokenAssign || tokenStream.Peek().Type == lexer.TokenColonEquals {
@@ -495,8 +451,8 @@ func (h *CStyleForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.St
 				}
 			}
 
-			// Проверяем, не является ли это вызовом функции
-			if tokenStream.Peek().Type == lexer.TokenDot {
+			// Проверяем, не является ли это вызовом функции (методы объектов разбираются ниже как builtin)
+			if tokenStream.Peek().Type == lexer.TokenDot && !isBuiltinCallStart(tokenStream) {
 				languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{})
 				result, err := languageCallHandler.Handle(ctx)
 				if err == nil {
@@ -513,7 +469,7 @@ func (h *CStyleForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.St
 		}
 
 		// Проверяем, не является ли это вызовом builtin функции
-		if current.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			if h.verbose {
 				fmt.Printf("DEBUG parseLoopBody: found identifier followed by '(', trying builtin function\n")
 			}
@@ -524,7 +480,7 @@ func (h *CStyleForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.St
 				if h.verbose {
 					fmt.Printf("DEBUG parseLoopBody: builtin function parsing succeeded\n")
 				}
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					body = append(body, builtinCall)
 					continue
 				}
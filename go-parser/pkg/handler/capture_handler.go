@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// CaptureHandler - обработчик присваивания перехваченного вывода:
+//
+//	out = capture { py.print(x); lua.print(y) }
+//
+// 'capture' - обычный идентификатор: без тела в фигурных скобках это не
+// перехват. Тело, как у retry, UnifiedParser разбирает как скрипт
+type CaptureHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewCaptureHandler создает новый обработчик capture
+func NewCaptureHandler(config config.ConstructHandlerConfig) *CaptureHandler {
+	return &CaptureHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *CaptureHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier
+}
+
+// Handle обрабатывает name = capture { ... }. Если это другое присваивание,
+// обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с "capture: ", парсер не передает их другим обработчикам
+func (h *CaptureHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	nameToken := tokenStream.Current()
+	captureToken := tokenStream.PeekN(2)
+	if !h.CanHandle(nameToken) || tokenStream.Peek().Type != lexer.TokenAssign ||
+		captureToken.Type != lexer.TokenIdentifier || captureToken.Value != "capture" || tokenStream.PeekN(3).Type != lexer.TokenLBrace {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	assignToken := tokenStream.Consume()
+	tokenStream.Consume()
+
+	capture := ast.NewCaptureExpression(tokenToPosition(captureToken))
+	source, bodyPos, err := captureBody(ctx, "capture", "the body of capture")
+	if err != nil {
+		return nil, err
+	}
+	capture.Source, capture.BodyPos = source, bodyPos
+	return ast.NewVariableAssignment(ast.NewIdentifier(nameToken, nameToken.Value), assignToken, capture), nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *CaptureHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *CaptureHandler) Name() string {
+	return h.config.Name
+}
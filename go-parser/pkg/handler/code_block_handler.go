@@ -31,7 +31,44 @@ func NewCodeBlockHandlerWithVerbose(config config.ConstructHandlerConfig, verbos
 
 // CanHandle проверяет, может ли обработчик обработать токен
 func (h *CodeBlockHandler) CanHandle(token lexer.Token) bool {
-	return token.Type == lexer.TokenLua || token.Type == lexer.TokenPython || token.Type == lexer.TokenPy || token.Type == lexer.TokenGo || token.Type == lexer.TokenNode || token.Type == lexer.TokenJS
+	return isCodeBlockToken(token)
+}
+
+// isCodeBlockToken проверяет, начинает ли токен блок кода
+func isCodeBlockToken(token lexer.Token) bool {
+	switch token.Type {
+	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
+		return true
+	}
+	return false
+}
+
+// isCodeBlockValue проверяет, начинается ли в потоке блок кода: py { ... } или py (vars) { ... }
+func isCodeBlockValue(tokenStream stream.TokenStream) bool {
+	if !tokenStream.HasMore() || !isCodeBlockToken(tokenStream.Current()) {
+		return false
+	}
+	lookahead := tokenStream.Clone()
+	lookahead.Consume()
+	if !lookahead.HasMore() {
+		return false
+	}
+	if lookahead.Current().Type == lexer.TokenLeftParen {
+		lookahead.Consume()
+		for lookahead.HasMore() && lookahead.Current().Type != lexer.TokenRightParen {
+			switch lookahead.Current().Type {
+			case lexer.TokenIdentifier, lexer.TokenComma, lexer.TokenNewline:
+				lookahead.Consume()
+			default:
+				return false
+			}
+		}
+		if !lookahead.HasMore() {
+			return false
+		}
+		lookahead.Consume()
+	}
+	return lookahead.HasMore() && lookahead.Current().Type == lexer.TokenLBrace
 }
 
 // skipWhitespaceTokens пропускает пробельные токены (переносы строк)
@@ -58,8 +95,8 @@ func (h *CodeBlockHandler) Handle(ctx *common.ParseContext) (interface{}, error)
 	// Потребляем токен рантайма
 	runtimeToken := tokenStream.Current()
 
-	if runtimeToken.Type != lexer.TokenLua && runtimeToken.Type != lexer.TokenPython && runtimeToken.Type != lexer.TokenPy && runtimeToken.Type != lexer.TokenGo && runtimeToken.Type != lexer.TokenNode && runtimeToken.Type != lexer.TokenJS {
-		return nil, fmt.Errorf("expected runtime token (lua, python, py, go, node, js), got %s", runtimeToken.Type)
+	if runtimeToken.Type != lexer.TokenLua && runtimeToken.Type != lexer.TokenPython && runtimeToken.Type != lexer.TokenPy && runtimeToken.Type != lexer.TokenGo && runtimeToken.Type != lexer.TokenNode && runtimeToken.Type != lexer.TokenJS && runtimeToken.Type != lexer.TokenPerl && runtimeToken.Type != lexer.TokenPl && runtimeToken.Type != lexer.TokenErlang && runtimeToken.Type != lexer.TokenErl && runtimeToken.Type != lexer.TokenElixir && runtimeToken.Type != lexer.TokenEx {
+		return nil, fmt.Errorf("expected runtime token (lua, python, py, go, node, js, perl, pl, erlang, erl, elixir, ex), got %s", runtimeToken.Type)
 	}
 	if h.verbose {
 		fmt.Printf("DEBUG: CodeBlockHandler - consuming runtime token\n")
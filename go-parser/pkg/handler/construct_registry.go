@@ -22,6 +22,7 @@ type ConstructHandlerRegistry interface {
 type ConstructHandlerRegistryImpl struct {
 	handlersByConstruct map[common.ConstructType][]*HandlerReference
 	tokenPatternIndex   map[lexer.TokenType][]*HandlerReference
+	disabled            map[string]bool // имена обработчиков, отключенных после регистрации
 }
 
 // HandlerReference - ссылка на обработчик с метаданными
@@ -36,6 +37,7 @@ func NewConstructHandlerRegistry() *ConstructHandlerRegistryImpl {
 	return &ConstructHandlerRegistryImpl{
 		handlersByConstruct: make(map[common.ConstructType][]*HandlerReference),
 		tokenPatternIndex:   make(map[lexer.TokenType][]*HandlerReference),
+		disabled:            make(map[string]bool),
 	}
 }
 
@@ -92,6 +94,9 @@ func (r *ConstructHandlerRegistryImpl) GetHandlerForTokenSequence(
 	var matchedHandlers []*HandlerReference
 
 	for _, candidate := range candidates {
+		if r.disabled[candidate.Config.Name] {
+			continue
+		}
 		if r.matchesTokenPattern(candidate.Config.TokenPatterns, tokens) {
 			matchedHandlers = append(matchedHandlers, candidate)
 		}
@@ -127,6 +132,9 @@ func (r *ConstructHandlerRegistryImpl) GetAllHandlersForTokenSequence(
 	var matchedHandlers []*HandlerReference
 
 	for _, candidate := range candidates {
+		if r.disabled[candidate.Config.Name] {
+			continue
+		}
 		if r.matchesTokenPattern(candidate.Config.TokenPatterns, tokens) {
 			matchedHandlers = append(matchedHandlers, candidate)
 		}
@@ -185,7 +193,7 @@ func (r *ConstructHandlerRegistryImpl) GetHandlerByConstruct(
 	// Фильтруем только основные обработчики (не fallback)
 	var nonFallbackRefs []*HandlerReference
 	for _, ref := range refs {
-		if !ref.Config.IsFallback {
+		if !ref.Config.IsFallback && !r.disabled[ref.Config.Name] {
 			nonFallbackRefs = append(nonFallbackRefs, ref)
 		}
 	}
@@ -218,7 +226,7 @@ func (r *ConstructHandlerRegistryImpl) GetFallbackHandlers(
 	handlerList := make([]handlerWithPriority, 0)
 
 	for _, ref := range refs {
-		if ref.Config.IsFallback {
+		if ref.Config.IsFallback && !r.disabled[ref.Config.Name] {
 			handlerList = append(handlerList, handlerWithPriority{
 				handler:  ref.Handler,
 				priority: ref.Config.FallbackPriority,
@@ -263,3 +271,58 @@ func (r *ConstructHandlerRegistryImpl) sortHandlersByConstruct(
 		return configI.Name < configJ.Name
 	})
 }
+
+// HandlerNames - возвращает отсортированные имена зарегистрированных обработчиков
+func (r *ConstructHandlerRegistryImpl) HandlerNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, refs := range r.handlersByConstruct {
+		for _, ref := range refs {
+			if !seen[ref.Config.Name] {
+				seen[ref.Config.Name] = true
+				names = append(names, ref.Config.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetHandlerEnabled - включает или отключает зарегистрированный обработчик по имени
+func (r *ConstructHandlerRegistryImpl) SetHandlerEnabled(name string, enabled bool) error {
+	if len(r.findHandlers(name)) == 0 {
+		return fmt.Errorf("unknown parser handler: %s", name)
+	}
+	if enabled {
+		delete(r.disabled, name)
+	} else {
+		r.disabled[name] = true
+	}
+	return nil
+}
+
+// SetHandlerPriority - меняет приоритет зарегистрированного обработчика по имени
+func (r *ConstructHandlerRegistryImpl) SetHandlerPriority(name string, priority int) error {
+	refs := r.findHandlers(name)
+	if len(refs) == 0 {
+		return fmt.Errorf("unknown parser handler: %s", name)
+	}
+	for _, ref := range refs {
+		ref.Config.Priority = priority
+		r.sortHandlersByConstruct(ref.ConstructType)
+	}
+	return nil
+}
+
+// findHandlers - ищет все регистрации обработчика с указанным именем
+func (r *ConstructHandlerRegistryImpl) findHandlers(name string) []*HandlerReference {
+	var found []*HandlerReference
+	for _, refs := range r.handlersByConstruct {
+		for _, ref := range refs {
+			if ref.Config.Name == name {
+				found = append(found, ref)
+			}
+		}
+	}
+	return found
+}
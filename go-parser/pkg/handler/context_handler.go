@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// ContextHandler - обработчик блоков с другим рабочим каталогом или окружением:
+//
+//	cd("build") { py.run_tests() }
+//	with_env({DEBUG: "1"}) { py.main() }
+//
+// 'cd' и 'with_env' - обычные идентификаторы: без тела в фигурных скобках
+// это не блок. Тело, как у retry, UnifiedParser разбирает как скрипт
+type ContextHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewContextHandler создает новый обработчик блоков cd и with_env
+func NewContextHandler(config config.ConstructHandlerConfig) *ContextHandler {
+	return &ContextHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *ContextHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && (token.Value == "cd" || token.Value == "with_env")
+}
+
+// Handle обрабатывает cd(dir) { ... } и with_env(vars) { ... }. Если за
+// аргументом не идет '{', обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с имени функции, парсер не передает их другим обработчикам
+func (h *ContextHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	nameToken := tokenStream.Current()
+	if !h.CanHandle(nameToken) || tokenStream.Peek().Type != lexer.TokenLeftParen || !callHasBody(tokenStream) {
+		return nil, nil
+	}
+	function := nameToken.Value
+	tokenStream.Consume()
+
+	arguments, err := parseCallArguments(ctx, function, nameToken)
+	if err != nil {
+		return nil, err
+	}
+	if len(arguments) != 1 {
+		if function == "cd" {
+			return nil, newErrorWithTokenPos(nameToken, "cd: expected one directory, e.g. cd(\"build\") { ... }")
+		}
+		return nil, newErrorWithTokenPos(nameToken, "with_env: expected one object of variables, e.g. with_env({DEBUG: \"1\"}) { ... }")
+	}
+
+	statement := ast.NewContextStatement(function, arguments[0], tokenToPosition(nameToken))
+	source, bodyPos, err := captureBody(ctx, function, "the body of "+function)
+	if err != nil {
+		return nil, err
+	}
+	statement.Source, statement.BodyPos = source, bodyPos
+	return statement, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *ContextHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *ContextHandler) Name() string {
+	return h.config.Name
+}
@@ -239,12 +239,15 @@ func (h *ElvisExpressionHandler) parseLanguageCall(ctx *common.ParseContext) (as
 			}
 
 			// Читаем аргумент
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			arg, err := h.parseOperand(ctx)
 			if err != nil {
 				return nil, newErrorWithPos(tokenStream, "failed to parse argument: %v", err)
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
@@ -341,12 +344,15 @@ func (h *ElvisExpressionHandler) parseQualifiedVariable(ctx *common.ParseContext
 					}
 
 					// Читаем аргумент
+					argName, kind := consumeArgumentPrefix(tokenStream)
 					arg, err := h.parseOperand(ctx)
 					if err != nil {
 						return nil, newErrorWithPos(tokenStream, "failed to parse argument: %v", err)
 					}
 
-					arguments = append(arguments, arg)
+					if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+						return nil, err
+					}
 
 					// Проверяем разделитель или конец
 					if !tokenStream.HasMore() {
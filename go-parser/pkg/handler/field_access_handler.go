@@ -37,7 +37,13 @@ func (h *FieldAccessHandler) CanHandle(token lexer.Token) bool {
 		token.Type == lexer.TokenPy ||
 		token.Type == lexer.TokenGo ||
 		token.Type == lexer.TokenNode ||
-		token.Type == lexer.TokenJS
+		token.Type == lexer.TokenJS ||
+		token.Type == lexer.TokenPerl ||
+		token.Type == lexer.TokenPl ||
+		token.Type == lexer.TokenErlang ||
+		token.Type == lexer.TokenErl ||
+		token.Type == lexer.TokenElixir ||
+		token.Type == lexer.TokenEx
 }
 
 // Handle обрабатывает доступ к полю с поддержкой цепочек (например, lua.data.name)
@@ -52,7 +58,13 @@ func (h *FieldAccessHandler) Handle(ctx *common.ParseContext) (interface{}, erro
 		firstToken.Type != lexer.TokenPy &&
 		firstToken.Type != lexer.TokenGo &&
 		firstToken.Type != lexer.TokenNode &&
-		firstToken.Type != lexer.TokenJS {
+		firstToken.Type != lexer.TokenJS &&
+		firstToken.Type != lexer.TokenPerl &&
+		firstToken.Type != lexer.TokenPl &&
+		firstToken.Type != lexer.TokenErlang &&
+		firstToken.Type != lexer.TokenErl &&
+		firstToken.Type != lexer.TokenElixir &&
+		firstToken.Type != lexer.TokenEx {
 		return nil, newErrorWithTokenPos(firstToken, "expected identifier as first part of field access, got %s", firstToken.Type)
 	}
 
@@ -65,12 +77,27 @@ func (h *FieldAccessHandler) Handle(ctx *common.ParseContext) (interface{}, erro
 	// Проверяем, является ли первый токен языковым токеном
 	if firstToken.Type == lexer.TokenLua || firstToken.Type == lexer.TokenPython ||
 		firstToken.Type == lexer.TokenPy || firstToken.Type == lexer.TokenGo ||
-		firstToken.Type == lexer.TokenJS || firstToken.Type == lexer.TokenNode {
+		firstToken.Type == lexer.TokenJS ||
+		firstToken.Type == lexer.TokenPerl ||
+		firstToken.Type == lexer.TokenPl ||
+		firstToken.Type == lexer.TokenErlang ||
+		firstToken.Type == lexer.TokenErl ||
+		firstToken.Type == lexer.TokenElixir ||
+		firstToken.Type == lexer.TokenEx || firstToken.Type == lexer.TokenNode {
 		// Создаем квалифицированный идентификатор для языкового токена
 		language := firstToken.Value
 		if language == "js" {
 			language = "node" // Нормализуем js в node
 		}
+		if language == "pl" {
+			language = "perl"
+		}
+		if language == "erl" {
+			language = "erlang"
+		}
+		if language == "ex" {
+			language = "elixir"
+		}
 		currentObject = ast.NewIdentifier(firstToken, firstToken.Value)
 		// Устанавливаем информацию о языке вручную, так как NewQualifiedIdentifier требует два токена
 		if ident, ok := currentObject.(*ast.Identifier); ok {
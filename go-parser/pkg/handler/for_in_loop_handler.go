@@ -127,10 +127,23 @@ func (h *ForInLoopHandler) Handle(ctx *common.ParseContext) (interface{}, error)
 			// Это qualified variable (python.my_list)
 			return h.handleQualifiedVariableAsIterable(ctx, variables[0], inToken)
 		} else {
-			// Простой идентификатор
+			// Простой идентификатор или начало выражения: for i in start..end
 			tokenStream.Consume()
-			iterable = ast.NewIdentifier(currentToken, currentToken.Value)
+			expr, err := continueOperandExpression(tokenStream, ast.NewIdentifier(currentToken, currentToken.Value))
+			if err != nil {
+				return nil, newErrorWithPos(tokenStream, "failed to parse iterable: %v", err)
+			}
+			iterable = expr
+		}
+
+	case lexer.TokenNumber, lexer.TokenLeftParen, lexer.TokenMinus, lexer.TokenString:
+		// Выражение, например диапазон: for i in 0..<10 или "a,b".split(",")
+		exprCtx := ctx.WithStream(tokenStream)
+		expr, err := NewUnifiedExpressionParser(h.verbose).ParseExpression(exprCtx)
+		if err != nil {
+			return nil, newErrorWithPos(tokenStream, "failed to parse iterable: %v", err)
 		}
+		iterable = expr
 
 	case lexer.TokenLBracket:
 		// Массив как итерируемый объект - используем ArrayHandler
@@ -520,8 +533,8 @@ func (h *ForInLoopHandler) parseLoopBodyWithBraces(ctx *common.ParseContext) ([]
 			break
 		}
 
-		// Пропускаем новые строки и пустые токены
-		if current.Type == lexer.TokenNewline {
+		// Пропускаем новые строки, ';' и пустые токены
+		if isStatementSeparator(current) {
 			tokenStream.Consume()
 			continue
 		}
@@ -547,8 +560,8 @@ func (h *ForInLoopHandler) parseLoopBodyWithBraces(ctx *common.ParseContext) ([]
 			}
 		}
 
-		// Пропускаем новые строки после выражения
-		for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenNewline {
+		// Пропускаем новые строки и ';' после выражения
+		for tokenStream.HasMore() && isStatementSeparator(tokenStream.Current()) {
 			tokenStream.Consume()
 		}
 	}
@@ -660,12 +673,12 @@ func (h *ForInLoopHandler) parseStatement(ctx *common.ParseContext) (ast.Stateme
 		savedPos := tokenStream.Position()
 
 		// Сначала проверяем, не является ли это вызовом builtin функции
-		if tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			// Это может быть builtin функция типа print(...)
 			builtinHandler := NewBuiltinFunctionHandlerWithVerbose(config.ConstructHandlerConfig{}, h.verbose)
 			result, err := builtinHandler.Handle(ctx)
 			if err == nil {
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					return builtinCall, nil
 				}
 			} else {
@@ -1007,7 +1020,7 @@ func (h *ForInLoopHandler) parseBinaryExpressionWithQualifiedVars(ctx *common.Pa
 // isLanguageIdentifier проверяет, является ли идентификатор именем языка
 func (h *ForInLoopHandler) isLanguageIdentifier(value string) bool {
 	switch value {
-	case "python", "py", "lua", "l", "javascript", "js", "node", "go":
+	case "python", "py", "lua", "l", "javascript", "js", "node", "go", "perl", "pl", "erlang", "erl", "elixir", "ex":
 		return true
 	default:
 		return false
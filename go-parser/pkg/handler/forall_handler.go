@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// ForallHandler - обработчик проверки свойства на случайных данных:
+//
+//	forall(n = gen.int(0, 255), runs=500) { ... }
+//
+// 'forall' - обычный идентификатор: без '(' ... ')' '{' это не проверка.
+// Все аргументы записываются как name=value
+type ForallHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewForallHandler создает новый обработчик проверки свойства
+func NewForallHandler(config config.ConstructHandlerConfig) *ForallHandler {
+	return &ForallHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *ForallHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && token.Value == "forall"
+}
+
+// Handle обрабатывает forall(name=value, ...) { ... }. Если за аргументами
+// не идет '{', это вызов функции forall, и обработчик возвращает nil.
+// Ошибки начинаются с "forall: ", парсер не передает их другим обработчикам
+func (h *ForallHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	forallToken := tokenStream.Current()
+	if forallToken.Type != lexer.TokenIdentifier || forallToken.Value != "forall" {
+		return nil, nil
+	}
+	if tokenStream.Peek().Type != lexer.TokenLeftParen || !callHasBody(tokenStream) {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	var arguments []*ast.NamedArgument
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
+		if len(arguments) > 0 {
+			if tokenStream.Current().Type != lexer.TokenComma {
+				return nil, newErrorWithPos(tokenStream, "forall: expected ',' or ')' after an argument")
+			}
+			tokenStream.Consume()
+		}
+
+		current := tokenStream.Current()
+		if current.Type != lexer.TokenIdentifier || tokenStream.Peek().Type != lexer.TokenAssign {
+			return nil, newErrorWithTokenPos(current, "forall: arguments are written as name=value, e.g. forall(n = gen.int(0, 9)) { ... }")
+		}
+		for _, argument := range arguments {
+			if argument.Name == current.Value {
+				return nil, newErrorWithTokenPos(current, "forall: '%s' is given twice", current.Value)
+			}
+		}
+		tokenStream.Consume()
+		tokenStream.Consume()
+		value, err := parseArgumentValue(ctx, "forall")
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, ast.NewNamedArgument(current.Value, value, tokenToPosition(current)))
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithTokenPos(forallToken, "forall: expected ')' after the arguments")
+	}
+	tokenStream.Consume()
+
+	statement := ast.NewForallStatement(arguments, tokenToPosition(forallToken))
+	source, bodyPos, err := captureBody(ctx, "forall", "the body of forall")
+	if err != nil {
+		return nil, err
+	}
+	statement.Source, statement.BodyPos = source, bodyPos
+	return statement, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *ForallHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *ForallHandler) Name() string {
+	return h.config.Name
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// HookHandler - обработчик on_signal и on_exit:
+//
+//	on_signal("SIGTERM", "SIGINT") { py.close_connections() }
+//	on_exit { py.flush() }
+//
+// 'on_signal' и 'on_exit' - обычные идентификаторы: без тела в фигурных
+// скобках это не обработчик. Тело, как у retry, UnifiedParser разбирает как скрипт
+type HookHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewHookHandler создает новый обработчик on_signal и on_exit
+func NewHookHandler(config config.ConstructHandlerConfig) *HookHandler {
+	return &HookHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *HookHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && (token.Value == "on_signal" || token.Value == "on_exit")
+}
+
+// Handle обрабатывает on_signal(names) { ... } и on_exit { ... }. Если тела
+// нет, обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с имени конструкции, парсер не передает их другим обработчикам
+func (h *HookHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	hookToken := tokenStream.Current()
+	if !h.CanHandle(hookToken) {
+		return nil, nil
+	}
+
+	var statement *ast.HookStatement
+	if hookToken.Value == "on_exit" {
+		if tokenStream.Peek().Type != lexer.TokenLBrace {
+			return nil, nil
+		}
+		tokenStream.Consume()
+		statement = ast.NewHookStatement("exit", nil, tokenToPosition(hookToken))
+	} else {
+		if tokenStream.Peek().Type != lexer.TokenLeftParen || !callHasBody(tokenStream) {
+			return nil, nil
+		}
+		tokenStream.Consume()
+		signals, err := parseCallArguments(ctx, "on_signal", hookToken)
+		if err != nil {
+			return nil, err
+		}
+		if len(signals) == 0 {
+			return nil, newErrorWithTokenPos(hookToken, "on_signal: expected the names of the signals, e.g. on_signal(\"SIGTERM\") { ... }")
+		}
+		statement = ast.NewHookStatement("signal", signals, tokenToPosition(hookToken))
+	}
+
+	source, bodyPos, err := captureBody(ctx, hookToken.Value, "the body of "+hookToken.Value)
+	if err != nil {
+		return nil, err
+	}
+	statement.Source, statement.BodyPos = source, bodyPos
+	return statement, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *HookHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *HookHandler) Name() string {
+	return h.config.Name
+}
@@ -275,13 +275,20 @@ func (h *IfHandler) parseConditionWithBinaryHandler(ctx *common.ParseContext) (a
 		firstToken.Type == lexer.TokenPy ||
 		firstToken.Type == lexer.TokenGo ||
 		firstToken.Type == lexer.TokenNode ||
-		firstToken.Type == lexer.TokenJS
+		firstToken.Type == lexer.TokenJS ||
+		firstToken.Type == lexer.TokenPerl ||
+		firstToken.Type == lexer.TokenPl ||
+		firstToken.Type == lexer.TokenErlang ||
+		firstToken.Type == lexer.TokenErl ||
+		firstToken.Type == lexer.TokenElixir ||
+		firstToken.Type == lexer.TokenEx
 
 	// Также проверяем идентификаторы, которые могут быть именами языков
 	isLanguageIdentifier := firstToken.Type == lexer.TokenIdentifier &&
 		(firstToken.Value == "python" || firstToken.Value == "lua" || firstToken.Value == "py" ||
 			firstToken.Value == "go" || firstToken.Value == "node" || firstToken.Value == "js" ||
-			firstToken.Value == "javascript")
+			firstToken.Value == "javascript" || firstToken.Value == "perl" || firstToken.Value == "pl" ||
+			firstToken.Value == "erlang" || firstToken.Value == "erl" || firstToken.Value == "elixir" || firstToken.Value == "ex")
 
 	if isLanguageToken || isLanguageIdentifier {
 		if h.verbose {
@@ -303,16 +310,8 @@ func (h *IfHandler) parseConditionWithBinaryHandler(ctx *common.ParseContext) (a
 				// Используем LanguageCallHandler напрямую с частичным парсингом
 				languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{})
 				// Создаем контекст с включенным режимом частичного парсинга
-				partialCtx := &common.ParseContext{
-					TokenStream:        ctx.TokenStream,
-					Parser:             ctx.Parser,
-					Depth:              ctx.Depth,
-					MaxDepth:           ctx.MaxDepth,
-					Guard:              ctx.Guard,
-					LoopDepth:          ctx.LoopDepth,
-					InputStream:        ctx.InputStream,
-					PartialParsingMode: true, // Включаем режим частичного парсинга
-				}
+				partialCtx := ctx.WithStream(ctx.TokenStream)
+				partialCtx.PartialParsingMode = true
 				result, err := languageCallHandler.Handle(partialCtx)
 				if err != nil {
 					if h.verbose {
@@ -394,7 +393,7 @@ func (h *IfHandler) parseConditionWithBinaryHandler(ctx *common.ParseContext) (a
 				tokenStream.Consume()
 				leftExpr = ast.NewIdentifier(firstToken, firstToken.Value)
 
-			case lexer.TokenPython, lexer.TokenLua, lexer.TokenPy, lexer.TokenGo, lexer.TokenJS, lexer.TokenNode:
+			case lexer.TokenPython, lexer.TokenLua, lexer.TokenPy, lexer.TokenGo, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenNode:
 				if h.verbose {
 					fmt.Printf("DEBUG: Case for language tokens, peek type: %d\n", tokenStream.Peek().Type)
 				}
@@ -502,15 +501,7 @@ func (h *IfHandler) parseConditionWithBinaryHandler(ctx *common.ParseContext) (a
 					fmt.Printf("DEBUG: Parsing array literal in condition\n")
 				}
 				arrayHandler := NewArrayHandler(100, 0)
-				arrayCtx := &common.ParseContext{
-					TokenStream: ctx.TokenStream,
-					Parser:      ctx.Parser,
-					Depth:       ctx.Depth,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				arrayCtx := ctx.WithStream(ctx.TokenStream)
 				arrayResult, err := arrayHandler.Handle(arrayCtx)
 				if err != nil {
 					return nil, fmt.Errorf("failed to parse array in condition: %v", err)
@@ -527,15 +518,7 @@ func (h *IfHandler) parseConditionWithBinaryHandler(ctx *common.ParseContext) (a
 					fmt.Printf("DEBUG: Parsing object literal in condition\n")
 				}
 				objectHandler := NewObjectHandler(100, 0)
-				objectCtx := &common.ParseContext{
-					TokenStream: ctx.TokenStream,
-					Parser:      ctx.Parser,
-					Depth:       ctx.Depth,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				objectCtx := ctx.WithStream(ctx.TokenStream)
 				objectResult, err := objectHandler.Handle(objectCtx)
 				if err != nil {
 					return nil, fmt.Errorf("failed to parse object in condition: %v", err)
@@ -734,12 +717,12 @@ func (h *IfHandler) parseIfBodyWithTerminator(ctx *common.ParseContext, terminat
 
 		// Пытаемся распарсить как statement
 		// Поддерживаем вызовы функций и присваивания
-		if current.Type == lexer.TokenPy || current.Type == lexer.TokenPython || current.Type == lexer.TokenLua || current.Type == lexer.TokenGo || current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenIdentifier {
+		if current.Type == lexer.TokenPy || current.Type == lexer.TokenPython || current.Type == lexer.TokenLua || current.Type == lexer.TokenGo || current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx || current.Type == lexer.TokenIdentifier {
 			if h.verbose {
 				fmt.Printf("DEBUG: parseIfBody - found token type %d, value '%s'\n", current.Type, current.Value)
 			}
-			// Проверяем, не является ли это вызовом функции другого языка
-			if tokenStream.Peek().Type == lexer.TokenDot {
+			// Проверяем, не является ли это вызовом функции другого языка (методы объектов разбираются ниже как builtin)
+			if tokenStream.Peek().Type == lexer.TokenDot && !isBuiltinCallStart(tokenStream) {
 				peek2 := tokenStream.PeekN(2)
 				peek3 := tokenStream.PeekN(3)
 				if h.verbose {
@@ -837,7 +820,7 @@ func (h *IfHandler) parseIfBodyWithTerminator(ctx *common.ParseContext, terminat
 		}
 
 		// Проверяем, не является ли это вызовом builtin функции
-		if current.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			if h.verbose {
 				fmt.Printf("DEBUG: parseIfBody - found identifier followed by '(', trying builtin function\n")
 			}
@@ -848,7 +831,7 @@ func (h *IfHandler) parseIfBodyWithTerminator(ctx *common.ParseContext, terminat
 				if h.verbose {
 					fmt.Printf("DEBUG: parseIfBody - builtin function parsing succeeded\n")
 				}
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					body = append(body, builtinCall)
 					continue
 				}
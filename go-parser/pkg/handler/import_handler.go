@@ -44,7 +44,7 @@ func (h *ImportHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
 	// Ожидаем один из рантаймов: lua, python, py
 	var runtimeToken lexer.Token
 	switch current.Type {
-	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		runtimeToken = current
 		tokenStream.Consume()
 	default:
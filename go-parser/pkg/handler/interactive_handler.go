@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// InteractiveHandler - обработчик вызова, которому передается терминал:
+//
+//	interactive sh.run("htop")
+//
+// 'interactive' - обычный идентификатор: если за ним не идет name.function,
+// это не конструкция
+type InteractiveHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewInteractiveHandler создает новый обработчик interactive
+func NewInteractiveHandler(config config.ConstructHandlerConfig) *InteractiveHandler {
+	return &InteractiveHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *InteractiveHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && token.Value == "interactive"
+}
+
+// Handle обрабатывает interactive sh.run(...). Если за interactive не идет
+// name.function, обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с "interactive: ", парсер не передает их другим обработчикам
+func (h *InteractiveHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	interactiveToken := tokenStream.Current()
+	if !h.CanHandle(interactiveToken) || tokenStream.PeekN(2).Type != lexer.TokenDot {
+		return nil, nil
+	}
+	tokenStream.Consume()
+
+	moduleToken := tokenStream.Current()
+	if moduleToken.Value != "sh" || tokenStream.PeekN(2).Value != "run" || tokenStream.PeekN(3).Type != lexer.TokenLeftParen {
+		return nil, newErrorWithTokenPos(moduleToken, "interactive: expected a program to run, e.g. interactive sh.run(\"htop\")")
+	}
+	tokenStream.Consume() // sh
+	tokenStream.Consume() // '.'
+	functionToken := tokenStream.Consume()
+	arguments, err := parseCallArguments(ctx, "interactive", functionToken)
+	if err != nil {
+		return nil, err
+	}
+	call := &ast.LanguageCall{
+		Language:  moduleToken.Value,
+		Function:  functionToken.Value,
+		Arguments: arguments,
+		Result:    ast.ResultWait,
+		Pos:       tokenToPosition(moduleToken),
+	}
+	if err := parseResultPolicy(tokenStream, call); err != nil {
+		return nil, err
+	}
+	if call.Result != ast.ResultWait {
+		return nil, newErrorWithTokenPos(moduleToken, "interactive: call options such as !%s don't apply to a program using the terminal", call.Result)
+	}
+	return ast.NewInteractiveStatement(call, tokenToPosition(interactiveToken)), nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *InteractiveHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *InteractiveHandler) Name() string {
+	return h.config.Name
+}
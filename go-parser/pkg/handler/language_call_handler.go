@@ -7,6 +7,7 @@ import (
 	"go-parser/pkg/common"
 	"go-parser/pkg/config"
 	"go-parser/pkg/lexer"
+	"go-parser/pkg/stream"
 )
 
 // LanguageCallHandler - обработчик для вызовов функций других языков
@@ -135,6 +136,69 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 	tokenStream.Consume() // Consuming '('
 
 	// 6. Читаем аргументы
+	arguments, err := h.parseCallArguments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 7. Создаем узел AST
+	startPos := tokenToPosition(languageToken)
+
+	node := &ast.LanguageCall{
+		Language:  resolvedLanguage, // Используем разрешенное имя языка
+		Function:  functionName,
+		Arguments: arguments,
+		Pos:       startPos,
+	}
+
+	// 8. Методы, вызываемые по цепочке на результате: .fetch("x").json()
+	if err := h.parseMethodChain(ctx, node); err != nil {
+		return nil, err
+	}
+
+	// 9. Необязательный суффикс политики результата (!nowait, !raw)
+	if err := parseResultPolicy(tokenStream, node); err != nil {
+		return nil, err
+	}
+
+	// Проверяем, что после language call нет лишних токенов (кроме NEWLINE и токенов начинающих новые statement)
+	// Если есть &, это должен обрабатывать LanguageCallStatementHandler
+	// В режиме частичного парсинга пропускаем эту проверку
+	if !ctx.PartialParsingMode && !h.skipBackgroundCheck {
+		if tokenStream.HasMore() {
+			nextToken := tokenStream.Current()
+			if nextToken.Type == lexer.TokenAmpersand {
+				// Если есть &, это background task, который должен обрабатывать LanguageCallStatementHandler
+				return nil, fmt.Errorf("background task detected - should be handled by LanguageCallStatementHandler")
+			}
+			// Разрешаем токены, которые могут начинать новые statement или продолжать выражение
+			if nextToken.Type != lexer.TokenNewline &&
+				nextToken.Type != lexer.TokenSemicolon &&
+				!nextToken.IsLanguageIdentifierOrCallToken() &&
+				nextToken.Type != lexer.TokenIdentifier &&
+				nextToken.Type != lexer.TokenIf &&
+				nextToken.Type != lexer.TokenFor &&
+				nextToken.Type != lexer.TokenWhile &&
+				nextToken.Type != lexer.TokenMatch &&
+				nextToken.Type != lexer.TokenBreak &&
+				nextToken.Type != lexer.TokenContinue &&
+				nextToken.Type != lexer.TokenImport &&
+				!isBinaryOperator(nextToken.Type) &&
+				nextToken.Type != lexer.TokenQuestion &&
+				nextToken.Type != lexer.TokenColon &&
+				!isUnaryOperator(nextToken.Type) {
+				return nil, fmt.Errorf("unexpected token '%s' after language call", nextToken.Type)
+			}
+		}
+	}
+
+	return node, nil
+}
+
+// parseCallArguments читает аргументы вызова после '(' вместе с закрывающей скобкой
+func (h *LanguageCallHandler) parseCallArguments(ctx *common.ParseContext) ([]ast.Expression, error) {
+	tokenStream := ctx.TokenStream
+	var err error
 	arguments := make([]ast.Expression, 0)
 
 	// Проверяем, есть ли аргументы
@@ -157,7 +221,8 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 				}
 			}
 
-			// Читаем аргумент
+			// Читаем аргумент, именованный аргумент начинается с name=
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			argToken := tokenStream.Current()
 			if h.verbose {
 				fmt.Printf("DEBUG: LanguageCallHandler - processing argument token: %s (%s) at pos %d\n", argToken.Value, argToken.Type, argToken.Position)
@@ -165,7 +230,7 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 			var arg ast.Expression
 
 			switch argToken.Type {
-			case lexer.TokenJS, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
+			case lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
 				// Language token - use parseArgument to handle language calls and field access
 				if h.verbose {
 					fmt.Printf("DEBUG: LanguageCallHandler - parsing language token argument: %s (%s)\n", argToken.Value, argToken.Type)
@@ -204,6 +269,10 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 						Pos:    arg.Position(),
 					}
 				}
+				arg, err = continueOperandExpression(tokenStream, arg)
+				if err != nil {
+					return nil, newErrorWithPos(ctx.TokenStream, "failed to parse language token argument: %v", err)
+				}
 			case lexer.TokenIdentifier:
 				// Check if this is a builtin function call (identifier followed by '(')
 				if tokenStream.HasMore() && tokenStream.Peek().Type == lexer.TokenLeftParen {
@@ -215,7 +284,7 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 						if err != nil {
 							return nil, newErrorWithPos(ctx.TokenStream, "failed to parse builtin function call: %v", err)
 						}
-						if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+						if builtinCall, ok := result.(ast.Expression); ok {
 							arg = builtinCall
 						} else {
 							return nil, newErrorWithPos(ctx.TokenStream, "expected BuiltinFunctionCall, got %T", result)
@@ -259,7 +328,11 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 				}
 			case lexer.TokenString:
 				tokenStream.Consume()
-				arg = &ast.StringLiteral{Value: argToken.Value, Raw: argToken.Value, Pos: tokenToPosition(argToken)}
+				var err error
+				arg, err = parseValueMethodCall(tokenStream, &ast.StringLiteral{Value: argToken.Value, Raw: argToken.Value, Pos: tokenToPosition(argToken)})
+				if err != nil {
+					return nil, err
+				}
 			case lexer.TokenNumber:
 				// Check if this number is part of a complex expression
 				if tokenStream.HasMore() && isBinaryOperator(tokenStream.Peek().Type) {
@@ -346,15 +419,7 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 			case lexer.TokenLBracket:
 				// Парсим массив
 				arrayHandler := NewArrayHandler(100, 0)
-				arrayCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       ctx.Depth + 1,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				arrayCtx := ctx.WithStream(tokenStream)
 				arrayResult, err := arrayHandler.Handle(arrayCtx)
 				if err != nil {
 					return nil, newErrorWithPos(ctx.TokenStream, "failed to parse array argument: %v", err)
@@ -367,15 +432,7 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 			case lexer.TokenLBrace:
 				// Парсим объект
 				objectHandler := NewObjectHandler(100, 0)
-				objectCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       ctx.Depth + 1,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				objectCtx := ctx.WithStream(tokenStream)
 				objectResult, err := objectHandler.Handle(objectCtx)
 				if err != nil {
 					return nil, newErrorWithPos(ctx.TokenStream, "failed to parse object argument: %v", err)
@@ -405,7 +462,9 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 				return nil, newErrorWithTokenPos(argToken, "unsupported argument type: %s", argToken.Type)
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
@@ -432,53 +491,97 @@ func (h *LanguageCallHandler) Handle(ctx *common.ParseContext) (interface{}, err
 		}
 	}
 
-	// 7. Проверяем закрывающую скобку
+	// Проверяем закрывающую скобку
 	if !tokenStream.HasMore() || (tokenStream.Current().Type != lexer.TokenRightParen && tokenStream.Current().Type != lexer.TokenRParen) {
 		return nil, newErrorWithPos(ctx.TokenStream, "expected ')' after arguments")
 	}
 	tokenStream.Consume() // Consuming ')'
+	return arguments, nil
+}
 
-	// 7. Создаем узел AST
-	startPos := tokenToPosition(languageToken)
-
-	node := &ast.LanguageCall{
-		Language:  resolvedLanguage, // Используем разрешенное имя языка
-		Function:  functionName,
-		Arguments: arguments,
-		Pos:       startPos,
+// parseMethodChain разбирает методы, вызываемые по цепочке на результате
+// вызова: .name(arguments), пока за точкой следуют имя и '('
+func (h *LanguageCallHandler) parseMethodChain(ctx *common.ParseContext, node *ast.LanguageCall) error {
+	chain, err := h.parseMethodCalls(ctx)
+	if err != nil {
+		return err
 	}
+	node.Chain = append(node.Chain, chain...)
+	return nil
+}
 
-	// Проверяем, что после language call нет лишних токенов (кроме NEWLINE и токенов начинающих новые statement)
-	// Если есть &, это должен обрабатывать LanguageCallStatementHandler
-	// В режиме частичного парсинга пропускаем эту проверку
-	if !ctx.PartialParsingMode && !h.skipBackgroundCheck {
-		if tokenStream.HasMore() {
-			nextToken := tokenStream.Current()
-			if nextToken.Type == lexer.TokenAmpersand {
-				// Если есть &, это background task, который должен обрабатывать LanguageCallStatementHandler
-				return nil, fmt.Errorf("background task detected - should be handled by LanguageCallStatementHandler")
-			}
-			// Разрешаем токены, которые могут начинать новые statement или продолжать выражение
-			if nextToken.Type != lexer.TokenNewline &&
-				!nextToken.IsLanguageIdentifierOrCallToken() &&
-				nextToken.Type != lexer.TokenIdentifier &&
-				nextToken.Type != lexer.TokenIf &&
-				nextToken.Type != lexer.TokenFor &&
-				nextToken.Type != lexer.TokenWhile &&
-				nextToken.Type != lexer.TokenMatch &&
-				nextToken.Type != lexer.TokenBreak &&
-				nextToken.Type != lexer.TokenContinue &&
-				nextToken.Type != lexer.TokenImport &&
-				!isBinaryOperator(nextToken.Type) &&
-				nextToken.Type != lexer.TokenQuestion &&
-				nextToken.Type != lexer.TokenColon &&
-				!isUnaryOperator(nextToken.Type) {
-				return nil, fmt.Errorf("unexpected token '%s' after language call", nextToken.Type)
-			}
+// parseMethodCalls разбирает вызовы .method(args) подряд, пока они идут
+func (h *LanguageCallHandler) parseMethodCalls(ctx *common.ParseContext) ([]*ast.MethodCall, error) {
+	tokenStream := ctx.TokenStream
+	var chain []*ast.MethodCall
+	for isMethodChainStart(tokenStream) {
+		tokenStream.Consume() // '.'
+		methodToken := tokenStream.Consume()
+		tokenStream.Consume() // '('
+		arguments, err := h.parseCallArguments(ctx)
+		if err != nil {
+			return nil, err
 		}
+		chain = append(chain, &ast.MethodCall{
+			Method:    methodToken.Value,
+			Arguments: arguments,
+			Pos:       tokenToPosition(methodToken),
+		})
 	}
+	return chain, nil
+}
 
-	return node, nil
+// isMethodChainStart проверяет, что дальше идет ".method("
+func isMethodChainStart(tokenStream stream.TokenStream) bool {
+	return tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenDot &&
+		tokenStream.Peek().Type == lexer.TokenIdentifier && tokenStream.PeekN(2).Type == lexer.TokenLeftParen
+}
+
+// parseCallMethodChain разбирает цепочку методов после вызова, разобранного
+// другим обработчиком
+func parseCallMethodChain(tokenStream stream.TokenStream, node *ast.LanguageCall) error {
+	chainCtx := common.NewParseContext(tokenStream)
+	chainCtx.PartialParsingMode = true
+	return NewLanguageCallHandler(config.ConstructHandlerConfig{}).parseMethodChain(chainCtx, node)
+}
+
+// parseValueMethodCall разбирает методы, вызванные на уже разобранном значении
+// (например, на строковом литерале: "a,b".split(",")). Без вызова метода
+// значение возвращается как есть
+func parseValueMethodCall(tokenStream stream.TokenStream, receiver ast.Expression) (ast.Expression, error) {
+	if !isMethodChainStart(tokenStream) {
+		return receiver, nil
+	}
+	chainCtx := common.NewParseContext(tokenStream)
+	chainCtx.PartialParsingMode = true
+	chain, err := NewLanguageCallHandler(config.ConstructHandlerConfig{}).parseMethodCalls(chainCtx)
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewValueMethodCall(receiver, chain, receiver.Position()), nil
+}
+
+// parseResultPolicy разбирает суффикс !nowait или !raw сразу после закрывающей скобки вызова.
+// Суффикс распознается только если имя следует за '!' вплотную
+func parseResultPolicy(tokenStream stream.TokenStream, node *ast.LanguageCall) error {
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenNot {
+		return nil
+	}
+	bang := tokenStream.Current()
+	name := tokenStream.Peek()
+	if name.Type != lexer.TokenIdentifier || name.Position != bang.Position+1 {
+		return nil
+	}
+
+	switch policy := ast.ResultPolicy(name.Value); policy {
+	case ast.ResultNoWait, ast.ResultRaw:
+		tokenStream.Consume() // '!'
+		tokenStream.Consume() // имя политики
+		node.Result = policy
+		return nil
+	default:
+		return newErrorWithTokenPos(name, "unknown call option '!%s', expected !nowait or !raw", name.Value)
+	}
 }
 
 // Config возвращает конфигурацию обработчика
@@ -632,7 +735,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 
 	case lexer.TokenString:
 		tokenStream.Consume()
-		return &ast.StringLiteral{Value: token.Value, Raw: token.Value, Pos: tokenToPosition(token)}, nil
+		return parseValueMethodCall(tokenStream, &ast.StringLiteral{Value: token.Value, Raw: token.Value, Pos: tokenToPosition(token)})
 
 	case lexer.TokenNumber:
 		// Check if this number is part of a complex expression
@@ -688,15 +791,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 	case lexer.TokenLBracket:
 		// Парсим массив
 		arrayHandler := NewArrayHandler(100, 0)
-		arrayCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		arrayCtx := ctx.WithStream(tokenStream)
 		arrayResult, err := arrayHandler.Handle(arrayCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse array argument: %v", err)
@@ -710,15 +805,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 	case lexer.TokenLBrace:
 		// Парсим объект
 		objectHandler := NewObjectHandler(100, 0)
-		objectCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		objectCtx := ctx.WithStream(tokenStream)
 		objectResult, err := objectHandler.Handle(objectCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse object argument: %v", err)
@@ -729,7 +816,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 			return nil, fmt.Errorf("expected ObjectLiteral, got %T", objectResult)
 		}
 
-	case lexer.TokenJS, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
+	case lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
 		// Check for named argument with language tokens (identifier = expression or := expression)
 		if tokenStream.HasMore() && (tokenStream.Peek().Type == lexer.TokenAssign || tokenStream.Peek().Type == lexer.TokenColonEquals) {
 			// This is a named argument: name = value or name := value
@@ -785,12 +872,8 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 				}
 				// This is a language call: language.something.something(...)
 				languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{})
-				languageCallCtx := &common.ParseContext{
-					TokenStream:        tokenStream,
-					Depth:              ctx.Depth + 1,
-					MaxDepth:           ctx.MaxDepth,
-					PartialParsingMode: true, // We're parsing an expression inside arguments
-				}
+				languageCallCtx := ctx.WithStream(tokenStream)
+				languageCallCtx.PartialParsingMode = true
 				languageCallResult, err := languageCallHandler.Handle(languageCallCtx)
 				if err != nil {
 					if h.verbose {
@@ -816,11 +899,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 
 		// Language tokens represent field access
 		fieldAccessHandler := NewFieldAccessHandler(config.ConstructHandlerConfig{})
-		fieldAccessCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-		}
+		fieldAccessCtx := ctx.WithStream(tokenStream)
 		fieldAccessResult, err := fieldAccessHandler.Handle(fieldAccessCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse field access argument: %v", err)
@@ -999,15 +1078,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 
 				// Используем FieldAccessHandler для парсинга цепочки полей
 				fieldAccessHandler := NewFieldAccessHandler(config.ConstructHandlerConfig{})
-				fieldAccessCtx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Parser:      nil,
-					Depth:       ctx.Depth + 1,
-					MaxDepth:    ctx.MaxDepth,
-					Guard:       ctx.Guard,
-					LoopDepth:   ctx.LoopDepth,
-					InputStream: ctx.InputStream,
-				}
+				fieldAccessCtx := ctx.WithStream(tokenStream)
 
 				// FieldAccessHandler обработает всю цепочку начиная с текущей позиции
 				fieldAccessResult, err := fieldAccessHandler.Handle(fieldAccessCtx)
@@ -1035,15 +1106,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 					tokenStream.Consume() // потребляем '['
 
 					// Создаем контекст для парсинга индекса
-					indexCtx := &common.ParseContext{
-						TokenStream: tokenStream,
-						Parser:      nil,
-						Depth:       ctx.Depth + 1,
-						MaxDepth:    ctx.MaxDepth,
-						Guard:       ctx.Guard,
-						LoopDepth:   ctx.LoopDepth,
-						InputStream: ctx.InputStream,
-					}
+					indexCtx := ctx.WithStream(tokenStream)
 
 					// Парсим индекс
 					indexArg, err := h.parseArgument(indexCtx)
@@ -1114,15 +1177,7 @@ func (h *LanguageCallHandler) parseArgument(ctx *common.ParseContext) (ast.Expre
 	case lexer.TokenDoubleLeftAngle:
 		// Битстринг как аргумент
 		bitstringHandler := NewBitstringHandler(config.ConstructHandlerConfig{})
-		bitstringCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       ctx.Depth + 1,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-			LoopDepth:   ctx.LoopDepth,
-			InputStream: ctx.InputStream,
-		}
+		bitstringCtx := ctx.WithStream(tokenStream)
 		bitstringResult, err := bitstringHandler.Handle(bitstringCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse bitstring argument: %v", err)
@@ -41,7 +41,13 @@ func (h *LanguageCallStatementHandler) CanHandle(token lexer.Token) bool {
 		token.Type == lexer.TokenPy ||
 		token.Type == lexer.TokenGo ||
 		token.Type == lexer.TokenNode ||
-		token.Type == lexer.TokenJS
+		token.Type == lexer.TokenJS ||
+		token.Type == lexer.TokenPerl ||
+		token.Type == lexer.TokenPl ||
+		token.Type == lexer.TokenErlang ||
+		token.Type == lexer.TokenErl ||
+		token.Type == lexer.TokenElixir ||
+		token.Type == lexer.TokenEx
 }
 
 // Handle обрабатывает language call statement
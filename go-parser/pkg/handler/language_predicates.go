@@ -32,5 +32,4 @@ func IsLanguageCallStart(token lexer.Token) bool {
 	// Проверяем, что следующий токен - точка или открывающая скобка
 	// Это будет использоваться в обработчиках для определения паттернов вызова
 	return true
-	return true
 }
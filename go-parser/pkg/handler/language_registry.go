@@ -290,10 +290,48 @@ func CreateDefaultLanguageRegistry() LanguageRegistry {
 	}
 	registry.RegisterLanguage("node", nodeHandler)
 
+	// Регистрируем обработчик для Perl
+	perlHandler := &LanguageHandler{
+		Language: "perl",
+		Constructs: map[common.ConstructType]common.Handler{
+			common.ConstructArray:      NewArrayHandler(10, 1),
+			common.ConstructObject:     NewObjectHandler(10, 1),
+			common.ConstructAssignment: NewAssignmentHandler(5, 1),
+		},
+		TokenMapping: map[lexer.TokenType]common.ConstructType{
+			lexer.TokenLBracket:   common.ConstructArray,
+			lexer.TokenLBrace:     common.ConstructObject,
+			lexer.TokenIdentifier: common.ConstructAssignment,
+		},
+		Priority: 60,
+	}
+	registry.RegisterLanguage("perl", perlHandler)
+
+	// Регистрируем обработчики для Erlang и Elixir
+	for _, language := range []string{"erlang", "elixir"} {
+		registry.RegisterLanguage(language, &LanguageHandler{
+			Language: language,
+			Constructs: map[common.ConstructType]common.Handler{
+				common.ConstructArray:      NewArrayHandler(10, 1),
+				common.ConstructObject:     NewObjectHandler(10, 1),
+				common.ConstructAssignment: NewAssignmentHandler(5, 1),
+			},
+			TokenMapping: map[lexer.TokenType]common.ConstructType{
+				lexer.TokenLBracket:   common.ConstructArray,
+				lexer.TokenLBrace:     common.ConstructObject,
+				lexer.TokenIdentifier: common.ConstructAssignment,
+			},
+			Priority: 60,
+		})
+	}
+
 	// Регистрируем стандартные алиасы
 	registry.RegisterAlias("py", "python")
 	registry.RegisterAlias("js", "node")
 	registry.RegisterAlias("l", "lua")
+	registry.RegisterAlias("pl", "perl")
+	registry.RegisterAlias("erl", "erlang")
+	registry.RegisterAlias("ex", "elixir")
 
 	return registry
 }
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// LimitHandler - обработчик блоков под ограничением:
+//
+//	rate_limit("api") { py.fetch(url) }
+//	semaphore("db", 2) { py.query(sql) }
+//
+// Без тела rate_limit(...) и semaphore(...) - обычные вызовы builtin функций,
+// которые объявляют ограничение, их разбирает BuiltinFunctionHandler.
+// Тело, как у retry, UnifiedParser разбирает как скрипт
+type LimitHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewLimitHandler создает новый обработчик блоков под ограничением
+func NewLimitHandler(config config.ConstructHandlerConfig) *LimitHandler {
+	return &LimitHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *LimitHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && (token.Value == "rate_limit" || token.Value == "semaphore")
+}
+
+// Handle обрабатывает name(args) { ... }. Если за аргументами не идет '{',
+// обработчик возвращает nil, и вызов разбирается как builtin функция.
+// Ошибки начинаются с имени функции, парсер не передает их другим обработчикам
+func (h *LimitHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	nameToken := tokenStream.Current()
+	if !h.CanHandle(nameToken) || tokenStream.Peek().Type != lexer.TokenLeftParen || !callHasBody(tokenStream) {
+		return nil, nil
+	}
+	function := nameToken.Value
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	var arguments []ast.Expression
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
+		if len(arguments) > 0 {
+			if tokenStream.Current().Type != lexer.TokenComma {
+				return nil, newErrorWithPos(tokenStream, "%s: expected ',' or ')' after an argument", function)
+			}
+			tokenStream.Consume()
+		}
+		argumentToken := tokenStream.Current()
+		exprParser := NewUnifiedExpressionParser(false)
+		argument, err := exprParser.ParseExpression(ctx)
+		if err != nil {
+			return nil, newErrorWithTokenPos(argumentToken, "%s: invalid argument: %v", function, err)
+		}
+		arguments = append(arguments, argument)
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithTokenPos(nameToken, "%s: expected ')' after the arguments", function)
+	}
+	tokenStream.Consume()
+	if len(arguments) == 0 || len(arguments) > 2 {
+		return nil, newErrorWithTokenPos(nameToken, "%s: expected the name of the limit and optionally its value, e.g. %s(\"api\") { ... }", function, function)
+	}
+
+	statement := ast.NewLimitStatement(function, arguments, tokenToPosition(nameToken))
+	source, bodyPos, err := captureBody(ctx, function, "the body of "+function)
+	if err != nil {
+		return nil, err
+	}
+	statement.Source, statement.BodyPos = source, bodyPos
+	return statement, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *LimitHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *LimitHandler) Name() string {
+	return h.config.Name
+}
@@ -122,7 +122,7 @@ func (h *MatchHandler) isBinaryOperator(tokenType lexer.TokenType) bool {
 	case lexer.TokenGreater, lexer.TokenLess, lexer.TokenGreaterEqual, lexer.TokenLessEqual,
 		lexer.TokenEqual, lexer.TokenNotEqual, lexer.TokenPlus, lexer.TokenMinus, lexer.TokenMultiply, lexer.TokenSlash,
 		lexer.TokenAnd, lexer.TokenOr, lexer.TokenModulo, lexer.TokenDoubleRightAngle, lexer.TokenDoubleLeftAngle,
-		lexer.TokenAmpersand, lexer.TokenCaret, lexer.TokenConcat:
+		lexer.TokenAmpersand, lexer.TokenCaret, lexer.TokenConcat, lexer.TokenIn, lexer.TokenNotIn:
 		return true
 	default:
 		return false
@@ -250,11 +250,7 @@ func (h *MatchHandler) parsePrimaryOrComplexExpressionWithDepth(tokenStream stre
 	// Проверяем унарные операторы
 	if h.isUnaryOperator(currentToken.Type) {
 		unaryHandler := NewUnaryExpressionHandler(config.ConstructHandlerConfig{})
-		ctx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Depth:       0,
-			MaxDepth:    100,
-		}
+		ctx := common.NewParseContext(tokenStream)
 		result, err := unaryHandler.Handle(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse unary expression: %v", err)
@@ -291,7 +287,7 @@ func (h *MatchHandler) parsePrimaryOrComplexExpressionWithDepth(tokenStream stre
 			return nil, newErrorWithTokenPos(token, "invalid number format: %s", token.Value)
 		}
 		return createNumberLiteral(token, numValue), nil
-	case lexer.TokenIdentifier, lexer.TokenPy, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenIdentifier, lexer.TokenPy, lexer.TokenLua, lexer.TokenPython, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		if tokenStream.HasMore() {
 			nextToken := tokenStream.Peek()
 			if nextToken.Type == lexer.TokenDot {
@@ -335,6 +331,14 @@ func (h *MatchHandler) parsePrimaryOrComplexExpressionWithDepth(tokenStream stre
 	case lexer.TokenLBrace:
 		// Объект как выражение
 		return h.parseObjectExpression(tokenStream)
+	case lexer.TokenTry:
+		// match try py.risky() { ok(v) -> ..., error(e) -> ... }
+		tryToken := tokenStream.Consume()
+		operand, err := h.parsePrimaryOrComplexExpressionWithDepth(tokenStream, parenDepth)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewTryExpression(tryToken, operand, matchHandlerTokenToPosition(tryToken)), nil
 	default:
 		return nil, newErrorWithTokenPos(currentToken, "unsupported expression type: %s", currentToken.Type)
 	}
@@ -463,6 +467,16 @@ func (h *MatchHandler) parseLanguageCall(tokenStream stream.TokenStream) (ast.Ex
 		functionName += part
 	}
 
+	// Без скобок после языка это чтение переменной, в том числе по
+	// глубокому пути: match py.config.server.port { ... }
+	if languageToken.IsLanguageToken() && (!tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenLeftParen) {
+		name := functionParts[len(functionParts)-1]
+		if len(functionParts) > 1 {
+			return ast.NewVariableRead(ast.NewQualifiedIdentifierWithPath(languageToken, functionToken, language, functionParts[:len(functionParts)-1], name)), nil
+		}
+		return ast.NewVariableRead(ast.NewQualifiedIdentifier(languageToken, functionToken, language, name)), nil
+	}
+
 	// 4. Проверяем, есть ли открывающая скобка (аргументы)
 	arguments := make([]ast.Expression, 0)
 
@@ -726,6 +740,15 @@ func (h *MatchHandler) parseMatchArms(tokenStream stream.TokenStream) ([]ast.Mat
 			return nil, err
 		}
 
+		// Необязательное условие: n if n in allowed -> ...
+		var guard ast.Expression
+		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenIf {
+			guard, err = h.parseGuard(tokenStream)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// Потребляем '->'
 		if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenArrow {
 			return nil, newErrorWithPos(tokenStream, "expected '->' after pattern")
@@ -741,6 +764,7 @@ func (h *MatchHandler) parseMatchArms(tokenStream stream.TokenStream) ([]ast.Mat
 		// Создаем MatchArm
 		arm := ast.MatchArm{
 			Pattern:    pattern,
+			Guard:      guard,
 			ArrowToken: arrowToken,
 			Statement:  statement,
 		}
@@ -770,6 +794,17 @@ func (h *MatchHandler) parseMatchArms(tokenStream stream.TokenStream) ([]ast.Mat
 	return arms, nil
 }
 
+// parseGuard парсит условие ветки после if; оно видит переменные паттерна
+func (h *MatchHandler) parseGuard(tokenStream stream.TokenStream) (ast.Expression, error) {
+	ifToken := tokenStream.Consume()
+	ctx := common.NewParseContext(tokenStream)
+	guard, err := NewUnifiedExpressionParser(h.verbose).ParseExpression(ctx)
+	if err != nil {
+		return nil, newErrorWithTokenPos(ifToken, "failed to parse match guard: %v", err)
+	}
+	return guard, nil
+}
+
 // parsePattern парсит паттерн
 func (h *MatchHandler) parsePattern(tokenStream stream.TokenStream) (ast.Pattern, error) {
 	currentToken := tokenStream.Current()
@@ -784,10 +819,12 @@ func (h *MatchHandler) parsePattern(tokenStream stream.TokenStream) (ast.Pattern
 	}
 
 	switch currentToken.Type {
-	case lexer.TokenString:
-		return h.parseLiteralPattern(tokenStream)
-	case lexer.TokenNumber:
-		return h.parseLiteralPattern(tokenStream)
+	case lexer.TokenString, lexer.TokenNumber:
+		pattern, err := h.parseLiteralPattern(tokenStream)
+		if err != nil || !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRange {
+			return pattern, err
+		}
+		return h.parseRangePattern(tokenStream, pattern.(*ast.LiteralPattern))
 	case lexer.TokenLBracket:
 		return h.parseArrayPattern(tokenStream)
 	case lexer.TokenLBrace:
@@ -800,6 +837,10 @@ func (h *MatchHandler) parsePattern(tokenStream stream.TokenStream) (ast.Pattern
 		if currentToken.Value == "_" {
 			return h.parseWildcardPattern(tokenStream)
 		}
+		// ok(...) и error(...) сопоставляются с результатом try
+		if (currentToken.Value == "ok" || currentToken.Value == "error") && tokenStream.Peek().Type == lexer.TokenLeftParen {
+			return h.parseResultPattern(tokenStream)
+		}
 		return h.parseVariablePattern(tokenStream)
 	case lexer.TokenUnderscore:
 		// Обработка токена underscore как wildcard
@@ -839,6 +880,33 @@ func (h *MatchHandler) parseLiteralPattern(tokenStream stream.TokenStream) (ast.
 	}, nil
 }
 
+// parseRangePattern парсит паттерн диапазона low..high или low..<high после
+// нижней границы
+func (h *MatchHandler) parseRangePattern(tokenStream stream.TokenStream, low *ast.LiteralPattern) (ast.Pattern, error) {
+	rangeToken := tokenStream.Consume() // ..
+	if !tokenStream.HasMore() {
+		return nil, newErrorWithTokenPos(rangeToken, "expected upper bound after '..'")
+	}
+	next := tokenStream.Current()
+	if next.Type != lexer.TokenString && next.Type != lexer.TokenNumber {
+		return nil, newErrorWithTokenPos(next, "range upper bound must be a number or string, got %s", next.Type)
+	}
+	if _, lowIsString := low.Value.(string); lowIsString != (next.Type == lexer.TokenString) {
+		return nil, newErrorWithTokenPos(next, "range bounds must both be numbers or both be strings")
+	}
+	high, err := h.parseLiteralPattern(tokenStream)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.RangePattern{
+		Low:       low.Value,
+		High:      high.(*ast.LiteralPattern).Value,
+		Exclusive: rangeToken.Value == "..<",
+		Pos:       low.Pos,
+	}, nil
+}
+
 // parseArrayPattern парсит массивный паттерн
 func (h *MatchHandler) parseArrayPattern(tokenStream stream.TokenStream) (ast.Pattern, error) {
 	lBracketToken := tokenStream.Consume() // [
@@ -950,6 +1018,29 @@ func (h *MatchHandler) parseObjectPattern(tokenStream stream.TokenStream) (ast.P
 	}, nil
 }
 
+// parseResultPattern парсит паттерн ok(pattern) или error(pattern)
+func (h *MatchHandler) parseResultPattern(tokenStream stream.TokenStream) (ast.Pattern, error) {
+	tagToken := tokenStream.Consume() // ok | error
+	tokenStream.Consume()             // (
+
+	inner, err := h.parsePattern(tokenStream)
+	if err != nil {
+		return nil, err
+	}
+
+	// Потребляем )
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithPos(tokenStream, "expected ')' to close %s pattern", tagToken.Value)
+	}
+	tokenStream.Consume() // )
+
+	return &ast.ResultPattern{
+		Ok:    tagToken.Value == "ok",
+		Inner: inner,
+		Pos:   matchHandlerTokenToPosition(tagToken),
+	}, nil
+}
+
 // parseVariablePattern парсит переменный паттерн
 func (h *MatchHandler) parseVariablePattern(tokenStream stream.TokenStream) (ast.Pattern, error) {
 	token := tokenStream.Consume()
@@ -995,7 +1086,13 @@ func (h *MatchHandler) parseStatement(tokenStream stream.TokenStream) (ast.State
 		currentToken.Type == lexer.TokenPy ||
 		currentToken.Type == lexer.TokenGo ||
 		currentToken.Type == lexer.TokenNode ||
-		currentToken.Type == lexer.TokenJS {
+		currentToken.Type == lexer.TokenJS ||
+		currentToken.Type == lexer.TokenPerl ||
+		currentToken.Type == lexer.TokenPl ||
+		currentToken.Type == lexer.TokenErlang ||
+		currentToken.Type == lexer.TokenErl ||
+		currentToken.Type == lexer.TokenElixir ||
+		currentToken.Type == lexer.TokenEx {
 
 		// Проверяем, что идет после идентификатора
 		if tokenStream.HasMore() {
@@ -1006,8 +1103,8 @@ func (h *MatchHandler) parseStatement(tokenStream stream.TokenStream) (ast.State
 				return h.parseAssignmentStatement(tokenStream)
 			}
 
-			// Если следующий токен '.', это language call
-			if nextToken.Type == lexer.TokenDot {
+			// Если следующий токен '.', это language call (кроме вызова метода объекта obj.method())
+			if nextToken.Type == lexer.TokenDot && !isBuiltinCallStart(tokenStream) {
 				// Пробуем распарсить как language call
 				expr, err := h.parseLanguageCall(tokenStream)
 				if err != nil {
@@ -1022,15 +1119,11 @@ func (h *MatchHandler) parseStatement(tokenStream stream.TokenStream) (ast.State
 				return nil, newErrorWithPos(tokenStream, "expression cannot be used as statement")
 			}
 
-			// Если следующий токен '(', это может быть builtin функция
-			if nextToken.Type == lexer.TokenLeftParen {
+			// Если следующий токен '(', это может быть builtin функция или метод объекта
+			if isBuiltinCallStart(tokenStream) {
 				// Пробуем распарсить как builtin функцию
 				// Создаем временный контекст для парсера builtin функций
-				ctx := &common.ParseContext{
-					TokenStream: tokenStream,
-					Depth:       0,
-					MaxDepth:    100,
-				}
+				ctx := common.NewParseContext(tokenStream)
 
 				// Создаем конфигурацию для builtin function handler
 				builtinConfig := config.ConstructHandlerConfig{
@@ -1060,7 +1153,7 @@ func (h *MatchHandler) parseStatement(tokenStream stream.TokenStream) (ast.State
 				}
 
 				// BuiltinFunctionCall implements Statement interface
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					return builtinCall, nil
 				}
 
@@ -1401,7 +1494,7 @@ func (h *MatchHandler) parseBitstringPattern(tokenStream stream.TokenStream) (as
 					return nil, err
 				}
 			}
-		case lexer.TokenIdentifier, lexer.TokenUnderscore, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenJS, lexer.TokenNode, lexer.TokenGo:
+		case lexer.TokenIdentifier, lexer.TokenUnderscore, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenNode, lexer.TokenGo:
 			// Переменная в битстринге (обычная или языковая)
 			currentToken := tokenStream.Current()
 
@@ -1617,6 +1710,10 @@ func (h *MatchHandler) parseSizePrimaryOrComplexExpressionWithDepth(tokenStream
 	case lexer.TokenIdentifier:
 		// Переменная
 		token := tokenStream.Consume()
+		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLeftParen {
+			// Вызов встроенной функции (например, byte_size(magic))
+			return h.parseSizeCall(tokenStream, token, parenDepth)
+		}
 		return &ast.Identifier{
 			Name: token.Value,
 			Pos:  matchHandlerTokenToPosition(token),
@@ -1626,6 +1723,31 @@ func (h *MatchHandler) parseSizePrimaryOrComplexExpressionWithDepth(tokenStream
 	}
 }
 
+// parseSizeCall парсит аргументы вызова функции в size выражении
+func (h *MatchHandler) parseSizeCall(tokenStream stream.TokenStream, functionToken lexer.Token, parenDepth int) (ast.Expression, error) {
+	tokenStream.Consume() // (
+
+	var arguments []ast.Expression
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
+		arg, err := h.parseSizeExpressionLimitedWithDepth(tokenStream, parenDepth+1)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, arg)
+		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenComma {
+			tokenStream.Consume() // ,
+		} else {
+			break
+		}
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithPos(tokenStream, "expected ')' after arguments of %s", functionToken.Value)
+	}
+	tokenStream.Consume() // )
+
+	return ast.NewBuiltinFunctionCall(functionToken.Value, arguments, matchHandlerTokenToPosition(functionToken)), nil
+}
+
 // isSizeExpressionTerminator проверяет, является ли токен терминатором size выражения
 func (h *MatchHandler) isSizeExpressionTerminator(tokenType lexer.TokenType) bool {
 	switch tokenType {
@@ -1798,8 +1920,8 @@ func (h *MatchHandler) parseBlockStatement(tokenStream stream.TokenStream) (ast.
 
 	// Парсим statements внутри блока
 	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRBrace {
-		// Пропускаем newline токены
-		for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenNewline {
+		// Пропускаем newline токены и ';'
+		for tokenStream.HasMore() && isStatementSeparator(tokenStream.Current()) {
 			tokenStream.Consume() // newline
 		}
 
@@ -1820,8 +1942,8 @@ func (h *MatchHandler) parseBlockStatement(tokenStream stream.TokenStream) (ast.
 
 		blockStmt.Statements = append(blockStmt.Statements, stmt)
 
-		// Пропускаем newline токены после statement
-		for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenNewline {
+		// Пропускаем newline токены и ';' после statement
+		for tokenStream.HasMore() && isStatementSeparator(tokenStream.Current()) {
 			tokenStream.Consume() // newline
 		}
 	}
@@ -1848,11 +1970,7 @@ func (h *MatchHandler) parseIfStatement(tokenStream stream.TokenStream) (ast.Sta
 	lParenToken := tokenStream.Consume() // (
 
 	// Парсим условие с помощью UnifiedExpressionParser для правильных приоритетов
-	ctx := &common.ParseContext{
-		TokenStream: tokenStream,
-		Depth:       0,
-		MaxDepth:    100,
-	}
+	ctx := common.NewParseContext(tokenStream)
 	expressionParser := NewUnifiedExpressionParser(h.verbose)
 	condition, err := expressionParser.ParseExpression(ctx)
 	if err != nil {
@@ -357,10 +357,10 @@ func (h *NumericForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.S
 		// Проверяем, не является ли это вызовом функции другого языка
 		if current.Type == lexer.TokenIdentifier || current.Type == lexer.TokenLua ||
 			current.Type == lexer.TokenPython || current.Type == lexer.TokenPy || current.Type == lexer.TokenGo ||
-			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS {
+			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx {
 
 			// Сначала проверяем, не является ли это присваиванием (смотрим на следующий через DOT токен)
-			if (current.Type == lexer.TokenJS || current.Type == lexer.TokenLua || current.Type == lexer.TokenPython ||
+			if (current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx || current.Type == lexer.TokenLua || current.Type == lexer.TokenPython ||
 				current.Type == lexer.TokenPy || current.Type == lexer.TokenGo || current.Type == lexer.TokenNode) &&
 				tokenStream.Peek().Type == lexer.TokenDot {
 				// Проверяем токен после DOT
@@ -451,7 +451,7 @@ func (h *NumericForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.S
 		// Проверяем, не является ли это присваиванием
 		if current.Type == lexer.TokenIdentifier || current.Type == lexer.TokenLua ||
 			current.Type == lexer.TokenPython || current.Type == lexer.TokenPy || current.Type == lexer.TokenGo ||
-			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS {
+			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx {
 
 			// Проверяем, не является ли это присваиванием (смотрим на следующий токен)
 			peekForAssign := tokenStream.Peek()
@@ -486,15 +486,7 @@ func (h *NumericForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.S
 
 			// Парсим индексное выражение с помощью BinaryExpressionHandler
 			binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
-			tempCtx := &common.ParseContext{
-				TokenStream: tokenStream,
-				Parser:      ctx.Parser,
-				Depth:       ctx.Depth,
-				MaxDepth:    ctx.MaxDepth,
-				Guard:       ctx.Guard,
-				LoopDepth:   ctx.LoopDepth,
-				InputStream: ctx.InputStream,
-			}
+			tempCtx := ctx.WithStream(tokenStream)
 
 			// Парсим выражение внутри скобок
 			indexExpr, err := binaryHandler.ParseFullExpression(tempCtx, nil)
@@ -526,7 +518,7 @@ func (h *NumericForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.S
 		}
 
 		// Проверяем, не является ли это вызовом builtin функции
-		if current.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			if h.verbose {
 				fmt.Printf("DEBUG parseLoopBody: found identifier followed by '(', trying builtin function\n")
 			}
@@ -537,7 +529,7 @@ func (h *NumericForLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.S
 				if h.verbose {
 					fmt.Printf("DEBUG parseLoopBody: builtin function parsing succeeded\n")
 				}
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					body = append(body, builtinCall)
 					continue
 				}
@@ -607,7 +599,7 @@ func (h *NumericForLoopHandler) parseSimpleIfStatement(ctx *common.ParseContext)
 		// Обрабатываем вызовы функций
 		if current.Type == lexer.TokenIdentifier || current.Type == lexer.TokenLua ||
 			current.Type == lexer.TokenPython || current.Type == lexer.TokenPy || current.Type == lexer.TokenGo ||
-			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS {
+			current.Type == lexer.TokenNode || current.Type == lexer.TokenJS || current.Type == lexer.TokenPerl || current.Type == lexer.TokenPl || current.Type == lexer.TokenErlang || current.Type == lexer.TokenErl || current.Type == lexer.TokenElixir || current.Type == lexer.TokenEx {
 
 			if tokenStream.Peek().Type == lexer.TokenDot {
 				// Это вызов функции вида js.print
@@ -100,15 +100,7 @@ func (h *ObjectHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
 		if ctx.TokenStream.Current().Type == lexer.TokenLBrace {
 			// Рекурсивно обрабатываем вложенный объект
 			nestedObjectHandler := NewObjectHandler(100, 0)
-			nestedCtx := &common.ParseContext{
-				TokenStream: ctx.TokenStream,
-				Parser:      nil,
-				Depth:       ctx.Depth + 1,
-				MaxDepth:    ctx.MaxDepth,
-				Guard:       ctx.Guard,
-				LoopDepth:   ctx.LoopDepth,
-				InputStream: ctx.InputStream,
-			}
+			nestedCtx := ctx.WithStream(ctx.TokenStream)
 
 			nestedResult, err := nestedObjectHandler.Handle(nestedCtx)
 			if err != nil {
@@ -123,15 +115,7 @@ func (h *ObjectHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
 		} else if ctx.TokenStream.Current().Type == lexer.TokenLBracket {
 			// Рекурсивно обрабатываем вложенный массив
 			nestedArrayHandler := NewArrayHandler(100, 0)
-			nestedCtx := &common.ParseContext{
-				TokenStream: ctx.TokenStream,
-				Parser:      nil,
-				Depth:       ctx.Depth + 1,
-				MaxDepth:    ctx.MaxDepth,
-				Guard:       ctx.Guard,
-				LoopDepth:   ctx.LoopDepth,
-				InputStream: ctx.InputStream,
-			}
+			nestedCtx := ctx.WithStream(ctx.TokenStream)
 
 			nestedResult, err := nestedArrayHandler.Handle(nestedCtx)
 			if err != nil {
@@ -146,15 +130,7 @@ func (h *ObjectHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
 		} else {
 			// Используем AssignmentHandler для парсинга сложных выражений
 			assignmentHandler := NewAssignmentHandler(100, 0)
-			assignmentCtx := &common.ParseContext{
-				TokenStream: ctx.TokenStream,
-				Parser:      nil,
-				Depth:       ctx.Depth + 1,
-				MaxDepth:    ctx.MaxDepth,
-				Guard:       ctx.Guard,
-				LoopDepth:   ctx.LoopDepth,
-				InputStream: ctx.InputStream,
-			}
+			assignmentCtx := ctx.WithStream(ctx.TokenStream)
 
 			value, err = assignmentHandler.parseComplexExpression(assignmentCtx)
 			if err != nil {
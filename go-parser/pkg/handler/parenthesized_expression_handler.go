@@ -31,12 +31,10 @@ func (h *ParenthesizedExpressionHandler) Handle(ctx *common.ParseContext) (inter
 	tokenStream := ctx.TokenStream
 
 	// Проверяем guard рекурсии
-	if ctx.Guard != nil {
-		if err := ctx.Guard.Enter(); err != nil {
-			return nil, err
-		}
-		defer ctx.Guard.Exit()
+	if err := ctx.Guard.Enter(); err != nil {
+		return nil, err
 	}
+	defer ctx.Guard.Exit()
 
 	// Проверяем, что текущий токен - открывающая скобка
 	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenLeftParen {
@@ -527,34 +525,12 @@ func (h *ParenthesizedExpressionHandler) parseLanguageCallInParentheses(ctx *com
 	}
 	tokenStream.Consume() // leftParenToken
 
-	// Парсим аргументы до закрывающей скобки
-	arguments := []ast.Expression{}
-	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
-		// Пропускаем запятые между аргументами
-		if tokenStream.Current().Type == lexer.TokenComma {
-			tokenStream.Consume()
-			continue
-		}
-
-		// Парсим аргумент
-		arg, err := h.parseArgument(tokenStream)
-		if err != nil {
-			return nil, err
-		}
-		arguments = append(arguments, arg)
-
-		// Проверяем, есть ли еще аргументы
-		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenComma {
-			tokenStream.Consume()
-			continue
-		}
-	}
-
-	// Проверяем закрывающую скобку
-	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
-		return nil, newErrorWithPos(tokenStream, "expected ')' after arguments")
+	// Аргументы - полные выражения, их разбирает LanguageCallHandler
+	languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{ConstructType: common.ConstructLanguageCall})
+	arguments, err := languageCallHandler.parseCallArguments(ctx)
+	if err != nil {
+		return nil, err
 	}
-	tokenStream.Consume() // rightParenToken
 
 	// Собираем полное имя функции (например "string.len" из "lua.string.len")
 	functionName := ""
@@ -583,7 +559,7 @@ func (h *ParenthesizedExpressionHandler) parseLanguageCallInParentheses(ctx *com
 // isLanguageToken проверяет, является ли токен языковым токеном
 func (h *ParenthesizedExpressionHandler) isLanguageToken(token lexer.Token) bool {
 	switch token.Type {
-	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		return true
 	default:
 		return false
@@ -645,39 +621,6 @@ func (h *ParenthesizedExpressionHandler) isLanguageCall(stream stream.TokenStrea
 	return false
 }
 
-// parseArgument разбирает аргумент функции
-func (h *ParenthesizedExpressionHandler) parseArgument(tokenStream stream.TokenStream) (ast.Expression, error) {
-	if !tokenStream.HasMore() {
-		return nil, newErrorWithPos(tokenStream, "unexpected end of input in argument")
-	}
-
-	token := tokenStream.Current()
-
-	switch token.Type {
-	case lexer.TokenString:
-		strToken := tokenStream.Consume()
-		return &ast.StringLiteral{
-			Value: strToken.Value,
-			Pos: ast.Position{
-				Line:   strToken.Line,
-				Column: strToken.Column,
-				Offset: strToken.Position,
-			},
-		}, nil
-
-	case lexer.TokenNumber:
-		numToken := tokenStream.Consume()
-		value, err := parseNumber(numToken.Value)
-		if err != nil {
-			return nil, newErrorWithTokenPos(numToken, "invalid number format: %s", numToken.Value)
-		}
-		return createNumberLiteral(numToken, value), nil
-
-	default:
-		return nil, newErrorWithTokenPos(token, "unsupported argument type: %s", token.Type)
-	}
-}
-
 // parseSingleExpression разбирает одно выражение (упрощенная версия)
 func (h *ParenthesizedExpressionHandler) parseSingleExpression(ctx *common.ParseContext) (ast.Expression, error) {
 	tokenStream := ctx.TokenStream
@@ -694,21 +637,21 @@ func (h *ParenthesizedExpressionHandler) parseSingleExpression(ctx *common.Parse
 	}
 
 	switch token.Type {
-	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		// Пробуем разобрать как language call
 		return h.parseLanguageCallInParentheses(ctx)
 
 	case lexer.TokenString:
 		// Строковый литерал
 		strToken := tokenStream.Consume()
-		return &ast.StringLiteral{
+		return parseValueMethodCall(tokenStream, &ast.StringLiteral{
 			Value: strToken.Value,
 			Pos: ast.Position{
 				Line:   strToken.Line,
 				Column: strToken.Column,
 				Offset: strToken.Position,
 			},
-		}, nil
+		})
 
 	case lexer.TokenNumber:
 		// Числовой литерал
@@ -790,7 +733,7 @@ func (h *ParenthesizedExpressionHandler) hasBinaryOperatorAfterParentheses(token
 		lexer.TokenGreater, lexer.TokenGreaterEqual, lexer.TokenAnd, lexer.TokenOr,
 		lexer.TokenBitwiseOr, lexer.TokenDoubleLeftAngle, lexer.TokenDoubleRightAngle,
 		lexer.TokenModulo, lexer.TokenConcat, lexer.TokenPower, lexer.TokenCaret,
-		lexer.TokenQuestion:
+		lexer.TokenIn, lexer.TokenNotIn, lexer.TokenQuestion:
 		return true
 	default:
 		return false
@@ -800,7 +743,7 @@ func (h *ParenthesizedExpressionHandler) hasBinaryOperatorAfterParentheses(token
 // isValidExpressionStart проверяет, может ли токен начинать выражение
 func (h *ParenthesizedExpressionHandler) isValidExpressionStart(token lexer.Token) bool {
 	switch token.Type {
-	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenString, lexer.TokenNumber, lexer.TokenLeftParen:
+	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenString, lexer.TokenNumber, lexer.TokenLeftParen:
 		return true
 	default:
 		return false
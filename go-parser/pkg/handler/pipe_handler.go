@@ -239,6 +239,13 @@ func (h *PipeHandler) parsePipeExpressionWithLeftAssociative(ctx *common.ParseCo
 		return nil, newErrorWithPos(tokenStream, "failed to parse expression before pipe: %v", err)
 	}
 
+	// Выражение слева должно закончиться ровно на |: иначе справа снова
+	// окажется этот же | и парсер зациклится
+	if !tokenStream.HasMore() || tokenStream.Current().Position != pipeToken.Position {
+		return nil, newErrorWithPos(tokenStream, "unexpected %s before pipe operator", tokenStream.Current().Type)
+	}
+	tokenStream.Consume()
+
 	// Теперь разбираем выражение справа от |
 	rightExpr, err := h.parseExpressionAfterPipe(ctx)
 	if err != nil {
@@ -382,11 +389,14 @@ func (h *PipeHandler) parseLanguageCallManuallyUntilPosition(ctx *common.ParseCo
 		}
 
 		// Разбираем аргумент
+		argName, kind := consumeArgumentPrefix(tokenStream)
 		arg, err := h.parseExpressionUntilPosition(ctx, stopPos)
 		if err != nil {
 			return nil, newErrorWithPos(tokenStream, "failed to parse argument: %v", err)
 		}
-		args = append(args, arg)
+		if args, err = appendCallArgument(args, argName, kind, arg); err != nil {
+			return nil, err
+		}
 
 		// Проверяем, есть ли еще аргументы
 		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenComma {
@@ -867,13 +877,7 @@ func (h *PipeHandler) parseSingleExpression(ctx *common.ParseContext) (ast.Expre
 		assignmentHandler := NewAssignmentHandler(100, 1)
 
 		// Создаем временный контекст
-		tempCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      ctx.Parser,
-			Depth:       ctx.Depth,
-			MaxDepth:    ctx.MaxDepth,
-			Guard:       ctx.Guard,
-		}
+		tempCtx := ctx.WithStream(tokenStream)
 
 		// Используем parseComplexExpression для разбора выражения
 		result, err := assignmentHandler.parseComplexExpression(tempCtx)
@@ -976,14 +980,8 @@ func (h *PipeHandler) parseLanguageCallOrIdentifier(ctx *common.ParseContext) (a
 
 	if languageCallHandler.CanHandle(tokenStream.Current()) {
 		// Создаем копию контекста с включенным режимом частичного парсинга
-		partialCtx := &common.ParseContext{
-			TokenStream:        ctx.TokenStream,
-			Parser:             ctx.Parser,
-			Depth:              ctx.Depth,
-			MaxDepth:           ctx.MaxDepth,
-			Guard:              ctx.Guard,
-			PartialParsingMode: true, // Включаем частичный парсинг
-		}
+		partialCtx := ctx.WithStream(ctx.TokenStream)
+		partialCtx.PartialParsingMode = true
 		result, err := languageCallHandler.Handle(partialCtx)
 		if err == nil {
 			if langCall, ok := result.(*ast.LanguageCall); ok {
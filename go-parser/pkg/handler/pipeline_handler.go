@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+	"go-parser/pkg/stream"
+)
+
+// PipelineHandler - обработчик конвейеров:
+//
+//	pipeline {
+//	    stage "a" { ... }
+//	    stage "b" after "a" retries 2 timeout 30 { ... }
+//	}
+//
+// 'pipeline', 'stage' и параметры стадий - обычные идентификаторы, так что
+// переменная pipeline по-прежнему допустима. Тела стадий здесь не разбираются:
+// обработчик сохраняет их текст, а UnifiedParser разбирает его как скрипт
+type PipelineHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewPipelineHandler создает новый обработчик конвейеров
+func NewPipelineHandler(config config.ConstructHandlerConfig) *PipelineHandler {
+	return &PipelineHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *PipelineHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && token.Value == "pipeline"
+}
+
+// Handle обрабатывает конвейер. Если за 'pipeline' не идет '{', это не
+// конвейер, и обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с "pipeline: ", парсер не передает их другим обработчикам
+func (h *PipelineHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	pipelineToken := tokenStream.Current()
+	if pipelineToken.Type != lexer.TokenIdentifier || pipelineToken.Value != "pipeline" {
+		return nil, nil
+	}
+	if tokenStream.Peek().Type != lexer.TokenLBrace {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	var stages []*ast.PipelineStage
+	for {
+		skipPipelineSeparators(tokenStream)
+		if !tokenStream.HasMore() || tokenStream.Current().Type == lexer.TokenEOF {
+			return nil, newErrorWithTokenPos(pipelineToken, "pipeline: expected '}' to close the pipeline")
+		}
+		current := tokenStream.Current()
+		if current.Type == lexer.TokenRBrace {
+			tokenStream.Consume()
+			break
+		}
+		if current.Type != lexer.TokenIdentifier || current.Value != "stage" {
+			return nil, newErrorWithTokenPos(current, "pipeline: expected 'stage', got '%s'", current.Value)
+		}
+		stage, err := h.parseStage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, stage)
+	}
+
+	if len(stages) == 0 {
+		return nil, newErrorWithTokenPos(pipelineToken, "pipeline: a pipeline needs at least one stage")
+	}
+	if err := checkPipelineGraph(stages); err != nil {
+		return nil, err
+	}
+	return ast.NewPipelineStatement(stages, tokenToPosition(pipelineToken)), nil
+}
+
+// parseStage разбирает stage "name" [after "a", "b"] [retries N] [timeout N] { ... }
+func (h *PipelineHandler) parseStage(ctx *common.ParseContext) (*ast.PipelineStage, error) {
+	tokenStream := ctx.TokenStream
+	stageToken := tokenStream.Consume()
+
+	nameToken := tokenStream.Current()
+	if nameToken.Type != lexer.TokenString || nameToken.Value == "" {
+		return nil, newErrorWithTokenPos(nameToken, "pipeline: expected the stage name as a string after 'stage'")
+	}
+	tokenStream.Consume()
+	stage := &ast.PipelineStage{Name: nameToken.Value, Pos: tokenToPosition(stageToken)}
+
+	seen := make(map[string]bool)
+	for tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenIdentifier {
+		option := tokenStream.Current()
+		if seen[option.Value] {
+			return nil, newErrorWithTokenPos(option, "pipeline: '%s' is given twice for stage \"%s\"", option.Value, stage.Name)
+		}
+		seen[option.Value] = true
+		tokenStream.Consume()
+
+		switch option.Value {
+		case "after":
+			after, err := parseStageNames(tokenStream, option)
+			if err != nil {
+				return nil, err
+			}
+			stage.After = after
+		case "retries":
+			numberToken := tokenStream.Current()
+			retries, err := strconv.Atoi(numberToken.Value)
+			if numberToken.Type != lexer.TokenNumber || err != nil || retries < 0 {
+				return nil, newErrorWithTokenPos(numberToken, "pipeline: 'retries' expects a whole number of retries, got '%s'", numberToken.Value)
+			}
+			tokenStream.Consume()
+			stage.Retries = retries
+		case "timeout":
+			numberToken := tokenStream.Current()
+			timeout, err := strconv.ParseFloat(numberToken.Value, 64)
+			if numberToken.Type != lexer.TokenNumber || err != nil || timeout <= 0 {
+				return nil, newErrorWithTokenPos(numberToken, "pipeline: 'timeout' expects a positive number of seconds, got '%s'", numberToken.Value)
+			}
+			tokenStream.Consume()
+			stage.Timeout = timeout
+		default:
+			return nil, newErrorWithTokenPos(option, "pipeline: unknown stage option '%s', expected after, retries or timeout", option.Value)
+		}
+	}
+
+	source, bodyPos, err := captureBody(ctx, "pipeline", fmt.Sprintf("the body of stage \"%s\"", stage.Name))
+	if err != nil {
+		return nil, err
+	}
+	stage.Source, stage.BodyPos = source, bodyPos
+	return stage, nil
+}
+
+// parseStageNames разбирает имена после 'after': "a", "b" или ["a", "b"]
+func parseStageNames(tokenStream stream.TokenStream, afterToken lexer.Token) ([]string, error) {
+	bracketed := tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenLBracket
+	if bracketed {
+		tokenStream.Consume()
+	}
+	var names []string
+	for {
+		nameToken := tokenStream.Current()
+		if nameToken.Type != lexer.TokenString {
+			return nil, newErrorWithTokenPos(nameToken, "pipeline: expected a stage name as a string after 'after'")
+		}
+		tokenStream.Consume()
+		names = append(names, nameToken.Value)
+		if tokenStream.Current().Type != lexer.TokenComma {
+			break
+		}
+		tokenStream.Consume()
+	}
+	if bracketed {
+		if tokenStream.Current().Type != lexer.TokenRBracket {
+			return nil, newErrorWithPos(tokenStream, "pipeline: expected ']' after the stage names")
+		}
+		tokenStream.Consume()
+	}
+	return names, nil
+}
+
+// skipPipelineSeparators пропускает переводы строк и ';' между стадиями
+func skipPipelineSeparators(tokenStream stream.TokenStream) {
+	for tokenStream.HasMore() {
+		switch tokenStream.Current().Type {
+		case lexer.TokenNewline, lexer.TokenSemicolon:
+			tokenStream.Consume()
+		default:
+			return
+		}
+	}
+}
+
+// checkPipelineGraph проверяет имена стадий и зависимости: имена уникальны,
+// after ссылается на объявленные стадии, а граф не содержит циклов
+func checkPipelineGraph(stages []*ast.PipelineStage) error {
+	byName := make(map[string]*ast.PipelineStage, len(stages))
+	for _, stage := range stages {
+		if _, exists := byName[stage.Name]; exists {
+			return stageError(stage, "pipeline: stage \"%s\" is declared twice", stage.Name)
+		}
+		byName[stage.Name] = stage
+	}
+	for _, stage := range stages {
+		for _, dependency := range stage.After {
+			if _, exists := byName[dependency]; !exists {
+				return stageError(stage, "pipeline: stage \"%s\" runs after unknown stage \"%s\"", stage.Name, dependency)
+			}
+		}
+	}
+
+	// Поиск в глубину: стадия в состоянии visiting, встреченная снова, замыкает цикл
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(stages))
+	var path []string
+	var visit func(stage *ast.PipelineStage) error
+	visit = func(stage *ast.PipelineStage) error {
+		switch state[stage.Name] {
+		case visiting:
+			cycle := stage.Name
+			for i := len(path) - 1; i >= 0 && path[i] != stage.Name; i-- {
+				cycle = path[i] + " -> " + cycle
+			}
+			return stageError(stage, "pipeline: stages depend on each other in a cycle: %s -> %s", stage.Name, cycle)
+		case visited:
+			return nil
+		}
+		state[stage.Name] = visiting
+		path = append(path, stage.Name)
+		for _, dependency := range stage.After {
+			if err := visit(byName[dependency]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[stage.Name] = visited
+		return nil
+	}
+	for _, stage := range stages {
+		if err := visit(stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stageError создает ошибку с позицией стадии
+func stageError(stage *ast.PipelineStage, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at line %d, column %d", fmt.Sprintf(format, args...), stage.Pos.Line, stage.Pos.Column)
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *PipelineHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *PipelineHandler) Name() string {
+	return h.config.Name
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// PragmaHandler - обработчик @quiet и @verbose перед оператором или блоком:
+//
+//	@quiet py.noisy_setup()
+//	@verbose {
+//	    py.step_one()
+//	}
+//
+// Без оператора на той же строке или блока это размер переменной quiet или
+// verbose. Оператор, как и тело блока, UnifiedParser разбирает как скрипт
+type PragmaHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewPragmaHandler создает новый обработчик @quiet и @verbose
+func NewPragmaHandler(config config.ConstructHandlerConfig) *PragmaHandler {
+	return &PragmaHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *PragmaHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenAt
+}
+
+// Handle обрабатывает @quiet и @verbose. Если за именем не идет '{' или
+// оператор, обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с "@quiet: " или "@verbose: ", парсер не передает их другим обработчикам
+func (h *PragmaHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	atToken := tokenStream.Current()
+	nameToken := tokenStream.Peek()
+	if !h.CanHandle(atToken) || nameToken.Type != lexer.TokenIdentifier || nameToken.Position != atToken.Position+1 ||
+		(nameToken.Value != "quiet" && nameToken.Value != "verbose") {
+		return nil, nil
+	}
+	next := tokenStream.PeekN(2)
+	if next.Type != lexer.TokenLBrace && (next.Line != nameToken.Line || !startsPragmaStatement(next)) {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	prefix := "@" + nameToken.Value
+	statement := ast.NewPragmaStatement(nameToken.Value, tokenToPosition(atToken))
+	var err error
+	if next.Type == lexer.TokenLBrace {
+		statement.Source, statement.BodyPos, err = captureBody(ctx, prefix, "the block of "+prefix)
+	} else {
+		statement.Source, statement.BodyPos, err = captureLine(ctx, prefix)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// startsPragmaStatement проверяет, что токен может начинать оператор после
+// @quiet или @verbose: имя, язык, ключевое слово или еще одна аннотация
+func startsPragmaStatement(token lexer.Token) bool {
+	switch token.Type {
+	case lexer.TokenIdentifier, lexer.TokenIf, lexer.TokenFor, lexer.TokenWhile, lexer.TokenMatch, lexer.TokenAt,
+		lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS,
+		lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
+		return true
+	}
+	return false
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *PragmaHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *PragmaHandler) Name() string {
+	return h.config.Name
+}
@@ -300,15 +300,7 @@ func (h *QualifiedVariableHandler) parseAssignmentValue(tokenStream stream.Token
 		binaryHandler := NewBinaryExpressionHandler(config.ConstructHandlerConfig{})
 
 		// Создаем временный контекст для парсинга
-		tempCtx := &common.ParseContext{
-			TokenStream: tokenStream,
-			Parser:      nil,
-			Depth:       0,
-			MaxDepth:    100,
-			Guard:       &simpleRecursionGuard{maxDepth: 100},
-			LoopDepth:   0,
-			InputStream: "",
-		}
+		tempCtx := common.NewParseContext(tokenStream)
 
 		// Парсим полное выражение с поддержкой бинарных операторов и Elvis оператора
 		result, err := binaryHandler.ParseFullExpression(tempCtx, nil)
@@ -393,12 +385,15 @@ func (h *QualifiedVariableHandler) parseLanguageCallValue(tokenStream stream.Tok
 			}
 
 			// Читаем аргумент с поддержкой различных типов
+			argName, kind := consumeArgumentPrefix(tokenStream)
 			arg, err := h.parseArgument(tokenStream)
 			if err != nil {
 				return nil, err
 			}
 
-			arguments = append(arguments, arg)
+			if arguments, err = appendCallArgument(arguments, argName, kind, arg); err != nil {
+				return nil, err
+			}
 
 			// Проверяем разделитель или конец
 			if !tokenStream.HasMore() {
@@ -22,7 +22,7 @@ func NewReservedKeywordHandler(config config.ConstructHandlerConfig) *ReservedKe
 // CanHandle проверяет, может ли обработчик обработать токен
 func (h *ReservedKeywordHandler) CanHandle(token lexer.Token) bool {
 	// Проверяем, является ли токен зарезервированным ключевым словом
-	return token.Type == lexer.TokenLua || token.Type == lexer.TokenPython || token.Type == lexer.TokenPy || token.Type == lexer.TokenGo || token.Type == lexer.TokenNode || token.Type == lexer.TokenJS
+	return token.Type == lexer.TokenLua || token.Type == lexer.TokenPython || token.Type == lexer.TokenPy || token.Type == lexer.TokenGo || token.Type == lexer.TokenNode || token.Type == lexer.TokenJS || token.Type == lexer.TokenPerl || token.Type == lexer.TokenPl || token.Type == lexer.TokenErlang || token.Type == lexer.TokenErl || token.Type == lexer.TokenElixir || token.Type == lexer.TokenEx
 }
 
 // Handle обрабатывает попытку использования зарезервированного слова
@@ -70,7 +70,7 @@ func (h *ReservedKeywordHandler) Handle(ctx *common.ParseContext) (interface{},
 	// Проверяем, текущий токен - это import, а следующий - зарезервированное слово?
 	// Это нужно для обработки import lua "file.lua"
 	if reservedToken.Type == lexer.TokenImport {
-		if nextToken.Type == lexer.TokenLua || nextToken.Type == lexer.TokenPython || nextToken.Type == lexer.TokenPy || nextToken.Type == lexer.TokenGo || nextToken.Type == lexer.TokenNode || nextToken.Type == lexer.TokenJS {
+		if nextToken.Type == lexer.TokenLua || nextToken.Type == lexer.TokenPython || nextToken.Type == lexer.TokenPy || nextToken.Type == lexer.TokenGo || nextToken.Type == lexer.TokenNode || nextToken.Type == lexer.TokenJS || nextToken.Type == lexer.TokenPerl || nextToken.Type == lexer.TokenPl || nextToken.Type == lexer.TokenErlang || nextToken.Type == lexer.TokenErl || nextToken.Type == lexer.TokenElixir || nextToken.Type == lexer.TokenEx {
 			// Это легальное использование в импорте
 			return nil, fmt.Errorf("not a reserved keyword assignment")
 		}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// retryOptions - допустимые именованные параметры retry
+var retryOptions = map[string]bool{"backoff": true, "base": true, "max": true, "jitter": true, "when": true}
+
+// RetryHandler - обработчик повтора блока после ошибки:
+//
+//	retry(3, backoff="exp", base=1s) { py.flaky_call() }
+//
+// 'retry' - обычный идентификатор: без '(' ... ')' '{' это не повтор.
+// Тело, как у стадий конвейера, UnifiedParser разбирает как скрипт
+type RetryHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewRetryHandler создает новый обработчик повтора
+func NewRetryHandler(config config.ConstructHandlerConfig) *RetryHandler {
+	return &RetryHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *RetryHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && token.Value == "retry"
+}
+
+// Handle обрабатывает retry(n, name=value, ...) { ... }. Если за аргументами
+// не идет '{', это вызов функции retry, и обработчик возвращает nil.
+// Ошибки начинаются с "retry: ", парсер не передает их другим обработчикам
+func (h *RetryHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	retryToken := tokenStream.Current()
+	if retryToken.Type != lexer.TokenIdentifier || retryToken.Value != "retry" {
+		return nil, nil
+	}
+	if tokenStream.Peek().Type != lexer.TokenLeftParen || !callHasBody(tokenStream) {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	var attempts ast.Expression
+	var options []*ast.NamedArgument
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
+		if len(options) > 0 || attempts != nil {
+			if tokenStream.Current().Type != lexer.TokenComma {
+				return nil, newErrorWithPos(tokenStream, "retry: expected ',' or ')' after an argument")
+			}
+			tokenStream.Consume()
+		}
+
+		current := tokenStream.Current()
+		if current.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenAssign {
+			if !retryOptions[current.Value] {
+				return nil, newErrorWithTokenPos(current, "retry: unknown option '%s', expected backoff, base, max, jitter or when", current.Value)
+			}
+			for _, option := range options {
+				if option.Name == current.Value {
+					return nil, newErrorWithTokenPos(current, "retry: '%s' is given twice", current.Value)
+				}
+			}
+			tokenStream.Consume()
+			tokenStream.Consume()
+			value, err := parseArgumentValue(ctx, "retry")
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, ast.NewNamedArgument(current.Value, value, tokenToPosition(current)))
+			continue
+		}
+
+		if attempts != nil || len(options) > 0 {
+			return nil, newErrorWithTokenPos(current, "retry: the number of attempts must be the first argument, options are written as name=value")
+		}
+		value, err := parseArgumentValue(ctx, "retry")
+		if err != nil {
+			return nil, err
+		}
+		attempts = value
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithTokenPos(retryToken, "retry: expected ')' after the arguments")
+	}
+	tokenStream.Consume()
+	if attempts == nil {
+		return nil, newErrorWithTokenPos(retryToken, "retry: expected the number of attempts, e.g. retry(3) { ... }")
+	}
+
+	statement := ast.NewRetryStatement(attempts, options, tokenToPosition(retryToken))
+	source, bodyPos, err := captureBody(ctx, "retry", "the body of retry")
+	if err != nil {
+		return nil, err
+	}
+	statement.Source, statement.BodyPos = source, bodyPos
+	return statement, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *RetryHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *RetryHandler) Name() string {
+	return h.config.Name
+}
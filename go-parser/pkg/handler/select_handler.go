@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+	"go-parser/pkg/lexer"
+)
+
+// SelectHandler - обработчик ожидания на нескольких каналах:
+//
+//	select {
+//	    msg = recv(events) { print("got", msg) }
+//	    send(results, total) { print("sent") }
+//	    timeout 1s { print("nothing within a second") }
+//	    default { print("nothing ready") }
+//	}
+//
+// 'select' и виды веток - обычные идентификаторы. Тела веток, как у стадий
+// конвейера, UnifiedParser разбирает как скрипт
+type SelectHandler struct {
+	config config.ConstructHandlerConfig
+}
+
+// NewSelectHandler создает новый обработчик select
+func NewSelectHandler(config config.ConstructHandlerConfig) *SelectHandler {
+	return &SelectHandler{
+		config: config,
+	}
+}
+
+// CanHandle проверяет, может ли обработчик обработать токен
+func (h *SelectHandler) CanHandle(token lexer.Token) bool {
+	return token.Type == lexer.TokenIdentifier && token.Value == "select"
+}
+
+// Handle обрабатывает select { ... }. Если за 'select' не идет '{', это не
+// select, и обработчик возвращает nil, чтобы попробовали следующие.
+// Ошибки начинаются с "select: ", парсер не передает их другим обработчикам
+func (h *SelectHandler) Handle(ctx *common.ParseContext) (interface{}, error) {
+	tokenStream := ctx.TokenStream
+
+	selectToken := tokenStream.Current()
+	if !h.CanHandle(selectToken) || tokenStream.Peek().Type != lexer.TokenLBrace {
+		return nil, nil
+	}
+	tokenStream.Consume()
+	tokenStream.Consume()
+
+	var cases []*ast.SelectCase
+	seen := make(map[string]bool)
+	for {
+		skipPipelineSeparators(tokenStream)
+		if !tokenStream.HasMore() || tokenStream.Current().Type == lexer.TokenEOF {
+			return nil, newErrorWithTokenPos(selectToken, "select: expected '}' to close the select")
+		}
+		if tokenStream.Current().Type == lexer.TokenRBrace {
+			tokenStream.Consume()
+			break
+		}
+		caseToken := tokenStream.Current()
+		selectCase, err := h.parseCase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if (selectCase.Kind == "default" || selectCase.Kind == "timeout") && seen[selectCase.Kind] {
+			return nil, newErrorWithTokenPos(caseToken, "select: only one '%s' case is allowed", selectCase.Kind)
+		}
+		seen[selectCase.Kind] = true
+		cases = append(cases, selectCase)
+	}
+
+	if len(cases) == 0 {
+		return nil, newErrorWithTokenPos(selectToken, "select: a select needs at least one case")
+	}
+	return ast.NewSelectStatement(cases, tokenToPosition(selectToken)), nil
+}
+
+// parseCase разбирает ветку: recv(ch), name = recv(ch), send(ch, value),
+// timeout duration или default, за которой идет тело в фигурных скобках
+func (h *SelectHandler) parseCase(ctx *common.ParseContext) (*ast.SelectCase, error) {
+	tokenStream := ctx.TokenStream
+	first := tokenStream.Current()
+	selectCase := &ast.SelectCase{Pos: tokenToPosition(first)}
+	if first.Type != lexer.TokenIdentifier {
+		return nil, newErrorWithTokenPos(first, "select: expected recv(...), send(...), timeout or default, got '%s'", first.Value)
+	}
+
+	if tokenStream.Peek().Type == lexer.TokenAssign {
+		selectCase.Variable = first.Value
+		tokenStream.Consume()
+		tokenStream.Consume()
+		if current := tokenStream.Current(); current.Type != lexer.TokenIdentifier || current.Value != "recv" {
+			return nil, newErrorWithTokenPos(current, "select: only recv(...) can be assigned to '%s'", first.Value)
+		}
+	}
+
+	kindToken := tokenStream.Consume()
+	selectCase.Kind = kindToken.Value
+	switch kindToken.Value {
+	case "recv", "send":
+		arguments, err := parseCallArguments(ctx, "select", kindToken)
+		if err != nil {
+			return nil, err
+		}
+		wanted := map[string]int{"recv": 1, "send": 2}[kindToken.Value]
+		if len(arguments) != wanted {
+			usage := map[string]string{"recv": "recv(channel)", "send": "send(channel, value)"}[kindToken.Value]
+			return nil, newErrorWithTokenPos(kindToken, "select: expected %s", usage)
+		}
+		selectCase.Channel = arguments[0]
+		if kindToken.Value == "send" {
+			selectCase.Value = arguments[1]
+		}
+	case "timeout":
+		value, err := parseArgumentValue(ctx, "select")
+		if err != nil {
+			return nil, err
+		}
+		selectCase.Value = value
+	case "default":
+	default:
+		return nil, newErrorWithTokenPos(kindToken, "select: expected recv(...), send(...), timeout or default, got '%s'", kindToken.Value)
+	}
+
+	source, bodyPos, err := captureBody(ctx, "select", "the body of the "+selectCase.Kind+" case")
+	if err != nil {
+		return nil, err
+	}
+	selectCase.Source, selectCase.BodyPos = source, bodyPos
+	return selectCase, nil
+}
+
+// Config возвращает конфигурацию обработчика
+func (h *SelectHandler) Config() common.HandlerConfig {
+	return common.HandlerConfig{
+		IsEnabled: h.config.IsEnabled,
+		Priority:  h.config.Priority,
+		Name:      h.config.Name,
+	}
+}
+
+// Name возвращает имя обработчика
+func (h *SelectHandler) Name() string {
+	return h.config.Name
+}
@@ -153,9 +153,9 @@ func (h *UnaryExpressionHandler) parseSimpleSizeOperand(ctx *common.ParseContext
 			return ast.NewVariableRead(ast.NewIdentifier(token, token.Value)), nil
 		}
 
-	case lexer.TokenLua, lexer.TokenPython, lexer.TokenJS, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
-		// Language token - qualified variable
-		return h.parseQualifiedVariable(ctx)
+	case lexer.TokenLua, lexer.TokenPython, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx, lexer.TokenGo, lexer.TokenNode, lexer.TokenPy:
+		// Language token - qualified variable, в том числе глубокий путь
+		return NewBinaryExpressionHandler(config.ConstructHandlerConfig{}).parseBasicOperand(ctx)
 
 	default:
 		return nil, newErrorWithTokenPos(token, "unsupported operand for @ operator: %s", token.Type)
@@ -173,9 +173,10 @@ func (h *UnaryExpressionHandler) parseOperand(ctx *common.ParseContext) (ast.Exp
 	token := tokenStream.Current()
 
 	// Проверяем language tokens (python, lua, js, etc.)
+	// Language tokens (python, lua, js, etc.): вызов, переменная или
+	// глубокий путь py.config.server.port разбираются как в бинарных выражениях
 	if token.IsLanguageToken() {
-		// Это language call
-		return h.parseLanguageCall(ctx)
+		return NewBinaryExpressionHandler(config.ConstructHandlerConfig{}).parseBasicOperand(ctx)
 	}
 
 	switch token.Type {
@@ -307,7 +308,7 @@ func (h *UnaryExpressionHandler) parseQualifiedVariable(ctx *common.ParseContext
 		idToken := tokenStream.Consume()
 
 		// Если следующий токен - точка, то это часть пути
-		if tokenStream.HasMore() && tokenStream.Peek().Type == lexer.TokenDot {
+		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenDot {
 			path = append(path, idToken.Value)
 		} else {
 			// Это последнее имя
@@ -3,7 +3,9 @@ package handler
 import (
 	"fmt"
 	"go-parser/pkg/ast"
+	"go-parser/pkg/common"
 	"go-parser/pkg/lexer"
+	"go-parser/pkg/stream"
 	"math/big"
 	"strconv"
 	"strings"
@@ -36,7 +38,7 @@ func isBinaryOperator(tokenType lexer.TokenType) bool {
 		lexer.TokenEqual, lexer.TokenNotEqual, lexer.TokenLess, lexer.TokenLessEqual,
 		lexer.TokenGreater, lexer.TokenGreaterEqual, lexer.TokenAnd, lexer.TokenOr, lexer.TokenConcat,
 		lexer.TokenBitwiseOr, lexer.TokenAmpersand, lexer.TokenCaret, lexer.TokenTilde, lexer.TokenPipe,
-		lexer.TokenDoubleLeftAngle, lexer.TokenDoubleRightAngle:
+		lexer.TokenDoubleLeftAngle, lexer.TokenDoubleRightAngle, lexer.TokenIn, lexer.TokenNotIn, lexer.TokenRange:
 		return true
 	default:
 		return false
@@ -62,11 +64,16 @@ func getOperatorPrecedence(tokenType lexer.TokenType) int {
 		return 3
 	case lexer.TokenLess, lexer.TokenLessEqual, lexer.TokenGreater, lexer.TokenGreaterEqual: // <, <=, >, >=
 		return 4
+	case lexer.TokenIn, lexer.TokenNotIn: // in, not in
+		return 4
 	// Битовые операторы
 	case lexer.TokenBitwiseOr: // |
 		return 4
 	case lexer.TokenDoubleLeftAngle, lexer.TokenDoubleRightAngle: // <<, >>
 		return 5
+	// Диапазоны (выше сравнений и in, ниже арифметики)
+	case lexer.TokenRange: // .., ..<
+		return 5
 	case lexer.TokenAmpersand: // &
 		return 6
 	case lexer.TokenCaret: // ^
@@ -163,37 +170,253 @@ func parseFloat(s string) float64 {
 
 // createNumberLiteral creates a NumberLiteral with the appropriate type based on the value
 func createNumberLiteral(token lexer.Token, value interface{}) *ast.NumberLiteral {
+	literal := ast.AcquireNumberLiteral()
+	literal.Pos = ast.Position{
+		Line:   token.Line,
+		Column: token.Column,
+		Offset: token.Position,
+	}
 	switch v := value.(type) {
 	case *big.Int:
-		return &ast.NumberLiteral{
-			IntValue: v,
-			IsInt:    true,
-			Pos: ast.Position{
-				Line:   token.Line,
-				Column: token.Column,
-				Offset: token.Position,
-			},
-		}
+		literal.IntValue = v
+		literal.IsInt = true
 	case float64:
-		return &ast.NumberLiteral{
-			FloatValue: v,
-			IsInt:      false,
-			Pos: ast.Position{
-				Line:   token.Line,
-				Column: token.Column,
-				Offset: token.Position,
-			},
+		literal.FloatValue = v
+	}
+	// Fallback - should not happen, the literal stays float 0
+	return literal
+}
+
+// captureBody пропускает тело в фигурных скобках и возвращает его текст и
+// позицию начала текста. Такие тела (стадии конвейера, retry) UnifiedParser
+// разбирает потом как отдельный скрипт. Ошибки начинаются с "prefix: ",
+// what называет тело в сообщениях
+func captureBody(ctx *common.ParseContext, prefix, what string) (string, ast.Position, error) {
+	tokenStream := ctx.TokenStream
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenLBrace {
+		return "", ast.Position{}, newErrorWithPos(tokenStream, "%s: expected '{' to start %s", prefix, what)
+	}
+	lBraceToken := tokenStream.Consume()
+
+	// Тело заканчивается на парной закрывающей скобке
+	braceLevel := 1
+	var rBraceToken lexer.Token
+	for braceLevel > 0 {
+		if !tokenStream.HasMore() || tokenStream.Current().Type == lexer.TokenEOF {
+			return "", ast.Position{}, newErrorWithTokenPos(lBraceToken, "%s: %s is not closed with '}'", prefix, what)
 		}
-	default:
-		// Fallback - should not happen
-		return &ast.NumberLiteral{
-			FloatValue: 0,
-			IsInt:      false,
-			Pos: ast.Position{
-				Line:   token.Line,
-				Column: token.Column,
-				Offset: token.Position,
-			},
+		current := tokenStream.Consume()
+		switch current.Type {
+		case lexer.TokenLBrace:
+			braceLevel++
+		case lexer.TokenRBrace:
+			braceLevel--
+			rBraceToken = current
+		}
+	}
+
+	start := lBraceToken.Position + len(lBraceToken.Value)
+	end := rBraceToken.Position
+	if start < 0 || end > len(ctx.InputStream) || start > end {
+		return "", ast.Position{}, newErrorWithTokenPos(lBraceToken, "%s: invalid positions of %s", prefix, what)
+	}
+	bodyPos := ast.Position{Line: lBraceToken.Line, Column: lBraceToken.Column + len(lBraceToken.Value), Offset: start}
+	return ctx.InputStream[start:end], bodyPos, nil
+}
+
+// captureLine, как captureBody, возвращает текст оператора от текущего токена
+// до конца строки. Скобки могут переносить оператор на следующие строки
+func captureLine(ctx *common.ParseContext, prefix string) (string, ast.Position, error) {
+	tokenStream := ctx.TokenStream
+	firstToken := tokenStream.Current()
+	end := len(ctx.InputStream)
+	depth := 0
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenEOF {
+		current := tokenStream.Current()
+		if current.Type == lexer.TokenNewline && depth == 0 {
+			end = current.Position
+			break
+		}
+		switch current.Type {
+		case lexer.TokenLeftParen, lexer.TokenLBracket, lexer.TokenLBrace:
+			depth++
+		case lexer.TokenRightParen, lexer.TokenRBracket, lexer.TokenRBrace:
+			depth--
+		}
+		tokenStream.Consume()
+	}
+
+	start := firstToken.Position
+	if start < 0 || end > len(ctx.InputStream) || start > end {
+		return "", ast.Position{}, newErrorWithTokenPos(firstToken, "%s: invalid positions of the statement", prefix)
+	}
+	return ctx.InputStream[start:end], tokenToPosition(firstToken), nil
+}
+
+// callHasBody проверяет, что за вызовом name(...), на имени которого стоит
+// поток, сразу идет тело в фигурных скобках
+func callHasBody(tokenStream stream.TokenStream) bool {
+	depth := 0
+	for i := 1; ; i++ {
+		token := tokenStream.PeekN(i)
+		switch token.Type {
+		case lexer.TokenEOF:
+			return false
+		case lexer.TokenLeftParen:
+			depth++
+		case lexer.TokenRightParen:
+			depth--
+			if depth == 0 {
+				return tokenStream.PeekN(i+1).Type == lexer.TokenLBrace
+			}
+		}
+	}
+}
+
+// durationUnits - множители суффиксов длительности в секундах: 500ms, 1s, 2m
+var durationUnits = map[string]float64{"ms": 0.001, "s": 1, "m": 60, "h": 3600}
+
+// parseArgumentValue разбирает значение аргумента конструкции. Число с
+// суффиксом единицы (500ms, 1s, 2m, 1h) - длительность, она становится
+// числом секунд. Ошибки начинаются с "prefix: "
+func parseArgumentValue(ctx *common.ParseContext, prefix string) (ast.Expression, error) {
+	tokenStream := ctx.TokenStream
+	number := tokenStream.Current()
+	unit := tokenStream.Peek()
+	if number.Type == lexer.TokenNumber && unit.Type == lexer.TokenIdentifier && unit.Position == number.Position+len(number.Value) {
+		factor, ok := durationUnits[unit.Value]
+		if !ok {
+			return nil, newErrorWithTokenPos(unit, "%s: unknown unit '%s' in '%s%s', expected ms, s, m or h", prefix, unit.Value, number.Value, unit.Value)
 		}
+		tokenStream.Consume()
+		tokenStream.Consume()
+		return &ast.NumberLiteral{FloatValue: parseFloat(number.Value) * factor, Pos: tokenToPosition(number)}, nil
+	}
+
+	exprParser := NewUnifiedExpressionParser(false)
+	value, err := exprParser.ParseExpression(ctx)
+	if err != nil {
+		return nil, newErrorWithTokenPos(number, "%s: invalid argument: %v", prefix, err)
+	}
+	return value, nil
+}
+
+// parseCallArguments разбирает аргументы в скобках после имени nameToken,
+// текущий токен - '('. Ошибки начинаются с "prefix: "
+func parseCallArguments(ctx *common.ParseContext, prefix string, nameToken lexer.Token) ([]ast.Expression, error) {
+	tokenStream := ctx.TokenStream
+	if tokenStream.Current().Type != lexer.TokenLeftParen {
+		return nil, newErrorWithPos(tokenStream, "%s: expected '(' after '%s'", prefix, nameToken.Value)
+	}
+	tokenStream.Consume()
+
+	var arguments []ast.Expression
+	for tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenRightParen {
+		if len(arguments) > 0 {
+			if tokenStream.Current().Type != lexer.TokenComma {
+				return nil, newErrorWithPos(tokenStream, "%s: expected ',' or ')' after an argument", prefix)
+			}
+			tokenStream.Consume()
+		}
+		argumentToken := tokenStream.Current()
+		var argument ast.Expression
+		var err error
+		if argumentToken.Type == lexer.TokenLBrace {
+			// Объектный литерал выражения не разбирают, как и в вызовах builtin функций
+			var object interface{}
+			if object, err = NewObjectHandler(0, 0).Handle(ctx); err == nil {
+				argument, _ = object.(ast.Expression)
+			}
+		} else {
+			argument, err = NewUnifiedExpressionParser(false).ParseExpression(ctx)
+		}
+		if err != nil || argument == nil {
+			return nil, newErrorWithTokenPos(argumentToken, "%s: invalid argument: %v", prefix, err)
+		}
+		arguments = append(arguments, argument)
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type != lexer.TokenRightParen {
+		return nil, newErrorWithTokenPos(nameToken, "%s: expected ')' after the arguments of '%s'", prefix, nameToken.Value)
+	}
+	tokenStream.Consume()
+	return arguments, nil
+}
+
+// continueOperandExpression продолжает разбор, если за уже разобранным
+// операндом следует бинарный оператор: аргумент py.config.server.port + 1
+func continueOperandExpression(tokenStream stream.TokenStream, operand ast.Expression) (ast.Expression, error) {
+	if !tokenStream.HasMore() || !isBinaryOperator(tokenStream.Current().Type) {
+		return operand, nil
+	}
+	ctx := common.NewParseContext(tokenStream)
+	return NewUnifiedExpressionParser(false).ContinueParsingExpression(ctx, operand)
+}
+
+// isStatementSeparator проверяет, что токен разделяет statements: перевод
+// строки или ';' между statements на одной строке
+func isStatementSeparator(token lexer.Token) bool {
+	return token.Type == lexer.TokenNewline || token.Type == lexer.TokenSemicolon
+}
+
+// argumentKind - вид аргумента вызова по его началу
+type argumentKind int
+
+const (
+	argumentPositional    argumentKind = iota
+	argumentNamed                      // name=value
+	argumentSpread                     // *items или ...items
+	argumentKeywordSpread              // **options
+)
+
+// consumeArgumentPrefix разбирает начало аргумента вызова: name= у
+// именованного, *, ... или ** у раскрытия. Префикс потребляется, возвращаются
+// его первый токен и вид аргумента
+func consumeArgumentPrefix(tokenStream stream.TokenStream) (lexer.Token, argumentKind) {
+	if !tokenStream.HasMore() {
+		return lexer.Token{}, argumentPositional
+	}
+	switch current := tokenStream.Current(); current.Type {
+	case lexer.TokenIdentifier:
+		if tokenStream.Peek().Type == lexer.TokenAssign {
+			tokenStream.Consume()
+			tokenStream.Consume() // '='
+			return current, argumentNamed
+		}
+	case lexer.TokenMultiply, lexer.TokenRest:
+		tokenStream.Consume()
+		return current, argumentSpread
+	case lexer.TokenPower:
+		tokenStream.Consume()
+		return current, argumentKeywordSpread
+	}
+	return lexer.Token{}, argumentPositional
+}
+
+// appendCallArgument добавляет аргумент вызова; именованный аргумент
+// оборачивается в NamedArgument, раскрытие - в SpreadArgument. Позиционный
+// аргумент после именованного и повторенное имя - ошибки, как в Python
+func appendCallArgument(arguments []ast.Expression, prefix lexer.Token, kind argumentKind, value ast.Expression) ([]ast.Expression, error) {
+	switch kind {
+	case argumentNamed:
+		for _, argument := range arguments {
+			if previous, ok := argument.(*ast.NamedArgument); ok && previous.Name == prefix.Value {
+				return nil, newErrorWithTokenPos(prefix, "named argument '%s' repeated", prefix.Value)
+			}
+		}
+		return append(arguments, ast.NewNamedArgument(prefix.Value, value, tokenToPosition(prefix))), nil
+	case argumentKeywordSpread:
+		return append(arguments, ast.NewSpreadArgument(value, true, tokenToPosition(prefix))), nil
+	}
+	if len(arguments) > 0 {
+		last := arguments[len(arguments)-1]
+		spread, isSpread := last.(*ast.SpreadArgument)
+		if _, named := last.(*ast.NamedArgument); named || isSpread && spread.Keyword {
+			pos := value.Position()
+			return nil, fmt.Errorf("positional argument follows named argument at line %d, column %d", pos.Line, pos.Column)
+		}
+	}
+	if kind == argumentSpread {
+		return append(arguments, ast.NewSpreadArgument(value, false, tokenToPosition(prefix))), nil
 	}
+	return append(arguments, value), nil
 }
@@ -188,7 +188,7 @@ func (h *WhileLoopHandler) parseCondition(ctx *common.ParseContext, tokenStream
 		var leftExpr ast.Expression
 
 		switch firstToken.Type {
-		case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+		case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 			if tokenStream.Peek().Type == lexer.TokenLeftParen {
 				// Это вызов функции
 				languageCallHandler := NewLanguageCallHandler(config.ConstructHandlerConfig{})
@@ -259,7 +259,7 @@ func (h *WhileLoopHandler) parseCondition(ctx *common.ParseContext, tokenStream
 
 		return leftExpr, nil
 
-	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS:
+	case lexer.TokenIdentifier, lexer.TokenLua, lexer.TokenPython, lexer.TokenPy, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx:
 		// Это может быть простой идентификатор, вызов функции, доступ к полю или бинарное выражение
 		if tokenStream.Peek().Type == lexer.TokenLeftParen {
 			// Это вызов функции, делегируем LanguageCallHandler
@@ -436,7 +436,13 @@ func (h *WhileLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.Statem
 		// Это позволит обрабатывать все типы statements включая JavaScript присваивания и вызовы функций
 		if current.Type == lexer.TokenIdentifier || current.Type == lexer.TokenLua || current.Type == lexer.TokenPython ||
 			current.Type == lexer.TokenPy || current.Type == lexer.TokenGo || current.Type == lexer.TokenNode ||
-			current.Type == lexer.TokenJS || current.Type == lexer.TokenString || current.Type == lexer.TokenNumber {
+			current.Type == lexer.TokenJS ||
+			current.Type == lexer.TokenPerl ||
+			current.Type == lexer.TokenPl ||
+			current.Type == lexer.TokenErlang ||
+			current.Type == lexer.TokenErl ||
+			current.Type == lexer.TokenElixir ||
+			current.Type == lexer.TokenEx || current.Type == lexer.TokenString || current.Type == lexer.TokenNumber {
 
 			// Сохраняем текущую позицию
 			currentPos := tokenStream.Position()
@@ -493,7 +499,7 @@ func (h *WhileLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.Statem
 		}
 
 		// Проверяем, не является ли это вызовом builtin функции
-		if current.Type == lexer.TokenIdentifier && tokenStream.Peek().Type == lexer.TokenLeftParen {
+		if isBuiltinCallStart(tokenStream) {
 			if h.verbose {
 				fmt.Printf("DEBUG parseLoopBody: found identifier followed by '(', trying builtin function\n")
 			}
@@ -504,7 +510,7 @@ func (h *WhileLoopHandler) parseLoopBody(ctx *common.ParseContext) ([]ast.Statem
 				if h.verbose {
 					fmt.Printf("DEBUG parseLoopBody: builtin function parsing succeeded\n")
 				}
-				if builtinCall, ok := result.(*ast.BuiltinFunctionCall); ok {
+				if builtinCall, ok := result.(ast.Statement); ok {
 					body = append(body, builtinCall)
 					continue
 				}
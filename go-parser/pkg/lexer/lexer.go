@@ -1,5 +1,7 @@
 package lexer
 
+import "strings"
+
 type Lexer interface {
 	NextToken() Token
 	Peek() Token
@@ -16,6 +18,10 @@ type SimpleLexer struct {
 	column           int
 	shebangChecked   bool
 	inSizeExpression bool
+	// Comments on their own lines waiting for the next token (its Doc)
+	comments       []string
+	lineHasToken   bool
+	lineHasComment bool
 }
 
 func NewLexer(input string) *SimpleLexer {
@@ -58,7 +64,28 @@ func (l *SimpleLexer) peekNext() rune {
 	return rune(l.input[l.position+1])
 }
 
+// NextToken returns the next token. Comments on the lines right above a
+// token, with no blank line in between, become its Doc.
 func (l *SimpleLexer) NextToken() Token {
+	token := l.nextToken()
+	if token.Type == TokenNewline {
+		// A blank line detaches the comments above it
+		if !l.lineHasToken && !l.lineHasComment {
+			l.comments = nil
+		}
+		l.lineHasToken = false
+		l.lineHasComment = false
+		return token
+	}
+	if token.Type != TokenEOF && len(l.comments) > 0 {
+		token.Doc = strings.Join(l.comments, "\n")
+	}
+	l.comments = nil
+	l.lineHasToken = true
+	return token
+}
+
+func (l *SimpleLexer) nextToken() Token {
 	// Check for shebang only on the first call
 	if !l.shebangChecked {
 		l.skipShebang()
@@ -191,6 +218,19 @@ func (l *SimpleLexer) NextToken() Token {
 			l.readChar()
 			return token
 		}
+		// Оператор диапазона .. (например, 1..5 в паттернах match) и ..< без
+		// верхней границы
+		if l.peekChar() == '.' {
+			l.readChar() // потребляем первую '.'
+			token.Type = TokenRange
+			token.Value = ".."
+			if l.peekChar() == '<' {
+				l.readChar()
+				token.Value = "..<"
+			}
+			l.readChar()
+			return token
+		}
 		token.Type = TokenDot
 		token.Value = "."
 		l.readChar()
@@ -332,6 +372,9 @@ func (l *SimpleLexer) NextToken() Token {
 		if isDigit(l.current) {
 			return l.readNumber()
 		}
+		if l.current == 'r' && (l.peekChar() == '"' || l.peekChar() == '\'') {
+			return l.readRawString()
+		}
 		if isLetter(l.current) {
 			return l.readIdentifier()
 		}
@@ -361,6 +404,9 @@ func (l *SimpleLexer) Peek() Token {
 	currentLine := l.line
 	currentColumn := l.column
 	currentShebangChecked := l.shebangChecked
+	currentComments := l.comments
+	currentLineHasToken := l.lineHasToken
+	currentLineHasComment := l.lineHasComment
 
 	token := l.NextToken()
 
@@ -369,6 +415,9 @@ func (l *SimpleLexer) Peek() Token {
 	l.line = currentLine
 	l.column = currentColumn
 	l.shebangChecked = currentShebangChecked
+	l.comments = currentComments
+	l.lineHasToken = currentLineHasToken
+	l.lineHasComment = currentLineHasComment
 
 	return token
 }
@@ -401,25 +450,18 @@ func (l *SimpleLexer) skipWhitespaceAndComments() {
 
 		// Skip block comments
 		if l.current == '/' && l.peekChar() == '*' {
+			start := l.position - 1
 			l.skipBlockComment()
+			l.recordComment(l.input[start : l.position-1])
 			continue
 		}
 
-		// Skip single-line comments starting with #
-		if l.current == '#' {
-			l.skipSingleLineComment()
-			continue
-		}
-
-		// Skip single-line comments starting with //
-		if l.current == '/' && l.peekChar() == '/' {
-			l.skipSingleLineComment()
-			continue
-		}
-
-		// Skip single-line comments starting with -- (Lua style)
-		if l.current == '-' && l.peekChar() == '-' {
+		// Skip single-line comments starting with #, // or -- (Lua style).
+		// The newline after the comment is left for the NEWLINE token.
+		if l.current == '#' || (l.current == '/' && l.peekChar() == '/') || (l.current == '-' && l.peekChar() == '-') {
+			start := l.position - 1
 			l.skipSingleLineComment()
+			l.recordComment(l.input[start : l.position-1])
 			continue
 		}
 
@@ -455,10 +497,43 @@ func (l *SimpleLexer) skipSingleLineComment() {
 	for l.current != '\n' && l.current != '\r' && l.current != 0 {
 		l.readChar()
 	}
-	// Also consume the newline character if it exists
-	if l.current == '\n' || l.current == '\r' {
-		l.readChar()
+}
+
+// recordComment keeps the text of a comment that starts its line for the
+// Doc of the next token; comments after a token on the same line are dropped
+func (l *SimpleLexer) recordComment(comment string) {
+	if l.lineHasToken {
+		return
 	}
+	l.lineHasComment = true
+	l.comments = append(l.comments, commentText(comment)...)
+}
+
+// commentText strips the comment markers and returns the comment's lines
+func commentText(comment string) []string {
+	if strings.HasPrefix(comment, "/*") {
+		comment = strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+		var lines []string
+		for _, line := range strings.Split(comment, "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+			if line == "" && len(lines) == 0 {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return lines
+	}
+	for _, marker := range []string{"#", "//", "--"} {
+		if strings.HasPrefix(comment, marker) {
+			comment = strings.TrimLeft(comment, marker[:1])
+			break
+		}
+	}
+	return []string{strings.TrimSpace(comment)}
 }
 func (l *SimpleLexer) skipShebang() {
 	// Check if we're at the very beginning of the file
@@ -499,6 +574,25 @@ func isHexDigit(ch rune) bool {
 	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
 }
 
+// notInLength возвращает число символов от текущего до конца " in" после
+// not, или 0, если за not не следует отдельное слово in
+func (l *SimpleLexer) notInLength() int {
+	i := l.position - 1
+	for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t') {
+		i++
+	}
+	if i == l.position-1 || !strings.HasPrefix(l.input[i:], "in") {
+		return 0
+	}
+	i += len("in")
+	if i < len(l.input) {
+		if ch := rune(l.input[i]); isLetter(ch) || isDigit(ch) || ch == '_' {
+			return 0
+		}
+	}
+	return i - (l.position - 1)
+}
+
 func (l *SimpleLexer) readIdentifier() Token {
 	startPos := l.position - 1
 	startLine := l.line
@@ -532,6 +626,22 @@ func (l *SimpleLexer) readIdentifier() Token {
 
 	identifier := l.input[startPos : l.position-1]
 
+	// not - обычный идентификатор, кроме оператора not in
+	if identifier == "not" {
+		if length := l.notInLength(); length > 0 {
+			for i := 0; i < length; i++ {
+				l.readChar()
+			}
+			return Token{
+				Type:     TokenNotIn,
+				Value:    "not in",
+				Position: startPos,
+				Line:     startLine,
+				Column:   startCol,
+			}
+		}
+	}
+
 	// Проверяем на ключевые слова
 	switch identifier {
 	case "for":
@@ -598,6 +708,14 @@ func (l *SimpleLexer) readIdentifier() Token {
 			Line:     startLine,
 			Column:   startCol,
 		}
+	case "try":
+		return Token{
+			Type:     TokenTry,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
 	case "lua":
 		return Token{
 			Type:     TokenLua,
@@ -647,6 +765,54 @@ func (l *SimpleLexer) readIdentifier() Token {
 			Line:     startLine,
 			Column:   startCol,
 		}
+	case "perl":
+		return Token{
+			Type:     TokenPerl,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
+	case "pl":
+		return Token{
+			Type:     TokenPl,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
+	case "erlang":
+		return Token{
+			Type:     TokenErlang,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
+	case "erl":
+		return Token{
+			Type:     TokenErl,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
+	case "elixir":
+		return Token{
+			Type:     TokenElixir,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
+	case "ex":
+		return Token{
+			Type:     TokenEx,
+			Value:    identifier,
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
+		}
 	case "import":
 		return Token{
 			Type:     TokenImport,
@@ -709,11 +875,8 @@ func (l *SimpleLexer) readIdentifierWithUnderscore() Token {
 	l.readChar()
 
 	// Последующие символы могут быть буквами, цифрами или подчеркиваниями
-	for isLetter(rune(l.input[l.position])) || isDigit(rune(l.input[l.position])) || rune(l.input[l.position]) == '_' {
+	for l.position < len(l.input) && (isLetter(rune(l.input[l.position])) || isDigit(rune(l.input[l.position])) || rune(l.input[l.position]) == '_') {
 		l.readChar()
-		if l.position >= len(l.input) {
-			break
-		}
 	}
 
 	identifier := l.input[startPos : l.position-1]
@@ -776,8 +939,8 @@ func (l *SimpleLexer) readNumber() Token {
 		l.readChar()
 	}
 
-	// Проверяем на десятичную точку
-	if l.current == '.' {
+	// Проверяем на десятичную точку (но не на оператор диапазона 1..5)
+	if l.current == '.' && l.peekChar() != '.' {
 		l.readChar()
 		for isDigit(l.current) {
 			l.readChar()
@@ -859,54 +1022,35 @@ func (l *SimpleLexer) readString() Token {
 	}
 }
 
+// readMultilineString reads a raw triple-quoted string. Escape sequences are
+// kept verbatim, so embedded Python/SQL/JS source can be written as is.
 func (l *SimpleLexer) readMultilineString(quote rune) Token {
 	// Consume the opening triple quotes
-	l.readChar() // consume first quote
-	l.readChar() // consume second quote
-	l.readChar() // consume third quote
+	l.readChar()
+	l.readChar()
+	l.readChar()
 
 	startPos := l.position - 1 // Start of content (after triple quotes)
 	startLine := l.line
 	startCol := l.column - 1
 
-	// Look for closing triple quotes
 	for l.current != 0 {
-		// First check for escape sequences - if we find a backslash,
-		// skip both the backslash and the next character
-		if l.current == '\\' {
-			l.readChar() // consume the backslash
-			if l.current != 0 {
-				l.readChar() // consume the escaped character
-			}
-			continue
-		}
-
-		// Only check for closing triple quotes if we're not in an escape sequence
 		if l.current == quote && l.peekChar() == quote && l.peekNext() == quote {
-			// Found closing triple quotes
-			endPos := l.position - 1
-			if endPos < startPos {
-				endPos = startPos
-			}
-			rawValue := l.input[startPos:endPos]
+			rawValue := l.input[startPos : l.position-1]
 
 			// Consume the closing triple quotes
-			l.readChar() // consume first quote
-			l.readChar() // consume second quote
-			l.readChar() // consume third quote
-
-			// Process escape sequences according to test expectations
-			value := l.processMultilineEscapeSequences(rawValue)
+			l.readChar()
+			l.readChar()
+			l.readChar()
 
 			return Token{
 				Type:     TokenString,
-				Value:    value,
+				Value:    trimHeredoc(rawValue),
 				Position: startPos,
 				Line:     startLine,
 				Column:   startCol,
 			}
 		}
-
 		l.readChar()
 	}
 
@@ -920,47 +1064,86 @@ func (l *SimpleLexer) readMultilineString(quote rune) Token {
 	}
 }
 
-func (l *SimpleLexer) processEscapeSequences(input string) string {
-	var result []rune
+// readRawString reads r"..." and r'...' literals without processing escapes
+func (l *SimpleLexer) readRawString() Token {
+	l.readChar() // Пропускаем префикс r
+	quote := l.current
+	if l.peekChar() == quote && l.peekNext() == quote {
+		return l.readMultilineString(quote)
+	}
+	l.readChar() // Пропускаем открывающую кавычку
 
-	// Convert input string to runes properly (handles UTF-8)
-	inputRunes := []rune(input)
+	startPos := l.position - 1
+	startLine := l.line
+	startCol := l.column - 1
 
-	for i := 0; i < len(inputRunes); i++ {
-		if inputRunes[i] == '\\' && i+1 < len(inputRunes) {
-			switch inputRunes[i+1] {
-			case 'n':
-				result = append(result, '\n')
-				i++
-			case 't':
-				result = append(result, '\t')
-				i++
-			case 'r':
-				result = append(result, '\r')
-				i++
-			case '\\':
-				result = append(result, '\\')
-				i++
-			case '"':
-				result = append(result, '"')
-				i++
-			case '\'':
-				result = append(result, '\'')
-				i++
-			default:
-				// If it's an unknown escape sequence, just keep the backslash and skip it
-				// This handles cases like \" where we want to keep the quote but remove the backslash
-				result = append(result, inputRunes[i+1])
-				i++
-			}
-		} else {
-			result = append(result, inputRunes[i])
+	for l.current != quote && l.current != 0 {
+		l.readChar()
+	}
+
+	if l.current == 0 {
+		return Token{
+			Type:     TokenUnknown,
+			Value:    l.input[startPos : l.position-1],
+			Position: startPos,
+			Line:     startLine,
+			Column:   startCol,
 		}
 	}
-	return string(result)
+
+	value := l.input[startPos : l.position-1]
+	l.readChar() // Пропускаем закрывающую кавычку
+
+	return Token{
+		Type:     TokenString,
+		Value:    value,
+		Position: startPos,
+		Line:     startLine,
+		Column:   startCol,
+	}
+}
+
+// trimHeredoc makes a triple-quoted block independent of the script's indentation:
+// the newline right after the opening quotes, the line holding only the closing
+// quotes and the indentation common to all non-blank lines are removed
+func trimHeredoc(raw string) string {
+	if !strings.Contains(raw, "\n") {
+		return raw
+	}
+	raw = strings.TrimPrefix(strings.TrimPrefix(raw, "\r"), "\n")
+
+	lines := strings.Split(raw, "\n")
+	if last := len(lines) - 1; last > 0 && strings.TrimSpace(lines[last]) == "" {
+		lines = lines[:last]
+	}
+
+	indent := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lead, false
+			continue
+		}
+		for !strings.HasPrefix(lead, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, indent) {
+			lines[i] = line[len(indent):]
+		} else if strings.TrimSpace(line) == "" {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
-func (l *SimpleLexer) processMultilineEscapeSequences(input string) string {
+func (l *SimpleLexer) processEscapeSequences(input string) string {
 	var result []rune
 
 	// Convert input string to runes properly (handles UTF-8)
@@ -978,18 +1161,18 @@ func (l *SimpleLexer) processMultilineEscapeSequences(input string) string {
 			case 'r':
 				result = append(result, '\r')
 				i++
+			case '\\':
+				result = append(result, '\\')
+				i++
 			case '"':
 				result = append(result, '"')
 				i++
 			case '\'':
 				result = append(result, '\'')
 				i++
-			case '\\':
-				result = append(result, '\\')
-				i++
 			default:
-				// For all other escape sequences, keep both the backslash and the next character
-				result = append(result, '\\')
+				// If it's an unknown escape sequence, just keep the backslash and skip it
+				// This handles cases like \" where we want to keep the quote but remove the backslash
 				result = append(result, inputRunes[i+1])
 				i++
 			}
@@ -91,6 +91,20 @@ const (
 	TokenUnderscore // _
 	// Новые токены для размера битстринга
 	TokenAt // @
+	// Новые токены для диапазонов в паттернах
+	TokenRange // .. и ..<
+	// Токены языка Perl
+	TokenPerl // perl
+	TokenPl   // pl
+	// Токены языков BEAM
+	TokenErlang // erlang
+	TokenErl    // erl
+	TokenElixir // elixir
+	TokenEx     // ex
+	// Токен выражения try
+	TokenTry // try
+	// Токен оператора принадлежности not in
+	TokenNotIn // not in
 )
 
 func (t TokenType) String() string {
@@ -233,6 +247,24 @@ func (t TokenType) String() string {
 		return "UNDERSCORE"
 	case TokenAt:
 		return "AT"
+	case TokenRange:
+		return "RANGE"
+	case TokenPerl:
+		return "PERL"
+	case TokenPl:
+		return "PL"
+	case TokenErlang:
+		return "ERLANG"
+	case TokenErl:
+		return "ERL"
+	case TokenElixir:
+		return "ELIXIR"
+	case TokenEx:
+		return "EX"
+	case TokenTry:
+		return "TRY"
+	case TokenNotIn:
+		return "NOT_IN"
 	default:
 		return "UNKNOWN"
 	}
@@ -244,6 +276,7 @@ type Token struct {
 	Position int
 	Line     int
 	Column   int
+	Doc      string // comments on the lines right above the token
 }
 
 func (t Token) String() string {
@@ -258,7 +291,13 @@ func (t Token) IsLanguageToken() bool {
 		t.Type == TokenLua ||
 		t.Type == TokenGo ||
 		t.Type == TokenNode ||
-		t.Type == TokenJS
+		t.Type == TokenJS ||
+		t.Type == TokenPerl ||
+		t.Type == TokenPl ||
+		t.Type == TokenErlang ||
+		t.Type == TokenErl ||
+		t.Type == TokenElixir ||
+		t.Type == TokenEx
 }
 
 // LanguageTokenToString преобразует токен языка в строковое представление
@@ -272,6 +311,12 @@ func (t Token) LanguageTokenToString() string {
 		return "go"
 	case TokenNode, TokenJS:
 		return "node"
+	case TokenPerl, TokenPl:
+		return "perl"
+	case TokenErlang, TokenErl:
+		return "erlang"
+	case TokenElixir, TokenEx:
+		return "elixir"
 	default:
 		return ""
 	}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"go-parser/pkg/ast"
+)
+
+// arenaScript содержит много идентификаторов и литералов, которые берутся из пулов
+const arenaScript = `name = "funterm"
+count = 42
+ratio = 0.5
+items = [1, 2, 3, "four", name]
+if count > 10 {
+    total = count + ratio * 2
+    label = "big " + name
+}
+`
+
+// TestReleaseTreeDoesNotAlias разбирает скрипт, освобождает дерево и разбирает
+// снова: узлы из пулов не должны оказаться в дереве, которое еще используется,
+// а повторный разбор должен дать то же дерево
+func TestReleaseTreeDoesNotAlias(t *testing.T) {
+	p := NewUnifiedParser()
+	parse := func() ast.Statement {
+		t.Helper()
+		statement, errs := p.Parse(arenaScript)
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		return statement
+	}
+
+	kept := parse()
+	want := kept.ToMap()
+	keptLeaves := leafNodes(kept)
+	if len(keptLeaves) == 0 {
+		t.Fatal("the tree has no pooled leaf nodes")
+	}
+
+	for i := 0; i < 5; i++ {
+		released := parse()
+		if got := released.ToMap(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("parse %d after a release gave another tree:\n got %v\nwant %v", i+1, got, want)
+		}
+		for node := range leafNodes(released) {
+			if keptLeaves[node] {
+				t.Fatalf("parse %d reused %T %p of a tree that was not released", i+1, node, node)
+			}
+		}
+		ast.ReleaseTree(released)
+	}
+
+	if got := kept.ToMap(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("the kept tree changed after other trees were released:\n got %v\nwant %v", got, want)
+	}
+}
+
+// leafNodes собирает узлы дерева, которые ReleaseTree возвращает в пулы
+func leafNodes(root ast.ProtoNode) map[ast.ProtoNode]bool {
+	leaves := make(map[ast.ProtoNode]bool)
+	ast.Inspect(root, func(node ast.ProtoNode) {
+		switch node.(type) {
+		case *ast.Identifier, *ast.NumberLiteral, *ast.StringLiteral:
+			leaves[node] = true
+		}
+	})
+	return leaves
+}
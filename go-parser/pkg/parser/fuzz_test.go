@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-parser/pkg/ast"
+)
+
+// FuzzParse разбирает произвольный вход как скрипт. Ошибки разбора ожидаемы,
+// падает тест только на панике. Начальный корпус - скрипты tests/*.su, они
+// разбираются при каждом go test
+func FuzzParse(f *testing.F) {
+	scripts, err := filepath.Glob(filepath.Join("..", "..", "..", "tests", "*.su"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, script := range scripts {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+
+	p := NewUnifiedParser()
+	f.Fuzz(func(t *testing.T, source string) {
+		statement, parseErrors := p.Parse(source)
+		if len(parseErrors) == 0 {
+			ast.ReleaseTree(statement)
+		}
+	})
+}
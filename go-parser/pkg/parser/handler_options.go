@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"go-parser/pkg/common"
+	"go-parser/pkg/config"
+)
+
+// HandlerOptions - настройка обработчиков парсера встраивающим приложением
+type HandlerOptions struct {
+	Disabled   []string       `json:"disabled" yaml:"disabled"`     // имена обработчиков, которые нужно отключить
+	Priorities map[string]int `json:"priorities" yaml:"priorities"` // новые приоритеты по имени обработчика
+}
+
+// ApplyHandlerOptions отключает обработчики и меняет их приоритеты.
+// Неизвестное имя обработчика - ошибка, чтобы опечатки в конфигурации не терялись.
+func (p *UnifiedParser) ApplyHandlerOptions(options HandlerOptions) error {
+	for _, name := range options.Disabled {
+		if err := p.registry.SetHandlerEnabled(name, false); err != nil {
+			return fmt.Errorf("%v (available: %v)", err, p.HandlerNames())
+		}
+	}
+
+	// Сортируем имена, чтобы порядок применения не зависел от обхода map
+	names := make([]string, 0, len(options.Priorities))
+	for name := range options.Priorities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := p.registry.SetHandlerPriority(name, options.Priorities[name]); err != nil {
+			return fmt.Errorf("%v (available: %v)", err, p.HandlerNames())
+		}
+	}
+	return nil
+}
+
+// SetHandlerEnabled включает или отключает обработчик по имени
+func (p *UnifiedParser) SetHandlerEnabled(name string, enabled bool) error {
+	return p.registry.SetHandlerEnabled(name, enabled)
+}
+
+// HandlerNames возвращает имена всех зарегистрированных обработчиков
+func (p *UnifiedParser) HandlerNames() []string {
+	return p.registry.HandlerNames()
+}
+
+// RegisterHandler добавляет пользовательский обработчик инструкций.
+// Обработчик выбирается по TokenPatterns и Priority из cfg, как и встроенные;
+// для новых конструкций он обычно возвращает *ast.CustomStatement.
+func (p *UnifiedParser) RegisterHandler(h common.Handler, cfg config.ConstructHandlerConfig) error {
+	if cfg.Name == "" {
+		return fmt.Errorf("parser handler must have a name")
+	}
+	if len(cfg.TokenPatterns) == 0 {
+		return fmt.Errorf("parser handler %s must have at least one token pattern", cfg.Name)
+	}
+	for _, name := range p.registry.HandlerNames() {
+		if name == cfg.Name {
+			return fmt.Errorf("parser handler %s is already registered", cfg.Name)
+		}
+	}
+	cfg.IsEnabled = true
+	return p.registry.RegisterConstructHandler(h, cfg)
+}
@@ -17,6 +17,9 @@ type UnifiedParser struct {
 	lexer    *lexer.Lexer
 	registry *handler.ConstructHandlerRegistryImpl
 	verbose  bool
+
+	// Первый токен инструкции, которую парсер разбирает сейчас или разобрал последней
+	statement lexer.Token
 }
 
 // ProtoParser - интерфейс парсера по ТЗ (не конфликтует с existing Parser)
@@ -76,6 +79,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -115,6 +124,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -138,6 +153,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 			{TokenType: lexer.TokenLeftParen, Offset: 0}, // Обрабатываем скобки (если есть | внутри)
 			{TokenType: lexer.TokenPipe, Offset: 0},      // Обрабатываем операторы |
 			{TokenType: lexer.TokenBitwiseOr, Offset: 0}, // Обрабатываем операторы |
@@ -163,6 +184,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -201,6 +228,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -304,6 +337,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -327,12 +366,182 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
 	backgroundTaskHandler := handler.NewBackgroundTaskHandler(backgroundTaskConfig)
 	registry.RegisterConstructHandler(backgroundTaskHandler, backgroundTaskConfig)
 
+	// Регистрируем Pipeline обработчик для pipeline { stage ... }
+	// Приоритет выше обработчиков идентификаторов: 'pipeline' - обычный идентификатор
+	pipelineConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructPipeline,
+		Name:          "pipeline",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "pipeline", Offset: 0},
+		},
+	}
+
+	pipelineHandler := handler.NewPipelineHandler(pipelineConfig)
+	registry.RegisterConstructHandler(pipelineHandler, pipelineConfig)
+
+	// Регистрируем обработчик повтора блока retry(n) { ... }
+	retryConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructRetry,
+		Name:          "retry",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "retry", Offset: 0},
+		},
+	}
+
+	retryHandler := handler.NewRetryHandler(retryConfig)
+	registry.RegisterConstructHandler(retryHandler, retryConfig)
+
+	// Регистрируем обработчик проверки свойства forall(x = ...) { ... }
+	forallConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructForall,
+		Name:          "forall",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "forall", Offset: 0},
+		},
+	}
+
+	forallHandler := handler.NewForallHandler(forallConfig)
+	registry.RegisterConstructHandler(forallHandler, forallConfig)
+
+	// Регистрируем обработчик блоков rate_limit(...) { ... } и semaphore(...) { ... }
+	limitConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructLimit,
+		Name:          "limit",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "rate_limit", Offset: 0},
+			{TokenType: lexer.TokenIdentifier, Value: "semaphore", Offset: 0},
+		},
+	}
+
+	limitHandler := handler.NewLimitHandler(limitConfig)
+	registry.RegisterConstructHandler(limitHandler, limitConfig)
+
+	// Регистрируем обработчик ожидания на каналах select { ... }
+	selectConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructSelect,
+		Name:          "select",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "select", Offset: 0},
+		},
+	}
+
+	selectHandler := handler.NewSelectHandler(selectConfig)
+	registry.RegisterConstructHandler(selectHandler, selectConfig)
+
+	// Регистрируем обработчик on_signal(...) { ... } и on_exit { ... }
+	hookConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructHook,
+		Name:          "hook",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "on_signal", Offset: 0},
+			{TokenType: lexer.TokenIdentifier, Value: "on_exit", Offset: 0},
+		},
+	}
+
+	hookHandler := handler.NewHookHandler(hookConfig)
+	registry.RegisterConstructHandler(hookHandler, hookConfig)
+
+	// Регистрируем обработчик блоков cd(...) { ... } и with_env(...) { ... }
+	contextConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructContext,
+		Name:          "context",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "cd", Offset: 0},
+			{TokenType: lexer.TokenIdentifier, Value: "with_env", Offset: 0},
+		},
+	}
+
+	contextHandler := handler.NewContextHandler(contextConfig)
+	registry.RegisterConstructHandler(contextHandler, contextConfig)
+
+	// Регистрируем обработчик interactive sh.run(...)
+	interactiveConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructInteractive,
+		Name:          "interactive",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Value: "interactive", Offset: 0},
+		},
+	}
+
+	interactiveHandler := handler.NewInteractiveHandler(interactiveConfig)
+	registry.RegisterConstructHandler(interactiveHandler, interactiveConfig)
+
+	// Регистрируем обработчик name = capture { ... }
+	captureConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructCapture,
+		Name:          "capture",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenIdentifier, Offset: 0},
+		},
+	}
+
+	captureHandler := handler.NewCaptureHandler(captureConfig)
+	registry.RegisterConstructHandler(captureHandler, captureConfig)
+
+	// Регистрируем обработчик @quiet и @verbose
+	pragmaConfig := config.ConstructHandlerConfig{
+		ConstructType: common.ConstructPragma,
+		Name:          "pragma",
+		Priority:      250,
+		Order:         1,
+		IsEnabled:     true,
+		IsFallback:    false,
+		TokenPatterns: []config.TokenPattern{
+			{TokenType: lexer.TokenAt, Offset: 0},
+		},
+	}
+
+	pragmaHandler := handler.NewPragmaHandler(pragmaConfig)
+	registry.RegisterConstructHandler(pragmaHandler, pragmaConfig)
+
 	// Регистрируем While обработчик для while циклов
 	whileLoopConfig := config.ConstructHandlerConfig{
 		ConstructType: common.ConstructWhileLoop,
@@ -413,6 +622,12 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 			{TokenType: lexer.TokenGo, Offset: 0},
 			{TokenType: lexer.TokenNode, Offset: 0},
 			{TokenType: lexer.TokenJS, Offset: 0},
+			{TokenType: lexer.TokenPerl, Offset: 0},
+			{TokenType: lexer.TokenPl, Offset: 0},
+			{TokenType: lexer.TokenErlang, Offset: 0},
+			{TokenType: lexer.TokenErl, Offset: 0},
+			{TokenType: lexer.TokenElixir, Offset: 0},
+			{TokenType: lexer.TokenEx, Offset: 0},
 		},
 	}
 
@@ -619,11 +834,102 @@ func NewUnifiedParserWithVerbose(verbose bool) *UnifiedParser {
 	}
 }
 
+// isBlockConstructError проверяет, что ошибку вернул обработчик конструкции
+// с телом (pipeline, retry, rate_limit, semaphore, select) после того, как узнал ее
+func isBlockConstructError(err error) bool {
+	for _, prefix := range []string{"pipeline: ", "retry: ", "rate_limit: ", "semaphore: ", "select: ", "on_signal: ", "on_exit: ", "cd: ", "with_env: ", "interactive: ", "capture: ", "@quiet: ", "@verbose: "} {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBody разбирает тело стадии конвейера или retry, сохраненное
+// обработчиком как текст. Текст дополняется переводами строк и пробелами до
+// своего места в скрипте, чтобы позиции ошибок и узлов совпадали со строками
+// и колонками исходного файла
+func (p *UnifiedParser) parseBody(source string, bodyPos ast.Position, input string) (*ast.BlockStatement, []ast.ParseError) {
+	lBrace := lexer.Token{Type: lexer.TokenLBrace, Value: "{", Position: bodyPos.Offset - 1, Line: bodyPos.Line, Column: bodyPos.Column - 1}
+	rBrace := lexer.Token{Type: lexer.TokenRBrace, Value: "}", Position: bodyPos.Offset + len(source)}
+	var statements []ast.Statement
+	if strings.TrimSpace(source) != "" {
+		padding := strings.Repeat("\n", bodyPos.Line-1) + strings.Repeat(" ", bodyPos.Column-1)
+		statement, parseErrors := p.Parse(padding + source)
+		if len(parseErrors) > 0 {
+			for i := range parseErrors {
+				parseErrors[i].Context = input
+			}
+			return nil, parseErrors
+		}
+		if block, ok := statement.(*ast.BlockStatement); ok {
+			statements = block.Statements
+		} else {
+			statements = []ast.Statement{statement}
+		}
+	}
+	return ast.NewBlockStatement(lBrace, rBrace, statements), nil
+}
+
+// redirectableCall возвращает statement вызова, вывод которого можно
+// перенаправить в файл, или nil
+func redirectableCall(result interface{}) ast.Statement {
+	switch call := result.(type) {
+	case *ast.LanguageCall:
+		return call
+	case *ast.BuiltinFunctionCall:
+		return &ast.ExpressionStatement{Expression: call}
+	}
+	return nil
+}
+
+// isRedirectOperator проверяет, что текущий токен - '>' или '>>'
+func isRedirectOperator(tokenStream stream.TokenStream) bool {
+	if !tokenStream.HasMore() {
+		return false
+	}
+	tokenType := tokenStream.Current().Type
+	return tokenType == lexer.TokenGreater || tokenType == lexer.TokenDoubleRightAngle
+}
+
+// isCapture проверяет, что присваивается перехваченный вывод capture { ... }
+func isCapture(value ast.Expression) bool {
+	_, ok := value.(*ast.CaptureExpression)
+	return ok
+}
+
+// parseRedirect разбирает '> file' или '>> file' после вызова. Имя файла -
+// выражение до конца строки
+func (p *UnifiedParser) parseRedirect(tokenStream stream.TokenStream, input string, call ast.Statement) (*ast.RedirectStatement, *ast.ParseError) {
+	operator := tokenStream.Consume()
+	redirectError := func(token lexer.Token, message string) *ast.ParseError {
+		return &ast.ParseError{Type: ast.ErrorSyntax, Position: tokenToPosition(token), Message: message, Context: input}
+	}
+	if !tokenStream.HasMore() || tokenStream.Current().Type == lexer.TokenNewline || tokenStream.Current().Type == lexer.TokenEOF {
+		return nil, redirectError(operator, fmt.Sprintf("redirect: expected a file name after '%s'", operator.Value))
+	}
+	ctx := common.NewParseContext(tokenStream)
+	ctx.InputStream = input
+	target, err := handler.NewUnifiedExpressionParser(p.verbose).ParseExpression(ctx)
+	if err != nil {
+		return nil, redirectError(operator, fmt.Sprintf("redirect: invalid file name after '%s': %v", operator.Value, err))
+	}
+	if tokenStream.HasMore() && tokenStream.Current().Type != lexer.TokenNewline && tokenStream.Current().Type != lexer.TokenEOF {
+		return nil, redirectError(tokenStream.Current(), fmt.Sprintf("redirect: unexpected %s after the file name", tokenStream.Current().Value))
+	}
+	return ast.NewRedirectStatement(call, target, operator.Type == lexer.TokenDoubleRightAngle, tokenToPosition(operator)), nil
+}
+
 // Parse разбирает входную строку и возвращает AST
 func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
+	// 0. Windows-переводы строк читаются как обычные, в том числе внутри
+	// блоков кода и тройных кавычек (их текст берется из input по смещениям)
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+
 	// 1. Создаем лексер
 	lex := lexer.NewLexer(input)
 	tokenStream := stream.NewTokenStream(lex)
+	defer tokenStream.Release()
 
 	// 2. Проверяем, есть ли токены
 	if !tokenStream.HasMore() {
@@ -639,13 +945,20 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 	statements := []ast.Statement{}
 	var parseErrors []ast.ParseError
 
+	// Номер statement среди отделенных ';' на одной строке, для сообщений об ошибках
+	lineStatement := 0
+	semicolonLine := 0
+
 	// 3. Обрабатываем все statements в вводе
 	for tokenStream.HasMore() {
 		// Пропускаем whitespace токены
-		// Пропускаем whitespace и newlines между statements
+		// Пропускаем whitespace, newlines и ; между statements
 		for tokenStream.HasMore() {
 			currentToken := tokenStream.Current()
-			if currentToken.Type == lexer.TokenNewline {
+			if currentToken.Type == lexer.TokenNewline || currentToken.Type == lexer.TokenSemicolon {
+				if currentToken.Type == lexer.TokenSemicolon {
+					semicolonLine = currentToken.Line
+				}
 				tokenStream.Consume()
 			} else {
 				// Проверяем, является ли текущий токен whitespace (пробел, таб и т.д.)
@@ -665,6 +978,13 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 
 		// 4. Получаем текущий токен
 		currentToken := tokenStream.Current()
+		p.statement = currentToken
+		if len(statements) > 0 && semicolonLine == currentToken.Line {
+			lineStatement++
+		} else {
+			lineStatement = 1
+		}
+		semicolonLine = 0
 
 		// 5. Получаем все обработчики для токена
 		tokens := []lexer.Token{currentToken}
@@ -677,7 +997,7 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 			parseErrors = append(parseErrors, ast.ParseError{
 				Type:     ast.ErrorSyntax,
 				Position: tokenToPosition(currentToken),
-				Message:  fmt.Sprintf("no handler found for token: %s line %d col %d", currentToken.Value, currentToken.Line, currentToken.Column),
+				Message:  statementPrefix(lineStatement, currentToken) + fmt.Sprintf("no handler found for token: %s line %d col %d", currentToken.Value, currentToken.Line, currentToken.Column),
 				Context:  input,
 			})
 			break
@@ -709,18 +1029,16 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 		}
 
 		// 9. Создаем контекст и вызываем обработчик с клоном потока
-		ctx = &common.ParseContext{
-				TokenStream: clonedStream,
-				Parser:      nil, // Не используем старый интерфейс
-				Depth:       0,
-				MaxDepth:    100,
-				Guard:       newProtoRecursionGuard(100),
-				LoopDepth:   0,     // Инициализируем глубину циклов для контекстной валидации
-				InputStream: input, // Передаем оригинальный исходный код
-			}
+		ctx = common.NewParseContext(clonedStream)
+		ctx.InputStream = input
 
 			var err error
 			result, err = h.Handle(ctx)
+			if err == nil && result != nil && lastErr != nil && isAssignToken(clonedStream) {
+				// Присваивание, которое не разобрал обработчик присваиваний, не
+				// превращаем в чтение переменной с повисшим '=': ошибка та, что была
+				break
+			}
 			if err == nil && result != nil {
 				// Обработчик успешно обработал входные данные и вернул непустой результат
 				// Для CodeBlockStatement нам нужно остановиться после закрывающей скобки
@@ -818,6 +1136,12 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 				break
 			}
 
+			// Ошибки внутри конвейера, retry и блоков под ограничением окончательные
+			if isBlockConstructError(err) {
+				lastErr = err
+				break
+			}
+
 			// Сохраняем последнюю ошибку
 			lastErr = err
 		}
@@ -832,7 +1156,7 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 			parseErrors = append(parseErrors, ast.ParseError{
 				Type:     ast.ErrorSyntax,
 				Position: position,
-				Message:  lastErr.Error(),
+				Message:  statementPrefix(lineStatement, currentToken) + lastErr.Error(),
 				Context:  input,
 			})
 			break
@@ -866,20 +1190,21 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 		fmt.Printf("DEBUG: UnifiedParser converting result to Statement, result type: %T\n", result)
 	}
 	// Сначала проверяем LanguageCall и BuiltinFunctionCall специально (они реализуют Statement но нуждаются в special handling)
-	if langCall, ok := result.(*ast.LanguageCall); ok {
+	if call := redirectableCall(result); call != nil && isRedirectOperator(tokenStream) {
+		// Вывод вызова пишется в файл: py.report() > "out.txt"
+		redirect, redirectErr := p.parseRedirect(tokenStream, input, call)
+		if redirectErr != nil {
+			parseErrors = append(parseErrors, *redirectErr)
+			break
+		}
+		statements = append(statements, redirect)
+	} else if langCall, ok := result.(*ast.LanguageCall); ok {
 		// Проверяем, идет ли после language call elvis/ternary оператор
 		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenQuestion {
 			// Это elvis или ternary выражение - продолжаем парсить
 			// Создаем новый контекст с основным tokenStream
-			elvisCtx := &common.ParseContext{
-				TokenStream: tokenStream,
-				Parser:      nil,
-				Depth:       0,
-				MaxDepth:    100,
-				Guard:       newProtoRecursionGuard(100),
-				LoopDepth:   0,
-				InputStream: input,
-			}
+			elvisCtx := common.NewParseContext(tokenStream)
+			elvisCtx.InputStream = input
 			binaryExprHandler := handler.NewBinaryExpressionHandlerWithVerbose(config.ConstructHandlerConfig{}, p.verbose)
 			fullExpr, err := binaryExprHandler.ParseFullExpression(elvisCtx, langCall)
 			if err == nil && fullExpr != nil {
@@ -901,15 +1226,8 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 		if tokenStream.HasMore() && tokenStream.Current().Type == lexer.TokenQuestion {
 			// Это elvis или ternary выражение - продолжаем парсить
 			// Создаем новый контекст с основным tokenStream
-			elvisCtx := &common.ParseContext{
-				TokenStream: tokenStream,
-				Parser:      nil,
-				Depth:       0,
-				MaxDepth:    100,
-				Guard:       newProtoRecursionGuard(100),
-				LoopDepth:   0,
-				InputStream: input,
-			}
+			elvisCtx := common.NewParseContext(tokenStream)
+			elvisCtx.InputStream = input
 			binaryExprHandler := handler.NewBinaryExpressionHandlerWithVerbose(config.ConstructHandlerConfig{}, p.verbose)
 			fullExpr, err := binaryExprHandler.ParseFullExpression(elvisCtx, builtinCall)
 			if err == nil && fullExpr != nil {
@@ -928,6 +1246,82 @@ func (p *UnifiedParser) Parse(input string) (ast.Statement, []ast.ParseError) {
 			exprStmt := &ast.ExpressionStatement{Expression: builtinCall}
 			statements = append(statements, exprStmt)
 		}
+	} else if pipeline, ok := result.(*ast.PipelineStatement); ok {
+		// Тела стадий разбираются как отдельные скрипты
+		var bodyErrors []ast.ParseError
+		for _, stage := range pipeline.Stages {
+			if stage.Body, bodyErrors = p.parseBody(stage.Source, stage.BodyPos, input); len(bodyErrors) > 0 {
+				break
+			}
+		}
+		if len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, pipeline)
+	} else if retry, ok := result.(*ast.RetryStatement); ok {
+		var bodyErrors []ast.ParseError
+		if retry.Body, bodyErrors = p.parseBody(retry.Source, retry.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, retry)
+	} else if forall, ok := result.(*ast.ForallStatement); ok {
+		var bodyErrors []ast.ParseError
+		if forall.Body, bodyErrors = p.parseBody(forall.Source, forall.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, forall)
+	} else if limit, ok := result.(*ast.LimitStatement); ok {
+		var bodyErrors []ast.ParseError
+		if limit.Body, bodyErrors = p.parseBody(limit.Source, limit.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, limit)
+	} else if selectStmt, ok := result.(*ast.SelectStatement); ok {
+		// Тела веток, как и тела стадий, разбираются как отдельные скрипты
+		var bodyErrors []ast.ParseError
+		for _, selectCase := range selectStmt.Cases {
+			if selectCase.Body, bodyErrors = p.parseBody(selectCase.Source, selectCase.BodyPos, input); len(bodyErrors) > 0 {
+				break
+			}
+		}
+		if len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, selectStmt)
+	} else if hook, ok := result.(*ast.HookStatement); ok {
+		var bodyErrors []ast.ParseError
+		if hook.Body, bodyErrors = p.parseBody(hook.Source, hook.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, hook)
+	} else if context, ok := result.(*ast.ContextStatement); ok {
+		var bodyErrors []ast.ParseError
+		if context.Body, bodyErrors = p.parseBody(context.Source, context.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, context)
+	} else if assignment, ok := result.(*ast.VariableAssignment); ok && isCapture(assignment.Value) {
+		capture := assignment.Value.(*ast.CaptureExpression)
+		var bodyErrors []ast.ParseError
+		if capture.Body, bodyErrors = p.parseBody(capture.Source, capture.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, assignment)
+	} else if pragma, ok := result.(*ast.PragmaStatement); ok {
+		var bodyErrors []ast.ParseError
+		if pragma.Body, bodyErrors = p.parseBody(pragma.Source, pragma.BodyPos, input); len(bodyErrors) > 0 {
+			parseErrors = append(parseErrors, bodyErrors...)
+			break
+		}
+		statements = append(statements, pragma)
 	} else if statement, ok := result.(ast.Statement); ok {
 		if p.verbose {
 			fmt.Printf("DEBUG: UnifiedParser appending statement: %T\n", statement)
@@ -1131,50 +1525,40 @@ func (p *UnifiedParser) tryParseLineAsExpression(tokenStream stream.TokenStream,
 	return false
 }
 
-// tokenToPosition конвертирует токен в позицию AST
-func tokenToPosition(token lexer.Token) ast.Position {
-	return ast.Position{
-		Line:   token.Line,
-		Column: token.Column,
-		Offset: token.Position,
-	}
-}
-
-// protoRecursionGuard реализует защиту от рекурсии для прототипа
-type protoRecursionGuard struct {
-	maxDepth     int
-	currentDepth int
+// StatementPosition возвращает позицию инструкции, которую парсер разбирает
+// сейчас или разобрал последней. Если обработчик упал с паникой, это
+// инструкция, на которой она случилась
+func (p *UnifiedParser) StatementPosition() ast.Position {
+	return tokenToPosition(p.statement)
 }
 
-func newProtoRecursionGuard(maxDepth int) *protoRecursionGuard {
-	return &protoRecursionGuard{
-		maxDepth:     maxDepth,
-		currentDepth: 0,
+// isAssignToken проверяет, что поток стоит на '=' или ':='
+func isAssignToken(tokenStream stream.TokenStream) bool {
+	if !tokenStream.HasMore() {
+		return false
 	}
+	current := tokenStream.Current().Type
+	return current == lexer.TokenAssign || current == lexer.TokenColonEquals
 }
 
-func (rg *protoRecursionGuard) Enter() error {
-	if rg.currentDepth >= rg.maxDepth {
-		return fmt.Errorf("maximum recursion depth exceeded: %d", rg.maxDepth)
+// statementPrefix начинает сообщение об ошибке в statement с его номера,
+// если на той же строке перед ним уже есть statements, отделенные ';'
+func statementPrefix(index int, token lexer.Token) string {
+	if index < 2 {
+		return ""
 	}
-	rg.currentDepth++
-	return nil
+	return fmt.Sprintf("statement %d on line %d: ", index, token.Line)
 }
 
-func (rg *protoRecursionGuard) Exit() {
-	if rg.currentDepth > 0 {
-		rg.currentDepth--
+// tokenToPosition конвертирует токен в позицию AST
+func tokenToPosition(token lexer.Token) ast.Position {
+	return ast.Position{
+		Line:   token.Line,
+		Column: token.Column,
+		Offset: token.Position,
 	}
 }
 
-func (rg *protoRecursionGuard) CurrentDepth() int {
-	return rg.currentDepth
-}
-
-func (rg *protoRecursionGuard) MaxDepth() int {
-	return rg.maxDepth
-}
-
 // isPartialMatchError проверяет, является ли ошибка результатом частичного совпадения
 // когда обработчик смог распознать часть конструкции, но не смог завершить
 func isPartialMatchError(errMsg string) bool {
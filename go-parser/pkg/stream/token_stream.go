@@ -1,6 +1,8 @@
 package stream
 
 import (
+	"sync"
+
 	"go-parser/pkg/lexer"
 )
 
@@ -18,6 +20,15 @@ type TokenStream interface {
 	GetLexer() lexer.Lexer
 }
 
+// tokenBuffers переиспользует буферы токенов между разборами: при повторном
+// разборе одного и того же файла (LSP, watch-режим) буфер не растет заново
+var tokenBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]lexer.Token, 0, 256)
+		return &buffer
+	},
+}
+
 type SimpleTokenStream struct {
 	lexer    lexer.Lexer
 	tokens   []lexer.Token
@@ -28,7 +39,7 @@ type SimpleTokenStream struct {
 func NewTokenStream(l lexer.Lexer) *SimpleTokenStream {
 	s := &SimpleTokenStream{
 		lexer:    l,
-		tokens:   make([]lexer.Token, 0),
+		tokens:   (*tokenBuffers.Get().(*[]lexer.Token))[:0],
 		position: 0,
 	}
 
@@ -146,15 +157,12 @@ func (s *SimpleTokenStream) HasMore() bool {
 }
 
 func (s *SimpleTokenStream) Clone() TokenStream {
-	// Для клонирования копируем только уже буферизованные токены
-	// НЕ потребляем токены из лексера, чтобы не нарушать состояние оригинала
-	allTokens := make([]lexer.Token, len(s.tokens))
-	copy(allTokens, s.tokens)
-
-	// Создаем клон с копией токенов и позиции
+	// Клон разделяет буфер токенов с оригиналом: NewTokenStream буферизует
+	// все токены до EOF, а клон без лексера ничего в буфер не дописывает.
+	// Емкость обрезана, чтобы дописывание в оригинал не задело клон
 	clone := &SimpleTokenStream{
 		lexer:    nil, // Клон не должен иметь доступа к лексеру
-		tokens:   allTokens,
+		tokens:   s.tokens[:len(s.tokens):len(s.tokens)],
 		position: s.position,
 		current:  s.current,
 	}
@@ -162,6 +170,18 @@ func (s *SimpleTokenStream) Clone() TokenStream {
 	return clone
 }
 
+// Release возвращает буфер токенов в пул. Вызывается, когда разбор закончен:
+// после него ни поток, ни его клоны использовать нельзя
+func (s *SimpleTokenStream) Release() {
+	if s.lexer == nil || s.tokens == nil {
+		return
+	}
+	buffer := s.tokens[:0]
+	s.tokens = nil
+	s.current = lexer.Token{Type: lexer.TokenEOF}
+	tokenBuffers.Put(&buffer)
+}
+
 func (s *SimpleTokenStream) GetLexer() lexer.Lexer {
 	return s.lexer
 }
@@ -14,6 +14,9 @@ const (
 	LanguageLua
 	LanguageGo
 	LanguageNode
+	LanguagePerl
+	LanguageErlang
+	LanguageElixir
 )
 
 // IsLanguageToken проверяет, является ли токен токеном языка
@@ -23,7 +26,13 @@ func IsLanguageToken(tokenType lexer.TokenType) bool {
 		tokenType == lexer.TokenLua ||
 		tokenType == lexer.TokenGo ||
 		tokenType == lexer.TokenNode ||
-		tokenType == lexer.TokenJS
+		tokenType == lexer.TokenJS ||
+		tokenType == lexer.TokenPerl ||
+		tokenType == lexer.TokenPl ||
+		tokenType == lexer.TokenErlang ||
+		tokenType == lexer.TokenErl ||
+		tokenType == lexer.TokenElixir ||
+		tokenType == lexer.TokenEx
 }
 
 // LanguageTokenToString преобразует токен языка в строковое представление
@@ -37,6 +46,12 @@ func LanguageTokenToString(tokenType lexer.TokenType) string {
 		return "go"
 	case lexer.TokenNode, lexer.TokenJS:
 		return "node"
+	case lexer.TokenPerl, lexer.TokenPl:
+		return "perl"
+	case lexer.TokenErlang, lexer.TokenErl:
+		return "erlang"
+	case lexer.TokenElixir, lexer.TokenEx:
+		return "elixir"
 	default:
 		return ""
 	}
@@ -45,7 +60,7 @@ func LanguageTokenToString(tokenType lexer.TokenType) string {
 // GetAllLanguageTokens возвращает все токены языков
 func GetAllLanguageTokens() []lexer.TokenType {
 	return []lexer.TokenType{
-		lexer.TokenPython, lexer.TokenPy, lexer.TokenLua, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS,
+		lexer.TokenPython, lexer.TokenPy, lexer.TokenLua, lexer.TokenGo, lexer.TokenNode, lexer.TokenJS, lexer.TokenPerl, lexer.TokenPl, lexer.TokenErlang, lexer.TokenErl, lexer.TokenElixir, lexer.TokenEx,
 	}
 }
 
@@ -75,6 +90,12 @@ func GetLanguageTokenFromName(name string) lexer.TokenType {
 		return lexer.TokenGo
 	case "node", "js":
 		return lexer.TokenNode
+	case "perl", "pl":
+		return lexer.TokenPerl
+	case "erlang", "erl":
+		return lexer.TokenErlang
+	case "elixir", "ex":
+		return lexer.TokenElixir
 	default:
 		return lexer.TokenIdentifier
 	}
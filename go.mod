@@ -4,17 +4,26 @@ go 1.25.0
 
 require (
 	github.com/chzyer/readline v1.5.1
+	github.com/dop251/goja v0.0.0-20260917113740-793a2a65c13b
 	github.com/funvibe/funbit v1.0.0
+	github.com/klauspost/compress v1.20.1
+	github.com/rivo/uniseg v0.4.7
 	github.com/stretchr/testify v1.8.4
 	github.com/yuin/gopher-lua v1.1.1
 	go-parser v0.0.0-00010101000000-000000000000
+	go.etcd.io/bbolt v1.3.11
+	go.starlark.net v0.0.0-20260908191801-89a6a09411d5
+	golang.org/x/sys v0.42.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/dlclark/regexp2/v2 v2.5.2 // indirect
+	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.3.8 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
 
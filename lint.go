@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"funterm/engine"
+)
+
+// LintFiles checks the match statements (matches) and the bitstring patterns
+// (patterns) of scripts without running them and prints one
+// "file:line:col: warning: message" line per problem. It returns the number
+// of warnings found.
+func LintFiles(paths []string, configPath string, matches, patterns bool) (int, error) {
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+		ParserHandlers: cfg.Parser,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return count, fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+
+		var warnings []engine.LintWarning
+		if matches {
+			if warnings, err = eng.Lint(string(content)); err != nil {
+				return count, fmt.Errorf("%s: %v", path, err)
+			}
+		}
+		if patterns {
+			patternWarnings, err := eng.CheckPatterns(string(content))
+			if err != nil {
+				return count, fmt.Errorf("%s: %v", path, err)
+			}
+			warnings = append(warnings, patternWarnings...)
+		}
+		for _, warning := range warnings {
+			fmt.Printf("%s:%d:%d: warning: %s\n", path, warning.Pos.Line, warning.Pos.Column, warning.Message)
+		}
+		count += len(warnings)
+	}
+	return count, nil
+}
@@ -2,7 +2,6 @@ package logging
 
 import (
 	"fmt"
-	"log/syslog"
 	"net"
 	"os"
 	"sync"
@@ -143,73 +142,6 @@ func (w *FileWriter) GetFilePath() string {
 	return w.filePath
 }
 
-// SyslogWriter writes log entries to syslog
-type SyslogWriter struct {
-	mu     sync.Mutex
-	writer *syslog.Writer
-}
-
-// NewSyslogWriter creates a new syslog writer
-func NewSyslogWriter(network, raddr string, priority syslog.Priority, tag string) (*SyslogWriter, error) {
-	writer, err := syslog.Dial(network, raddr, priority, tag)
-	if err != nil {
-		return nil, err
-	}
-
-	return &SyslogWriter{
-		writer: writer,
-	}, nil
-}
-
-// NewSyslogWriterWithPriority creates a new syslog writer with default network and raddr
-func NewSyslogWriterWithPriority(priority syslog.Priority, tag string) (*SyslogWriter, error) {
-	return NewSyslogWriter("", "", priority, tag)
-}
-
-// NewSyslogWriterWithTag creates a new syslog writer with default settings
-func NewSyslogWriterWithTag(tag string) (*SyslogWriter, error) {
-	return NewSyslogWriter("", "", syslog.LOG_INFO, tag)
-}
-
-// Write writes data to syslog
-func (w *SyslogWriter) Write(data []byte) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	// Convert log level to syslog priority
-	// This is a simple mapping - you might want to make it more sophisticated
-	_, err := w.writer.Write(data)
-	return err
-}
-
-// WriteWithPriority writes data to syslog with a specific priority
-func (w *SyslogWriter) WriteWithPriority(data []byte, priority syslog.Priority) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	_, err := w.writer.Write(data)
-	return err
-}
-
-// Flush flushes the syslog writer
-func (w *SyslogWriter) Flush() error {
-	// Syslog writer doesn't have a flush method
-	return nil
-}
-
-// Close closes the syslog writer
-func (w *SyslogWriter) Close() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	return w.writer.Close()
-}
-
-// GetName returns the name of the writer
-func (w *SyslogWriter) GetName() string {
-	return "syslog"
-}
-
 // RemoteWriter writes log entries to a remote server
 type RemoteWriter struct {
 	mu         sync.Mutex
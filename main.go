@@ -3,202 +3,330 @@ package main
 import (
 	"flag"
 	"fmt"
+	"funterm/engine"
 	"funterm/factory"
 	"funterm/repl"
+	"funterm/runtime"
 	"funterm/runtime/python"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 )
 
 func main() {
+	// However funterm ends, the interpreters it started end with it
+	runtime.HandleShutdownSignals()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			runtime.Shutdown()
+			panic(recovered)
+		}
+	}()
+	if stopped := runtime.SweepOrphans(); stopped > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: stopped %d process(es) left running by a funterm session that crashed\n", stopped)
+	}
+	removeReplacedExecutable()
 
-	// Parse command line flags
-	var (
-		configPath  = flag.String("config", "", "Path to configuration file")
-		showVersion = flag.Bool("version", false, "Show version information")
-		showHelp    = flag.Bool("help", false, "Show help information")
-		execFile    = flag.String("exec", "", "Execute file in batch mode")
-		language    = flag.String("lang", "", "Language for file execution (lua, python, go, mixed)")
-
-		// Package management flags
-		packages      = flag.String("packages", "", "Python package management (list, install, check)")
-		packageTarget = flag.String("package-name", "", "Target package for install/check operations")
-
-		// Module management flags
-		modules      = flag.String("modules", "", "Lua module management (list, info, test)")
-		moduleTarget = flag.String("module-name", "", "Target module for info/test operations")
-
-		// Diagnostic flags
-		doctor  = flag.Bool("doctor", false, "Run system diagnostics")
-		verbose = flag.Bool("verbose", false, "Enable verbose output")
-		envInfo = flag.Bool("env-info", false, "Show Python environment information")
-	)
-	flag.Parse()
+	// A tool made by `funterm build` runs its script instead of funterm
+	b, err := readBundle()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		runtime.Exit(1)
+	}
+	if b != nil {
+		runtime.Exit(runBundle(b, os.Args[1:]))
+	}
+	runtime.Exit(run(os.Args[1:]))
+}
 
-	// Handle shebang execution (when script is run as ./script.su)
-	args := flag.Args()
-	if len(args) > 0 && *execFile == "" {
-		// Check if the argument is a .su file
-		filePath := args[0]
-		if strings.HasSuffix(filePath, ".su") {
-			// Parse additional arguments that might be passed to the script
-			shebangVerbose := *verbose
-			shebangLanguage := *language
-			shebangConfigPath := *configPath
-
-			// Check if there are additional arguments after the filename
-			for i := 1; i < len(args); i++ {
-				switch args[i] {
-				case "--verbose", "-v":
-					shebangVerbose = true
-				case "--lang":
-					if i+1 < len(args) {
-						shebangLanguage = args[i+1]
-						i++ // Skip next arg
-					}
-				case "--config":
-					if i+1 < len(args) {
-						shebangConfigPath = args[i+1]
-						i++ // Skip next arg
-					}
-				}
-			}
+// run handles the command line and returns the exit status
+func run(arguments []string) int {
+	options := &cliOptions{}
+	cli := newCLI(options)
+	flags := cli.flagSet()
+	if err := flags.Parse(arguments); err != nil {
+		if err == flag.ErrHelp {
+			writeHelp(os.Stdout, cli)
+			return 0
+		}
+		return 2
+	}
 
-			// Automatically execute .su files in batch mode
-			if err := BatchMode(filePath, shebangLanguage, shebangConfigPath, shebangVerbose); err != nil {
-				fmt.Printf("Error executing script: %v\n", err)
-				os.Exit(1)
-			}
-			os.Exit(0)
+	if options.noConfig && options.configPath != "" {
+		return exitStatus(false, usageErrorf("", "--config and --no-config cannot be used together"))
+	}
+
+	// Handle lint mode: check the given scripts without running them
+	args := flags.Args()
+	if options.lint || options.checkPatterns {
+		count, err := LintFiles(args, options.configPath, options.lint, options.checkPatterns)
+		return exitStatus(count == 0, err)
+	}
+
+	// Handle commands: `funterm run`, `funterm pkg ...` and the rest
+	if len(args) > 0 {
+		if command := cli.command(args[0]); command != nil {
+			return exitStatus(cli.runCommand(command, args[1:], options))
 		}
 	}
 
+	// Handle shebang execution (when script is run as ./script.su)
+	if len(args) > 0 && options.execFile == "" && strings.HasSuffix(args[0], ".su") {
+		return runScript(args, options)
+	}
+
 	// Handle version flag with verbose support
-	if *showVersion {
-		if *verbose {
-			fmt.Println("funterm v0.1.0 - Multi-Language REPL")
+	if options.showVersion {
+		fmt.Printf("funterm v%s - Multi-Language REPL\n", funtermVersion)
+		if options.verbose {
 			fmt.Println("Build: development")
 			fmt.Println("Go Version: runtime.Version()")
 			fmt.Println("Supported Languages: Go, JS, Lua, Python")
-		} else {
-			fmt.Println("funterm v0.1.0 - Multi-Language REPL")
 		}
-		os.Exit(0)
+		return 0
 	}
 
 	// Handle help flag
-	if *showHelp {
-		printHelp()
-		os.Exit(0)
+	if options.showHelp {
+		writeHelp(os.Stdout, cli)
+		return 0
 	}
 
-	// Handle package management commands
-	if *packages != "" {
-		// Check if there are additional arguments after flag.Parse()
-		args := flag.Args()
-		var additionalTarget string
-		if len(args) > 0 {
-			additionalTarget = args[0]
-		}
-
-		// Use additional target if packageTarget is not provided
-		finalTarget := *packageTarget
-		if finalTarget == "" {
-			finalTarget = additionalTarget
+	// The flags before the commands: --packages "install <name>" and
+	// --modules "info <name>" take the name from the command string, a
+	// --package-name/--module-name flag or the first argument
+	if options.packages != "" || options.modules != "" {
+		commandLine, target := options.packages, options.packageTarget
+		if options.packages == "" {
+			commandLine, target = options.modules, options.moduleTarget
 		}
-
-		if err := handlePythonPackages(*packages, finalTarget, *configPath, *verbose); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+		parts := strings.Fields(commandLine)
+		if len(parts) > 1 {
+			target = parts[1]
 		}
-		os.Exit(0)
-	}
-
-	// Handle module management commands
-	if *modules != "" {
-		// Check if there are additional arguments after flag.Parse()
-		args := flag.Args()
-		var additionalTarget string
-		if len(args) > 0 {
-			additionalTarget = args[0]
+		if target == "" && len(args) > 0 {
+			target = args[0]
 		}
-
-		// Use additional target if moduleTarget is not provided
-		finalTarget := *moduleTarget
-		if finalTarget == "" {
-			finalTarget = additionalTarget
+		if len(parts) == 0 {
+			return exitStatus(false, usageErrorf("", "no command specified"))
 		}
-
-		if err := handleLuaModules(*modules, finalTarget, *configPath, *verbose); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+		if options.packages != "" {
+			return exitStatus(true, handlePythonPackages(parts[0], target, options.verbose))
 		}
-		os.Exit(0)
+		return exitStatus(true, handleLuaModules(parts[0], target, options.verbose))
+	}
+	if options.doctor {
+		return exitStatus(runDiagnostics(options.configPath, options.noConfig, options.verbose, false))
+	}
+	if options.envInfo {
+		return exitStatus(true, showPythonEnvironmentInfo(options.configPath, options.verbose))
 	}
 
-	// Handle diagnostic commands
-	if *doctor {
-		if err := runDiagnostics(*configPath, *verbose); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-		os.Exit(0)
+	if options.execFile == "" && len(args) > 0 {
+		return exitStatus(false, usageErrorf("", "unknown command '%s'", args[0]))
 	}
 
-	// Handle environment info command
-	if *envInfo {
-		if err := showPythonEnvironmentInfo(*configPath, *verbose); err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
-		}
-		os.Exit(0)
+	cassette, err := openCassette(options.recordPath, options.replayPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
 	}
+	defer cassette.Close()
 
 	// Если указан файл для выполнения, запускаем в пакетном режиме
-	if *execFile != "" {
-		if err := BatchMode(*execFile, *language, *configPath, *verbose); err != nil {
+	if options.execFile != "" {
+		if err := BatchMode(options.execFile, options.language, options.configPath, options.verbose, cassette); err != nil {
 			fmt.Printf("Ошибка выполнения файла: %v\n", err)
-			os.Exit(1)
+			return 1
 		}
-		os.Exit(0)
+		return 0
 	}
 
-	// Load configuration
-	configFilePath := *configPath
-	if configFilePath == "" {
-		// Try default locations
-		home, _ := os.UserHomeDir()
-		defaultPaths := []string{
-			filepath.Join(home, ".funterm", "config.yaml"),
-			"./config.yaml",
-		}
-		for _, path := range defaultPaths {
-			if _, err := os.Stat(path); err == nil {
-				configFilePath = path
-				break
+	return runREPL(options, cassette)
+}
+
+// runScript runs a .su file given as the first argument. Flags after the file
+// name apply to the script, which lets a shebang line pass them
+func runScript(args []string, options *cliOptions) int {
+	scriptOptions := *options
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--verbose", "-v":
+			scriptOptions.verbose = true
+		case "--lang":
+			if i+1 < len(args) {
+				scriptOptions.language = args[i+1]
+				i++ // Skip next arg
+			}
+		case "--config":
+			if i+1 < len(args) {
+				scriptOptions.configPath = args[i+1]
+				i++ // Skip next arg
+			}
+		case "--target":
+			if i+1 < len(args) {
+				scriptOptions.target = args[i+1]
+				i++ // Skip next arg
+			}
+		case "--no-config":
+			scriptOptions.configPath = ""
+		case "--record":
+			if i+1 < len(args) {
+				scriptOptions.recordPath = args[i+1]
+				i++ // Skip next arg
+			}
+		case "--replay":
+			if i+1 < len(args) {
+				scriptOptions.replayPath = args[i+1]
+				i++ // Skip next arg
 			}
 		}
 	}
 
+	if _, err := runFile(args[:1], &scriptOptions); err != nil {
+		fmt.Printf("Error executing script: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runFile implements `funterm run <file>`
+func runFile(args []string, options *cliOptions) (bool, error) {
+	if len(args) != 1 {
+		return false, usageErrorf("run", "expected one script file")
+	}
+	if options.graph != "" {
+		return printPipelineGraph(args[0], options)
+	}
+	cassette, err := openCassette(options.recordPath, options.replayPath)
+	if err != nil {
+		return false, err
+	}
+	defer cassette.Close()
+
+	// Scripts read only the configuration they are given
+	configPath := options.configPath
+	if options.noConfig {
+		configPath = ""
+	}
+	return true, runBatch(args[0], options.language, configPath, options.verbose, cassette, batchOptions{target: options.target})
+}
+
+// printPipelineGraph implements `funterm run --graph`: it parses the script
+// and prints the stages of its pipelines as a graph, running nothing
+func printPipelineGraph(script string, options *cliOptions) (bool, error) {
+	if options.graph != "dot" && options.graph != "mermaid" {
+		return false, usageErrorf("run", "--graph: unknown format '%s', expected dot or mermaid", options.graph)
+	}
+	configPath := options.configPath
+	if options.noConfig {
+		configPath = ""
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	source, err := os.ReadFile(script)
+	if err != nil {
+		return false, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+		ParserHandlers: cfg.Parser,
+	})
+	if err != nil {
+		return false, err
+	}
+	program, err := eng.Parse(string(source))
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", script, err)
+	}
+	graph, err := engine.PipelineGraph(program, options.graph)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", script, err)
+	}
+	fmt.Print(graph)
+	return true, nil
+}
+
+// runREPL starts the interactive REPL with the configuration and runtimes
+func runREPL(options *cliOptions, cassette *runtime.Cassette) int {
+	// Load configuration
+	configFilePath := ""
+	if !options.noConfig {
+		configFilePath = findConfigFile(options.configPath)
+	}
 	cfg, err := LoadConfig(configFilePath)
 	if err != nil {
 		fmt.Printf("Error loading configuration: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// Override config with command line flags
-	if *verbose {
+	if options.verbose {
 		cfg.Engine.Verbose = true
 	}
+	if options.noPrelude {
+		cfg.REPL.Prelude = ""
+	}
+	if options.target != "" {
+		if err := cfg.SetRemote(options.target); err != nil {
+			return exitStatus(false, usageErrorf("repl", "--target: %v", err))
+		}
+	}
+
+	registry := newRuntimeRegistry(cfg, cassette)
+
+	// Create REPL with configuration
+	replInstance := repl.NewREPLWithConfig(repl.REPLConfig{
+		Registry:       registry,
+		Verbose:        cfg.Engine.Verbose,
+		Prompt:         cfg.REPL.Prompt,
+		RightPrompt:    cfg.REPL.RightPrompt,
+		ContinuePrompt: "... ", // Default continuation prompt
+		HistoryFile:    cfg.REPL.HistoryFile,
+		HistorySize:    cfg.REPL.HistorySize,
+		// Спиннер для долгих вызовов рантаймов
+		SpinnerThreshold: time.Duration(cfg.Engine.SpinnerThresholdMs) * time.Millisecond,
+		MemoryBudget:     int64(cfg.Engine.MemoryBudgetMB) << 20,
+		ParserHandlers:   cfg.Parser,
+		Numbers:          cfg.Engine.Numbers,
+		MatchWarnings:    cfg.Engine.MatchWarnings,
+		GuardedCalls:     cfg.Engine.GuardedCalls,
+		Prelude:          expandHome(cfg.REPL.Prelude),
+		PreludeCacheDir:  expandHome(cfg.REPL.PreludeCache),
+		CrashDir:         expandHome(cfg.REPL.CrashDir),
+		UpdateNotice:     updateNotice(cfg),
+		CollectUsage:     cfg.Telemetry.Enabled,
+		ResultCacheDir:   expandHome(cfg.Engine.ResultCache),
+		StorePath:        expandHome(cfg.Engine.Store),
+		Snippets:         cfg.REPL.Snippets,
+		Strict:           cfg.Engine.Strict,
+		StrictRules:      cfg.Engine.StrictRules,
+		SegmentOverflow:  cfg.Engine.SegmentOverflow,
+		Policy:           cfg.Engine.Policy.sessionPolicy(),
+	})
+	defer func() { recordTelemetry(cfg, "repl", registry, replInstance.GetEngine().Usage()) }()
+	// Run the REPL
+	if err := replInstance.Run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
 
-	// Create runtime registry with disabled languages support
+// newRuntimeRegistry registers the runtimes the configuration doesn't disable
+func newRuntimeRegistry(cfg *Config, cassette *runtime.Cassette) *factory.RuntimeRegistry {
 	registry := factory.NewRuntimeRegistry()
+	runtime.SetStopGrace(time.Duration(cfg.Engine.StopGraceMs) * time.Millisecond)
 
 	// Register runtimes based on configuration
 	if !cfg.IsLanguageDisabled("lua") {
 		luaFactory := factory.NewLuaRuntimeFactory()
+		luaFactory.SetProcessOptions(cfg.GetProcessOptions("lua"))
+		luaFactory.SetExternal(cfg.GetRuntimeMode("lua") == "external")
+		luaFactory.SetLuaPath(cfg.GetRuntimePath("lua"))
+		luaFactory.SetExecutionTimeout(time.Duration(cfg.Engine.MaxExecutionTime) * time.Second)
+		luaFactory.SetCassette(cassette)
 		if err := registry.RegisterFactory(luaFactory); err != nil {
 			fmt.Printf("Warning: Failed to register Lua runtime: %v\n", err)
 		}
@@ -208,6 +336,10 @@ func main() {
 		pythonPath := cfg.GetRuntimePath("python")
 		executionTimeout := time.Duration(cfg.Engine.MaxExecutionTime) * time.Second
 		pythonFactory := factory.NewPythonRuntimeFactoryWithConfig(pythonPath, cfg.Engine.Verbose, executionTimeout)
+		pythonFactory.SetCassette(cassette)
+		pythonFactory.SetProcessOptions(cfg.GetProcessOptions("python"))
+		pythonFactory.SetEmbedded(cfg.GetRuntimeMode("python") == "embedded")
+		pythonFactory.SetWarmStart(cfg.Languages.Runtimes["python"].Preload, cfg.Languages.Runtimes["python"].Snapshot)
 		if err := registry.RegisterFactory(pythonFactory); err != nil {
 			fmt.Printf("Warning: Failed to register Python runtime: %v\n", err)
 		}
@@ -222,97 +354,76 @@ func main() {
 
 	if !cfg.IsLanguageDisabled("node") && !cfg.IsLanguageDisabled("js") && !cfg.IsLanguageDisabled("javascript") {
 		nodeFactory := factory.NewNodeRuntimeFactory()
+		nodeFactory.SetNodePath(cfg.GetRuntimePath("node"))
+		nodeFactory.SetCassette(cassette)
+		nodeFactory.SetProcessOptions(cfg.GetProcessOptions("node"))
+		nodeFactory.SetEmbedded(cfg.GetRuntimeMode("node") == "embedded")
+		nodeFactory.SetExecutionTimeout(time.Duration(cfg.Engine.MaxExecutionTime) * time.Second)
 		if err := registry.RegisterFactory(nodeFactory); err != nil {
 			fmt.Printf("Warning: Failed to register Node.js runtime: %v\n", err)
 		}
 	}
 
-	// Create REPL with configuration
-	replInstance := repl.NewREPLWithConfig(repl.REPLConfig{
-		Registry:       registry,
-		Verbose:        cfg.Engine.Verbose,
-		Prompt:         cfg.REPL.Prompt,
-		ContinuePrompt: "... ", // Default continuation prompt
-		HistoryFile:    cfg.REPL.HistoryFile,
-		HistorySize:    cfg.REPL.HistorySize,
-	})
-	// Run the REPL
-	if err := replInstance.Run(); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+	if !cfg.IsLanguageDisabled("perl") && !cfg.IsLanguageDisabled("pl") {
+		perlFactory := factory.NewPerlRuntimeFactory()
+		perlFactory.SetPerlPath(cfg.GetRuntimePath("perl"))
+		perlFactory.SetExecutionTimeout(time.Duration(cfg.Engine.MaxExecutionTime) * time.Second)
+		perlFactory.SetCassette(cassette)
+		perlFactory.SetProcessOptions(cfg.GetProcessOptions("perl"))
+		if err := registry.RegisterFactory(perlFactory); err != nil {
+			fmt.Printf("Warning: Failed to register Perl runtime: %v\n", err)
+		}
 	}
-}
 
-// printHelp displays help information
-func printHelp() {
-	fmt.Println("funterm - Multi-Language REPL")
-	fmt.Println()
-	fmt.Println("Usage: funterm [options]")
-	fmt.Println("Run script: funterm <path-to-file>")
-	fmt.Println()
-	fmt.Println("Options:")
-	fmt.Println("  --config <path>           Path to configuration file")
-	fmt.Println("  --version                 Show version information")
-	fmt.Println("  --version --verbose       Show detailed version information")
-	fmt.Println("  --help                    Show this help message")
-	// fmt.Println("  --exec <file>             Execute file in batch mode")
-	// fmt.Println("  --lang <language>         Specify language for file execution (lua, python, go, mixed)")
-	fmt.Println()
-	fmt.Println("Package Management:")
-	fmt.Println("  --packages <command>      Python package management")
-	fmt.Println("  --package-name <name>     Target package for install/check operations")
-	fmt.Println("    Commands:")
-	fmt.Println("      list                   List installed packages")
-	fmt.Println("      install <name>         Install a package")
-	fmt.Println("      check <name>           Check if package is installed")
-	fmt.Println()
-	fmt.Println("Module Management:")
-	fmt.Println("  --modules <command>       Lua module management")
-	fmt.Println("  --module-name <name>      Target module for info/test operations")
-	fmt.Println("    Commands:")
-	fmt.Println("      list                   List available modules")
-	fmt.Println("      info <name>            Show module information")
-	fmt.Println("      test <name>            Test module loading")
-	fmt.Println()
-	fmt.Println("Diagnostic Commands:")
-	fmt.Println("  --doctor                  Run system diagnostics")
-	fmt.Println("  --env-info                Show Python environment information")
-	fmt.Println("  --verbose                 Enable verbose output")
-	fmt.Println()
-	fmt.Println("Environment Variables:")
-	fmt.Println("  SUTERM_CONFIG            Path to configuration file")
-	fmt.Println()
-	fmt.Println("Examples:")
-	fmt.Println("  funterm                              Run REPL with default configuration")
-	fmt.Println("  funterm script.su                    Run a script file")
-	// fmt.Println("  funterm --exec \"lua.print('hello')\"  Execute a command string")
-	// fmt.Println("  funterm --config config.yaml         Run with custom configuration")
-	fmt.Println("  funterm --no-config                  Run without loading any config file")
-	fmt.Println("  funterm --help                       Show this help message")
-	fmt.Println("\nConfiguration:")
-	fmt.Println("  Configuration files are searched in the following order:")
-	fmt.Println("  1. Path specified by --config flag")
-	fmt.Println("  2. Path specified by SUTERM_CONFIG environment variable")
-	fmt.Println("  3. Default locations: ~/.funterm/config.yaml, ./config.yaml")
-	fmt.Println()
-	fmt.Println("For more information, visit: https://github.com/funvibe/funterm")
-}
+	if !cfg.IsLanguageDisabled("erlang") && !cfg.IsLanguageDisabled("erl") {
+		erlangFactory := factory.NewErlangRuntimeFactory()
+		erlangFactory.SetExecutable(cfg.GetRuntimePath("erlang"))
+		erlangFactory.SetExecutionTimeout(time.Duration(cfg.Engine.MaxExecutionTime) * time.Second)
+		erlangFactory.SetCassette(cassette)
+		erlangFactory.SetProcessOptions(cfg.GetProcessOptions("erlang"))
+		if err := registry.RegisterFactory(erlangFactory); err != nil {
+			fmt.Printf("Warning: Failed to register Erlang runtime: %v\n", err)
+		}
+	}
 
-// handlePythonPackages handles Python package management commands
-func handlePythonPackages(commandArg, targetFromFlag, configPath string, verbose bool) error {
-	// Parse the command argument to extract command and target
-	// Support formats: "list", "install <package>", "check <package>"
-	parts := strings.Fields(commandArg)
-	if len(parts) == 0 {
-		return fmt.Errorf("no command specified")
+	if !cfg.IsLanguageDisabled("elixir") && !cfg.IsLanguageDisabled("ex") {
+		elixirFactory := factory.NewElixirRuntimeFactory()
+		elixirFactory.SetExecutable(cfg.GetRuntimePath("elixir"))
+		elixirFactory.SetExecutionTimeout(time.Duration(cfg.Engine.MaxExecutionTime) * time.Second)
+		elixirFactory.SetCassette(cassette)
+		elixirFactory.SetProcessOptions(cfg.GetProcessOptions("elixir"))
+		if err := registry.RegisterFactory(elixirFactory); err != nil {
+			fmt.Printf("Warning: Failed to register Elixir runtime: %v\n", err)
+		}
 	}
 
-	command := parts[0]
-	var target string
-	if len(parts) > 1 {
-		target = parts[1]
-	} else {
-		target = targetFromFlag
+	return registry
+}
+
+// openCassette prepares record/replay of runtime interactions; nil means neither was requested
+func openCassette(recordPath, replayPath string) (*runtime.Cassette, error) {
+	switch {
+	case recordPath != "" && replayPath != "":
+		return nil, fmt.Errorf("--record and --replay cannot be used together")
+	case recordPath != "":
+		return runtime.NewRecordingCassette(recordPath)
+	case replayPath != "":
+		return runtime.LoadCassette(replayPath)
+	}
+	return nil, nil
+}
+
+// handlePythonPackages handles Python package management commands: "list",
+// "install <package>" and "check <package>"
+func handlePythonPackages(command, target string, verbose bool) error {
+	switch command {
+	case "list":
+	case "install", "check":
+		if target == "" {
+			return usageErrorf("pkg", "package name is required for %s command", command)
+		}
+	default:
+		return usageErrorf("pkg", "unknown package command: %s. Supported commands: list, install, check", command)
 	}
 
 	if verbose {
@@ -357,9 +468,6 @@ func handlePythonPackages(commandArg, targetFromFlag, configPath string, verbose
 		}
 
 	case "install":
-		if target == "" {
-			return fmt.Errorf("package name is required for install command")
-		}
 		if verbose {
 			fmt.Printf("Installing Python package: %s\n", target)
 		}
@@ -369,9 +477,6 @@ func handlePythonPackages(commandArg, targetFromFlag, configPath string, verbose
 		fmt.Printf("Successfully installed package: %s\n", target)
 
 	case "check":
-		if target == "" {
-			return fmt.Errorf("package name is required for check command")
-		}
 		if verbose {
 			fmt.Printf("Checking Python package: %s\n", target)
 		}
@@ -381,28 +486,22 @@ func handlePythonPackages(commandArg, targetFromFlag, configPath string, verbose
 		}
 		fmt.Printf("Package '%s' is installed (version: %s)\n", target, version)
 
-	default:
-		return fmt.Errorf("unknown package command: %s. Supported commands: list, install, check", command)
 	}
 
 	return nil
 }
 
-// handleLuaModules handles Lua module management commands
-func handleLuaModules(commandArg, targetFromFlag, configPath string, verbose bool) error {
-	// Parse the command argument to extract command and target
-	// Support formats: "list", "info <module>", "test <module>"
-	parts := strings.Fields(commandArg)
-	if len(parts) == 0 {
-		return fmt.Errorf("no command specified")
-	}
-
-	command := parts[0]
-	var target string
-	if len(parts) > 1 {
-		target = parts[1]
-	} else {
-		target = targetFromFlag
+// handleLuaModules handles Lua module management commands: "list",
+// "info <module>" and "test <module>"
+func handleLuaModules(command, target string, verbose bool) error {
+	switch command {
+	case "list":
+	case "info", "test":
+		if target == "" {
+			return usageErrorf("modules", "module name is required for %s command", command)
+		}
+	default:
+		return usageErrorf("modules", "unknown module command: %s. Supported commands: list, info, test", command)
 	}
 
 	if verbose {
@@ -451,9 +550,6 @@ func handleLuaModules(commandArg, targetFromFlag, configPath string, verbose boo
 		}
 
 	case "info":
-		if target == "" {
-			return fmt.Errorf("module name is required for info command")
-		}
 		if verbose {
 			fmt.Printf("Showing info for Lua module: %s\n", target)
 		}
@@ -475,9 +571,6 @@ func handleLuaModules(commandArg, targetFromFlag, configPath string, verbose boo
 		}
 
 	case "test":
-		if target == "" {
-			return fmt.Errorf("module name is required for test command")
-		}
 		if verbose {
 			fmt.Printf("Testing Lua module: %s\n", target)
 		}
@@ -488,85 +581,8 @@ func handleLuaModules(commandArg, targetFromFlag, configPath string, verbose boo
 			return fmt.Errorf("failed to test module '%s': %w", target, err)
 		}
 
-	default:
-		return fmt.Errorf("unknown module command: %s. Supported commands: list, info, test", command)
-	}
-
-	return nil
-}
-
-// runDiagnostics runs system diagnostics
-func runDiagnostics(configPath string, verbose bool) error {
-	if verbose {
-		fmt.Println("Running system diagnostics...")
 	}
 
-	fmt.Println("=== Funterm System Diagnostics ===")
-	fmt.Println()
-
-	// Check Python runtime
-	fmt.Println("1. Python Runtime:")
-	registry := factory.DefaultRuntimeRegistry()
-	pythonRuntime, err := registry.CreateRuntimeForLanguage("python")
-	if err != nil {
-		fmt.Printf("   ❌ Failed to create Python runtime: %v\n", err)
-	} else {
-		if err := pythonRuntime.Initialize(); err != nil {
-			fmt.Printf("   ❌ Failed to initialize Python runtime: %v\n", err)
-		} else {
-			fmt.Printf("   ✅ Python runtime initialized successfully\n")
-			if pyRuntime, ok := pythonRuntime.(*python.PythonRuntime); ok {
-				pyRuntime.SetVerbose(verbose)
-				// Virtual environment support disabled in simplified runtime
-				fmt.Printf("   ⚠️  Virtual environment support disabled (simplified runtime)\n")
-
-				packages := pyRuntime.ListPackages()
-				fmt.Printf("   📦 Installed packages: %d\n", len(packages))
-			}
-		}
-	}
-	fmt.Println()
-
-	// Check Lua runtime
-	fmt.Println("2. Lua Runtime:")
-	luaRuntime, err := registry.CreateRuntimeForLanguage("lua")
-	if err != nil {
-		fmt.Printf("   ❌ Failed to create Lua runtime: %v\n", err)
-	} else {
-		if err := luaRuntime.Initialize(); err != nil {
-			fmt.Printf("   ❌ Failed to initialize Lua runtime: %v\n", err)
-		} else {
-			fmt.Printf("   ✅ Lua runtime initialized successfully\n")
-			modules := luaRuntime.GetModules()
-			fmt.Printf("   📚 Available modules: %d\n", len(modules))
-		}
-	}
-	fmt.Println()
-
-	// Check configuration
-	fmt.Println("3. Configuration:")
-	if configPath == "" {
-		fmt.Printf("   ℹ️  Using default configuration\n")
-	} else {
-		if _, err := os.Stat(configPath); err != nil {
-			fmt.Printf("   ❌ Configuration file not found: %v\n", err)
-		} else {
-			fmt.Printf("   ✅ Configuration file found: %s\n", configPath)
-		}
-	}
-	fmt.Println()
-
-	// Check working directory
-	fmt.Println("4. Working Directory:")
-	workDir, err := os.Getwd()
-	if err != nil {
-		fmt.Printf("   ❌ Failed to get working directory: %v\n", err)
-	} else {
-		fmt.Printf("   ✅ Working directory: %s\n", workDir)
-	}
-	fmt.Println()
-
-	fmt.Println("=== Diagnostics Complete ===")
 	return nil
 }
 
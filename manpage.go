@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeManPage writes the manual page of the command line in roff
+func writeManPage(w io.Writer, cli *cliDefinition) {
+	name := cli.Name
+	fmt.Fprintf(w, ".\\\" Generated by `%s man`\n", name)
+	fmt.Fprintf(w, ".TH %s 1 \"\" \"%s %s\" \"User Commands\"\n", strings.ToUpper(name), name, funtermVersion)
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- %s\n", name, roffEscape(cli.Summary))
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s\n[\\fIoptions\\fR]\n.br\n", name)
+	fmt.Fprintf(w, ".B %s\n[\\fIoptions\\fR] \\fIscript.su\\fR\n.br\n", name)
+	fmt.Fprintf(w, ".B %s\n\\fIcommand\\fR [\\fIoptions\\fR] [\\fIargs\\fR]\n", name)
+
+	fmt.Fprintln(w, ".SH DESCRIPTION")
+	fmt.Fprintln(w, roffEscape(cli.Description))
+
+	fmt.Fprintln(w, ".SH OPTIONS")
+	for _, group := range cli.Groups {
+		var flags []cliFlag
+		for _, f := range cli.Flags {
+			if f.Group == group {
+				flags = append(flags, f)
+			}
+		}
+		if len(flags) == 0 {
+			continue
+		}
+		if group != "Options" {
+			fmt.Fprintf(w, ".SS %s\n", roffEscape(group))
+		}
+		for _, f := range flags {
+			writeManFlag(w, f)
+		}
+	}
+
+	fmt.Fprintln(w, ".SH COMMANDS")
+	for _, command := range cli.Commands {
+		if len(command.Commands) == 0 {
+			writeManCommand(w, command.Name, command)
+		}
+		for _, sub := range command.Commands {
+			writeManCommand(w, command.Name+" "+sub.Name, sub)
+		}
+	}
+
+	fmt.Fprintln(w, ".SH EXIT STATUS")
+	for _, code := range cli.ExitCodes {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(code.Name), roffEscape(code.Usage))
+	}
+	fmt.Fprintln(w, ".SH ENVIRONMENT")
+	for _, env := range cli.Environment {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(env.Name), roffEscape(env.Usage))
+	}
+	fmt.Fprintln(w, ".SH FILES")
+	for _, file := range cli.Files {
+		fmt.Fprintf(w, ".TP\n.I %s\n%s\n", roffEscape(file.Name), roffEscape(file.Usage))
+	}
+	fmt.Fprintln(w, ".SH EXAMPLES")
+	for _, example := range cli.Examples {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(example.Name), roffEscape(example.Usage))
+	}
+	fmt.Fprintln(w, ".SH SEE ALSO")
+	fmt.Fprintln(w, "https://github.com/funvibe/funterm")
+}
+
+func writeManFlag(w io.Writer, f cliFlag) {
+	fmt.Fprint(w, ".TP\n")
+	if f.Short != "" {
+		fmt.Fprintf(w, "\\fB\\-%s\\fR, ", roffEscape(f.Short))
+	}
+	fmt.Fprintf(w, "\\fB\\-\\-%s\\fR", roffEscape(f.Name))
+	if f.Arg != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(f.Arg))
+	}
+	fmt.Fprintf(w, "\n%s\n", roffEscape(f.Usage))
+	if len(f.Choices) > 0 {
+		fmt.Fprintln(w, ".RS")
+		for _, choice := range f.Choices {
+			fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roffEscape(choice.Name), roffEscape(choice.Usage))
+		}
+		fmt.Fprintln(w, ".RE")
+	}
+}
+
+func writeManCommand(w io.Writer, name string, command cliCommand) {
+	fmt.Fprintf(w, ".TP\n\\fB%s\\fR", roffEscape(name))
+	if command.Args != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", roffEscape(command.Args))
+	}
+	fmt.Fprintf(w, "\n%s\n", roffEscape(command.Usage))
+	if len(command.Flags) > 0 {
+		fmt.Fprintln(w, ".RS")
+		for _, f := range command.Flags {
+			writeManFlag(w, f)
+		}
+		fmt.Fprintln(w, ".RE")
+	}
+}
+
+// roffEscape keeps text from being read as roff requests or escapes
+func roffEscape(text string) string {
+	text = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
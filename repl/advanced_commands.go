@@ -3,9 +3,12 @@ package repl
 import (
 	"fmt"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"funterm/engine"
 )
 
 // CommandHandler represents a function that handles a specific command
@@ -30,6 +33,9 @@ type ProfileOperation struct {
 	Name      string
 	Duration  time.Duration
 	CallCount int
+	// CPU time and peak memory growth of the interpreter, see engine.SetCallStats
+	CPU     time.Duration
+	PeakRSS int64
 }
 
 // Debugger handles debugging operations
@@ -365,8 +371,19 @@ func (ac *AdvancedCommands) HandleDependenciesCommand(args []string) (interface{
 
 // HandleGCCommand handles :gc command
 func (ac *AdvancedCommands) HandleGCCommand(args []string) (interface{}, error) {
-	runtime.GC()
-	return "Garbage collection completed", nil
+	report, err := ac.repl.engine.CollectGarbage()
+	if err != nil {
+		return nil, err
+	}
+	released := report.ReleasedHandles
+	if released == nil {
+		released = []string{}
+	}
+	return map[string]interface{}{
+		"released_handles": released,
+		"heap_before":      engine.FormatBytes(int64(report.HeapBefore)),
+		"heap_after":       engine.FormatBytes(int64(report.HeapAfter)),
+	}, nil
 }
 
 // Helper methods
@@ -467,6 +484,24 @@ func (p *Profiler) Reset() {
 	p.operations = make(map[string]*ProfileOperation)
 }
 
+// Record adds the runtime calls of a command to their operations
+func (p *Profiler) Record(calls []engine.RuntimeCall) {
+	if !p.enabled {
+		return
+	}
+	for _, call := range calls {
+		op, ok := p.operations[call.Name]
+		if !ok {
+			op = &ProfileOperation{Name: call.Name}
+			p.operations[call.Name] = op
+		}
+		op.CallCount++
+		op.Duration += call.Duration
+		op.CPU += call.CPU
+		op.PeakRSS += call.PeakRSS
+	}
+}
+
 func (p *Profiler) GetReport() map[string]interface{} {
 	if !p.enabled {
 		return map[string]interface{}{
@@ -478,9 +513,23 @@ func (p *Profiler) GetReport() map[string]interface{} {
 	var totalOps int
 	var totalDuration time.Duration
 
+	operations := make([]*ProfileOperation, 0, len(p.operations))
 	for _, op := range p.operations {
 		totalOps += op.CallCount
 		totalDuration += op.Duration
+		operations = append(operations, op)
+	}
+	// The most expensive operations first
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Duration > operations[j].Duration })
+	report := make([]interface{}, len(operations))
+	for i, op := range operations {
+		report[i] = map[string]interface{}{
+			"name":     op.Name,
+			"calls":    op.CallCount,
+			"duration": op.Duration.String(),
+			"cpu":      op.CPU.String(),
+			"peak_rss": "+" + engine.FormatBytes(op.PeakRSS),
+		}
 	}
 
 	return map[string]interface{}{
@@ -489,7 +538,7 @@ func (p *Profiler) GetReport() map[string]interface{} {
 		"duration":         duration.String(),
 		"total_operations": totalOps,
 		"total_duration":   totalDuration.String(),
-		"operations":       p.operations,
+		"operations":       report,
 	}
 }
 
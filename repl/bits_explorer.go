@@ -0,0 +1,474 @@
+package repl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"funterm/errors"
+	"funterm/shared"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// bitsExplorerRowBytes is the number of bytes shown per line around the cursor
+const bitsExplorerRowBytes = 16
+
+// bitsSpec is a segment spec typed in the explorer, such as u16-le or bytes:4
+type bitsSpec struct {
+	text   string
+	kind   string // "u", "i", "f", "utf8", "utf16", "utf32", "bytes" or "bits"
+	size   int    // in bits; 0 for UTF specs, whose size depends on the data
+	little bool
+}
+
+// bitsField is a spec accepted with take, remembered to build a pattern
+type bitsField struct {
+	name   string
+	spec   bitsSpec
+	offset int
+	size   int
+}
+
+// BitsExplorer shows a bitstring with a cursor and decodes candidate segment
+// specs at the cursor, to work out unknown binary formats step by step
+type BitsExplorer struct {
+	data    []byte
+	length  int // in bits
+	cursor  int // bit offset
+	preview *bitsSpec
+	fields  []bitsField
+}
+
+// NewBitsExplorer creates an explorer for a bitstring, byte slice or string
+func NewBitsExplorer(value interface{}) (*BitsExplorer, error) {
+	switch v := value.(type) {
+	case *shared.BitstringObject:
+		return &BitsExplorer{data: v.Bytes(), length: v.Len()}, nil
+	case *funbit.BitString:
+		return &BitsExplorer{data: v.ToBytes(), length: int(v.Length())}, nil
+	case []byte:
+		return &BitsExplorer{data: v, length: len(v) * 8}, nil
+	case string:
+		return &BitsExplorer{data: []byte(v), length: len(v) * 8}, nil
+	}
+	return nil, errors.NewUserError("BITS_UNSUPPORTED_VALUE", fmt.Sprintf("cannot explore %T, expected a bitstring", value))
+}
+
+// parseBitsSpec parses u8, i16-le, u12, f32-be, utf8, utf16-le, bytes:4 or bits:3.
+// Integers and floats are big-endian unless -le is given.
+func parseBitsSpec(text string) (bitsSpec, error) {
+	spec := bitsSpec{text: text}
+	body := text
+	if strings.HasSuffix(body, "-le") {
+		spec.little = true
+		body = strings.TrimSuffix(body, "-le")
+	} else {
+		body = strings.TrimSuffix(body, "-be")
+	}
+
+	invalid := errors.NewUserError("BITS_INVALID_SPEC", fmt.Sprintf("invalid spec '%s', expected u<N>, i<N>, f16/f32/f64, utf8, utf16, utf32, bytes:<N> or bits:<N> (add -le or -be for byte order)", text))
+	switch {
+	case body == "utf8" || body == "utf16" || body == "utf32":
+		spec.kind = body
+		if body == "utf8" && spec.little {
+			return spec, invalid
+		}
+		return spec, nil
+	case strings.HasPrefix(body, "bytes:") || strings.HasPrefix(body, "bits:"):
+		parts := strings.SplitN(body, ":", 2)
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 || spec.little {
+			return spec, invalid
+		}
+		spec.kind = parts[0]
+		spec.size = n
+		if spec.kind == "bytes" {
+			spec.size *= 8
+		}
+		return spec, nil
+	case len(body) > 1 && strings.ContainsRune("uif", rune(body[0])):
+		n, err := strconv.Atoi(body[1:])
+		if err != nil || n <= 0 || n > 64 {
+			return spec, invalid
+		}
+		spec.kind = body[:1]
+		spec.size = n
+		if spec.kind == "f" && n != 16 && n != 32 && n != 64 {
+			return spec, invalid
+		}
+		if spec.little && n%8 != 0 {
+			return spec, errors.NewUserError("BITS_INVALID_SPEC", fmt.Sprintf("%s: little-endian values must be whole bytes", text))
+		}
+		return spec, nil
+	}
+	return spec, invalid
+}
+
+// decode reads the spec at offset and returns the value and its size in bits
+func (be *BitsExplorer) decode(spec bitsSpec, offset int) (interface{}, int, error) {
+	size := spec.size
+	if size == 0 {
+		size = be.utfSize(spec, offset)
+	}
+	if offset+size > be.length {
+		return nil, 0, errors.NewUserError("BITS_OUT_OF_RANGE", fmt.Sprintf("%s needs %d bits at bit %d, only %d left", spec.text, size, offset, be.length-offset))
+	}
+
+	raw := be.read(offset, size)
+	switch spec.kind {
+	case "u", "i":
+		n := be.integer(raw, size, spec.little)
+		if spec.kind == "i" && n.Bit(size-1) == 1 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+		}
+		if n.IsInt64() {
+			return n.Int64(), size, nil
+		}
+		return n, size, nil
+	case "f":
+		bits := be.integer(raw, size, spec.little).Uint64()
+		switch size {
+		case 16:
+			return shared.Float16ToFloat64(uint16(bits)), size, nil
+		case 32:
+			return float64(math.Float32frombits(uint32(bits))), size, nil
+		}
+		return math.Float64frombits(bits), size, nil
+	case "utf8":
+		r, _ := utf8.DecodeRune(raw)
+		return string(r), size, nil
+	case "utf16":
+		units := make([]uint16, size/16)
+		for i := range units {
+			units[i] = be.unit16(raw[i*2:], spec.little)
+		}
+		return string(utf16.Decode(units)), size, nil
+	case "utf32":
+		if spec.little {
+			return string(rune(binary.LittleEndian.Uint32(raw))), size, nil
+		}
+		return string(rune(binary.BigEndian.Uint32(raw))), size, nil
+	case "bytes":
+		return shared.NewBitstringObjectFromBytes(raw), size, nil
+	}
+	return shared.NewBitstringObject(funbit.NewBitStringFromBits(raw, uint(size))), size, nil
+}
+
+// utfSize returns the encoded length of the character at offset
+func (be *BitsExplorer) utfSize(spec bitsSpec, offset int) int {
+	switch spec.kind {
+	case "utf16":
+		if offset+16 <= be.length {
+			if unit := be.unit16(be.read(offset, 16), spec.little); unit >= 0xD800 && unit < 0xDC00 {
+				return 32
+			}
+		}
+		return 16
+	case "utf32":
+		return 32
+	}
+	if offset+8 > be.length {
+		return 8
+	}
+	first := be.read(offset, 8)[0]
+	switch {
+	case first>>5 == 0x6:
+		return 16
+	case first>>4 == 0xE:
+		return 24
+	case first>>3 == 0x1E:
+		return 32
+	}
+	return 8
+}
+
+// read copies size bits starting at offset into left-aligned bytes
+func (be *BitsExplorer) read(offset, size int) []byte {
+	out := make([]byte, (size+7)/8)
+	for i := 0; i < size; i++ {
+		bit := offset + i
+		if be.data[bit/8]>>(7-uint(bit%8))&1 == 1 {
+			out[i/8] |= 1 << (7 - uint(i%8))
+		}
+	}
+	return out
+}
+
+// integer interprets left-aligned bits as an unsigned number
+func (be *BitsExplorer) integer(raw []byte, size int, little bool) *big.Int {
+	if little {
+		reversed := make([]byte, len(raw))
+		for i, b := range raw {
+			reversed[len(raw)-1-i] = b
+		}
+		return new(big.Int).SetBytes(reversed)
+	}
+	n := new(big.Int).SetBytes(raw)
+	return n.Rsh(n, uint(len(raw)*8-size))
+}
+
+// unit16 reads a UTF-16 code unit
+func (be *BitsExplorer) unit16(raw []byte, little bool) uint16 {
+	if little {
+		return binary.LittleEndian.Uint16(raw)
+	}
+	return binary.BigEndian.Uint16(raw)
+}
+
+// Preview decodes a spec at the cursor without moving it
+func (be *BitsExplorer) Preview(text string) error {
+	spec, err := parseBitsSpec(text)
+	if err != nil {
+		return err
+	}
+	if _, _, err := be.decode(spec, be.cursor); err != nil {
+		return err
+	}
+	be.preview = &spec
+	return nil
+}
+
+// Take accepts the previewed spec as a field and moves the cursor past it
+func (be *BitsExplorer) Take(name string) error {
+	if be.preview == nil {
+		return errors.NewUserError("BITS_NO_PREVIEW", "type a spec such as u16-be first, then take it")
+	}
+	if name == "" {
+		name = "_"
+	}
+	if n := len(be.fields); n > 0 && be.cursor < be.fields[n-1].offset+be.fields[n-1].size {
+		return errors.NewUserError("BITS_OVERLAP", "the cursor is inside the last field taken; use back to undo it")
+	}
+	_, size, err := be.decode(*be.preview, be.cursor)
+	if err != nil {
+		return err
+	}
+	be.fields = append(be.fields, bitsField{name: name, spec: *be.preview, offset: be.cursor, size: size})
+	be.cursor += size
+	return nil
+}
+
+// Back undoes the last take
+func (be *BitsExplorer) Back() error {
+	if len(be.fields) == 0 {
+		return errors.NewUserError("BITS_NOTHING_TAKEN", "no fields taken yet")
+	}
+	last := be.fields[len(be.fields)-1]
+	be.fields = be.fields[:len(be.fields)-1]
+	be.cursor = last.offset
+	spec := last.spec
+	be.preview = &spec
+	return nil
+}
+
+// Seek moves the cursor to a bit offset, clamped to the data
+func (be *BitsExplorer) Seek(offset int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > be.length {
+		offset = be.length
+	}
+	be.cursor = offset
+}
+
+// Pattern returns a bitstring pattern for the fields taken so far; skipped
+// bits become _ segments and the bits after the cursor a rest segment
+func (be *BitsExplorer) Pattern() string {
+	segments := make([]string, 0, len(be.fields)+1)
+	end := 0
+	for _, field := range be.fields {
+		if field.offset > end {
+			segments = append(segments, fmt.Sprintf("_:%d", field.offset-end))
+		}
+		segments = append(segments, field.segment())
+		end = field.offset + field.size
+	}
+	if end < be.length {
+		segments = append(segments, "rest/bits")
+	}
+	return "<<" + strings.Join(segments, ", ") + ">>"
+}
+
+// segment renders a field as a pattern segment
+func (f bitsField) segment() string {
+	var specifiers []string
+	size := ""
+	switch f.spec.kind {
+	case "u", "i", "f":
+		size = strconv.Itoa(f.spec.size)
+		if f.spec.little {
+			specifiers = append(specifiers, "little")
+		}
+		if f.spec.kind == "i" {
+			specifiers = append(specifiers, "signed")
+		}
+		if f.spec.kind == "f" {
+			specifiers = append(specifiers, "float")
+		}
+	case "utf8", "utf16", "utf32":
+		if f.spec.little {
+			specifiers = append(specifiers, "little")
+		}
+		specifiers = append(specifiers, f.spec.kind)
+	case "bytes":
+		size = strconv.Itoa(f.spec.size / 8)
+		specifiers = append(specifiers, "binary")
+	case "bits":
+		size = strconv.Itoa(f.spec.size)
+		specifiers = append(specifiers, "bits")
+	}
+	segment := f.name
+	if size != "" {
+		segment += ":" + size
+	}
+	if len(specifiers) > 0 {
+		segment += "/" + strings.Join(specifiers, "-")
+	}
+	return segment
+}
+
+// Render prints the bytes around the cursor, the taken fields and the preview
+func (be *BitsExplorer) Render(w io.Writer) {
+	fmt.Fprintf(w, "bit %d of %d (byte %d", be.cursor, be.length, be.cursor/8)
+	if be.cursor%8 != 0 {
+		fmt.Fprintf(w, " + %d bits", be.cursor%8)
+	}
+	fmt.Fprintln(w, ")")
+
+	if len(be.data) > 0 {
+		// The line holding the cursor, with the cursor byte in brackets
+		row := be.cursor / 8 / bitsExplorerRowBytes * bitsExplorerRowBytes
+		if row >= len(be.data) {
+			row = (len(be.data) - 1) / bitsExplorerRowBytes * bitsExplorerRowBytes
+		}
+		var line strings.Builder
+		fmt.Fprintf(&line, "%08x ", row)
+		for i := row; i < row+bitsExplorerRowBytes && i < len(be.data); i++ {
+			if i == be.cursor/8 && be.cursor < be.length {
+				fmt.Fprintf(&line, "[%02x]", be.data[i])
+			} else {
+				fmt.Fprintf(&line, " %02x ", be.data[i])
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+
+	nameWidth, specWidth := 0, 0
+	for _, field := range be.fields {
+		nameWidth = max(nameWidth, len(field.name))
+		specWidth = max(specWidth, len(field.spec.text))
+	}
+	for _, field := range be.fields {
+		value, _, _ := be.decode(field.spec, field.offset)
+		fmt.Fprintf(w, "  %-*s  %-*s  bits %d..%d = %s\n", nameWidth, field.name, specWidth, field.spec.text, field.offset, field.offset+field.size-1, formatBitsValue(value))
+	}
+	if be.preview != nil {
+		if value, size, err := be.decode(*be.preview, be.cursor); err == nil {
+			fmt.Fprintf(w, "  > %s = %s (%d bits)\n", be.preview.text, formatBitsValue(value), size)
+		}
+	}
+	if len(be.fields) > 0 {
+		fmt.Fprintf(w, "pattern: %s\n", be.Pattern())
+	}
+}
+
+// HandleKey applies an explorer command or previews a spec; it returns true when the explorer should close
+func (be *BitsExplorer) HandleKey(input string) (bool, error) {
+	input = strings.TrimSpace(input)
+	parts := strings.Fields(input)
+	if len(parts) == 0 {
+		return false, nil
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(input, parts[0]))
+
+	switch parts[0] {
+	case "q", "quit":
+		return true, nil
+	case "t", "take":
+		return false, be.Take(arg)
+	case "b", "back":
+		return false, be.Back()
+	case "seek", "@":
+		offset, err := strconv.Atoi(arg)
+		if err != nil {
+			return false, errors.NewUserError("BITS_INVALID_OFFSET", fmt.Sprintf("invalid bit offset: %s", arg))
+		}
+		be.Seek(offset)
+	default:
+		// +N and -N move the cursor by N bits, anything else is a spec
+		if offset, err := strconv.Atoi(input); err == nil && (input[0] == '+' || input[0] == '-') {
+			be.Seek(be.cursor + offset)
+			return false, nil
+		}
+		return false, be.Preview(input)
+	}
+	return false, nil
+}
+
+// formatBitsValue shows decoded values, with hex for non-negative integers
+func formatBitsValue(value interface{}) string {
+	switch v := value.(type) {
+	case int64:
+		if v >= 10 {
+			return fmt.Sprintf("%d (0x%x)", v, v)
+		}
+		return strconv.FormatInt(v, 10)
+	case string:
+		return strconv.Quote(v)
+	}
+	return shared.FormatValueForDisplay(value)
+}
+
+// printBitsExplorerHelp shows the commands understood by the explorer
+func printBitsExplorerHelp(w io.Writer) {
+	fmt.Fprintln(w, "Type a spec to decode it at the cursor: u8, i16-le, u12, f32-be, utf8, utf16-le, bytes:4, bits:3")
+	fmt.Fprintln(w, "Explorer commands: t [name] take it and advance, b back, +N/-N move N bits, @ <bit> seek, q quit")
+}
+
+// exploreBits implements the :bits explore command. The argument is evaluated
+// as a funterm expression, as with :view.
+func (r *REPL) exploreBits(expr string) error {
+	if expr == "" {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :bits explore <expression>")
+	}
+
+	value, _, _, err := r.engine.Execute(expr)
+	if err != nil {
+		return err
+	}
+	be, err := NewBitsExplorer(value)
+	if err != nil {
+		return err
+	}
+
+	be.Render(os.Stdout)
+	if r.readLine == nil {
+		return nil
+	}
+
+	printBitsExplorerHelp(os.Stdout)
+	for {
+		input, err := r.readLine("bits> ")
+		if err != nil {
+			// Ctrl+C or EOF simply leave the explorer
+			return nil
+		}
+		done, err := be.HandleKey(input)
+		if done {
+			return nil
+		}
+		if err != nil {
+			r.displayError(err)
+			continue
+		}
+		be.Render(os.Stdout)
+	}
+}
@@ -0,0 +1,128 @@
+package repl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// How a bitstring result is shown, set with :format
+const (
+	bitsFormatSummary = "summary" // length, the first bytes in hex and a UTF-8 preview (default)
+	bitsFormatHex     = "hex"     // every byte in hex
+	bitsFormatBin     = "bin"     // every bit, grouped by byte
+	bitsFormatRaw     = "raw"     // <<1,2,3>>, as print() shows it
+)
+
+// summaryBytes is how many bytes the summary shows in hex
+const summaryBytes = 16
+
+// handleFormatCommand implements :format [summary|hex|bin|raw]
+func (r *REPL) handleFormatCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Printf("Bitstrings are shown as %s\n", r.bitsFormat())
+		return nil
+	}
+	switch args[0] {
+	case bitsFormatSummary, bitsFormatHex, bitsFormatBin, bitsFormatRaw:
+		r.bitsView = args[0]
+	default:
+		return errors.NewUserError("INVALID_COMMAND", "usage: :format [summary|hex|bin|raw]")
+	}
+	return nil
+}
+
+// bitsFormat returns the :format in effect
+func (r *REPL) bitsFormat() string {
+	if r.bitsView == "" {
+		return bitsFormatSummary
+	}
+	return r.bitsView
+}
+
+// formatBitstring shows a bitstring result the way :format asks for
+func (r *REPL) formatBitstring(bits *shared.BitstringObject) string {
+	data, length := bits.Bytes(), bits.Len()
+	switch r.bitsFormat() {
+	case bitsFormatHex:
+		return fmt.Sprintf("%s %s", bitsLength(length), hexBytes(data, length))
+	case bitsFormatBin:
+		return fmt.Sprintf("%s %s", bitsLength(length), binBytes(data, length))
+	case bitsFormatRaw:
+		return shared.FormatValueForDisplay(bits)
+	}
+
+	summary := bitsLength(length)
+	if length == 0 {
+		return summary
+	}
+	shown, more := data, 0
+	if len(data) > summaryBytes {
+		shown, more = data[:summaryBytes], len(data)-summaryBytes
+	}
+	summary += " " + hexBytes(shown, min(length, summaryBytes*8))
+	if more > 0 {
+		summary += fmt.Sprintf(" ... (%d more bytes)", more)
+	}
+	if preview, ok := utf8Preview(data, length); ok {
+		summary += " " + preview
+	}
+	return summary
+}
+
+// bitsLength describes the length of a bitstring, "<<6 bytes, 48 bits>>"
+func bitsLength(length int) string {
+	switch {
+	case length%8 != 0:
+		return fmt.Sprintf("<<%d bits>>", length)
+	case length == 8:
+		return "<<1 byte, 8 bits>>"
+	default:
+		return fmt.Sprintf("<<%d bytes, %d bits>>", length/8, length)
+	}
+}
+
+// hexBytes shows the first length bits of data in hex, a trailing partial
+// byte as its bits: "0a ff +101"
+func hexBytes(data []byte, length int) string {
+	parts := make([]string, 0, length/8+1)
+	for i := 0; i < length/8; i++ {
+		parts = append(parts, fmt.Sprintf("%02x", data[i]))
+	}
+	if rest := length % 8; rest > 0 {
+		parts = append(parts, "+"+fmt.Sprintf("%08b", data[length/8])[:rest])
+	}
+	return strings.Join(parts, " ")
+}
+
+// binBytes shows the first length bits of data, a group of 8 per byte
+func binBytes(data []byte, length int) string {
+	parts := make([]string, 0, length/8+1)
+	for i := 0; i < length; i += 8 {
+		bits := fmt.Sprintf("%08b", data[i/8])
+		parts = append(parts, bits[:min(8, length-i)])
+	}
+	return strings.Join(parts, " ")
+}
+
+// utf8Preview quotes whole-byte data that is valid UTF-8 text
+func utf8Preview(data []byte, length int) (string, bool) {
+	if length%8 != 0 || !utf8.Valid(data) {
+		return "", false
+	}
+	text := string(data)
+	for _, r := range text {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	if runes := []rune(text); len(runes) > summaryBytes*4 {
+		return strconv.Quote(string(runes[:summaryBytes*4])) + "...", true
+	}
+	return strconv.Quote(text), true
+}
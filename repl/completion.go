@@ -193,8 +193,11 @@ func (rc *RuntimeCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 // getLanguageAliases возвращает маппинг алиасов языков к их полным названиям
 func (rc *RuntimeCompleter) getLanguageAliases() map[string]string {
 	return map[string]string{
-		"py": "python",
-		"js": "node", // js -> node (JavaScript)
+		"py":  "python",
+		"js":  "node", // js -> node (JavaScript)
+		"pl":  "perl",
+		"erl": "erlang",
+		"ex":  "elixir",
 	}
 }
 
@@ -579,7 +582,7 @@ func (rc *RuntimeCompleter) fallbackCompletion(input string) ([][]rune, int) {
 	}
 
 	// Базовые языки для fallback
-	languages := []string{"python", "lua", "js", "node", "go"}
+	languages := []string{"python", "lua", "js", "node", "go", "perl", "pl", "erlang", "erl", "elixir", "ex"}
 	var suggestions [][]rune
 
 	for _, lang := range languages {
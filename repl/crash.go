@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"funterm/errors"
+)
+
+// recoverPanic turns a panic of the parser or the engine while running code
+// into an INTERNAL_ERROR at the statement it happened on, so the session
+// goes on. The stack is written to the crash directory for a bug report
+func (r *REPL) recoverPanic(code string, recovered interface{}) error {
+	stack := debug.Stack()
+	pos := r.engine.PanicPosition()
+	r.engine.RecoverFromPanic()
+
+	message := fmt.Sprintf("internal error: %v", recovered)
+	if path, err := writeCrashReport(r.crashDir, code, recovered, stack); err == nil && path != "" {
+		message += " (report saved to " + path + ")"
+	} else if err != nil && r.verbose {
+		fmt.Printf("DEBUG: failed to write crash report: %v\n", err)
+	}
+	if pos.Line > 0 {
+		return errors.NewSystemErrorWithPosition("INTERNAL_ERROR", message, pos.Line, pos.Column)
+	}
+	return errors.NewSystemError("INTERNAL_ERROR", message)
+}
+
+// writeCrashReport saves the input, the panic and its stack to a new file in
+// dir and returns its path, empty when dir is empty
+func writeCrashReport(dir, code string, recovered interface{}, stack []byte) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	file, err := os.CreateTemp(dir, "crash-"+now.Format("20060102-150405")+"-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	fmt.Fprintf(file, "time: %s\ngo: %s %s/%s\npanic: %v\n\ninput:\n%s\n\n%s", now.Format(time.RFC3339), runtime.Version(), runtime.GOOS, runtime.GOARCH, recovered, code, stack)
+	return file.Name(), nil
+}
@@ -0,0 +1,37 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"funterm/errors"
+)
+
+// showDoc implements :doc [language.function]: it prints the comments
+// written right above the code block that defined the function, or lists
+// the functions that have them
+func (r *REPL) showDoc(args []string) error {
+	if len(args) > 1 {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :doc [language.function]")
+	}
+	if len(args) == 0 {
+		names := r.engine.DocNames()
+		if len(names) == 0 {
+			fmt.Println("No documented functions; comment the line above a code block to document it")
+			return nil
+		}
+		fmt.Println("Documented functions:")
+		for _, name := range names {
+			doc, _ := r.engine.Doc(name)
+			summary, _, _ := strings.Cut(doc, "\n")
+			fmt.Printf("  %-24s %s\n", name, summary)
+		}
+		return nil
+	}
+	doc, ok := r.engine.Doc(args[0])
+	if !ok {
+		return errors.NewUserError("NO_DOC", fmt.Sprintf("no documentation for %s", args[0]))
+	}
+	fmt.Println(doc)
+	return nil
+}
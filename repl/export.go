@@ -0,0 +1,67 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"funterm/errors"
+)
+
+// exportHistory implements :export-history <file>: it writes the statements
+// of the session that ran without error to a script that replays the
+// session with `funterm -exec <file>`. Colon commands and shell commands are
+// not part of it
+func (r *REPL) exportHistory(args []string) error {
+	if len(args) != 1 {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :export-history <file>")
+	}
+	if len(r.session) == 0 {
+		return errors.NewUserError("EMPTY_HISTORY", "no statements have run successfully in this session")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Exported from a funterm session on %s\n", time.Now().Format("2006-01-02 15:04"))
+	for _, statement := range r.session {
+		sb.WriteString(statement)
+		if !strings.HasSuffix(statement, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if err := os.WriteFile(args[0], []byte(sb.String()), 0644); err != nil {
+		return errors.NewSystemError("FILE_WRITE_ERROR", fmt.Sprintf("failed to write %s: %v", args[0], err))
+	}
+	fmt.Printf("Exported %d statement(s) to %s\n", len(r.session), args[0])
+	return nil
+}
+
+// exportVariables implements :export-vars <file> [name...]: it writes the
+// session variables with their types to a JSON file for :import-vars
+func (r *REPL) exportVariables(args []string) error {
+	if len(args) < 1 {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :export-vars <file> [name...]")
+	}
+	exported, skipped, err := r.engine.ExportVariables(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+	for _, variable := range skipped {
+		fmt.Printf("Skipped %s (%s only lives in this session)\n", variable.Name, variable.Type)
+	}
+	fmt.Printf("Exported %d variable(s) to %s\n", len(exported), args[0])
+	return nil
+}
+
+// importVariables implements :import-vars <file>
+func (r *REPL) importVariables(args []string) error {
+	if len(args) != 1 {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :import-vars <file>")
+	}
+	imported, err := r.engine.ImportVariables(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d variable(s) from %s: %s\n", len(imported), args[0], strings.Join(imported, ", "))
+	return nil
+}
@@ -0,0 +1,19 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// confirmCall asks whether a call matching a guarded pattern may run
+func (r *REPL) confirmCall(call string) bool {
+	if r.readLine == nil {
+		return false
+	}
+	answer, err := r.readLine(fmt.Sprintf("Allow %s? [y/N] ", call))
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
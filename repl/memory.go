@@ -0,0 +1,77 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"funterm/engine"
+	"funterm/errors"
+)
+
+// printVariables shows session variables with their approximate memory usage
+func (r *REPL) printVariables() error {
+	usage := r.engine.VariableUsage()
+	if len(usage) == 0 {
+		fmt.Println("No variables defined")
+		return nil
+	}
+
+	nameWidth, typeWidth := len("Name"), len("Type")
+	for _, v := range usage {
+		if len(v.Name) > nameWidth {
+			nameWidth = len(v.Name)
+		}
+		if len(v.Type) > typeWidth {
+			typeWidth = len(v.Type)
+		}
+	}
+
+	var total int64
+	fmt.Printf("  %-*s  %-*s  %s\n", nameWidth, "Name", typeWidth, "Type", "Size")
+	for _, v := range usage {
+		total += v.Bytes
+		fmt.Printf("  %-*s  %-*s  %s\n", nameWidth, v.Name, typeWidth, v.Type, engine.FormatBytes(v.Bytes))
+	}
+
+	fmt.Printf("Total: %s in %d variable(s)", engine.FormatBytes(total), len(usage))
+	if budget := r.engine.MemoryBudget(); budget > 0 {
+		fmt.Printf(" (budget %s)", engine.FormatBytes(budget))
+	}
+	fmt.Println()
+	return nil
+}
+
+// ReportMemoryWarnings prints leaked handle and memory budget warnings after execution
+func (r *REPL) ReportMemoryWarnings() {
+	for _, warning := range r.engine.CheckMemory() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// undoAssignments implements :undo [n], reverting the last n assignments of
+// session variables
+func (r *REPL) undoAssignments(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 1 || len(args) > 1 {
+			return errors.NewUserError("INVALID_COMMAND", "usage: :undo [count]")
+		}
+		n = parsed
+	}
+
+	undone := r.engine.UndoAssignments(n)
+	if len(undone) == 0 {
+		fmt.Println("Nothing to undo")
+		return nil
+	}
+	for _, assignment := range undone {
+		if assignment.Removed {
+			fmt.Printf("Removed %s\n", assignment.Name)
+		} else {
+			fmt.Printf("Restored the previous value of %s\n", assignment.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/chzyer/readline"
+)
+
+// paletteKey stands for Ctrl+P in the input of readline, see paletteInput
+const paletteKey = '\uE000'
+
+// paletteRows is the number of matches shown under the search line
+const paletteRows = 10
+
+// paletteItem is an entry of the command palette
+type paletteItem struct {
+	kind string // "history", "snippet", "command" or "function"
+	text string // What is inserted at the prompt
+	note string // Shown after the text and searched as well
+}
+
+// paletteCommands are the colon commands offered by the palette; the ones
+// that take an argument end in a space
+var paletteCommands = []paletteItem{
+	{"command", ":help", "show the commands"},
+	{"command", ":quit", "exit the REPL"},
+	{"command", ":languages", "list available languages"},
+	{"command", ":history", "show command history"},
+	{"command", ":clear", "clear the screen"},
+	{"command", ":version", "show version information"},
+	{"command", ":run ", "execute code from a file"},
+	{"command", ":jobs", "list background jobs"},
+	{"command", ":vars", "list variables with their type and memory usage"},
+	{"command", ":gc", "release unreferenced proxy handles"},
+	{"command", ":undo ", "revert the last assignments of variables"},
+	{"command", ":time on", "show timing after each statement"},
+	{"command", ":time off", "stop showing timing"},
+	{"command", ":trace on", "show each language call"},
+	{"command", ":trace off", "stop tracing language calls"},
+	{"command", ":format ", "show bitstring results as hex, bin, raw or summary"},
+	{"command", ":export-history ", "save the statements that ran as a .su script"},
+	{"command", ":export-vars ", "save the variables with their types as JSON"},
+	{"command", ":import-vars ", "set the variables saved by :export-vars"},
+	{"command", ":doc ", "show the comments above a function"},
+	{"command", ":view ", "show an array of maps as a table"},
+	{"command", ":bits explore ", "decode a bitstring spec by spec"},
+}
+
+// commandPalette shows the palette when Ctrl+P is pressed at the prompt and
+// hands the chosen entry to readline, which inserts it at the cursor
+type commandPalette struct {
+	rl     *readline.Instance
+	items  func() []paletteItem
+	closed bool   // The palette was just closed, the prompt has to be redrawn
+	chosen []rune // The entry to insert
+}
+
+// filterKey is the FuncFilterInputRune of readline. The palette runs while
+// readline waits for the key; the key is then replaced by a bell, which
+// readline ignores, so that onChange can insert the entry
+func (p *commandPalette) filterKey(key rune) (rune, bool) {
+	if key != paletteKey {
+		return key, true
+	}
+	p.rl.Clean()
+	choice, ok := p.run()
+	p.closed = true
+	if ok {
+		p.chosen = []rune(choice)
+	}
+	return readline.CharBell, true
+}
+
+// onChange is the Listener of readline: it inserts the chosen entry at the cursor
+func (p *commandPalette) onChange(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if !p.closed {
+		return nil, 0, false
+	}
+	p.closed = false
+	chosen := p.chosen
+	p.chosen = nil
+	inserted := make([]rune, 0, len(line)+len(chosen))
+	inserted = append(append(append(inserted, line[:pos]...), chosen...), line[pos:]...)
+	return inserted, pos + len(chosen), true
+}
+
+// run shows the palette below the prompt until an entry is chosen with Enter
+// or the palette is closed with Ctrl+C, Ctrl+G or Ctrl+D
+func (p *commandPalette) run() (string, bool) {
+	items := p.items()
+	query := []rune{}
+	matches := filterPalette(items, "")
+	selected, top := 0, 0
+	for {
+		if selected >= len(matches) {
+			selected = len(matches) - 1
+		}
+		if selected < 0 {
+			selected = 0
+		}
+		if selected < top {
+			top = selected
+		} else if selected >= top+paletteRows {
+			top = selected - paletteRows + 1
+		}
+		p.render(string(query), matches, selected, top, len(items))
+
+		key := p.rl.Terminal.ReadRune()
+		switch key {
+		case readline.CharEnter, readline.CharCtrlJ, readline.CharInterrupt, readline.CharDelete:
+			// The terminal stops reading after these keys until it is kicked
+			p.rl.Terminal.KickRead()
+		}
+		switch key {
+		case 0, readline.CharInterrupt, readline.CharBell, readline.CharDelete:
+			p.clear()
+			return "", false
+		case readline.CharEnter, readline.CharCtrlJ:
+			p.clear()
+			if len(matches) == 0 {
+				return "", false
+			}
+			return matches[selected].text, true
+		case readline.CharPrev, paletteKey:
+			selected--
+		case readline.CharNext, readline.CharTab:
+			selected++
+		case readline.CharBackspace, readline.CharCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				matches, selected, top = filterPalette(items, string(query)), 0, 0
+			}
+		case readline.CharCtrlU:
+			query = query[:0]
+			matches, selected, top = filterPalette(items, ""), 0, 0
+		default:
+			if unicode.IsPrint(key) {
+				query = append(query, key)
+				matches, selected, top = filterPalette(items, string(query)), 0, 0
+			}
+		}
+	}
+}
+
+// render draws the search line and the visible matches, leaving the cursor
+// at the end of the search line. The terminal is in raw mode, so lines end
+// in \r\n
+func (p *commandPalette) render(query string, matches []paletteItem, selected, top, total int) {
+	width := readline.GetScreenWidth()
+	if width <= 0 {
+		width = 80
+	}
+	var sb strings.Builder
+	search := fitPaletteLine(fmt.Sprintf("palette (%d/%d)> %s", len(matches), total, query), width)
+	sb.WriteString("\r\033[J")
+	sb.WriteString(search)
+	rows := 0
+	for i := top; i < len(matches) && i < top+paletteRows; i++ {
+		item := matches[i]
+		line := fmt.Sprintf("  %-8s %s", item.kind, strings.ReplaceAll(item.text, "\n", "⏎"))
+		if item.note != "" {
+			line += "  - " + item.note
+		}
+		line = fitPaletteLine(line, width)
+		if i == selected {
+			line = "\033[7m" + line + "\033[0m"
+		}
+		sb.WriteString("\r\n" + line)
+		rows++
+	}
+	if len(matches) == 0 {
+		sb.WriteString("\r\n  no matches")
+		rows++
+	}
+	fmt.Fprintf(&sb, "\033[%dA\r", rows)
+	if column := len([]rune(search)); column > 0 {
+		fmt.Fprintf(&sb, "\033[%dC", column)
+	}
+	os.Stdout.WriteString(sb.String())
+}
+
+// clear removes the palette; readline then draws the prompt where it was
+func (p *commandPalette) clear() {
+	os.Stdout.WriteString("\r\033[J")
+}
+
+// fitPaletteLine cuts a line to the width of the terminal
+func fitPaletteLine(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) < width {
+		return line
+	}
+	return string(runes[:width-2]) + "…"
+}
+
+// filterPalette returns the items matching query, best match first; items
+// that match equally well keep their order
+func filterPalette(items []paletteItem, query string) []paletteItem {
+	type scored struct {
+		item  paletteItem
+		score int
+	}
+	var found []scored
+	for _, item := range items {
+		score, ok := fuzzyScore(query, item.text)
+		if noteScore, noteOk := fuzzyScore(query, item.note); noteOk {
+			// The name of a snippet counts as much as its code, other notes
+			// count for less than the text
+			if item.kind != "snippet" {
+				noteScore /= 2
+			}
+			if !ok || noteScore > score {
+				score, ok = noteScore, true
+			}
+		}
+		if ok {
+			found = append(found, scored{item, score})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+	matches := make([]paletteItem, len(found))
+	for i, f := range found {
+		matches[i] = f.item
+	}
+	return matches
+}
+
+// fuzzyScore reports whether the characters of query appear in text in
+// order, ignoring case. Characters that follow each other in text or start
+// a word score higher, so "exv" ranks :export-vars above :export-history
+func fuzzyScore(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+	score, matched, previous := 0, 0, -2
+	for i := 0; i < len(t) && matched < len(q); i++ {
+		if t[i] != q[matched] {
+			continue
+		}
+		score++
+		if i == previous+1 {
+			score += 4
+		}
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 2
+		}
+		previous = i
+		matched++
+	}
+	if matched < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
+// paletteItems collects the entries of the palette: the history, newest
+// first, the snippets of the config, the colon commands and the functions
+// of the runtimes that are running
+func (r *REPL) paletteItems() []paletteItem {
+	var items []paletteItem
+	seen := make(map[string]bool)
+	add := func(item paletteItem) {
+		if item.text == "" || seen[item.kind+"\x00"+item.text] {
+			return
+		}
+		seen[item.kind+"\x00"+item.text] = true
+		items = append(items, item)
+	}
+
+	history := r.historyEntries()
+	for i := len(history) - 1; i >= 0; i-- {
+		add(paletteItem{kind: "history", text: history[i]})
+	}
+
+	names := make([]string, 0, len(r.snippets))
+	for name := range r.snippets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(paletteItem{kind: "snippet", text: r.snippets[name], note: name})
+	}
+
+	for _, command := range paletteCommands {
+		add(command)
+	}
+
+	for _, name := range r.engine.DocNames() {
+		add(paletteItem{kind: "function", text: name + "(", note: "documented"})
+	}
+	for _, rt := range r.engine.GetRuntimeManager().GetAllRuntimes() {
+		if !rt.IsReady() {
+			continue
+		}
+		language := rt.GetName()
+		for _, function := range rt.GetUserDefinedFunctions() {
+			add(paletteItem{kind: "function", text: language + "." + function + "(", note: "defined in this session"})
+		}
+		for _, module := range rt.GetModules() {
+			for _, function := range rt.GetModuleFunctions(module) {
+				add(paletteItem{kind: "function", text: language + "." + module + "." + function + "(", note: language + " " + module})
+			}
+		}
+	}
+	return items
+}
+
+// historyEntries reads the lines of the history file, oldest first
+func (r *REPL) historyEntries() []string {
+	file, err := os.Open(r.historyFile)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package repl
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/chzyer/readline"
+)
+
+// paletteInput is the terminal input of readline with Ctrl+P replaced by
+// paletteKey; readline turns the Up arrow into Ctrl+P, so the key can only
+// be told apart before it does
+func paletteInput() io.ReadCloser {
+	return &paletteKeyReader{ReadCloser: readline.NewCancelableStdin(readline.Stdin)}
+}
+
+// paletteKeyReader replaces the Ctrl+P byte with paletteKey
+type paletteKeyReader struct {
+	io.ReadCloser
+	pending []byte
+}
+
+func (p *paletteKeyReader) Read(buf []byte) (int, error) {
+	if len(p.pending) > 0 {
+		n := copy(buf, p.pending)
+		p.pending = p.pending[n:]
+		return n, nil
+	}
+	n, err := p.ReadCloser.Read(buf)
+	if bytes.IndexByte(buf[:n], readline.CharPrev) < 0 {
+		return n, err
+	}
+	data := bytes.ReplaceAll(buf[:n], []byte{readline.CharPrev}, []byte(string(paletteKey)))
+	n = copy(buf, data)
+	p.pending = data[n:]
+	return n, err
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package repl
+
+import "io"
+
+// paletteInput returns nil on Windows: the console reports the Up arrow as
+// Ctrl+P, so Ctrl+P keeps going through the history there
+func paletteInput() io.ReadCloser {
+	return nil
+}
@@ -0,0 +1,139 @@
+package repl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-parser/pkg/ast"
+)
+
+// runPrelude executes the user prelude before the first prompt. A failing
+// prelude is reported but doesn't stop the REPL
+func (r *REPL) runPrelude() {
+	if r.preludePath == "" {
+		return
+	}
+	content, err := os.ReadFile(r.preludePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Warning: failed to read prelude %s: %v\n", r.preludePath, err)
+		}
+		return
+	}
+
+	statement, err := r.loadPrelude(content)
+	if err != nil {
+		fmt.Printf("Error in prelude %s:\n", r.preludePath)
+		r.displayError(err)
+		return
+	}
+	result, _, _, err := r.engine.ExecuteParsed(statement)
+	if err != nil {
+		fmt.Printf("Error in prelude %s:\n", r.preludePath)
+		r.displayError(err)
+		return
+	}
+	// A script returns its collected printed output; values of definitions aren't shown
+	if output, ok := result.(string); ok && output != "" {
+		fmt.Println(output)
+	}
+}
+
+// loadPrelude returns the parsed prelude, from the cache if it holds a tree
+// for this content, parsing and caching it otherwise. The cache is best
+// effort: any problem with it falls back to parsing
+func (r *REPL) loadPrelude(content []byte) (ast.Statement, error) {
+	cachePath := r.preludeCachePath(content)
+	if cachePath != "" {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			if statement, err := ast.UnmarshalTree(data); err == nil {
+				if r.verbose {
+					fmt.Printf("DEBUG: prelude loaded from cache %s\n", cachePath)
+				}
+				// Mark the entry as used so pruning keeps it
+				now := time.Now()
+				_ = os.Chtimes(cachePath, now, now)
+				return statement, nil
+			}
+		}
+	}
+
+	statement, err := r.engine.Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	if cachePath != "" {
+		if data, err := ast.MarshalTree(statement); err == nil {
+			if err := writeFileAtomic(cachePath, data); err != nil && r.verbose {
+				fmt.Printf("DEBUG: failed to cache prelude: %v\n", err)
+			}
+			pruneCache(r.preludeCacheDir)
+		}
+	}
+	return statement, nil
+}
+
+// preludeCachePath names the cache entry by a hash of the prelude, the parser
+// options and the funterm binary, so edits, parser configuration changes and
+// upgrades all miss the cache. Empty disables caching
+func (r *REPL) preludeCachePath(content []byte) string {
+	if r.preludeCacheDir == "" {
+		return ""
+	}
+	hash := sha256.New()
+	hash.Write(content)
+	fmt.Fprintf(hash, "\x00%v", r.parserHandlers)
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			fmt.Fprintf(hash, "\x00%s\x00%d\x00%d", executable, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return filepath.Join(r.preludeCacheDir, hex.EncodeToString(hash.Sum(nil))+".ast")
+}
+
+// preludeCacheMaxAge is how long an unused cache entry is kept
+const preludeCacheMaxAge = 30 * 24 * time.Hour
+
+// pruneCache removes the entries of earlier versions of the prelude that
+// haven't been used for preludeCacheMaxAge
+func pruneCache(dir string) {
+	entries, err := filepath.Glob(filepath.Join(dir, "*.ast"))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := os.Stat(entry); err == nil && time.Since(info.ModTime()) > preludeCacheMaxAge {
+			os.Remove(entry)
+		}
+	}
+}
+
+// writeFileAtomic writes through a temporary file, so a concurrently starting
+// REPL never reads a half-written entry
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
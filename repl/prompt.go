@@ -0,0 +1,114 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/chzyer/readline/runes"
+)
+
+// promptVariable matches a template variable such as {cwd}
+var promptVariable = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// lastCommand is the outcome of the previous command, shown by the
+// {last_duration} and {error_state} prompt variables
+type lastCommand struct {
+	ran      bool
+	duration time.Duration
+	failed   bool
+}
+
+// renderPrompt fills in the template variables of a prompt:
+//
+//	{cwd}             working directory, with the home directory as ~
+//	{active_runtimes} runtimes ready to run code, e.g. "lua,node,python"
+//	{last_duration}   run time of the previous command, empty before the first
+//	{error_state}     ✗ if the previous command failed, empty otherwise
+//
+// Unknown variables are left as they are
+func (r *REPL) renderPrompt(template string) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	return promptVariable.ReplaceAllStringFunc(template, func(variable string) string {
+		switch variable {
+		case "{cwd}":
+			return promptCwd()
+		case "{active_runtimes}":
+			return strings.Join(r.engine.ActiveRuntimes(), ",")
+		case "{last_duration}":
+			if !r.last.ran {
+				return ""
+			}
+			return formatPromptDuration(r.last.duration)
+		case "{error_state}":
+			if r.last.failed {
+				return "✗"
+			}
+			return ""
+		}
+		return variable
+	})
+}
+
+// recordCommand remembers how the command that started at start ended
+func (r *REPL) recordCommand(start time.Time, err error) {
+	r.last = lastCommand{ran: true, duration: time.Since(start), failed: err != nil}
+}
+
+func promptCwd() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "?"
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if cwd == home {
+			return "~"
+		}
+		if strings.HasPrefix(cwd, home+string(filepath.Separator)) {
+			return "~" + cwd[len(home):]
+		}
+	}
+	return cwd
+}
+
+// formatPromptDuration shows milliseconds below a second and tenths of a
+// second above: 42ms, 1.3s, 2m5.1s
+func formatPromptDuration(d time.Duration) string {
+	if d < time.Second {
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// rightPromptPainter draws the right prompt at the right edge of the input
+// line. readline has no right prompt, so it is painted after the input and
+// the cursor is moved back to the end of the input. It is left out when the
+// input gets too long to leave room for it, and once the line is submitted
+type rightPromptPainter struct {
+	left  string // rendered left prompt
+	right string // rendered right prompt, empty hides it
+}
+
+func (p *rightPromptPainter) Paint(line []rune, _ int) []rune {
+	if p.right == "" || (len(line) > 0 && line[len(line)-1] == '\n') {
+		return line
+	}
+	rightWidth := runes.WidthAll(runes.ColorFilter([]rune(p.right)))
+	// One column is kept free so the terminal doesn't wrap the line
+	padding := readline.GetScreenWidth() - runes.WidthAll(runes.ColorFilter([]rune(p.left))) - runes.WidthAll(line) - rightWidth - 1
+	if padding < 1 {
+		return line
+	}
+	painted := make([]rune, 0, len(line)+padding+len(p.right)+8)
+	painted = append(painted, line...)
+	painted = append(painted, []rune(strings.Repeat(" ", padding))...)
+	painted = append(painted, []rune(p.right)...)
+	painted = append(painted, []rune("\033["+strconv.Itoa(padding+rightWidth)+"D")...)
+	return painted
+}
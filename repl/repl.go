@@ -8,6 +8,7 @@ import (
 	"funterm/errors"
 	"funterm/factory"
 	"funterm/jobmanager"
+	"funterm/runtime"
 	"funterm/shared"
 	"io"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/chzyer/readline"
+	"go-parser/pkg/parser"
 )
 
 // History settings are now configurable via REPLConfig
@@ -35,13 +37,26 @@ type REPL struct {
 	performanceOptimizer *PerformanceOptimizer
 	optimizer            *PerformanceOptimizer // Alias for backward compatibility
 	advancedCommands     *AdvancedCommands
-	errorHandler         interface{}                     // Placeholder for error handler integration
-	jobNotifications     chan jobmanager.JobNotification // Channel for job notifications
-	verbose              bool                            // Enable verbose/debug output
-	enableMultiline      bool                            // Enable multiline input
-	enableColors         bool                            // Enable color output
-	buffer               *MultiLineBuffer                // Buffer for multiline input
-	displayManager       *DisplayManager                 // Display manager for formatting
+	errorHandler         interface{}                         // Placeholder for error handler integration
+	jobNotifications     chan jobmanager.JobNotification     // Channel for job notifications
+	verbose              bool                                // Enable verbose/debug output
+	enableMultiline      bool                                // Enable multiline input
+	enableColors         bool                                // Enable color output
+	buffer               *MultiLineBuffer                    // Buffer for multiline input
+	displayManager       *DisplayManager                     // Display manager for formatting
+	readLine             func(prompt string) (string, error) // Line reader for nested prompts (set in interactive mode)
+	preludePath          string                              // Script run before the first prompt
+	preludeCacheDir      string                              // Directory of cached prelude trees
+	crashDir             string                              // Where reports of recovered panics go, empty for nowhere
+	parserHandlers       parser.HandlerOptions               // Parser options, part of the prelude cache key
+	rightPrompt          string                              // Right-hand prompt template, empty for none
+	last                 lastCommand                         // Outcome of the previous command for the prompt
+	updateNotice         string                              // Shown under the welcome message
+	timing               bool                                // :time on - report timing after each statement
+	timingReport         string                              // Report of the last statement, printed after its output
+	bitsView             string                              // :format - how bitstring results are shown, empty for the summary
+	session              []string                            // Statements that ran successfully, for :export-history
+	snippets             map[string]string                   // Snippets of the command palette by name
 }
 
 // NewREPL creates a new REPL instance
@@ -62,18 +77,71 @@ type REPLConfig struct {
 	Verbose         bool
 	EnableMultiline bool // Enable multiline input
 	EnableColors    bool
-	Prompt          string // Main prompt (default: "> ")
+	Prompt          string // Main prompt (default: "> "), may use {cwd}, {active_runtimes}, {last_duration}, {error_state}
+	RightPrompt     string // Prompt shown at the right edge of the input line, same variables
 	ContinuePrompt  string // Continuation prompt for multiline (default: "... ")
 	HistoryFile     string // History file path (default: "/tmp/funterm_history")
 	HistorySize     int    // Maximum history size (default: 1000)
+	// SpinnerThreshold is how long a runtime call may run before a spinner is shown
+	// (default: 1s, negative disables)
+	SpinnerThreshold time.Duration
+	// MemoryBudget is the size of session variables in bytes above which
+	// a warning is shown (0 disables)
+	MemoryBudget int64
+	// ParserHandlers disables parser constructs or changes their priorities
+	ParserHandlers parser.HandlerOptions
+	// Numbers controls how numbers coming from runtimes are represented
+	Numbers runtime.NumberPolicy
+	// MatchWarnings reports unreachable and non-exhaustive match arms when a match first runs
+	MatchWarnings bool
+	// GuardedCalls are patterns of calls that run only after the user confirms them
+	GuardedCalls []string
+	// Prelude is a script executed before the first prompt (empty disables)
+	Prelude string
+	// PreludeCacheDir holds the parsed prelude between launches (empty disables caching)
+	PreludeCacheDir string
+	// CrashDir receives a report with the stack of every panic the REPL
+	// recovers from (empty keeps none)
+	CrashDir string
+	// UpdateNotice is shown under the welcome message, e.g. that a new version is out
+	UpdateNotice string
+	// CollectUsage counts what the session uses for the opt-in usage statistics
+	CollectUsage bool
+	// ResultCacheDir keeps the results of cache_result() between runs (empty disables)
+	ResultCacheDir string
+	// StorePath is the database of store.put() and store.get() (empty disables)
+	StorePath string
+	// Strict turns the silent coercions of the strict rules into errors
+	Strict bool
+	// StrictRules sets single rules to "error", "warn" or "allow"
+	StrictRules map[string]string
+	// SegmentOverflow is what happens to integers too large for their bitstring segment
+	SegmentOverflow string
+	// Policy limits the languages, builtins and time of the session
+	Policy *engine.SessionPolicy
+	// Snippets are offered by the Ctrl+P palette, code by name
+	Snippets map[string]string
 }
 
 // NewREPLWithConfig creates a new REPL instance with configuration
 func NewREPLWithConfig(config REPLConfig) *REPL {
 	// Create execution engine
 	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
-		RuntimeRegistry: config.Registry,
-		Verbose:         config.Verbose,
+		RuntimeRegistry:  config.Registry,
+		Verbose:          config.Verbose,
+		SpinnerThreshold: config.SpinnerThreshold,
+		MemoryBudget:     config.MemoryBudget,
+		ParserHandlers:   config.ParserHandlers,
+		Numbers:          config.Numbers,
+		MatchWarnings:    config.MatchWarnings,
+		GuardedCalls:     config.GuardedCalls,
+		CollectUsage:     config.CollectUsage,
+		ResultCacheDir:   config.ResultCacheDir,
+		StorePath:        config.StorePath,
+		Strict:           config.Strict,
+		StrictRules:      config.StrictRules,
+		SegmentOverflow:  config.SegmentOverflow,
+		Policy:           config.Policy,
 	})
 	if err != nil {
 		panic(errors.NewSystemError("ENGINE_CREATION_FAILED", fmt.Sprintf("Failed to create execution engine: %v", err)).Error())
@@ -127,6 +195,13 @@ func NewREPLWithConfig(config REPLConfig) *REPL {
 		enableColors:         config.EnableColors,
 		buffer:               NewMultiLineBuffer(),
 		displayManager:       NewDisplayManager(config.EnableColors, config.Verbose),
+		preludePath:          config.Prelude,
+		preludeCacheDir:      config.PreludeCacheDir,
+		crashDir:             config.CrashDir,
+		parserHandlers:       config.ParserHandlers,
+		rightPrompt:          config.RightPrompt,
+		updateNotice:         config.UpdateNotice,
+		snippets:             config.Snippets,
 	}
 
 	// Initialize advanced commands with the REPL instance
@@ -183,6 +258,8 @@ func (r *REPL) Run() error {
 	// Start the job notification listener
 	r.startJobNotificationListener()
 
+	r.runPrelude()
+
 	// Check if we're running in interactive mode or piped mode
 	if r.isInteractive() {
 		// Use interactive mode with readline for arrow key navigation
@@ -199,14 +276,28 @@ func (r *REPL) runInteractive() error {
 	completer := NewFallbackCompleter(runtimeManager)
 
 	// Create readline instance
-	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          r.prompt,
+	rlConfig := &readline.Config{
+		Prompt:          r.renderPrompt(r.prompt),
 		HistoryFile:     r.historyFile,
 		HistoryLimit:    r.historySize,
 		InterruptPrompt: "^C",
 		EOFPrompt:       ":exit",
 		AutoComplete:    completer,
-	})
+	}
+	// Ctrl+P opens the command palette where the terminal can tell it from the Up arrow
+	var palette *commandPalette
+	if input := paletteInput(); input != nil {
+		palette = &commandPalette{items: r.paletteItems}
+		rlConfig.Stdin = input
+		rlConfig.FuncFilterInputRune = palette.filterKey
+		rlConfig.Listener = readline.FuncListener(palette.onChange)
+	}
+	var rightPrompt *rightPromptPainter
+	if r.rightPrompt != "" {
+		rightPrompt = &rightPromptPainter{}
+		rlConfig.Painter = rightPrompt
+	}
+	rl, err := readline.NewEx(rlConfig)
 	if err != nil {
 		return errors.NewSystemError("READLINE_INIT_FAILED", fmt.Sprintf("failed to initialize readline: %v", err))
 	}
@@ -216,22 +307,51 @@ func (r *REPL) runInteractive() error {
 			fmt.Printf("Warning: Failed to close readline: %v\n", err)
 		}
 	}()
+	if palette != nil {
+		palette.rl = rl
+	}
+	// A signal ending funterm restores the terminal and closes the history
+	runtime.OnShutdown(func() { rl.Close() })
 
 	fmt.Println("Multi-line mode is always enabled:")
 	fmt.Println("  Enter      - execute the code")
 	fmt.Println("  \\ at end   - add a line to the buffer (like Shift+Enter)")
 	fmt.Println("  :help ml   - more detailed")
+	if palette != nil {
+		fmt.Println("  Ctrl+P     - search history, commands, functions and snippets")
+	}
 	fmt.Println()
 
+	// Allow nested prompts (e.g. :view) to share the readline instance
+	r.readLine = func(prompt string) (string, error) {
+		if rightPrompt != nil {
+			rightPrompt.right = ""
+		}
+		rl.SetPrompt(prompt)
+		return rl.Readline()
+	}
+	defer func() { r.readLine = nil }()
+	// Guarded calls can be confirmed only here; piped and batch runs deny them
+	r.engine.SetCallConfirmation(r.confirmCall)
+	defer r.engine.SetCallConfirmation(nil)
+
 	buffer := NewMultiLineBuffer()
 
 	// Start the main REPL loop
 	for r.running {
 		// Show prompt based on buffer state
 		if buffer.IsActive() {
+			if rightPrompt != nil {
+				rightPrompt.right = ""
+			}
 			rl.SetPrompt(r.continuePrompt)
 		} else {
-			rl.SetPrompt(r.prompt)
+			prompt := r.renderPrompt(r.prompt)
+			if rightPrompt != nil {
+				rightPrompt.left = prompt
+				rightPrompt.right = r.renderPrompt(r.rightPrompt)
+			}
+			rl.SetPrompt(prompt)
 		}
 
 		input, err := rl.Readline()
@@ -307,7 +427,8 @@ func (r *REPL) runInteractive() error {
 		r.checkAndPrintJobNotifications()
 	}
 
-	// Cleanup
+	// Cleanup, on_exit handlers still use the runtimes
+	r.engine.RunExitHooks()
 	if err := r.engine.CleanupRuntimes(); err != nil {
 		return errors.NewSystemError("CLEANUP_ERROR", fmt.Sprintf("cleanup error: %v", err))
 	}
@@ -327,8 +448,13 @@ func (r *REPL) runPiped() error {
 		}
 
 		// Process the command
-		if err := r.processCommand(input); err != nil {
+		err := r.processCommand(input)
+		if err != nil {
 			r.displayError(err)
+		}
+		r.printTimingReport()
+		if err != nil {
+			r.engine.RunExitHooks()
 			return err
 		}
 	}
@@ -337,7 +463,8 @@ func (r *REPL) runPiped() error {
 		return errors.NewSystemError("STDIN_READ_ERROR", fmt.Sprintf("error reading from stdin: %v", err))
 	}
 
-	// Cleanup
+	// Cleanup, on_exit handlers still use the runtimes
+	r.engine.RunExitHooks()
 	if err := r.engine.CleanupRuntimes(); err != nil {
 		return errors.NewSystemError("CLEANUP_ERROR", fmt.Sprintf("cleanup error: %v", err))
 	}
@@ -350,6 +477,9 @@ func (r *REPL) printWelcome() {
 	fmt.Println("Welcome to funterm - Multi-Language REPL")
 	fmt.Println("Type ':help' for available commands or ':quit' to exit")
 	fmt.Println("Available languages: go, js, lua, python")
+	if r.updateNotice != "" {
+		fmt.Println(r.updateNotice)
+	}
 	fmt.Println()
 }
 
@@ -434,7 +564,7 @@ func (r *REPL) processCommand(input string) error {
 			}
 
 		// Execute the funterm code directly
-		result, isPrint, hasResult, err := r.engine.Execute(funtermCode)
+		result, isPrint, hasResult, err := r.execute(funtermCode)
 		if err != nil {
 			return err
 		}
@@ -472,10 +602,11 @@ func (r *REPL) processCommand(input string) error {
 	_ = r.performanceOptimizer.PreParseCommand(input)
 
 	// Execute the command
-	result, isPrint, hasResult, err := r.engine.Execute(input)
+	result, isPrint, hasResult, err := r.execute(input)
 	if err != nil {
 		return err
 	}
+	r.ReportMemoryWarnings()
 
 	// Cache the result for future use, but only for safe operations
 	// Most runtime commands can have side effects or change state, so we disable caching
@@ -517,7 +648,7 @@ func (r *REPL) handleBuiltInCommand(input string) error {
 	// First try to handle with advanced commands
 	if r.advancedCommands != nil {
 		// Check if this is an advanced command and handle it
-		switch cmd {
+		switch command {
 		case "debug", "breakpoint", "step", "continue", "inspect", "stack",
 			"profile", "benchmark", "memory", "gc",
 			"save", "load", "reset", "snapshot",
@@ -570,6 +701,31 @@ func (r *REPL) handleBuiltInCommand(input string) error {
 		return r.executeMixedFile(filePath)
 	case "jobs":
 		return r.printJobs()
+	case "vars":
+		return r.printVariables()
+	case "undo":
+		return r.undoAssignments(parts[1:])
+	case "time":
+		return r.handleTimeCommand(parts[1:])
+	case "format":
+		return r.handleFormatCommand(parts[1:])
+	case "trace":
+		return r.handleTraceCommand(parts[1:])
+	case "export-history":
+		return r.exportHistory(parts[1:])
+	case "export-vars":
+		return r.exportVariables(parts[1:])
+	case "import-vars":
+		return r.importVariables(parts[1:])
+	case "doc":
+		return r.showDoc(parts[1:])
+	case "view":
+		return r.viewTable(strings.TrimSpace(strings.TrimPrefix(cmd, command)))
+	case "bits":
+		if len(parts) < 2 || parts[1] != "explore" {
+			return errors.NewUserError("INVALID_COMMAND", "usage: :bits explore <expression>")
+		}
+		return r.exploreBits(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(cmd, command)), "explore")))
 	default:
 		// Check if this is a language command (lua, python, js, etc.)
 		if r.isLanguageCommand(command) {
@@ -604,6 +760,18 @@ func (r *REPL) printHelp() {
 	// fmt.Println("  :mixed <file>      - Execute mixed language code from file")
 	fmt.Println("  :run <file>, r: <file>  - Execute mixed language code from file")
 	fmt.Println("  :jobs                   - List background jobs and their status")
+	fmt.Println("  :vars                   - List variables with their type and memory usage")
+	fmt.Println("  :gc                     - Release unreferenced proxy handles and run garbage collection")
+	fmt.Println("  :undo [n]               - Revert the last n assignments of variables (default 1)")
+	fmt.Println("  :time on|off            - Show parse/engine/runtime time, CPU and memory of runtime calls, and variable memory change after each statement")
+	fmt.Println("  :trace on|off           - Show each language call, what is sent to the runtime and the raw answer")
+	fmt.Println("  :format hex|bin|raw     - Show bitstring results in hex, bit by bit or as <<1,2,3>> (:format summary to go back)")
+	fmt.Println("  :export-history <file>  - Save the statements that ran successfully as a .su script")
+	fmt.Println("  :export-vars <file>     - Save the variables (or only the names after file) with their types as JSON")
+	fmt.Println("  :import-vars <file>     - Set the variables saved by :export-vars")
+	fmt.Println("  :doc [lang.function]    - Show the comments above the code block that defined a function")
+	fmt.Println("  :view <expr>            - Show an array of maps as a pageable table (sort/filter inside)")
+	fmt.Println("  :bits explore <expr>    - Decode a bitstring interactively, spec by spec (u16-le, f32, utf8, ...)")
 	fmt.Println()
 
 	fmt.Println("Terminal commands:")
@@ -652,10 +820,16 @@ func (r *REPL) printAvailableLanguages() {
 
 		// Add descriptions for built-in languages
 		switch lang {
-		case "js", "py":
+		case "js", "py", "pl", "erl", "ex":
 			skip = true
 		case "node":
 			description = " (also available as js)"
+		case "perl":
+			description = " (also available as pl)"
+		case "erlang":
+			description = " (also available as erl)"
+		case "elixir":
+			description = " (also available as ex)"
 		case "python":
 			description = " (also available as py)"
 		}
@@ -707,6 +881,12 @@ func (r *REPL) formatResult(result interface{}) string {
 		}
 		return fmt.Sprintf("\"%s\"", str)
 	}
+	if bits, ok := result.(*shared.BitstringObject); ok {
+		return r.formatBitstring(bits)
+	}
+	if shared.IsDiff(result) {
+		return shared.FormatDiff(result.([]interface{}), r.enableColors)
+	}
 	// For all other types, use the shared formatter
 	return shared.FormatValueForDisplay(result)
 }
@@ -1116,8 +1296,11 @@ func (r *REPL) isLanguageCommand(command string) bool {
 
 	// Check common aliases
 	aliases := map[string]bool{
-		"py": true,
-		"js": true,
+		"py":  true,
+		"js":  true,
+		"pl":  true,
+		"erl": true,
+		"ex":  true,
 	}
 
 	return aliases[command]
@@ -1134,6 +1317,12 @@ func (r *REPL) printLanguageInfo(language string) error {
 		runtimeName = "python"
 	case "js":
 		runtimeName = "node"
+	case "pl":
+		runtimeName = "perl"
+	case "erl":
+		runtimeName = "erlang"
+	case "ex":
+		runtimeName = "elixir"
 	default:
 		runtimeName = language
 	}
@@ -1218,6 +1407,12 @@ func (r *REPL) printModuleFunctions(language, module string) error {
 		runtimeName = "python"
 	case "js":
 		runtimeName = "node"
+	case "pl":
+		runtimeName = "perl"
+	case "erl":
+		runtimeName = "erlang"
+	case "ex":
+		runtimeName = "elixir"
 	default:
 		runtimeName = language
 	}
@@ -1280,7 +1475,9 @@ func (r *REPL) executeBufferSimple(buffer *MultiLineBuffer) {
 	fmt.Printf("Executing a buffer (%d lines):\n", buffer.GetLineCount())
 
 	// Execute the code
-	result, isPrint, hasResult, err := r.engine.Execute(content)
+	start := time.Now()
+	result, isPrint, hasResult, err := r.execute(content)
+	r.recordCommand(start, err)
 
 	// Show the result
 	if err != nil {
@@ -1290,6 +1487,7 @@ func (r *REPL) executeBufferSimple(buffer *MultiLineBuffer) {
 	} else if !isPrint {
 		fmt.Println("✓ Executed")
 	}
+	r.printTimingReport()
 }
 
 // handleSpecialCommandsSimple handles special commands for buffer management
@@ -1340,12 +1538,47 @@ func (r *REPL) handleSpecialCommandsSimple(line string, buffer *MultiLineBuffer)
 	return true
 }
 
+// execute runs funterm code typed or piped into the REPL. With :time on it
+// prepares the timing report printed after the command's output, with
+// :profile start its runtime calls go to the profile, and code that ran
+// without error is kept for :export-history. A panic while parsing
+// or running the code becomes an error instead of ending the session
+func (r *REPL) execute(code string) (result interface{}, isPrint bool, hasResult bool, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, isPrint, hasResult, err = nil, false, false, r.recoverPanic(code, recovered)
+		}
+	}()
+
+	var before int64
+	if r.timing {
+		before = r.engine.VariableBytes()
+	}
+	profiling := r.advancedCommands != nil && r.advancedCommands.profiler.enabled
+	r.engine.SetCallStats(r.timing || profiling)
+	result, isPrint, hasResult, err = r.engine.Execute(code)
+	if r.timing {
+		r.timingReport = formatTiming(r.engine.LastTiming(), r.engine.VariableBytes()-before)
+	}
+	if profiling {
+		r.advancedCommands.profiler.Record(r.engine.LastTiming().Calls)
+	}
+	if err == nil {
+		r.session = append(r.session, code)
+	}
+	return result, isPrint, hasResult, err
+}
+
 // processSingleLine processes a single line of input
 func (r *REPL) processSingleLine(line string) {
 	// Process the command
-	if err := r.processCommand(line); err != nil {
+	start := time.Now()
+	err := r.processCommand(line)
+	r.recordCommand(start, err)
+	if err != nil {
 		r.displayError(err)
 	}
+	r.printTimingReport()
 }
 
 // IsMultilineEnabled returns whether multiline mode is enabled
@@ -1478,7 +1711,7 @@ func (r *REPL) handleDollarCommand(command string, buffer *MultiLineBuffer) {
 	}
 
 	// Execute the output as a funterm command
-	result, isPrint, hasResult, err := r.engine.Execute(output)
+	result, isPrint, hasResult, err := r.execute(output)
 	if err != nil {
 		r.displayError(err)
 	} else if hasResult {
@@ -1486,6 +1719,7 @@ func (r *REPL) handleDollarCommand(command string, buffer *MultiLineBuffer) {
 	} else if !isPrint {
 		fmt.Println("✓ Executed")
 	}
+	r.printTimingReport()
 }
 
 // displayError displays an error with position information if available
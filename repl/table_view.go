@@ -0,0 +1,522 @@
+package repl
+
+import (
+	"fmt"
+	"funterm/errors"
+	"funterm/shared"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultTablePageSize = 20
+	maxTableCellWidth    = 30
+)
+
+// tableFilter is a single column condition applied by the table viewer
+type tableFilter struct {
+	column   string
+	operator string
+	value    string
+}
+
+// TableView renders arrays-of-maps as a pageable table with sorting and filtering
+type TableView struct {
+	columns  []string
+	rows     []map[string]interface{}
+	visible  []int // Indices into rows after filtering and sorting
+	page     int
+	pageSize int
+	sortBy   string
+	sortDesc bool
+	filters  []tableFilter
+}
+
+// NewTableView creates a table view from a record list (array of maps)
+// or a column-oriented map (column name -> array of values), e.g. the
+// result of pandas DataFrame.to_dict("records") or to_dict("list")
+func NewTableView(value interface{}) (*TableView, error) {
+	rows, err := tableRowsFromValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the union of keys so rows with missing fields still line up
+	seen := make(map[string]bool)
+	columns := make([]string, 0)
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	tv := &TableView{
+		columns:  columns,
+		rows:     rows,
+		pageSize: defaultTablePageSize,
+	}
+	tv.refresh()
+	return tv, nil
+}
+
+// tableRowsFromValue normalizes supported value shapes into a list of records
+func tableRowsFromValue(value interface{}) ([]map[string]interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		rows := make([]map[string]interface{}, 0, len(v))
+		for i, item := range v {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.NewUserError("VIEW_UNSUPPORTED_VALUE", fmt.Sprintf("element %d is %T, expected a map", i, item))
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case []map[string]interface{}:
+		return v, nil
+	case map[string]interface{}:
+		// Column-oriented data: every value must be an array of the same length
+		length := -1
+		for name, column := range v {
+			items, ok := column.([]interface{})
+			if !ok {
+				return nil, errors.NewUserError("VIEW_UNSUPPORTED_VALUE", fmt.Sprintf("column '%s' is %T, expected an array", name, column))
+			}
+			if length >= 0 && len(items) != length {
+				return nil, errors.NewUserError("VIEW_UNSUPPORTED_VALUE", fmt.Sprintf("column '%s' has %d values, expected %d", name, len(items), length))
+			}
+			length = len(items)
+		}
+		if length < 0 {
+			length = 0
+		}
+		rows := make([]map[string]interface{}, length)
+		for i := range rows {
+			rows[i] = make(map[string]interface{}, len(v))
+		}
+		for name, column := range v {
+			for i, item := range column.([]interface{}) {
+				rows[i][name] = item
+			}
+		}
+		return rows, nil
+	default:
+		return nil, errors.NewUserError("VIEW_UNSUPPORTED_VALUE", fmt.Sprintf("cannot display %T as a table, expected an array of maps", value))
+	}
+}
+
+// RowCount returns the number of rows that pass the current filters
+func (tv *TableView) RowCount() int {
+	return len(tv.visible)
+}
+
+// PageCount returns the number of pages for the current filters
+func (tv *TableView) PageCount() int {
+	if len(tv.visible) == 0 {
+		return 1
+	}
+	return (len(tv.visible) + tv.pageSize - 1) / tv.pageSize
+}
+
+// SetPageSize changes the number of rows shown per page
+func (tv *TableView) SetPageSize(size int) {
+	if size <= 0 {
+		size = defaultTablePageSize
+	}
+	tv.pageSize = size
+	tv.GoToPage(tv.page)
+}
+
+// GoToPage moves to the given zero-based page, clamping to the valid range
+func (tv *TableView) GoToPage(page int) {
+	if page >= tv.PageCount() {
+		page = tv.PageCount() - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	tv.page = page
+}
+
+// SortBy sorts rows by column; sorting by the same column again flips the order
+func (tv *TableView) SortBy(column string) error {
+	if !tv.hasColumn(column) {
+		return errors.NewUserError("VIEW_UNKNOWN_COLUMN", fmt.Sprintf("unknown column: %s", column))
+	}
+	if tv.sortBy == column {
+		tv.sortDesc = !tv.sortDesc
+	} else {
+		tv.sortBy = column
+		tv.sortDesc = false
+	}
+	tv.refresh()
+	return nil
+}
+
+// AddFilter parses a condition like "age >= 30" or "name ~ ann" and applies it.
+// The column ends at the first operator, so the value may contain operator
+// characters itself, e.g. "url ~ a=b"
+func (tv *TableView) AddFilter(expr string) error {
+	idx, op := -1, ""
+	for _, candidate := range []string{">=", "<=", "!=", "=", ">", "<", "~"} {
+		i := strings.Index(expr, candidate)
+		if i <= 0 {
+			continue
+		}
+		// The left-most operator wins, and the longer one at the same position
+		if idx < 0 || i < idx || (i == idx && len(candidate) > len(op)) {
+			idx, op = i, candidate
+		}
+	}
+	if idx < 0 {
+		return errors.NewUserError("VIEW_INVALID_FILTER", fmt.Sprintf("invalid filter '%s', expected <column> <op> <value> where op is one of = != < <= > >= ~", expr))
+	}
+
+	column := strings.TrimSpace(expr[:idx])
+	value := strings.Trim(strings.TrimSpace(expr[idx+len(op):]), "\"'")
+	if !tv.hasColumn(column) {
+		return errors.NewUserError("VIEW_UNKNOWN_COLUMN", fmt.Sprintf("unknown column: %s", column))
+	}
+	tv.filters = append(tv.filters, tableFilter{column: column, operator: op, value: value})
+	tv.refresh()
+	return nil
+}
+
+// ClearFilters removes all filters
+func (tv *TableView) ClearFilters() {
+	tv.filters = nil
+	tv.refresh()
+}
+
+// hasColumn reports whether the table has the given column
+func (tv *TableView) hasColumn(column string) bool {
+	for _, c := range tv.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh recomputes visible rows after filters or sorting change
+func (tv *TableView) refresh() {
+	tv.visible = tv.visible[:0]
+	for i, row := range tv.rows {
+		if tv.matchesFilters(row) {
+			tv.visible = append(tv.visible, i)
+		}
+	}
+
+	if tv.sortBy != "" {
+		sort.SliceStable(tv.visible, func(a, b int) bool {
+			left := tv.rows[tv.visible[a]][tv.sortBy]
+			right := tv.rows[tv.visible[b]][tv.sortBy]
+			// nil values always go last regardless of direction
+			if left == nil || right == nil {
+				return left != nil && right == nil
+			}
+			cmp := compareTableValues(left, right)
+			if tv.sortDesc {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	tv.GoToPage(tv.page)
+}
+
+// matchesFilters checks a row against every active filter
+func (tv *TableView) matchesFilters(row map[string]interface{}) bool {
+	for _, f := range tv.filters {
+		cell := formatTableCell(row[f.column])
+		if f.operator == "~" {
+			if !strings.Contains(strings.ToLower(cell), strings.ToLower(f.value)) {
+				return false
+			}
+			continue
+		}
+
+		var cmp int
+		if num, ok := tableNumber(row[f.column]); ok {
+			target, err := strconv.ParseFloat(f.value, 64)
+			if err != nil {
+				return false
+			}
+			cmp = compareFloats(num, target)
+		} else {
+			cmp = strings.Compare(cell, f.value)
+		}
+
+		switch f.operator {
+		case "=":
+			if cmp != 0 {
+				return false
+			}
+		case "!=":
+			if cmp == 0 {
+				return false
+			}
+		case "<":
+			if cmp >= 0 {
+				return false
+			}
+		case "<=":
+			if cmp > 0 {
+				return false
+			}
+		case ">":
+			if cmp <= 0 {
+				return false
+			}
+		case ">=":
+			if cmp < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Render writes the current page of the table to w
+func (tv *TableView) Render(w io.Writer) {
+	if len(tv.columns) == 0 {
+		fmt.Fprintln(w, "(empty table)")
+		return
+	}
+
+	start := tv.page * tv.pageSize
+	end := start + tv.pageSize
+	if end > len(tv.visible) {
+		end = len(tv.visible)
+	}
+
+	// Column widths are computed for the visible page only
+	widths := make([]int, len(tv.columns))
+	headers := make([]string, len(tv.columns))
+	for i, column := range tv.columns {
+		header := column
+		if column == tv.sortBy {
+			if tv.sortDesc {
+				header += " ↓"
+			} else {
+				header += " ↑"
+			}
+		}
+		headers[i] = header
+		widths[i] = utf8.RuneCountInString(header)
+	}
+	cells := make([][]string, 0, end-start)
+	for _, idx := range tv.visible[start:end] {
+		line := make([]string, len(tv.columns))
+		for i, column := range tv.columns {
+			line[i] = truncateTableCell(formatTableCell(tv.rows[idx][column]))
+			if n := utf8.RuneCountInString(line[i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	writeTableLine(w, headers, widths)
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("─", width)
+	}
+	fmt.Fprintln(w, strings.Join(separators, "─┼─"))
+	for _, line := range cells {
+		writeTableLine(w, line, widths)
+	}
+
+	status := fmt.Sprintf("rows %d-%d of %d | page %d/%d", start+1, end, len(tv.visible), tv.page+1, tv.PageCount())
+	if len(tv.visible) == 0 {
+		status = fmt.Sprintf("no rows (of %d) | page 1/1", len(tv.rows))
+	} else if len(tv.visible) != len(tv.rows) {
+		status += fmt.Sprintf(" | filtered from %d", len(tv.rows))
+	}
+	if len(tv.filters) > 0 {
+		conditions := make([]string, len(tv.filters))
+		for i, f := range tv.filters {
+			conditions[i] = fmt.Sprintf("%s %s %s", f.column, f.operator, f.value)
+		}
+		status += " | filter: " + strings.Join(conditions, ", ")
+	}
+	fmt.Fprintln(w, status)
+}
+
+// HandleKey applies a viewer command; it returns true when the viewer should close
+func (tv *TableView) HandleKey(input string) (bool, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		input = "n"
+	}
+	parts := strings.SplitN(input, " ", 2)
+	arg := ""
+	if len(parts) > 1 {
+		arg = strings.TrimSpace(parts[1])
+	}
+
+	switch parts[0] {
+	case "q", "quit":
+		return true, nil
+	case "n", "next":
+		tv.GoToPage(tv.page + 1)
+	case "p", "prev":
+		tv.GoToPage(tv.page - 1)
+	case "first":
+		tv.GoToPage(0)
+	case "last":
+		tv.GoToPage(tv.PageCount() - 1)
+	case "g", "goto":
+		page, err := strconv.Atoi(arg)
+		if err != nil {
+			return false, errors.NewUserError("VIEW_INVALID_PAGE", fmt.Sprintf("invalid page number: %s", arg))
+		}
+		tv.GoToPage(page - 1)
+	case "s", "sort":
+		return false, tv.SortBy(arg)
+	case "f", "filter":
+		if arg == "" || arg == "clear" {
+			tv.ClearFilters()
+			return false, nil
+		}
+		return false, tv.AddFilter(arg)
+	case "size":
+		size, err := strconv.Atoi(arg)
+		if err != nil || size <= 0 {
+			return false, errors.NewUserError("VIEW_INVALID_PAGE_SIZE", fmt.Sprintf("invalid page size: %s", arg))
+		}
+		tv.SetPageSize(size)
+	default:
+		return false, errors.NewUserError("VIEW_UNKNOWN_KEY", fmt.Sprintf("unknown viewer command: %s", parts[0]))
+	}
+	return false, nil
+}
+
+// printTableViewHelp shows the keys understood by the interactive viewer
+func printTableViewHelp(w io.Writer) {
+	fmt.Fprintln(w, "Viewer commands: n/Enter next, p prev, first, last, g <page>, s <column> sort (again to reverse),")
+	fmt.Fprintln(w, "                 f <column> <op> <value> filter (= != < <= > >= ~), f clear drop filters, size <n>, q quit")
+}
+
+// writeTableLine writes one padded table row
+func writeTableLine(w io.Writer, values []string, widths []int) {
+	padded := make([]string, len(values))
+	for i, value := range values {
+		padded[i] = value + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value))
+	}
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(padded, " │ "), " "))
+}
+
+// formatTableCell converts a cell value to its display form
+func formatTableCell(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	return shared.FormatValueForDisplay(value)
+}
+
+// truncateTableCell shortens long cells and flattens newlines
+func truncateTableCell(cell string) string {
+	cell = strings.ReplaceAll(cell, "\n", "\\n")
+	if utf8.RuneCountInString(cell) <= maxTableCellWidth {
+		return cell
+	}
+	runes := []rune(cell)
+	return string(runes[:maxTableCellWidth-1]) + "…"
+}
+
+// tableNumber extracts a numeric value for sorting and filtering
+func tableNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// compareTableValues orders two cells numerically when possible, otherwise as text
+func compareTableValues(left, right interface{}) int {
+	if l, ok := tableNumber(left); ok {
+		if r, ok := tableNumber(right); ok {
+			return compareFloats(l, r)
+		}
+	}
+	return strings.Compare(formatTableCell(left), formatTableCell(right))
+}
+
+// compareFloats returns -1, 0 or 1
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// viewTable implements the :view command. The argument is evaluated as a
+// funterm expression, so variables and language calls are both accepted:
+//
+//	:view users
+//	:view python.df.to_dict("records")
+//
+// In interactive mode the viewer keeps reading commands until q is entered;
+// otherwise the first page is printed once.
+func (r *REPL) viewTable(expr string) error {
+	if expr == "" {
+		return errors.NewUserError("INVALID_COMMAND", "usage: :view <expression>")
+	}
+
+	value, _, _, err := r.engine.Execute(expr)
+	if err != nil {
+		return err
+	}
+	tv, err := NewTableView(value)
+	if err != nil {
+		return err
+	}
+
+	tv.Render(os.Stdout)
+	if r.readLine == nil {
+		return nil
+	}
+
+	printTableViewHelp(os.Stdout)
+	for {
+		input, err := r.readLine("view> ")
+		if err != nil {
+			// Ctrl+C or EOF simply leave the viewer
+			return nil
+		}
+		done, err := tv.HandleKey(input)
+		if done {
+			return nil
+		}
+		if err != nil {
+			r.displayError(err)
+			continue
+		}
+		tv.Render(os.Stdout)
+	}
+}
@@ -0,0 +1,149 @@
+package repl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestTableView(t *testing.T) *TableView {
+	t.Helper()
+	tv, err := NewTableView([]interface{}{
+		map[string]interface{}{"name": "ann", "age": 31.0, "url": "x?a=b"},
+		map[string]interface{}{"name": "bob", "age": 25.0, "url": "y?c=d"},
+		map[string]interface{}{"name": "a<b", "age": 40.0, "url": "z"},
+		map[string]interface{}{"name": "cy", "age": 19.0, "url": "x?a=c"},
+		map[string]interface{}{"name": "dee", "age": 52.0, "url": "w"},
+	})
+	if err != nil {
+		t.Fatalf("NewTableView() error = %v", err)
+	}
+	return tv
+}
+
+// visibleNames lists the name column of the rows that pass the filters, in order
+func visibleNames(tv *TableView) []string {
+	names := make([]string, 0, len(tv.visible))
+	for _, idx := range tv.visible {
+		names = append(names, tv.rows[idx]["name"].(string))
+	}
+	return names
+}
+
+func TestTableView_Paging(t *testing.T) {
+	tv := newTestTableView(t)
+	if _, err := tv.HandleKey("size 2"); err != nil {
+		t.Fatalf("size 2: %v", err)
+	}
+	if tv.PageCount() != 3 {
+		t.Fatalf("PageCount() = %d, want 3", tv.PageCount())
+	}
+
+	tests := []struct {
+		key  string
+		page int
+	}{
+		{"n", 1},
+		{"", 2},
+		{"n", 2},
+		{"p", 1},
+		{"first", 0},
+		{"last", 2},
+		{"g 2", 1},
+		{"g 99", 2},
+	}
+	for _, tt := range tests {
+		if _, err := tv.HandleKey(tt.key); err != nil {
+			t.Fatalf("%q: %v", tt.key, err)
+		}
+		if tv.page != tt.page {
+			t.Errorf("after %q page = %d, want %d", tt.key, tv.page, tt.page)
+		}
+	}
+
+	var out bytes.Buffer
+	tv.Render(&out)
+	if !strings.Contains(out.String(), "rows 5-5 of 5 | page 3/3") {
+		t.Errorf("Render() status line missing, got:\n%s", out.String())
+	}
+}
+
+func TestTableView_Sorting(t *testing.T) {
+	tv := newTestTableView(t)
+	if err := tv.SortBy("age"); err != nil {
+		t.Fatalf("SortBy(age) error = %v", err)
+	}
+	if got, want := visibleNames(tv), []string{"cy", "bob", "ann", "a<b", "dee"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ascending = %v, want %v", got, want)
+	}
+	if err := tv.SortBy("age"); err != nil {
+		t.Fatalf("SortBy(age) again error = %v", err)
+	}
+	if got, want := visibleNames(tv), []string{"dee", "a<b", "ann", "bob", "cy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("descending = %v, want %v", got, want)
+	}
+	if err := tv.SortBy("missing"); err == nil {
+		t.Error("SortBy(missing) expected an error")
+	}
+}
+
+func TestTableView_Filters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   []string
+		column string
+		op     string
+		value  string
+	}{
+		{"greater or equal", "age >= 31", []string{"ann", "a<b", "dee"}, "age", ">=", "31"},
+		{"less", "age < 25", []string{"cy"}, "age", "<", "25"},
+		{"not equal", "name != bob", []string{"ann", "a<b", "cy", "dee"}, "name", "!=", "bob"},
+		{"value with equals sign", "url ~ a=b", []string{"ann"}, "url", "~", "a=b"},
+		{"value with less sign", "name ~ a<b", []string{"a<b"}, "name", "~", "a<b"},
+		{"quoted value", "name = 'bob'", []string{"bob"}, "name", "=", "bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tv := newTestTableView(t)
+			if _, err := tv.HandleKey("f " + tt.filter); err != nil {
+				t.Fatalf("f %s: %v", tt.filter, err)
+			}
+			f := tv.filters[0]
+			if f.column != tt.column || f.operator != tt.op || f.value != tt.value {
+				t.Errorf("filter = %+v, want %s %s %s", f, tt.column, tt.op, tt.value)
+			}
+			if got := visibleNames(tv); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableView_ClearFilters(t *testing.T) {
+	for _, key := range []string{"f", "f clear", "filter clear"} {
+		tv := newTestTableView(t)
+		if _, err := tv.HandleKey("f age > 30"); err != nil {
+			t.Fatalf("f age > 30: %v", err)
+		}
+		if tv.RowCount() != 3 {
+			t.Fatalf("RowCount() = %d, want 3", tv.RowCount())
+		}
+		if _, err := tv.HandleKey(key); err != nil {
+			t.Fatalf("%q: %v", key, err)
+		}
+		if len(tv.filters) != 0 || tv.RowCount() != 5 {
+			t.Errorf("after %q filters = %v, rows = %d, want none and 5", key, tv.filters, tv.RowCount())
+		}
+	}
+}
+
+func TestTableView_InvalidFilter(t *testing.T) {
+	tv := newTestTableView(t)
+	for _, filter := range []string{"age", "= 5", "height > 2"} {
+		if err := tv.AddFilter(filter); err == nil {
+			t.Errorf("AddFilter(%q) expected an error", filter)
+		}
+	}
+}
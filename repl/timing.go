@@ -0,0 +1,106 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"funterm/engine"
+	"funterm/errors"
+	"funterm/runtime"
+)
+
+// handleTimeCommand implements :time [on|off]
+func (r *REPL) handleTimeCommand(args []string) error {
+	if len(args) == 0 {
+		state := "off"
+		if r.timing {
+			state = "on"
+		}
+		fmt.Printf("Timing is %s\n", state)
+		return nil
+	}
+	switch args[0] {
+	case "on":
+		r.timing = true
+	case "off":
+		r.timing = false
+	default:
+		return errors.NewUserError("INVALID_COMMAND", "usage: :time [on|off]")
+	}
+	return nil
+}
+
+// printTimingReport prints the report of the last command, if there is one
+func (r *REPL) printTimingReport() {
+	if r.timingReport == "" {
+		return
+	}
+	fmt.Println(r.timingReport)
+	r.timingReport = ""
+}
+
+// formatTiming formats e.g. "⏱ 12.4ms (parse 0.3ms, engine 1.1ms, runtime 11.0ms), vars +1.2 KB",
+// followed by a line for each function the command called in a runtime
+// when their cost was measured:
+//
+//	python.load ×2  11.0ms  cpu 10.0ms  peak +4.2 MB
+func formatTiming(timing engine.CommandTiming, memoryDelta int64) string {
+	sign := "+"
+	if memoryDelta < 0 {
+		sign = "-"
+		memoryDelta = -memoryDelta
+	}
+	report := fmt.Sprintf("⏱ %s (parse %s, engine %s, runtime %s), vars %s%s",
+		formatMillis(timing.Total()), formatMillis(timing.Parse), formatMillis(timing.Engine), formatMillis(timing.Runtime),
+		sign, engine.FormatBytes(memoryDelta))
+
+	// Calls of the same function, as in a loop, are added up
+	var names []string
+	totals := make(map[string]*ProfileOperation)
+	width := 0
+	for _, call := range timing.Calls {
+		total, ok := totals[call.Name]
+		if !ok {
+			total = &ProfileOperation{Name: call.Name}
+			totals[call.Name] = total
+			names = append(names, call.Name)
+			width = max(width, len(call.Name))
+		}
+		total.CallCount++
+		total.Duration += call.Duration
+		total.CPU += call.CPU
+		total.PeakRSS += call.PeakRSS
+	}
+	for _, name := range names {
+		total := totals[name]
+		report += fmt.Sprintf("\n  %-*s ×%d  %s  cpu %s  peak +%s", width, name, total.CallCount,
+			formatMillis(total.Duration), formatMillis(total.CPU), engine.FormatBytes(total.PeakRSS))
+	}
+	return report
+}
+
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// handleTraceCommand implements :trace [on|off]
+func (r *REPL) handleTraceCommand(args []string) error {
+	if len(args) == 0 {
+		state := "off"
+		if runtime.Tracing() {
+			state = "on"
+		}
+		fmt.Printf("Tracing is %s\n", state)
+		return nil
+	}
+	switch args[0] {
+	case "on":
+		runtime.SetTrace(os.Stderr)
+	case "off":
+		runtime.SetTrace(nil)
+	default:
+		return errors.NewUserError("INVALID_COMMAND", "usage: :trace [on|off]")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package beam
+
+import (
+	"sort"
+	"strings"
+
+	"funterm/runtime"
+)
+
+// erlangModules are standard library modules offered for completion
+var erlangModules = map[string][]string{
+	"binary":  {"at", "bin_to_list", "copy", "decode_unsigned", "encode_unsigned", "first", "last", "list_to_bin", "match", "matches", "part", "replace", "split"},
+	"crypto":  {"hash", "mac", "strong_rand_bytes"},
+	"erlang":  {"binary_to_list", "binary_to_term", "bit_size", "byte_size", "crc32", "list_to_binary", "md5", "phash2", "term_to_binary"},
+	"lists":   {"append", "filter", "foldl", "map", "max", "min", "nth", "reverse", "seq", "sort", "sum"},
+	"maps":    {"from_list", "get", "keys", "merge", "put", "to_list", "values"},
+	"string":  {"find", "lexemes", "lowercase", "pad", "replace", "split", "trim", "uppercase"},
+	"zlib":    {"compress", "gunzip", "gzip", "uncompress", "unzip", "zip"},
+	"base64":  {"decode", "encode"},
+	"io_lib":  {"format"},
+	"io":      {"format", "fwrite"},
+	"unicode": {"characters_to_binary", "characters_to_list"},
+}
+
+// elixirModules are Elixir standard modules offered for completion
+var elixirModules = map[string][]string{
+	"Base":    {"decode16", "decode64", "encode16", "encode64"},
+	"Enum":    {"count", "filter", "join", "map", "reduce", "reverse", "sort", "sum", "zip"},
+	"IO":      {"inspect", "puts", "write"},
+	"Integer": {"digits", "parse", "to_string"},
+	"Map":     {"get", "keys", "merge", "put", "values"},
+	"String":  {"downcase", "length", "replace", "reverse", "split", "trim", "upcase"},
+}
+
+func (br *BeamRuntime) modules() map[string][]string {
+	if br.dialect == Elixir {
+		return elixirModules
+	}
+	return erlangModules
+}
+
+// GetModules returns available modules for the runtime
+func (br *BeamRuntime) GetModules() []string {
+	modules := make([]string, 0, len(br.modules()))
+	for module := range br.modules() {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// GetModuleFunctions returns available functions for a specific module
+func (br *BeamRuntime) GetModuleFunctions(module string) []string {
+	return br.modules()[module]
+}
+
+// GetFunctionSignature returns the signature of a function in a module
+func (br *BeamRuntime) GetFunctionSignature(module, function string) (string, error) {
+	if module == "" {
+		return function + "(...)", nil
+	}
+	if br.dialect == Elixir {
+		return module + "." + function + "(...)", nil
+	}
+	return module + ":" + function + "(...)", nil
+}
+
+// GetGlobalVariables returns available global variables
+func (br *BeamRuntime) GetGlobalVariables() []string {
+	return []string{}
+}
+
+// GetCompletionSuggestions returns completion suggestions for a given input
+func (br *BeamRuntime) GetCompletionSuggestions(input string) []string {
+	suggestions := []string{}
+	for _, module := range br.GetModules() {
+		if strings.HasPrefix(module, input) {
+			suggestions = append(suggestions, module)
+		}
+	}
+	return suggestions
+}
+
+// GetUserDefinedFunctions returns functions defined by the user during the session
+func (br *BeamRuntime) GetUserDefinedFunctions() []string { return []string{} }
+
+// GetImportedModules returns modules that have been imported during the session
+func (br *BeamRuntime) GetImportedModules() []string { return []string{} }
+
+// GetDynamicCompletions returns completions based on current runtime state
+func (br *BeamRuntime) GetDynamicCompletions(input string) ([]string, error) {
+	return br.GetCompletionSuggestions(input), nil
+}
+
+// GetObjectProperties returns properties and methods of a runtime object
+func (br *BeamRuntime) GetObjectProperties(objectName string) ([]string, error) {
+	return br.GetModuleFunctions(objectName), nil
+}
+
+// GetFunctionParameters returns parameter names and types for a function;
+// BEAM functions are identified by arity, not parameter names
+func (br *BeamRuntime) GetFunctionParameters(functionName string) ([]runtime.FunctionParameter, error) {
+	return []runtime.FunctionParameter{}, nil
+}
+
+// UpdateCompletionContext updates the completion context after code execution
+func (br *BeamRuntime) UpdateCompletionContext(executedCode string, result interface{}) error {
+	return nil
+}
+
+// RefreshRuntimeState refreshes the runtime state for completion
+func (br *BeamRuntime) RefreshRuntimeState() error { return nil }
+
+// GetRuntimeObjects returns all objects currently available in the runtime
+func (br *BeamRuntime) GetRuntimeObjects() map[string]interface{} {
+	return map[string]interface{}{}
+}
@@ -0,0 +1,203 @@
+package beam
+
+// driverModule is compiled and loaded when the BEAM node starts. It reads
+// {Id, Op, Name, Code, Args} requests framed with a 4-byte length from stdin
+// and answers each with {Id, ok | error, Value, Output} on stdout. Output
+// printed while a request runs is collected by a temporary group leader.
+const driverModule = `-module(funterm_beam).
+-export([main/1]).
+
+main(Dialect) ->
+    catch logger:update_handler_config(default, config, #{type => standard_error}),
+    Port = open_port({fd, 0, 1}, [{packet, 4}, binary, eof]),
+    loop(Port, Dialect, new_bindings(Dialect)).
+
+loop(Port, Dialect, Bindings) ->
+    receive
+        {Port, {data, Data}} ->
+            {Id, Op, Name, Code, Args} = binary_to_term(Data),
+            Capture = spawn(fun() -> capture(<<>>) end),
+            Previous = group_leader(),
+            group_leader(Capture, self()),
+            {Status, Value, NewBindings} =
+                try run(Dialect, Op, Name, Code, Args, Bindings) of
+                    {Result, Updated} -> {ok, plain(Result), Updated}
+                catch
+                    Class:Reason:Stack -> {error, format_error(Dialect, Class, Reason, Stack), Bindings}
+                end,
+            group_leader(Previous, self()),
+            Output = collect(Capture),
+            port_command(Port, term_to_binary({Id, Status, Value, Output})),
+            loop(Port, Dialect, NewBindings);
+        {Port, eof} ->
+            halt(0)
+    end.
+
+run(Dialect, call, Name, _Code, Args, Bindings) ->
+    {call(Dialect, Name, Args, Bindings), Bindings};
+run(erlang, eval, _Name, Code, _Args, Bindings) ->
+    eval_erlang(Code, Bindings);
+run(elixir, eval, _Name, Code, _Args, Bindings) ->
+    'Elixir.Code':eval_string(Code, Bindings);
+run(Dialect, set, Name, _Code, [Value], Bindings) ->
+    {ok, set_binding(Dialect, variable(Dialect, Name), Value, Bindings)};
+run(Dialect, get, Name, _Code, _Args, Bindings) ->
+    case get_binding(Dialect, variable(Dialect, Name), Bindings) of
+        {value, Value} -> {Value, Bindings};
+        unbound -> throw({message, io_lib:format("variable '~ts' not found", [Name])})
+    end.
+
+%% Module.function calls go to the module, Elixir.Module for capitalized names
+%% in Elixir; plain names call a fun bound to that variable, or a BIF
+call(Dialect, Name, Args, Bindings) ->
+    Parts = binary:split(Name, <<".">>, [global]),
+    {ModuleParts, [Function]} = lists:split(length(Parts) - 1, Parts),
+    case ModuleParts of
+        [] ->
+            case get_binding(Dialect, variable(Dialect, Function), Bindings) of
+                {value, Fun} when is_function(Fun) -> apply(Fun, Args);
+                _ when Dialect =:= elixir -> apply('Elixir.Kernel', binary_to_atom(Function, utf8), Args);
+                _ -> apply(erlang, binary_to_atom(Function, utf8), Args)
+            end;
+        _ ->
+            apply(module(Dialect, ModuleParts), binary_to_atom(Function, utf8), Args)
+    end.
+
+module(elixir, [<<C, _/binary>> | _] = Parts) when C >= $A, C =< $Z ->
+    binary_to_atom(iolist_to_binary(lists:join(<<".">>, [<<"Elixir">> | Parts])), utf8);
+module(_, Parts) ->
+    binary_to_atom(iolist_to_binary(lists:join(<<".">>, Parts)), utf8).
+
+%% Erlang variables are capitalized, so funterm's count is Count
+variable(erlang, <<C, Rest/binary>>) when C >= $a, C =< $z ->
+    binary_to_atom(<<(C - 32), Rest/binary>>, utf8);
+variable(_, Name) ->
+    binary_to_atom(Name, utf8).
+
+new_bindings(erlang) -> erl_eval:new_bindings();
+new_bindings(elixir) -> [].
+
+set_binding(erlang, Name, Value, Bindings) -> erl_eval:add_binding(Name, Value, Bindings);
+set_binding(elixir, Name, Value, Bindings) -> lists:keystore(Name, 1, Bindings, {Name, Value}).
+
+get_binding(erlang, Name, Bindings) -> erl_eval:binding(Name, Bindings);
+get_binding(elixir, Name, Bindings) ->
+    case lists:keyfind(Name, 1, Bindings) of
+        {Name, Value} -> {value, Value};
+        false -> unbound
+    end.
+
+%% Code starting with an attribute is a module to compile and load; anything
+%% else is a sequence of dot-terminated expressions evaluated in the shell's
+%% bindings
+eval_erlang(Code, Bindings) ->
+    Tokens = case erl_scan:string(unicode:characters_to_list(Code)) of
+        {ok, Scanned, _} -> Scanned;
+        {error, {Location, Module, Error}, _} -> throw({message, describe(Location, Module, Error)})
+    end,
+    case Tokens of
+        [] -> {undefined, Bindings};
+        [{'-', _} | _] -> {load_module(chunks(Tokens)), Bindings};
+        _ -> eval_chunks(chunks(Tokens), undefined, Bindings)
+    end.
+
+chunks(Tokens) ->
+    chunks(Tokens, [], []).
+
+chunks([], [], Chunks) ->
+    lists:reverse(Chunks);
+chunks([], Current, Chunks) ->
+    Location = element(2, hd(Current)),
+    chunks([], [], [lists:reverse([{dot, Location} | Current]) | Chunks]);
+chunks([{dot, _} = Dot | Rest], Current, Chunks) ->
+    chunks(Rest, [], [lists:reverse([Dot | Current]) | Chunks]);
+chunks([Token | Rest], Current, Chunks) ->
+    chunks(Rest, [Token | Current], Chunks).
+
+eval_chunks([], Value, Bindings) ->
+    {Value, Bindings};
+eval_chunks([Chunk | Rest], _, Bindings) ->
+    case erl_parse:parse_exprs(Chunk) of
+        {ok, Exprs} ->
+            {value, Value, NewBindings} = erl_eval:exprs(Exprs, Bindings),
+            eval_chunks(Rest, Value, NewBindings);
+        {error, {Location, Module, Error}} ->
+            throw({message, describe(Location, Module, Error)})
+    end.
+
+load_module(Chunks) ->
+    Forms = [case erl_parse:parse_form(Chunk) of
+                 {ok, Form} -> Form;
+                 {error, {Location, Module, Error}} -> throw({message, describe(Location, Module, Error)})
+             end || Chunk <- Chunks],
+    case compile:forms(Forms, [binary, return_errors]) of
+        {ok, Module, Binary} ->
+            code:purge(Module),
+            {module, Module} = code:load_binary(Module, "funterm", Binary),
+            Module;
+        {error, [{_, [{Location, Module, Error} | _]} | _], _} ->
+            throw({message, describe(Location, Module, Error)})
+    end.
+
+describe({Line, _Column}, Module, Error) ->
+    describe(Line, Module, Error);
+describe(Line, Module, Error) ->
+    io_lib:format("line ~p: ~ts", [Line, Module:format_error(Error)]).
+
+format_error(_, throw, {message, Message}, _) ->
+    unicode:characters_to_binary(Message);
+format_error(elixir, Class, Reason, Stack) ->
+    'Elixir.Exception':format_banner(Class, Reason, Stack);
+format_error(erlang, Class, Reason, _) ->
+    unicode:characters_to_binary(io_lib:format("~p: ~tp", [Class, Reason])).
+
+%% plain turns pids, references, ports and funs into their printed form
+plain(Value) when is_list(Value) -> plain_list(Value);
+plain(Value) when is_tuple(Value) -> list_to_tuple([plain(Item) || Item <- tuple_to_list(Value)]);
+plain(Value) when is_map(Value) -> maps:from_list([{plain(K), plain(V)} || {K, V} <- maps:to_list(Value)]);
+plain(Value) when is_atom(Value); is_number(Value); is_bitstring(Value) -> Value;
+plain(Value) -> unicode:characters_to_binary(io_lib:format("~p", [Value])).
+
+plain_list([]) -> [];
+plain_list([Head | Tail]) -> [plain(Head) | plain_list(Tail)];
+plain_list(Tail) -> plain(Tail).
+
+%% capture is an io server that keeps everything written to it
+capture(Output) ->
+    receive
+        {io_request, From, ReplyAs, Request} ->
+            {Reply, NewOutput} = io_request(Request, Output),
+            From ! {io_reply, ReplyAs, Reply},
+            capture(NewOutput);
+        {collect, From} ->
+            From ! {captured, self(), Output}
+    end.
+
+collect(Capture) ->
+    Capture ! {collect, self()},
+    receive
+        {captured, Capture, Output} -> Output
+    end.
+
+io_request({put_chars, Encoding, Chars}, Output) ->
+    case unicode:characters_to_binary(Chars, Encoding) of
+        Text when is_binary(Text) -> {ok, <<Output/binary, Text/binary>>};
+        _ -> {{error, put_chars}, Output}
+    end;
+io_request({put_chars, Encoding, Module, Function, Args}, Output) ->
+    io_request({put_chars, Encoding, apply(Module, Function, Args)}, Output);
+io_request({put_chars, Chars}, Output) ->
+    io_request({put_chars, latin1, Chars}, Output);
+io_request({put_chars, Module, Function, Args}, Output) ->
+    io_request({put_chars, latin1, apply(Module, Function, Args)}, Output);
+io_request({requests, Requests}, Output) ->
+    lists:foldl(fun(Request, {_, Acc}) -> io_request(Request, Acc) end, {ok, Output}, Requests);
+io_request(getopts, Output) ->
+    {[{binary, false}, {encoding, unicode}], Output};
+io_request(Request, Output) when is_tuple(Request), element(1, Request) =:= get_chars;
+                                 is_tuple(Request), element(1, Request) =:= get_line;
+                                 is_tuple(Request), element(1, Request) =:= get_until ->
+    {eof, Output};
+io_request(_, Output) ->
+    {{error, request}, Output}.
+`
@@ -0,0 +1,511 @@
+package beam
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"funterm/errors"
+	"funterm/runtime"
+	"funterm/shared"
+)
+
+// Dialect selects the language spoken on a BEAM node
+type Dialect string
+
+const (
+	// Erlang evaluates Erlang expressions and compiles -module forms
+	Erlang Dialect = "erlang"
+	// Elixir evaluates Elixir code with Code.eval_string
+	Elixir Dialect = "elixir"
+)
+
+// beamResponse is the driver's answer to a request
+type beamResponse struct {
+	id     int64
+	ok     bool
+	value  interface{}
+	output string
+}
+
+// BeamRuntime implements the LanguageRuntime interface for Erlang and Elixir.
+// Each runtime runs one BEAM node that speaks the external term format over
+// stdin/stdout, so binaries and bitstrings cross unchanged in both directions.
+// The node is started on first use.
+type BeamRuntime struct {
+	dialect          Dialect
+	ready            bool
+	executable       string
+	mutex            sync.Mutex
+	executionTimeout time.Duration
+	outputCapture    strings.Builder // output printed by the last call or block
+	cmd              *exec.Cmd
+	stdin            io.WriteCloser
+	frames           chan []byte
+	driverDir        string // temporary directory holding the driver source
+	nextID           int64
+	cassette         *runtime.Cassette // Record/replay of node traffic
+	// Environment and locale of the node
+	processOptions runtime.ProcessOptions
+}
+
+// NewErlangRuntime creates a runtime that runs Erlang code on erl
+func NewErlangRuntime() *BeamRuntime {
+	return newBeamRuntime(Erlang, "erl")
+}
+
+// NewElixirRuntime creates a runtime that runs Elixir code on elixir
+func NewElixirRuntime() *BeamRuntime {
+	return newBeamRuntime(Elixir, "elixir")
+}
+
+func newBeamRuntime(dialect Dialect, executable string) *BeamRuntime {
+	return &BeamRuntime{
+		dialect:          dialect,
+		executable:       executable,
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// nilAtom is the atom funterm's nil maps to
+func (br *BeamRuntime) nilAtom() atom {
+	if br.dialect == Elixir {
+		return "nil"
+	}
+	return "undefined"
+}
+
+// SetExecutable sets the erl or elixir executable to run; an empty path keeps the default
+func (br *BeamRuntime) SetExecutable(path string) {
+	if path != "" {
+		br.executable = path
+	}
+}
+
+// SetCassette attaches a cassette for recording or replaying node traffic
+func (br *BeamRuntime) SetCassette(cassette *runtime.Cassette) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.cassette = cassette
+}
+
+// SetProcessOptions sets the environment and locale of the node
+func (br *BeamRuntime) SetProcessOptions(options runtime.ProcessOptions) error {
+	if err := options.Validate(string(br.dialect)); err != nil {
+		return err
+	}
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.processOptions = options
+	return nil
+}
+
+// SetExecutionTimeout limits the run time of a single request
+func (br *BeamRuntime) SetExecutionTimeout(timeout time.Duration) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.executionTimeout = timeout
+}
+
+// Initialize marks the runtime ready; the node starts on first use
+func (br *BeamRuntime) Initialize() error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.ready = true
+	return nil
+}
+
+// startProcess writes the driver module to a temporary directory and starts
+// a node that compiles and runs it
+func (br *BeamRuntime) startProcess() error {
+	path, err := runtime.ResolveExecutable(br.executable)
+	if err != nil {
+		product := "Erlang/OTP"
+		if br.dialect == Elixir {
+			product = "Elixir"
+		}
+		return fmt.Errorf("'%s' executable not found in PATH. Please install %s", br.executable, product)
+	}
+
+	dir, err := os.MkdirTemp("", "funterm-beam-")
+	if err != nil {
+		return err
+	}
+	source := filepath.Join(dir, "funterm_beam.erl")
+	if err := os.WriteFile(source, []byte(driverModule), 0o600); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	source = strings.ReplaceAll(filepath.ToSlash(source), `"`, `\"`)
+
+	// -noinput keeps the node's own io server from reading the protocol stream
+	var cmd *exec.Cmd
+	if br.dialect == Elixir {
+		boot := fmt.Sprintf(`path = String.to_charlist("%s"); {:ok, mod, bin} = :compile.file(path, [:binary]); {:module, mod} = :code.load_binary(mod, path, bin); mod.main(:elixir)`, source)
+		cmd = exec.Command(path, "--erl", "-noinput", "-e", boot)
+	} else {
+		boot := fmt.Sprintf(`{ok, Mod, Bin} = compile:file("%s", [binary]), {module, Mod} = code:load_binary(Mod, "%s", Bin), Mod:main(erlang).`, source, source)
+		cmd = exec.Command(path, "-noinput", "-eval", boot)
+	}
+	runtime.PrepareCommand(cmd)
+	cmd.Env = br.processOptions.Environ()
+	cmd.Stderr = os.Stderr // logger reports and crashes outside requests
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to start %s: %w", br.executable, err)
+	}
+	runtime.TrackProcess(cmd)
+	runtime.TrackTempPath(dir)
+
+	br.cmd = cmd
+	br.stdin = stdin
+	br.driverDir = dir
+	br.frames = make(chan []byte)
+	go readFrames(stdout, br.frames)
+	return nil
+}
+
+// readFrames reads length-prefixed frames until the node exits
+func readFrames(pipe io.Reader, ch chan<- []byte) {
+	defer close(ch)
+	reader := bufio.NewReader(pipe)
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxFrameSize {
+			// Not a frame: something other than the driver wrote to stdout
+			return
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return
+		}
+		ch <- frame
+	}
+}
+
+// stopProcess kills the node; the next request starts a fresh one
+func (br *BeamRuntime) stopProcess() {
+	if br.cmd == nil {
+		return
+	}
+	br.stdin.Close()
+	runtime.KillProcessTree(br.cmd)
+	br.cmd.Wait()
+	br.cmd = nil
+	runtime.RemoveTempPath(br.driverDir)
+}
+
+// encodeRequest encodes a request as an external term
+func (br *BeamRuntime) encodeRequest(id int64, op, name, code string, args []interface{}) ([]byte, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	return encodeTerm(tuple{id, atom(op), name, code, args}, br.nilAtom())
+}
+
+// decodeResponse decodes a {Id, ok | error, Value, Output} frame
+func (br *BeamRuntime) decodeResponse(frame []byte) (beamResponse, error) {
+	term, err := decodeTerm(frame, br.nilAtom())
+	if err != nil {
+		return beamResponse{}, fmt.Errorf("invalid response from %s: %v", br.dialect, err)
+	}
+	fields, ok := term.([]interface{})
+	if !ok || len(fields) != 4 {
+		return beamResponse{}, fmt.Errorf("invalid response from %s", br.dialect)
+	}
+	id, _ := fields[0].(int64)
+	output, _ := fields[3].(string)
+	if bits, ok := fields[3].(*shared.BitstringObject); ok {
+		output = string(bits.Bytes())
+	}
+	return beamResponse{id: id, ok: fields[1] == "ok", value: fields[2], output: output}, nil
+}
+
+// send performs one request, through the cassette if one is attached
+func (br *BeamRuntime) send(op, name, code string, args []interface{}) (response beamResponse, err error) {
+	if runtime.Tracing() {
+		defer func() {
+			status := "error"
+			if response.ok {
+				status = "ok"
+			}
+			request := fmt.Sprintf("%s %s %#v", op, name, args)
+			if code != "" {
+				request = fmt.Sprintf("%s %s", op, code)
+			}
+			runtime.Trace(string(br.dialect), request, fmt.Sprintf("%s %#v", status, response.value), err)
+		}()
+	}
+	if br.cassette == nil {
+		return br.sendToProcess(op, name, code, args)
+	}
+
+	// Requests are recorded without their id, so the key is stable across runs
+	key, err := br.encodeRequest(0, op, name, code, args)
+	if err != nil {
+		return beamResponse{}, err
+	}
+	language := string(br.dialect)
+	if br.cassette.Replaying() {
+		it, err := br.cassette.Next(language, base64.StdEncoding.EncodeToString(key))
+		if err != nil {
+			return beamResponse{}, err
+		}
+		line, err := it.Result()
+		if err != nil {
+			return beamResponse{}, err
+		}
+		frame, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return beamResponse{}, fmt.Errorf("invalid recorded %s response: %v", language, err)
+		}
+		return br.decodeResponse(frame)
+	}
+
+	frame, err := br.exchange(op, name, code, args)
+	line := ""
+	if err == nil {
+		line = base64.StdEncoding.EncodeToString(frame)
+	}
+	br.cassette.Record(language, base64.StdEncoding.EncodeToString(key), line, "", err)
+	if err != nil {
+		return beamResponse{}, err
+	}
+	return br.decodeResponse(frame)
+}
+
+// sendToProcess performs a request against the node and decodes the answer
+func (br *BeamRuntime) sendToProcess(op, name, code string, args []interface{}) (beamResponse, error) {
+	frame, err := br.exchange(op, name, code, args)
+	if err != nil {
+		return beamResponse{}, err
+	}
+	return br.decodeResponse(frame)
+}
+
+// exchange writes a request frame to the node and waits for its answer frame
+func (br *BeamRuntime) exchange(op, name, code string, args []interface{}) ([]byte, error) {
+	if br.cmd == nil {
+		if err := br.startProcess(); err != nil {
+			return nil, err
+		}
+	}
+
+	br.nextID++
+	id := br.nextID
+	payload, err := br.encodeRequest(id, op, name, code, args)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	if _, err := br.stdin.Write(frame); err != nil {
+		br.stopProcess()
+		return nil, fmt.Errorf("failed to write to %s stdin: %w", br.dialect, err)
+	}
+
+	timeout := time.After(br.executionTimeout)
+	for {
+		select {
+		case answer, ok := <-br.frames:
+			if !ok {
+				br.stopProcess()
+				return nil, fmt.Errorf("%s process exited unexpectedly", br.dialect)
+			}
+			// Answers to requests that timed out earlier are skipped
+			if response, err := br.decodeResponse(answer); err == nil && response.id == id {
+				return answer, nil
+			}
+		case <-timeout:
+			// The node is stuck in the request; restart it instead of waiting
+			br.stopProcess()
+			return nil, fmt.Errorf("%s execution timed out after %v, the node was restarted", br.dialect, br.executionTimeout)
+		}
+	}
+}
+
+// request performs a request and returns its value; printed output is
+// appended to the output capture
+func (br *BeamRuntime) request(op, name, code string, args []interface{}) (interface{}, error) {
+	language := string(br.dialect)
+	if !br.ready {
+		return nil, errors.NewRuntimeError(language, "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+	response, err := br.send(op, name, code, args)
+	if err != nil {
+		return nil, errors.NewRuntimeError(language, "EXECUTION_FAILED", err.Error())
+	}
+	br.outputCapture.WriteString(response.output)
+	if !response.ok {
+		return nil, errors.NewRuntimeError(language, "EXECUTION_FAILED", fmt.Sprint(response.value))
+	}
+	return response.value, nil
+}
+
+// ExecuteFunction calls a function: module.function calls into a module
+// (String.upcase reaches Elixir.String in Elixir), a plain name calls a fun
+// bound to that variable or a BIF
+func (br *BeamRuntime) ExecuteFunction(name string, args []interface{}) (interface{}, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.outputCapture.Reset()
+	return br.request("call", name, "", args)
+}
+
+// ExecuteFunctionMultiple calls a function and returns a returned list or
+// tuple as multiple values
+func (br *BeamRuntime) ExecuteFunctionMultiple(functionName string, args ...interface{}) ([]interface{}, error) {
+	result, err := br.ExecuteFunction(functionName, args)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := result.([]interface{}); ok {
+		return values, nil
+	}
+	return []interface{}{result}, nil
+}
+
+// Eval evaluates code and returns the value of its last expression. Erlang
+// code starting with -module(...) is compiled and loaded as a module.
+func (br *BeamRuntime) Eval(code string) (interface{}, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.outputCapture.Reset()
+	return br.request("eval", "", code, nil)
+}
+
+// ExecuteCodeBlock runs a code block and returns what it printed
+func (br *BeamRuntime) ExecuteCodeBlock(code string) (interface{}, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.outputCapture.Reset()
+	if _, err := br.request("eval", "", code, nil); err != nil {
+		return nil, err
+	}
+	if output := br.takeOutput(); output != "" {
+		return output, nil
+	}
+	return nil, nil
+}
+
+// ExecuteCodeBlockWithVariables runs a code block. Bindings persist between
+// blocks anyway, so the variable list is not needed.
+func (br *BeamRuntime) ExecuteCodeBlockWithVariables(code string, variables []string) (interface{}, error) {
+	return br.ExecuteCodeBlock(code)
+}
+
+// EvaluateBlock runs a code block used as a value and returns its last
+// expression; printed output is kept for GetCapturedOutput
+func (br *BeamRuntime) EvaluateBlock(code string) (interface{}, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.outputCapture.Reset()
+	return br.request("eval", "", code, nil)
+}
+
+// ExecuteBatch executes code printing all output directly
+func (br *BeamRuntime) ExecuteBatch(code string) error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.outputCapture.Reset()
+	_, err := br.request("eval", "", code, nil)
+	if output := br.takeOutput(); output != "" {
+		fmt.Println(output)
+	}
+	return err
+}
+
+// GetCapturedOutput returns and clears the output of the last call or block
+func (br *BeamRuntime) GetCapturedOutput() string {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	return br.takeOutput()
+}
+
+func (br *BeamRuntime) takeOutput() string {
+	output := strings.TrimSuffix(br.outputCapture.String(), "\n")
+	br.outputCapture.Reset()
+	return output
+}
+
+// SetVariable binds a variable for later code; in Erlang the name is
+// capitalized, so count becomes Count
+func (br *BeamRuntime) SetVariable(name string, value interface{}) error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	_, err := br.request("set", name, "", []interface{}{value})
+	return err
+}
+
+// GetVariable reads a variable bound by earlier code or SetVariable
+func (br *BeamRuntime) GetVariable(name string) (interface{}, error) {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	return br.request("get", name, "", nil)
+}
+
+// Isolate restarts the node, dropping all bindings and loaded modules
+func (br *BeamRuntime) Isolate() error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.stopProcess()
+	return nil
+}
+
+// Cleanup stops the node
+func (br *BeamRuntime) Cleanup() error {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	br.stopProcess()
+	br.ready = false
+	return nil
+}
+
+// GetSupportedTypes returns the types supported by this runtime
+func (br *BeamRuntime) GetSupportedTypes() []string {
+	return []string{"integer", "float", "atom", "binary", "bitstring", "list", "tuple", "map"}
+}
+
+// GetName returns the name of the language runtime
+func (br *BeamRuntime) GetName() string {
+	return string(br.dialect)
+}
+
+// ProcessID returns the pid of the node, 0 when it isn't running
+func (br *BeamRuntime) ProcessID() int {
+	br.mutex.Lock()
+	defer br.mutex.Unlock()
+	if br.cmd == nil || br.cmd.Process == nil {
+		return 0
+	}
+	return br.cmd.Process.Pid
+}
+
+// IsReady checks if the runtime is ready for execution
+func (br *BeamRuntime) IsReady() bool {
+	return br.ready
+}
@@ -0,0 +1,507 @@
+package beam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"funterm/shared"
+
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// Tags of the Erlang external term format (term_to_binary/binary_to_term)
+const (
+	etfVersion       = 131
+	etfNewFloat      = 70
+	etfBitBinary     = 77
+	etfSmallInteger  = 97
+	etfInteger       = 98
+	etfAtom          = 100
+	etfSmallTuple    = 104
+	etfLargeTuple    = 105
+	etfNil           = 106
+	etfString        = 107
+	etfList          = 108
+	etfBinary        = 109
+	etfSmallBig      = 110
+	etfLargeBig      = 111
+	etfSmallAtom     = 115
+	etfMap           = 116
+	etfAtomUTF8      = 118
+	etfSmallAtomUTF8 = 119
+)
+
+// maxFrameSize bounds a frame read from the node
+const maxFrameSize uint32 = 1 << 30
+
+// atom is an Erlang atom
+type atom string
+
+// tuple is an Erlang tuple
+type tuple []interface{}
+
+// termEncoder writes Go values as external terms. nilAtom is the atom nil
+// becomes: undefined in Erlang, nil in Elixir.
+type termEncoder struct {
+	buf     bytes.Buffer
+	nilAtom atom
+}
+
+// encodeTerm encodes value as a complete term_to_binary payload
+func encodeTerm(value interface{}, nilAtom atom) ([]byte, error) {
+	e := &termEncoder{nilAtom: nilAtom}
+	e.buf.WriteByte(etfVersion)
+	if err := e.encode(value); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+func (e *termEncoder) encode(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		e.encodeAtom(e.nilAtom)
+	case atom:
+		e.encodeAtom(v)
+	case bool:
+		if v {
+			e.encodeAtom("true")
+		} else {
+			e.encodeAtom("false")
+		}
+	case int:
+		e.encodeInt(int64(v))
+	case int8:
+		e.encodeInt(int64(v))
+	case int16:
+		e.encodeInt(int64(v))
+	case int32:
+		e.encodeInt(int64(v))
+	case int64:
+		e.encodeInt(v)
+	case uint8:
+		e.encodeInt(int64(v))
+	case uint16:
+		e.encodeInt(int64(v))
+	case uint32:
+		e.encodeInt(int64(v))
+	case uint:
+		e.encodeBig(new(big.Int).SetUint64(uint64(v)))
+	case uint64:
+		e.encodeBig(new(big.Int).SetUint64(v))
+	case *big.Int:
+		e.encodeBig(v)
+	case float32:
+		e.encodeFloat(float64(v))
+	case float64:
+		e.encodeFloat(v)
+	case string:
+		e.encodeBinary([]byte(v), 8)
+	case []byte:
+		e.encodeBinary(v, 8)
+	case shared.BitstringByte:
+		e.encodeInt(int64(v.Value))
+	case *shared.BitstringObject:
+		bits := v.Len() % 8
+		if bits == 0 {
+			bits = 8
+		}
+		e.encodeBinary(v.Bytes(), bits)
+	case tuple:
+		if len(v) < 256 {
+			e.buf.WriteByte(etfSmallTuple)
+			e.buf.WriteByte(byte(len(v)))
+		} else {
+			e.buf.WriteByte(etfLargeTuple)
+			e.writeUint32(uint32(len(v)))
+		}
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			e.buf.WriteByte(etfList)
+			e.writeUint32(uint32(len(v)))
+			for _, item := range v {
+				if err := e.encode(item); err != nil {
+					return err
+				}
+			}
+		}
+		e.buf.WriteByte(etfNil)
+	case map[string]interface{}:
+		// Sorted keys keep the encoding stable for cassettes
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		e.buf.WriteByte(etfMap)
+		e.writeUint32(uint32(len(v)))
+		for _, key := range keys {
+			e.encodeBinary([]byte(key), 8)
+			if err := e.encode(v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("values of type %T cannot be passed to BEAM code", value)
+	}
+	return nil
+}
+
+func (e *termEncoder) writeUint32(n uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	e.buf.Write(b[:])
+}
+
+func (e *termEncoder) encodeAtom(name atom) {
+	if len(name) < 256 {
+		e.buf.WriteByte(etfSmallAtomUTF8)
+		e.buf.WriteByte(byte(len(name)))
+	} else {
+		e.buf.WriteByte(etfAtomUTF8)
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(len(name)))
+		e.buf.Write(b[:])
+	}
+	e.buf.WriteString(string(name))
+}
+
+func (e *termEncoder) encodeInt(n int64) {
+	switch {
+	case n >= 0 && n <= 255:
+		e.buf.WriteByte(etfSmallInteger)
+		e.buf.WriteByte(byte(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		e.buf.WriteByte(etfInteger)
+		e.writeUint32(uint32(int32(n)))
+	default:
+		e.encodeBig(big.NewInt(n))
+	}
+}
+
+// encodeBig writes an integer as a bignum: sign byte, then little-endian digits
+func (e *termEncoder) encodeBig(n *big.Int) {
+	if n.IsInt64() && n.Int64() >= math.MinInt32 && n.Int64() <= math.MaxInt32 {
+		e.encodeInt(n.Int64())
+		return
+	}
+	magnitude := new(big.Int).Abs(n).Bytes()
+	for i, j := 0, len(magnitude)-1; i < j; i, j = i+1, j-1 {
+		magnitude[i], magnitude[j] = magnitude[j], magnitude[i]
+	}
+	if len(magnitude) < 256 {
+		e.buf.WriteByte(etfSmallBig)
+		e.buf.WriteByte(byte(len(magnitude)))
+	} else {
+		e.buf.WriteByte(etfLargeBig)
+		e.writeUint32(uint32(len(magnitude)))
+	}
+	if n.Sign() < 0 {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+	e.buf.Write(magnitude)
+}
+
+func (e *termEncoder) encodeFloat(f float64) {
+	e.buf.WriteByte(etfNewFloat)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+	e.buf.Write(b[:])
+}
+
+// encodeBinary writes a binary, or a bitstring when the last byte holds
+// fewer than 8 bits
+func (e *termEncoder) encodeBinary(data []byte, lastBits int) {
+	if lastBits == 8 || len(data) == 0 {
+		e.buf.WriteByte(etfBinary)
+		e.writeUint32(uint32(len(data)))
+	} else {
+		e.buf.WriteByte(etfBitBinary)
+		e.writeUint32(uint32(len(data)))
+		e.buf.WriteByte(byte(lastBits))
+	}
+	e.buf.Write(data)
+}
+
+// termDecoder reads external terms into funterm values. Binaries holding
+// printable UTF-8 text become strings, other binaries bitstrings; tuples
+// become lists and atoms strings, except true, false and the nil atom.
+type termDecoder struct {
+	data    []byte
+	pos     int
+	nilAtom atom
+}
+
+// decodeTerm decodes a complete term_to_binary payload
+func decodeTerm(data []byte, nilAtom atom) (interface{}, error) {
+	if len(data) == 0 || data[0] != etfVersion {
+		return nil, fmt.Errorf("not an external term")
+	}
+	d := &termDecoder{data: data, pos: 1, nilAtom: nilAtom}
+	value, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("%d trailing bytes after term", len(data)-d.pos)
+	}
+	return value, nil
+}
+
+func (d *termDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, fmt.Errorf("truncated term")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *termDecoder) readByte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *termDecoder) readUint16() (int, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *termDecoder) readUint32() (int, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *termDecoder) decode() (interface{}, error) {
+	tag, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case etfSmallInteger:
+		b, err := d.readByte()
+		return int64(b), err
+	case etfInteger:
+		n, err := d.readUint32()
+		return int64(int32(uint32(n))), err
+	case etfNewFloat:
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case etfSmallBig, etfLargeBig:
+		var n int
+		if tag == etfSmallBig {
+			b, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			n = int(b)
+		} else if n, err = d.readUint32(); err != nil {
+			return nil, err
+		}
+		sign, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		digits, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		bigEndian := make([]byte, n)
+		for i, digit := range digits {
+			bigEndian[n-1-i] = digit
+		}
+		value := new(big.Int).SetBytes(bigEndian)
+		if sign != 0 {
+			value.Neg(value)
+		}
+		if value.IsInt64() {
+			return value.Int64(), nil
+		}
+		return value, nil
+	case etfAtom, etfAtomUTF8:
+		n, err := d.readUint16()
+		if err != nil {
+			return nil, err
+		}
+		return d.atom(n)
+	case etfSmallAtom, etfSmallAtomUTF8:
+		n, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return d.atom(int(n))
+	case etfBinary:
+		n, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		if isText(data) {
+			return string(data), nil
+		}
+		return shared.NewBitstringObjectFromBytes(append([]byte(nil), data...)), nil
+	case etfBitBinary:
+		n, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		bits, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		length := uint(n) * 8
+		if n > 0 {
+			length = uint(n-1)*8 + uint(bits)
+		}
+		return shared.NewBitstringObject(funbit.NewBitStringFromBits(append([]byte(nil), data...), length)), nil
+	case etfSmallTuple, etfLargeTuple:
+		var n int
+		if tag == etfSmallTuple {
+			b, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			n = int(b)
+		} else if n, err = d.readUint32(); err != nil {
+			return nil, err
+		}
+		return d.items(n)
+	case etfNil:
+		return []interface{}{}, nil
+	case etfString:
+		// Lists of small integers; charlists read as text stay strings
+		n, err := d.readUint16()
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		if isText(data) {
+			return string(data), nil
+		}
+		items := make([]interface{}, n)
+		for i, b := range data {
+			items[i] = int64(b)
+		}
+		return items, nil
+	case etfList:
+		n, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		items, err := d.items(n)
+		if err != nil {
+			return nil, err
+		}
+		tail, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		// An improper list keeps its tail as the last element
+		if t, ok := tail.([]interface{}); !ok || len(t) > 0 {
+			items = append(items, tail)
+		}
+		return items, nil
+	case etfMap:
+		n, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := key.(string); ok {
+				result[s] = value
+			} else {
+				result[fmt.Sprint(key)] = value
+			}
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported term tag %d", tag)
+	}
+}
+
+func (d *termDecoder) atom(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	switch name := atom(b); name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case d.nilAtom:
+		return nil, nil
+	default:
+		return string(name), nil
+	}
+}
+
+func (d *termDecoder) items(n int) ([]interface{}, error) {
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// isText reports whether data is UTF-8 without control characters other than
+// tabs and line breaks
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
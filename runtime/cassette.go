@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"funterm/errors"
+)
+
+// Interaction is a single request/response exchange with an interpreter process
+type Interaction struct {
+	Runtime  string `json:"runtime"`
+	Request  string `json:"request"`
+	Response string `json:"response"`
+	Output   string `json:"output,omitempty"` // print output captured during the call
+	Error    string `json:"error,omitempty"`
+}
+
+// Cassette records runtime interactions to a file or serves them back.
+// The file holds one JSON-encoded Interaction per line.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	replay       bool
+	file         *os.File
+	encoder      *json.Encoder
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecordingCassette creates (or truncates) a cassette file for recording
+func NewRecordingCassette(path string) (*Cassette, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.NewSystemError("CASSETTE_CREATE_FAILED", fmt.Sprintf("failed to create cassette '%s': %v", path, err))
+	}
+	return &Cassette{
+		path:    path,
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// LoadCassette reads a previously recorded cassette for replay
+func LoadCassette(path string) (*Cassette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.NewSystemError("CASSETTE_OPEN_FAILED", fmt.Sprintf("failed to open cassette '%s': %v", path, err))
+	}
+	defer file.Close()
+
+	c := &Cassette{path: path, replay: true}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var it Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &it); err != nil {
+			return nil, errors.NewUserError("CASSETTE_PARSE_ERROR", fmt.Sprintf("%s:%d: %v", path, line, err))
+		}
+		c.interactions = append(c.interactions, it)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.NewSystemError("CASSETTE_READ_FAILED", fmt.Sprintf("failed to read cassette '%s': %v", path, err))
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Replaying reports whether interactions are served from the cassette
+func (c *Cassette) Replaying() bool {
+	return c != nil && c.replay
+}
+
+// Record appends an interaction; it is written immediately so that
+// a session that crashes or exits early still leaves a usable cassette
+func (c *Cassette) Record(runtimeName, request, response, output string, callErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it := Interaction{Runtime: runtimeName, Request: request, Response: response, Output: output}
+	if callErr != nil {
+		it.Error = callErr.Error()
+	}
+	// Recording writes straight to the file; keeping every interaction in
+	// memory as well would grow without bound in a long session
+	if c.replay {
+		c.interactions = append(c.interactions, it)
+		c.used = append(c.used, true)
+	}
+	if c.encoder != nil {
+		if err := c.encoder.Encode(it); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write cassette '%s': %v\n", c.path, err)
+		}
+	}
+}
+
+// Next returns the earliest unused interaction with the same runtime and request.
+// Matching by request rather than strict position tolerates housekeeping
+// calls that only happen when a real interpreter is running.
+func (c *Cassette) Next(runtimeName, request string) (Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, it := range c.interactions {
+		if !c.used[i] && it.Runtime == runtimeName && it.Request == request {
+			c.used[i] = true
+			return it, nil
+		}
+	}
+	return Interaction{}, errors.NewRuntimeError(runtimeName, "CASSETTE_MISS", fmt.Sprintf("no recorded interaction in '%s' for request: %s", c.path, request))
+}
+
+// Result converts a replayed interaction back into the values returned by the live call
+func (it Interaction) Result() (string, error) {
+	if it.Error != "" {
+		return "", fmt.Errorf("%s", it.Error)
+	}
+	return it.Response, nil
+}
+
+// Close flushes and closes the cassette file
+func (c *Cassette) Close() error {
+	if c == nil || c.file == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.file.Close()
+	c.file = nil
+	c.encoder = nil
+	return err
+}
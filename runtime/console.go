@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// errNoConsole means a pseudo console can't be used, so the interpreter is
+// started with plain pipes
+var errNoConsole = errors.New("no pseudo console")
+
+// StartInterpreter starts an interpreter prepared with PrepareCommand and
+// returns the pipes of its stdin, stdout and stderr. On Windows it is
+// attached to a pseudo console (ConPTY): a console program then has a
+// console of its own, for console APIs, Ctrl+C events and the programs it
+// starts, while the pipes still carry the exchange with funterm. Without
+// ConPTY, on older Windows and on other systems, only the pipes are used
+func StartInterpreter(cmd *exec.Cmd) (stdin io.WriteCloser, stdout, stderr io.ReadCloser, err error) {
+	stdin, stdout, stderr, err = startWithConsole(cmd)
+	if !errors.Is(err, errNoConsole) {
+		return stdin, stdout, stderr, err
+	}
+
+	if stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, nil, nil, err
+	}
+	if stdout, err = cmd.StdoutPipe(); err != nil {
+		return nil, nil, nil, err
+	}
+	if stderr, err = cmd.StderrPipe(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+	return stdin, stdout, stderr, nil
+}
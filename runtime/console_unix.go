@@ -0,0 +1,14 @@
+//go:build !windows
+
+package runtime
+
+import (
+	"io"
+	"os/exec"
+)
+
+// startWithConsole is only available on Windows; elsewhere an interpreter
+// reading pipes behaves the same
+func startWithConsole(cmd *exec.Cmd) (io.WriteCloser, io.ReadCloser, io.ReadCloser, error) {
+	return nil, nil, nil, errNoConsole
+}
@@ -0,0 +1,192 @@
+//go:build windows
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// consoleSize is the size of the pseudo console in characters
+var consoleSize = windows.Coord{X: 120, Y: 30}
+
+// createPseudoConsole is looked up first: Windows before 10 1809 has no ConPTY
+var createPseudoConsole = windows.NewLazySystemDLL("kernel32.dll").NewProc("CreatePseudoConsole")
+
+// startWithConsole starts cmd attached to a new pseudo console, with pipes
+// as its standard handles. exec.Cmd can't pass a pseudo console, so the
+// process is created here and cmd.Process set, for Wait, TrackProcess and
+// KillProcessTree. What the interpreter writes to the console itself, rather
+// than to stdout, is copied to funterm's stdout; the console is closed when
+// the interpreter exits
+func startWithConsole(cmd *exec.Cmd) (io.WriteCloser, io.ReadCloser, io.ReadCloser, error) {
+	if createPseudoConsole.Find() != nil {
+		return nil, nil, nil, errNoConsole
+	}
+	if cmd.Err != nil {
+		return nil, nil, nil, cmd.Err
+	}
+	if cmd.Process != nil {
+		return nil, nil, nil, errors.New("exec: already started")
+	}
+
+	var opened []windows.Handle
+	closeAll := func() {
+		for _, handle := range opened {
+			windows.CloseHandle(handle)
+		}
+	}
+	newPipe := func(childReads bool) (child, parent windows.Handle, err error) {
+		var read, write windows.Handle
+		if err := windows.CreatePipe(&read, &write, nil, 0); err != nil {
+			return 0, 0, err
+		}
+		opened = append(opened, read, write)
+		child, parent = write, read
+		if childReads {
+			child, parent = read, write
+		}
+		return child, parent, windows.SetHandleInformation(child, windows.HANDLE_FLAG_INHERIT, windows.HANDLE_FLAG_INHERIT)
+	}
+
+	// The console reads its input from one pipe and renders to another
+	var consoleIn, consoleInput, consoleOut, consoleOutput windows.Handle
+	var err error
+	if err = windows.CreatePipe(&consoleIn, &consoleInput, nil, 0); err == nil {
+		opened = append(opened, consoleIn, consoleInput)
+		if err = windows.CreatePipe(&consoleOutput, &consoleOut, nil, 0); err == nil {
+			opened = append(opened, consoleOutput, consoleOut)
+		}
+	}
+	if err != nil {
+		closeAll()
+		return nil, nil, nil, fmt.Errorf("%w: %v", errNoConsole, err)
+	}
+	var console windows.Handle
+	if err := windows.CreatePseudoConsole(consoleSize, consoleIn, consoleOut, 0, &console); err != nil {
+		closeAll()
+		return nil, nil, nil, fmt.Errorf("%w: %v", errNoConsole, err)
+	}
+	// The console keeps its own copies of its ends
+	windows.CloseHandle(consoleIn)
+	windows.CloseHandle(consoleOut)
+	opened = []windows.Handle{consoleInput, consoleOutput}
+	fail := func(err error) (io.WriteCloser, io.ReadCloser, io.ReadCloser, error) {
+		windows.ClosePseudoConsole(console)
+		closeAll()
+		return nil, nil, nil, err
+	}
+
+	stdinChild, stdinParent, err := newPipe(true)
+	if err != nil {
+		return fail(err)
+	}
+	stdoutChild, stdoutParent, err := newPipe(false)
+	if err != nil {
+		return fail(err)
+	}
+	stderrChild, stderrParent, err := newPipe(false)
+	if err != nil {
+		return fail(err)
+	}
+
+	attributes, err := windows.NewProcThreadAttributeList(2)
+	if err != nil {
+		return fail(err)
+	}
+	defer attributes.Delete()
+	// The attribute's value is the console handle itself
+	if err := attributes.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, *(*unsafe.Pointer)(unsafe.Pointer(&console)), unsafe.Sizeof(console)); err != nil {
+		return fail(err)
+	}
+	// Only the pipes are inherited, not every inheritable handle of funterm
+	inherited := []windows.Handle{stdinChild, stdoutChild, stderrChild}
+	if err := attributes.Update(windows.PROC_THREAD_ATTRIBUTE_HANDLE_LIST, unsafe.Pointer(&inherited[0]), uintptr(len(inherited))*unsafe.Sizeof(inherited[0])); err != nil {
+		return fail(err)
+	}
+
+	startup := &windows.StartupInfoEx{ProcThreadAttributeList: attributes.List()}
+	startup.Cb = uint32(unsafe.Sizeof(*startup))
+	startup.Flags = windows.STARTF_USESTDHANDLES
+	startup.StdInput, startup.StdOutput, startup.StdErr = stdinChild, stdoutChild, stderrChild
+
+	flags := uint32(windows.EXTENDED_STARTUPINFO_PRESENT | windows.CREATE_UNICODE_ENVIRONMENT)
+	if cmd.SysProcAttr != nil {
+		flags |= cmd.SysProcAttr.CreationFlags
+	}
+	path, err := windows.UTF16PtrFromString(cmd.Path)
+	if err != nil {
+		return fail(err)
+	}
+	commandLine, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(cmd.Args))
+	if err != nil {
+		return fail(err)
+	}
+	var dir *uint16
+	if cmd.Dir != "" {
+		if dir, err = windows.UTF16PtrFromString(cmd.Dir); err != nil {
+			return fail(err)
+		}
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	var info windows.ProcessInformation
+	err = windows.CreateProcess(path, commandLine, nil, nil, true, flags, environmentBlock(env), dir, &startup.StartupInfo, &info)
+	if err != nil {
+		return fail(&os.PathError{Op: "fork/exec", Path: cmd.Path, Err: err})
+	}
+	windows.CloseHandle(info.Thread)
+	for _, child := range inherited {
+		windows.CloseHandle(child)
+	}
+
+	process, err := os.FindProcess(int(info.ProcessId))
+	if err != nil {
+		windows.TerminateProcess(info.Process, 1)
+		windows.CloseHandle(info.Process)
+		windows.ClosePseudoConsole(console)
+		windows.CloseHandle(consoleInput)
+		windows.CloseHandle(consoleOutput)
+		windows.CloseHandle(stdinParent)
+		windows.CloseHandle(stdoutParent)
+		windows.CloseHandle(stderrParent)
+		return nil, nil, nil, err
+	}
+	cmd.Process = process
+
+	output := os.NewFile(uintptr(consoleOutput), "console output")
+	go func() {
+		io.Copy(os.Stdout, output)
+		output.Close()
+	}()
+	go func() {
+		windows.WaitForSingleObject(info.Process, windows.INFINITE)
+		windows.CloseHandle(info.Process)
+		// Closing the console ends its output, which ends the copy
+		windows.ClosePseudoConsole(console)
+		windows.CloseHandle(consoleInput)
+	}()
+
+	return os.NewFile(uintptr(stdinParent), "stdin"),
+		os.NewFile(uintptr(stdoutParent), "stdout"),
+		os.NewFile(uintptr(stderrParent), "stderr"),
+		nil
+}
+
+// environmentBlock encodes variables as CreateProcess expects them: each
+// NAME=value ends in a NUL, and the block in another one
+func environmentBlock(env []string) *uint16 {
+	block := utf16.Encode([]rune(strings.Join(env, "\x00") + "\x00\x00"))
+	return &block[0]
+}
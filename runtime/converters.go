@@ -0,0 +1,180 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Converter converts values of a type FunTerm doesn't know between FunTerm
+// and the runtimes, e.g. a numpy array into a slice or a Decimal into a
+// big.Float. Embedders register converters with RegisterConverter; the engine
+// applies them to every value it gets from a runtime or passes to one
+type Converter struct {
+	// Name identifies the converter, e.g. "numpy"
+	Name string
+	// FromRuntime converts a value returned by language or read from it,
+	// *ForeignValue included; ok is false to leave the value to the next
+	// converter. The result should be a FunTerm value: nil, bool, numbers,
+	// string, *big.Int, []interface{} or map[string]interface{}
+	FromRuntime func(language string, value interface{}) (converted interface{}, ok bool)
+	// ToRuntime converts a value before it is passed to language
+	ToRuntime func(language string, value interface{}) (converted interface{}, ok bool)
+}
+
+// ForeignValue is a value a runtime can't encode for FunTerm, such as a
+// Python Decimal or numpy array. Without a converter for it, it becomes Text
+type ForeignValue struct {
+	Language string
+	Type     string      // Type in the runtime, "decimal.Decimal", "numpy.ndarray"
+	Text     string      // String form, str() in Python
+	Value    interface{} // Plain form if the runtime has one, the tolist() of a numpy array
+}
+
+// String returns the string form of the value
+func (v *ForeignValue) String() string {
+	return v.Text
+}
+
+var converterRegistry struct {
+	mu   sync.RWMutex
+	list []Converter
+}
+
+// RegisterConverter adds a converter; converters are tried in the order
+// they were registered
+func RegisterConverter(converter Converter) error {
+	if converter.Name == "" {
+		return fmt.Errorf("converter has no name")
+	}
+	if converter.FromRuntime == nil && converter.ToRuntime == nil {
+		return fmt.Errorf("converter '%s' converts nothing", converter.Name)
+	}
+	converterRegistry.mu.Lock()
+	defer converterRegistry.mu.Unlock()
+	for _, registered := range converterRegistry.list {
+		if registered.Name == converter.Name {
+			return fmt.Errorf("converter '%s' is already registered", converter.Name)
+		}
+	}
+	converterRegistry.list = append(converterRegistry.list, converter)
+	return nil
+}
+
+// UnregisterConverter removes a converter, reporting whether it was registered
+func UnregisterConverter(name string) bool {
+	converterRegistry.mu.Lock()
+	defer converterRegistry.mu.Unlock()
+	for i, registered := range converterRegistry.list {
+		if registered.Name == name {
+			converterRegistry.list = append(converterRegistry.list[:i:i], converterRegistry.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ConverterNames returns the names of the registered converters, sorted
+func ConverterNames() []string {
+	converterRegistry.mu.RLock()
+	defer converterRegistry.mu.RUnlock()
+	names := make([]string, len(converterRegistry.list))
+	for i, registered := range converterRegistry.list {
+		names[i] = registered.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConvertFromRuntime applies the FromRuntime converters to a value that came
+// from language and to the elements of its arrays and objects. A
+// ForeignValue no converter takes becomes its Text
+func ConvertFromRuntime(language string, value interface{}) interface{} {
+	converted, _ := convertTree(value, func(value interface{}) (interface{}, bool) {
+		for _, converter := range currentConverters() {
+			if converter.FromRuntime == nil {
+				continue
+			}
+			if result, ok := converter.FromRuntime(language, value); ok {
+				return result, true
+			}
+		}
+		if foreign, ok := value.(*ForeignValue); ok {
+			return foreign.Text, true
+		}
+		return nil, false
+	})
+	return converted
+}
+
+// ConvertToRuntime applies the ToRuntime converters to a value passed to
+// language and to the elements of its arrays and objects
+func ConvertToRuntime(language string, value interface{}) interface{} {
+	list := currentConverters()
+	if len(list) == 0 {
+		return value
+	}
+	converted, _ := convertTree(value, func(value interface{}) (interface{}, bool) {
+		for _, converter := range list {
+			if converter.ToRuntime == nil {
+				continue
+			}
+			if result, ok := converter.ToRuntime(language, value); ok {
+				return result, true
+			}
+		}
+		return nil, false
+	})
+	return converted
+}
+
+func currentConverters() []Converter {
+	converterRegistry.mu.RLock()
+	defer converterRegistry.mu.RUnlock()
+	return converterRegistry.list
+}
+
+// convertTree applies convert to value or, when convert leaves it alone, to
+// the elements of an array or object. Arrays and objects are only copied
+// when something in them changed
+func convertTree(value interface{}, convert func(interface{}) (interface{}, bool)) (interface{}, bool) {
+	if converted, ok := convert(value); ok {
+		return converted, true
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		var result []interface{}
+		for i, item := range v {
+			converted, changed := convertTree(item, convert)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), v...)
+			}
+			result[i] = converted
+		}
+		if result != nil {
+			return result, true
+		}
+	case map[string]interface{}:
+		var result map[string]interface{}
+		for key, item := range v {
+			converted, changed := convertTree(item, convert)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]interface{}, len(v))
+				for k, val := range v {
+					result[k] = val
+				}
+			}
+			result[key] = converted
+		}
+		if result != nil {
+			return result, true
+		}
+	}
+	return value, false
+}
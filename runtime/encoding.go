@@ -0,0 +1,346 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Charset is a single-byte encoding used by a runtime instead of UTF-8.
+// A nil *Charset stands for UTF-8 and passes text through unchanged.
+type Charset struct {
+	name   string
+	decode [128]rune // characters of bytes 0x80-0xFF; 0x00-0x7F are ASCII
+	encode map[rune]byte
+}
+
+// charsetAliases maps accepted encoding names to canonical ones
+var charsetAliases = map[string]string{
+	"utf-8":        "utf-8",
+	"utf8":         "utf-8",
+	"iso-8859-1":   "iso-8859-1",
+	"latin1":       "iso-8859-1",
+	"latin-1":      "iso-8859-1",
+	"windows-1252": "windows-1252",
+	"cp1252":       "windows-1252",
+	"windows-1251": "windows-1251",
+	"cp1251":       "windows-1251",
+	"ibm866":       "ibm866",
+	"cp866":        "ibm866",
+	"koi8-r":       "koi8-r",
+}
+
+// LookupCharset returns the charset with the given name; UTF-8 and an empty
+// name return nil, meaning no transcoding is needed
+func LookupCharset(name string) (*Charset, error) {
+	if name == "" {
+		return nil, nil
+	}
+	canonical, ok := charsetAliases[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding '%s' (supported: utf-8, iso-8859-1, windows-1252, windows-1251, ibm866, koi8-r)", name)
+	}
+	if canonical == "utf-8" {
+		return nil, nil
+	}
+
+	cs := &Charset{name: canonical, encode: make(map[rune]byte, 128)}
+	if canonical == "iso-8859-1" {
+		for i := range cs.decode {
+			cs.decode[i] = rune(0x80 + i)
+		}
+	} else {
+		cs.decode = charsetTables[canonical]
+	}
+	for i, r := range cs.decode {
+		if r != utf8.RuneError {
+			cs.encode[r] = byte(0x80 + i)
+		}
+	}
+	return cs, nil
+}
+
+// Name returns the canonical name of the charset, "utf-8" for nil
+func (cs *Charset) Name() string {
+	if cs == nil {
+		return "utf-8"
+	}
+	return cs.name
+}
+
+// Decode converts text in the charset to UTF-8
+func (cs *Charset) Decode(s string) string {
+	if cs == nil || isASCII(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s) * 2)
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < utf8.RuneSelf {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cs.decode[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+// Encode converts UTF-8 text to the charset; characters the charset
+// cannot represent become '?'
+func (cs *Charset) Encode(s string) string {
+	if cs == nil || isASCII(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			b.WriteByte(byte(r))
+		} else if c, ok := cs.encode[r]; ok {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// DecodeReader wraps a pipe carrying text in the charset so that reads return UTF-8
+func (cs *Charset) DecodeReader(r io.ReadCloser) io.ReadCloser {
+	if cs == nil {
+		return r
+	}
+	return &decodingReader{ReadCloser: r, cs: cs}
+}
+
+// EncodeWriter wraps a pipe expecting text in the charset so that UTF-8 can be written to it
+func (cs *Charset) EncodeWriter(w io.WriteCloser) io.WriteCloser {
+	if cs == nil {
+		return w
+	}
+	return &encodingWriter{WriteCloser: w, cs: cs}
+}
+
+type decodingReader struct {
+	io.ReadCloser
+	cs      *Charset
+	pending []byte // decoded text that did not fit into the caller's buffer
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if len(d.pending) == 0 {
+		buf := make([]byte, len(p))
+		n, err := d.ReadCloser.Read(buf)
+		if n == 0 {
+			return 0, err
+		}
+		d.pending = []byte(d.cs.Decode(string(buf[:n])))
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+type encodingWriter struct {
+	io.WriteCloser
+	cs      *Charset
+	partial []byte // start of a UTF-8 sequence split between writes
+}
+
+func (w *encodingWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	// Keep an incomplete trailing sequence for the next write
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+	w.partial = append([]byte(nil), data[end:]...)
+	if _, err := io.WriteString(w.WriteCloser, w.cs.Encode(string(data[:end]))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcessOptions describe the environment of a runtime's interpreter
+type ProcessOptions struct {
+	// Encoding of the interpreter's text, UTF-8 when empty
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	// Locale is exported as LANG and LC_ALL when set
+	Locale string `json:"locale,omitempty" yaml:"locale,omitempty"`
+	// Env holds extra environment variables, overriding inherited ones
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// Container is an image the interpreter runs in, e.g. python:3.12-slim;
+	// empty runs it on the host
+	Container string `json:"container,omitempty" yaml:"container,omitempty"`
+	// ContainerEngine is docker or podman, the first found in PATH when empty
+	ContainerEngine string `json:"container_engine,omitempty" yaml:"container_engine,omitempty"`
+	// Remote is an SSH destination, e.g. user@host, the interpreter runs on;
+	// empty runs it on this machine
+	Remote string `json:"remote,omitempty" yaml:"remote,omitempty"`
+}
+
+// Validate checks the options for a runtime of the given language: Lua runs
+// inside funterm and has no process of its own, node always uses UTF-8
+func (o ProcessOptions) Validate(language string) error {
+	charset, err := LookupCharset(o.Encoding)
+	if err != nil {
+		return err
+	}
+	switch language {
+	case "lua":
+		if o.Locale != "" || len(o.Env) > 0 {
+			return fmt.Errorf("lua runs inside funterm, only the encoding can be set")
+		}
+	case "node", "js", "javascript":
+		if charset != nil {
+			return fmt.Errorf("node always exchanges UTF-8 text, encoding '%s' is not supported", o.Encoding)
+		}
+	case "perl", "pl":
+		if charset != nil {
+			return fmt.Errorf("perl exchanges values as UTF-8 JSON, encoding '%s' is not supported", o.Encoding)
+		}
+	case "erlang", "erl", "elixir", "ex":
+		if charset != nil {
+			return fmt.Errorf("%s exchanges values as Erlang terms with UTF-8 text, encoding '%s' is not supported", language, o.Encoding)
+		}
+	}
+	switch language {
+	case "python", "py", "node", "js", "javascript":
+	default:
+		if o.Container != "" || o.ContainerEngine != "" {
+			return fmt.Errorf("only python and node can run in a container")
+		}
+	}
+	switch language {
+	case "python", "py", "node", "js", "javascript", "perl", "pl":
+	default:
+		if o.Remote != "" {
+			return fmt.Errorf("only python, node and perl can run on a remote host")
+		}
+	}
+	if o.Remote != "" && o.Container != "" {
+		return fmt.Errorf("a runtime runs either in a container or on a remote host, not both")
+	}
+	if strings.HasPrefix(o.Remote, "-") {
+		return fmt.Errorf("invalid remote host '%s'", o.Remote)
+	}
+	if err := o.checkContainerImage(); err != nil {
+		return err
+	}
+	if o.ContainerEngine != "" && o.ContainerEngine != "docker" && o.ContainerEngine != "podman" {
+		return fmt.Errorf("unknown container engine '%s', expected one of %s", o.ContainerEngine, strings.Join(ContainerEngines, ", "))
+	}
+	return nil
+}
+
+// Environ returns the environment for an interpreter process: the current
+// environment with the locale and the extra variables applied
+func (o ProcessOptions) Environ() []string {
+	env := os.Environ()
+	if o.Locale != "" {
+		env = append(env, "LANG="+o.Locale, "LC_ALL="+o.Locale)
+	}
+	for key, value := range o.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// charsetTables holds the characters of bytes 0x80-0xFF of each code page
+var charsetTables = map[string][128]rune{
+	"windows-1252": windows1252,
+	"windows-1251": windows1251,
+	"ibm866":       ibm866,
+	"koi8-r":       koi8r,
+}
+
+var (
+	windows1252 = [128]rune{
+		0x20AC, 0xFFFD, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+		0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0xFFFD, 0x017D, 0xFFFD,
+		0xFFFD, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+		0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0xFFFD, 0x017E, 0x0178,
+		0x00A0, 0x00A1, 0x00A2, 0x00A3, 0x00A4, 0x00A5, 0x00A6, 0x00A7,
+		0x00A8, 0x00A9, 0x00AA, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x00AF,
+		0x00B0, 0x00B1, 0x00B2, 0x00B3, 0x00B4, 0x00B5, 0x00B6, 0x00B7,
+		0x00B8, 0x00B9, 0x00BA, 0x00BB, 0x00BC, 0x00BD, 0x00BE, 0x00BF,
+		0x00C0, 0x00C1, 0x00C2, 0x00C3, 0x00C4, 0x00C5, 0x00C6, 0x00C7,
+		0x00C8, 0x00C9, 0x00CA, 0x00CB, 0x00CC, 0x00CD, 0x00CE, 0x00CF,
+		0x00D0, 0x00D1, 0x00D2, 0x00D3, 0x00D4, 0x00D5, 0x00D6, 0x00D7,
+		0x00D8, 0x00D9, 0x00DA, 0x00DB, 0x00DC, 0x00DD, 0x00DE, 0x00DF,
+		0x00E0, 0x00E1, 0x00E2, 0x00E3, 0x00E4, 0x00E5, 0x00E6, 0x00E7,
+		0x00E8, 0x00E9, 0x00EA, 0x00EB, 0x00EC, 0x00ED, 0x00EE, 0x00EF,
+		0x00F0, 0x00F1, 0x00F2, 0x00F3, 0x00F4, 0x00F5, 0x00F6, 0x00F7,
+		0x00F8, 0x00F9, 0x00FA, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF,
+	}
+	windows1251 = [128]rune{
+		0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+		0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+		0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+		0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+		0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+		0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+		0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+		0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+		0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+		0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+		0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+		0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+		0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+		0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+		0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+		0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+	}
+	ibm866 = [128]rune{
+		0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+		0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+		0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+		0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+		0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+		0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+		0x2591, 0x2592, 0x2593, 0x2502, 0x2524, 0x2561, 0x2562, 0x2556,
+		0x2555, 0x2563, 0x2551, 0x2557, 0x255D, 0x255C, 0x255B, 0x2510,
+		0x2514, 0x2534, 0x252C, 0x251C, 0x2500, 0x253C, 0x255E, 0x255F,
+		0x255A, 0x2554, 0x2569, 0x2566, 0x2560, 0x2550, 0x256C, 0x2567,
+		0x2568, 0x2564, 0x2565, 0x2559, 0x2558, 0x2552, 0x2553, 0x256B,
+		0x256A, 0x2518, 0x250C, 0x2588, 0x2584, 0x258C, 0x2590, 0x2580,
+		0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+		0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+		0x0401, 0x0451, 0x0404, 0x0454, 0x0407, 0x0457, 0x040E, 0x045E,
+		0x00B0, 0x2219, 0x00B7, 0x221A, 0x2116, 0x00A4, 0x25A0, 0x00A0,
+	}
+	koi8r = [128]rune{
+		0x2500, 0x2502, 0x250C, 0x2510, 0x2514, 0x2518, 0x251C, 0x2524,
+		0x252C, 0x2534, 0x253C, 0x2580, 0x2584, 0x2588, 0x258C, 0x2590,
+		0x2591, 0x2592, 0x2593, 0x2320, 0x25A0, 0x2219, 0x221A, 0x2248,
+		0x2264, 0x2265, 0x00A0, 0x2321, 0x00B0, 0x00B2, 0x00B7, 0x00F7,
+		0x2550, 0x2551, 0x2552, 0x0451, 0x2553, 0x2554, 0x2555, 0x2556,
+		0x2557, 0x2558, 0x2559, 0x255A, 0x255B, 0x255C, 0x255D, 0x255E,
+		0x255F, 0x2560, 0x2561, 0x0401, 0x2562, 0x2563, 0x2564, 0x2565,
+		0x2566, 0x2567, 0x2568, 0x2569, 0x256A, 0x256B, 0x256C, 0x00A9,
+		0x044E, 0x0430, 0x0431, 0x0446, 0x0434, 0x0435, 0x0444, 0x0433,
+		0x0445, 0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E,
+		0x043F, 0x044F, 0x0440, 0x0441, 0x0442, 0x0443, 0x0436, 0x0432,
+		0x044C, 0x044B, 0x0437, 0x0448, 0x044D, 0x0449, 0x0447, 0x044A,
+		0x042E, 0x0410, 0x0411, 0x0426, 0x0414, 0x0415, 0x0424, 0x0413,
+		0x0425, 0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E,
+		0x041F, 0x042F, 0x0420, 0x0421, 0x0422, 0x0423, 0x0416, 0x0412,
+		0x042C, 0x042B, 0x0417, 0x0428, 0x042D, 0x0429, 0x0427, 0x042A,
+	}
+)
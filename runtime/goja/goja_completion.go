@@ -0,0 +1,195 @@
+package goja
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"funterm/runtime"
+
+	js "github.com/dop251/goja"
+)
+
+// modules are the built-in objects offered as modules
+var modules = []string{"Array", "JSON", "Math", "Number", "Object", "String", "console"}
+
+// GetModules returns the built-in objects offered as modules
+func (gr *GojaRuntime) GetModules() []string {
+	return append([]string(nil), modules...)
+}
+
+// GetModuleFunctions returns the functions of a built-in object
+func (gr *GojaRuntime) GetModuleFunctions(module string) []string {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	value, _, err := gr.lookup(module)
+	if err != nil {
+		return []string{}
+	}
+	object, ok := value.(*js.Object)
+	if !ok {
+		return []string{}
+	}
+	names := []string{}
+	for _, name := range object.GetOwnPropertyNames() {
+		if _, callable := js.AssertFunction(object.Get(name)); callable {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetFunctionSignature returns the signature of a function in a module
+func (gr *GojaRuntime) GetFunctionSignature(module, function string) (string, error) {
+	name := function
+	if module != "" {
+		name = module + "." + function
+	}
+	params, err := gr.GetFunctionParameters(name)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Name
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(names, ", ")), nil
+}
+
+// GetGlobalVariables returns the global names, built-in ones included
+func (gr *GojaRuntime) GetGlobalVariables() []string {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	if !gr.ready {
+		return []string{}
+	}
+	names := gr.vm.GlobalObject().GetOwnPropertyNames()
+	sort.Strings(names)
+	return names
+}
+
+// GetCompletionSuggestions returns completion suggestions for a given input
+func (gr *GojaRuntime) GetCompletionSuggestions(input string) []string {
+	if dot := strings.LastIndex(input, "."); dot >= 0 {
+		properties, err := gr.GetObjectProperties(input[:dot])
+		if err != nil {
+			return []string{}
+		}
+		return filterPrefix(properties, input[dot+1:], input[:dot+1])
+	}
+	return filterPrefix(gr.GetGlobalVariables(), input, "")
+}
+
+// filterPrefix returns the names starting with prefix, each prepended with qualifier
+func filterPrefix(names []string, prefix, qualifier string) []string {
+	suggestions := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			suggestions = append(suggestions, qualifier+name)
+		}
+	}
+	return suggestions
+}
+
+// GetUserDefinedFunctions returns the global functions defined during the session
+func (gr *GojaRuntime) GetUserDefinedFunctions() []string {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	names := []string{}
+	if !gr.ready {
+		return names
+	}
+	global := gr.vm.GlobalObject()
+	for _, name := range global.GetOwnPropertyNames() {
+		if gr.builtins[name] {
+			continue
+		}
+		if _, callable := js.AssertFunction(global.Get(name)); callable {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetImportedModules returns the built-in objects, there is nothing to import
+func (gr *GojaRuntime) GetImportedModules() []string {
+	return gr.GetModules()
+}
+
+// GetDynamicCompletions returns completions based on current runtime state
+func (gr *GojaRuntime) GetDynamicCompletions(input string) ([]string, error) {
+	return gr.GetCompletionSuggestions(input), nil
+}
+
+// GetObjectProperties returns the properties and methods of a runtime object
+func (gr *GojaRuntime) GetObjectProperties(objectName string) ([]string, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	value, _, err := gr.lookup(objectName)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := value.(*js.Object)
+	if !ok {
+		return []string{}, nil
+	}
+	names := object.GetOwnPropertyNames()
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetFunctionParameters returns the parameter names of a function written in
+// JavaScript; built-in functions don't expose theirs
+func (gr *GojaRuntime) GetFunctionParameters(functionName string) ([]runtime.FunctionParameter, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	value, _, err := gr.lookup(functionName)
+	if err != nil {
+		return nil, err
+	}
+	if _, callable := js.AssertFunction(value); !callable {
+		return []runtime.FunctionParameter{}, nil
+	}
+	source := value.String()
+	open, end := strings.Index(source, "("), strings.Index(source, ")")
+	if open < 0 || end < open || strings.Contains(source, "[native code]") {
+		return []runtime.FunctionParameter{}, nil
+	}
+	params := []runtime.FunctionParameter{}
+	for _, param := range strings.Split(source[open+1:end], ",") {
+		if param = strings.TrimSpace(param); param != "" {
+			params = append(params, runtime.FunctionParameter{Name: param, Type: "any"})
+		}
+	}
+	return params, nil
+}
+
+// UpdateCompletionContext is a no-op: completions read the globals directly
+func (gr *GojaRuntime) UpdateCompletionContext(executedCode string, result interface{}) error {
+	return nil
+}
+
+// RefreshRuntimeState is a no-op for the same reason
+func (gr *GojaRuntime) RefreshRuntimeState() error { return nil }
+
+// GetRuntimeObjects returns the globals defined during the session
+func (gr *GojaRuntime) GetRuntimeObjects() map[string]interface{} {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	objects := make(map[string]interface{})
+	if !gr.ready {
+		return objects
+	}
+	global := gr.vm.GlobalObject()
+	for _, name := range global.GetOwnPropertyNames() {
+		if gr.builtins[name] {
+			continue
+		}
+		if value, err := gr.fromJS(global.Get(name)); err == nil {
+			objects[name] = value
+		}
+	}
+	return objects
+}
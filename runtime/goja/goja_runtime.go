@@ -0,0 +1,395 @@
+// Package goja provides the embedded JavaScript runtime: a goja interpreter
+// (github.com/dop251/goja) used instead of an external node process when
+// languages.runtimes.node.mode is "embedded".
+package goja
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	js "github.com/dop251/goja"
+)
+
+// globalDeclaration matches let and const at the start of a line; blocks run
+// them as var, so running a block again doesn't redeclare its variables, as
+// with the node runtime
+var globalDeclaration = regexp.MustCompile(`(?m)^([ \t]*)(?:let|const)([ \t]+)`)
+
+// GojaRuntime implements the LanguageRuntime interface for embedded JavaScript
+type GojaRuntime struct {
+	ready            bool
+	mutex            sync.Mutex
+	vm               *js.Runtime
+	stringify        js.Callable // JSON.stringify as it was at start, scripts may replace it
+	parse            js.Callable // JSON.parse, likewise
+	builtins         map[string]bool
+	executionTimeout time.Duration
+	outputCapture    strings.Builder // console.log() output of the last call or block
+}
+
+// NewGojaRuntime creates a new embedded JavaScript runtime instance
+func NewGojaRuntime() *GojaRuntime {
+	return &GojaRuntime{
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// SetExecutionTimeout limits the run time of a single call or code block
+func (gr *GojaRuntime) SetExecutionTimeout(timeout time.Duration) {
+	gr.executionTimeout = timeout
+}
+
+// Initialize creates the interpreter with console and print
+func (gr *GojaRuntime) Initialize() error {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+
+	vm := js.New()
+	console := vm.NewObject()
+	for _, name := range []string{"log", "info", "debug"} {
+		if err := console.Set(name, gr.printer(nil)); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"error", "warn"} {
+		if err := console.Set(name, gr.printer(os.Stderr)); err != nil {
+			return err
+		}
+	}
+	if err := vm.Set("console", console); err != nil {
+		return err
+	}
+	if err := vm.Set("print", gr.printer(nil)); err != nil {
+		return err
+	}
+	if err := vm.Set("require", func(call js.FunctionCall) js.Value {
+		message := fmt.Sprintf("module '%s' is not available in embedded JavaScript", call.Argument(0).String())
+		exception, err := vm.New(vm.Get("Error"), vm.ToValue(message))
+		if err != nil {
+			panic(err)
+		}
+		panic(exception)
+	}); err != nil {
+		return err
+	}
+
+	jsonObject := vm.Get("JSON").ToObject(vm)
+	stringify, _ := js.AssertFunction(jsonObject.Get("stringify"))
+	parse, _ := js.AssertFunction(jsonObject.Get("parse"))
+
+	gr.builtins = make(map[string]bool)
+	for _, name := range vm.GlobalObject().GetOwnPropertyNames() {
+		gr.builtins[name] = true
+	}
+	gr.vm, gr.stringify, gr.parse = vm, stringify, parse
+	gr.ready = true
+	return nil
+}
+
+// printer returns a console function writing its arguments separated by
+// spaces; a nil writer means the output capture
+func (gr *GojaRuntime) printer(writer io.Writer) func(js.FunctionCall) js.Value {
+	return func(call js.FunctionCall) js.Value {
+		parts := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			parts[i] = gr.display(arg)
+		}
+		line := strings.Join(parts, " ")
+		if writer != nil {
+			fmt.Fprintln(writer, line)
+		} else {
+			gr.outputCapture.WriteString(line)
+			gr.outputCapture.WriteString("\n")
+		}
+		return js.Undefined()
+	}
+}
+
+// display formats a value for console.log: strings as they are, objects
+// and arrays as JSON
+func (gr *GojaRuntime) display(value js.Value) string {
+	if object, ok := value.(*js.Object); ok {
+		if _, callable := js.AssertFunction(object); !callable && object.ClassName() != "Error" {
+			if text, err := gr.stringify(js.Undefined(), object); err == nil && !js.IsUndefined(text) {
+				return text.String()
+			}
+		}
+	}
+	return value.String()
+}
+
+// run calls fn and interrupts it after the execution timeout
+func (gr *GojaRuntime) run(fn func() (js.Value, error)) (js.Value, error) {
+	if !gr.ready {
+		return nil, fmt.Errorf("node runtime is not initialized")
+	}
+	defer gr.vm.ClearInterrupt()
+	if gr.executionTimeout > 0 {
+		timeout := gr.executionTimeout
+		timer := time.AfterFunc(timeout, func() {
+			gr.vm.Interrupt(fmt.Sprintf("execution timed out after %v", timeout))
+		})
+		defer timer.Stop()
+	}
+	value, err := fn()
+	if err != nil {
+		return nil, formatError(err)
+	}
+	return value, nil
+}
+
+// exec runs code in the global scope and returns the value of its last statement
+func (gr *GojaRuntime) exec(code string) (js.Value, error) {
+	return gr.run(func() (js.Value, error) {
+		return gr.vm.RunString(globalDeclaration.ReplaceAllString(code, "${1}var${2}"))
+	})
+}
+
+// formatError reports exceptions as node does, e.g. "TypeError: x is not a function"
+func formatError(err error) error {
+	switch e := err.(type) {
+	case *js.InterruptedError:
+		return fmt.Errorf("%v", e.Value())
+	case *js.Exception:
+		if value := e.Value(); value != nil {
+			return fmt.Errorf("%s", value.String())
+		}
+	}
+	return err
+}
+
+// lookup resolves a possibly dotted name such as Math.floor and returns it
+// with the object it was read from
+func (gr *GojaRuntime) lookup(name string) (js.Value, js.Value, error) {
+	if !gr.ready {
+		return nil, nil, fmt.Errorf("node runtime is not initialized")
+	}
+	var holder js.Value = gr.vm.GlobalObject()
+	var value js.Value
+	for i, part := range strings.Split(name, ".") {
+		if i > 0 {
+			if value == nil || js.IsUndefined(value) || js.IsNull(value) {
+				return nil, nil, fmt.Errorf("%s is not defined", strings.Join(strings.Split(name, ".")[:i], "."))
+			}
+			holder = value
+		}
+		value = holder.ToObject(gr.vm).Get(part)
+		if value == nil || js.IsUndefined(value) && i == 0 {
+			return nil, nil, fmt.Errorf("%s is not defined", part)
+		}
+	}
+	return value, holder, nil
+}
+
+// toJS converts a funterm value through JSON, as values reach the node runtime
+func (gr *GojaRuntime) toJS(value interface{}) (js.Value, error) {
+	if value == nil {
+		return js.Null(), nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot pass %T to JavaScript: %v", value, err)
+	}
+	return gr.parse(js.Undefined(), gr.vm.ToValue(string(encoded)))
+}
+
+// fromJS converts a JavaScript value through JSON, as values come back from
+// the node runtime: numbers are float64, functions and undefined are nil
+func (gr *GojaRuntime) fromJS(value js.Value) (interface{}, error) {
+	if value == nil || js.IsUndefined(value) || js.IsNull(value) {
+		return nil, nil
+	}
+	encoded, err := gr.stringify(js.Undefined(), value)
+	if err != nil {
+		return nil, formatError(err)
+	}
+	if js.IsUndefined(encoded) {
+		return nil, nil
+	}
+	var result interface{}
+	if err := json.Unmarshal([]byte(encoded.String()), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// call invokes the named function with converted arguments; methods such as
+// Math.floor are called on their object
+func (gr *GojaRuntime) call(name string, args []interface{}) (js.Value, error) {
+	value, holder, err := gr.lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("function '%s' not found", name)
+	}
+	fn, ok := js.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a function", name)
+	}
+	callArgs := make([]js.Value, len(args))
+	for i, arg := range args {
+		if callArgs[i], err = gr.toJS(arg); err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i+1, err)
+		}
+	}
+	if holder == js.Value(gr.vm.GlobalObject()) {
+		holder = js.Undefined()
+	}
+	return gr.run(func() (js.Value, error) {
+		return fn(holder, callArgs...)
+	})
+}
+
+// ExecuteFunction calls a global function or a method such as Math.floor;
+// what print and console.log write is kept for GetCapturedOutput
+func (gr *GojaRuntime) ExecuteFunction(name string, args []interface{}) (interface{}, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.outputCapture.Reset()
+	result, err := gr.call(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return gr.fromJS(result)
+}
+
+// ExecuteFunctionMultiple calls a function and unpacks a returned array
+func (gr *GojaRuntime) ExecuteFunctionMultiple(functionName string, args ...interface{}) ([]interface{}, error) {
+	result, err := gr.ExecuteFunction(functionName, args)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := result.([]interface{}); ok {
+		return values, nil
+	}
+	return []interface{}{result}, nil
+}
+
+// Eval runs code and returns the value of its last statement
+func (gr *GojaRuntime) Eval(code string) (interface{}, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.outputCapture.Reset()
+	value, err := gr.exec(code)
+	if err != nil {
+		return nil, err
+	}
+	return gr.fromJS(value)
+}
+
+// ExecuteCodeBlock runs a js { ... } block and returns what it printed
+func (gr *GojaRuntime) ExecuteCodeBlock(code string) (interface{}, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.outputCapture.Reset()
+	if _, err := gr.exec(code); err != nil {
+		return nil, err
+	}
+	if output := gr.takeOutput(); output != "" {
+		return output, nil
+	}
+	return nil, nil
+}
+
+// ExecuteCodeBlockWithVariables runs a code block; its var and function
+// declarations are globals and persist between blocks anyway
+func (gr *GojaRuntime) ExecuteCodeBlockWithVariables(code string, variables []string) (interface{}, error) {
+	return gr.ExecuteCodeBlock(code)
+}
+
+// EvaluateBlock runs a code block used as a value and returns the value of
+// its last statement; printed output is kept for GetCapturedOutput
+func (gr *GojaRuntime) EvaluateBlock(code string) (interface{}, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.outputCapture.Reset()
+	value, err := gr.exec(code)
+	if err != nil {
+		return nil, err
+	}
+	return gr.fromJS(value)
+}
+
+// ExecuteBatch executes code printing all output directly
+func (gr *GojaRuntime) ExecuteBatch(code string) error {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.outputCapture.Reset()
+	_, err := gr.exec(code)
+	if output := gr.takeOutput(); output != "" {
+		fmt.Println(output)
+	}
+	return err
+}
+
+// GetCapturedOutput returns and clears the output of the last call or block
+func (gr *GojaRuntime) GetCapturedOutput() string {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	return gr.takeOutput()
+}
+
+func (gr *GojaRuntime) takeOutput() string {
+	output := strings.TrimSuffix(gr.outputCapture.String(), "\n")
+	gr.outputCapture.Reset()
+	return output
+}
+
+// SetVariable sets a global variable
+func (gr *GojaRuntime) SetVariable(name string, value interface{}) error {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	if !gr.ready {
+		return fmt.Errorf("node runtime is not initialized")
+	}
+	converted, err := gr.toJS(value)
+	if err != nil {
+		return err
+	}
+	return gr.vm.Set(name, converted)
+}
+
+// GetVariable retrieves a global variable or a property such as Math.PI
+func (gr *GojaRuntime) GetVariable(name string) (interface{}, error) {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	value, _, err := gr.lookup(name)
+	if err != nil || js.IsUndefined(value) {
+		return nil, fmt.Errorf("variable '%s' not found", name)
+	}
+	return gr.fromJS(value)
+}
+
+// Isolate drops all user-defined globals
+func (gr *GojaRuntime) Isolate() error {
+	return gr.Initialize()
+}
+
+// Cleanup releases the interpreter
+func (gr *GojaRuntime) Cleanup() error {
+	gr.mutex.Lock()
+	defer gr.mutex.Unlock()
+	gr.vm = nil
+	gr.ready = false
+	return nil
+}
+
+// GetSupportedTypes returns the types supported by this runtime
+func (gr *GojaRuntime) GetSupportedTypes() []string {
+	return []string{"string", "number", "boolean", "null", "array", "object"}
+}
+
+// GetName returns the name of the language runtime; the embedded runtime
+// stands in for node, so js.* calls and js blocks reach it
+func (gr *GojaRuntime) GetName() string {
+	return "node"
+}
+
+// IsReady checks if the runtime is ready for execution
+func (gr *GojaRuntime) IsReady() bool {
+	return gr.ready
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Handle is a proxy for an object that stays inside a language runtime.
+// It is returned by proxy(...) instead of a deep copy and can be passed back
+// to the same runtime as an argument until it is released.
+type Handle struct {
+	Language string
+	ID       string
+	TypeName string
+	released atomic.Bool // A handle may be released by another task while in use
+}
+
+// Released reports whether the runtime object behind the handle was freed
+func (h *Handle) Released() bool {
+	return h.released.Load()
+}
+
+// MarkReleased flags the handle as freed; further use is an error
+func (h *Handle) MarkReleased() {
+	h.released.Store(true)
+}
+
+// String is used when the handle itself is displayed
+func (h *Handle) String() string {
+	if h.released.Load() {
+		return fmt.Sprintf("<%s %s %s, released>", h.Language, h.TypeName, h.ID)
+	}
+	return fmt.Sprintf("<%s %s %s>", h.Language, h.TypeName, h.ID)
+}
+
+// HandleRuntime is implemented by runtimes that can keep call results on
+// their side and hand out proxies to them
+type HandleRuntime interface {
+	// ExecuteFunctionAsHandle calls a function and keeps its result in the runtime
+	ExecuteFunctionAsHandle(name string, args []interface{}) (*Handle, error)
+
+	// ReleaseHandle frees the runtime object behind the handle
+	ReleaseHandle(handle *Handle) error
+
+	// CallHandleMethod calls a method of the object behind the handle. With
+	// keep the result stays in the runtime and a new handle is returned
+	CallHandleMethod(handle *Handle, method string, args []interface{}, keep bool) (interface{}, error)
+}
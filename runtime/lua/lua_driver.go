@@ -0,0 +1,275 @@
+package lua
+
+// driverScript runs inside an external lua interpreter (5.1 to 5.4 and
+// LuaJIT). It reads one JSON request per line from stdin and answers with one
+// JSON line on stdout; whatever the request prints through print, io.write
+// or io.stdout is captured and sent back in the "output" field
+const driverScript = `
+local load = loadstring or load
+local unpack = table.unpack or unpack
+local protocol = io.stdout
+local requests = io.stdin
+
+-- JSON decoding; arrays remember their length, so null items survive
+local lengths = setmetatable({}, { __mode = "k" })
+local escapes = { b = "\b", f = "\f", n = "\n", r = "\r", t = "\t" }
+
+local function utf8char(code)
+    if code < 0x80 then return string.char(code) end
+    if code < 0x800 then
+        return string.char(0xC0 + math.floor(code / 0x40), 0x80 + code % 0x40)
+    end
+    if code < 0x10000 then
+        return string.char(0xE0 + math.floor(code / 0x1000), 0x80 + math.floor(code / 0x40) % 0x40, 0x80 + code % 0x40)
+    end
+    return string.char(0xF0 + math.floor(code / 0x40000), 0x80 + math.floor(code / 0x1000) % 0x40,
+        0x80 + math.floor(code / 0x40) % 0x40, 0x80 + code % 0x40)
+end
+
+local decode
+
+local function skip(text, pos)
+    return text:find("[^ \t\r\n]", pos) or #text + 1
+end
+
+local function decodeString(text, pos)
+    local parts = {}
+    pos = pos + 1
+    while true do
+        local c = text:sub(pos, pos)
+        if c == "" then error("unterminated string") end
+        if c == '"' then return table.concat(parts), pos + 1 end
+        if c == "\\" then
+            local e = text:sub(pos + 1, pos + 1)
+            if e == "u" then
+                local code = tonumber(text:sub(pos + 2, pos + 5), 16)
+                pos = pos + 6
+                if code >= 0xD800 and code < 0xDC00 and text:sub(pos, pos + 1) == "\\u" then
+                    local low = tonumber(text:sub(pos + 2, pos + 5), 16)
+                    code = 0x10000 + (code - 0xD800) * 0x400 + (low - 0xDC00)
+                    pos = pos + 6
+                end
+                parts[#parts + 1] = utf8char(code)
+            else
+                parts[#parts + 1] = escapes[e] or e
+                pos = pos + 2
+            end
+        else
+            local stop = text:find('["\\]', pos) or #text + 1
+            parts[#parts + 1] = text:sub(pos, stop - 1)
+            pos = stop
+        end
+    end
+end
+
+decode = function(text, pos)
+    pos = skip(text, pos)
+    local c = text:sub(pos, pos)
+    if c == "{" then
+        local object = {}
+        pos = skip(text, pos + 1)
+        if text:sub(pos, pos) == "}" then return object, pos + 1 end
+        while true do
+            local key
+            key, pos = decodeString(text, skip(text, pos))
+            pos = skip(text, pos) + 1
+            object[key], pos = decode(text, pos)
+            pos = skip(text, pos)
+            c = text:sub(pos, pos)
+            pos = pos + 1
+            if c == "}" then return object, pos end
+            if c ~= "," then error("expected , or } in object") end
+        end
+    elseif c == "[" then
+        local array, n = {}, 0
+        pos = skip(text, pos + 1)
+        if text:sub(pos, pos) == "]" then
+            lengths[array] = 0
+            return array, pos + 1
+        end
+        while true do
+            n = n + 1
+            array[n], pos = decode(text, pos)
+            pos = skip(text, pos)
+            c = text:sub(pos, pos)
+            pos = pos + 1
+            if c == "]" then
+                lengths[array] = n
+                return array, pos
+            end
+            if c ~= "," then error("expected , or ] in array") end
+        end
+    elseif c == '"' then
+        return decodeString(text, pos)
+    elseif text:sub(pos, pos + 3) == "true" then
+        return true, pos + 4
+    elseif text:sub(pos, pos + 4) == "false" then
+        return false, pos + 5
+    elseif text:sub(pos, pos + 3) == "null" then
+        return nil, pos + 4
+    end
+    local number = text:match("^-?%d+%.?%d*[eE]?[-+]?%d*", pos)
+    if not number or number == "" then error("unexpected character at " .. pos) end
+    return tonumber(number), pos + #number
+end
+
+-- JSON encoding; values JSON can't represent become strings
+local function encodeString(s)
+    return '"' .. s:gsub('[%c"\\]', function(c)
+        if c == '"' then return '\\"' end
+        if c == "\\" then return "\\\\" end
+        if c == "\n" then return "\\n" end
+        if c == "\t" then return "\\t" end
+        if c == "\r" then return "\\r" end
+        return string.format("\\u%04x", c:byte())
+    end) .. '"'
+end
+
+local encode
+
+-- arrayLength returns the length of a table with only positive integer
+-- keys; an empty table is an object, as with the embedded runtime
+local function arrayLength(t)
+    local n = lengths[t]
+    if n then return n end
+    n = 0
+    for key in pairs(t) do
+        if type(key) ~= "number" or key < 1 or key % 1 ~= 0 then return nil end
+        if key > n then n = key end
+    end
+    if n == 0 then return nil end
+    return n
+end
+
+encode = function(value, visited)
+    local kind = type(value)
+    if value == nil then return "null" end
+    if kind == "boolean" then return tostring(value) end
+    if kind == "number" then
+        if value ~= value or value == math.huge or value == -math.huge then return "null" end
+        if math.type and math.type(value) == "integer" then return string.format("%d", value) end
+        return string.format("%.17g", value)
+    end
+    if kind == "string" then return encodeString(value) end
+    if kind ~= "table" then return encodeString(tostring(value)) end
+    if visited[value] then return '"<circular_reference>"' end
+    visited[value] = true
+    local parts = {}
+    local n = arrayLength(value)
+    if n then
+        for i = 1, n do parts[i] = encode(value[i], visited) end
+        visited[value] = nil
+        return "[" .. table.concat(parts, ",") .. "]"
+    end
+    for key, item in pairs(value) do
+        parts[#parts + 1] = encodeString(tostring(key)) .. ":" .. encode(item, visited)
+    end
+    visited[value] = nil
+    return "{" .. table.concat(parts, ",") .. "}"
+end
+
+local function lookup(name)
+    local value = _G
+    for part in name:gmatch("[^.]+") do
+        if type(value) ~= "table" then return nil end
+        value = value[part]
+    end
+    return value
+end
+
+local function resultOf(n, ...)
+    if n == 0 then return nil end
+    if n == 1 then return (...) end
+    local values = { ... }
+    lengths[values] = n
+    return values
+end
+
+-- compile turns a final expression line into a return, as the embedded
+-- runtime does, and keeps the code as it is when that doesn't compile
+local function compile(code, returnLast)
+    if returnLast then
+        local chunk = load("return " .. code, "=lua")
+        if chunk then return chunk end
+        local head, last = code:match("^(.*\n)([^\n]+)%s*$")
+        if last and not last:match("^%s*return[%s(]") then
+            chunk = load(head .. "return " .. last, "=lua")
+            if chunk then return chunk end
+        end
+    end
+    local chunk, err = load(code, "=lua")
+    if not chunk then error(err, 0) end
+    return chunk
+end
+
+local function run(request)
+    local op = request.op
+    if op == "call" then
+        local args = request.args or {}
+        local n = lengths[args] or #args
+        local fn = lookup(request.name)
+        if type(fn) ~= "function" and not (type(fn) == "table" and getmetatable(fn) and getmetatable(fn).__call) then
+            error("function '" .. request.name .. "' not found", 0)
+        end
+        local function call(...) return resultOf(select("#", ...), ...) end
+        return call(fn(unpack(args, 1, n)))
+    end
+    if op == "eval" or op == "block" then
+        local chunk = compile(request.code, true)
+        local function values(...) return resultOf(select("#", ...), ...) end
+        if op == "eval" then return values(chunk()) end
+        return (chunk())
+    end
+    if op == "set" then
+        _G[request.name] = request.value
+        return nil
+    end
+    if op == "get" then
+        local value = lookup(request.name)
+        if value == nil then error("variable '" .. request.name .. "' not found", 0) end
+        return value
+    end
+    error("unknown operation '" .. tostring(op) .. "'", 0)
+end
+
+local print, write, stdout = print, io.write, io.stdout
+while true do
+    local line = requests:read("*l")
+    if not line then break end
+    local ok, request = pcall(decode, line, 1)
+    if ok and type(request) == "table" then
+        local output = {}
+        local capture = {}
+        function capture.write(self, ...)
+            for i = 1, select("#", ...) do output[#output + 1] = tostring((select(i, ...))) end
+            return self
+        end
+        function capture.flush() return true end
+        function capture.setvbuf() return true end
+        _G.print = function(...)
+            local parts = {}
+            for i = 1, select("#", ...) do parts[i] = tostring((select(i, ...))) end
+            output[#output + 1] = table.concat(parts, "\t") .. "\n"
+        end
+        io.write = function(...) return capture:write(...) end
+        io.stdout = capture
+
+        local done, value = pcall(run, request)
+        _G.print, io.write, io.stdout = print, write, stdout
+
+        local response
+        if done then
+            local encoded, result = pcall(encode, value, {})
+            if encoded then
+                response = '"value":' .. result
+            else
+                response = '"error":' .. encodeString(tostring(result))
+            end
+        else
+            response = '"error":' .. encodeString(tostring(value))
+        end
+        protocol:write('{"id":', encode(request.id, {}), ",", response, ',"output":', encodeString(table.concat(output)), "}\n")
+        protocol:flush()
+    end
+end
+`
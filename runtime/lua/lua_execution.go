@@ -8,6 +8,7 @@ import (
 	"funterm/errors"
 
 	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
 )
 
 // ExecuteFunction calls a function in the Lua runtime
@@ -364,7 +365,7 @@ func (lr *LuaRuntime) Eval(code string) (interface{}, error) {
 		}()
 
 		// Выполняем код и получаем результат (без буферизации)
-		err := lr.state.DoString(code)
+		err := lr.doString(code)
 		if err != nil {
 			errorChan <- errors.NewRuntimeError("lua", "LUA_EVAL_ERROR", fmt.Sprintf("error evaluating code: %v", err))
 			return
@@ -436,7 +437,7 @@ func (lr *LuaRuntime) ExecuteBatch(code string) error {
 	}
 
 	// Execute code without output capture - let print statements go directly to console
-	err := lr.state.DoString(code)
+	err := lr.doString(code)
 	if err != nil {
 		return errors.NewRuntimeError("lua", "LUA_BATCH_ERROR", fmt.Sprintf("batch execution error: %v", err))
 	}
@@ -457,7 +458,7 @@ func (lr *LuaRuntime) ExecuteCodeBlockWithVariables(code string, variables []str
 	}
 
 	// Выполняем код без буферизации (как Eval)
-	err := lr.state.DoString(code)
+	err := lr.doString(code)
 	if err != nil {
 		return nil, errors.NewRuntimeError("lua", "LUA_EVAL_ERROR", fmt.Sprintf("error evaluating code: %v", err))
 	}
@@ -513,13 +514,59 @@ func (lr *LuaRuntime) ExecuteCodeBlockWithVariables(code string, variables []str
 	return result, nil
 }
 
+// EvaluateBlock executes a code block and returns its value: the chunk's return
+// value, or the value of the last line when that line is an expression.
+// Output printed by the block stays in the output capture.
+func (lr *LuaRuntime) EvaluateBlock(code string) (interface{}, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	lr.outputCapture = &strings.Builder{}
+
+	if !lr.ready {
+		return nil, errors.NewRuntimeError("lua", "LUA_RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+
+	top := lr.state.GetTop()
+	if err := lr.doString(withTrailingReturn(code)); err != nil {
+		return nil, errors.NewRuntimeError("lua", "LUA_EVAL_ERROR", fmt.Sprintf("error evaluating code: %v", err))
+	}
+
+	var result interface{}
+	if lr.state.GetTop() > top {
+		result = lr.convertLuaValueToGo(lr.state.Get(top + 1))
+		lr.state.SetTop(top)
+	}
+	return result, nil
+}
+
+// withTrailingReturn turns a final expression line into a return statement,
+// keeping the code unchanged when that would not compile
+func withTrailingReturn(code string) string {
+	lines := strings.Split(strings.TrimRight(code, " \t\r\n"), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" || strings.HasPrefix(last, "return") {
+		return code
+	}
+	lines[len(lines)-1] = "return " + last
+	candidate := strings.Join(lines, "\n")
+	if _, err := parse.Parse(strings.NewReader(candidate), "<block>"); err != nil {
+		return code
+	}
+	return candidate
+}
+
 // SetOutputCapture sets the output capture buffer
 func (lr *LuaRuntime) SetOutputCapture(buffer *strings.Builder) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
 	lr.outputCapture = buffer
 }
 
 // GetCapturedOutput returns the captured output and clears the buffer
 func (lr *LuaRuntime) GetCapturedOutput() string {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
 	if lr.outputCapture == nil {
 		return ""
 	}
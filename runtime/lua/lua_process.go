@@ -0,0 +1,444 @@
+package lua
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"funterm/errors"
+	"funterm/runtime"
+)
+
+// functionDefinition matches the definition of a global function
+var functionDefinition = regexp.MustCompile(`(?m)^\s*function\s+([A-Za-z_][A-Za-z0-9_.]*)\s*\(`)
+
+// processRequest is one line of the driver protocol
+type processRequest struct {
+	ID    int64         `json:"id,omitempty"`
+	Op    string        `json:"op"`
+	Name  string        `json:"name,omitempty"`
+	Code  string        `json:"code,omitempty"`
+	Args  []interface{} `json:"args,omitempty"`
+	Value interface{}   `json:"value,omitempty"`
+}
+
+// processResponse is the driver's answer to a request
+type processResponse struct {
+	ID     int64           `json:"id"`
+	Value  json.RawMessage `json:"value"`
+	Error  *string         `json:"error"`
+	Output string          `json:"output"`
+}
+
+// LuaProcessRuntime implements the LanguageRuntime interface with an
+// external lua interpreter, for scripts that need C modules or a newer Lua
+// than gopher-lua's 5.1. It is used when languages.runtimes.lua.mode is
+// "external"; the process is started on first use.
+type LuaProcessRuntime struct {
+	ready            bool
+	luaPath          string
+	mutex            sync.Mutex
+	executionTimeout time.Duration
+	outputCapture    strings.Builder // printed output of the last call or block
+	cmd              *exec.Cmd
+	stdin            io.WriteCloser
+	responses        chan processResponse
+	nextID           int64
+	cassette         *runtime.Cassette // Record/replay of interpreter traffic
+	functions        map[string]bool   // functions the code blocks defined, for completion
+}
+
+// NewLuaProcessRuntime creates a new external Lua runtime instance
+func NewLuaProcessRuntime() *LuaProcessRuntime {
+	return &LuaProcessRuntime{
+		luaPath:          "lua",
+		executionTimeout: 30 * time.Second,
+		functions:        make(map[string]bool),
+	}
+}
+
+// SetLuaPath sets the lua executable to run; an empty path keeps "lua"
+func (lp *LuaProcessRuntime) SetLuaPath(path string) {
+	if path != "" {
+		lp.luaPath = path
+	}
+}
+
+// SetCassette attaches a cassette for recording or replaying interpreter traffic
+func (lp *LuaProcessRuntime) SetCassette(cassette *runtime.Cassette) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.cassette = cassette
+}
+
+// SetExecutionTimeout limits the run time of a single request
+func (lp *LuaProcessRuntime) SetExecutionTimeout(timeout time.Duration) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.executionTimeout = timeout
+}
+
+// Initialize marks the runtime ready; the lua process starts on first use
+func (lp *LuaProcessRuntime) Initialize() error {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.ready = true
+	return nil
+}
+
+// startProcess starts lua with the driver script
+func (lp *LuaProcessRuntime) startProcess() error {
+	path, err := runtime.ResolveExecutable(lp.luaPath)
+	if err != nil {
+		return fmt.Errorf("'%s' executable not found in PATH. Please install Lua or remove languages.runtimes.lua.mode", lp.luaPath)
+	}
+	cmd := exec.Command(path, "-e", driverScript)
+	runtime.PrepareCommand(cmd)
+	cmd.Stderr = os.Stderr // errors outside requests
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start lua: %w", err)
+	}
+	runtime.TrackProcess(cmd)
+
+	lp.cmd = cmd
+	lp.stdin = stdin
+	lp.responses = make(chan processResponse)
+	go readProcessResponses(stdout, lp.responses)
+	return nil
+}
+
+// readProcessResponses decodes response lines until the process exits
+func readProcessResponses(pipe io.Reader, ch chan<- processResponse) {
+	defer close(ch)
+	reader := bufio.NewReader(pipe)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var response processResponse
+			if json.Unmarshal(line, &response) == nil {
+				ch <- response
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stopProcess kills the lua process; the next request starts a fresh one
+func (lp *LuaProcessRuntime) stopProcess() {
+	if lp.cmd == nil {
+		return
+	}
+	lp.stdin.Close()
+	runtime.KillProcessTree(lp.cmd)
+	lp.cmd.Wait()
+	lp.cmd = nil
+}
+
+// send performs one request, through the cassette if one is attached
+func (lp *LuaProcessRuntime) send(request processRequest) (response processResponse, err error) {
+	if runtime.Tracing() {
+		defer func() {
+			sent, _ := json.Marshal(request)
+			received, _ := json.Marshal(response)
+			runtime.Trace("lua", string(sent), string(received), err)
+		}()
+	}
+	if lp.cassette == nil {
+		return lp.sendToProcess(request)
+	}
+
+	key, err := json.Marshal(request)
+	if err != nil {
+		return processResponse{}, err
+	}
+	if lp.cassette.Replaying() {
+		it, err := lp.cassette.Next("lua", string(key))
+		if err != nil {
+			return processResponse{}, err
+		}
+		line, err := it.Result()
+		if err != nil {
+			return processResponse{}, err
+		}
+		var response processResponse
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			return processResponse{}, fmt.Errorf("invalid recorded lua response: %v", err)
+		}
+		return response, nil
+	}
+
+	response, err = lp.sendToProcess(request)
+	line := ""
+	if err == nil {
+		encoded, _ := json.Marshal(response)
+		line = string(encoded)
+	}
+	lp.cassette.Record("lua", string(key), line, "", err)
+	return response, err
+}
+
+// sendToProcess writes a request to the lua process and waits for its answer
+func (lp *LuaProcessRuntime) sendToProcess(request processRequest) (processResponse, error) {
+	if lp.cmd == nil {
+		if err := lp.startProcess(); err != nil {
+			return processResponse{}, err
+		}
+	}
+
+	lp.nextID++
+	request.ID = lp.nextID
+	line, err := json.Marshal(request)
+	if err != nil {
+		return processResponse{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if _, err := lp.stdin.Write(append(line, '\n')); err != nil {
+		lp.stopProcess()
+		return processResponse{}, fmt.Errorf("failed to write to lua stdin: %w", err)
+	}
+
+	timeout := time.After(lp.executionTimeout)
+	for {
+		select {
+		case response, ok := <-lp.responses:
+			if !ok {
+				lp.stopProcess()
+				return processResponse{}, fmt.Errorf("lua process exited unexpectedly")
+			}
+			if response.ID == request.ID {
+				response.ID = 0
+				return response, nil
+			}
+		case <-timeout:
+			// The interpreter is stuck in the request; restart it instead of waiting
+			lp.stopProcess()
+			return processResponse{}, fmt.Errorf("lua execution timed out after %v, the interpreter was restarted", lp.executionTimeout)
+		}
+	}
+}
+
+// request performs a request and returns its decoded value; printed output is
+// appended to the output capture
+func (lp *LuaProcessRuntime) request(request processRequest) (interface{}, error) {
+	if !lp.ready {
+		return nil, errors.NewRuntimeError("lua", "LUA_RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+	response, err := lp.send(request)
+	if err != nil {
+		return nil, errors.NewRuntimeError("lua", "LUA_EXECUTION_ERROR", err.Error())
+	}
+	lp.outputCapture.WriteString(response.Output)
+	if response.Error != nil {
+		return nil, errors.NewRuntimeError("lua", "LUA_EXECUTION_ERROR", *response.Error)
+	}
+	return decodeProcessValue(response.Value)
+}
+
+// decodeProcessValue converts a JSON value from lua, keeping integers exact
+func decodeProcessValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.NewRuntimeError("lua", "INVALID_RESULT", fmt.Sprintf("failed to decode result: %v", err))
+	}
+	return convertProcessNumbers(value), nil
+}
+
+// convertProcessNumbers replaces json.Number with int64, *big.Int or float64
+func convertProcessNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if i, ok := new(big.Int).SetString(string(v), 10); ok {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertProcessNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertProcessNumbers(item)
+		}
+	}
+	return value
+}
+
+// ExecuteFunction calls a global function or one of a module such as string.format
+func (lp *LuaProcessRuntime) ExecuteFunction(name string, args []interface{}) (interface{}, error) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.outputCapture.Reset()
+	return lp.request(processRequest{Op: "call", Name: name, Args: args})
+}
+
+// ExecuteFunctionMultiple calls a function and returns all the values it returned
+func (lp *LuaProcessRuntime) ExecuteFunctionMultiple(functionName string, args ...interface{}) ([]interface{}, error) {
+	result, err := lp.ExecuteFunction(functionName, args)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := result.([]interface{}); ok {
+		return values, nil
+	}
+	return []interface{}{result}, nil
+}
+
+// Eval runs lua code; an expression, or a final expression line, is returned
+func (lp *LuaProcessRuntime) Eval(code string) (interface{}, error) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.outputCapture.Reset()
+	lp.rememberFunctions(code)
+	return lp.request(processRequest{Op: "eval", Code: code})
+}
+
+// ExecuteCodeBlock runs a lua { ... } block and returns what it printed
+func (lp *LuaProcessRuntime) ExecuteCodeBlock(code string) (interface{}, error) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.outputCapture.Reset()
+	lp.rememberFunctions(code)
+	if _, err := lp.request(processRequest{Op: "block", Code: code}); err != nil {
+		return nil, err
+	}
+	if output := lp.takeOutput(); output != "" {
+		return output, nil
+	}
+	return nil, nil
+}
+
+// ExecuteCodeBlockWithVariables runs a code block; globals persist between
+// blocks anyway, locals end with the block
+func (lp *LuaProcessRuntime) ExecuteCodeBlockWithVariables(code string, variables []string) (interface{}, error) {
+	return lp.ExecuteCodeBlock(code)
+}
+
+// EvaluateBlock runs a code block used as a value and returns its final
+// expression; printed output is kept for GetCapturedOutput
+func (lp *LuaProcessRuntime) EvaluateBlock(code string) (interface{}, error) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.outputCapture.Reset()
+	lp.rememberFunctions(code)
+	return lp.request(processRequest{Op: "block", Code: code})
+}
+
+// ExecuteBatch executes code printing all output directly
+func (lp *LuaProcessRuntime) ExecuteBatch(code string) error {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.outputCapture.Reset()
+	lp.rememberFunctions(code)
+	_, err := lp.request(processRequest{Op: "eval", Code: code})
+	if output := lp.takeOutput(); output != "" {
+		fmt.Println(output)
+	}
+	return err
+}
+
+// GetCapturedOutput returns and clears the output of the last call or block
+func (lp *LuaProcessRuntime) GetCapturedOutput() string {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	return lp.takeOutput()
+}
+
+func (lp *LuaProcessRuntime) takeOutput() string {
+	output := strings.TrimSuffix(lp.outputCapture.String(), "\n")
+	lp.outputCapture.Reset()
+	return output
+}
+
+// SetVariable sets a global variable
+func (lp *LuaProcessRuntime) SetVariable(name string, value interface{}) error {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	_, err := lp.request(processRequest{Op: "set", Name: name, Value: value})
+	return err
+}
+
+// GetVariable reads a global variable or a module field such as math.pi
+func (lp *LuaProcessRuntime) GetVariable(name string) (interface{}, error) {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	return lp.request(processRequest{Op: "get", Name: name})
+}
+
+// Isolate restarts the interpreter, dropping all definitions
+func (lp *LuaProcessRuntime) Isolate() error {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.stopProcess()
+	lp.functions = make(map[string]bool)
+	return nil
+}
+
+// Cleanup stops the lua process
+func (lp *LuaProcessRuntime) Cleanup() error {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	lp.stopProcess()
+	lp.ready = false
+	return nil
+}
+
+// GetSupportedTypes returns the types supported by this runtime
+func (lp *LuaProcessRuntime) GetSupportedTypes() []string {
+	return []string{"nil", "boolean", "number", "string", "table"}
+}
+
+// GetName returns the name of the language runtime; the external runtime
+// stands in for lua, so lua.* calls and lua blocks reach it
+func (lp *LuaProcessRuntime) GetName() string {
+	return "lua"
+}
+
+// ProcessID returns the pid of the lua process, 0 before it has started
+func (lp *LuaProcessRuntime) ProcessID() int {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	if lp.cmd == nil || lp.cmd.Process == nil {
+		return 0
+	}
+	return lp.cmd.Process.Pid
+}
+
+// IsReady checks if the runtime is ready for execution
+func (lp *LuaProcessRuntime) IsReady() bool {
+	return lp.ready
+}
+
+// rememberFunctions notes the global functions code defines, for completion
+func (lp *LuaProcessRuntime) rememberFunctions(code string) {
+	for _, match := range functionDefinition.FindAllStringSubmatch(code, -1) {
+		lp.functions[match[1]] = true
+	}
+}
@@ -0,0 +1,100 @@
+package lua
+
+import (
+	"sort"
+	"strings"
+
+	"funterm/runtime"
+)
+
+// processModules are the standard libraries of every Lua version
+var processModules = map[string][]string{
+	"io":     {"close", "lines", "open", "read", "write"},
+	"math":   {"abs", "ceil", "cos", "exp", "floor", "fmod", "log", "max", "min", "random", "sin", "sqrt", "tan"},
+	"os":     {"clock", "date", "difftime", "getenv", "remove", "rename", "time"},
+	"string": {"byte", "char", "find", "format", "gmatch", "gsub", "len", "lower", "match", "rep", "reverse", "sub", "upper"},
+	"table":  {"concat", "insert", "remove", "sort"},
+}
+
+// GetModules returns available modules for the runtime
+func (lp *LuaProcessRuntime) GetModules() []string {
+	modules := make([]string, 0, len(processModules))
+	for module := range processModules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// GetModuleFunctions returns available functions for a specific module
+func (lp *LuaProcessRuntime) GetModuleFunctions(module string) []string {
+	return processModules[module]
+}
+
+// GetFunctionSignature returns the signature of a function in a module
+func (lp *LuaProcessRuntime) GetFunctionSignature(module, function string) (string, error) {
+	if module == "" {
+		return function + "(...)", nil
+	}
+	return module + "." + function + "(...)", nil
+}
+
+// GetGlobalVariables returns available global variables
+func (lp *LuaProcessRuntime) GetGlobalVariables() []string {
+	return []string{}
+}
+
+// GetCompletionSuggestions returns completion suggestions for a given input
+func (lp *LuaProcessRuntime) GetCompletionSuggestions(input string) []string {
+	suggestions := []string{}
+	for _, name := range append(lp.GetUserDefinedFunctions(), lp.GetModules()...) {
+		if strings.HasPrefix(name, input) {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions
+}
+
+// GetUserDefinedFunctions returns the global functions the code blocks defined
+func (lp *LuaProcessRuntime) GetUserDefinedFunctions() []string {
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	names := make([]string, 0, len(lp.functions))
+	for name := range lp.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetImportedModules returns modules that have been imported during the session
+func (lp *LuaProcessRuntime) GetImportedModules() []string { return []string{} }
+
+// GetDynamicCompletions returns completions based on current runtime state
+func (lp *LuaProcessRuntime) GetDynamicCompletions(input string) ([]string, error) {
+	return lp.GetCompletionSuggestions(input), nil
+}
+
+// GetObjectProperties returns properties and methods of a runtime object
+func (lp *LuaProcessRuntime) GetObjectProperties(objectName string) ([]string, error) {
+	return lp.GetModuleFunctions(objectName), nil
+}
+
+// GetFunctionParameters returns parameter names and types for a function;
+// the interpreter doesn't expose them
+func (lp *LuaProcessRuntime) GetFunctionParameters(functionName string) ([]runtime.FunctionParameter, error) {
+	return []runtime.FunctionParameter{}, nil
+}
+
+// UpdateCompletionContext updates the completion context after code execution
+func (lp *LuaProcessRuntime) UpdateCompletionContext(executedCode string, result interface{}) error {
+	return nil
+}
+
+// RefreshRuntimeState refreshes the runtime state for completion
+func (lp *LuaProcessRuntime) RefreshRuntimeState() error { return nil }
+
+// GetRuntimeObjects returns all objects currently available in the runtime
+func (lp *LuaProcessRuntime) GetRuntimeObjects() map[string]interface{} {
+	return map[string]interface{}{}
+}
@@ -2,10 +2,13 @@ package lua
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strings"
 	"sync"
 
+	"funterm/runtime"
 	"funterm/shared"
 	"go-parser/pkg/ast"
 
@@ -32,6 +35,7 @@ type LuaRuntime struct {
 	ffiEnhancer          *FFIEnhancer     // Enhanced FFI support
 	moduleManager        *ModuleManager   // Built-in modules manager
 	verbose              bool             // Флаг для вывода отладочной информации
+	charset              *runtime.Charset // Кодировка строк Lua, nil для UTF-8
 }
 
 // NewLuaRuntime creates a new Lua runtime instance
@@ -263,6 +267,28 @@ func (lr *LuaRuntime) Execute(code string) (string, error) {
 	return "", nil
 }
 
+// SetProcessOptions sets the encoding of Lua strings, for scripts written
+// for a legacy code page
+func (lr *LuaRuntime) SetProcessOptions(options runtime.ProcessOptions) error {
+	if err := options.Validate("lua"); err != nil {
+		return err
+	}
+	charset, _ := runtime.LookupCharset(options.Encoding)
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.charset = charset
+	return nil
+}
+
+// doString runs UTF-8 code in the configured encoding of Lua strings
+func (lr *LuaRuntime) doString(code string) error {
+	err := lr.state.DoString(lr.charset.Encode(code))
+	if err != nil && lr.charset != nil {
+		return fmt.Errorf("%s", lr.charset.Decode(err.Error()))
+	}
+	return err
+}
+
 // GetState returns the Lua state
 func (lr *LuaRuntime) GetState() *lua.LState {
 	return lr.state
@@ -401,7 +427,7 @@ func (lr *LuaRuntime) registerGoFunctions() {
 			return 0
 		}
 
-		code := codeArg.String()
+		code := lr.charset.Decode(codeArg.String())
 
 		// Use the existing Eval method to execute the code
 		result, err := lr.Eval(code)
@@ -485,7 +511,7 @@ func (lr *LuaRuntime) GetVariableFromRuntimeObject(name string) (interface{}, er
 func (lr *LuaRuntime) GoToLua(value interface{}) (lua.LValue, error) {
 	switch v := value.(type) {
 	case string:
-		return lua.LString(v), nil
+		return lua.LString(lr.charset.Encode(v)), nil
 	case int:
 		return lua.LNumber(v), nil
 	case int8:
@@ -506,8 +532,14 @@ func (lr *LuaRuntime) GoToLua(value interface{}) (lua.LValue, error) {
 		return lua.LNumber(v), nil
 	case uint64:
 		return lua.LNumber(v), nil
-	case float32, float64:
-		return lua.LNumber(v.(float64)), nil
+	case float32:
+		return lua.LNumber(v), nil
+	case float64:
+		return lua.LNumber(v), nil
+	case *big.Int:
+		// Lua numbers are doubles, integers beyond 2^53 are rounded
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return lua.LNumber(f), nil
 	case bool:
 		return lua.LBool(v), nil
 	case nil:
@@ -529,7 +561,7 @@ func (lr *LuaRuntime) GoToLua(value interface{}) (lua.LValue, error) {
 			if err != nil {
 				return nil, err
 			}
-			table.RawSetString(key, luaItem)
+			table.RawSetString(lr.charset.Encode(key), luaItem)
 		}
 		return table, nil
 	case *ast.BitstringExpression:
@@ -546,7 +578,7 @@ func (lr *LuaRuntime) GoToLua(value interface{}) (lua.LValue, error) {
 		metaTable.RawSetString("__tostring", lr.state.NewFunction(func(L *lua.LState) int {
 			userData := L.CheckUserData(1)
 			if bs, ok := userData.Value.(*shared.BitstringObject); ok {
-				L.Push(lua.LString(funbit.ToFunbitFormat(bs.BitString)))
+				L.Push(lua.LString(funbit.ToFunbitFormat(bs.Bits())))
 				return 1
 			}
 			L.Push(lua.LString("<<>>"))
@@ -556,7 +588,7 @@ func (lr *LuaRuntime) GoToLua(value interface{}) (lua.LValue, error) {
 		metaTable.RawSetString("bytes", lr.state.NewFunction(func(L *lua.LState) int {
 			userData := L.CheckUserData(1)
 			if bs, ok := userData.Value.(*shared.BitstringObject); ok {
-				bytes := bs.BitString.ToBytes()
+				bytes := bs.Bytes()
 				// Convert []byte to Lua string (which can be used as bytes)
 				L.Push(lua.LString(string(bytes)))
 				return 1
@@ -636,11 +668,11 @@ func (lr *LuaRuntime) luaToGo(value lua.LValue) interface{} {
 func (lr *LuaRuntime) luaToGoWithVisited(value lua.LValue, visited map[uintptr]bool) interface{} {
 	switch value.Type() {
 	case lua.LTString:
-		return value.String()
+		return lr.charset.Decode(value.String())
 	case lua.LTNumber:
 		num := float64(value.(lua.LNumber))
-		// Check if it's a whole number within int64 range
-		if num == float64(int64(num)) && num >= -9223372036854775808 && num <= 9223372036854775807 {
+		// Whole numbers within int64 range become int64; 2^63 itself does not fit
+		if num == math.Trunc(num) && num >= math.MinInt64 && num < math.MaxInt64 {
 			return int64(num)
 		}
 		return num
@@ -707,7 +739,7 @@ func (lr *LuaRuntime) luaToGoWithVisited(value lua.LValue, visited map[uintptr]b
 				var keyStr string
 				switch key.Type() {
 				case lua.LTString:
-					keyStr = key.String()
+					keyStr = lr.charset.Decode(key.String())
 				case lua.LTNumber:
 					num := float64(key.(lua.LNumber))
 					if num == float64(int64(num)) && num >= -9223372036854775808 && num <= 9223372036854775807 {
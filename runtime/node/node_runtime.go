@@ -35,6 +35,9 @@ type NodeRuntime struct {
 	stderr        io.ReadCloser
 	resultChan    chan string
 	errorChan     chan error
+	cassette      *runtime.Cassette // Record/replay of interpreter traffic
+	// Environment and locale of the node process
+	processOptions runtime.ProcessOptions
 }
 
 // NewNodeRuntime creates a new Node.js runtime instance
@@ -50,6 +53,13 @@ func NewNodeRuntime() *NodeRuntime {
 	}
 }
 
+// SetNodePath sets the node executable to run; an empty path keeps "node"
+func (nr *NodeRuntime) SetNodePath(path string) {
+	if path != "" {
+		nr.nodePath = path
+	}
+}
+
 // SetVerbose sets the verbose mode for the Node runtime
 func (nr *NodeRuntime) SetVerbose(verbose bool) {
 	nr.verbose = verbose
@@ -65,7 +75,22 @@ func (nr *NodeRuntime) InitializeWithConfig() error {
 	nr.mutex.Lock()
 	defer nr.mutex.Unlock()
 
-	if err := nr.checkNodeAvailability(); err != nil {
+	// When replaying a cassette no node process is needed
+	if nr.cassette.Replaying() {
+		nr.available = true
+		nr.ready = true
+		return nil
+	}
+
+	// In a container or on a remote host node is found there; only ssh or
+	// the container engine has to be installed here
+	if !nr.processOptions.OnHost() {
+		if _, err := nr.processOptions.Launcher(); err != nil {
+			fmt.Printf("Warning: Node.js runtime is not available. %v\n", err)
+			nr.available = false
+			return nil
+		}
+	} else if err := nr.checkNodeAvailability(); err != nil {
 		fmt.Printf("Warning: Node.js runtime is not available. %v\n", err)
 		nr.available = false
 		// Do not return an error, just mark as unavailable
@@ -86,33 +111,32 @@ func (nr *NodeRuntime) InitializeWithConfig() error {
 }
 
 func (nr *NodeRuntime) checkNodeAvailability() error {
-	cmd := exec.Command(nr.nodePath, "--version")
+	path, err := runtime.ResolveExecutable(nr.nodePath)
+	if err != nil {
+		return fmt.Errorf("'%s' executable not found in PATH", nr.nodePath)
+	}
+	cmd := exec.Command(path, "--version")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("'%s' executable not found in PATH", nr.nodePath)
 	}
+	nr.nodePath = path
 	return nil
 }
 
 func (nr *NodeRuntime) startPersistentProcess() error {
-	nr.cmd = exec.Command(nr.nodePath, "-i")
-
 	var err error
-	nr.stdin, err = nr.cmd.StdinPipe()
-	if err != nil {
-		return err
-	}
-	nr.stdout, err = nr.cmd.StdoutPipe()
-	if err != nil {
-		return err
-	}
-	nr.stderr, err = nr.cmd.StderrPipe()
+	nr.cmd, err = nr.processOptions.InterpreterCommand(nr.nodePath, []string{"-i"})
 	if err != nil {
 		return err
 	}
+	runtime.PrepareCommand(nr.cmd)
 
-	if err := nr.cmd.Start(); err != nil {
+	// On Windows node gets a pseudo console, see StartInterpreter
+	nr.stdin, nr.stdout, nr.stderr, err = runtime.StartInterpreter(nr.cmd)
+	if err != nil {
 		return fmt.Errorf("failed to start persistent node process: %w", err)
 	}
+	runtime.TrackProcess(nr.cmd)
 
 	nr.resultChan = make(chan string)
 	nr.errorChan = make(chan error)
@@ -171,7 +195,58 @@ func (nr *NodeRuntime) readError(pipe io.ReadCloser, ch chan<- error) {
 	}
 }
 
-func (nr *NodeRuntime) sendAndAwait(code string) (string, error) {
+// SetCassette attaches a cassette for recording or replaying interpreter traffic.
+// It must be called before initialization: in replay mode no node process is started.
+func (nr *NodeRuntime) SetCassette(cassette *runtime.Cassette) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	nr.cassette = cassette
+}
+
+// SetProcessOptions sets the environment and locale of the node process;
+// it takes effect when the process is started
+func (nr *NodeRuntime) SetProcessOptions(options runtime.ProcessOptions) error {
+	if err := options.Validate("node"); err != nil {
+		return err
+	}
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	nr.processOptions = options
+	return nil
+}
+
+func (nr *NodeRuntime) sendAndAwait(code string) (result string, err error) {
+	defer func() { runtime.Trace("node", code, result, err) }()
+	if nr.cassette == nil {
+		return nr.sendToProcess(code)
+	}
+
+	if nr.cassette.Replaying() {
+		it, err := nr.cassette.Next("node", code)
+		if err != nil {
+			return "", err
+		}
+		if nr.outputCapture != nil && it.Output != "" {
+			nr.outputCapture.WriteString(it.Output)
+		}
+		return it.Result()
+	}
+
+	// readOutput finishes writing the capture before the result is delivered
+	start := 0
+	if nr.outputCapture != nil {
+		start = nr.outputCapture.Len()
+	}
+	result, err = nr.sendToProcess(code)
+	output := ""
+	if nr.outputCapture != nil && nr.outputCapture.Len() >= start {
+		output = nr.outputCapture.String()[start:]
+	}
+	nr.cassette.Record("node", code, result, output, err)
+	return result, err
+}
+
+func (nr *NodeRuntime) sendToProcess(code string) (string, error) {
 	nr.processMutex.Lock()
 	defer nr.processMutex.Unlock()
 
@@ -304,6 +379,10 @@ func (nr *NodeRuntime) ExecuteFunction(name string, args []interface{}) (interfa
 	}
 
 	lines := strings.Split(strings.TrimSpace(output), "\n")
+	// The REPL reports an exception thrown by the function instead of a result
+	if strings.HasPrefix(lines[0], "Uncaught ") {
+		return nil, errors.NewRuntimeError("node", "EXECUTION_FAILED", strings.TrimPrefix(lines[0], "Uncaught "))
+	}
 	if len(lines) > 0 {
 		lastLine := lines[len(lines)-1]
 		if lastLine == "undefined" || lastLine == "" {
@@ -419,6 +498,17 @@ func (nr *NodeRuntime) GetName() string {
 	return "node"
 }
 
+// ProcessID returns the pid of the node process when it runs on this
+// machine, 0 otherwise
+func (nr *NodeRuntime) ProcessID() int {
+	nr.mutex.RLock()
+	defer nr.mutex.RUnlock()
+	if nr.cmd == nil || nr.cmd.Process == nil || !nr.processOptions.OnHost() {
+		return 0
+	}
+	return nr.cmd.Process.Pid
+}
+
 func (nr *NodeRuntime) IsReady() bool {
 	return nr.ready
 }
@@ -426,10 +516,7 @@ func (nr *NodeRuntime) IsReady() bool {
 func (nr *NodeRuntime) Cleanup() error {
 	nr.mutex.Lock()
 	defer nr.mutex.Unlock()
-	if nr.cmd != nil && nr.cmd.Process != nil {
-		return nr.cmd.Process.Kill()
-	}
-	return nil
+	return runtime.KillProcessTree(nr.cmd)
 }
 
 // --- Stubs for the rest of the LanguageRuntime interface ---
@@ -498,6 +585,65 @@ func (nr *NodeRuntime) ExecuteCodeBlock(code string) (interface{}, error) {
 	return nr.processExecuteCodeBlockOutput(output)
 }
 
+// Markers prefix the lines carrying the JSON value or the error of a code block
+const (
+	BlockValueMarker = "---SUTERM-NODE-VALUE---"
+	BlockErrorMarker = "---SUTERM-NODE-ERROR---"
+)
+
+// EvaluateBlock executes a code block with indirect eval, so declarations land in
+// the global scope, and returns the completion value of its last statement.
+// Output printed by the block stays in the output capture.
+func (nr *NodeRuntime) EvaluateBlock(code string) (interface{}, error) {
+	if !nr.ready {
+		if !nr.available {
+			return nil, errors.NewRuntimeError("node", "RUNTIME_UNAVAILABLE", "Node.js runtime is unavailable. Please install Node.js.")
+		}
+		return nil, errors.NewRuntimeError("node", "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+
+	nr.mutex.Lock()
+	nr.outputCapture = &strings.Builder{}
+	nr.mutex.Unlock()
+
+	sourceJSON, _ := json.Marshal(nr.processCodeForGlobalScope(code))
+	script := fmt.Sprintf("try { const __funterm_value = (0, eval)(%s); console.log(%q + JSON.stringify(__funterm_value === undefined ? null : __funterm_value)); } catch (e) { console.log(%q + String(e)); }",
+		string(sourceJSON), BlockValueMarker, BlockErrorMarker)
+	if _, err := nr.sendAndAwait(script); err != nil {
+		return nil, errors.NewRuntimeError("node", "EXECUTION_FAILED", err.Error())
+	}
+
+	// Separate the value line from the block's own output
+	nr.mutex.Lock()
+	var valueJSON, blockError string
+	var printed []string
+	for _, line := range strings.Split(strings.TrimRight(nr.outputCapture.String(), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, BlockValueMarker):
+			valueJSON = strings.TrimPrefix(line, BlockValueMarker)
+		case strings.HasPrefix(line, BlockErrorMarker):
+			blockError = strings.TrimPrefix(line, BlockErrorMarker)
+		default:
+			printed = append(printed, line)
+		}
+	}
+	nr.outputCapture.Reset()
+	nr.outputCapture.WriteString(strings.Join(printed, "\n"))
+	nr.mutex.Unlock()
+
+	if blockError != "" {
+		return nil, errors.NewRuntimeError("node", "EXECUTION_FAILED", blockError)
+	}
+	if valueJSON == "" {
+		return nil, errors.NewRuntimeError("node", "CODE_BLOCK_VALUE_ERROR", "code block produced no value")
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+		return nil, errors.NewRuntimeError("node", "CODE_BLOCK_VALUE_ERROR", fmt.Sprintf("failed to decode block value: %v", err))
+	}
+	return value, nil
+}
+
 // processCodeForGlobalScope converts let/const declarations to var for global scope
 func (nr *NodeRuntime) processCodeForGlobalScope(code string) string {
 	// Simple replacement of let/const with var
@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// MaxSafeInteger is 2^53, the largest magnitude up to which every integer
+// has an exact float64 (and JavaScript number) representation
+const MaxSafeInteger = 1 << 53
+
+// Values of NumberPolicy fields; an empty field selects the default
+const (
+	IntegersAsInt    = "int"    // integral numbers become int64
+	IntegersAsFloat  = "float"  // integral numbers stay float64
+	BigIntsAsBigInt  = "bigint" // integers beyond 2^53 become *big.Int
+	BigIntsAsStrings = "string" // integers beyond 2^53 become decimal strings
+)
+
+// NumberPolicy controls how numbers coming from language runtimes are
+// represented in FunTerm
+type NumberPolicy struct {
+	// LuaIntegers is "int" (default) or "float" for integral Lua numbers
+	LuaIntegers string `json:"lua_integers" yaml:"lua_integers"`
+	// JSIntegers is "float" (default) or "int" for integral JavaScript numbers
+	JSIntegers string `json:"js_integers" yaml:"js_integers"`
+	// PythonBigInts is "bigint" (default) or "string" for Python ints beyond 2^53
+	PythonBigInts string `json:"python_big_ints" yaml:"python_big_ints"`
+}
+
+// Validate reports an unknown value in any of the policy fields
+func (p NumberPolicy) Validate() error {
+	check := func(field, value string, allowed ...string) error {
+		if value == "" {
+			return nil
+		}
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid %s '%s', expected %s", field, value, strings.Join(allowed, " or "))
+	}
+	if err := check("lua_integers", p.LuaIntegers, IntegersAsInt, IntegersAsFloat); err != nil {
+		return err
+	}
+	if err := check("js_integers", p.JSIntegers, IntegersAsInt, IntegersAsFloat); err != nil {
+		return err
+	}
+	return check("python_big_ints", p.PythonBigInts, BigIntsAsBigInt, BigIntsAsStrings)
+}
+
+// WithDefaults returns the policy with empty fields set to their defaults
+func (p NumberPolicy) WithDefaults() NumberPolicy {
+	if p.LuaIntegers == "" {
+		p.LuaIntegers = IntegersAsInt
+	}
+	if p.JSIntegers == "" {
+		p.JSIntegers = IntegersAsFloat
+	}
+	if p.PythonBigInts == "" {
+		p.PythonBigInts = BigIntsAsBigInt
+	}
+	return p
+}
+
+// UnmarshalJSONNumbers works like json.Unmarshal into interface{}, []interface{}
+// or map[string]interface{} targets, but keeps integers beyond 2^53 exact as
+// *big.Int instead of rounding them to float64
+func UnmarshalJSONNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	// Like json.Unmarshal, reject anything after the value
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+
+	switch target := v.(type) {
+	case *interface{}:
+		*target = convertJSONNumbers(*target)
+	case *[]interface{}:
+		for i, item := range *target {
+			(*target)[i] = convertJSONNumbers(item)
+		}
+	case *map[string]interface{}:
+		for key, item := range *target {
+			(*target)[key] = convertJSONNumbers(item)
+		}
+	}
+	return nil
+}
+
+// convertJSONNumbers replaces json.Number values: integers beyond 2^53
+// become *big.Int, everything else float64 as json.Unmarshal would produce
+func convertJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		text := v.String()
+		if !strings.ContainsAny(text, ".eE") {
+			if n, ok := new(big.Int).SetString(text, 10); ok && n.CmpAbs(big.NewInt(MaxSafeInteger)) > 0 {
+				return n
+			}
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
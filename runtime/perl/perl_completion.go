@@ -0,0 +1,92 @@
+package perl
+
+import (
+	"sort"
+	"strings"
+
+	"funterm/runtime"
+)
+
+// perlModules are core modules offered for completion
+var perlModules = map[string][]string{
+	"List::Util":   {"first", "max", "min", "maxstr", "minstr", "reduce", "shuffle", "sum", "sum0", "uniq"},
+	"POSIX":        {"ceil", "floor", "fmod", "pow", "strftime", "strtol"},
+	"Scalar::Util": {"blessed", "looks_like_number", "reftype"},
+}
+
+// perlHelpers are the helpers the driver script defines
+var perlHelpers = []string{"print", "re_extract", "re_match", "re_split", "say"}
+
+// GetModules returns available modules for the runtime
+func (pr *PerlRuntime) GetModules() []string {
+	modules := make([]string, 0, len(perlModules))
+	for module := range perlModules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// GetModuleFunctions returns available functions for a specific module
+func (pr *PerlRuntime) GetModuleFunctions(module string) []string {
+	return perlModules[strings.ReplaceAll(module, ".", "::")]
+}
+
+// GetFunctionSignature returns the signature of a function in a module
+func (pr *PerlRuntime) GetFunctionSignature(module, function string) (string, error) {
+	if module == "" {
+		return function + "(...)", nil
+	}
+	return module + "::" + function + "(...)", nil
+}
+
+// GetGlobalVariables returns available global variables
+func (pr *PerlRuntime) GetGlobalVariables() []string {
+	return []string{}
+}
+
+// GetCompletionSuggestions returns completion suggestions for a given input
+func (pr *PerlRuntime) GetCompletionSuggestions(input string) []string {
+	suggestions := []string{}
+	for _, name := range append(perlHelpers, pr.GetModules()...) {
+		if strings.HasPrefix(name, input) {
+			suggestions = append(suggestions, name)
+		}
+	}
+	return suggestions
+}
+
+// GetUserDefinedFunctions returns functions defined by the user during the session
+func (pr *PerlRuntime) GetUserDefinedFunctions() []string { return []string{} }
+
+// GetImportedModules returns modules that have been imported during the session
+func (pr *PerlRuntime) GetImportedModules() []string { return []string{} }
+
+// GetDynamicCompletions returns completions based on current runtime state
+func (pr *PerlRuntime) GetDynamicCompletions(input string) ([]string, error) {
+	return pr.GetCompletionSuggestions(input), nil
+}
+
+// GetObjectProperties returns properties and methods of a runtime object
+func (pr *PerlRuntime) GetObjectProperties(objectName string) ([]string, error) {
+	return pr.GetModuleFunctions(objectName), nil
+}
+
+// GetFunctionParameters returns parameter names and types for a function;
+// perl subroutines take a flat argument list
+func (pr *PerlRuntime) GetFunctionParameters(functionName string) ([]runtime.FunctionParameter, error) {
+	return []runtime.FunctionParameter{}, nil
+}
+
+// UpdateCompletionContext updates the completion context after code execution
+func (pr *PerlRuntime) UpdateCompletionContext(executedCode string, result interface{}) error {
+	return nil
+}
+
+// RefreshRuntimeState refreshes the runtime state for completion
+func (pr *PerlRuntime) RefreshRuntimeState() error { return nil }
+
+// GetRuntimeObjects returns all objects currently available in the runtime
+func (pr *PerlRuntime) GetRuntimeObjects() map[string]interface{} {
+	return map[string]interface{}{}
+}
@@ -0,0 +1,114 @@
+package perl
+
+// driverScript runs inside the perl process. It reads one JSON request per line
+// from the original stdin and answers with one JSON line on the original stdout;
+// whatever the request prints is captured and sent back in the "output" field.
+// Scripts may reopen STDIN, e.g. to feed input to a call
+const driverScript = `use strict;
+use warnings;
+no warnings 'once';
+use JSON::PP ();
+use Encode ();
+
+my $json = JSON::PP->new->utf8->canonical->allow_nonref;
+open(my $protocol, '>&', \*STDOUT) or die "cannot dup stdout: $!";
+binmode($protocol);
+$protocol->autoflush(1);
+open(my $requests, '<&', \*STDIN) or die "cannot dup stdin: $!";
+binmode($requests);
+
+# Helpers available to scripts as pl.re_extract, pl.re_match and pl.re_split
+sub main::re_extract {
+    my ($string, $pattern) = @_;
+    my $re = qr/$pattern/;
+    my @found;
+    while ($string =~ /$re/g) {
+        my @groups = map { defined $-[$_] ? substr($string, $-[$_], $+[$_] - $-[$_]) : undef } 1 .. $#+;
+        push @found, !@groups ? $& : @groups == 1 ? $groups[0] : \@groups;
+    }
+    return \@found;
+}
+sub main::re_match { my ($string, $pattern) = @_; return $string =~ /$pattern/ ? JSON::PP::true : JSON::PP::false }
+sub main::re_split { my ($string, $pattern) = @_; return [split /$pattern/, $string] }
+
+# plain turns values JSON can't represent into strings
+sub plain {
+    my ($value) = @_;
+    my $type = ref $value;
+    return $value if $type eq '' || $type eq 'JSON::PP::Boolean';
+    return [map { plain($_) } @$value] if $type eq 'ARRAY';
+    return { map { $_ => plain($value->{$_}) } keys %$value } if $type eq 'HASH';
+    return plain($$value) if $type eq 'SCALAR' || $type eq 'REF';
+    return "$value";
+}
+
+sub result_of {
+    my @values = @_;
+    return undef unless @values;
+    return $values[0] if @values == 1;
+    return \@values;
+}
+
+sub run {
+    my ($request) = @_;
+    my $op = $request->{op};
+    if ($op eq 'call') {
+        my ($name, @args) = ($request->{name}, @{ $request->{args} || [] });
+        $name =~ s/\./::/g;
+        if ($name eq 'print' || $name eq 'say') {
+            print join('', map { defined $_ ? $_ : '' } @args), "\n";
+            return undef;
+        }
+        if ($name =~ /^(.+)::[^:]+$/ && !defined &{$name}) {
+            (my $file = "$1.pm") =~ s{::}{/}g;
+            require $file;
+        }
+        no strict 'refs';
+        die "Undefined subroutine &$name called\n" unless defined &{$name};
+        return result_of(&{\&{$name}}(@args));
+    }
+    if ($op eq 'eval' || $op eq 'block') {
+        my @values = eval "package main; no strict; no warnings;\n#line 1 \"perl\"\n$request->{code}\n;";
+        die $@ if $@;
+        return $op eq 'eval' ? result_of(@values) : result_of(@values[-1 .. -1]);
+    }
+    no strict 'refs';
+    my $name = "main::$request->{name}";
+    if ($op eq 'set') {
+        ${$name} = $request->{value};
+        return undef;
+    }
+    if ($op eq 'get') {
+        return ${$name} if defined ${$name};
+        return [@{$name}] if @{$name};
+        return {%{$name}} if %{$name};
+        die "variable '$request->{name}' not found\n";
+    }
+    die "unknown operation '$op'\n";
+}
+
+while (my $line = <$requests>) {
+    my $request = eval { $json->decode($line) };
+    next unless $request;
+    my $output = '';
+    my %response = (id => $request->{id});
+    {
+        local *STDOUT;
+        open(STDOUT, '>', \$output) or die "cannot capture stdout: $!";
+        binmode(STDOUT, ':encoding(UTF-8)');
+        my $previous = select(STDOUT);
+        my $value = eval { run($request) };
+        if ($@) {
+            (my $error = "$@") =~ s/,? <(?:STDIN|\$requests)> line \d+\.?//;
+            $error =~ s/\s+$//;
+            $response{error} = $error;
+        } else {
+            $response{value} = plain($value);
+        }
+        select($previous);
+        close(STDOUT);
+    }
+    $response{output} = Encode::decode('UTF-8', $output);
+    print {$protocol} $json->encode(\%response), "\n";
+}
+`
@@ -0,0 +1,449 @@
+package perl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"funterm/errors"
+	"funterm/runtime"
+)
+
+// perlRequest is one line of the driver protocol
+type perlRequest struct {
+	ID    int64         `json:"id,omitempty"`
+	Op    string        `json:"op"`
+	Name  string        `json:"name,omitempty"`
+	Code  string        `json:"code,omitempty"`
+	Args  []interface{} `json:"args,omitempty"`
+	Value interface{}   `json:"value,omitempty"`
+}
+
+// perlResponse is the driver's answer to a request
+type perlResponse struct {
+	ID     int64           `json:"id"`
+	Value  json.RawMessage `json:"value"`
+	Error  *string         `json:"error"`
+	Output string          `json:"output"`
+}
+
+// PerlRuntime implements the LanguageRuntime interface for Perl 5.
+// The perl process is started on first use, so funterm works without perl
+// installed as long as no script calls it.
+type PerlRuntime struct {
+	ready            bool
+	perlPath         string
+	mutex            sync.Mutex
+	executionTimeout time.Duration
+	outputCapture    strings.Builder // output printed by the last call or block
+	cmd              *exec.Cmd
+	stdin            io.WriteCloser
+	responses        chan perlResponse
+	nextID           int64
+	cassette         *runtime.Cassette // Record/replay of interpreter traffic
+	// Environment and locale of the perl process
+	processOptions runtime.ProcessOptions
+}
+
+// NewPerlRuntime creates a new Perl runtime instance
+func NewPerlRuntime() *PerlRuntime {
+	return &PerlRuntime{
+		perlPath:         "perl",
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// SetPerlPath sets the perl executable to run; an empty path keeps "perl"
+func (pr *PerlRuntime) SetPerlPath(path string) {
+	if path != "" {
+		pr.perlPath = path
+	}
+}
+
+// SetCassette attaches a cassette for recording or replaying interpreter traffic
+func (pr *PerlRuntime) SetCassette(cassette *runtime.Cassette) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.cassette = cassette
+}
+
+// SetProcessOptions sets the environment and locale of the perl process
+func (pr *PerlRuntime) SetProcessOptions(options runtime.ProcessOptions) error {
+	if err := options.Validate("perl"); err != nil {
+		return err
+	}
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.processOptions = options
+	return nil
+}
+
+// SetExecutionTimeout limits the run time of a single request
+func (pr *PerlRuntime) SetExecutionTimeout(timeout time.Duration) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.executionTimeout = timeout
+}
+
+// Initialize marks the runtime ready; the perl process starts on first use
+func (pr *PerlRuntime) Initialize() error {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.ready = true
+	return nil
+}
+
+// startProcess starts perl with the driver script
+func (pr *PerlRuntime) startProcess() error {
+	// On a remote host perl is looked up there
+	path := pr.perlPath
+	if pr.processOptions.OnHost() {
+		resolved, err := runtime.ResolveExecutable(pr.perlPath)
+		if err != nil {
+			return fmt.Errorf("'%s' executable not found in PATH. Please install Perl", pr.perlPath)
+		}
+		path = resolved
+	}
+
+	cmd, err := pr.processOptions.InterpreterCommand(path, []string{"-e", driverScript})
+	if err != nil {
+		return err
+	}
+	runtime.PrepareCommand(cmd)
+	cmd.Stderr = os.Stderr // warn() and die outside requests
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start perl: %w", err)
+	}
+	runtime.TrackProcess(cmd)
+
+	pr.cmd = cmd
+	pr.stdin = stdin
+	pr.responses = make(chan perlResponse)
+	go readResponses(stdout, pr.responses)
+	return nil
+}
+
+// readResponses decodes response lines until the process exits
+func readResponses(pipe io.Reader, ch chan<- perlResponse) {
+	defer close(ch)
+	reader := bufio.NewReader(pipe)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var response perlResponse
+			if json.Unmarshal(line, &response) == nil {
+				ch <- response
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stopProcess kills the perl process; the next request starts a fresh one
+func (pr *PerlRuntime) stopProcess() {
+	if pr.cmd == nil {
+		return
+	}
+	pr.stdin.Close()
+	runtime.KillProcessTree(pr.cmd)
+	pr.cmd.Wait()
+	pr.cmd = nil
+}
+
+// send performs one request, through the cassette if one is attached
+func (pr *PerlRuntime) send(request perlRequest) (response perlResponse, err error) {
+	if runtime.Tracing() {
+		defer func() {
+			sent, _ := json.Marshal(request)
+			received, _ := json.Marshal(response)
+			runtime.Trace("perl", string(sent), string(received), err)
+		}()
+	}
+	if pr.cassette == nil {
+		return pr.sendToProcess(request)
+	}
+
+	key, err := json.Marshal(request)
+	if err != nil {
+		return perlResponse{}, err
+	}
+	if pr.cassette.Replaying() {
+		it, err := pr.cassette.Next("perl", string(key))
+		if err != nil {
+			return perlResponse{}, err
+		}
+		line, err := it.Result()
+		if err != nil {
+			return perlResponse{}, err
+		}
+		var response perlResponse
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			return perlResponse{}, fmt.Errorf("invalid recorded perl response: %v", err)
+		}
+		return response, nil
+	}
+
+	response, err = pr.sendToProcess(request)
+	line := ""
+	if err == nil {
+		encoded, _ := json.Marshal(response)
+		line = string(encoded)
+	}
+	pr.cassette.Record("perl", string(key), line, "", err)
+	return response, err
+}
+
+// sendToProcess writes a request to the perl process and waits for its answer
+func (pr *PerlRuntime) sendToProcess(request perlRequest) (perlResponse, error) {
+	if pr.cmd == nil {
+		if err := pr.startProcess(); err != nil {
+			return perlResponse{}, err
+		}
+	}
+
+	pr.nextID++
+	request.ID = pr.nextID
+	line, err := json.Marshal(request)
+	if err != nil {
+		return perlResponse{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if _, err := pr.stdin.Write(append(line, '\n')); err != nil {
+		pr.stopProcess()
+		return perlResponse{}, fmt.Errorf("failed to write to perl stdin: %w", err)
+	}
+
+	timeout := time.After(pr.executionTimeout)
+	for {
+		select {
+		case response, ok := <-pr.responses:
+			if !ok {
+				pr.stopProcess()
+				return perlResponse{}, fmt.Errorf("perl process exited unexpectedly")
+			}
+			if response.ID == request.ID {
+				response.ID = 0
+				return response, nil
+			}
+		case <-timeout:
+			// The interpreter is stuck in the request; restart it instead of waiting
+			pr.stopProcess()
+			return perlResponse{}, fmt.Errorf("perl execution timed out after %v, the interpreter was restarted", pr.executionTimeout)
+		}
+	}
+}
+
+// request performs a request and returns its decoded value; printed output is
+// appended to the output capture
+func (pr *PerlRuntime) request(request perlRequest) (interface{}, error) {
+	if !pr.ready {
+		return nil, errors.NewRuntimeError("perl", "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+	response, err := pr.send(request)
+	if err != nil {
+		return nil, errors.NewRuntimeError("perl", "EXECUTION_FAILED", err.Error())
+	}
+	pr.outputCapture.WriteString(response.Output)
+	if response.Error != nil {
+		return nil, errors.NewRuntimeError("perl", "EXECUTION_FAILED", *response.Error)
+	}
+	return decodeValue(response.Value)
+}
+
+// decodeValue converts a JSON value from perl, keeping integers exact
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.NewRuntimeError("perl", "INVALID_RESULT", fmt.Sprintf("failed to decode result: %v", err))
+	}
+	return convertNumbers(value), nil
+}
+
+// convertNumbers replaces json.Number with int64, *big.Int or float64
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if i, ok := new(big.Int).SetString(string(v), 10); ok {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	}
+	return value
+}
+
+// ExecuteFunction calls a perl subroutine; Module.func and Module::func call
+// into a module, which is loaded on first use
+func (pr *PerlRuntime) ExecuteFunction(name string, args []interface{}) (interface{}, error) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.outputCapture.Reset()
+	return pr.request(perlRequest{Op: "call", Name: name, Args: args})
+}
+
+// ExecuteFunctionMultiple calls a subroutine and returns its list of values
+func (pr *PerlRuntime) ExecuteFunctionMultiple(functionName string, args ...interface{}) ([]interface{}, error) {
+	result, err := pr.ExecuteFunction(functionName, args)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := result.([]interface{}); ok {
+		return values, nil
+	}
+	return []interface{}{result}, nil
+}
+
+// Eval evaluates perl code and returns the value of its last statement
+func (pr *PerlRuntime) Eval(code string) (interface{}, error) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.outputCapture.Reset()
+	return pr.request(perlRequest{Op: "eval", Code: code})
+}
+
+// ExecuteCodeBlock runs a perl { ... } block and returns what it printed
+func (pr *PerlRuntime) ExecuteCodeBlock(code string) (interface{}, error) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.outputCapture.Reset()
+	if _, err := pr.request(perlRequest{Op: "eval", Code: code}); err != nil {
+		return nil, err
+	}
+	if output := pr.takeOutput(); output != "" {
+		return output, nil
+	}
+	return nil, nil
+}
+
+// ExecuteCodeBlockWithVariables runs a code block. Package variables persist
+// between blocks anyway; variables declared with my are local to the block.
+func (pr *PerlRuntime) ExecuteCodeBlockWithVariables(code string, variables []string) (interface{}, error) {
+	return pr.ExecuteCodeBlock(code)
+}
+
+// EvaluateBlock runs a code block used as a value and returns its last
+// statement; printed output is kept for GetCapturedOutput
+func (pr *PerlRuntime) EvaluateBlock(code string) (interface{}, error) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.outputCapture.Reset()
+	return pr.request(perlRequest{Op: "block", Code: code})
+}
+
+// ExecuteBatch executes code printing all output directly
+func (pr *PerlRuntime) ExecuteBatch(code string) error {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.outputCapture.Reset()
+	_, err := pr.request(perlRequest{Op: "eval", Code: code})
+	if output := pr.takeOutput(); output != "" {
+		fmt.Println(output)
+	}
+	return err
+}
+
+// GetCapturedOutput returns and clears the output of the last call or block
+func (pr *PerlRuntime) GetCapturedOutput() string {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	return pr.takeOutput()
+}
+
+func (pr *PerlRuntime) takeOutput() string {
+	output := strings.TrimSuffix(pr.outputCapture.String(), "\n")
+	pr.outputCapture.Reset()
+	return output
+}
+
+// SetVariable sets a package variable: scalars become $name, lists and maps
+// array and hash references
+func (pr *PerlRuntime) SetVariable(name string, value interface{}) error {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	_, err := pr.request(perlRequest{Op: "set", Name: name, Value: value})
+	return err
+}
+
+// GetVariable reads the package variable $name, @name or %name
+func (pr *PerlRuntime) GetVariable(name string) (interface{}, error) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	return pr.request(perlRequest{Op: "get", Name: name})
+}
+
+// Isolate restarts the interpreter, dropping all definitions
+func (pr *PerlRuntime) Isolate() error {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.stopProcess()
+	return nil
+}
+
+// Cleanup stops the perl process
+func (pr *PerlRuntime) Cleanup() error {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.stopProcess()
+	pr.ready = false
+	return nil
+}
+
+// GetSupportedTypes returns the types supported by this runtime
+func (pr *PerlRuntime) GetSupportedTypes() []string {
+	return []string{"scalar", "array", "hash", "undef"}
+}
+
+// GetName returns the name of the language runtime
+func (pr *PerlRuntime) GetName() string {
+	return "perl"
+}
+
+// ProcessID returns the pid of the perl process when it runs on this
+// machine, 0 otherwise
+func (pr *PerlRuntime) ProcessID() int {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	if pr.cmd == nil || pr.cmd.Process == nil || !pr.processOptions.OnHost() {
+		return 0
+	}
+	return pr.cmd.Process.Pid
+}
+
+// IsReady checks if the runtime is ready for execution
+func (pr *PerlRuntime) IsReady() bool {
+	return pr.ready
+}
@@ -0,0 +1,202 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStopGrace is how long a stopped process group may take to exit
+// after SIGTERM before it is killed
+const DefaultStopGrace = 2 * time.Second
+
+// stopGrace is the grace period of killTree, see SetStopGrace
+var stopGrace atomic.Int64
+
+func init() {
+	stopGrace.Store(int64(DefaultStopGrace))
+}
+
+// SetStopGrace sets how long a stopped process group may take to exit after
+// SIGTERM before it is killed; 0 kills it right away
+func SetStopGrace(grace time.Duration) {
+	if grace < 0 {
+		grace = 0
+	}
+	stopGrace.Store(int64(grace))
+}
+
+// StopGrace returns the grace period set by SetStopGrace
+func StopGrace() time.Duration {
+	return time.Duration(stopGrace.Load())
+}
+
+// ResolveExecutable finds the interpreter to run for a configured name or
+// path. On Windows the extensions from PATHEXT (.exe, .cmd, ...) are tried,
+// so "python" or "C:\Python311\python" need no ".exe" in the config.
+func ResolveExecutable(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if errors.Is(err, exec.ErrDot) {
+		// A relative path from the config is meant relative to the working directory
+		return filepath.Abs(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("'%s' executable not found", name)
+	}
+	return path, nil
+}
+
+// KillProcessTree stops an interpreter started with PrepareCommand together
+// with the processes it spawned, so a timed out call leaves nothing behind.
+// The group gets SIGTERM and StopGrace to exit before it is killed
+func KillProcessTree(cmd *exec.Cmd) error {
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+	untrackProcess(cmd)
+	if err := killTree(cmd.Process); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// containerImage matches an image reference such as python:3.12-slim,
+// ghcr.io/org/tool:1.0 or name@sha256:...; it can't start with '-', so
+// docker or podman can't read the image as an option such as --privileged
+var containerImage = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/:@-]*$`)
+
+// checkContainerImage fails if Container is not an image reference
+func (o ProcessOptions) checkContainerImage() error {
+	if o.Container != "" && !containerImage.MatchString(o.Container) {
+		return fmt.Errorf("invalid container image '%s'", o.Container)
+	}
+	return nil
+}
+
+// ContainerEngines are the programs that can run an interpreter in a
+// container, in the order they are looked for
+var ContainerEngines = []string{"docker", "podman"}
+
+// FindContainerEngine finds the program running containers: the configured one
+// or the first of ContainerEngines in PATH
+func (o ProcessOptions) FindContainerEngine() (string, error) {
+	if o.ContainerEngine != "" {
+		return ResolveExecutable(o.ContainerEngine)
+	}
+	for _, name := range ContainerEngines {
+		if path, err := ResolveExecutable(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("neither docker nor podman found in PATH to run %s", o.Container)
+}
+
+// OnHost reports whether the interpreter runs on this machine, rather than
+// in a container or on a remote host
+func (o ProcessOptions) OnHost() bool {
+	return o.Container == "" && o.Remote == ""
+}
+
+// Launcher finds the program that starts the interpreter elsewhere: ssh for
+// a remote host, docker or podman for a container
+func (o ProcessOptions) Launcher() (string, error) {
+	if o.Remote != "" {
+		path, err := ResolveExecutable("ssh")
+		if err != nil {
+			return "", fmt.Errorf("ssh not found in PATH to reach %s", o.Remote)
+		}
+		return path, nil
+	}
+	return o.FindContainerEngine()
+}
+
+// InterpreterCommand returns the command that starts an interpreter with its
+// arguments and the extra variables in env. On the host it runs with Environ.
+// In a container the working directory is mounted at the same path and used
+// as the working directory there; on a remote host it runs over ssh in the
+// home directory. Only the locale, Env and env are passed to either, since the
+// host environment means nothing there
+func (o ProcessOptions) InterpreterCommand(path string, args []string, env ...string) (*exec.Cmd, error) {
+	if o.OnHost() {
+		cmd := exec.Command(path, args...)
+		cmd.Env = append(o.Environ(), env...)
+		return cmd, nil
+	}
+	if o.Remote != "" {
+		return o.remoteCommand(path, args, env)
+	}
+	if err := o.checkContainerImage(); err != nil {
+		return nil, err
+	}
+	engine, err := o.FindContainerEngine()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	mount := dir
+	if goruntime.GOOS == "windows" {
+		mount = "/work"
+	}
+	// -i keeps stdin open: the interpreter exits, and --rm removes the
+	// container, when funterm closes it
+	run := []string{"run", "--rm", "-i", "--init", "-v", dir + ":" + mount, "-w", mount}
+	if goruntime.GOOS != "windows" && filepath.Base(engine) == "docker" {
+		// Files written to the mounted directory belong to the user, not root;
+		// rootless podman maps the user by itself
+		run = append(run, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	if o.Locale != "" {
+		run = append(run, "-e", "LANG="+o.Locale, "-e", "LC_ALL="+o.Locale)
+	}
+	for key, value := range o.Env {
+		run = append(run, "-e", key+"="+value)
+	}
+	for _, variable := range env {
+		run = append(run, "-e", variable)
+	}
+	run = append(run, o.Container, path)
+	return exec.Command(engine, append(run, args...)...), nil
+}
+
+// remoteCommand runs the interpreter over ssh. Nothing is installed on the
+// host: the interpreter reads the requests on stdin as it does locally, and
+// ssh ends when funterm closes it or the call is stopped. ssh hands the
+// command line to the remote shell, so every word is quoted
+func (o ProcessOptions) remoteCommand(path string, args, env []string) (*exec.Cmd, error) {
+	ssh, err := o.Launcher()
+	if err != nil {
+		return nil, err
+	}
+	words := []string{"env"}
+	if o.Locale != "" {
+		words = append(words, "LANG="+o.Locale, "LC_ALL="+o.Locale)
+	}
+	for key, value := range o.Env {
+		words = append(words, key+"="+value)
+	}
+	words = append(words, env...)
+	words = append(words, path)
+	words = append(words, args...)
+	for i, word := range words {
+		words[i] = shellQuote(word)
+	}
+	// BatchMode fails instead of asking for a password nobody can type,
+	// since stdin belongs to the interpreter. "--" keeps a host that starts
+	// with '-' from being read as an ssh option such as -oProxyCommand
+	return exec.Command(ssh, "-T", "-o", "BatchMode=yes", "--", o.Remote, strings.Join(words, " ")), nil
+}
+
+// shellQuote quotes a word for a POSIX shell
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
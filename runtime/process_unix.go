@@ -0,0 +1,66 @@
+//go:build !windows
+
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// PrepareCommand starts the interpreter in its own process group, which
+// KillProcessTree stops as a whole
+func PrepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// ownedByUser reports whether the file belongs to the user funterm runs as
+func ownedByUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == uint32(os.Getuid())
+}
+
+// privateDir reports whether a directory belongs to the user and only the
+// user can open it
+func privateDir(info os.FileInfo) bool {
+	return info.Mode().Perm() == 0700 && ownedByUser(info)
+}
+
+// shutdownSignals end funterm after the shutdown manager ran
+var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// ProcessAlive reports whether the process is still running
+func ProcessAlive(process *os.Process) bool {
+	return process != nil && process.Signal(syscall.Signal(0)) == nil
+}
+
+// killTree sends SIGTERM to the process group, waits up to StopGrace for it
+// to exit and then kills what is left of it
+func killTree(process *os.Process) error {
+	grace := StopGrace()
+	if grace <= 0 {
+		return syscall.Kill(-process.Pid, syscall.SIGKILL)
+	}
+	if err := syscall.Kill(-process.Pid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(grace); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if !groupAlive(process.Pid) {
+			return nil
+		}
+	}
+	if err := syscall.Kill(-process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// groupAlive reports whether a process of the group is still running.
+// Without /proc a zombie leader nobody waited for counts as running
+func groupAlive(pgid int) bool {
+	if alive, known := groupRunning(pgid); known {
+		return alive
+	}
+	return syscall.Kill(-pgid, 0) == nil
+}
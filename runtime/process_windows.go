@@ -0,0 +1,68 @@
+//go:build windows
+
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+const (
+	createNewProcessGroup          = 0x00000200
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// PrepareCommand starts the interpreter in its own process group without
+// a console window, so Ctrl+C in funterm doesn't reach it directly
+func PrepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: createNewProcessGroup,
+		HideWindow:    true,
+	}
+}
+
+// ownedByUser reports whether the file belongs to the user funterm runs as.
+// The temporary directory on Windows is the user's own, so what is in it is
+func ownedByUser(info os.FileInfo) bool {
+	return true
+}
+
+// privateDir reports whether only the user can open a directory. The mode
+// bits say nothing about that on Windows, where the directory is left to
+// the access rules of the user's profile
+func privateDir(info os.FileInfo) bool {
+	return true
+}
+
+// shutdownSignals end funterm after the shutdown manager ran
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// ProcessAlive reports whether the process is still running; signal 0
+// is not available on Windows, so the exit code is queried instead
+func ProcessAlive(process *os.Process) bool {
+	if process == nil {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(process.Pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// killTree stops the process with its children right away: console
+// programs have no SIGTERM to exit on, so there is no grace period
+func killTree(process *os.Process) error {
+	// taskkill /T also stops the children of the interpreter
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid))
+	kill.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return kill.Run()
+}
@@ -0,0 +1,48 @@
+package python
+
+import (
+	"funterm/runtime"
+)
+
+// SetCassette attaches a cassette for recording or replaying interpreter traffic.
+// It must be called before initialization: in replay mode no Python process is started.
+func (pr *PythonRuntime) SetCassette(cassette *runtime.Cassette) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.cassette = cassette
+}
+
+// exchange routes a request through the cassette if one is attached, and
+// traces it with :trace on.
+// The capture buffer is read without pr.mutex: callers such as
+// InitializeWithConfig already hold it, and readOutput finishes writing
+// before the result is delivered over resultChan.
+func (pr *PythonRuntime) exchange(code string, send func() (string, error)) (result string, err error) {
+	defer func() { runtime.Trace("python", code, result, err) }()
+	if pr.cassette == nil {
+		return send()
+	}
+
+	if pr.cassette.Replaying() {
+		it, err := pr.cassette.Next("python", code)
+		if err != nil {
+			return "", err
+		}
+		if pr.outputCapture != nil && it.Output != "" {
+			pr.outputCapture.WriteString(it.Output)
+		}
+		return it.Result()
+	}
+
+	start := 0
+	if pr.outputCapture != nil {
+		start = pr.outputCapture.Len()
+	}
+	result, err = send()
+	output := ""
+	if pr.outputCapture != nil && pr.outputCapture.Len() >= start {
+		output = pr.outputCapture.String()[start:]
+	}
+	pr.cassette.Record("python", code, result, output, err)
+	return result, err
+}
@@ -8,10 +8,17 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"funterm/runtime"
 )
 
 // sendAndAwait is the new core method for all communication with the Python REPL.
 func (pr *PythonRuntime) sendAndAwait(code string) (string, error) {
+	return pr.exchange(code, func() (string, error) { return pr.sendToProcess(code) })
+}
+
+// sendToProcess writes code to the persistent Python process and waits for its marker
+func (pr *PythonRuntime) sendToProcess(code string) (string, error) {
 	// Thread-safe access to Python process - use separate mutex to prevent race conditions
 	pr.processMutex.Lock()
 	defer pr.processMutex.Unlock()
@@ -149,7 +156,7 @@ func (pr *PythonRuntime) sendAndAwait(code string) (string, error) {
 			}
 		case <-timeout:
 			if pr.cmd != nil && pr.cmd.Process != nil {
-				_ = pr.cmd.Process.Kill()
+				_ = runtime.KillProcessTree(pr.cmd)
 			}
 			// If we timed out but got some error message, return that.
 			if stderrResult.Len() > 0 {
@@ -194,6 +201,11 @@ func (pr *PythonRuntime) sendAndAwait(code string) (string, error) {
 
 // sendAndAwaitWithID is the new core method for all communication with the Python REPL.
 func (pr *PythonRuntime) sendAndAwaitWithID(code string, execID int64) (string, error) {
+	return pr.exchange(code, func() (string, error) { return pr.sendToProcessWithID(code, execID) })
+}
+
+// sendToProcessWithID is sendToProcess with a caller-provided execution ID
+func (pr *PythonRuntime) sendToProcessWithID(code string, execID int64) (string, error) {
 	// Thread-safe access to Python process - use separate mutex to prevent race conditions
 	pr.processMutex.Lock()
 	defer pr.processMutex.Unlock()
@@ -347,7 +359,7 @@ func (pr *PythonRuntime) sendAndAwaitWithID(code string, execID int64) (string,
 			}
 		case <-timeout:
 			if pr.cmd != nil && pr.cmd.Process != nil {
-				_ = pr.cmd.Process.Kill()
+				_ = runtime.KillProcessTree(pr.cmd)
 			}
 			// If we timed out but got some error message, return that.
 			if stderrResult.Len() > 0 {
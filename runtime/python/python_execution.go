@@ -7,8 +7,17 @@ import (
 	"strings"
 
 	"funterm/errors"
+	"funterm/runtime"
+	"funterm/shared"
 )
 
+// pythonJSONLiteral quotes JSON text as a Python string literal. A JSON string
+// literal is also a valid Python one, so backslashes and quotes in values survive.
+func pythonJSONLiteral(data []byte) string {
+	quoted, _ := json.Marshal(string(data))
+	return string(quoted)
+}
+
 // preprocessArgsForJSON converts []byte to a special format that Python can recognize
 func preprocessArgsForJSON(args []interface{}) []interface{} {
 	result := make([]interface{}, len(args))
@@ -25,6 +34,14 @@ func preprocessValueForJSON(value interface{}) interface{} {
 		return map[string]interface{}{
 			"base64_bytes": base64.StdEncoding.EncodeToString(v),
 		}
+	case *shared.BitstringObject:
+		// Bitstrings arrive in Python as bytes, padded to whole bytes
+		return map[string]interface{}{
+			"base64_bytes": base64.StdEncoding.EncodeToString(v.Bytes()),
+		}
+	case *runtime.Handle:
+		// The object stays in Python, only its handle ID is sent
+		return map[string]interface{}{handleKey: v.ID}
 	case []interface{}:
 		// Handle slices recursively
 		result := make([]interface{}, len(v))
@@ -44,6 +61,86 @@ func preprocessValueForJSON(value interface{}) interface{} {
 	}
 }
 
+// objectKey marks a result json couldn't encode, described by _funterm_object
+const objectKey = "__funterm_object__"
+
+// convertArgsHelperCode defines the Python helpers that restore values which
+// could not be passed through JSON as is, byte arrays and proxy handles, and
+// describe results JSON can't encode for the converters of the runtime package
+const convertArgsHelperCode = `import json
+import base64
+
+def _funterm_object(value):
+    """Encode bytes as base64 and describe other values json can't encode"""
+    if isinstance(value, (bytes, bytearray)):
+        return {'base64_bytes': base64.b64encode(value).decode('ascii')}
+    kind = type(value)
+    described = {'` + objectKey + `': kind.__module__ + '.' + kind.__qualname__, 'text': str(value)}
+    if hasattr(value, 'tolist'):
+        try:
+            plain = value.tolist()
+            json.dumps(plain)
+            described['value'] = plain
+        except Exception:
+            pass
+    return described
+
+def _convert_bytes_in_args(data):
+    """Recursively convert base64-encoded byte arrays back to bytes"""
+    if isinstance(data, list):
+        return [_convert_bytes_in_args(item) for item in data]
+    elif isinstance(data, dict):
+        # Check if this looks like a base64-encoded byte array
+        if len(data) == 1 and 'base64_bytes' in data:
+            return base64.b64decode(data['base64_bytes'])
+        # Proxy handles refer to objects kept on the Python side
+        if len(data) == 1 and '` + handleKey + `' in data:
+            return _funterm_handles[data['` + handleKey + `']]
+        return {k: _convert_bytes_in_args(v) for k, v in data.items()}
+    else:
+        return data
+`
+
+// buildCallCode renders a Python call expression for name with positional,
+// keyword or mixed arguments as produced by the engine
+func buildCallCode(name string, args []interface{}, argsJSON []byte) string {
+	isKwargs := false
+	isMixedArgs := false
+	if len(args) == 1 {
+		// Check if this is our mixed args structure
+		if mixedArgs, ok := args[0].(map[string]interface{}); ok {
+			if _, hasPositional := mixedArgs["positional"]; hasPositional {
+				if _, hasKeyword := mixedArgs["keyword"]; hasKeyword {
+					isMixedArgs = true
+				}
+			} else {
+				// Regular kwargs map
+				isKwargs = true
+			}
+		}
+	}
+
+	var callCode string
+	if isMixedArgs {
+		// Handle mixed positional and keyword arguments
+		mixedArgs := args[0].(map[string]interface{})
+		positionalPreprocessed := preprocessArgsForJSON(mixedArgs["positional"].([]interface{}))
+		keywordPreprocessed := preprocessValueForJSON(mixedArgs["keyword"])
+		positionalJSON, _ := json.Marshal(positionalPreprocessed)
+		keywordJSON, _ := json.Marshal(keywordPreprocessed)
+		callCode = fmt.Sprintf("%s(*_convert_bytes_in_args(json.loads(%s)), **_convert_bytes_in_args(json.loads(%s)))", name, pythonJSONLiteral(positionalJSON), pythonJSONLiteral(keywordJSON))
+	} else if isKwargs {
+		// Marshal just the map for keyword arguments
+		kwargsPreprocessed := preprocessValueForJSON(args[0])
+		kwargsJSON, _ := json.Marshal(kwargsPreprocessed)
+		callCode = fmt.Sprintf("%s(**_convert_bytes_in_args(json.loads(%s)))", name, pythonJSONLiteral(kwargsJSON))
+	} else {
+		// Marshal all args for positional arguments
+		callCode = fmt.Sprintf("%s(*_convert_bytes_in_args(json.loads(%s)))", name, pythonJSONLiteral(argsJSON))
+	}
+	return callCode
+}
+
 func (pr *PythonRuntime) ensureModuleImported(functionName string) error {
 	parts := strings.Split(functionName, ".")
 	if len(parts) > 1 {
@@ -156,7 +253,7 @@ if '%s' not in globals():
 		var processedArgs []interface{}
 		if len(argsJSON) > 2 { // More than just "[]"
 			var tempArgs []interface{}
-			if err := json.Unmarshal(argsJSON, &tempArgs); err == nil {
+			if err := runtime.UnmarshalJSONNumbers(argsJSON, &tempArgs); err == nil {
 				for _, arg := range tempArgs {
 					if arg != nil {
 						processedArgs = append(processedArgs, arg)
@@ -170,80 +267,25 @@ if '%s' not in globals():
 			code = "print()"
 		} else {
 			processedArgsJSON, _ := json.Marshal(processedArgs)
-			code = fmt.Sprintf("%s(*json.loads('''%s'''))", name, string(processedArgsJSON))
+			code = fmt.Sprintf("%s(*json.loads(%s))", name, pythonJSONLiteral(processedArgsJSON))
 		}
 	} else {
 		// For other functions, just execute and let print() output be visible
 		if pr.verbose {
 			fmt.Printf("DEBUG: Python function call: %s with args: %s\n", name, string(argsJSON))
 		}
-		// Generate unique marker for this execution
+		// sendAndAwaitWithID prints the end marker after the code; printing
+		// it here as well left an empty result behind for the next call
 		executionID++
-		uniqueMarker := fmt.Sprintf("%s-%d", EndOfOutputMarker, executionID)
-
-		isKwargs := false
-		isMixedArgs := false
-		if len(args) == 1 {
-			// Check if this is our mixed args structure
-			if mixedArgs, ok := args[0].(map[string]interface{}); ok {
-				if _, hasPositional := mixedArgs["positional"]; hasPositional {
-					if _, hasKeyword := mixedArgs["keyword"]; hasKeyword {
-						isMixedArgs = true
-					}
-				} else {
-					// Regular kwargs map
-					isKwargs = true
-				}
-			}
-		}
 
-		var callCode string
-		if isMixedArgs {
-			// Handle mixed positional and keyword arguments
-			mixedArgs := args[0].(map[string]interface{})
-			positionalPreprocessed := preprocessArgsForJSON(mixedArgs["positional"].([]interface{}))
-			keywordPreprocessed := preprocessValueForJSON(mixedArgs["keyword"])
-			positionalJSON, _ := json.Marshal(positionalPreprocessed)
-			keywordJSON, _ := json.Marshal(keywordPreprocessed)
-			callCode = fmt.Sprintf("%s(*_convert_bytes_in_args(json.loads('''%s''')), **_convert_bytes_in_args(json.loads('''%s''')))", name, string(positionalJSON), string(keywordJSON))
-		} else if isKwargs {
-			// Marshal just the map for keyword arguments
-			kwargsPreprocessed := preprocessValueForJSON(args[0])
-			kwargsJSON, _ := json.Marshal(kwargsPreprocessed)
-			callCode = fmt.Sprintf("%s(**_convert_bytes_in_args(json.loads('''%s''')))", name, string(kwargsJSON))
-		} else {
-			// Marshal all args for positional arguments
-			callCode = fmt.Sprintf("%s(*_convert_bytes_in_args(json.loads('''%s''')))", name, string(argsJSON))
-		}
+		callCode := buildCallCode(name, args, argsJSON)
 
 		code = fmt.Sprintf(`
-import json
-import base64
-
-def _convert_bytes_in_args(data):
-    """Recursively convert base64-encoded byte arrays back to bytes"""
-    if isinstance(data, list):
-        return [_convert_bytes_in_args(item) for item in data]
-    elif isinstance(data, dict):
-        # Check if this looks like a base64-encoded byte array
-        if len(data) == 1 and 'base64_bytes' in data:
-            return base64.b64decode(data['base64_bytes'])
-        return {k: _convert_bytes_in_args(v) for k, v in data.items()}
-    else:
-        return data
-
+%s
 _result = %s
 if _result is not None:
-	try:
-		print(json.dumps(_result))
-	except TypeError:
-		# Handle bytes objects specially
-		if isinstance(_result, bytes):
-			print(json.dumps({"base64_bytes": base64.b64encode(_result).decode('ascii')}))
-		else:
-			print(json.dumps(str(_result)))
-print('%s')
-`, callCode, uniqueMarker)
+	print(json.dumps(_result, default=_funterm_object))
+`, convertArgsHelperCode, callCode)
 		if pr.verbose {
 			fmt.Printf("DEBUG: Generated Python code: %s\n", code)
 		}
@@ -289,7 +331,7 @@ print('%s')
 	}
 
 	var result interface{}
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
+	if err := runtime.UnmarshalJSONNumbers([]byte(output), &result); err != nil {
 		if pr.verbose {
 			fmt.Printf("DEBUG: JSON unmarshal failed for '%s': %v\n", output, err)
 		}
@@ -326,6 +368,11 @@ print('%s')
 func (pr *PythonRuntime) convertBase64BytesInResult(data interface{}) interface{} {
 	switch v := data.(type) {
 	case map[string]interface{}:
+		// Values json can't encode are described by _funterm_object
+		if kind, ok := v[objectKey].(string); ok {
+			text, _ := v["text"].(string)
+			return &runtime.ForeignValue{Language: "python", Type: kind, Text: text, Value: pr.convertBase64BytesInResult(v["value"])}
+		}
 		// Check if this looks like a base64-encoded byte array
 		if len(v) == 1 {
 			if base64Str, ok := v["base64_bytes"]; ok {
@@ -378,7 +425,7 @@ func (pr *PythonRuntime) ExecuteFunctionMultiple(functionName string, args ...in
 
 	// Execute the function using the new persistent process method
 	// For multiple return values, wrap the result in a list
-	code := fmt.Sprintf("import json; result = %s(*json.loads('''%s''')); print(json.dumps(list(result) if isinstance(result, (list, tuple)) else [result]))", functionName, string(argsJSON))
+	code := fmt.Sprintf("import json; result = %s(*json.loads(%s)); print(json.dumps(list(result) if isinstance(result, (list, tuple)) else [result]))", functionName, pythonJSONLiteral(argsJSON))
 
 	output, err := pr.sendAndAwait(code)
 	if err != nil {
@@ -395,7 +442,7 @@ func (pr *PythonRuntime) ExecuteFunctionMultiple(functionName string, args ...in
 
 	// Try to parse as JSON first
 	var result []interface{}
-	if err := json.Unmarshal([]byte(output), &result); err == nil {
+	if err := runtime.UnmarshalJSONNumbers([]byte(output), &result); err == nil {
 		return result, nil
 	}
 
@@ -432,7 +479,7 @@ func (pr *PythonRuntime) SetVariable(name string, value interface{}) error {
 
 	// Set the variable in Python using the persistent process
 	// Generate simple assignment code
-	code := fmt.Sprintf("%s = json.loads('''%s''')", name, string(valueJSON))
+	code := fmt.Sprintf("%s = json.loads(%s)", name, pythonJSONLiteral(valueJSON))
 
 	_, err = pr.sendAndAwait(code)
 	if err != nil {
@@ -551,7 +598,7 @@ func (pr *PythonRuntime) GetVariable(name string) (interface{}, error) {
 
 	// Parse the JSON result
 	var value interface{}
-	if err := json.Unmarshal([]byte(result), &value); err != nil {
+	if err := runtime.UnmarshalJSONNumbers([]byte(result), &value); err != nil {
 		if pr.verbose {
 			fmt.Printf("DEBUG: Failed to parse JSON result for variable '%s': %v\n", name, err)
 		}
@@ -729,7 +776,7 @@ print(json.dumps(funterm_globals_dict))
 
 	// Parse the variables JSON
 	var variables map[string]interface{}
-	if err := json.Unmarshal([]byte(variablesOutput), &variables); err == nil {
+	if err := runtime.UnmarshalJSONNumbers([]byte(variablesOutput), &variables); err == nil {
 		pr.mutex.Lock()
 		// Update local cache with new variables
 		for name, value := range variables {
@@ -903,7 +950,7 @@ for var_name in [%s]:
 
 		// Парсим JSON с переменными
 		var variables map[string]interface{}
-		if err := json.Unmarshal([]byte(variablesOutput), &variables); err == nil {
+		if err := runtime.UnmarshalJSONNumbers([]byte(variablesOutput), &variables); err == nil {
 			pr.mutex.Lock()
 			// Обновляем локальный кеш только с указанными переменными
 			for name, value := range variables {
@@ -967,3 +1014,71 @@ func (pr *PythonRuntime) GetAllVariables() map[string]interface{} {
 	}
 	return variables
 }
+
+// BlockValueMarker prefixes the line carrying the JSON value of a code block
+const BlockValueMarker = "---SUTERM-PYTHON-VALUE---"
+
+// blockValueHelperCode runs a block in the session globals and returns the
+// value of its final expression statement (None otherwise) as JSON
+const blockValueHelperCode = `import ast as _funterm_ast
+import json
+
+def _funterm_block_value(source):
+	tree = _funterm_ast.parse(source)
+	last = None
+	if tree.body and isinstance(tree.body[-1], _funterm_ast.Expr):
+		last = _funterm_ast.Expression(tree.body.pop().value)
+	exec(compile(tree, '<block>', 'exec'), globals())
+	value = eval(compile(last, '<block>', 'eval'), globals()) if last is not None else None
+	try:
+		return json.dumps(value)
+	except TypeError:
+		return json.dumps(str(value))
+`
+
+// EvaluateBlock executes a code block like ExecuteCodeBlock and returns the value
+// of its final expression. Output printed by the block stays in the output capture.
+func (pr *PythonRuntime) EvaluateBlock(code string) (interface{}, error) {
+	if !pr.ready {
+		if !pr.available {
+			return nil, errors.NewRuntimeError("python", "RUNTIME_UNAVAILABLE", "Python runtime is unavailable. Please install Python.")
+		}
+		return nil, errors.NewRuntimeError("python", "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+
+	pr.mutex.Lock()
+	pr.outputCapture = &strings.Builder{}
+	pr.mutex.Unlock()
+
+	sourceJSON, _ := json.Marshal(code)
+	script := fmt.Sprintf("%s\nprint(%q + _funterm_block_value(%s))\n", blockValueHelperCode, BlockValueMarker, string(sourceJSON))
+
+	executionID++
+	if _, err := pr.sendAndAwaitWithID(script, executionID); err != nil {
+		return nil, pr.enhanceError(err.Error(), code)
+	}
+
+	// Separate the value line from the block's own output
+	pr.mutex.Lock()
+	var valueJSON string
+	var printed []string
+	for _, line := range strings.Split(pr.outputCapture.String(), "\n") {
+		if strings.HasPrefix(line, BlockValueMarker) {
+			valueJSON = strings.TrimPrefix(line, BlockValueMarker)
+			continue
+		}
+		printed = append(printed, line)
+	}
+	pr.outputCapture.Reset()
+	pr.outputCapture.WriteString(strings.Join(printed, "\n"))
+	pr.mutex.Unlock()
+
+	if valueJSON == "" {
+		return nil, errors.NewRuntimeError("python", "CODE_BLOCK_VALUE_ERROR", "code block produced no value")
+	}
+	var value interface{}
+	if err := runtime.UnmarshalJSONNumbers([]byte(valueJSON), &value); err != nil {
+		return nil, errors.NewRuntimeError("python", "CODE_BLOCK_VALUE_ERROR", fmt.Sprintf("failed to decode block value: %v", err))
+	}
+	return value, nil
+}
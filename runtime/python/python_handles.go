@@ -0,0 +1,83 @@
+package python
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"funterm/errors"
+	"funterm/runtime"
+)
+
+// handleKey marks a JSON argument that refers to an object in _funterm_handles
+const handleKey = "__funterm_handle__"
+
+// handleCounter numbers handles within the Python process. Background tasks
+// and sessions call into the same runtime concurrently, so it is atomic
+var handleCounter atomic.Int64
+
+// ExecuteFunctionAsHandle calls a function and keeps the result in Python,
+// returning a proxy handle instead of a JSON copy
+func (pr *PythonRuntime) ExecuteFunctionAsHandle(name string, args []interface{}) (*runtime.Handle, error) {
+	if !pr.ready {
+		if !pr.available {
+			return nil, errors.NewRuntimeError("python", "RUNTIME_UNAVAILABLE", "Python runtime is unavailable. Please install Python.")
+		}
+		return nil, errors.NewRuntimeError("python", "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+
+	if err := pr.ensureModuleImported(name); err != nil && pr.verbose {
+		fmt.Printf("DEBUG: Error during auto-import check for '%s': %v\n", name, err)
+	}
+
+	argsJSON, err := json.Marshal(preprocessArgsForJSON(args))
+	if err != nil {
+		return nil, errors.NewRuntimeError("python", "INVALID_ARGUMENT", fmt.Sprintf("failed to marshal arguments: %v", err))
+	}
+
+	id := fmt.Sprintf("h%d", handleCounter.Add(1))
+	code := fmt.Sprintf(`%s
+if '_funterm_handles' not in globals():
+    _funterm_handles = {}
+_funterm_handles[%q] = %s
+print(json.dumps(type(_funterm_handles[%q]).__name__))`, convertArgsHelperCode, id, buildCallCode(name, args, argsJSON), id)
+
+	if pr.verbose {
+		fmt.Printf("DEBUG: Python handle call: %s\n", code)
+	}
+
+	output, err := pr.sendAndAwait(code)
+	if err != nil {
+		return nil, pr.enhanceError(err.Error(), code)
+	}
+
+	typeName := "object"
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &typeName); err != nil && pr.verbose {
+		fmt.Printf("DEBUG: Failed to read handle type from '%s': %v\n", output, err)
+	}
+
+	return &runtime.Handle{Language: "python", ID: id, TypeName: typeName}, nil
+}
+
+// ReleaseHandle drops the Python reference held for the handle
+func (pr *PythonRuntime) ReleaseHandle(handle *runtime.Handle) error {
+	if !pr.ready {
+		return errors.NewRuntimeError("python", "RUNTIME_NOT_INITIALIZED", "runtime is not initialized")
+	}
+	code := fmt.Sprintf(`if '_funterm_handles' in globals():
+    _funterm_handles.pop(%q, None)`, handle.ID)
+	if _, err := pr.sendAndAwait(code); err != nil {
+		return pr.enhanceError(err.Error(), code)
+	}
+	return nil
+}
+
+// CallHandleMethod calls a method of the Python object behind the handle
+func (pr *PythonRuntime) CallHandleMethod(handle *runtime.Handle, method string, args []interface{}, keep bool) (interface{}, error) {
+	target := fmt.Sprintf("_funterm_handles[%q].%s", handle.ID, method)
+	if keep {
+		return pr.ExecuteFunctionAsHandle(target, args)
+	}
+	return pr.ExecuteFunction(target, args)
+}
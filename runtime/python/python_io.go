@@ -16,9 +16,14 @@ func filterVSCodeOutput(output string) string {
 	return filtered
 }
 
+// maxOutputLine is the longest line readOutput accepts; a result is printed
+// as one line of JSON, and a longer line would stop the reader for good
+const maxOutputLine = 256 << 20
+
 // readOutput reads from a pipe (stdout) and sends buffered output to a channel
 func (pr *PythonRuntime) readOutput(pipe io.ReadCloser, ch chan<- string) {
 	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64*1024), maxOutputLine)
 	var outputBuffer strings.Builder
 	for scanner.Scan() {
 		line := scanner.Text()
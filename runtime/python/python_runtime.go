@@ -7,8 +7,9 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
+
+	"funterm/runtime"
 )
 
 const EndOfOutputMarker = "---SUTERM-PYTHON-EOP---"
@@ -46,6 +47,15 @@ type PythonRuntime struct {
 	stderr     io.ReadCloser
 	resultChan chan string
 	errorChan  chan error
+	// Record/replay of interpreter traffic
+	cassette *runtime.Cassette
+	// Environment and encoding of the interpreter process
+	processOptions runtime.ProcessOptions
+	charset        *runtime.Charset
+	// Modules imported when the interpreter starts, and whether interpreters
+	// are forked from a process that has imported them
+	preload  []string
+	snapshot bool
 }
 
 // NewPythonRuntime creates a new Python runtime instance
@@ -77,6 +87,20 @@ func (pr *PythonRuntime) SetVerbose(verbose bool) {
 	pr.verbose = verbose
 }
 
+// SetProcessOptions sets the environment, locale and encoding of the Python
+// process; it takes effect when the process is started
+func (pr *PythonRuntime) SetProcessOptions(options runtime.ProcessOptions) error {
+	if err := options.Validate("python"); err != nil {
+		return err
+	}
+	charset, _ := runtime.LookupCharset(options.Encoding)
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.processOptions = options
+	pr.charset = charset
+	return nil
+}
+
 // SetExecutionTimeout sets the execution timeout for the Python runtime
 func (pr *PythonRuntime) SetExecutionTimeout(timeout time.Duration) {
 	pr.mutex.Lock()
@@ -102,8 +126,23 @@ func (pr *PythonRuntime) InitializeWithConfig(pythonPath string, verbose bool) e
 	}
 	pr.verbose = verbose
 
-	// Check if Python is available
-	if err := pr.checkPythonAvailability(); err != nil {
+	// При воспроизведении кассеты интерпретатор не нужен
+	if pr.cassette.Replaying() {
+		pr.available = true
+		pr.ready = true
+		return nil
+	}
+
+	// In a container or on a remote host the interpreter is found there;
+	// only ssh or the container engine has to be installed here
+	if !pr.processOptions.OnHost() {
+		if _, err := pr.processOptions.Launcher(); err != nil {
+			fmt.Printf("Warning: Python runtime is not available. %v\n", err)
+			pr.ready = false
+			pr.available = false
+			return nil
+		}
+	} else if err := pr.checkPythonAvailability(); err != nil {
 		// fmt.Printf("Warning: Python runtime is not available. %v\n", err)
 		pr.ready = false
 		pr.available = false
@@ -114,10 +153,12 @@ func (pr *PythonRuntime) InitializeWithConfig(pythonPath string, verbose bool) e
 
 	// Environment manager initialization removed - virtual environments disabled by default
 
-	// Initialize package manager
-	if err := pr.initializePackageManager(); err != nil {
-		fmt.Printf("Warning: Failed to initialize package manager: %v\n", err)
-		// Continue without package management
+	// Initialize package manager; it manages only the packages of this machine
+	if pr.processOptions.OnHost() {
+		if err := pr.initializePackageManager(); err != nil {
+			fmt.Printf("Warning: Failed to initialize package manager: %v\n", err)
+			// Continue without package management
+		}
 	}
 
 	// Simplified configuration - no complex library config needed for beta
@@ -148,25 +189,32 @@ func (pr *PythonRuntime) InitializeWithConfig(pythonPath string, verbose bool) e
 
 // startPersistentProcess starts a persistent Python process for stateful execution
 func (pr *PythonRuntime) startPersistentProcess() error {
-	pr.cmd = exec.Command(pr.pythonPath, "-i", "-u")
+	if pr.usesSnapshot() {
+		err := pr.startFromSnapshot()
+		if err == nil {
+			return nil
+		}
+		fmt.Printf("Warning: Failed to fork Python from a snapshot, starting it afresh: %v\n", err)
+	}
 
+	// Exchange text in the configured encoding (UTF-8 by default) regardless
+	// of the locale; characters it cannot represent become '?'
 	var err error
-	pr.stdin, err = pr.cmd.StdinPipe()
+	pr.cmd, err = pr.processOptions.InterpreterCommand(pr.pythonPath, []string{"-i", "-u"}, "PYTHONIOENCODING="+pr.charset.Name()+":replace")
 	if err != nil {
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
-	}
-	pr.stdout, err = pr.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-	pr.stderr, err = pr.cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
+		return err
 	}
+	runtime.PrepareCommand(pr.cmd)
 
-	if err := pr.cmd.Start(); err != nil {
+	// On Windows python gets a pseudo console, see StartInterpreter
+	stdin, stdout, stderr, err := runtime.StartInterpreter(pr.cmd)
+	if err != nil {
 		return fmt.Errorf("failed to start persistent python process: %w", err)
 	}
+	runtime.TrackProcess(pr.cmd)
+	pr.stdin = pr.charset.EncodeWriter(stdin)
+	pr.stdout = pr.charset.DecodeReader(stdout)
+	pr.stderr = pr.charset.DecodeReader(stderr)
 
 	pr.resultChan = make(chan string)
 	pr.errorChan = make(chan error)
@@ -175,27 +223,32 @@ func (pr *PythonRuntime) startPersistentProcess() error {
 	go pr.readOutput(pr.stdout, pr.resultChan)
 	go pr.readError(pr.stderr, pr.errorChan)
 
+	pr.preloadModules()
 	return nil
 }
 
 // checkPythonAvailability checks if Python is available on the system
 func (pr *PythonRuntime) checkPythonAvailability() error {
-	originalPath := pr.pythonPath
-	cmd := exec.Command(pr.pythonPath, "--version")
-	if err := cmd.Run(); err != nil {
-		// Only try fallback if the original path was the default "python3"
-		if originalPath == "python3" {
-			pr.pythonPath = "python"
-			cmd = exec.Command(pr.pythonPath, "--version")
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("neither python3 nor python found in PATH")
-			}
-		} else {
-			// Custom path specified but not found
-			return fmt.Errorf("python executable not found at specified path: %s", originalPath)
+	candidates := []string{pr.pythonPath}
+	if pr.pythonPath == "python3" {
+		// Windows installs python.exe and the py launcher rather than python3
+		candidates = append(candidates, "python", "py")
+	}
+	for _, candidate := range candidates {
+		path, err := runtime.ResolveExecutable(candidate)
+		if err != nil {
+			continue
+		}
+		if err := exec.Command(path, "--version").Run(); err == nil {
+			pr.pythonPath = path
+			return nil
 		}
 	}
-	return nil
+	if pr.pythonPath == "python3" {
+		return fmt.Errorf("neither python3 nor python found in PATH")
+	}
+	// Custom path specified but not found
+	return fmt.Errorf("python executable not found at specified path: %s", pr.pythonPath)
 }
 
 // initializePythonEnvironment sets up the basic Python environment
@@ -297,7 +350,7 @@ func (pr *PythonRuntime) Cleanup() error {
 	defer pr.mutex.Unlock()
 
 	if pr.cmd != nil && pr.cmd.Process != nil {
-		pr.cmd.Process.Kill()
+		runtime.KillProcessTree(pr.cmd)
 		pr.cmd = nil
 	}
 
@@ -311,6 +364,17 @@ func (pr *PythonRuntime) Cleanup() error {
 	return nil
 }
 
+// ProcessID returns the pid of the Python process when it runs on this
+// machine, 0 otherwise
+func (pr *PythonRuntime) ProcessID() int {
+	pr.mutex.RLock()
+	defer pr.mutex.RUnlock()
+	if pr.cmd == nil || pr.cmd.Process == nil || !pr.processOptions.OnHost() {
+		return 0
+	}
+	return pr.cmd.Process.Pid
+}
+
 // GetName returns the name of the language runtime
 func (pr *PythonRuntime) GetName() string {
 	return "python"
@@ -339,7 +403,7 @@ func (pr *PythonRuntime) Isolate() error {
 
 	// Clear all variables by restarting the Python process
 	if pr.cmd != nil && pr.cmd.Process != nil {
-		pr.cmd.Process.Kill()
+		runtime.KillProcessTree(pr.cmd)
 		pr.cmd.Wait()
 	}
 
@@ -347,6 +411,9 @@ func (pr *PythonRuntime) Isolate() error {
 	pr.variables = make(map[string]interface{})
 
 	// Restart the process
+	if pr.cassette.Replaying() {
+		return nil
+	}
 	return pr.startPersistentProcess()
 }
 
@@ -393,7 +460,7 @@ func (pr *PythonRuntime) ResetForTest() error {
 	// Check if Python process is still alive
 	if pr.cmd != nil && pr.cmd.Process != nil {
 		// Try to check if process is still running
-		if err := pr.cmd.Process.Signal(syscall.Signal(0)); err != nil {
+		if !runtime.ProcessAlive(pr.cmd.Process) {
 			// Process is dead, restart it
 			if pr.verbose {
 				fmt.Printf("DEBUG: Python process is dead, restarting for test reset\n")
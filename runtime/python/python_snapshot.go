@@ -0,0 +1,338 @@
+package python
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"funterm/runtime"
+)
+
+// snapshotScript is the fork server: it imports the preloaded modules once
+// and listens on a unix socket. For every request it forks an interpreter,
+// which opens the three FIFOs named in the request as its stdin, stdout and
+// stderr, takes the working directory and environment of the request and
+// then reads code like python -i does. The server exits when it has been
+// idle for snapshotIdle
+const snapshotScript = `
+import json, os, signal, socket, sys, types
+
+def serve(module, request):
+    import code, io
+    os.setsid()
+    signal.signal(signal.SIGCHLD, signal.SIG_DFL)
+    for fd, name, flags in ((0, "stdin", os.O_RDONLY), (1, "stdout", os.O_WRONLY), (2, "stderr", os.O_WRONLY)):
+        opened = os.open(request[name], flags)
+        os.dup2(opened, fd)
+        os.close(opened)
+    os.chdir(request["cwd"])
+    os.environ.clear()
+    os.environ.update(variable.split("=", 1) for variable in request["env"] if "=" in variable)
+    encoding, _, errors = os.environ.get("PYTHONIOENCODING", "utf-8").partition(":")
+    sys.stdin = io.TextIOWrapper(io.FileIO(0, "r", closefd=False), encoding=encoding, errors=errors or "strict")
+    sys.stdout = io.TextIOWrapper(io.FileIO(1, "w", closefd=False), encoding=encoding, errors=errors or "strict", write_through=True)
+    sys.stderr = io.TextIOWrapper(io.FileIO(2, "w", closefd=False), encoding=encoding, errors="backslashreplace", write_through=True)
+    sys.modules["__main__"] = module
+    sys.argv = [""]
+
+    class Console(code.InteractiveConsole):
+        def raw_input(self, prompt=""):
+            line = sys.stdin.readline()
+            if not line:
+                raise EOFError
+            return line.rstrip("\n")
+
+    Console(module.__dict__, filename="<stdin>").interact(banner="", exitmsg="")
+
+def main():
+    path, idle = sys.argv[1], float(sys.argv[2])
+    module = types.ModuleType("__main__")
+    failed = {}
+    for name in json.loads(sys.argv[3]):
+        try:
+            exec("import " + name, module.__dict__)
+        except Exception as error:
+            failed[name] = "%s: %s" % (type(error).__name__, error)
+    signal.signal(signal.SIGCHLD, signal.SIG_IGN)
+    if os.path.exists(path):
+        os.unlink(path)
+    listener = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+    listener.bind(path)
+    listener.listen()
+    listener.settimeout(idle)
+    # The starter waits for this line, then the server runs on its own
+    print("ready", flush=True)
+    devnull = os.open(os.devnull, os.O_RDWR)
+    for fd in (0, 1, 2):
+        os.dup2(devnull, fd)
+    while True:
+        try:
+            connection, _ = listener.accept()
+        except socket.timeout:
+            break
+        with connection, connection.makefile("rw") as stream:
+            try:
+                request = json.loads(stream.readline())
+                pid = os.fork()
+            except (OSError, ValueError) as error:
+                stream.write(json.dumps({"error": str(error)}) + "\n")
+                continue
+            if pid == 0:
+                try:
+                    listener.close()
+                    serve(module, request)
+                finally:
+                    os._exit(0)
+            stream.write(json.dumps({"pid": pid, "failed": failed}) + "\n")
+    if os.path.exists(path):
+        os.unlink(path)
+
+main()
+`
+
+const (
+	// snapshotIdle is how long the fork server waits for a request before it exits
+	snapshotIdle = 10 * time.Minute
+	// snapshotTimeout bounds starting the server and forking an interpreter
+	snapshotTimeout = 10 * time.Second
+)
+
+// SetWarmStart makes the interpreter run import statements, e.g. "numpy" or
+// "pandas as pd", when it starts. With snapshot on Linux the interpreters are
+// forked from a process that has already imported them, which outlives
+// funterm, so the next session doesn't pay for the imports again
+func (pr *PythonRuntime) SetWarmStart(preload []string, snapshot bool) {
+	pr.mutex.Lock()
+	defer pr.mutex.Unlock()
+	pr.preload = preload
+	pr.snapshot = snapshot
+}
+
+// usesSnapshot reports whether interpreters are forked from a snapshot,
+// which needs Linux and an interpreter on this machine
+func (pr *PythonRuntime) usesSnapshot() bool {
+	return pr.snapshot && len(pr.preload) > 0 && goruntime.GOOS == "linux" && pr.processOptions.OnHost()
+}
+
+// preloadModules imports the preloaded modules into a freshly started
+// interpreter; a module that fails to import is reported and skipped
+func (pr *PythonRuntime) preloadModules() {
+	for _, module := range pr.preload {
+		// The imports are not calls of the script, so they bypass the cassette
+		if _, err := pr.sendToProcess("import " + module); err != nil {
+			fmt.Printf("Warning: Failed to preload Python module '%s': %s\n", module, lastLine(err.Error()))
+		}
+	}
+}
+
+// lastLine returns the last non-empty line of a traceback, the exception
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// snapshotSocket is where the fork server of this runtime's interpreter and
+// preload list listens, in the state directory, which only the user can open
+func (pr *PythonRuntime) snapshotSocket() (string, error) {
+	dir, err := runtime.StateDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(pr.pythonPath + "\x00" + strings.Join(pr.preload, "\x00")))
+	return filepath.Join(dir, "python-"+hex.EncodeToString(sum[:6])+".sock"), nil
+}
+
+// startFromSnapshot forks the interpreter from the fork server, starting the
+// server when none is listening
+func (pr *PythonRuntime) startFromSnapshot() error {
+	socket, err := pr.snapshotSocket()
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "funterm-python-")
+	if err != nil {
+		return err
+	}
+	runtime.TrackTempPath(dir)
+	defer runtime.RemoveTempPath(dir)
+
+	names := []string{"stdin", "stdout", "stderr"}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	request := map[string]interface{}{
+		"cwd": cwd,
+		"env": append(pr.processOptions.Environ(), "PYTHONIOENCODING="+pr.charset.Name()+":replace"),
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := makeFifo(path); err != nil {
+			return fmt.Errorf("failed to create %s of the interpreter: %w", name, err)
+		}
+		request[name] = path
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		if err := pr.startSnapshotServer(socket); err != nil {
+			return err
+		}
+		if conn, err = net.Dial("unix", socket); err != nil {
+			return err
+		}
+	}
+	if err := checkSnapshotPeer(conn); err != nil {
+		conn.Close()
+		return err
+	}
+	reply, err := snapshotFork(conn, request)
+	if err != nil {
+		return err
+	}
+	for _, module := range pr.preload {
+		if reason, failed := reply.Failed[module]; failed {
+			fmt.Printf("Warning: Failed to preload Python module '%s': %s\n", module, reason)
+		}
+	}
+	process, err := os.FindProcess(reply.Pid)
+	if err != nil {
+		return err
+	}
+
+	// Opening a FIFO waits for the other end, so the ends are opened in the
+	// order the interpreter opens them
+	var files [3]*os.File
+	opened := make(chan error, 1)
+	go func() {
+		for i, name := range names {
+			flag := os.O_RDONLY
+			if name == "stdin" {
+				flag = os.O_WRONLY
+			}
+			file, err := os.OpenFile(request[name].(string), flag, 0)
+			if err != nil {
+				opened <- err
+				return
+			}
+			files[i] = file
+		}
+		opened <- nil
+	}()
+	select {
+	case err = <-opened:
+	case <-time.After(snapshotTimeout):
+		err = fmt.Errorf("forked interpreter did not start in %v", snapshotTimeout)
+		// Opening the other ends releases the goroutine
+		for _, name := range names {
+			if other, openErr := os.OpenFile(request[name].(string), os.O_RDWR, 0); openErr == nil {
+				other.Close()
+			}
+		}
+		<-opened
+	}
+	if err != nil {
+		process.Kill()
+		for _, file := range files {
+			if file != nil {
+				file.Close()
+			}
+		}
+		return err
+	}
+
+	pr.cmd = &exec.Cmd{Path: pr.pythonPath, Process: process}
+	runtime.TrackProcess(pr.cmd)
+	pr.stdin = pr.charset.EncodeWriter(files[0])
+	pr.stdout = pr.charset.DecodeReader(files[1])
+	pr.stderr = pr.charset.DecodeReader(files[2])
+	pr.resultChan = make(chan string)
+	pr.errorChan = make(chan error)
+	go pr.readOutput(pr.stdout, pr.resultChan)
+	go pr.readError(pr.stderr, pr.errorChan)
+	return nil
+}
+
+// snapshotReply is the answer of the fork server to a request
+type snapshotReply struct {
+	Pid    int               `json:"pid"`
+	Failed map[string]string `json:"failed"`
+	Error  string            `json:"error"`
+}
+
+// snapshotFork sends a request to the fork server and reads its reply
+func snapshotFork(conn net.Conn, request map[string]interface{}) (*snapshotReply, error) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(snapshotTimeout))
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("python fork server is not responding: %w", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("python fork server is not responding: %w", err)
+	}
+	var reply snapshotReply
+	if err := json.Unmarshal([]byte(line), &reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("python fork server failed: %s", reply.Error)
+	}
+	return &reply, nil
+}
+
+// startSnapshotServer starts the fork server and waits until it listens on
+// socket. The server runs in its own process group and outlives funterm
+func (pr *PythonRuntime) startSnapshotServer(socket string) error {
+	preload, err := json.Marshal(pr.preload)
+	if err != nil {
+		return err
+	}
+	args := []string{"-u", "-c", snapshotScript, socket, fmt.Sprint(snapshotIdle.Seconds()), string(preload)}
+	cmd, err := pr.processOptions.InterpreterCommand(pr.pythonPath, args)
+	if err != nil {
+		return err
+	}
+	runtime.PrepareCommand(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start python fork server: %w", err)
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		if err == nil && strings.TrimSpace(line) != "ready" {
+			err = fmt.Errorf("unexpected output %q", line)
+		}
+		ready <- err
+		// The server is reaped if it exits while funterm is still running
+		cmd.Wait()
+	}()
+	select {
+	case err = <-ready:
+	case <-time.After(snapshotTimeout):
+		err = fmt.Errorf("timed out after %v", snapshotTimeout)
+	}
+	if err != nil {
+		runtime.KillProcessTree(cmd)
+		return fmt.Errorf("python fork server did not start: %v", err)
+	}
+	return nil
+}
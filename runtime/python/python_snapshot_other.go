@@ -0,0 +1,13 @@
+//go:build !linux
+
+package python
+
+import (
+	"fmt"
+	"net"
+)
+
+// checkSnapshotPeer is not needed where interpreters are never forked
+func checkSnapshotPeer(conn net.Conn) error {
+	return fmt.Errorf("the python fork server needs Linux")
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package python
+
+import "syscall"
+
+// makeFifo creates the named pipe a forked interpreter is connected through
+func makeFifo(path string) error {
+	return syscall.Mkfifo(path, 0600)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package python
+
+import "fmt"
+
+// makeFifo is not needed on Windows, where interpreters are never forked
+func makeFifo(path string) error {
+	return fmt.Errorf("named pipes are not supported")
+}
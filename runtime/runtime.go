@@ -157,6 +157,9 @@ func NewRuntimeManager() *RuntimeManager {
 
 	// Регистрируем стандартные алиасы
 	rm.RegisterAlias("py", "python")
+	rm.RegisterAlias("pl", "perl")
+	rm.RegisterAlias("erl", "erlang")
+	rm.RegisterAlias("ex", "elixir")
 
 	return rm
 }
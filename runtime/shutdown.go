@@ -0,0 +1,317 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The shutdown manager stops what a session leaves behind however funterm
+// ends: the interpreters and programs it started, which run in their own
+// process groups and so outlive it, and its temporary files. What is running
+// is also written to a session file, so the next funterm can clean up after
+// a session that crashed or was killed
+var shutdown = struct {
+	mu        sync.Mutex
+	processes map[*exec.Cmd]trackedProcess
+	paths     map[string]bool
+	actions   []func()
+	claimed   map[os.Signal]int
+	done      bool
+}{
+	processes: make(map[*exec.Cmd]trackedProcess),
+	paths:     make(map[string]bool),
+	claimed:   make(map[os.Signal]int),
+}
+
+// trackedProcess is a process group a session started. Started is the start
+// time of its leader where the system tells it, so a pid reused by another
+// program is not taken for it
+type trackedProcess struct {
+	Pid     int    `json:"pid"`
+	Started uint64 `json:"started,omitempty"`
+}
+
+// sessionFile is what the session file records
+type sessionFile struct {
+	Pid       int              `json:"pid"`
+	Started   uint64           `json:"started,omitempty"`
+	Processes []trackedProcess `json:"processes"`
+	Paths     []string         `json:"paths"`
+}
+
+// StateDir is the directory of the user for what funterm keeps between
+// sessions while the machine runs: sockets and session files. It is in
+// $XDG_RUNTIME_DIR when the system provides one, else in the temporary
+// directory, where another user could have made it first; so it is only
+// used when it is a directory of the user that nobody else can open
+func StateDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("funterm-%d", os.Getuid()))
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(runtimeDir) {
+		dir = filepath.Join(runtimeDir, "funterm")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() || !privateDir(info) {
+		return "", fmt.Errorf("%s is not a private directory of the user", dir)
+	}
+	return dir, nil
+}
+
+// TrackProcess makes the shutdown manager stop a started command, together
+// with its process group, when funterm exits. KillProcessTree forgets it
+func TrackProcess(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	started, _ := processStart(cmd.Process.Pid)
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	shutdown.processes[cmd] = trackedProcess{Pid: cmd.Process.Pid, Started: started}
+	saveSession()
+}
+
+// untrackProcess forgets a command that was stopped
+func untrackProcess(cmd *exec.Cmd) {
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	if _, tracked := shutdown.processes[cmd]; tracked {
+		delete(shutdown.processes, cmd)
+		saveSession()
+	}
+}
+
+// TrackTempPath makes the shutdown manager remove a temporary file or
+// directory when funterm exits
+func TrackTempPath(path string) {
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	shutdown.paths[path] = true
+	saveSession()
+}
+
+// RemoveTempPath removes a temporary file or directory and forgets it
+func RemoveTempPath(path string) {
+	os.RemoveAll(path)
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	if shutdown.paths[path] {
+		delete(shutdown.paths, path)
+		saveSession()
+	}
+}
+
+// OnShutdown adds work to do when funterm exits, before the processes are
+// stopped, e.g. restoring the terminal. The last added runs first
+func OnShutdown(action func()) {
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	shutdown.actions = append(shutdown.actions, action)
+}
+
+// ClaimSignals tells the shutdown manager that someone else handles the
+// signals, e.g. an on_signal handler of the script, so they don't end
+// funterm by themselves. The returned function gives them back
+func ClaimSignals(signals ...os.Signal) func() {
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	for _, sig := range signals {
+		shutdown.claimed[sig]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			shutdown.mu.Lock()
+			defer shutdown.mu.Unlock()
+			for _, sig := range signals {
+				shutdown.claimed[sig]--
+			}
+		})
+	}
+}
+
+// HandleShutdownSignals makes the signals that end funterm, Ctrl+C,
+// SIGTERM and SIGHUP, shut it down first, unless they are claimed
+func HandleShutdownSignals() {
+	incoming := make(chan os.Signal, 1)
+	signal.Notify(incoming, shutdownSignals...)
+	go func() {
+		for sig := range incoming {
+			shutdown.mu.Lock()
+			claimed := shutdown.claimed[sig] > 0
+			shutdown.mu.Unlock()
+			if !claimed {
+				number, _ := sig.(syscall.Signal)
+				Exit(128 + int(number))
+			}
+		}
+	}()
+}
+
+// Exit shuts the session down and ends funterm with the status code
+func Exit(code int) {
+	Shutdown()
+	os.Exit(code)
+}
+
+// Shutdown runs the shutdown work, stops the tracked processes that are
+// still running, removes the temporary paths and the session file. It runs
+// once: later calls do nothing
+func Shutdown() {
+	shutdown.mu.Lock()
+	if shutdown.done {
+		shutdown.mu.Unlock()
+		return
+	}
+	shutdown.done = true
+	actions := shutdown.actions
+	shutdown.mu.Unlock()
+
+	for i := len(actions) - 1; i >= 0; i-- {
+		actions[i]()
+	}
+
+	shutdown.mu.Lock()
+	defer shutdown.mu.Unlock()
+	// The groups are stopped together, so their grace periods overlap
+	var stopping sync.WaitGroup
+	for cmd, process := range shutdown.processes {
+		// A command somebody waited for has ended and its pid may be reused
+		if cmd.ProcessState == nil && sameProcess(process) {
+			stopping.Add(1)
+			go func(process *os.Process) {
+				defer stopping.Done()
+				killTree(process)
+			}(cmd.Process)
+		}
+	}
+	stopping.Wait()
+	for path := range shutdown.paths {
+		os.RemoveAll(path)
+	}
+	shutdown.processes = make(map[*exec.Cmd]trackedProcess)
+	shutdown.paths = make(map[string]bool)
+	if path, err := sessionPath(os.Getpid()); err == nil {
+		os.Remove(path)
+	}
+}
+
+// sameProcess reports whether the process a record was made for still runs
+func sameProcess(process trackedProcess) bool {
+	if process.Started != 0 {
+		started, ok := processStart(process.Pid)
+		return ok && started == process.Started
+	}
+	found, err := os.FindProcess(process.Pid)
+	return err == nil && ProcessAlive(found)
+}
+
+// sessionPath is the session file of the funterm with the pid
+func sessionPath(pid int) (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("session-%d.json", pid)), nil
+}
+
+// saveSession writes what the session is tracking to its session file, or
+// removes the file when it tracks nothing. The caller holds shutdown.mu
+func saveSession() {
+	path, err := sessionPath(os.Getpid())
+	if err != nil {
+		return
+	}
+	if len(shutdown.processes) == 0 && len(shutdown.paths) == 0 {
+		os.Remove(path)
+		return
+	}
+	session := sessionFile{Pid: os.Getpid(), Processes: []trackedProcess{}, Paths: []string{}}
+	session.Started, _ = processStart(session.Pid)
+	for _, process := range shutdown.processes {
+		session.Processes = append(session.Processes, process)
+	}
+	for path := range shutdown.paths {
+		session.Paths = append(session.Paths, path)
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+	// A sweep never reads a half-written file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+// SweepOrphans cleans up after the sessions that ended without shutting
+// down, e.g. killed with SIGKILL or crashed: it stops the process groups
+// they left running and removes their temporary paths. A process is only
+// stopped when its start time shows it is the one the session started,
+// which needs Linux; elsewhere only the paths are removed. It returns the
+// number of processes stopped
+func SweepOrphans() int {
+	dir, err := StateDir()
+	if err != nil {
+		return 0
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "session-*.json"))
+	if err != nil {
+		return 0
+	}
+	stopped := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var session sessionFile
+		if err := json.Unmarshal(data, &session); err != nil || session.Pid == 0 {
+			os.Remove(file)
+			continue
+		}
+		if session.Pid == os.Getpid() || sameProcess(trackedProcess{Pid: session.Pid, Started: session.Started}) {
+			continue // the session is still running
+		}
+		for _, process := range session.Processes {
+			if process.Started == 0 || !sameProcess(process) {
+				continue
+			}
+			if found, err := os.FindProcess(process.Pid); err == nil && killTree(found) == nil {
+				stopped++
+			}
+		}
+		for _, path := range session.Paths {
+			if sweepablePath(path) {
+				os.RemoveAll(path)
+			}
+		}
+		os.Remove(file)
+	}
+	return stopped
+}
+
+// sweepablePath reports whether a path of a session file is one funterm
+// creates: a funterm- entry right in the temporary directory that belongs
+// to the user. A session file may be old, so the path is checked as it is
+// now rather than trusted
+func sweepablePath(path string) bool {
+	if filepath.Dir(path) != filepath.Clean(os.TempDir()) || !strings.HasPrefix(filepath.Base(path), "funterm-") {
+		return false
+	}
+	info, err := os.Lstat(path)
+	return err == nil && ownedByUser(info)
+}
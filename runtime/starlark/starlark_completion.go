@@ -0,0 +1,176 @@
+package starlark
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"funterm/runtime"
+
+	sl "go.starlark.net/starlark"
+)
+
+// GetModules returns the predeclared modules
+func (sr *StarlarkRuntime) GetModules() []string {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetModuleFunctions returns the members of a predeclared module
+func (sr *StarlarkRuntime) GetModuleFunctions(module string) []string {
+	value, ok := modules[module]
+	if !ok {
+		return []string{}
+	}
+	holder, ok := value.(sl.HasAttrs)
+	if !ok {
+		return []string{}
+	}
+	names := holder.AttrNames()
+	sort.Strings(names)
+	return names
+}
+
+// GetFunctionSignature returns the signature of a function in a module
+func (sr *StarlarkRuntime) GetFunctionSignature(module, function string) (string, error) {
+	name := function
+	if module != "" {
+		name = module + "." + function
+	}
+	params, err := sr.GetFunctionParameters(name)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Name
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(names, ", ")), nil
+}
+
+// GetGlobalVariables returns the module globals and builtins
+func (sr *StarlarkRuntime) GetGlobalVariables() []string {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	names := make([]string, 0, len(sr.globals)+len(sl.Universe))
+	for name := range sr.globals {
+		names = append(names, name)
+	}
+	for name := range sl.Universe {
+		if _, shadowed := sr.globals[name]; !shadowed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetCompletionSuggestions returns completion suggestions for a given input
+func (sr *StarlarkRuntime) GetCompletionSuggestions(input string) []string {
+	if dot := strings.LastIndex(input, "."); dot >= 0 {
+		properties, err := sr.GetObjectProperties(input[:dot])
+		if err != nil {
+			return []string{}
+		}
+		return filterPrefix(properties, input[dot+1:], input[:dot+1])
+	}
+	return filterPrefix(sr.GetGlobalVariables(), input, "")
+}
+
+// filterPrefix returns the names starting with prefix, each prepended with qualifier
+func filterPrefix(names []string, prefix, qualifier string) []string {
+	suggestions := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			suggestions = append(suggestions, qualifier+name)
+		}
+	}
+	return suggestions
+}
+
+// GetUserDefinedFunctions returns functions defined by the user during the session
+func (sr *StarlarkRuntime) GetUserDefinedFunctions() []string {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	names := []string{}
+	for name, value := range sr.globals {
+		if _, ok := value.(*sl.Function); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetImportedModules returns the predeclared modules, which need no import
+func (sr *StarlarkRuntime) GetImportedModules() []string {
+	return sr.GetModules()
+}
+
+// GetDynamicCompletions returns completions based on current runtime state
+func (sr *StarlarkRuntime) GetDynamicCompletions(input string) ([]string, error) {
+	return sr.GetCompletionSuggestions(input), nil
+}
+
+// GetObjectProperties returns attributes and methods of a runtime object
+func (sr *StarlarkRuntime) GetObjectProperties(objectName string) ([]string, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	value, err := sr.lookup(objectName)
+	if err != nil {
+		return nil, err
+	}
+	holder, ok := value.(sl.HasAttrs)
+	if !ok {
+		return []string{}, nil
+	}
+	names := holder.AttrNames()
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetFunctionParameters returns parameter names of a user-defined function;
+// builtins do not expose their parameters
+func (sr *StarlarkRuntime) GetFunctionParameters(functionName string) ([]runtime.FunctionParameter, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	value, err := sr.lookup(functionName)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := value.(*sl.Function)
+	if !ok {
+		return []runtime.FunctionParameter{}, nil
+	}
+	params := make([]runtime.FunctionParameter, fn.NumParams())
+	for i := range params {
+		name, _ := fn.Param(i)
+		params[i] = runtime.FunctionParameter{Name: name, Type: "any"}
+	}
+	return params, nil
+}
+
+// UpdateCompletionContext is a no-op: completions read the module globals directly
+func (sr *StarlarkRuntime) UpdateCompletionContext(executedCode string, result interface{}) error {
+	return nil
+}
+
+// RefreshRuntimeState is a no-op for the same reason
+func (sr *StarlarkRuntime) RefreshRuntimeState() error { return nil }
+
+// GetRuntimeObjects returns the user's module globals
+func (sr *StarlarkRuntime) GetRuntimeObjects() map[string]interface{} {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	objects := make(map[string]interface{}, len(sr.globals))
+	for name, value := range sr.globals {
+		if _, isModule := modules[name]; !isModule {
+			objects[name] = FromStarlark(value)
+		}
+	}
+	return objects
+}
@@ -0,0 +1,483 @@
+// Package starlark provides the embedded Python runtime: a Starlark interpreter
+// (go.starlark.net) used instead of an external python3 process when
+// languages.runtimes.python.mode is "embedded".
+package starlark
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sljson "go.starlark.net/lib/json"
+	slmath "go.starlark.net/lib/math"
+	sltime "go.starlark.net/lib/time"
+	sl "go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// fileOptions enable the Python statements Starlark leaves out by default
+var fileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+	Recursion:       true,
+}
+
+// modules are predeclared, so `import math` is accepted and does nothing
+var modules = sl.StringDict{
+	"math": slmath.Module,
+	"json": sljson.Module,
+	"time": sltime.Module,
+}
+
+// importLine matches imports of the predeclared modules, anyImport any other import
+var (
+	importLine = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+(math|json|time)[ \t]*$`)
+	anyImport  = regexp.MustCompile(`(?m)^[ \t]*(?:import|from)[ \t]+([\w.]+)`)
+)
+
+// StarlarkRuntime implements the LanguageRuntime interface for the embedded Python subset
+type StarlarkRuntime struct {
+	ready            bool
+	mutex            sync.Mutex
+	globals          sl.StringDict
+	executionTimeout time.Duration
+	outputCapture    *strings.Builder // print() output of the running block, nil prints directly
+	capturedOutput   string           // output of the last value code block
+}
+
+// NewStarlarkRuntime creates a new embedded Python runtime instance
+func NewStarlarkRuntime() *StarlarkRuntime {
+	return &StarlarkRuntime{
+		executionTimeout: 30 * time.Second,
+	}
+}
+
+// SetExecutionTimeout limits the run time of a single call or code block
+func (sr *StarlarkRuntime) SetExecutionTimeout(timeout time.Duration) {
+	sr.executionTimeout = timeout
+}
+
+// Initialize sets up the module globals
+func (sr *StarlarkRuntime) Initialize() error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.globals = sl.StringDict{}
+	for name, module := range modules {
+		sr.globals[name] = module
+	}
+	sr.ready = true
+	return nil
+}
+
+// newThread returns a thread that prints into the capture buffer when one is
+// active and is cancelled after the execution timeout
+func (sr *StarlarkRuntime) newThread() (*sl.Thread, func()) {
+	thread := &sl.Thread{
+		Name: "python",
+		Print: func(_ *sl.Thread, msg string) {
+			if sr.outputCapture != nil {
+				sr.outputCapture.WriteString(msg)
+				sr.outputCapture.WriteString("\n")
+				return
+			}
+			fmt.Println(msg)
+		},
+	}
+	if sr.executionTimeout <= 0 {
+		return thread, func() {}
+	}
+	timer := time.AfterFunc(sr.executionTimeout, func() {
+		thread.Cancel(fmt.Sprintf("execution timed out after %v", sr.executionTimeout))
+	})
+	return thread, func() { timer.Stop() }
+}
+
+// exec runs code against the module globals. When the last statement is an
+// expression and keepLast is set, its value is returned instead of discarded.
+func (sr *StarlarkRuntime) exec(code string, keepLast bool) (sl.Value, error) {
+	if !sr.ready {
+		return nil, fmt.Errorf("python runtime is not initialized")
+	}
+	code = importLine.ReplaceAllString(dedent(code), "")
+	if match := anyImport.FindStringSubmatch(code); match != nil {
+		return nil, fmt.Errorf("module '%s' is not available in embedded python (available: math, json, time)", match[1])
+	}
+
+	file, err := fileOptions.Parse("<python>", code, 0)
+	if err != nil {
+		return nil, err
+	}
+	var last syntax.Expr
+	if n := len(file.Stmts); keepLast && n > 0 {
+		if stmt, ok := file.Stmts[n-1].(*syntax.ExprStmt); ok {
+			last = stmt.X
+			file.Stmts = file.Stmts[:n-1]
+		}
+	}
+
+	thread, stop := sr.newThread()
+	defer stop()
+	if err := sl.ExecREPLChunk(file, thread, sr.globals); err != nil {
+		return nil, formatError(err)
+	}
+	if last == nil {
+		return sl.None, nil
+	}
+	value, err := sl.EvalExprOptions(fileOptions, thread, last, sr.globals)
+	if err != nil {
+		return nil, formatError(err)
+	}
+	return value, nil
+}
+
+// dedent removes the indentation common to all non-blank lines of a code block
+func dedent(code string) string {
+	lines := strings.Split(code, "\n")
+	indent := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prefix := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = prefix, true
+			continue
+		}
+		for !strings.HasPrefix(prefix, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+	if indent == "" {
+		return code
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatError keeps the Starlark backtrace of evaluation errors
+func formatError(err error) error {
+	if evalErr, ok := err.(*sl.EvalError); ok {
+		return fmt.Errorf("%s", evalErr.Backtrace())
+	}
+	return err
+}
+
+// capture runs fn with print() output collected instead of written to stdout
+func (sr *StarlarkRuntime) capture(fn func() (sl.Value, error)) (sl.Value, string, error) {
+	sr.outputCapture = &strings.Builder{}
+	defer func() { sr.outputCapture = nil }()
+	value, err := fn()
+	return value, strings.TrimSuffix(sr.outputCapture.String(), "\n"), err
+}
+
+// lookup resolves a possibly dotted name such as math.sqrt
+func (sr *StarlarkRuntime) lookup(name string) (sl.Value, error) {
+	parts := strings.Split(name, ".")
+	value, ok := sr.globals[parts[0]]
+	if !ok {
+		if value, ok = sl.Universe[parts[0]]; !ok {
+			return nil, fmt.Errorf("name '%s' is not defined", parts[0])
+		}
+	}
+	for _, attr := range parts[1:] {
+		holder, ok := value.(sl.HasAttrs)
+		if !ok {
+			return nil, fmt.Errorf("%s has no attribute '%s'", value.Type(), attr)
+		}
+		next, err := holder.Attr(attr)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%s has no attribute '%s'", value.Type(), attr)
+		}
+		value = next
+	}
+	return value, nil
+}
+
+// call invokes the named function with converted arguments
+func (sr *StarlarkRuntime) call(name string, args []interface{}) (sl.Value, error) {
+	if !sr.ready {
+		return nil, fmt.Errorf("python runtime is not initialized")
+	}
+	fn, err := sr.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := fn.(sl.Callable); !ok {
+		return nil, fmt.Errorf("'%s' is not callable (%s)", name, fn.Type())
+	}
+	callArgs := make(sl.Tuple, len(args))
+	for i, arg := range args {
+		if callArgs[i], err = ToStarlark(arg); err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i+1, err)
+		}
+	}
+
+	thread, stop := sr.newThread()
+	defer stop()
+	result, err := sl.Call(thread, fn, callArgs, nil)
+	if err != nil {
+		return nil, formatError(err)
+	}
+	return result, nil
+}
+
+// ExecuteFunction calls a global function or a module function such as math.sqrt
+func (sr *StarlarkRuntime) ExecuteFunction(name string, args []interface{}) (interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	result, err := sr.call(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return FromStarlark(result), nil
+}
+
+// ExecuteFunctionMultiple calls a function and unpacks a returned tuple
+func (sr *StarlarkRuntime) ExecuteFunctionMultiple(functionName string, args ...interface{}) ([]interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	result, err := sr.call(functionName, args)
+	if err != nil {
+		return nil, err
+	}
+	if tuple, ok := result.(sl.Tuple); ok {
+		values := make([]interface{}, len(tuple))
+		for i, item := range tuple {
+			values[i] = FromStarlark(item)
+		}
+		return values, nil
+	}
+	return []interface{}{FromStarlark(result)}, nil
+}
+
+// Eval evaluates an expression and returns its value; statements are executed
+// for their effect. print() writes directly to stdout.
+func (sr *StarlarkRuntime) Eval(code string) (interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	expr, err := fileOptions.ParseExpr("<python>", strings.TrimSpace(code), 0)
+	if err != nil || !sr.ready {
+		_, err := sr.exec(code, false)
+		return nil, err
+	}
+	thread, stop := sr.newThread()
+	defer stop()
+	value, err := sl.EvalExprOptions(fileOptions, thread, expr, sr.globals)
+	if err != nil {
+		return nil, formatError(err)
+	}
+	return FromStarlark(value), nil
+}
+
+// EvaluateBlock runs a code block used as a value and returns its final
+// expression; printed output is kept for GetCapturedOutput
+func (sr *StarlarkRuntime) EvaluateBlock(code string) (interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	value, output, err := sr.capture(func() (sl.Value, error) {
+		return sr.exec(code, true)
+	})
+	sr.capturedOutput = output
+	if err != nil {
+		return nil, err
+	}
+	return FromStarlark(value), nil
+}
+
+// GetCapturedOutput returns and clears the output of the last value code block
+func (sr *StarlarkRuntime) GetCapturedOutput() string {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	output := sr.capturedOutput
+	sr.capturedOutput = ""
+	return output
+}
+
+// ExecuteBatch executes code printing all output directly
+func (sr *StarlarkRuntime) ExecuteBatch(code string) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	_, err := sr.exec(code, false)
+	return err
+}
+
+// ExecuteCodeBlockWithVariables executes a code block; all module globals
+// persist between blocks, so the variable list needs no special handling
+func (sr *StarlarkRuntime) ExecuteCodeBlockWithVariables(code string, variables []string) (interface{}, error) {
+	return sr.Eval(code)
+}
+
+// SetVariable sets a module global
+func (sr *StarlarkRuntime) SetVariable(name string, value interface{}) error {
+	converted, err := ToStarlark(value)
+	if err != nil {
+		return err
+	}
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	if sr.globals == nil {
+		return fmt.Errorf("python runtime is not initialized")
+	}
+	sr.globals[name] = converted
+	return nil
+}
+
+// GetVariable retrieves a module global or a module attribute such as math.pi
+func (sr *StarlarkRuntime) GetVariable(name string) (interface{}, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	value, err := sr.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return FromStarlark(value), nil
+}
+
+// Isolate drops all user-defined globals
+func (sr *StarlarkRuntime) Isolate() error {
+	return sr.Initialize()
+}
+
+// Cleanup releases the module globals
+func (sr *StarlarkRuntime) Cleanup() error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.globals = nil
+	sr.ready = false
+	return nil
+}
+
+// GetSupportedTypes returns the types supported by this runtime
+func (sr *StarlarkRuntime) GetSupportedTypes() []string {
+	return []string{"NoneType", "bool", "int", "float", "str", "bytes", "list", "tuple", "dict", "set"}
+}
+
+// GetName returns the name of the language runtime; the embedded runtime
+// stands in for python, so py.* calls and python blocks reach it
+func (sr *StarlarkRuntime) GetName() string {
+	return "python"
+}
+
+// IsReady checks if the runtime is ready for execution
+func (sr *StarlarkRuntime) IsReady() bool {
+	return sr.ready
+}
+
+// ToStarlark converts a funterm value to a Starlark value
+func ToStarlark(value interface{}) (sl.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return sl.None, nil
+	case sl.Value:
+		return v, nil
+	case bool:
+		return sl.Bool(v), nil
+	case int:
+		return sl.MakeInt(v), nil
+	case int64:
+		return sl.MakeInt64(v), nil
+	case *big.Int:
+		return sl.MakeBigInt(v), nil
+	case float64:
+		return sl.Float(v), nil
+	case string:
+		return sl.String(v), nil
+	case []byte:
+		return sl.Bytes(v), nil
+	case []interface{}:
+		items := make([]sl.Value, len(v))
+		for i, item := range v {
+			converted, err := ToStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = converted
+		}
+		return sl.NewList(items), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := sl.NewDict(len(v))
+		for _, key := range keys {
+			converted, err := ToStarlark(v[key])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(sl.String(key), converted); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("cannot pass %T to python", value)
+}
+
+// FromStarlark converts a Starlark value to a funterm value; integers stay
+// exact (int64 or *big.Int), functions and other objects become their repr
+func FromStarlark(value sl.Value) interface{} {
+	switch v := value.(type) {
+	case nil, sl.NoneType:
+		return nil
+	case sl.Bool:
+		return bool(v)
+	case sl.Int:
+		if i, ok := v.Int64(); ok {
+			return i
+		}
+		return v.BigInt()
+	case sl.Float:
+		return float64(v)
+	case sl.String:
+		return string(v)
+	case sl.Bytes:
+		return string(v)
+	case *sl.List:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = FromStarlark(v.Index(i))
+		}
+		return items
+	case sl.Tuple:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = FromStarlark(item)
+		}
+		return items
+	case *sl.Set:
+		items := make([]interface{}, 0, v.Len())
+		iter := v.Iterate()
+		defer iter.Done()
+		var item sl.Value
+		for iter.Next(&item) {
+			items = append(items, FromStarlark(item))
+		}
+		return items
+	case *sl.Dict:
+		result := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := sl.AsString(item[0])
+			if !ok {
+				key = item[0].String()
+			}
+			result[key] = FromStarlark(item[1])
+		}
+		return result
+	}
+	return value.String()
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// tracer writes the trace of language calls, see SetTrace
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var activeTracer atomic.Pointer[tracer]
+
+// SetTrace writes, for every language call, the call, what the runtime
+// sends to its interpreter process, the raw answer and the result before it
+// is converted to w; nil turns tracing off
+func SetTrace(w io.Writer) {
+	if w == nil {
+		activeTracer.Store(nil)
+		return
+	}
+	activeTracer.Store(&tracer{w: w})
+}
+
+// Tracing reports whether SetTrace is on
+func Tracing() bool {
+	return activeTracer.Load() != nil
+}
+
+// TraceCall traces a call made by the engine, before it is made
+func TraceCall(language, function string, args []interface{}) {
+	if !Tracing() {
+		return
+	}
+	shown := make([]string, len(args))
+	for i, arg := range args {
+		shown[i] = fmt.Sprintf("%#v", arg)
+	}
+	traceLines("", fmt.Sprintf("%s.%s(%s)", language, function, strings.Join(shown, ", ")))
+}
+
+// TraceResult traces the value a runtime returned for a call, as the engine
+// gets it before converting it
+func TraceResult(result interface{}, err error) {
+	if !Tracing() {
+		return
+	}
+	if err != nil {
+		traceLines("  error: ", err.Error())
+		return
+	}
+	traceLines("  result: ", fmt.Sprintf("%#v (%T)", result, result))
+}
+
+// Trace traces one exchange of a runtime with its interpreter process: the
+// request as sent and the response as received
+func Trace(runtimeName, request, response string, err error) {
+	if !Tracing() {
+		return
+	}
+	traceLines("  "+runtimeName+" > ", request)
+	if err != nil {
+		traceLines("  "+runtimeName+" ! ", err.Error())
+		return
+	}
+	traceLines("  "+runtimeName+" < ", response)
+}
+
+// traceLines writes text with prefix before each of its lines
+func traceLines(prefix, text string) {
+	t := activeTracer.Load()
+	if t == nil {
+		return
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		b.WriteString("[trace] " + prefix + line + "\n")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.w, b.String())
+}
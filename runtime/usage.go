@@ -0,0 +1,37 @@
+package runtime
+
+import "time"
+
+// Usage is the CPU time a process has used and the peak of its resident
+// memory so far
+type Usage struct {
+	CPU     time.Duration
+	PeakRSS int64 // bytes
+}
+
+// CallStats is what a single call cost the interpreter that ran it
+type CallStats struct {
+	CPU time.Duration
+	// PeakRSS is how much the call raised the peak resident memory of the
+	// interpreter, 0 when it stayed below an earlier peak
+	PeakRSS int64
+}
+
+// Since returns the cost of a call that started when the usage was before
+func (u Usage) Since(before Usage) CallStats {
+	return CallStats{CPU: u.CPU - before.CPU, PeakRSS: u.PeakRSS - before.PeakRSS}
+}
+
+// ProcessRuntime is implemented by runtimes whose interpreter is a separate
+// process; the other runtimes run inside funterm
+type ProcessRuntime interface {
+	// ProcessID returns the pid of the interpreter on this machine, 0 when it
+	// isn't running or runs in a container or on a remote host
+	ProcessID() int
+}
+
+// ReadUsage returns the usage of the process pid, or of funterm itself
+// when pid is 0. It is only available on Linux
+func ReadUsage(pid int) (Usage, bool) {
+	return readUsage(pid)
+}
@@ -0,0 +1,103 @@
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTick is the unit of the CPU times in /proc, USER_HZ, which is 100
+// on every architecture Linux runs funterm on
+const clockTick = 10 * time.Millisecond
+
+// statFields returns the fields of /proc/<pid>/stat after the command name,
+// which is in parentheses and may contain spaces
+func statFields(dir string) ([]string, bool) {
+	stat, err := os.ReadFile(dir + "/stat")
+	if err != nil {
+		return nil, false
+	}
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return nil, false
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	// starttime, the last field read, is the 20th
+	if len(fields) < 20 {
+		return nil, false
+	}
+	return fields, true
+}
+
+// groupRunning reports whether a process of the group, other than a zombie,
+// is running
+func groupRunning(pgid int) (bool, bool) {
+	dirs, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, false
+	}
+	group := strconv.Itoa(pgid)
+	for _, dir := range dirs {
+		if _, err := strconv.Atoi(dir.Name()); err != nil {
+			continue
+		}
+		// state and pgrp are the 1st and 3rd fields after the command name
+		if fields, ok := statFields("/proc/" + dir.Name()); ok && fields[2] == group && fields[0] != "Z" {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// processStart returns when the process started, in clock ticks since boot;
+// a zombie has ended and has none
+func processStart(pid int) (uint64, bool) {
+	fields, ok := statFields(fmt.Sprintf("/proc/%d", pid))
+	if !ok || fields[0] == "Z" {
+		return 0, false
+	}
+	started, err := strconv.ParseUint(fields[19], 10, 64)
+	return started, err == nil
+}
+
+func readUsage(pid int) (Usage, bool) {
+	dir := "/proc/self"
+	if pid != 0 {
+		dir = fmt.Sprintf("/proc/%d", pid)
+	}
+	// utime and stime are the 12th and 13th fields after the command name
+	fields, ok := statFields(dir)
+	if !ok {
+		return Usage{}, false
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Usage{}, false
+	}
+	usage := Usage{CPU: time.Duration(utime+stime) * clockTick}
+
+	status, err := os.Open(dir + "/status")
+	if err != nil {
+		return Usage{}, false
+	}
+	defer status.Close()
+	scanner := bufio.NewScanner(status)
+	for scanner.Scan() {
+		// VmHWM:	  123456 kB
+		if value, found := strings.CutPrefix(scanner.Text(), "VmHWM:"); found {
+			kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return Usage{}, false
+			}
+			usage.PeakRSS = kb * 1024
+			return usage, true
+		}
+	}
+	return Usage{}, false
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package runtime
+
+func readUsage(pid int) (Usage, bool) {
+	return Usage{}, false
+}
+
+func groupRunning(pgid int) (bool, bool) {
+	return false, false
+}
+
+func processStart(pid int) (uint64, bool) {
+	return 0, false
+}
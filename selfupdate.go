@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updateChannels are the values of update.channel and --channel
+var updateChannels = []string{"stable", "nightly"}
+
+// releasePublicKey is the base64 ed25519 key the SHA256SUMS of releases are
+// signed with. Release builds set it with
+// -ldflags "-X main.releasePublicKey=..."; a build without it can't update itself
+var releasePublicKey = ""
+
+// Every release has the binaries as funterm_<os>_<arch>[.exe], their
+// SHA-256 sums in SHA256SUMS and the signature of that file in SHA256SUMS.sig.
+// SHA256SUMS also names the version in a "version v1.2.3" line, so an older
+// release can't be passed off as a newer one
+const (
+	checksumsAsset = "SHA256SUMS"
+	signatureAsset = "SHA256SUMS.sig"
+)
+
+// Limits of what self-update downloads, so a broken or hostile server can't
+// fill the memory
+const (
+	maxMetadataSize = 16 << 20  // the release list, checksums and signature
+	maxBinarySize   = 512 << 20 // a binary
+)
+
+// updateCheckInterval is how often the REPL looks for a new version
+const updateCheckInterval = 24 * time.Hour
+
+// release is the part of a GitHub release self-update needs
+type release struct {
+	Tag        string         `json:"tag_name"`
+	Draft      bool           `json:"draft"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// updateState is what the last background check found, kept between launches
+type updateState struct {
+	Checked time.Time `json:"checked"`
+	Channel string    `json:"channel"`
+	Latest  string    `json:"latest"`
+}
+
+// RunSelfUpdate implements `funterm self-update`: it finds the newest
+// release of the channel, downloads the binary for this platform, checks it
+// against the signed checksums and replaces the running executable. With
+// checkOnly it only reports whether there is a newer version; it returns
+// false then if there is one. A build without releasePublicKey can only check.
+func RunSelfUpdate(configPath, channel string, checkOnly bool) (bool, error) {
+	cfg, err := LoadConfig(findConfigFile(configPath))
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	if channel == "" {
+		channel = cfg.Update.Channel
+	}
+	if !contains(updateChannels, channel) {
+		return false, usageErrorf("self-update", "unknown channel '%s', expected one of %s", channel, strings.Join(updateChannels, ", "))
+	}
+	if !checkOnly && releasePublicKey == "" {
+		return false, fmt.Errorf("this build has no release signing key, so a download can't be verified; install a release binary or build the new version from source")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	latest, err := latestRelease(client, cfg.Update.URL, channel)
+	if err != nil {
+		return false, err
+	}
+	saveUpdateState(updateState{Checked: time.Now(), Channel: channel, Latest: latest.Tag})
+	if compareVersions(latest.Tag, funtermVersion) <= 0 {
+		fmt.Printf("funterm v%s is up to date (%s channel)\n", funtermVersion, channel)
+		return true, nil
+	}
+	if checkOnly {
+		fmt.Printf("funterm %s is available (%s channel), this is v%s\n", latest.Tag, channel, funtermVersion)
+		return false, nil
+	}
+
+	name := "funterm_" + goruntime.GOOS + "_" + goruntime.GOARCH
+	if goruntime.GOOS == "windows" {
+		name += ".exe"
+	}
+	binaryAsset, checksums, signature := latest.asset(name), latest.asset(checksumsAsset), latest.asset(signatureAsset)
+	if binaryAsset == nil {
+		return false, fmt.Errorf("release %s has no binary for %s/%s", latest.Tag, goruntime.GOOS, goruntime.GOARCH)
+	}
+	if checksums == nil {
+		return false, fmt.Errorf("release %s has no %s", latest.Tag, checksumsAsset)
+	}
+
+	sums, err := download(client, checksums.URL, maxMetadataSize)
+	if err != nil {
+		return false, err
+	}
+	if signature == nil {
+		return false, fmt.Errorf("release %s has no %s", latest.Tag, signatureAsset)
+	}
+	sig, err := download(client, signature.URL, maxMetadataSize)
+	if err != nil {
+		return false, err
+	}
+	if err := verifyRelease(sums, sig, latest.Tag); err != nil {
+		return false, err
+	}
+	expected, err := checksumOf(sums, name)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Downloading funterm %s...\n", latest.Tag)
+	binary, err := download(client, binaryAsset.URL, maxBinarySize)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(binary)
+	if hex.EncodeToString(sum[:]) != expected {
+		return false, fmt.Errorf("checksum mismatch for %s, the download is corrupt or was tampered with", name)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return false, err
+	}
+	if err := replaceExecutable(executable, binary); err != nil {
+		return false, err
+	}
+	fmt.Printf("Updated %s from v%s to %s\n", executable, funtermVersion, latest.Tag)
+	return true, nil
+}
+
+// latestRelease returns the newest release of the channel: nightly takes
+// pre-releases too, drafts are never taken
+func latestRelease(client *http.Client, url, channel string) (*release, error) {
+	data, err := download(client, url, maxMetadataSize)
+	if err != nil {
+		return nil, err
+	}
+	var releases []release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("unexpected answer from %s: %v", url, err)
+	}
+	var latest *release
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft || r.Prerelease && channel != "nightly" {
+			continue
+		}
+		if latest == nil || compareVersions(r.Tag, latest.Tag) > 0 {
+			latest = r
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no %s release found at %s", channel, url)
+	}
+	return latest, nil
+}
+
+func (r *release) asset(name string) *releaseAsset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// download fetches url, failing when it is larger than limit bytes
+func download(client *http.Client, url string, limit int64) ([]byte, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download %s: %s", url, response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot download %s: %v", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("cannot download %s: larger than %d MB", url, limit>>20)
+	}
+	return data, nil
+}
+
+// verifySignature checks the ed25519 signature of the checksums, given raw
+// or in base64, against releasePublicKey
+func verifySignature(sums, signature []byte) error {
+	key, err := base64.StdEncoding.DecodeString(releasePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("the release signing key of this build is invalid")
+	}
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil {
+			return fmt.Errorf("%s is neither a raw nor a base64 signature", signatureAsset)
+		}
+		signature = decoded
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), sums, signature) {
+		return fmt.Errorf("the signature of %s doesn't match, the release was not signed by the funterm maintainers", checksumsAsset)
+	}
+	return nil
+}
+
+// verifyRelease checks the signature of the checksums and that they name
+// the version of the release tag
+func verifyRelease(sums, signature []byte, tag string) error {
+	if err := verifySignature(sums, signature); err != nil {
+		return err
+	}
+	// The tag isn't signed, the version in the checksums is
+	version, err := signedVersion(sums)
+	if err != nil {
+		return err
+	}
+	if compareVersions(version, tag) != 0 {
+		return fmt.Errorf("release %s is signed as version %s, the release was tampered with", tag, version)
+	}
+	return nil
+}
+
+// signedVersion finds the version line of the checksums
+func signedVersion(sums []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "version" {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("%s doesn't name the version of the release", checksumsAsset)
+}
+
+// checksumOf finds the sum of a file in sha256sum output
+func checksumOf(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// replaceExecutable writes the new binary next to the running one and moves
+// it into place, so a failed update leaves the old binary working. Windows
+// can't overwrite a running executable but can rename it; the renamed
+// <exe>.old is removed by removeReplacedExecutable on the next start
+func replaceExecutable(executable string, binary []byte) error {
+	dir := filepath.Dir(executable)
+	temp, err := os.CreateTemp(dir, ".funterm-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %v; run the update as the owner of the file", dir, err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(binary); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), 0755); err != nil {
+		return err
+	}
+	if goruntime.GOOS == "windows" {
+		old := executable + ".old"
+		os.Remove(old)
+		if err := os.Rename(executable, old); err != nil {
+			return err
+		}
+		if err := os.Rename(temp.Name(), executable); err != nil {
+			// Without the old binary back there would be no funterm at all
+			os.Rename(old, executable)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(temp.Name(), executable)
+}
+
+// removeReplacedExecutable deletes the <exe>.old a Windows update left
+// behind, which can't be removed while the old binary is still running
+func removeReplacedExecutable() {
+	if goruntime.GOOS != "windows" {
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return
+	}
+	os.Remove(executable + ".old")
+}
+
+// compareVersions orders versions like v1.2.3 and 1.2.3-nightly.20260101;
+// a pre-release comes before its version
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return comparePrerelease(aPre, bPre)
+}
+
+// comparePrerelease orders pre-release tags like semver: dot-separated
+// identifiers are compared one by one, numbers numerically (rc.9 < rc.10)
+// and before words, and a tag that is a prefix of the other comes first
+func comparePrerelease(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		x, xErr := strconv.ParseUint(aIDs[i], 10, 64)
+		y, yErr := strconv.ParseUint(bIDs[i], 10, 64)
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			return -1
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aIDs[i], bIDs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(aIDs) < len(bIDs):
+		return -1
+	case len(aIDs) > len(bIDs):
+		return 1
+	}
+	return 0
+}
+
+// updateNotice returns the notice for the REPL banner from the last check
+// and starts a new check in the background when that one is old; it never
+// waits for the network
+func updateNotice(cfg *Config) string {
+	if !cfg.Update.Notify {
+		return ""
+	}
+	state := loadUpdateState()
+	if time.Since(state.Checked) > updateCheckInterval || state.Channel != cfg.Update.Channel {
+		go func() {
+			client := &http.Client{Timeout: 10 * time.Second}
+			if latest, err := latestRelease(client, cfg.Update.URL, cfg.Update.Channel); err == nil {
+				saveUpdateState(updateState{Checked: time.Now(), Channel: cfg.Update.Channel, Latest: latest.Tag})
+			}
+		}()
+	}
+	if state.Channel != cfg.Update.Channel || compareVersions(state.Latest, funtermVersion) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("funterm %s is available, run 'funterm self-update' to install it", state.Latest)
+}
+
+func updateStatePath() string {
+	return expandHome("~/.funterm/update.json")
+}
+
+func loadUpdateState() updateState {
+	var state updateState
+	if data, err := os.ReadFile(updateStatePath()); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func saveUpdateState(state updateState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	path := updateStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644)
+}
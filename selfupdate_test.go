@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.10.0", "v1.9.9", 1},
+		{"v1.2", "v1.2.0", 0},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.2.3-rc.1", "v1.2.3", -1},
+		{"v1.2.3", "v1.2.3-rc.1", 1},
+		{"v1.2.3-rc.9", "v1.2.3-rc.10", -1},
+		{"v1.2.3-rc.10", "v1.2.3-rc.9", 1},
+		{"v1.2.3-alpha", "v1.2.3-beta", -1},
+		{"v1.2.3-alpha", "v1.2.3-alpha.1", -1},
+		{"v1.2.3-alpha.1", "v1.2.3-alpha.beta", -1},
+		{"v1.2.3-nightly.20260101", "v1.2.3-nightly.20260102", -1},
+		{"v1.2.3-rc.2", "v1.2.3-rc.2", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumOf(t *testing.T) {
+	sums := []byte("version v1.2.3\nABCDEF01  funterm_linux_amd64\n123456 *funterm_windows_amd64.exe\nbroken line here\n")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"funterm_linux_amd64", "abcdef01", false},
+		{"funterm_windows_amd64.exe", "123456", false},
+		{"funterm_darwin_arm64", "", true},
+	}
+	for _, tt := range tests {
+		got, err := checksumOf(sums, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checksumOf(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("checksumOf(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// withSigningKey sets releasePublicKey to a new key for the test and
+// returns the private half
+func withSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	saved := releasePublicKey
+	releasePublicKey = base64.StdEncoding.EncodeToString(public)
+	t.Cleanup(func() { releasePublicKey = saved })
+	return private
+}
+
+func TestVerifySignature(t *testing.T) {
+	private := withSigningKey(t)
+	sums := []byte("version v1.2.3\nabcdef  funterm_linux_amd64\n")
+	signature := ed25519.Sign(private, sums)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name      string
+		sums      []byte
+		signature []byte
+		wantErr   string
+	}{
+		{"raw signature", sums, signature, ""},
+		{"base64 signature", sums, []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), ""},
+		{"changed checksums", []byte("version v1.2.3\n000000  funterm_linux_amd64\n"), signature, "doesn't match"},
+		{"other key", sums, ed25519.Sign(otherKey, sums), "doesn't match"},
+		{"garbage", sums, []byte("not a signature"), "neither a raw nor a base64 signature"},
+	}
+	for _, tt := range tests {
+		err := verifySignature(tt.sums, tt.signature)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: verifySignature() error = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: verifySignature() error = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestVerifySignatureWithoutKey(t *testing.T) {
+	saved := releasePublicKey
+	releasePublicKey = ""
+	defer func() { releasePublicKey = saved }()
+	if err := verifySignature([]byte("sums"), make([]byte, ed25519.SignatureSize)); err == nil {
+		t.Error("verifySignature() without a key expected an error")
+	}
+}
+
+func TestVerifyRelease(t *testing.T) {
+	private := withSigningKey(t)
+	signed := func(sums string) []byte { return ed25519.Sign(private, []byte(sums)) }
+
+	tests := []struct {
+		name      string
+		sums      string
+		signature []byte
+		tag       string
+		wantErr   string
+	}{
+		{"matching version", "version v1.2.3\nabcdef  funterm_linux_amd64\n", nil, "v1.2.3", ""},
+		{"tag without v", "version v1.2.3\n", nil, "1.2.3", ""},
+		{"mismatched version line", "version v1.2.2\nabcdef  funterm_linux_amd64\n", nil, "v1.2.3", "signed as version v1.2.2"},
+		{"no version line", "abcdef  funterm_linux_amd64\n", nil, "v1.2.3", "doesn't name the version"},
+		{"bad signature", "version v1.2.3\n", signed("version v9.9.9\n"), "v1.2.3", "doesn't match"},
+	}
+	for _, tt := range tests {
+		signature := tt.signature
+		if signature == nil {
+			signature = signed(tt.sums)
+		}
+		err := verifyRelease([]byte(tt.sums), signature, tt.tag)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: verifyRelease() error = %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: verifyRelease() error = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
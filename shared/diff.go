@@ -0,0 +1,89 @@
+package shared
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Operations of a change in the result of diff()
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// Diff colors, used when the REPL shows a diff() result
+const (
+	diffColorAdded   = "\033[32m" // Green
+	diffColorRemoved = "\033[31m" // Red
+	diffColorChanged = "\033[33m" // Yellow
+	diffColorReset   = "\033[0m"
+)
+
+// IsDiff reports whether value has the shape diff() returns: a non-empty
+// array of objects with op, path, old and new
+func IsDiff(value interface{}) bool {
+	changes, ok := value.([]interface{})
+	if !ok || len(changes) == 0 {
+		return false
+	}
+	for _, item := range changes {
+		change, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		op, _ := change["op"].(string)
+		if _, ok := change["path"].(string); !ok || (op != DiffAdded && op != DiffRemoved && op != DiffChanged) {
+			return false
+		}
+		for key := range change {
+			if key != "op" && key != "path" && key != "old" && key != "new" && key != "detail" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FormatDiff shows the changes diff() returned one per line: "+ path: new"
+// for an added value, "- path: old" for a removed one and
+// "~ path: old -> new" for a changed one, with the lines of its detail
+// indented under it. color paints the lines green, red and yellow
+func FormatDiff(changes []interface{}, color bool) string {
+	lines := make([]string, 0, len(changes))
+	for _, item := range changes {
+		change, _ := item.(map[string]interface{})
+		op, _ := change["op"].(string)
+		path, _ := change["path"].(string)
+
+		var line, paint string
+		switch op {
+		case DiffAdded:
+			line, paint = "+ "+path+": "+formatDiffValue(change["new"]), diffColorAdded
+		case DiffRemoved:
+			line, paint = "- "+path+": "+formatDiffValue(change["old"]), diffColorRemoved
+		default:
+			line, paint = "~ "+path+": "+formatDiffValue(change["old"])+" -> "+formatDiffValue(change["new"]), diffColorChanged
+		}
+		if detail, ok := change["detail"].(string); ok && detail != "" {
+			line += "\n    " + strings.ReplaceAll(detail, "\n", "\n    ")
+		}
+		if color {
+			line = paint + line + diffColorReset
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDiffValue shows a value in a diff line, strings quoted so that "1"
+// and 1 can be told apart
+func formatDiffValue(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	if value == nil {
+		return "nil"
+	}
+	return FormatValueForDisplay(value)
+}
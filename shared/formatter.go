@@ -14,7 +14,11 @@ func FormatValueForDisplay(value interface{}) string {
 	switch v := value.(type) {
 	case *BitstringObject:
 		// Display in byte format: <<42,0,0,0>>
-		return formatBitstringAsBytes(v.BitString)
+		return formatBitstringAsBytes(v.Bits())
+
+	case *ResultObject:
+		// Display as ok(42) or error({"msg": ..., "type": ...})
+		return v.Tag() + "(" + FormatValueForDisplay(v.Value) + ")"
 
 	case BitstringByte:
 		// For bitstring bytes, convert to ASCII character if printable (basic ASCII)
@@ -1,10 +1,100 @@
 package shared
 
-import "github.com/funvibe/funbit/pkg/funbit"
+import (
+	"fmt"
+	"math"
+	"sync"
 
-// BitstringObject wraps a funbit.BitString with methods for Lua
+	"github.com/funvibe/funbit/pkg/funbit"
+)
+
+// BitstringObject wraps a funbit.BitString with methods for Lua.
+// Slices are copy-on-write views: they share the parent's byte storage
+// and only materialize their own funbit.BitString when funbit needs one.
 type BitstringObject struct {
-	BitString *funbit.BitString
+	mu     sync.Mutex
+	bits   *funbit.BitString // materialized value, nil for views until requested
+	data   []byte            // shared storage, never modified in place
+	offset uint              // start of the view in data, in bytes
+	length uint              // length in bits
+}
+
+// NewBitstringObject wraps an existing funbit.BitString
+func NewBitstringObject(bits *funbit.BitString) *BitstringObject {
+	if bits == nil {
+		bits = funbit.NewBitString()
+	}
+	return &BitstringObject{bits: bits, length: bits.Length()}
+}
+
+// NewBitstringObjectFromBytes takes ownership of data without copying it;
+// the caller must not modify data afterwards
+func NewBitstringObjectFromBytes(data []byte) *BitstringObject {
+	if data == nil {
+		data = []byte{}
+	}
+	return &BitstringObject{data: data, length: uint(len(data)) * 8}
+}
+
+// storage returns the shared backing bytes, converting a materialized value once
+func (bo *BitstringObject) storage() []byte {
+	bo.mu.Lock()
+	defer bo.mu.Unlock()
+	if bo.data == nil {
+		if bo.bits == nil {
+			return []byte{}
+		}
+		bo.data = bo.bits.ToBytes()
+	}
+	return bo.data
+}
+
+// Bits returns the funbit representation, materializing a view on first use
+func (bo *BitstringObject) Bits() *funbit.BitString {
+	if bo == nil {
+		return nil
+	}
+	bo.mu.Lock()
+	defer bo.mu.Unlock()
+	if bo.bits == nil {
+		if bo.data == nil {
+			bo.bits = funbit.NewBitString()
+		} else {
+			bo.bits = funbit.NewBitStringFromBits(bo.data[bo.offset:], bo.length)
+		}
+	}
+	return bo.bits
+}
+
+// Bytes returns the content padded to whole bytes. The slice may be shared
+// with other views and must be treated as read-only; use ToBytes to modify it.
+func (bo *BitstringObject) Bytes() []byte {
+	data := bo.storage()
+	return data[bo.offset : bo.offset+(bo.length+7)/8]
+}
+
+// ToBytes returns a private copy of the content padded to whole bytes
+func (bo *BitstringObject) ToBytes() []byte {
+	bytes := bo.Bytes()
+	result := make([]byte, len(bytes))
+	copy(result, bytes)
+	return result
+}
+
+// Slice returns length bits starting at bit offset. Whole-byte slices share
+// storage with bo; other slices are extracted into a new bitstring.
+func (bo *BitstringObject) Slice(offset, length uint) (*BitstringObject, error) {
+	if offset+length > bo.length || offset+length < offset {
+		return nil, fmt.Errorf("slice [%d:%d] is out of range for a %d-bit bitstring", offset, offset+length, bo.length)
+	}
+	if offset%8 != 0 || length%8 != 0 {
+		extracted, err := funbit.ExtractBits(bo.Bytes(), offset, length)
+		if err != nil {
+			return nil, err
+		}
+		return NewBitstringObject(funbit.NewBitStringFromBits(extracted, length)), nil
+	}
+	return &BitstringObject{data: bo.storage(), offset: bo.offset + offset/8, length: length}, nil
 }
 
 // BitstringByte represents a byte extracted from a bitstring
@@ -15,14 +105,125 @@ type BitstringByte struct {
 
 // Len returns the length in bits
 func (bo *BitstringObject) Len() int {
-	return int(bo.BitString.Length())
+	return int(bo.length)
 }
 
 // GetByte returns byte at index (for []byte access)
 func (bo *BitstringObject) GetByte(index int) BitstringByte {
-	bytes := bo.BitString.ToBytes()
+	bytes := bo.Bytes()
 	if index < 0 || index >= len(bytes) {
 		return BitstringByte{Value: 0}
 	}
 	return BitstringByte{Value: bytes[index]}
 }
+
+// MethodObject is implemented by engine-side values that expose methods
+// callable from funterm code as obj.method(args)
+type MethodObject interface {
+	CallMethod(name string, args []interface{}) (interface{}, error)
+}
+
+// Iterator is implemented by values that for-in loops read one item at a
+// time, such as packet captures; ok is false once there are no more items
+type Iterator interface {
+	Next() (item interface{}, ok bool, err error)
+}
+
+// ResultObject is the value of a try expression: ok(Value) when the
+// expression succeeded, error(Value) with a description of the failure otherwise
+type ResultObject struct {
+	Ok    bool
+	Value interface{}
+}
+
+// Tag returns "ok" or "error"
+func (ro *ResultObject) Tag() string {
+	if ro.Ok {
+		return "ok"
+	}
+	return "error"
+}
+
+// String formats the result as ok(...) or error(...)
+func (ro *ResultObject) String() string {
+	return FormatValueForDisplay(ro)
+}
+
+// RangeObject is the value of a range literal: Start..End includes End,
+// Start..<End stops before it. Items are computed as they are read, so a
+// range of any size takes no memory; a range whose end is below its start
+// is empty
+type RangeObject struct {
+	Start     int64
+	End       int64
+	Exclusive bool
+}
+
+// Last returns the last item of the range; it is below Start when the
+// range is empty
+func (r *RangeObject) Last() int64 {
+	if r.Exclusive {
+		return r.End - 1
+	}
+	return r.End
+}
+
+// Len returns the number of items
+func (r *RangeObject) Len() int64 {
+	if r.Last() < r.Start {
+		return 0
+	}
+	return r.Last() - r.Start + 1
+}
+
+// Contains reports whether n is an item of the range
+func (r *RangeObject) Contains(n int64) bool {
+	return n >= r.Start && n <= r.Last()
+}
+
+// Next returns a function reading the items one at a time, for for-in loops
+func (r *RangeObject) Next() func() (interface{}, bool, error) {
+	current, last := r.Start, r.Last()
+	done := last < current
+	return func() (interface{}, bool, error) {
+		if done {
+			return nil, false, nil
+		}
+		item := current
+		// The last item may be math.MaxInt64, so stop before overflowing
+		if current == last {
+			done = true
+		} else {
+			current++
+		}
+		return item, true, nil
+	}
+}
+
+// String formats the range as it is written: 1..10 or 0..<10
+func (r *RangeObject) String() string {
+	if r.Exclusive {
+		return fmt.Sprintf("%d..<%d", r.Start, r.End)
+	}
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+// Float16ToFloat64 converts an IEEE 754 half-precision value
+func Float16ToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"funterm/engine"
+	"funterm/factory"
+)
+
+// telemetryCommands are the subcommands of `funterm telemetry`
+var telemetryCommands = []string{"show", "export", "reset"}
+
+// telemetryStats is the file of the opt-in usage statistics: counts summed
+// over the sessions since Since. It holds nothing but counts and names of
+// funterm's own statements, runtimes and error codes
+type telemetryStats struct {
+	Since    time.Time      `json:"since"`
+	Updated  time.Time      `json:"updated"`
+	Funterm  string         `json:"funterm"`
+	Platform string         `json:"platform"`
+	Sessions map[string]int `json:"sessions"` // by mode: repl or script
+	Runtimes map[string]int `json:"runtimes"` // sessions each runtime was enabled in
+	engine.UsageCounts
+}
+
+func newTelemetryStats() *telemetryStats {
+	return &telemetryStats{
+		Since:       time.Now().UTC(),
+		Sessions:    make(map[string]int),
+		Runtimes:    make(map[string]int),
+		UsageCounts: engine.NewUsageCounts(),
+	}
+}
+
+// recordTelemetry adds a finished session to the statistics file when
+// telemetry is enabled. Failures are ignored, statistics never get in the way
+func recordTelemetry(cfg *Config, mode string, registry *factory.RuntimeRegistry, usage engine.UsageCounts) {
+	if !cfg.Telemetry.Enabled {
+		return
+	}
+	path := expandHome(cfg.Telemetry.File)
+	stats, err := loadTelemetry(path)
+	if err != nil {
+		stats = newTelemetryStats()
+	}
+	stats.Updated = time.Now().UTC()
+	stats.Funterm = funtermVersion
+	stats.Platform = goruntime.GOOS + "/" + goruntime.GOARCH
+	stats.Sessions[mode]++
+	for _, name := range registry.ListFactories() {
+		stats.Runtimes[name]++
+	}
+	stats.Add(usage)
+	_ = saveTelemetry(path, stats)
+}
+
+// loadTelemetry reads the statistics file; a missing file is empty statistics
+func loadTelemetry(path string) (*telemetryStats, error) {
+	stats := newTelemetryStats()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	// Maps missing from an older or edited file
+	if stats.Sessions == nil {
+		stats.Sessions = make(map[string]int)
+	}
+	if stats.Runtimes == nil {
+		stats.Runtimes = make(map[string]int)
+	}
+	fresh := engine.NewUsageCounts()
+	fresh.Add(stats.UsageCounts)
+	stats.UsageCounts = fresh
+	return stats, nil
+}
+
+// saveTelemetry writes the file through a temporary one, so sessions ending
+// at the same time never leave a half-written file
+func saveTelemetry(path string, stats *telemetryStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// RunTelemetryCommand implements `funterm telemetry show|export|reset`
+func RunTelemetryCommand(command, configPath string, noConfig bool) (bool, error) {
+	path := ""
+	if !noConfig {
+		path = findConfigFile(configPath)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	file := expandHome(cfg.Telemetry.File)
+
+	switch command {
+	case "show":
+		stats, err := loadTelemetry(file)
+		if err != nil {
+			return false, err
+		}
+		printTelemetry(cfg, file, stats)
+	case "export":
+		stats, err := loadTelemetry(file)
+		if err != nil {
+			return false, err
+		}
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(data))
+	case "reset":
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		fmt.Printf("Usage statistics in %s deleted\n", file)
+	default:
+		return false, usageErrorf("telemetry", "unknown command '%s', expected one of %s", command, strings.Join(telemetryCommands, ", "))
+	}
+	return true, nil
+}
+
+func printTelemetry(cfg *Config, file string, stats *telemetryStats) {
+	if cfg.Telemetry.Enabled {
+		fmt.Printf("Telemetry is enabled, counting into %s\n", file)
+	} else {
+		fmt.Println("Telemetry is disabled; set telemetry.enabled: true in the configuration")
+		fmt.Println("or FUNTERM_TELEMETRY_ENABLED=true to count usage into a local file")
+	}
+	total := 0
+	for _, count := range stats.Sessions {
+		total += count
+	}
+	if total == 0 {
+		fmt.Println("No sessions recorded")
+		return
+	}
+	fmt.Printf("%d sessions since %s\n", total, stats.Since.Local().Format("2006-01-02"))
+	printTelemetryCounts("Sessions", stats.Sessions)
+	printTelemetryCounts("Runtimes enabled", stats.Runtimes)
+	printTelemetryCounts("Languages called", stats.Languages)
+	printTelemetryCounts("Statements", stats.Features)
+	printTelemetryCounts("Errors", stats.Errors)
+}
+
+// printTelemetryCounts prints the counts of a section, most frequent first
+func printTelemetryCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Printf("\n%s:\n", title)
+	for _, name := range names {
+		fmt.Printf("  %-28s %d\n", name, counts[name])
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunTests implements `funterm test [path...]`: it runs every script in the
+// paths, each in its own funterm process started in the script's directory,
+// and reports the ones that fail. A script passes when it exits with status
+// 0; a script named *_error.su passes when it fails, and its output must
+// contain the text of each "// expect-error:" comment. The output of a
+// script that passes must contain each "// expect-output:". A script with a
+// "// requires:" comment is skipped when one of the executables it names is
+// not in PATH. "// env: NAME=value" sets a variable for the script, e.g. a
+// FUNTERM_* override of the config; {tmp} in the value is replaced with a
+// temporary directory removed after the script. The output of a failed script is shown,
+// of every script with --verbose. --runs and --seed are passed to forall in
+// the scripts. It returns false if a script failed.
+func RunTests(paths []string, options *cliOptions) (bool, error) {
+	timeout := time.Minute
+	if options.timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(options.timeout); err != nil || timeout <= 0 {
+			return false, usageErrorf("test", "invalid --timeout '%s'", options.timeout)
+		}
+	}
+	env := os.Environ()
+	if options.runs != "" {
+		if runs, err := strconv.Atoi(options.runs); err != nil || runs < 1 {
+			return false, usageErrorf("test", "invalid --runs '%s'", options.runs)
+		}
+		env = append(env, "FUNTERM_FORALL_RUNS="+options.runs)
+	}
+	if options.seed != "" {
+		if _, err := strconv.ParseInt(options.seed, 10, 64); err != nil {
+			return false, usageErrorf("test", "invalid --seed '%s'", options.seed)
+		}
+		env = append(env, "FUNTERM_FORALL_SEED="+options.seed)
+	}
+	if len(paths) == 0 {
+		paths = []string{"tests"}
+	}
+
+	var scripts []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if !info.IsDir() {
+			scripts = append(scripts, path)
+			continue
+		}
+		found, err := filepath.Glob(filepath.Join(path, "*.su"))
+		if err != nil {
+			return false, err
+		}
+		sort.Strings(found)
+		scripts = append(scripts, found...)
+	}
+	if len(scripts) == 0 {
+		return false, fmt.Errorf("no scripts in %s", strings.Join(paths, ", "))
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return false, err
+	}
+	// Scripts can run funterm itself, e.g. to test the REPL or a command
+	env = append(env, "FUNTERM_EXECUTABLE="+executable)
+	var flags []string
+	if options.noConfig {
+		flags = append(flags, "--no-config")
+	} else if options.configPath != "" {
+		configPath, err := filepath.Abs(expandHome(options.configPath))
+		if err != nil {
+			return false, err
+		}
+		flags = append(flags, "--config", configPath)
+	}
+
+	failed, skipped := 0, 0
+	start := time.Now()
+	for _, script := range scripts {
+		markers, err := readTestMarkers(script)
+		if err != nil {
+			return false, err
+		}
+		if missing := missingExecutables(markers.requires); len(missing) > 0 {
+			fmt.Printf("skip %s (%s not in PATH)\n", script, strings.Join(missing, ", "))
+			skipped++
+			continue
+		}
+
+		scriptFlags := flags
+		if markers.config != "" {
+			// A missing file would silently run the script with the defaults
+			if _, err := os.Stat(filepath.Join(filepath.Dir(script), markers.config)); err != nil {
+				return false, fmt.Errorf("%s: \"// config:\" %v", script, err)
+			}
+			scriptFlags = []string{"--config", markers.config}
+		}
+		scriptEnv, tmpDir, err := expandTestEnv(markers.env)
+		if err != nil {
+			return false, fmt.Errorf("%s: %v", script, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, executable, append(append([]string{"run"}, scriptFlags...), filepath.Base(script))...)
+		cmd.Dir = filepath.Dir(script)
+		cmd.Env = append(env[:len(env):len(env)], scriptEnv...)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		scriptStart := time.Now()
+		err = cmd.Run()
+		cancel()
+		if tmpDir != "" {
+			os.RemoveAll(tmpDir)
+		}
+		elapsed := time.Since(scriptStart)
+
+		status := "ok  "
+		expectFailure := strings.HasSuffix(script, "_error.su")
+		passed := (err == nil) != expectFailure
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			status, passed = "FAIL", false
+			output.WriteString(fmt.Sprintf("timed out after %v\n", timeout))
+		case !passed:
+			status = "FAIL"
+			if err == nil {
+				output.WriteString("expected the script to fail\n")
+			}
+		default:
+			expected, what := markers.expectOutput, "output"
+			if expectFailure {
+				expected, what = markers.expectErrors, "error"
+			}
+			for _, text := range expected {
+				if !strings.Contains(output.String(), text) {
+					status, passed = "FAIL", false
+					output.WriteString(fmt.Sprintf("expected the %s to contain %q\n", what, text))
+				}
+			}
+		}
+		fmt.Printf("%s %s (%.2fs)\n", status, script, elapsed.Seconds())
+		if !passed {
+			failed++
+		}
+		if !passed || options.verbose {
+			for _, line := range strings.Split(strings.TrimRight(output.String(), "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("%d passed, %d failed", len(scripts)-failed-skipped, failed)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	fmt.Printf("\n%s (%.2fs)\n", summary, time.Since(start).Seconds())
+	return failed == 0, nil
+}
+
+// testMarkers are the comments of a script that funterm test reads
+type testMarkers struct {
+	requires     []string // Executables from "// requires:"
+	expectErrors []string // Texts from "// expect-error:"
+	expectOutput []string // Texts from "// expect-output:"
+	env          []string // NAME=value pairs from "// env:"
+	config       string   // Config file from "// config:", relative to the script
+}
+
+// readTestMarkers reads the markers of a script
+func readTestMarkers(script string) (testMarkers, error) {
+	var markers testMarkers
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return markers, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "// requires:"); ok {
+			markers.requires = append(markers.requires, strings.Fields(rest)...)
+		} else if rest, ok := strings.CutPrefix(line, "// expect-error:"); ok {
+			if text := strings.TrimSpace(rest); text != "" {
+				markers.expectErrors = append(markers.expectErrors, text)
+			}
+		} else if rest, ok := strings.CutPrefix(line, "// expect-output:"); ok {
+			if text := strings.TrimSpace(rest); text != "" {
+				markers.expectOutput = append(markers.expectOutput, text)
+			}
+		} else if rest, ok := strings.CutPrefix(line, "// config:"); ok {
+			markers.config = strings.TrimSpace(rest)
+		} else if rest, ok := strings.CutPrefix(line, "// env:"); ok {
+			for _, pair := range strings.Fields(rest) {
+				if !strings.Contains(pair, "=") {
+					return markers, fmt.Errorf("%s: \"// env:\" needs NAME=value, got %q", script, pair)
+				}
+				markers.env = append(markers.env, pair)
+			}
+		}
+	}
+	return markers, nil
+}
+
+// expandTestEnv replaces {tmp} in the "// env:" values with a new temporary
+// directory, which is returned so that it can be removed after the script
+func expandTestEnv(pairs []string) ([]string, string, error) {
+	tmpDir := ""
+	expanded := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if strings.Contains(pair, "{tmp}") && tmpDir == "" {
+			dir, err := os.MkdirTemp("", "funterm-test-")
+			if err != nil {
+				return nil, "", err
+			}
+			tmpDir = dir
+		}
+		expanded = append(expanded, strings.ReplaceAll(pair, "{tmp}", tmpDir))
+	}
+	return expanded, tmpDir, nil
+}
+
+// missingExecutables returns the names that are not found in PATH
+func missingExecutables(names []string) []string {
+	var missing []string
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
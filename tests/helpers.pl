@@ -0,0 +1,4 @@
+sub imported_function {
+    my ($a, $b) = @_;
+    return $a + $b;
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"funterm/engine"
+	"funterm/errors"
+	"funterm/shared"
+)
+
+// tutorialStep is one exercise: what it teaches, code run before it and how
+// to tell that the exercise was done
+type tutorialStep struct {
+	Text     string
+	Task     string
+	Setup    string // run silently before the exercise
+	Check    string // empty checks the value of the answer itself
+	Uses     string // text the answer must contain, the construct being taught
+	Want     string // the value of Check as the REPL shows it
+	Hint     string
+	Solution string
+}
+
+type tutorialLesson struct {
+	Name  string
+	Title string
+	Steps []tutorialStep
+}
+
+// tutorialLessons are the lessons of `funterm tutorial` in the order they are taken
+var tutorialLessons = []tutorialLesson{
+	{
+		Name:  "calls",
+		Title: "Calling Lua and Python",
+		Steps: []tutorialStep{
+			{
+				Text:     "A runtime is reached through its prefix: lua.string.upper(\"hi\") calls Lua's\nstring.upper and py.abs(-5) calls Python's abs. The results are funterm\nvalues you can keep in variables.",
+				Task:     "Store Lua's string.upper of \"funterm\" in a variable called name.",
+				Check:    "name",
+				Want:     `"FUNTERM"`,
+				Hint:     "Assign the call: name = lua.string.<function>(\"funterm\")",
+				Solution: `name = lua.string.upper("funterm")`,
+			},
+			{
+				Text:     "Python builtins are called the same way, with py. in front.",
+				Task:     "Store the absolute value of -42, computed by Python's abs, in distance.",
+				Check:    "distance",
+				Want:     "42",
+				Hint:     "Python's abs is py.abs",
+				Solution: `distance = py.abs(-42)`,
+			},
+			{
+				Text:     "The result of one runtime can be an argument of another; funterm converts\nthe value on the way.",
+				Task:     "Pass lua.string.rep(\"ab\", 3) to Python's len and store the length in size.",
+				Check:    "size",
+				Want:     "6",
+				Hint:     "Nest the Lua call inside py.len(...)",
+				Solution: `size = py.len(lua.string.rep("ab", 3))`,
+			},
+		},
+	},
+	{
+		Name:  "variables",
+		Title: "Variables in funterm and in the runtimes",
+		Steps: []tutorialStep{
+			{
+				Text:     "Plain variables belong to funterm. A qualified name such as lua.count sets a\nglobal inside Lua itself, where Lua code sees it.",
+				Task:     "Set the Lua global limit to 10.",
+				Check:    "lua.limit",
+				Want:     "10",
+				Hint:     "Assign to the qualified name: lua.<name> = <value>",
+				Solution: `lua.limit = 10`,
+			},
+			{
+				Text:     "Python globals work the same way with py.",
+				Task:     "Set the Python global greeting to \"hello\".",
+				Check:    "py.greeting",
+				Want:     `"hello"`,
+				Hint:     "Assign to py.greeting",
+				Solution: `py.greeting = "hello"`,
+			},
+			{
+				Setup:    "lua.limit = 10",
+				Text:     "Qualified variables can be read in any expression.",
+				Task:     "Store the Lua global limit plus 5 in total.",
+				Check:    "total",
+				Want:     "15",
+				Hint:     "Read lua.limit in an addition",
+				Solution: `total = lua.limit + 5`,
+			},
+		},
+	},
+	{
+		Name:  "match",
+		Title: "Pattern matching",
+		Steps: []tutorialStep{
+			{
+				Setup:    "code = 404",
+				Text:     "match compares a value with patterns in order and runs the first arm that\nfits; _ matches anything. Arms are separated by commas, and the match has\nthe value of the arm that ran. id(x) is simply x:\n  match x { 1 -> id(\"one\"), _ -> id(\"other\") }\nThe variable code is set to 404.",
+				Task:     "Write a match on code that gives \"not found\" for 404 and \"other\" otherwise.",
+				Uses:     "match",
+				Want:     `"not found"`,
+				Hint:     "Two arms: 404 -> id(\"not found\") and _ -> id(\"other\")",
+				Solution: `match code { 404 -> id("not found"), _ -> id("other") }`,
+			},
+			{
+				Setup:    "score = 95",
+				Text:     "A pattern can be an inclusive range such as 1..10. The variable score is\nset to 95.",
+				Task:     "Write a match on score that gives \"A\" for 90..100 and \"B\" for anything else.",
+				Uses:     "..",
+				Want:     `"A"`,
+				Hint:     "Start with the arm 90..100 -> id(\"A\")",
+				Solution: `match score { 90..100 -> id("A"), _ -> id("B") }`,
+			},
+		},
+	},
+	{
+		Name:  "bitstrings",
+		Title: "Bitstrings",
+		Steps: []tutorialStep{
+			{
+				Text:     "<<...>> builds binary data from segments; value:size gives the size in bits.\n<<1:8, 2:8>> is two bytes.",
+				Task:     "Build packet: the byte 1 followed by 515 as a 16-bit integer.",
+				Check:    "packet",
+				Want:     "<<1,2,3>>",
+				Hint:     "Two segments: 1:8 and 515:16",
+				Solution: `packet = <<1:8, 515:16>>`,
+			},
+			{
+				Setup:    "packet = <<1:8, 515:16>>",
+				Text:     "The same syntax on the left of = takes data apart, binding each segment to a\nvariable. packet holds the bitstring of the last exercise.",
+				Task:     "Read the first byte of packet into version and the 16-bit field into length.",
+				Check:    "length",
+				Want:     "515",
+				Hint:     "<<version:8, length:16>> = packet",
+				Solution: `<<version:8, length:16>> = packet`,
+			},
+			{
+				Setup:    `frame = <<5:8, "Hello", " World">>`,
+				Text:     "A size can come from a field read earlier in the same pattern, and\nname/binary takes the rest. frame starts with a length byte.",
+				Task:     "Read the length byte of frame into n, the next n bytes into word and the rest into rest.",
+				Check:    "word",
+				Want:     `"Hello"`,
+				Hint:     "word:n/binary reads n bytes",
+				Solution: `<<n:8, word:n/binary, rest/binary>> = frame`,
+			},
+		},
+	},
+}
+
+// tutorialBuiltins are the builtins exercises may call; files, the network
+// and runtimes other than Lua and Python stay out of reach
+var tutorialBuiltins = []string{"print", "len", "concat", "id", "sort", "min", "max", "join", "hexdump", "byte_size", "bit_size"}
+
+// tutorialProgress is the number of steps done in each lesson
+type tutorialProgress map[string]int
+
+// RunTutorial implements `funterm tutorial`: it continues where the last
+// session stopped, or starts the named lesson from the beginning
+func RunTutorial(args []string, configPath string, noConfig, list, reset bool) (bool, error) {
+	if len(args) > 1 {
+		return false, usageErrorf("tutorial", "unexpected argument '%s'", args[1])
+	}
+	progress := loadTutorialProgress()
+	if reset {
+		if err := os.Remove(tutorialProgressPath()); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		fmt.Println("Tutorial progress reset")
+		return true, nil
+	}
+	if list {
+		for _, lesson := range tutorialLessons {
+			fmt.Printf("  %-12s %-42s %d/%d\n", lesson.Name, lesson.Title, min(progress[lesson.Name], len(lesson.Steps)), len(lesson.Steps))
+		}
+		return true, nil
+	}
+
+	first, step := -1, 0
+	if len(args) == 1 {
+		for i, lesson := range tutorialLessons {
+			if lesson.Name == args[0] {
+				first = i
+			}
+		}
+		if first < 0 {
+			return false, usageErrorf("tutorial", "unknown lesson '%s', expected one of %s", args[0], strings.Join(tutorialLessonNames(), ", "))
+		}
+	} else {
+		for i, lesson := range tutorialLessons {
+			if progress[lesson.Name] < len(lesson.Steps) {
+				first, step = i, progress[lesson.Name]
+				break
+			}
+		}
+		if first < 0 {
+			fmt.Println("You have finished every lesson. Run 'funterm tutorial <lesson>' to take one again,")
+			fmt.Println("or 'funterm tutorial --list' to see them.")
+			return true, nil
+		}
+	}
+
+	path := ""
+	if !noConfig {
+		path = findConfigFile(configPath)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return false, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	eng, err := newTutorialEngine(cfg)
+	if err != nil {
+		return false, err
+	}
+	defer eng.CleanupRuntimes()
+
+	fmt.Println("funterm tutorial. Type the code for each exercise; :hint shows a hint,")
+	fmt.Println(":solution the answer, :skip goes to the next exercise and :quit stops.")
+	in := bufio.NewReader(os.Stdin)
+	for i := first; i < len(tutorialLessons); i++ {
+		lesson := tutorialLessons[i]
+		fmt.Printf("\n== %s ==\n", lesson.Title)
+		for ; step < len(lesson.Steps); step++ {
+			done, err := runTutorialStep(eng, in, lesson, step)
+			if err != nil {
+				return false, err
+			}
+			if !done {
+				fmt.Println("Progress saved, run 'funterm tutorial' to continue.")
+				return true, nil
+			}
+			if step+1 > progress[lesson.Name] {
+				progress[lesson.Name] = step + 1
+				saveTutorialProgress(progress)
+			}
+		}
+		step = 0
+		fmt.Printf("\nLesson '%s' complete.\n", lesson.Name)
+	}
+	fmt.Println("\nThat was the last lesson. The README has the rest of the language.")
+	return true, nil
+}
+
+// newTutorialEngine creates the engine the exercises run in: Lua and Python
+// only, a few builtins and a time limit per command
+func newTutorialEngine(cfg *Config) (*engine.ExecutionEngine, error) {
+	eng, err := engine.NewExecutionEngineWithConfig(engine.ExecutionEngineConfig{
+		RuntimeRegistry:  newRuntimeRegistry(cfg, nil),
+		SpinnerThreshold: -1,
+		Numbers:          cfg.Engine.Numbers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := eng.SetPolicy(&engine.SessionPolicy{
+		Languages:        []string{"lua", "python"},
+		Builtins:         tutorialBuiltins,
+		MaxExecutionTime: 10 * time.Second,
+	}); err != nil {
+		return nil, err
+	}
+	if err := eng.InitializeRuntimes(); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации рантаймов: %v", err)
+	}
+	return eng, nil
+}
+
+// runTutorialStep reads input until the exercise is solved or skipped; it
+// returns false when the user quits
+func runTutorialStep(eng *engine.ExecutionEngine, in *bufio.Reader, lesson tutorialLesson, index int) (bool, error) {
+	step := lesson.Steps[index]
+	if step.Setup != "" {
+		if _, _, _, err := eng.Execute(step.Setup); err != nil {
+			return false, fmt.Errorf("tutorial setup '%s' failed: %v", step.Setup, err)
+		}
+	}
+	fmt.Printf("\n[%s %d/%d]\n%s\n\n%s\n", lesson.Name, index+1, len(lesson.Steps), step.Text, step.Task)
+	for {
+		code, err := readTutorialInput(in)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		switch strings.TrimSpace(code) {
+		case "":
+			continue
+		case ":quit", ":q":
+			return false, nil
+		case ":hint":
+			fmt.Println("Hint:", step.Hint)
+			continue
+		case ":solution":
+			fmt.Println("Solution:", step.Solution)
+			continue
+		case ":skip":
+			return true, nil
+		}
+
+		result, isPrint, hasResult, err := eng.Execute(code)
+		if err != nil {
+			fmt.Println(tutorialError(err))
+			continue
+		}
+		if hasResult && !isPrint {
+			fmt.Println("=>", tutorialValue(result))
+		}
+		if step.Uses != "" && !strings.Contains(code, step.Uses) {
+			fmt.Printf("Not yet: this exercise is about %s, use it. Type :hint for a hint.\n", step.Uses)
+			continue
+		}
+		got := result
+		if step.Check != "" {
+			if got, _, _, err = eng.Execute(step.Check); err != nil {
+				fmt.Printf("Not yet: %s isn't set. Type :hint for a hint.\n", step.Check)
+				continue
+			}
+		}
+		if tutorialValue(got) == step.Want {
+			fmt.Println("Correct!")
+			return true, nil
+		}
+		if step.Check != "" {
+			fmt.Printf("Not yet: %s is %s, it should be %s. Type :hint for a hint.\n", step.Check, tutorialValue(got), step.Want)
+		} else {
+			fmt.Printf("Not yet: that gives %s, it should give %s. Type :hint for a hint.\n", tutorialValue(got), step.Want)
+		}
+	}
+}
+
+// readTutorialInput reads a line, and more lines while brackets are open
+func readTutorialInput(in *bufio.Reader) (string, error) {
+	prompt := "tutorial> "
+	var code strings.Builder
+	for {
+		fmt.Print(prompt)
+		line, err := in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if code.Len() > 0 && err == io.EOF {
+				return code.String(), nil
+			}
+			return "", err
+		}
+		code.WriteString(line)
+		if bracketBalance(code.String()) <= 0 {
+			return strings.TrimRight(code.String(), "\r\n"), nil
+		}
+		prompt = "...       "
+	}
+}
+
+// bracketBalance counts the brackets opened and not closed outside strings
+func bracketBalance(code string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// tutorialValue shows a value the way the REPL does
+func tutorialValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case *shared.PreFormattedResult:
+		return v.Value
+	case string:
+		return "\"" + v + "\""
+	}
+	return shared.FormatValueForDisplay(value)
+}
+
+func tutorialError(err error) string {
+	if execErr, ok := err.(*errors.ExecutionError); ok {
+		return "Error: " + execErr.Message
+	}
+	return "Error: " + err.Error()
+}
+
+func tutorialProgressPath() string {
+	return expandHome("~/.funterm/tutorial.json")
+}
+
+func loadTutorialProgress() tutorialProgress {
+	progress := make(tutorialProgress)
+	if data, err := os.ReadFile(tutorialProgressPath()); err == nil {
+		_ = json.Unmarshal(data, &progress)
+	}
+	return progress
+}
+
+func saveTutorialProgress(progress tutorialProgress) {
+	data, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return
+	}
+	path := tutorialProgressPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0644)
+}